@@ -0,0 +1,107 @@
+import {TestMeApp} from './index.ts'
+import {CliParser} from './cli.ts'
+import {redirectOutput} from './utils/tty.ts'
+import {countCategories} from './utils/categories.ts'
+import type {ResultCategory} from './utils/categories.ts'
+import type {CliOptions, Reporter, TestResult} from './types.ts'
+
+/*
+ Configuration of an embedded run
+ */
+export type RunnerConfig = {
+    options?: Partial<CliOptions> // Command line options, e.g. {patterns: ['unit'], workers: 4, chdir: 'test'}
+    output?: NodeJS.WritableStream // Stream for the human-readable output (default: the console)
+    reporters?: Reporter[] // Reporters receiving the run's events as they happen
+}
+
+/*
+ Outcome of an embedded run
+ */
+export type RunResults = {
+    exitCode: number // Exit status the tm command would return
+    passed: boolean // True if the exit status is zero
+    interrupted: boolean // True if the run was stopped through its abort signal
+    tests: TestResult[] // Result of every test that ran
+    categories: Record<ResultCategory, number> // Result counts per category (pass, fail, crash, ...)
+}
+
+/*
+ Runner - Library API for running tests from another program instead of invoking the tm command
+
+ A run takes the same options as the command line and returns the results of every test. The tm
+ command is a thin wrapper over the same run. Human-readable output goes to the console unless an
+ output stream is given, and reporters added with addReporter() receive the run's events as they
+ happen, alongside any --report and --json reporters. Formats registered with registerReporter() can be
+ selected by name with the report option, as built-in formats are. Aborting the signal passed to run() stops the
+ run gracefully as Ctrl+C does: running tests are interrupted and teardown, summary and reports still
+ complete. The runner does not install signal handlers of its own.
+
+ Example:
+     const results = await new Runner({options: {patterns: ['unit']}}).run(AbortSignal.timeout(600000))
+ */
+export class Runner {
+    private config: RunnerConfig
+    private reporters: Reporter[]
+
+    /*
+     Creates a runner
+     @param config Run options, output stream and reporters
+     */
+    constructor(config: RunnerConfig = {}) {
+        this.config = config
+        this.reporters = [...(config.reporters || [])]
+    }
+
+    /*
+     Adds a reporter for later runs
+     @param reporter Reporter
+     */
+    addReporter(reporter: Reporter): void {
+        this.reporters.push(reporter)
+    }
+
+    /*
+     Runs the tests
+     The options are validated as the command line would validate them. Problems found once the run has
+     started (e.g., a bad configuration file) are reported on the output and give a non-zero exit status.
+     @param signal Optional abort signal that stops the run gracefully
+     @returns Results of the run
+     @throws Error if the options are invalid
+     */
+    async run(signal?: AbortSignal): Promise<RunResults> {
+        const options: CliOptions = {...CliParser.parse([]), ...this.config.options}
+        CliParser.validateOptions(options)
+
+        const app = new TestMeApp(false)
+        this.reporters.forEach((reporter) => app.addReporter(reporter))
+        const stop = () => app.stop('SIGINT')
+        signal?.addEventListener('abort', stop, {once: true})
+        const restore = this.config.output ? redirectOutput(this.config.output) : undefined
+        // The chdir option changes the working directory of the whole process for the run
+        const cwd = process.cwd()
+        try {
+            if (signal?.aborted) {
+                stop()
+            }
+            const exitCode = await app.execute(options)
+            const tests = app.getResults()
+            return {
+                exitCode,
+                passed: exitCode === 0,
+                interrupted: signal?.aborted ?? false,
+                tests,
+                categories: countCategories(tests),
+            }
+        } finally {
+            restore?.()
+            process.chdir(cwd)
+            signal?.removeEventListener('abort', stop)
+        }
+    }
+}
+
+export {registerReporter} from './reporters/index.ts'
+export type {ReporterFactory} from './reporters/index.ts'
+export type {CliOptions, Reporter, TestResult, TestFile} from './types.ts'
+export type {ResultCategory} from './utils/categories.ts'
+export {TestStatus, TestType} from './types.ts'
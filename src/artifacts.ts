@@ -4,6 +4,12 @@ import * as path from 'path'
 import {mkdir, rmdir, readdir, unlink} from 'node:fs/promises'
 import {existsSync} from 'node:fs'
 import {GlobExpansion} from './utils/glob-expansion.ts'
+import {ConfigManager} from './config.ts'
+
+/*
+ Default name of the artifact directory created in each test directory (execution.artifactDir)
+ */
+export const ARTIFACT_DIR = '.testme'
 
 /**
  * Manages build artifacts and temporary files for test execution
@@ -58,7 +64,7 @@ export class ArtifactManager implements IArtifactManager {
             // Explicitly create parent .testme directory first to work around
             // Windows mkdir recursive issues in GitHub runners
             const parentDir = dirname(artifactDir)
-            if (basename(parentDir) === '.testme' && !existsSync(parentDir)) {
+            if (dirname(parentDir) === testFile.directory && !existsSync(parentDir)) {
                 await mkdir(parentDir, {recursive: true})
             }
 
@@ -89,7 +95,7 @@ export class ArtifactManager implements IArtifactManager {
 
             // Check if parent .testme directory is now empty and remove it
             const parentDir = dirname(artifactDir)
-            if (basename(parentDir) === '.testme' && existsSync(parentDir)) {
+            if (dirname(parentDir) === testFile.directory && existsSync(parentDir)) {
                 const entries = await readdir(parentDir)
                 if (entries.length === 0) {
                     await rmdir(parentDir)
@@ -102,15 +108,22 @@ export class ArtifactManager implements IArtifactManager {
 
     /*
      Recursively removes all .testme directories in a directory tree
+     The .testme directory at the root also holds the run state (failures, history and timings), which is removed
+     with it.
      @param rootDir Root directory to start cleaning from
+     @param names Artifact directory names to remove (default: .testme)
+     @param dryRun Find the directories without removing them
+     @returns Paths of the directories removed, or that would be removed with dryRun
      @throws Error if cleanup fails
      */
-    async cleanAllArtifacts(rootDir: string): Promise<void> {
+    async cleanAllArtifacts(rootDir: string, names: string[] = [ARTIFACT_DIR], dryRun = false): Promise<string[]> {
+        const removed: string[] = []
         try {
-            await this.findAndRemoveArtifactDirs(rootDir)
+            await this.findAndRemoveArtifactDirs(rootDir, new Set(names), dryRun, removed)
         } catch (error) {
             throw new Error(`Failed to clean all artifacts in ${rootDir}: ${error}`)
         }
+        return removed
     }
 
     /*
@@ -267,8 +280,16 @@ export class ArtifactManager implements IArtifactManager {
      Recursively finds and removes all .testme artifact directories
      Uses readdir with withFileTypes to avoid extra stat() calls
      @param dirPath Directory to search for artifact directories
+     @param names Artifact directory names
+     @param dryRun Record the directories without removing them
+     @param removed Paths of the directories found so far
      */
-    private async findAndRemoveArtifactDirs(dirPath: string): Promise<void> {
+    private async findAndRemoveArtifactDirs(
+        dirPath: string,
+        names: Set<string>,
+        dryRun: boolean,
+        removed: string[]
+    ): Promise<void> {
         try {
             const entries = await readdir(dirPath, {withFileTypes: true})
 
@@ -276,12 +297,15 @@ export class ArtifactManager implements IArtifactManager {
                 if (entry.isDirectory()) {
                     const fullPath = join(dirPath, entry.name)
 
-                    if (entry.name === '.testme') {
+                    if (names.has(entry.name)) {
                         // Found an artifact directory, remove it
-                        await this.removeDirectory(fullPath)
+                        if (!dryRun) {
+                            await this.removeDirectory(fullPath)
+                        }
+                        removed.push(fullPath)
                     } else if (!this.shouldSkipDirectory(entry.name)) {
                         // Recursively search subdirectories
-                        await this.findAndRemoveArtifactDirs(fullPath)
+                        await this.findAndRemoveArtifactDirs(fullPath, names, dryRun, removed)
                     }
                 }
             }
@@ -364,21 +388,22 @@ export class ArtifactManager implements IArtifactManager {
         }
 
         // 2. Add TESTME_* variables derived from CLI options/config
-        allEnvVars.TESTME_VERBOSE = config.output?.verbose === true ? '1' : '0'
+        allEnvVars.TESTME_VERBOSE = config.output?.verbose === true || config.output?.verboseOnFail === true ? '1' : '0'
         allEnvVars.TESTME_QUIET = config.output?.quiet === true ? '1' : '0'
         allEnvVars.TESTME_KEEP = config.execution?.keepArtifacts === true ? '1' : '0'
         allEnvVars.TESTME_STOP = config.execution?.stopOnFailure === true ? '1' : '0'
         allEnvVars.TESTME_ITERATIONS = (config.execution?.iterations ?? 1).toString()
+        allEnvVars.TESTME_DEPTH = ConfigManager.getDepth(config).toString()
 
-        if (config.execution?.depth !== undefined) {
-            allEnvVars.TESTME_DEPTH = config.execution.depth.toString()
-        }
         if (config.execution?.duration !== undefined) {
             allEnvVars.TESTME_DURATION = config.execution.duration.toString()
         }
         if (config.execution?.testClass !== undefined) {
             allEnvVars.TESTME_CLASS = config.execution.testClass
         }
+        if (config.execution?.bench) {
+            allEnvVars.TESTME_BENCH = '1'
+        }
 
         // 3. Add special variables (PLATFORM, PROFILE, OS, ARCH, CC, TESTDIR, CONFIGDIR)
         const specialVars = GlobExpansion.createSpecialVariables(
@@ -0,0 +1,159 @@
+import type {BenchResult, TestConfig, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {Matrix} from './matrix.ts'
+import {TestCases} from './cases.ts'
+import {existsSync, readFileSync, writeFileSync} from 'fs'
+import {basename} from 'path'
+
+/*
+ Default regression, in percent of the baseline, that fails a benchmark
+ */
+export const BENCH_THRESHOLD = 10
+
+/*
+ Timing line written by a benchmark: TESTME-BENCH name=NAME ns=NANOSECONDS
+ */
+const BENCH_LINE = /^TESTME-BENCH\s+(.*)$/gm
+
+/*
+ Benchmarks - Compares timings reported by tests against a recorded baseline (--bench)
+
+ A test reports a benchmark by printing a line such as "TESTME-BENCH name=parse ns=1234" to stdout. In bench
+ mode (--bench, execution.bench) the timings are compared with the baseline in a file named after the test with
+ a .bench suffix (e.g., math.tst.c.bench), a JSON object mapping benchmark names to nanoseconds. A benchmark
+ slower than its baseline by more than execution.benchThreshold percent (default 10) fails the test. Benchmarks
+ without a baseline are reported but never fail. With --bench-update (execution.benchUpdate), the timings of a
+ passing test are written to the baseline instead, keeping entries for benchmarks that did not run.
+
+ The cases and matrix cells of a test share its baseline file, so their benchmark names are qualified with the
+ case and cell labels. The file is read and written synchronously so concurrent cells cannot interleave updates.
+ */
+export class Benchmarks {
+    /*
+     Gets the baseline file path for a test
+     @param testPath Path to the test file
+     @returns Path of the .bench file
+     */
+    static getPath(testPath: string): string {
+        return `${testPath}.bench`
+    }
+
+    /*
+     Parses the benchmark lines of a test's stdout
+     Lines without a name or a non-negative ns value are ignored. A repeated name keeps the last value.
+     @param stdout Test stdout
+     @returns Nanoseconds keyed by benchmark name
+     */
+    static parse(stdout: string): Map<string, number> {
+        const timings = new Map<string, number>()
+        for (const match of stdout.replace(/\r/g, '').matchAll(BENCH_LINE)) {
+            const fields = new Map<string, string>()
+            for (const field of match[1]!.trim().split(/\s+/)) {
+                const eq = field.indexOf('=')
+                if (eq > 0) {
+                    fields.set(field.slice(0, eq), field.slice(eq + 1))
+                }
+            }
+            const name = fields.get('name')
+            const ns = Number(fields.get('ns') || NaN)
+            if (name && Number.isFinite(ns) && ns >= 0) {
+                timings.set(name, ns)
+            }
+        }
+        return timings
+    }
+
+    /*
+     Compares a test's benchmarks against the baseline, or records a new baseline with --bench-update
+     @param result Test result with captured stdout
+     @param config Test configuration
+     @returns The result with its benchmarks, failed if one regressed beyond the threshold
+     */
+    static check(result: TestResult, config: TestConfig): TestResult {
+        if (!config.execution?.bench || result.stdout === undefined) {
+            return result
+        }
+        const timings = this.parse(result.stdout)
+        if (timings.size === 0) {
+            return result
+        }
+        const path = this.getPath(result.file.path)
+        const baseline = this.load(path)
+        const qualify = (name: string) => Matrix.label(result.file, TestCases.label(result.file, name))
+
+        if (config.execution.benchUpdate) {
+            if (result.status !== TestStatus.Passed) {
+                return result
+            }
+            for (const [name, ns] of timings) {
+                baseline[qualify(name)] = ns
+            }
+            const sorted = Object.fromEntries(Object.entries(baseline).sort(([a], [b]) => a.localeCompare(b)))
+            writeFileSync(path, JSON.stringify(sorted, null, 4) + '\n')
+            const benchmarks = [...timings].map(([name, ns]) => ({name: qualify(name), ns}))
+            const note = `Recorded ${timings.size} benchmark(s) in ${basename(path)}`
+            return {...result, benchmarks, output: `${result.output}\n${note}`.trim()}
+        }
+
+        const threshold = config.execution.benchThreshold ?? BENCH_THRESHOLD
+        const benchmarks: BenchResult[] = []
+        for (const [name, ns] of timings) {
+            const key = qualify(name)
+            const base = baseline[key]
+            if (base === undefined) {
+                benchmarks.push({name: key, ns})
+                continue
+            }
+            const delta = base > 0 ? ((ns - base) / base) * 100 : 0
+            benchmarks.push({name: key, ns, baseline: base, delta, regressed: delta > threshold})
+        }
+        const regressed = benchmarks.filter((bench) => bench.regressed)
+        if (regressed.length === 0 || result.status !== TestStatus.Passed) {
+            return {...result, benchmarks}
+        }
+        const messages = regressed.map(
+            (bench) =>
+                `Benchmark ${bench.name} regressed ${bench.delta!.toFixed(1)}% ` +
+                `(${bench.ns} ns vs ${bench.baseline} ns baseline, limit ${threshold}%)`
+        )
+        return {
+            ...result,
+            benchmarks,
+            status: TestStatus.Failed,
+            error: [...messages, result.error].filter((text) => text).join('\n'),
+        }
+    }
+
+    /*
+     Formats a benchmark's timing and its change against the baseline
+     @param bench Benchmark result
+     @returns Text such as "1234 ns (baseline 1000 ns, +23.4%)"
+     */
+    static format(bench: BenchResult): string {
+        if (bench.baseline === undefined) {
+            return `${bench.ns} ns (no baseline)`
+        }
+        const delta = bench.delta ?? 0
+        return `${bench.ns} ns (baseline ${bench.baseline} ns, ${delta >= 0 ? '+' : ''}${delta.toFixed(1)}%)`
+    }
+
+    /*
+     Reads a baseline file
+     @param path Baseline file path
+     @returns Nanoseconds keyed by qualified benchmark name (empty if the file is missing or invalid)
+     */
+    private static load(path: string): Record<string, number> {
+        if (!existsSync(path)) {
+            return {}
+        }
+        try {
+            const data = JSON.parse(readFileSync(path, 'utf-8')) as Record<string, unknown>
+            return Object.fromEntries(
+                Object.entries(data).filter(([, ns]) => typeof ns === 'number' && Number.isFinite(ns))
+            ) as Record<string, number>
+        } catch (error) {
+            console.warn(`⚠️  Ignoring invalid benchmark baseline ${path}: ${error}`)
+            return {}
+        }
+    }
+}
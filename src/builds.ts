@@ -0,0 +1,119 @@
+import type {BuildResult, TestFile} from './types.ts'
+
+/*
+ TestBuilds - Build phase that compiles tests ahead of running them (--build-workers)
+
+ Tests are built by a pool of build workers sized separately from the workers that run tests. Each
+ test is handed to the run queue as soon as its build completes, so tests start running while others
+ are still building. Tests whose handler has nothing to build are ready at once. The build phase
+ lasts from the first build to the last and its wall clock time is reported in the summary.
+ */
+export class TestBuilds {
+    private queue: TestFile[]
+    private ready: TestFile[] = []
+    private waiters: Array<() => void> = []
+    private results = new Map<TestFile, BuildResult | null>()
+    private building = new Map<TestFile, Promise<BuildResult | null>>()
+    private remaining: number
+    private build: (file: TestFile) => Promise<BuildResult | null>
+    private stopped = false
+    private started = 0
+    private ended = 0
+
+    /*
+     Starts building tests
+     @param tests Tests in the order their builds should start
+     @param workers Maximum number of concurrent builds
+     @param build Builds one test, returning null if it has nothing to build
+     */
+    constructor(tests: TestFile[], workers: number, build: (file: TestFile) => Promise<BuildResult | null>) {
+        this.queue = [...tests]
+        this.remaining = tests.length
+        this.build = build
+        for (let i = 0; i < Math.min(workers, tests.length); i++) {
+            this.worker()
+        }
+    }
+
+    /*
+     Gets the next built test, in the order builds complete
+     @returns Test ready to run, or undefined when every test has been handed out or building stopped
+     */
+    async next(): Promise<TestFile | undefined> {
+        while (this.ready.length === 0 && this.remaining > 0 && !this.stopped) {
+            await new Promise<void>((resolve) => this.waiters.push(resolve))
+        }
+        const file = this.ready.shift()
+        if (file) {
+            this.remaining--
+        }
+        return file
+    }
+
+    /*
+     Waits for a test's build, starting it at once if no worker has yet (used when tests run in order)
+     @param file Test to wait for
+     @returns Build result, or null if the test had nothing to build
+     */
+    async wait(file: TestFile): Promise<BuildResult | null> {
+        const index = this.queue.indexOf(file)
+        if (index >= 0) {
+            this.queue.splice(index, 1)
+            await this.run(file)
+        }
+        return (await this.building.get(file)) ?? null
+    }
+
+    /*
+     Gets the result of a completed build
+     @param file Test file
+     @returns Build result, null if the test had nothing to build, or undefined if it was not built
+     */
+    get(file: TestFile): BuildResult | null | undefined {
+        return this.results.get(file)
+    }
+
+    /*
+     Stops starting builds, for example when the run is aborted. Builds in progress complete.
+     */
+    stop(): void {
+        this.stopped = true
+        this.queue.length = 0
+        this.waiters.splice(0).forEach((resolve) => resolve())
+    }
+
+    /*
+     Gets the wall clock time of the build phase so far
+     @returns Milliseconds from the start of the first build to the end of the last
+     */
+    getDuration(): number {
+        return this.ended - this.started
+    }
+
+    /*
+     Builds tests from the queue until it is empty
+     */
+    private async worker(): Promise<void> {
+        let file: TestFile | undefined
+        while (!this.stopped && (file = this.queue.shift())) {
+            await this.run(file)
+        }
+    }
+
+    /*
+     Builds one test and marks it ready to run. A build that throws is recorded as failed.
+     @param file Test to build
+     */
+    private async run(file: TestFile): Promise<void> {
+        this.started ||= Date.now()
+        const pending = this.build(file).catch(
+            (error): BuildResult => ({success: false, duration: 0, output: '', error: `Build failed: ${error}`})
+        )
+        this.building.set(file, pending)
+        const result = await pending
+        this.ended = Date.now()
+        this.results.set(file, result)
+        this.ready.push(file)
+        this.waiters.shift()?.()
+    }
+}
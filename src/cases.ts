@@ -0,0 +1,84 @@
+import type {TestFile} from './types.ts'
+import {existsSync} from 'fs'
+import {readFile} from 'fs/promises'
+import {basename, join} from 'path'
+
+/*
+ TestCases - Data-driven test cases from sidecar .cases.json files
+
+ If a file named after the test with a .cases.json suffix (e.g., foo.tst.sh.cases.json) sits next
+ to the test, it holds an array of case objects and the test runs once per case. Each case is an
+ independent test named foo.tst.sh[NAME] that is scheduled, reported and counted on its own.
+ The case fields are passed as TESTME_CASE_<field> environment variables and the case name as
+ TESTME_CASE. The case name is the case's "name" field, or its index in the array.
+ */
+export class TestCases {
+    /*
+     Gets the cases file path for a test
+     @param testPath Path to the test file
+     @returns Path of the .cases.json file
+     */
+    static getPath(testPath: string): string {
+        return `${testPath}.cases.json`
+    }
+
+    /*
+     Expands tests with a cases file into one test per case
+     @param tests Discovered test files
+     @returns Tests with each parameterized test replaced by its cases, in order
+     */
+    static async expand(tests: TestFile[]): Promise<TestFile[]> {
+        const expanded: TestFile[] = []
+        for (const test of tests) {
+            expanded.push(...(await this.load(test)))
+        }
+        return expanded
+    }
+
+    /*
+     Loads the cases of a test
+     An unreadable or invalid cases file yields the test with casesError set, so it is reported as an error.
+     @param test Test file
+     @returns One test per case, or the test itself if it has no cases file
+     */
+    static async load(test: TestFile): Promise<TestFile[]> {
+        const path = this.getPath(test.path)
+        if (test.testCase || !existsSync(path)) {
+            return [test]
+        }
+        let cases: unknown
+        try {
+            cases = JSON.parse(await readFile(path, 'utf-8'))
+        } catch (error) {
+            return [{...test, casesError: `Cannot read ${basename(path)}: ${error}`}]
+        }
+        if (!Array.isArray(cases) || cases.some((item) => !item || typeof item !== 'object' || Array.isArray(item))) {
+            return [{...test, casesError: `${basename(path)} must contain an array of case objects`}]
+        }
+        return cases.map((fields: Record<string, unknown>, index) => {
+            const name = typeof fields.name === 'string' && fields.name ? fields.name : String(index)
+            const env: Record<string, string> = {TESTME_CASE: name}
+            for (const [key, value] of Object.entries(fields)) {
+                const text = typeof value === 'string' ? value : JSON.stringify(value)
+                env[`TESTME_CASE_${key.replace(/\W/g, '_')}`] = text
+            }
+            return {
+                ...test,
+                name: `${test.name}[${name}]`,
+                // Each case builds separately so parallel cases do not overwrite each other's binaries
+                artifactDir: join(test.artifactDir, 'cases', name.replace(/[^\w.-]/g, '_')),
+                testCase: {name, env},
+            }
+        })
+    }
+
+    /*
+     Gets the display name of a test, adding the case name for a parameterized test
+     @param file Test file
+     @param path Display path of the test file
+     @returns Path, followed by [NAME] for a test case
+     */
+    static label(file: TestFile, path: string): string {
+        return file.testCase ? `${path}[${file.testCase.name}]` : path
+    }
+}
@@ -1,4 +1,7 @@
-import type {CliOptions} from './types.ts'
+import type {CliOptions, ColorMode} from './types.ts'
+import {TestShards} from './shards.ts'
+import {TestTags} from './tags.ts'
+import {parseByteSize} from './utils/output-limit.ts'
 
 /*
  Command-line interface parser for the testme application
@@ -31,6 +34,7 @@ export class CliParser {
             noServices: false,
             stop: false,
             live: false,
+            watch: false,
             testClass: undefined,
         }
 
@@ -54,18 +58,63 @@ export class CliParser {
                     i++
                     break
 
+                case '--clean-dry-run':
+                    options.cleanDryRun = true
+                    i++
+                    break
+
                 case '--list':
                 case '-l':
                     options.list = true
                     i++
                     break
 
+                case '--check-config':
+                    options.checkConfig = true
+                    i++
+                    break
+
+                case '--ignore-unknown-keys':
+                    options.ignoreUnknownKeys = true
+                    i++
+                    break
+
+                case '--show-config':
+                    if (i + 1 < args.length) {
+                        options.showConfig = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a directory`)
+                    }
+                    break
+
+                case '--list-json':
+                    options.list = true
+                    options.listJson = true
+                    i++
+                    break
+
+                case '--list-suites':
+                    options.listSuites = true
+                    i++
+                    break
+
                 case '--verbose':
                 case '-v':
                     options.verbose = true
                     i++
                     break
 
+                case '--verbose-on-fail':
+                    options.verboseOnFail = true
+                    i++
+                    break
+
+                case '--progress':
+                    options.progress = true
+                    i++
+                    break
+
                 case '--help':
                 case '-h':
                     options.help = true
@@ -99,6 +148,92 @@ export class CliParser {
                     i++
                     break
 
+                case '--keep-tmp':
+                    options.keepTmp = true
+                    i++
+                    break
+
+                case '--keep-tmp-on-fail':
+                    options.keepTmpOnFail = true
+                    i++
+                    break
+
+                case '--keep-on-fail':
+                    options.keepOnFail = true
+                    i++
+                    break
+
+                case '--asan':
+                    options.asan = true
+                    i++
+                    break
+
+                case '--backtrace':
+                    options.backtrace = true
+                    i++
+                    break
+
+                case '--bench':
+                    options.bench = true
+                    i++
+                    break
+
+                case '--bench-update':
+                    options.benchUpdate = true
+                    i++
+                    break
+
+                case '--cc':
+                    if (i + 1 < args.length) {
+                        options.cc = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a compiler name or path (e.g., clang)`)
+                    }
+                    break
+
+                case '--shell':
+                    if (i + 1 < args.length) {
+                        options.shell = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires an interpreter name or path (e.g., bash)`)
+                    }
+                    break
+
+                case '--accept':
+                    options.accept = true
+                    i++
+                    break
+
+                case '--coverage':
+                    options.coverage = true
+                    i++
+                    break
+
+                case '--coverage-threshold':
+                    if (i + 1 < args.length) {
+                        const threshold = Number(args[i + 1])
+                        if (isNaN(threshold) || threshold < 0 || threshold > 100) {
+                            throw new Error(`${arg} requires a percentage between 0 and 100`)
+                        }
+                        options.coverageThreshold = threshold
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a percentage value`)
+                    }
+                    break
+
+                case '--valgrind':
+                    options.valgrind = true
+                    i++
+                    break
+
+                case '--watch':
+                    options.watch = true
+                    i++
+                    break
+
                 case '--rebuild':
                 case '-R':
                     options.rebuild = true
@@ -110,6 +245,11 @@ export class CliParser {
                     i++
                     break
 
+                case '--strict':
+                    options.strict = true
+                    i++
+                    break
+
                 case '--depth':
                     if (i + 1 < args.length) {
                         const depthValue = parseInt(args[i + 1]!, 10)
@@ -129,6 +269,11 @@ export class CliParser {
                     i++
                     break
 
+                case '--dry-run':
+                    options.dryRun = true
+                    i++
+                    break
+
                 case '--show':
                 case '-s':
                     options.show = true
@@ -149,6 +294,19 @@ export class CliParser {
                     }
                     break
 
+                case '--build-workers':
+                    if (i + 1 < args.length) {
+                        const buildWorkers = parseInt(args[i + 1]!, 10)
+                        if (isNaN(buildWorkers) || buildWorkers < 1) {
+                            throw new Error(`${arg} requires a positive number`)
+                        }
+                        options.buildWorkers = buildWorkers
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a number value`)
+                    }
+                    break
+
                 case '--warning':
                 case '-w':
                     options.warning = true
@@ -174,6 +332,94 @@ export class CliParser {
                     }
                     break
 
+                case '--shuffle':
+                    options.shuffle = true
+                    i++
+                    break
+
+                case '--seed':
+                    if (i + 1 < args.length) {
+                        const seedValue = Number(args[i + 1])
+                        if (!Number.isInteger(seedValue) || seedValue < 0 || seedValue > 0xffffffff) {
+                            throw new Error(`${arg} requires a non-negative integer below 2^32`)
+                        }
+                        options.seed = seedValue
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a number value`)
+                    }
+                    break
+
+                case '--since':
+                    if (i + 1 < args.length) {
+                        options.since = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a git ref`)
+                    }
+                    break
+
+                case '--tags':
+                    if (i + 1 < args.length) {
+                        TestTags.parse(args[i + 1]!)
+                        options.tags = [...(options.tags || []), args[i + 1]!]
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a tag expression`)
+                    }
+                    break
+
+                case '--not-tags':
+                    if (i + 1 < args.length) {
+                        const tags = args[i + 1]!.split(',').map((tag) => tag.trim())
+                        if (tags.some((tag) => !tag)) {
+                            throw new Error(`${arg} requires tag names`)
+                        }
+                        options.notTags = [...(options.notTags || []), ...tags]
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires tag names`)
+                    }
+                    break
+
+                case '--suite':
+                    if (i + 1 < args.length) {
+                        const names = args[i + 1]!.split(',').map((name) => name.trim())
+                        if (names.some((name) => !name)) {
+                            throw new Error(`${arg} requires suite names`)
+                        }
+                        options.suite = [...(options.suite || []), ...names]
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a suite name`)
+                    }
+                    break
+
+                case '--fail-fast':
+                    options.failFast = true
+                    i++
+                    break
+
+                case '--failed':
+                    options.failed = true
+                    i++
+                    break
+
+                case '--flaky-report':
+                    options.flakyReport = true
+                    i++
+                    break
+
+                case '--filter':
+                case '--exclude':
+                    if (i + 1 < args.length) {
+                        options[arg === '--filter' ? 'filter' : 'exclude'] = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a regular expression`)
+                    }
+                    break
+
                 case '--iterations':
                 case '-i':
                     if (i + 1 < args.length) {
@@ -193,6 +439,52 @@ export class CliParser {
                     i++
                     break
 
+                case '--docker':
+                    if (i + 1 < args.length) {
+                        options.docker = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires an image name (e.g., gcc:13)`)
+                    }
+                    break
+
+                case '--doctor':
+                    options.doctor = true
+                    i++
+                    break
+
+                case '--completion':
+                    if (i + 1 < args.length) {
+                        options.completion = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a shell (bash, zsh or fish)`)
+                    }
+                    break
+
+                case '--color':
+                    if (i + 1 < args.length && ['auto', 'always', 'never'].includes(args[i + 1]!)) {
+                        options.color = args[i + 1] as ColorMode
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires auto, always or never`)
+                    }
+                    break
+
+                case '--force':
+                    options.force = true
+                    i++
+                    break
+
+                case '--emit-header':
+                    if (i + 1 < args.length) {
+                        options.emitHeader = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a language (c, go or sh)`)
+                    }
+                    break
+
                 case '--new':
                     if (i + 1 < args.length) {
                         options.new = args[i + 1]!
@@ -207,6 +499,16 @@ export class CliParser {
                     i++
                     break
 
+                case '--no-github':
+                    options.noGithub = true
+                    i++
+                    break
+
+                case '--no-quarantine':
+                    options.noQuarantine = true
+                    i++
+                    break
+
                 case '--no-services':
                 case '-n':
                     options.noServices = true
@@ -218,6 +520,86 @@ export class CliParser {
                     i++
                     break
 
+                case '--summary-failures':
+                    options.summaryFailures = true
+                    i++
+                    break
+
+                case '--shard':
+                    if (i + 1 < args.length) {
+                        options.shard = TestShards.parse(args[i + 1]!)
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a shard value (i/n)`)
+                    }
+                    break
+
+                case '--balance':
+                    options.balance = true
+                    i++
+                    break
+
+                case '--slowest':
+                    if (i + 1 < args.length) {
+                        const slowest = parseInt(args[i + 1]!, 10)
+                        if (isNaN(slowest) || slowest < 1) {
+                            throw new Error(`${arg} requires a positive number`)
+                        }
+                        options.slowest = slowest
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a number value`)
+                    }
+                    break
+
+                case '--max-failures':
+                    if (i + 1 < args.length) {
+                        const maxFailures = parseInt(args[i + 1]!, 10)
+                        if (isNaN(maxFailures) || maxFailures < 1) {
+                            throw new Error(`${arg} requires a positive number`)
+                        }
+                        options.maxFailures = maxFailures
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a number value`)
+                    }
+                    break
+
+                case '--max-output':
+                    if (i + 1 < args.length) {
+                        const maxOutput = parseByteSize(args[i + 1]!)
+                        if (maxOutput === null) {
+                            throw new Error(`${arg} requires a size in bytes (e.g., 10MB)`)
+                        }
+                        options.maxOutput = maxOutput
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a size value`)
+                    }
+                    break
+
+                case '--metrics-push':
+                    if (i + 1 < args.length) {
+                        options.metricsPush = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a Pushgateway URL`)
+                    }
+                    break
+
+                case '--matrix':
+                    if (i + 1 < args.length) {
+                        const match = args[i + 1]!.match(/^(\w+)=(.*)$/)
+                        if (!match) {
+                            throw new Error(`${arg} requires NAME=VALUE`)
+                        }
+                        options.matrix = {...options.matrix, [match[1]!]: match[2]!}
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires NAME=VALUE`)
+                    }
+                    break
+
                 case '--monitor':
                 case '-m':
                     options.live = true
@@ -234,17 +616,121 @@ export class CliParser {
                     }
                     break
 
+                case '--env':
+                    if (i + 1 < args.length) {
+                        const match = args[i + 1]!.match(/^(\w+)=(.*)$/)
+                        if (!match) {
+                            throw new Error(`${arg} requires KEY=VALUE`)
+                        }
+                        options.env = {...options.env, [match[1]!]: match[2]!}
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires KEY=VALUE`)
+                    }
+                    break
+
+                case '--set':
+                    if (i + 1 < args.length) {
+                        const match = args[i + 1]!.match(/^([A-Za-z_][\w-]*(?:\.[A-Za-z_][\w-]*)*)=(.*)$/s)
+                        if (!match) {
+                            throw new Error(`${arg} requires KEY.PATH=VALUE`)
+                        }
+                        const reserved = ['__proto__', 'constructor', 'prototype']
+                        if (match[1]!.split('.').some((key) => reserved.includes(key))) {
+                            throw new Error(`${arg} cannot set ${match[1]}`)
+                        }
+                        options.set = {...options.set, [match[1]!]: this.parseSetting(match[2]!)}
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires KEY.PATH=VALUE`)
+                    }
+                    break
+
+                case '--events':
+                    if (i + 1 < args.length) {
+                        options.events = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a destination (fd:N or file:PATH)`)
+                    }
+                    break
+
+                case '--logdir':
+                    if (i + 1 < args.length) {
+                        options.logdir = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a directory`)
+                    }
+                    break
+
+                case '--json':
+                    if (i + 1 < args.length) {
+                        options.json = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a file path`)
+                    }
+                    break
+
+                case '--report':
+                    if (i + 1 < args.length) {
+                        options.report = [...(options.report || []), args[i + 1]!]
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a report format (e.g., junit:results.xml)`)
+                    }
+                    break
+
+                case '--retries':
+                    if (i + 1 < args.length) {
+                        const retriesValue = parseInt(args[i + 1]!, 10)
+                        if (isNaN(retriesValue) || retriesValue < 0) {
+                            throw new Error(`${arg} requires a non-negative number`)
+                        }
+                        options.retries = retriesValue
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a number value`)
+                    }
+                    break
+
+                case '--remote':
+                    if (i + 1 < args.length) {
+                        options.remote = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a host (e.g., root@board)`)
+                    }
+                    break
+
+                case '--target':
+                    if (i + 1 < args.length) {
+                        options.target = args[i + 1]!
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a target triple (e.g., aarch64-linux-gnu)`)
+                    }
+                    break
+
+                case '--idle-timeout':
+                    if (i + 1 < args.length) {
+                        // Accepts a duration such as 60, 60s or 2m. Zero disables the idle timeout.
+                        options.idleTimeout = this.parseDuration(args[i + 1]!)
+                        i += 2
+                    } else {
+                        throw new Error(`${arg} requires a timeout value (e.g., 60s, 2m, 0 for none)`)
+                    }
+                    break
+
                 case '--timeout':
                 case '-t':
                     if (i + 1 < args.length) {
-                        const timeoutValue = parseInt(args[i + 1]!, 10)
-                        if (isNaN(timeoutValue) || timeoutValue < 1) {
-                            throw new Error(`${arg} requires a positive number`)
-                        }
-                        options.timeout = timeoutValue
+                        // Accepts a duration such as 30, 30s, 500ms or 2m. Zero disables the timeout.
+                        options.timeout = this.parseDuration(args[i + 1]!)
                         i += 2
                     } else {
-                        throw new Error(`${arg} requires a timeout value in seconds`)
+                        throw new Error(`${arg} requires a timeout value (e.g., 30s, 2m, 0 for none)`)
                     }
                     break
 
@@ -263,16 +749,29 @@ export class CliParser {
     }
 
     /*
-     Parses a duration value with optional suffix (secs/mins/hours/days)
-     @param value Duration string (e.g., "30", "5mins", "2hours", "3days")
+     Parses a --set value as JSON, falling back to the text itself
+     @param value Text after the first '=' (e.g., "60", "true", '["-O2"]' or "gcc")
+     @returns Parsed JSON value, or the text if it is not valid JSON
+     */
+    private static parseSetting(value: string): unknown {
+        try {
+            return JSON.parse(value)
+        } catch {
+            return value
+        }
+    }
+
+    /*
+     Parses a duration value with optional suffix (ms/secs/mins/hours/days)
+     @param value Duration string (e.g., "30", "30s", "500ms", "5mins", "2h", "3days")
      @returns Duration in seconds
      @throws Error if value is invalid
      */
     private static parseDuration(value: string): number {
-        const match = value.match(/^(\d+(?:\.\d+)?)\s*(secs?|mins?|hours?|hrs?|days?)?$/i)
+        const match = value.match(/^(\d+(?:\.\d+)?)\s*(ms|s|secs?|m|mins?|h|hours?|hrs?|d|days?)?$/i)
         if (!match) {
             throw new Error(
-                `Invalid duration format: "${value}". Expected format: <count>[secs|mins|hours|hrs|days]`
+                `Invalid duration format: "${value}". Expected format: <count>[ms|secs|mins|hours|hrs|days]`
             )
         }
 
@@ -284,17 +783,19 @@ export class CliParser {
         }
 
         // Convert to seconds based on suffix
-        if (!suffix || suffix === 'sec' || suffix === 'secs') {
+        if (suffix === 'ms') {
+            return count / 1000
+        } else if (!suffix || suffix === 's' || suffix === 'sec' || suffix === 'secs') {
             return count
-        } else if (suffix === 'min' || suffix === 'mins') {
+        } else if (suffix === 'm' || suffix === 'min' || suffix === 'mins') {
             return count * 60
-        } else if (suffix === 'hour' || suffix === 'hours' || suffix === 'hr' || suffix === 'hrs') {
+        } else if (suffix === 'h' || suffix === 'hour' || suffix === 'hours' || suffix === 'hr' || suffix === 'hrs') {
             return count * 3600
-        } else if (suffix === 'day' || suffix === 'days') {
+        } else if (suffix === 'd' || suffix === 'day' || suffix === 'days') {
             return count * 86400
         }
 
-        throw new Error(`Unknown duration suffix: "${suffix}". Use secs, mins, hours, hrs, or days`)
+        throw new Error(`Unknown duration suffix: "${suffix}". Use ms, secs, mins, hours, hrs, or days`)
     }
 
     /*
@@ -319,42 +820,114 @@ ARGUMENTS:
                   - Path patterns: "**/math*", "tests/*.tst.c"
 
 OPTIONS:
+        --accept             Rewrite .expected files with the current test output
+        --asan               Build C and Go tests with AddressSanitizer and report sanitizer aborts
+        --backtrace          Capture a gdb or lldb backtrace when a C test crashes with a signal
+        --balance            With --shard, balance shards by recorded test durations (.testme/timings.json)
+        --bench              Compare TESTME-BENCH timings with .bench baselines, failing on regressions
+        --bench-update       Record TESTME-BENCH timings as the new .bench baselines
+        --build-workers <N>  Compile C, Go and Rust tests with N workers ahead of running them with --workers
+        --cc <COMPILER>      Build C tests with COMPILER (name or path), overriding compiler.cc and $CC
         --chdir <DIR>        Change to directory before running tests
+        --check-config       Validate every testme.json5 in the tree and exit (non-zero on any problem)
         --class <STRING>     Set TESTME_CLASS environment variable for tests
-        --clean              Clean all .testme artifact directories and exit
+        --clean              Remove .testme artifact directories and leftover temporary directories, and exit
+        --clean-dry-run      List the directories --clean would remove and exit
+        --color <WHEN>       Color output: auto (terminal without NO_COLOR, default), always or never
+        --completion <SHELL> Print a completion script for SHELL (bash, zsh or fish) and exit
     -c, --config <FILE>      Use specific configuration file
         --continue           Continue running tests even if some fail, always exit with 0
-    -d, --debug              Launch debugger (GDB on Linux, Xcode on macOS)
+        --coverage           Collect C and Go coverage into coverage.info and coverage.out
+        --coverage-threshold <PERCENT>
+                             Fail the run if total coverage is below PERCENT (implies --coverage)
+    -d, --debug              Debug a single test with its environment and working directory (see debug config)
         --depth <NUMBER>     Run tests with depth requirement <= NUMBER (default: 0)
+        --docker <IMAGE>     Run each test inside IMAGE with docker run, mounting the test directory
+        --doctor             Check the tools and include/library directories the selected tests need and exit
+        --dry-run            Print compile, run and service commands with their environment without running them
         --duration <COUNT>   Set duration count with optional suffix (secs/mins/hrs/hours/days)
                              Exports TESTME_DURATION in seconds to tests and scripts
                              Examples: --duration 30, --duration 5mins, --duration 2hrs, --duration 3days
+        --emit-header <LANG> Print the assertion header or result helper for LANG (c, go or sh) and exit
+        --env <KEY=VALUE>    Set an environment variable for tests, overriding the config and .env (repeatable)
+        --events <DEST>      Stream NDJSON events to DEST (fd:N or file:PATH)
+        --exclude <REGEX>    Skip tests whose path relative to the test root matches REGEX
+        --fail-fast          Abort on the first failure, killing tests still running
+        --failed             Run only the tests that failed in the last run
+        --filter <REGEX>     Run only tests whose path relative to the test root matches REGEX
+        --flaky-report       List tests whose recent runs alternate between passing and failing, and exit
+        --force              With --init, overwrite an existing testme.json5
     -h, --help               Show this help message
+        --idle-timeout <TIME>
+                             Fail a test that produces no output for TIME, e.g. 60s (0 for none)
+        --ignore-unknown-keys
+                             Do not warn about unknown configuration keys
     -i, --iterations <N>     Set iteration count (exports TESTME_ITERATIONS for tests to use, TestMe does not repeat execution)
-        --init               Create testme.json5 configuration file in current directory
+        --init               Create testme.json5 for the test languages found under the current directory
+        --json <FILE>        Write structured JSON results to FILE (same as --report json:FILE)
     -k, --keep               Keep .testme artifacts (default; use --clean to remove)
-    -l, --list               List discovered tests without running them
+        --keep-on-fail       Remove the artifacts and temporary directories of passing tests, keep failing ones
+        --keep-tmp           Keep each test's temporary directory (TESTME_TMP) after it runs
+        --keep-tmp-on-fail   Keep the temporary directories of tests that did not pass
+    -l, --list               List discovered tests without running them, one path per line
+        --list-json          List discovered tests as JSON with language and resolved timeout
+        --list-suites        List the suites defined by the suite key and directive with their test counts
+        --logdir <DIR>       Write each test's output, exit code, duration and command to DIR/<test>.log
+        --matrix <NAME=VALUE>
+                             Run only the matrix cells where NAME is VALUE (repeatable)
+        --max-failures <N>   Stop starting new tests once N tests have failed
+        --max-output <SIZE>  Keep at most SIZE bytes of each test's output (e.g., 10MB)
+        --metrics-push <URL> Push run metrics to the Prometheus Pushgateway at URL
     -m, --monitor            Stream test output in real-time to console (requires TTY)
+        --no-github          Do not write GitHub Actions annotations when GITHUB_ACTIONS is true
+        --no-quarantine      Treat tests in the quarantine file as normal tests, failing the run
+        --not-tags <TAGS>    Skip tests with any of these comma-separated tags (repeatable)
     -n, --no-services        Skip all service commands (skip, prep, setup, cleanup)
         --new <NAME>         Create new test file from template (e.g., --new math.c)
     -p, --profile <NAME>     Set build profile (overrides config and env.PROFILE)
+        --progress           Show one updating line of completed/total, pass and fail counts and elapsed time
     -q, --quiet              Run silently with no output, only exit codes
-    -R, --rebuild            Force recompilation of C tests (default: skip if binary is newer)
+    -R, --rebuild            Force recompilation of C tests (default: reuse if source, flags and headers unchanged)
+        --remote <HOST>      Run compiled C, Go and Rust tests on HOST (e.g., root@board) over ssh
+        --report <SPEC>      Write a machine-readable report, SPEC is FORMAT[:FILE] (repeatable)
+                             Formats: junit (default file: junit.xml), tap (default: stdout),
+                             json (default file: results.json), markdown (default file: summary.md),
+                             github (workflow commands), console (human output on stdout)
+        --retries <N>        Re-run failing tests up to N times, passing if any attempt succeeds
+        --seed <N>           Shuffle test order using seed N to reproduce a previous order
+        --set <KEY=VALUE>    Set a dotted config key for this run, e.g. execution.timeout=60 (repeatable)
+                             VALUE is parsed as JSON if possible, otherwise used as a string
+        --shard <I/N>        Run only shard I of N, partitioning tests by a hash of their path
+        --shell <SHELL>      Run shell tests with SHELL (name or path), overriding shebangs and shell.interpreter
     -s, --show               Display test configuration and environment variables
-        --step               Run tests one at a time with prompts (forces serial mode)
+        --show-config <DIR>  Print the merged configuration that applies to tests in DIR and exit
+        --shuffle            Run tests in a random order and print the seed used
+        --since <REF>        Run only tests affected by files changed since git REF
+        --slowest <N>        List the N slowest tests with their durations after the run
+        --step               Run tests one at a time, pausing after each result (forces serial mode)
         --stop               Stop immediately when a test fails (fast-fail mode)
-    -t, --timeout <SECONDS>  Set test timeout in seconds (overrides config)
+        --strict             Fail tests whose required tools (testme: requires) are missing instead of skipping
+        --suite <NAME>       Run only tests in suite NAME, or in any of a comma-separated list (repeatable)
+        --summary-failures   With --verbose, repeat failed tests grouped by directory after all results
+        --tags <EXPR>        Run only tests with these tags: a+b for all, a,b for any (repeatable)
+        --target <TRIPLE>    Cross-compile C and Go tests for TRIPLE, running them via target.runner if set
+    -t, --timeout <TIME>     Set per-test timeout, e.g. 30s or 2m (0 for none, overrides config)
+        --valgrind           Run C tests under valgrind and fail tests with memory errors or leaks
     -v, --verbose            Enable verbose mode with detailed output and TESTME_VERBOSE
+        --verbose-on-fail    Set TESTME_VERBOSE but print output only for tests that fail
     -V, --version            Show version information
     -w, --warning            Show compiler warnings and compile command line for C tests
-    -W, --workers <NUMBER>   Number of parallel workers (overrides config)
+        --watch              Re-run affected tests when files change (Ctrl+C to exit)
+    -W, --workers <NUMBER>   Number of parallel workers (default: number of CPUs)
 
 EXAMPLES:
     # Getting Started
     tm --init                  # Create testme.json5 configuration file
+    tm --init --force          # Replace testme.json5 with a fresh starter configuration
     tm --new math.c            # Create math.tst.c from template
     tm --new api.js            # Create api.tst.js from template
     tm --new test.sh           # Create test.tst.sh from template
+    tm --emit-header c         # Print the C assertion header (redirect to testme.h)
 
     # Running Tests
     tm                         # Run all tests in current directory tree
@@ -363,18 +936,59 @@ EXAMPLES:
     tm "math.tst.c"            # Run specific test file
     tm "**/math*"              # Run tests with 'math' in their name
     tm --list                  # List all discoverable tests
+    tm --list-json --filter io # List matching tests as JSON
+    tm --suite integration     # Run only the tests in the integration suite
+    tm --tags slow+network     # Run only tests tagged both slow and network
+    tm --show-config test/unit # Show the merged configuration for test/unit
+    tm --check-config          # Validate all configuration files
+    tm --doctor                # Check compilers and runtimes before a long run
+    tm --completion bash       # Print the bash completion script (see README for installing)
     tm --clean                 # Clean all test artifacts
+    tm --clean-dry-run         # List the artifacts --clean would remove
     tm -v "integration*"       # Run integration tests with verbose output
     tm --keep "*.tst.c"        # Run C tests and keep build artifacts
     tm --step                  # Run tests one at a time with prompts
     tm --stop                  # Stop immediately when first test fails
+    tm --strict                # Fail, rather than skip, tests whose required tools are missing
+    tm --fail-fast             # Abort on the first failure and kill running tests
+    tm --max-failures 5        # Stop once 5 tests have failed
+    tm --matrix MODE=fast      # Run only the MODE=fast matrix cells
+    tm --env LOG_LEVEL=debug   # Run tests with LOG_LEVEL=debug in their environment
+    tm --set 'compiler.c.flags=["-O2"]'  # Build C tests with -O2 for this run
+    tm --timeout 2m            # Kill and report tests running longer than 2 minutes
+    tm --retries 2             # Re-run failing tests up to twice and report flaky tests
+    tm --slowest 10            # List the 10 slowest tests after the run
+    tm --shard 2/4             # Run the second quarter of the tests on this CI machine
+    tm --shard 2/4 --balance   # Split into shards with similar run times
+    tm --shuffle               # Run tests in random order to find hidden dependencies
+    tm --seed 1234             # Repeat the order from a previous --shuffle run
+    tm --since origin/main     # Run tests affected by changes on this branch
+    tm --failed                # Re-run the tests that failed last time
+    tm --filter '^unit/'       # Run only tests under the unit directory
+    tm --exclude 'slow|stress' # Skip tests with slow or stress in their path
+    tm --accept "cli*"         # Update cli*.expected files with the current output
+    tm --watch                 # Re-run affected tests as files are edited
+    tm --valgrind "*.tst.c"    # Check C tests for memory errors and leaks
+    tm --coverage "*.tst.go"   # Write Go coverage to coverage.out and print the total
+    tm --coverage-threshold 80 # Fail the run if coverage is below 80%
+    tm --asan -v "*.tst.c"     # Build C tests with AddressSanitizer
+    tm --cc clang "*.tst.c"    # Build C tests with clang regardless of config and $CC
+    tm --shell bash "*.tst.sh" # Run shell tests under bash whatever their shebang
+    tm --remote root@board     # Run compiled tests on the board over ssh
+    tm --docker gcc:13 math    # Run math.tst.c inside the gcc:13 image
     tm --depth 5               # Run tests with depth requirement <= 5
     tm --debug math            # Debug math.tst.c with GDB/Xcode
+    tm --dry-run math.tst.c    # Show the compile and run commands for math.tst.c
     tm -s "*.tst.c"            # Display test configuration and environment
     tm -w "*.tst.c"            # Show compiler warnings and compile command
     tm -W 8                    # Use 8 parallel workers (overrides config)
+    tm --build-workers 16 -W 4 # Compile 16 tests at a time, run 4 at a time as builds finish
     tm --quiet                 # Run silently with no output, only exit codes
     tm -n                      # Run tests without any service commands (run services externally)
+    tm --report junit          # Write JUnit XML results to junit.xml for CI
+    tm --report tap | prove -  # Pipe a TAP version 13 stream to a TAP consumer
+    tm --json results.json     # Write structured JSON results for dashboards
+    tm --events fd:3 3>ev.json # Stream NDJSON events to file descriptor 3
 
 SUPPORTED TEST TYPES:
     *.tst.sh    Shell script tests (bash/zsh/fish)
@@ -384,6 +998,7 @@ SUPPORTED TEST TYPES:
     *.tst.c     C program tests (compiled with gcc/clang/MSVC)
     *.tst.js    JavaScript tests (run with Bun)
     *.tst.ts    TypeScript tests (run with Bun)
+    *.tst.rs    Rust tests (compiled with rustc)
     *.tst.es    Ejscript tests (run with ejs)
 
 CONFIGURATION:
@@ -415,10 +1030,47 @@ For more information, see the project documentation.
             return // Help option is always valid, skip other validations
         }
 
-        if (options.clean && options.list) {
+        if ((options.clean || options.cleanDryRun) && options.list) {
             throw new Error('Cannot use --clean and --list together')
         }
 
+        if (options.asan && (options.valgrind || options.debug)) {
+            throw new Error('Cannot use --asan with --valgrind or --debug')
+        }
+
+        if (options.dryRun && (options.clean || options.list || options.step || options.debug)) {
+            throw new Error('Cannot use --dry-run with --clean, --list, --step or --debug')
+        }
+
+        if (options.watch && (options.clean || options.list || options.step || options.debug)) {
+            throw new Error('Cannot use --watch with --clean, --list, --step or --debug')
+        }
+
+        if (options.force && !options.init) {
+            throw new Error('--force requires --init')
+        }
+
+        if (options.balance && !options.shard) {
+            throw new Error('--balance requires --shard')
+        }
+
+        if (options.keepOnFail && options.keep) {
+            throw new Error('--keep-on-fail cannot be used with --keep')
+        }
+
+        for (const [flag, value] of [
+            ['--filter', options.filter],
+            ['--exclude', options.exclude],
+        ]) {
+            if (value !== undefined) {
+                try {
+                    new RegExp(value)
+                } catch (err) {
+                    throw new Error(`Invalid ${flag} regular expression: ${(err as Error).message}`)
+                }
+            }
+        }
+
         // Validate test patterns
         for (const pattern of options.patterns) {
             if (!pattern.trim()) {
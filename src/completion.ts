@@ -0,0 +1,256 @@
+import {CliParser} from './cli.ts'
+import {REPORT_FORMATS} from './reporters/index.ts'
+import {HEADER_LANGUAGES} from './headers.ts'
+
+/*
+ Shells that completion scripts can be generated for
+ */
+export const COMPLETION_SHELLS = ['bash', 'zsh', 'fish']
+
+/*
+ A command line option parsed from the help text
+ */
+export type CompletionOption = {
+    long: string // Long flag (e.g., '--config')
+    short?: string // Short flag (e.g., '-c')
+    value?: string // Value placeholder (e.g., 'FILE'), undefined for switches
+    description: string
+    repeatable: boolean // Option may be given more than once
+}
+
+/*
+ What to complete for an option value: files, directories, discovered tests or a fixed list of words
+ */
+type ValueKind = 'file' | 'dir' | 'test' | 'none' | string[]
+
+/*
+ Command that lists discovered tests, one path per line, for completing test patterns and --filter values
+ */
+const LIST_TESTS = "tm --list 2>/dev/null | grep '\\.tst\\.'"
+
+/*
+ Completion - Shell completion scripts for --completion
+
+ Builds bash, zsh and fish completion scripts from the OPTIONS section of the help text so that new
+ options are completed without further changes. Values are completed by kind: --report offers the
+ report formats, --completion the shells, --emit-header the header languages, --config and --json files,
+ --chdir and --show-config directories, and --filter, --exclude and test patterns the tests found by
+ "tm --list" at the time of completion.
+ */
+export class Completion {
+    /*
+     Renders the completion script for a shell
+     @param shell Shell name (bash, zsh or fish)
+     @returns Completion script text
+     @throws Error if the shell is not supported
+     */
+    static render(shell: string): string {
+        const options = this.getOptions()
+        switch (shell) {
+            case 'bash':
+                return this.renderBash(options)
+            case 'zsh':
+                return this.renderZsh(options)
+            case 'fish':
+                return this.renderFish(options)
+            default:
+                throw new Error(`Unsupported shell: "${shell}". Supported shells: ${COMPLETION_SHELLS.join(', ')}`)
+        }
+    }
+
+    /*
+     Parses the options listed in the help text
+     @returns Options in help text order
+     */
+    static getOptions(): CompletionOption[] {
+        const lines = CliParser.getUsage().split('\n')
+        const start = lines.indexOf('OPTIONS:') + 1
+        const options: CompletionOption[] = []
+        for (let i = start; i < lines.length && lines[i]!.trim(); i++) {
+            const match = lines[i]!.match(/^ {4}(?:(-\w), | {4})(--[\w-]+)(?: <([^>]+)>)?\s*(.*)$/)
+            if (!match) {
+                continue
+            }
+            // Options with long value placeholders have their description on the next line
+            const description = match[4] || lines[i + 1]!.trim()
+            options.push({
+                long: match[2]!,
+                short: match[1],
+                value: match[3],
+                description,
+                repeatable: description.includes('(repeatable)'),
+            })
+        }
+        return options
+    }
+
+    /*
+     Gets what to complete for an option's value
+     @param option Option taking a value
+     @returns Value kind
+     */
+    private static getValueKind(option: CompletionOption): ValueKind {
+        switch (option.long) {
+            case '--report':
+                return REPORT_FORMATS
+            case '--completion':
+                return COMPLETION_SHELLS
+            case '--emit-header':
+                return HEADER_LANGUAGES
+            case '--filter':
+            case '--exclude':
+                return 'test'
+        }
+        switch (option.value) {
+            case 'FILE':
+                return 'file'
+            case 'DIR':
+                return 'dir'
+            default:
+                return 'none'
+        }
+    }
+
+    /*
+     Renders the bash completion script
+     @param options Options to complete
+     @returns Script for "complete -F"
+     */
+    private static renderBash(options: CompletionOption[]): string {
+        const flags = options.flatMap((option) => (option.short ? [option.short, option.long] : [option.long]))
+        const cases = new Map<string, string[]>()
+        for (const option of options.filter((option) => option.value)) {
+            const kind = this.getValueKind(option)
+            const action =
+                kind === 'file'
+                    ? 'compopt -o filenames 2>/dev/null; COMPREPLY=($(compgen -f -- "$cur"))'
+                    : kind === 'dir'
+                      ? 'compopt -o filenames 2>/dev/null; COMPREPLY=($(compgen -d -- "$cur"))'
+                      : kind === 'test'
+                        ? 'COMPREPLY=($(compgen -W "$(_tm_tests)" -- "$cur"))'
+                        : kind === 'none'
+                          ? 'COMPREPLY=()'
+                          : `COMPREPLY=($(compgen -W "${kind.join(' ')}" -- "$cur"))`
+            const names = option.short ? [option.short, option.long] : [option.long]
+            cases.set(action, [...(cases.get(action) || []), ...names])
+        }
+        const patterns = [...cases]
+            .map(([action, names]) => [`        ${names.join('|')})`, action, 'return', ';;'].join('\n            '))
+            .join('\n')
+        return `# tm bash completion
+# Install: tm --completion bash > ~/.local/share/bash-completion/completions/tm
+#      or: echo 'source <(tm --completion bash)' >> ~/.bashrc
+
+_tm_tests() {
+    ${LIST_TESTS}
+}
+
+_tm() {
+    local cur="\${COMP_WORDS[COMP_CWORD]}"
+    local prev="\${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+${patterns}
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "${flags.join(' ')}" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W "$(_tm_tests)" -- "$cur"))
+    fi
+}
+
+complete -F _tm tm
+`
+    }
+
+    /*
+     Renders the zsh completion script
+     @param options Options to complete
+     @returns Script usable from fpath or by sourcing
+     */
+    private static renderZsh(options: CompletionOption[]): string {
+        const specs = options.map((option) => {
+            const description = option.description.replace(/'/g, "'\\''").replace(/([[\]])/g, '\\$1')
+            let spec = `[${description}]`
+            if (option.value) {
+                const kind = this.getValueKind(option)
+                const action =
+                    kind === 'file'
+                        ? '_files'
+                        : kind === 'dir'
+                          ? '_files -/'
+                          : kind === 'test'
+                            ? '_tm_tests'
+                            : kind === 'none'
+                              ? ' '
+                              : `(${kind.join(' ')})`
+                spec += `:${option.value.toLowerCase()}:${action}`
+            }
+            if (option.short) {
+                // Short and long forms exclude each other
+                return `        '(${option.short} ${option.long})'{${option.short},${option.long}}'${spec}' \\\n`
+            }
+            return `        '${option.repeatable ? '*' : ''}${option.long}${spec}' \\\n`
+        })
+        return `#compdef tm
+# tm zsh completion
+# Install: tm --completion zsh > "\${fpath[1]}/_tm" and restart zsh
+#      or: echo 'source <(tm --completion zsh)' >> ~/.zshrc (after compinit)
+
+_tm_tests() {
+    local -a tests
+    tests=(\${(f)"$(${LIST_TESTS})"})
+    compadd -a tests
+}
+
+_tm() {
+    _arguments -s \\
+${specs.join('')}        '*:test:_tm_tests'
+}
+
+if [ "$funcstack[1]" = "_tm" ]; then
+    _tm "$@"
+else
+    compdef _tm tm
+fi
+`
+    }
+
+    /*
+     Renders the fish completion script
+     @param options Options to complete
+     @returns Script of "complete" commands
+     */
+    private static renderFish(options: CompletionOption[]): string {
+        const lines = options.map((option) => {
+            const description = option.description.replace(/\\/g, '\\\\').replace(/'/g, "\\'")
+            let line = 'complete -c tm'
+            if (option.short) {
+                line += ` -s ${option.short.slice(1)}`
+            }
+            line += ` -l ${option.long.slice(2)}`
+            if (option.value) {
+                const kind = this.getValueKind(option)
+                line +=
+                    kind === 'file'
+                        ? ' -r -F'
+                        : kind === 'dir'
+                          ? " -x -a '(__fish_complete_directories)'"
+                          : kind === 'test'
+                            ? " -x -a '(__tm_tests)'"
+                            : kind === 'none'
+                              ? ' -x'
+                              : ` -x -a '${kind.join(' ')}'`
+            }
+            return `${line} -d '${description}'\n`
+        })
+        return `# tm fish completion
+# Install: tm --completion fish > ~/.config/fish/completions/tm.fish
+
+function __tm_tests
+    tm --list 2>/dev/null | string match '*.tst.*'
+end
+
+complete -c tm -f -a '(__tm_tests)'
+${lines.join('')}`
+    }
+}
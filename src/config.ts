@@ -1,8 +1,11 @@
 import type {TestConfig} from './types.ts'
 import {join, dirname, resolve} from 'path'
 import {readdir, stat} from 'fs/promises'
+import {availableParallelism} from 'os'
 import JSON5 from 'json5'
 import {ErrorMessages} from './utils/error-messages.ts'
+import {ConfigSchema} from './schema.ts'
+import type {ConfigIssue} from './schema.ts'
 
 /*
  ConfigManager - Hierarchical configuration management
@@ -78,6 +81,18 @@ export class ConfigManager {
      */
     private static configCache = new Map<string, TestConfig>()
 
+    /**
+     * Config files already validated, so problems are reported once per file
+     * @internal
+     */
+    private static validatedFiles = new Set<string>()
+
+    /**
+     * Whether unknown configuration keys are reported (disabled by --ignore-unknown-keys)
+     * @internal
+     */
+    private static unknownKeyWarnings = true
+
     /**
      * Default configuration values used as fallback
      * @internal
@@ -96,7 +111,7 @@ export class ConfigManager {
         execution: {
             timeout: 30, // 30 seconds
             parallel: true,
-            workers: 4,
+            workers: availableParallelism(),
         },
         output: {
             verbose: false,
@@ -104,7 +119,7 @@ export class ConfigManager {
             colors: true,
         },
         patterns: {
-            include: ['**/*.tst.c', '**/*.tst.js', '**/*.tst.ts', '**/*.tst.py', '**/*.tst.go', '**/*.tst.es'],
+            include: ['**/*.tst.c', '**/*.tst.js', '**/*.tst.ts', '**/*.tst.py', '**/*.tst.go', '**/*.tst.rs', '**/*.tst.es'],
             exclude: ['**/node_modules/**', '**/.testme/**', '**/.*/**'],
             windows: {
                 include: ['**/*.tst.sh', '**/*.tst.ps1', '**/*.tst.bat', '**/*.tst.cmd'],
@@ -166,11 +181,181 @@ export class ConfigManager {
         return result
     }
 
+    /**
+     * Gets the configuration files that contribute to the configuration of a directory
+     *
+     * @param startDir - Directory to get the configuration for
+     * @returns Config file paths with their inherit setting, nearest (highest precedence) first
+     *
+     * @remarks
+     * Starts with the nearest testme.json5 and follows inherit to ancestor config files.
+     * Returns an empty list if no config file is found.
+     */
+    static async getConfigChain(startDir: string): Promise<{path: string; inherit?: boolean | string[]}[]> {
+        const chain: {path: string; inherit?: boolean | string[]}[] = []
+        let {config, configDir} = await this.findConfigFile(startDir)
+        while (config && configDir) {
+            chain.push({path: join(configDir, this.CONFIG_FILENAME), inherit: config.inherit})
+            const parentDir = dirname(configDir)
+            if (!config.inherit || parentDir === configDir) {
+                break
+            }
+            ;({config, configDir} = await this.findConfigFile(parentDir))
+        }
+        return chain
+    }
+
+    /**
+     * Discards cached configurations so edited config files are re-read
+     *
+     * @remarks
+     * Used by watch mode when a testme.json5 file changes between runs.
+     */
+    static clearCache(): void {
+        this.configCache.clear()
+        this.validatedFiles.clear()
+    }
+
+    /**
+     * Enables or disables warnings for unknown configuration keys
+     *
+     * @param enabled - False to ignore unknown keys (e.g., keys for newer TestMe versions)
+     */
+    static setUnknownKeyWarnings(enabled: boolean): void {
+        this.unknownKeyWarnings = enabled
+    }
+
+    /**
+     * Validates a parsed configuration file against the configuration schema
+     *
+     * @param config - Parsed configuration file contents
+     * @returns Problems found, without unknown key warnings if they are disabled
+     */
+    static validateConfig(config: unknown): ConfigIssue[] {
+        const issues = ConfigSchema.validate(config)
+        return this.unknownKeyWarnings ? issues : issues.filter((issue) => issue.severity === 'error')
+    }
+
+    /**
+     * Validates --set overrides against the configuration schema
+     *
+     * @param settings - Values keyed by dotted configuration key
+     * @throws Error listing the errors if a value has the wrong type or is malformed
+     *
+     * @remarks
+     * Unknown keys are printed as warnings, as they are for configuration files.
+     */
+    static checkSettings(settings: Record<string, unknown>): void {
+        const issues = this.validateConfig(this.applySettings({}, settings))
+        const warnings = issues.filter((issue) => issue.severity === 'warning')
+        const errors = issues.filter((issue) => issue.severity === 'error')
+        if (warnings.length > 0) {
+            console.warn(ConfigSchema.format('--set', warnings))
+        }
+        if (errors.length > 0) {
+            throw new Error(`Invalid configuration:\n${ConfigSchema.format('--set', errors)}`)
+        }
+    }
+
+    /**
+     * Applies --set overrides to a configuration
+     *
+     * @param config - Merged configuration
+     * @param settings - Values keyed by dotted configuration key, applied in order
+     * @returns Copy of the configuration with each key set
+     *
+     * @remarks
+     * Missing or non-object intermediate keys are replaced by objects. Values are used as given: unlike
+     * values in configuration files, they are not expanded or resolved relative to a directory.
+     */
+    static applySettings<T extends object>(config: T, settings: Record<string, unknown> = {}): T {
+        const result: any = {...config}
+        for (const [key, value] of Object.entries(settings)) {
+            const parts = key.split('.')
+            let target = result
+            for (const part of parts.slice(0, -1)) {
+                const child = target[part]
+                target[part] = child && typeof child === 'object' && !Array.isArray(child) ? {...child} : {}
+                target = target[part]
+            }
+            target[parts[parts.length - 1]!] = value
+        }
+        return result
+    }
+
+    /**
+     * Validates every testme.json5 file in a directory tree
+     *
+     * @param rootDir - Directory to search
+     * @returns Each config file path with its problems (including syntax errors), sorted by path
+     *
+     * @remarks
+     * Skips node_modules and hidden directories such as .testme and .git.
+     */
+    static async checkConfigFiles(rootDir: string): Promise<{path: string; issues: ConfigIssue[]}[]> {
+        const results: {path: string; issues: ConfigIssue[]}[] = []
+        const visit = async (dir: string): Promise<void> => {
+            let entries
+            try {
+                entries = await readdir(dir, {withFileTypes: true})
+            } catch {
+                return
+            }
+            for (const entry of entries) {
+                const path = join(dir, entry.name)
+                if (entry.isDirectory()) {
+                    if (!entry.name.startsWith('.') && entry.name !== 'node_modules') {
+                        await visit(path)
+                    }
+                } else if (entry.name === this.CONFIG_FILENAME) {
+                    try {
+                        const config = JSON5.parse(await Bun.file(path).text())
+                        results.push({path, issues: this.validateConfig(config)})
+                    } catch (error) {
+                        const message = error instanceof Error ? error.message : String(error)
+                        results.push({path, issues: [{path: '', message, severity: 'error'}]})
+                    }
+                }
+            }
+        }
+        await visit(rootDir)
+        return results.sort((a, b) => a.path.localeCompare(b.path))
+    }
+
+    /**
+     * Reports problems in a configuration file the first time it is loaded
+     *
+     * @param configPath - Path of the configuration file
+     * @param config - Parsed configuration file contents
+     * @throws Error listing the errors if the configuration has wrong types or malformed values
+     *
+     * @internal
+     * @remarks
+     * Unknown keys are printed as warnings and do not stop the run.
+     */
+    private static reportConfigIssues(configPath: string, config: unknown): void {
+        if (this.validatedFiles.has(configPath)) {
+            return
+        }
+        this.validatedFiles.add(configPath)
+        const issues = this.validateConfig(config)
+        const warnings = issues.filter((issue) => issue.severity === 'warning')
+        const errors = issues.filter((issue) => issue.severity === 'error')
+        if (warnings.length > 0) {
+            console.warn(ConfigSchema.format(configPath, warnings))
+        }
+        if (errors.length > 0) {
+            this.validatedFiles.delete(configPath)
+            throw new Error(`Invalid configuration:\n${ConfigSchema.format(configPath, errors)}`)
+        }
+    }
+
     /**
      * Searches for configuration file by walking up directory tree
      *
      * @param startDir - Directory to start searching from
      * @returns Object with parsed configuration and config directory path
+     * @throws Error if the configuration file found has invalid values
      *
      * @remarks
      * Returns null for both config and configDir if no configuration file is found.
@@ -184,17 +369,21 @@ export class ConfigManager {
         while (true) {
             const configPath = join(currentDir, this.CONFIG_FILENAME)
 
+            let config: Partial<TestConfig> | undefined
             try {
                 const file = Bun.file(configPath)
                 if (await file.exists()) {
                     const configText = await file.text()
-                    const config = JSON5.parse(configText) as Partial<TestConfig>
-                    return {config, configDir: currentDir}
+                    config = JSON5.parse(configText) as Partial<TestConfig>
                 }
             } catch (error) {
                 console.error(ErrorMessages.configFileError(configPath, error))
                 // Continue searching in parent directories
             }
+            if (config) {
+                this.reportConfigIssues(configPath, config)
+                return {config, configDir: currentDir}
+            }
 
             const parentDir = dirname(currentDir)
             if (parentDir === currentDir) {
@@ -392,6 +581,10 @@ export class ConfigManager {
      * Resolves relative paths in compiler flags
      */
     private static resolvePathsInFlags(flags: string[], configDir: string): string[] {
+        // Resolve the values of a {values, append} list, keeping its append marker for inheritance merging
+        if (this.isListMarker(flags)) {
+            return {...flags, values: this.resolvePathsInFlags(flags.values as string[], configDir)} as any
+        }
         return flags.map((flag) => {
             // -I../include → -I/absolute/path/include
             if (flag.startsWith('-I') && flag.length > 2) {
@@ -637,7 +830,25 @@ export class ConfigManager {
         // Determine which keys to inherit
         const keysToInherit: string[] =
             childConfig.inherit === true
-                ? ['compiler', 'debug', 'execution', 'output', 'patterns', 'services', 'environment', 'env', 'profile']
+                ? [
+                      'compiler',
+                      'debug',
+                      'valgrind',
+                      'coverage',
+                      'golden',
+                      'redact',
+                      'parse',
+                      'shell',
+                      'handlers',
+                      'suite',
+                      'execution',
+                      'output',
+                      'patterns',
+                      'services',
+                      'environment',
+                      'env',
+                      'profile',
+                  ]
                 : Array.isArray(childConfig.inherit)
                   ? childConfig.inherit
                   : []
@@ -650,6 +861,27 @@ export class ConfigManager {
                 inherited.compiler = this.deepMerge(parentConfig.compiler, childConfig.compiler || {})
             } else if (key === 'debug' && parentConfig.debug) {
                 inherited.debug = this.deepMerge(parentConfig.debug, childConfig.debug || {})
+            } else if (key === 'valgrind' && parentConfig.valgrind) {
+                inherited.valgrind = {...parentConfig.valgrind, ...childConfig.valgrind}
+            } else if (key === 'coverage' && parentConfig.coverage) {
+                inherited.coverage = {...parentConfig.coverage, ...childConfig.coverage}
+            } else if (key === 'golden' && parentConfig.golden) {
+                inherited.golden = {...parentConfig.golden, ...childConfig.golden}
+            } else if (key === 'parse' && parentConfig.parse) {
+                inherited.parse = {...parentConfig.parse, ...childConfig.parse}
+            } else if (key === 'redact' && parentConfig.redact) {
+                // Secrets accumulate, so a child config cannot unmask its parent's secrets
+                const env = [...(parentConfig.redact.env || []), ...(childConfig.redact?.env || [])]
+                const patterns = [...(parentConfig.redact.patterns || []), ...(childConfig.redact?.patterns || [])]
+                inherited.redact = {env, patterns}
+            } else if (key === 'shell' && parentConfig.shell) {
+                inherited.shell = {...parentConfig.shell, ...childConfig.shell}
+            } else if (key === 'handlers' && parentConfig.handlers) {
+                inherited.handlers = {...parentConfig.handlers, ...childConfig.handlers}
+            } else if (key === 'suite' && parentConfig.suite) {
+                // Suites accumulate, so tests in a nested directory stay in the parent's suites
+                const suites = [parentConfig.suite, childConfig.suite || []].flat()
+                inherited.suite = [...new Set(suites)]
             } else if (key === 'execution' && parentConfig.execution) {
                 inherited.execution = {...parentConfig.execution, ...childConfig.execution}
             } else if (key === 'output' && parentConfig.output) {
@@ -700,6 +932,7 @@ export class ConfigManager {
      * @internal
      * @remarks
      * Arrays are concatenated (parent items first, then child items).
+     * A child list written as {values: [...], append: false} replaces the parent list instead.
      * Objects are recursively merged.
      * Primitives from child override parent.
      */
@@ -711,10 +944,22 @@ export class ConfigManager {
 
         for (const key in child) {
             if (child[key] !== undefined) {
-                if (Array.isArray(child[key])) {
-                    // Concatenate arrays (parent first, then child)
-                    const parentArray = Array.isArray(parent[key]) ? parent[key] : []
-                    result[key] = [...parentArray, ...child[key]]
+                if (Array.isArray(child[key]) || this.isListMarker(child[key])) {
+                    const parentArray = Array.isArray(parent[key])
+                        ? parent[key]
+                        : this.isListMarker(parent[key])
+                          ? parent[key].values
+                          : []
+                    if (this.isListMarker(child[key])) {
+                        // Append (the default) or replace as marked, keeping the marker for further merging
+                        result[key] =
+                            child[key].append === false
+                                ? child[key]
+                                : {...child[key], values: [...parentArray, ...child[key].values]}
+                    } else {
+                        // Concatenate arrays (parent first, then child)
+                        result[key] = [...parentArray, ...child[key]]
+                    }
                 } else if (typeof child[key] === 'object' && child[key] !== null) {
                     // Recursively merge objects
                     result[key] = this.deepMerge(parent[key], child[key])
@@ -728,6 +973,39 @@ export class ConfigManager {
         return result
     }
 
+    /**
+     * Tests if a value is a list written with an append marker
+     *
+     * @param value - Configuration value
+     * @returns True for an object of the form {values: [...], append?: boolean}
+     *
+     * @internal
+     */
+    private static isListMarker(value: unknown): value is {values: unknown[]; append?: boolean} {
+        return (
+            typeof value === 'object' && value !== null && !Array.isArray(value) && Array.isArray((value as any).values)
+        )
+    }
+
+    /**
+     * Replaces {values, append} list markers with their values once inheritance merging is complete
+     *
+     * @param value - Configuration value
+     * @returns Copy of the value with every marked list replaced by a plain array
+     *
+     * @internal
+     */
+    private static resolveListMarkers(value: any): any {
+        if (this.isListMarker(value)) {
+            return value.values
+        } else if (Array.isArray(value)) {
+            return value.map((item) => this.resolveListMarkers(item))
+        } else if (typeof value === 'object' && value !== null) {
+            return Object.fromEntries(Object.entries(value).map(([key, item]) => [key, this.resolveListMarkers(item)]))
+        }
+        return value
+    }
+
     /**
      * Merges user configuration with default values
      *
@@ -742,14 +1020,21 @@ export class ConfigManager {
      * use in resolving relative paths.
      */
     private static mergeWithDefaults(userConfig: Partial<TestConfig> | null, configDir: string | null): TestConfig {
+        if (userConfig) {
+            userConfig = this.resolveListMarkers(userConfig) as Partial<TestConfig>
+        }
         const baseConfig = userConfig
             ? {
                   enable: userConfig.enable !== undefined ? userConfig.enable : this.DEFAULT_CONFIG.enable,
                   depth: userConfig.depth,
+                  maxDepth: userConfig.maxDepth,
                   profile: userConfig.profile, // Include profile from user config
                   compiler: {
                       ...this.DEFAULT_CONFIG.compiler,
                       ...userConfig.compiler,
+                      ...(userConfig.compiler?.cc !== undefined && {
+                          cc: this.resolvePlatformCompiler(userConfig.compiler.cc),
+                      }),
                       c: {
                           ...this.DEFAULT_CONFIG.compiler?.c,
                           ...userConfig.compiler?.c,
@@ -766,8 +1051,31 @@ export class ConfigManager {
                             py: this.resolvePlatformValue(userConfig.debug.py),
                             go: this.resolvePlatformValue(userConfig.debug.go),
                             es: this.resolvePlatformValue(userConfig.debug.es),
+                            sh: this.resolvePlatformValue(userConfig.debug.sh),
                         }
                       : undefined,
+                  valgrind: userConfig.valgrind,
+                  depends: userConfig.depends,
+                  platform: userConfig.platform,
+                  suite: userConfig.suite,
+                  matrix: userConfig.matrix,
+                  toolchain: userConfig.toolchain,
+                  setup: userConfig.setup,
+                  teardown: userConfig.teardown,
+                  quarantine: userConfig.quarantine,
+                  target: userConfig.target,
+                  remote: userConfig.remote,
+                  docker: userConfig.docker,
+                  coverage: userConfig.coverage,
+                  golden: userConfig.golden,
+                  redact: userConfig.redact,
+                  parse: userConfig.parse,
+                  build: userConfig.build,
+                  discover: userConfig.discover,
+                  handlers: userConfig.handlers,
+                  shell: userConfig.shell,
+                  notify: userConfig.notify,
+                  metrics: userConfig.metrics,
                   execution: {
                       ...this.DEFAULT_CONFIG.execution,
                       ...userConfig.execution,
@@ -776,7 +1084,10 @@ export class ConfigManager {
                       ...this.DEFAULT_CONFIG.output,
                       ...userConfig.output,
                   },
-                  patterns: this.mergePlatformPatterns(this.DEFAULT_CONFIG.patterns!, userConfig.patterns),
+                  patterns: this.addHandlerPatterns(
+                      this.mergePlatformPatterns(this.DEFAULT_CONFIG.patterns!, userConfig.patterns),
+                      userConfig.handlers
+                  ),
                   services: {
                       ...this.DEFAULT_CONFIG.services,
                       ...userConfig.services,
@@ -910,27 +1221,43 @@ export class ConfigManager {
         return merged
     }
 
+    /**
+     * Adds an include pattern for the suffix of each configured handler
+     *
+     * @param patterns - Merged pattern configuration
+     * @param handlers - Handlers keyed by test file suffix
+     * @returns Patterns that also discover tests for the handlers
+     *
+     * @internal
+     */
+    private static addHandlerPatterns(patterns: any, handlers: TestConfig['handlers']): any {
+        const extra = Object.keys(handlers || {}).map((suffix) => `**/*${suffix}`)
+        const include = [...patterns.include, ...extra.filter((pattern) => !patterns.include.includes(pattern))]
+        return {...patterns, include}
+    }
+
     /**
      * Loads configuration from a specific file path
      *
      * @param configPath - Path to configuration file
      * @returns Merged configuration with defaults
-     * @throws Error if file cannot be loaded or parsed
+     * @throws Error if file cannot be loaded or parsed, or has invalid values
      *
      * @remarks
      * Directly loads a configuration file from a specific path, useful when
      * you already know the exact configuration file location.
      */
     static async loadConfigFromFile(configPath: string): Promise<TestConfig> {
+        let userConfig: Partial<TestConfig>
         try {
             const file = Bun.file(configPath)
             const configText = await file.text()
-            const userConfig = JSON5.parse(configText) as Partial<TestConfig>
-            const configDir = dirname(configPath)
-            return this.mergeWithDefaults(userConfig, configDir)
+            userConfig = JSON5.parse(configText) as Partial<TestConfig>
         } catch (error) {
             throw new Error(ErrorMessages.configFileError(configPath, error))
         }
+        this.reportConfigIssues(configPath, userConfig)
+        return this.mergeWithDefaults(userConfig, dirname(configPath))
     }
 
     /**
@@ -988,4 +1315,28 @@ export class ConfigManager {
     static getDefaultConfig(): TestConfig {
         return {...this.DEFAULT_CONFIG}
     }
+
+    /**
+     * Gets the depth exported to tests and service scripts as TESTME_DEPTH
+     *
+     * @param config - Test or group configuration
+     * @returns The --depth value (default 0), capped by the configuration's maxDepth
+     *
+     * @remarks
+     * maxDepth lets an expensive directory limit how intensely its tests run when a high
+     * depth is requested for the whole run. It does not affect which tests are selected.
+     */
+    static getDepth(config: TestConfig): number {
+        const depth = config.execution?.depth ?? 0
+        return config.maxDepth !== undefined ? Math.min(depth, config.maxDepth) : depth
+    }
+
+    /**
+     * Gets the number of parallel workers used when execution.workers is not set
+     *
+     * @returns One worker per available CPU, resolved once in the default configuration
+     */
+    static getDefaultWorkers(): number {
+        return ConfigManager.DEFAULT_CONFIG.execution!.workers!
+    }
 }
@@ -0,0 +1,258 @@
+import type {TestConfig, TestDirectives, TestFile, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {PlatformDetector} from './platform/detector.ts'
+import {readFile} from 'fs/promises'
+import {resolve} from 'path'
+
+/*
+ Directives - Inline "testme:" directives in test source files
+
+ A directive is a comment near the top of a test file of the form "testme: NAME [ARGS]", written with
+ the comment syntax of the test's language (//, /*, #, ;, --, :: or REM). Only the first lines of the
+ file are searched, and unknown directive names are ignored.
+
+ Supported directives:
+ - xfail: the test documents a known bug and is expected to fail
+ - requires TOOL, ...: executables that must be on PATH, otherwise the test is skipped (failed with --strict)
+ - platform NAME, ...: platforms the test runs on (windows, linux, darwin), or !NAME to exclude a platform
+ - exit CODE: exit code expected from the test instead of 0, or "nonzero" for any non-zero code
+ - args ARG ...: command-line arguments passed to the test program (quote arguments containing spaces)
+ - stdin FILE: file supplied as the test's stdin, relative to the test file
+ - ports [COUNT]: free localhost ports reserved for the test, exported as TESTME_PORT0 onwards (default 1)
+ - chdir [DIR]: working directory instead of the test's temporary directory, relative to the test file (default .)
+ - resource NAME, ...: shared resources; tests declaring the same resource never run at the same time
+ - after TEST, ...: tests that must run and pass first, relative to the test file; otherwise the test is skipped
+ - suite NAME, ...: named suites the test belongs to, in addition to those of its configuration (--suite)
+ - tags NAME, ...: labels for selecting tests with --tags and --not-tags
+ */
+export class Directives {
+    // Number of lines searched for directives
+    private static readonly MAX_LINES = 20
+
+    /*
+     Reads the directives of a test file
+     @param path Path to the test file
+     @returns Parsed directives (empty if the file cannot be read)
+     */
+    static async read(path: string): Promise<TestDirectives> {
+        try {
+            return this.parse(await readFile(path, 'utf-8'))
+        } catch {
+            return {}
+        }
+    }
+
+    /*
+     Parses the directives in test source
+     @param source Test file contents
+     @returns Parsed directives
+     */
+    static parse(source: string): TestDirectives {
+        const directives: TestDirectives = {}
+        const pattern = /^\s*(?:\/\/+|\/\*+|#+|;+|--|::|rem\s)\s*testme:\s*([\w-]+)(.*)$/i
+        for (const line of source.split('\n', this.MAX_LINES)) {
+            const match = line.match(pattern)
+            if (!match) {
+                continue
+            }
+            const name = match[1]!.toLowerCase()
+            const text = match[2]!.replace(/\*\/\s*$/, '').trim()
+            const args = text.split(/[\s,]+/).filter((arg) => arg)
+            if (name === 'xfail') {
+                directives.xfail = true
+            } else if (name === 'requires') {
+                directives.requires = [...(directives.requires || []), ...args]
+            } else if (name === 'platform') {
+                directives.platforms = [...(directives.platforms || []), ...args]
+            } else if (name === 'exit' && args[0]) {
+                const code = args[0].toLowerCase()
+                if (code === 'nonzero') {
+                    directives.exitCode = code
+                } else if (/^\d+$/.test(code)) {
+                    directives.exitCode = parseInt(code, 10)
+                }
+            } else if (name === 'args') {
+                directives.args = [...(directives.args || []), ...this.splitArgs(text)]
+            } else if (name === 'stdin' && text) {
+                directives.stdin = text
+            } else if (name === 'ports' && (!args[0] || /^\d+$/.test(args[0]))) {
+                directives.ports = args[0] ? parseInt(args[0], 10) : 1
+            } else if (name === 'chdir') {
+                directives.chdir = this.splitArgs(text)[0] ?? '.'
+            } else if (name === 'resource' || name === 'resources') {
+                directives.resources = [...(directives.resources || []), ...args]
+            } else if (name === 'after') {
+                directives.after = [...(directives.after || []), ...args]
+            } else if (name === 'suite' || name === 'suites') {
+                directives.suites = [...(directives.suites || []), ...args]
+            } else if (name === 'tags' || name === 'tag') {
+                directives.tags = [...new Set([...(directives.tags || []), ...args])]
+            }
+        }
+        return directives
+    }
+
+    /*
+     Splits directive arguments on whitespace, keeping single or double quoted arguments together
+     @param text Argument text
+     @returns Arguments with quotes removed
+     */
+    static splitArgs(text: string): string[] {
+        const args: string[] = []
+        for (const match of text.matchAll(/"([^"]*)"|'([^']*)'|(\S+)/g)) {
+            args.push(match[1] ?? match[2] ?? match[3]!)
+        }
+        return args
+    }
+
+    /*
+     Applies the args, stdin and chdir directives to the configuration of a test
+     Directive values override execution.args, execution.stdin and execution.chdir. The stdin file is resolved to an
+     absolute path, relative to the test file for the directive and to the config file for the configuration.
+     The working directory is resolved relative to the test file for both.
+     @param config Test configuration
+     @param directives Directives of the test
+     @param file Test file
+     @returns Configuration with the test's arguments and absolute stdin and working directory paths
+     */
+    static applyTestInput(config: TestConfig, directives: TestDirectives, file: TestFile): TestConfig {
+        const execution = config.execution
+        const args = directives.args ?? execution?.args
+        const stdin = directives.stdin
+            ? resolve(file.directory, directives.stdin)
+            : execution?.stdin && resolve(config.configDir || file.directory, execution.stdin)
+        const dir = directives.chdir ?? execution?.chdir
+        const chdir = dir !== undefined ? resolve(file.directory, dir) : undefined
+        if (!args && !stdin && !chdir) {
+            return config
+        }
+        return {...config, execution: {...execution!, args, stdin, chdir}}
+    }
+
+    /*
+     Finds the required tools that are not on PATH
+     @param directives Directives of the test
+     @returns Names of the missing executables
+     */
+    static async findMissingTools(directives: TestDirectives): Promise<string[]> {
+        const missing: string[] = []
+        for (const tool of directives.requires || []) {
+            if (!(await PlatformDetector.findInPath(tool))) {
+                missing.push(tool)
+            }
+        }
+        return missing
+    }
+
+    /*
+     Checks the platform restrictions of a test from its configuration and its platform directive
+     @param directives Directives of the test
+     @param configPlatform Platform key from the test's testme.json5
+     @param platform Platform to check (defaults to the current platform)
+     @returns Skip reason if the test does not run on the platform, otherwise null
+     */
+    static checkPlatform(
+        directives: TestDirectives,
+        configPlatform?: string | string[],
+        platform: string = process.platform
+    ): string | null {
+        const current = this.normalizePlatform(platform)
+        const sources: [string[] | undefined, string][] = [
+            [typeof configPlatform === 'string' ? [configPlatform] : configPlatform, 'platform in testme.json5'],
+            [directives.platforms, 'testme: platform'],
+        ]
+        for (const [platforms, source] of sources) {
+            if (platforms?.length && !this.matchesPlatform(platforms, current)) {
+                return `Not supported on ${current} (${source} ${platforms.join(', ')})`
+            }
+        }
+        return null
+    }
+
+    /*
+     Tests a platform against a platform list
+     Names without "!" form an allow list (empty allows all), names with "!" are excluded.
+     @param platforms Platform names, optionally negated with "!"
+     @param platform Normalized platform name
+     @returns True if the platform is allowed
+     */
+    static matchesPlatform(platforms: string[], platform: string): boolean {
+        const allowed: string[] = []
+        const excluded: string[] = []
+        for (const name of platforms.map((name) => name.trim())) {
+            if (name.startsWith('!')) {
+                excluded.push(this.normalizePlatform(name.slice(1)))
+            } else {
+                allowed.push(this.normalizePlatform(name))
+            }
+        }
+        if (excluded.includes(platform)) {
+            return false
+        }
+        return allowed.length === 0 || allowed.includes(platform)
+    }
+
+    /*
+     Normalizes a platform name to windows, linux or darwin
+     Accepts Node platform names (win32) and the macosx/macos names used elsewhere in configuration.
+     @param name Platform name
+     @returns Normalized platform name
+     */
+    private static normalizePlatform(name: string): string {
+        const lower = name.trim().toLowerCase()
+        if (lower === 'win32') return 'windows'
+        if (lower === 'macosx' || lower === 'macos') return 'darwin'
+        return lower
+    }
+
+    /*
+     Applies the expected exit code of a test, from its exit directive or execution.exitCode
+     The test passes if it exits with the expected code, otherwise it fails with the actual and expected codes.
+     Results without an exit code (compile errors, timeouts) are left unchanged.
+     @param result Test result
+     @param directives Directives of the test
+     @param config Test configuration
+     @returns The result, passed or failed against the expected exit code
+     */
+    static applyExpectedExit(result: TestResult, directives: TestDirectives, config: TestConfig): TestResult {
+        const expected = directives.exitCode ?? config.execution?.exitCode
+        const actual = result.exitCode
+        if (expected === undefined || expected === 0 || actual === undefined) {
+            return result
+        }
+        if (result.status !== TestStatus.Passed && result.status !== TestStatus.Failed) {
+            return result
+        }
+        if (expected === 'nonzero' ? actual !== 0 : actual === expected) {
+            return actual === 0 ? result : {...result, status: TestStatus.Passed, error: undefined}
+        }
+        const message = `Exit code ${actual}, expected ${expected === 'nonzero' ? 'non-zero' : expected}`
+        return {
+            ...result,
+            status: TestStatus.Failed,
+            error: [message, result.error].filter((text) => text).join('\n'),
+        }
+    }
+
+    /*
+     Applies an xfail directive to a test result
+     A failure (including a timeout) becomes an expected failure. A pass becomes an unexpected pass,
+     which fails the run so the directive is removed once the bug is fixed. Errors are left unchanged.
+     @param result Test result
+     @param directives Directives of the test
+     @returns The result with xfail or xpass status if the test is marked xfail
+     */
+    static applyExpectedFailure(result: TestResult, directives: TestDirectives): TestResult {
+        if (!directives.xfail) {
+            return result
+        }
+        if ([TestStatus.Failed, TestStatus.Timeout, TestStatus.IdleTimeout].includes(result.status)) {
+            return {...result, status: TestStatus.XFail}
+        }
+        if (result.status === TestStatus.Passed) {
+            const message = 'Test passed but is marked xfail (remove the "testme: xfail" directive)'
+            return {...result, status: TestStatus.XPass, error: message}
+        }
+        return result
+    }
+}
@@ -1,7 +1,18 @@
-import type {TestFile, DiscoveryOptions} from './types.ts'
+import type {TestConfig, TestFile, DiscoveryOptions} from './types.ts'
 import {TestType} from './types.ts'
-import {join, dirname, basename, extname} from 'path'
-import {readdir} from 'node:fs/promises'
+import {join, dirname, basename, extname, isAbsolute, relative, resolve} from 'path'
+import {readdir, realpath, stat} from 'node:fs/promises'
+import {Fixtures} from './fixtures.ts'
+import {ARTIFACT_DIR} from './artifacts.ts'
+import {compileIgnore, isIgnored} from './utils/ignore.ts'
+import type {IgnoreRule} from './utils/ignore.ts'
+
+// State of one discovery walk
+type DiscoveryWalk = {
+    visited: Set<string> // Real paths of directories walked (only kept when following symlinks)
+    linked: Set<TestFile> // Tests reached through a symlink, which may duplicate another test
+    ignore: IgnoreRule[] // Compiled discover.exclude patterns
+}
 
 /*
  TestDiscovery - Pattern-driven test file discovery engine
@@ -9,7 +20,7 @@ import {readdir} from 'node:fs/promises'
  Responsibilities:
  - Recursively walks directory trees to find test files
  - Uses include patterns to determine which files are tests
- - Identifies test types by final file extension (.c, .js, .sh, etc.)
+ - Identifies test types by final file extension (.c, .js, .sh, etc.), or by a suffix registered in handlers
  - Supports platform-specific pattern matching
  - Creates TestFile objects with metadata
 
@@ -28,6 +39,7 @@ import {readdir} from 'node:fs/promises'
  - Batch (.bat, .cmd)
  - Python (.py)
  - Go (.go)
+ - Rust (.rs)
  - Ejscript (.es)
 
  Pattern Matching:
@@ -40,6 +52,13 @@ import {readdir} from 'node:fs/promises'
  - .testme artifact directories
  - Hidden directories (.star-slash)
  - Custom exclusion patterns
+ - discover.exclude gitignore-style patterns, checked during the walk so excluded directories are not entered
+
+ Symlinks:
+ - Symlinked test files are discovered, broken links are ignored
+ - Symlinked directories are skipped unless discover.followSymlinks is set
+ - When following, a symlink to a directory already walked (by real path) is not followed, which breaks cycles
+ - A test reached by more than one path is discovered once, preferring a path without symlinks
  */
 export class TestDiscovery {
     // Mapping of final file extensions to test types
@@ -54,6 +73,7 @@ export class TestDiscovery {
         '.cmd': TestType.Batch,
         '.py': TestType.Python,
         '.go': TestType.Go,
+        '.rs': TestType.Rust,
         '.es': TestType.Ejscript,
     }
 
@@ -64,10 +84,17 @@ export class TestDiscovery {
      @throws Error if directory cannot be read
      */
     static async discoverTests(options: DiscoveryOptions): Promise<TestFile[]> {
-        const tests: TestFile[] = []
+        let tests: TestFile[] = []
+        const walk: DiscoveryWalk = {visited: new Set(), linked: new Set(), ignore: compileIgnore(options.ignore || [])}
 
         try {
-            await this.searchDirectory(options.rootDir, options, tests)
+            if (options.followSymlinks) {
+                walk.visited.add(await realpath(options.rootDir))
+            }
+            await this.searchDirectory(options.rootDir, options, tests, walk)
+            if (walk.linked.size > 0) {
+                tests = await this.removeDuplicates(tests, walk.linked)
+            }
         } catch (error) {
             throw new Error(`Failed to discover tests in ${options.rootDir}: ${error}`)
         }
@@ -75,38 +102,103 @@ export class TestDiscovery {
         return this.filterByPatterns(tests, options.patterns, options.rootDir)
     }
 
+    /*
+     Gets the discovery options that say where artifact directories go (execution.artifactDir and build.dir)
+     @param config Root configuration
+     @returns Artifact directory name, and with build.dir the build directory and the directory it mirrors
+     */
+    static getArtifactOptions(config: TestConfig): Pick<DiscoveryOptions, 'artifactDir' | 'buildDir' | 'buildRoot'> {
+        const buildRoot = config.configDir || process.cwd()
+        return {
+            artifactDir: config.execution?.artifactDir,
+            buildDir: config.build?.dir ? resolve(buildRoot, config.build.dir) : undefined,
+            buildRoot,
+        }
+    }
+
+    /*
+     Gets the discovery options that say how the tree is walked (discover) and which suffixes have handlers
+     @param config Root configuration
+     @returns Symlink, exclude and custom handler options
+     */
+    static getWalkOptions(config: TestConfig): Pick<DiscoveryOptions, 'followSymlinks' | 'ignore' | 'handlers'> {
+        return {
+            followSymlinks: config.discover?.followSymlinks,
+            ignore: config.discover?.exclude,
+            handlers: Object.keys(config.handlers || {}),
+        }
+    }
+
     /*
      Recursively searches a directory for test files
      Pattern-driven: Only files matching include patterns are analyzed
-     Uses readdir with withFileTypes to avoid extra stat() calls
+     Uses readdir with withFileTypes to avoid extra stat() calls. Only symlinks are stat'd to see what they point to.
      @param dirPath Directory path to search
      @param options Discovery options
      @param tests Array to accumulate found test files
+     @param walk Directories walked and tests reached through symlinks
+     @param linked Whether dirPath was reached through a symlinked directory
      */
-    private static async searchDirectory(dirPath: string, options: DiscoveryOptions, tests: TestFile[]): Promise<void> {
+    private static async searchDirectory(
+        dirPath: string,
+        options: DiscoveryOptions,
+        tests: TestFile[],
+        walk: DiscoveryWalk,
+        linked = false
+    ): Promise<void> {
         try {
             const entries = await readdir(dirPath, {withFileTypes: true})
 
             for (const entry of entries) {
                 const fullPath = join(dirPath, entry.name)
+                let isDirectory = entry.isDirectory()
+                let isFile = entry.isFile()
+                const isLink = entry.isSymbolicLink()
+                if (isLink) {
+                    const target = await stat(fullPath).catch(() => null)
+                    if (!target) {
+                        continue // Broken link
+                    }
+                    isDirectory = target.isDirectory() && !!options.followSymlinks
+                    isFile = target.isFile()
+                }
+                if (walk.ignore.length > 0 && (isDirectory || isFile)) {
+                    const path = relative(options.rootDir, fullPath).replace(/\\/g, '/')
+                    if (isIgnored(walk.ignore, path, isDirectory)) {
+                        continue
+                    }
+                }
 
-                if (entry.isDirectory()) {
+                if (isDirectory) {
                     // Skip excluded directories
-                    if (this.shouldSkipDirectory(entry.name)) {
+                    const artifacts = entry.name === options.artifactDir || fullPath === options.buildDir
+                    if (this.shouldSkipDirectory(entry.name) || artifacts) {
                         continue
                     }
+                    // When following symlinks, never follow a link to a directory already walked so link cycles end
+                    if (options.followSymlinks) {
+                        const real = await realpath(fullPath)
+                        if (isLink && walk.visited.has(real)) {
+                            continue
+                        }
+                        walk.visited.add(real)
+                    }
 
                     // Recursively search subdirectories
-                    await this.searchDirectory(fullPath, options, tests)
-                } else if (entry.isFile()) {
+                    await this.searchDirectory(fullPath, options, tests, walk, linked || isLink)
+                } else if (isFile && !Fixtures.isFixture(entry.name)) {
+                    // Directory setup and teardown scripts are not tests
                     // First check if file matches include patterns
                     if (this.matchesIncludePatterns(fullPath, options.patterns, options.rootDir)) {
                         // Then check if it's excluded
                         if (this.matchesExcludePatterns(fullPath, options.excludePatterns, options.rootDir)) {
                             // Analyze file based on final extension
-                            const testFile = this.analyzeFileByExtension(fullPath)
+                            const testFile = this.analyzeFileByExtension(fullPath, options)
                             if (testFile) {
                                 tests.push(testFile)
+                                if (linked || isLink) {
+                                    walk.linked.add(testFile)
+                                }
                             }
                         }
                     }
@@ -118,6 +210,25 @@ export class TestDiscovery {
         }
     }
 
+    /*
+     Removes tests reached by more than one path through symlinks
+     @param tests Discovered tests
+     @param linked Tests whose path goes through a symlink
+     @returns Tests with one entry per real file, keeping a path without symlinks where there is one
+     */
+    private static async removeDuplicates(tests: TestFile[], linked: Set<TestFile>): Promise<TestFile[]> {
+        const byReal = new Map<string, TestFile>()
+        for (const test of tests) {
+            const real = await realpath(test.path)
+            const existing = byReal.get(real)
+            if (!existing || (linked.has(existing) && !linked.has(test))) {
+                byReal.set(real, test)
+            }
+        }
+        const keep = new Set(byReal.values())
+        return tests.filter((test) => keep.has(test))
+    }
+
     /*
      Checks if a file matches any include patterns
      @param filePath Full path to the file
@@ -138,10 +249,12 @@ export class TestDiscovery {
 
     /*
      Analyzes a file by its final extension to determine test type
+     A suffix registered in handlers takes precedence, the longest matching suffix winning.
      @param filePath Path to the file to analyze
+     @param options Discovery options giving the artifact directory name or build directory
      @returns TestFile object if extension is recognized, null otherwise
      */
-    private static analyzeFileByExtension(filePath: string): TestFile | null {
+    private static analyzeFileByExtension(filePath: string, options: DiscoveryOptions): TestFile | null {
         const fileName = basename(filePath)
         const directory = dirname(filePath)
 
@@ -149,7 +262,10 @@ export class TestDiscovery {
         const ext = extname(fileName).toLowerCase()
 
         // Map extension to test type
-        const testType = this.EXTENSION_TO_TYPE[ext]
+        const suffix = (options.handlers || [])
+            .filter((handler) => fileName.length > handler.length && fileName.endsWith(handler))
+            .sort((a, b) => b.length - a.length)[0]
+        const testType = suffix ? TestType.Custom : this.EXTENSION_TO_TYPE[ext]
         if (!testType) {
             return null // Unknown extension
         }
@@ -157,13 +273,21 @@ export class TestDiscovery {
         // Create artifact directory based on full filename without final extension
         const testBaseName = fileName.slice(0, -ext.length)
 
+        // With build.dir, artifacts mirror the source tree under the build directory instead of sitting beside tests
+        let artifactDir = join(directory, options.artifactDir || ARTIFACT_DIR, testBaseName)
+        if (options.buildDir) {
+            const mirror = relative(options.buildRoot || options.rootDir, directory)
+            if (!mirror.startsWith('..') && !isAbsolute(mirror)) {
+                artifactDir = join(options.buildDir, mirror, testBaseName)
+            }
+        }
         return {
             path: filePath,
             name: fileName,
-            extension: ext,
+            extension: suffix || ext,
             type: testType,
             directory,
-            artifactDir: join(directory, '.testme', testBaseName),
+            artifactDir,
         }
     }
 
@@ -221,6 +345,23 @@ export class TestDiscovery {
         return this.filterByPatterns(tests, patterns, rootDir)
     }
 
+    /*
+     Filters tests by regular expressions matched against the path relative to the root directory
+     @param tests Array of test files to filter
+     @param rootDir Root directory for relative path calculation
+     @param filter Only keep tests whose relative path matches this expression (optional)
+     @param exclude Drop tests whose relative path matches this expression (optional)
+     @returns Filtered array of test files
+     */
+    static filterTestsByRegex(tests: TestFile[], rootDir: string, filter?: string, exclude?: string): TestFile[] {
+        const include = filter ? new RegExp(filter) : null
+        const skip = exclude ? new RegExp(exclude) : null
+        return tests.filter((test) => {
+            const relativePath = relative(rootDir, test.path).replace(/\\/g, '/')
+            return (!include || include.test(relativePath)) && (!skip || !skip.test(relativePath))
+        })
+    }
+
     /*
      Filters test files by include patterns
      @param tests Array of test files to filter
@@ -346,6 +487,7 @@ export class TestDiscovery {
             '.tst.es',
             '.tst.py',
             '.tst.go',
+            '.tst.rs',
         ]
         for (const ext of testExtensions) {
             if (fileName.endsWith(ext)) {
@@ -0,0 +1,98 @@
+import type {TestConfig, TestFile} from './types.ts'
+import {randomUUID} from 'crypto'
+import {sep} from 'path'
+
+/*
+ Container a command runs in: the image, host directories mounted at the same path and extra docker run arguments
+ */
+export type ContainerSpec = {
+    image: string
+    mounts: string[]
+    args: string[] // docker.args (e.g., ['--network', 'host', '-v', '/data:/data'])
+}
+
+/*
+ Docker - Runs test commands inside a container image
+
+ The image comes from --docker (execution.docker) or docker.image. Each test command is run with
+ "docker run --rm" with the test directory and configuration directory mounted at the same path, so
+ the absolute paths testme uses for tests and build artifacts work unchanged, and with the test
+ environment passed by -e. Builds of compiled tests run on the host unless docker.build is true.
+ The test's temporary directory, working directory and artifact directory (which build.dir may place
+ outside the configuration directory) are mounted too. Containers are named so a timed out test's
+ container can be killed.
+ */
+export class Docker {
+    /*
+     Gets the container image for a configuration
+     @param config Test configuration
+     @returns Image name, or undefined to run tests on the host
+     */
+    static getImage(config: TestConfig): string | undefined {
+        return config.execution?.docker || config.docker?.image || undefined
+    }
+
+    /*
+     Gets the container a test command runs in
+     @param config Test configuration
+     @param file Test file
+     @param build True for a build command, which only runs in the container if docker.build is true
+     @returns Container, or undefined to run the command on the host
+     */
+    static getContainer(config: TestConfig, file: TestFile, build: boolean = false): ContainerSpec | undefined {
+        const image = this.getImage(config)
+        if (!image || (build && config.docker?.build !== true)) {
+            return undefined
+        }
+        // Mount each directory once, skipping directories inside another mounted directory
+        const {tmpDir, chdir} = config.execution || {}
+        const paths = [config.configDir, file.directory, tmpDir, chdir, file.artifactDir]
+        const dirs = [...new Set(paths.filter((dir): dir is string => !!dir))]
+        const mounts = dirs.filter((dir) => !dirs.some((other) => other !== dir && dir.startsWith(other + sep)))
+        return {image, mounts, args: config.docker?.args || []}
+    }
+
+    /*
+     Gets a unique container name
+     @returns Name for docker run --name
+     */
+    static getName(): string {
+        return `testme-${randomUUID().slice(0, 12)}`
+    }
+
+    /*
+     Builds the docker run arguments for a command
+     PATH is not passed because host search paths do not apply inside the image
+     @param container Container to run in
+     @param name Container name
+     @param command Command to run in the container
+     @param args Command arguments
+     @param options Working directory, environment and whether stdin is supplied
+     @returns Arguments for docker
+     */
+    static getRunArgs(
+        container: ContainerSpec,
+        name: string,
+        command: string,
+        args: string[],
+        options: {cwd?: string; env?: Record<string, string>; stdin?: boolean}
+    ): string[] {
+        const result = ['run', '--rm', '--name', name]
+        if (options.stdin) {
+            result.push('-i')
+        }
+        for (const mount of container.mounts) {
+            result.push('-v', `${mount}:${mount}`)
+        }
+        if (options.cwd) {
+            result.push('-w', options.cwd)
+        }
+        for (const [key, value] of Object.entries(options.env || {})) {
+            if (key.toUpperCase() !== 'PATH') {
+                result.push('-e', `${key}=${value}`)
+            }
+        }
+        result.push(...container.args, container.image, command, ...args)
+        return result
+    }
+}
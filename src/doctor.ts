@@ -0,0 +1,322 @@
+import type {TestConfig, TestFile} from './types.ts'
+import {TestType} from './types.ts'
+import {CompilerManager} from './platform/compiler.ts'
+import {PlatformDetector} from './platform/detector.ts'
+import {ShellDetector} from './platform/shell.ts'
+import {Remote} from './remote.ts'
+import {Docker} from './docker.ts'
+import {Directives} from './directives.ts'
+import {existsSync} from 'fs'
+import {basename, isAbsolute, resolve} from 'path'
+
+/*
+ A tool needed by the selected tests and what was found for it
+ */
+export type ToolCheck = {
+    name: string // Tool name used for minimum versions and display (e.g., 'gcc', 'go', 'python3')
+    language: string // Test language needing the tool (e.g., 'C')
+    tests: number // Number of tests needing the tool
+    path: string | null // Resolved executable, or null if not found
+    version?: string // Version reported by the tool
+    minimum?: string // Configured minimum version
+    source?: string // How a C compiler was chosen (e.g., '--cc', 'compiler.cc', '$CC', 'auto-detected')
+    problem?: string // Why the tool is unusable (missing or too old)
+}
+
+/*
+ An include or library directory named in compiler flags
+ */
+export type PathCheck = {
+    flag: string // Flag as configured (e.g., '-I../include')
+    path: string // Absolute directory the flag refers to
+    configDir: string // Directory of the configuration that sets the flag
+    problem?: string
+}
+
+/*
+ A tool requirement of one test: the first candidate found on PATH is used
+ */
+type Requirement = {language: string; candidates: string[]; source?: string}
+
+/*
+ Arguments that make each tool print its version (default: --version)
+ */
+const VERSION_ARGS: Record<string, string[]> = {
+    go: ['version'],
+    cl: [],
+    cmd: ['/c', 'ver'],
+    powershell: ['-NoProfile', '-Command', '$PSVersionTable.PSVersion.ToString()'],
+}
+
+/*
+ Language names for messages
+ */
+const LANGUAGES: Record<TestType, string> = {
+    [TestType.Shell]: 'Shell',
+    [TestType.PowerShell]: 'PowerShell',
+    [TestType.Batch]: 'Batch',
+    [TestType.C]: 'C',
+    [TestType.JavaScript]: 'JavaScript',
+    [TestType.TypeScript]: 'TypeScript',
+    [TestType.Ejscript]: 'Ejscript',
+    [TestType.Python]: 'Python',
+    [TestType.Go]: 'Go',
+    [TestType.Rust]: 'Rust',
+    [TestType.Custom]: 'Custom',
+}
+
+/*
+ Doctor - Toolchain checks for --doctor
+
+ Works out which executables the selected tests need (the configured or detected C compiler, go, bun,
+ tsc and node, ts-node, python3, rustc, ejs, and the shells that run scripts), finds them on PATH and
+ asks each for its version. Minimum versions come from the "toolchain" configuration key, keyed by tool
+ name (gcc, go, python3) or language (c, python). The -I, -L, /I and /LIBPATH: directories in the
+ C compiler flags of each configuration are checked to exist.
+ */
+export class Doctor {
+    /*
+     Checks the tools needed by tests
+     @param tests Tests with their configurations
+     @param minimums Minimum versions keyed by tool name or language
+     @returns One check per tool, in order of first use
+     */
+    static async checkTools(
+        tests: {file: TestFile; config: TestConfig}[],
+        minimums: Record<string, string | number> = {}
+    ): Promise<ToolCheck[]> {
+        const checks = new Map<string, ToolCheck & {type: TestType}>()
+        for (const {file, config} of tests) {
+            for (const {language, candidates, source} of await this.getRequirements(file, config)) {
+                const key = `${language}:${candidates.join('|')}`
+                const check = checks.get(key)
+                if (check) {
+                    check.tests++
+                    continue
+                }
+                let path: string | null = null
+                let command = candidates[0]!
+                for (const candidate of candidates) {
+                    path = await this.findTool(candidate)
+                    if (path) {
+                        command = candidate
+                        break
+                    }
+                }
+                const name = this.getToolName(command)
+                checks.set(key, {name, language, tests: 1, path, source, type: file.type})
+            }
+        }
+
+        const results: ToolCheck[] = []
+        for (const {type, ...check} of checks.values()) {
+            const minimum = minimums[check.name] ?? minimums[type]
+            check.minimum = minimum !== undefined ? String(minimum) : undefined
+            if (!check.path) {
+                check.problem = 'not found'
+            } else {
+                check.version = await this.getVersion(check.path, check.name)
+                if (check.minimum && !check.version) {
+                    check.problem = `version unknown, ${check.minimum} required`
+                } else if (check.minimum && this.compareVersions(check.version!, check.minimum) < 0) {
+                    check.problem = `version ${check.version} is older than ${check.minimum}`
+                }
+            }
+            results.push(check)
+        }
+        return results
+    }
+
+    /*
+     Checks that the include and library directories in C compiler flags exist
+     @param configs Configurations used by C tests
+     @returns One check per distinct directory flag
+     */
+    static checkPaths(configs: TestConfig[]): PathCheck[] {
+        const checks = new Map<string, PathCheck>()
+        const platform = this.getPlatform()
+        for (const config of configs) {
+            const c = config.compiler?.c
+            if (!c) {
+                continue
+            }
+            const configDir = config.configDir || process.cwd()
+            const sections = PlatformDetector.isWindows() ? [c.gcc, c.clang, c.msvc] : [c.gcc, c.clang]
+            const flags = [
+                ...(c.flags || []),
+                ...sections.flatMap((section) => [
+                    ...(section?.flags || []),
+                    ...(section?.[platform]?.flags || []),
+                ]),
+            ]
+            for (const flag of flags) {
+                const match = flag.match(/^(-I|-L|\/I|\/LIBPATH:)(.+)$/)
+                // Paths with ${...} references or wildcards are expanded at compile time
+                if (!match || /[$*?]/.test(match[2]!)) {
+                    continue
+                }
+                const path = isAbsolute(match[2]!) ? match[2]! : resolve(configDir, match[2]!)
+                if (!checks.has(path)) {
+                    checks.set(path, {flag, path, configDir, ...(!existsSync(path) && {problem: 'not found'})})
+                }
+            }
+        }
+        return [...checks.values()]
+    }
+
+    /*
+     Compares two dotted version numbers
+     @param a First version (e.g., '1.21.3')
+     @param b Second version (e.g., '1.21')
+     @returns Negative if a is older than b, zero if equal, positive if newer
+     */
+    static compareVersions(a: string, b: string): number {
+        const left = a.split('.').map((part) => parseInt(part, 10) || 0)
+        const right = b.split('.').map((part) => parseInt(part, 10) || 0)
+        for (let i = 0; i < Math.max(left.length, right.length); i++) {
+            const diff = (left[i] || 0) - (right[i] || 0)
+            if (diff !== 0) {
+                return diff
+            }
+        }
+        return 0
+    }
+
+    /*
+     Extracts a version number from a tool's version output
+     @param text Version output (e.g., 'go version go1.22.1 linux/amd64')
+     @returns First dotted version number, or undefined if there is none
+     */
+    static parseVersion(text: string): string | undefined {
+        return text.match(/(\d+\.\d+(?:\.\d+)*)/)?.[1]
+    }
+
+    /*
+     Gets the tools a test needs, mirroring how its handler runs it
+     Compiled tests run on a remote host (--remote or remote.host) also need ssh and scp. Tests run in a
+     container (--docker or docker.image) need docker, and language tools only to build compiled tests on the host.
+     @param file Test file
+     @param config Test configuration
+     @returns Requirements, each a list of interchangeable executables
+     */
+    private static async getRequirements(file: TestFile, config: TestConfig): Promise<Requirement[]> {
+        const language = LANGUAGES[file.type]
+        const compiled = [TestType.C, TestType.Go, TestType.Rust].includes(file.type)
+        const docker = Docker.getImage(config) !== undefined
+        const requirements: Requirement[] = docker ? [{language, candidates: ['docker']}] : []
+        if (!docker || (compiled && !config.docker?.build)) {
+            requirements.push(...(await this.getLanguageRequirements(file, config)))
+        }
+        if (Remote.getHost(config) && compiled) {
+            requirements.push({language, candidates: ['ssh']}, {language, candidates: ['scp']})
+        }
+        return requirements
+    }
+
+    /*
+     Gets the language tools a test needs
+     @param file Test file
+     @param config Test configuration
+     @returns Requirements, each a list of interchangeable executables
+     */
+    private static async getLanguageRequirements(file: TestFile, config: TestConfig): Promise<Requirement[]> {
+        const language = LANGUAGES[file.type]
+        const need = (...candidates: string[]) => ({language, candidates})
+        switch (file.type) {
+            case TestType.C: {
+                const {name, source} = CompilerManager.selectCompiler(config)
+                return [{...need((await CompilerManager.getDefaultCompilerConfig(name)).compiler), source}]
+            }
+            case TestType.JavaScript: {
+                const javascript = config.compiler?.javascript
+                return javascript?.mode === 'node' ? [need(javascript.node || 'node')] : [need('bun')]
+            }
+            case TestType.TypeScript: {
+                const mode = config.compiler?.typescript?.mode || 'bun'
+                return mode === 'tsc' ? [need('tsc'), need('node')] : [need(mode)]
+            }
+            case TestType.Python: {
+                const interpreter = config.compiler?.python?.interpreter
+                return [interpreter ? need(interpreter) : need('python3', 'python')]
+            }
+            case TestType.Go:
+                return [need('go')]
+            case TestType.Rust:
+                return [need(config.compiler?.rust?.compiler || 'rustc')]
+            case TestType.Ejscript:
+                return [need(config.compiler?.es?.interpreter || 'ejs')]
+            case TestType.Shell: {
+                const interpreter = config.shell?.interpreter
+                return [need(await ShellDetector.selectInterpreter(file.path, interpreter, config.execution?.shell))]
+            }
+            case TestType.PowerShell:
+                return [need((await ShellDetector.selectPowerShell(config.shell?.powershell)) ?? 'pwsh')]
+            case TestType.Custom: {
+                // The first word of each command, unless it is the built test ({out}) or another placeholder
+                const handler = config.handlers?.[file.extension]
+                const commands = [handler?.build, handler?.run].map((command) => Directives.splitArgs(command || '')[0])
+                return commands.filter((command) => command && !command.includes('{')).map((command) => need(command!))
+            }
+            default:
+                return [need(await ShellDetector.detectShell(file.path))]
+        }
+    }
+
+    /*
+     Finds a tool given by name or path
+     @param command Executable name or path
+     @returns Resolved path, or null if not found
+     */
+    private static async findTool(command: string): Promise<string | null> {
+        if (isAbsolute(command) || /[\\/]/.test(command)) {
+            return existsSync(command) ? resolve(command) : null
+        }
+        return await PlatformDetector.findInPath(command)
+    }
+
+    /*
+     Runs a tool to get its version
+     @param path Tool executable
+     @param name Tool name (selects the version arguments)
+     @returns Version, or undefined if the tool does not report one
+     */
+    private static async getVersion(path: string, name: string): Promise<string | undefined> {
+        try {
+            const proc = Bun.spawn([path, ...(VERSION_ARGS[name] ?? ['--version'])], {
+                stdout: 'pipe',
+                stderr: 'pipe',
+                stdin: 'ignore',
+            })
+            const timer = setTimeout(() => proc.kill(), 10000)
+            const [stdout, stderr] = await Promise.all([
+                new Response(proc.stdout).text(),
+                new Response(proc.stderr).text(),
+            ])
+            await proc.exited
+            clearTimeout(timer)
+            // Some tools (e.g., cl) print their version on stderr
+            return this.parseVersion(stdout) || this.parseVersion(stderr)
+        } catch {
+            return undefined
+        }
+    }
+
+    /*
+     Gets the tool name of a command, without directory or .exe suffix
+     @param command Executable name or path
+     @returns Lower case tool name (e.g., 'gcc')
+     */
+    private static getToolName(command: string): string {
+        return basename(command.replace(/\\/g, '/'))
+            .replace(/\.exe$/i, '')
+            .toLowerCase()
+    }
+
+    /*
+     Gets the current platform key used in configuration files
+     @returns windows, macosx or linux
+     */
+    private static getPlatform(): 'windows' | 'macosx' | 'linux' {
+        return PlatformDetector.isWindows() ? 'windows' : PlatformDetector.isMacOS() ? 'macosx' : 'linux'
+    }
+}
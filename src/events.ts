@@ -0,0 +1,150 @@
+import type {TestFile, TestResult} from './types.ts'
+import {TestCases} from './cases.ts'
+import {Matrix} from './matrix.ts'
+import {AsyncLocalStorage} from 'async_hooks'
+import {closeSync, mkdirSync, openSync, writeSync} from 'fs'
+import {dirname, relative, resolve} from 'path'
+
+/*
+ EventStream - Newline-delimited JSON (NDJSON) event feed for live viewers
+
+ Enabled with --events fd:N or --events file:PATH. Each line is a JSON object with:
+ - event: discovered | test-start | test-output | test-retry | test-end | run-end
+ - ts: Monotonic timestamp in milliseconds (performance.now())
+ - path: Test path relative to the root directory (for test events)
+ - case: Case name, for a case of a parameterized test
+ - matrix: Matrix cell label, for a test run in a matrix cell
+
+ The feed is independent of the console reporter. Test output events are correlated to
+ their test via an async context so interleaved output from parallel workers can be
+ separated by consumers.
+ */
+export class EventStream {
+    private static fd: number | null = null
+    private static ownsFd: boolean = false
+    private static rootDir: string = process.cwd()
+    private static context = new AsyncLocalStorage<TestFile>()
+
+    /*
+     Opens the event feed
+     @param spec Destination: 'fd:N' for an inherited file descriptor or 'file:PATH' for a file
+     @param rootDir Root directory for relative paths
+     @throws Error if the specification is invalid or the file cannot be opened
+     */
+    static open(spec: string, rootDir: string): void {
+        this.rootDir = rootDir
+        const index = spec.indexOf(':')
+        const kind = index >= 0 ? spec.slice(0, index) : ''
+        const value = index >= 0 ? spec.slice(index + 1) : ''
+
+        if (kind === 'fd') {
+            const fd = parseInt(value, 10)
+            if (isNaN(fd) || fd < 0 || String(fd) !== value) {
+                throw new Error(`Invalid events file descriptor: "${value}"`)
+            }
+            this.fd = fd
+            this.ownsFd = false
+        } else if (kind === 'file' && value) {
+            const path = resolve(rootDir, value)
+            mkdirSync(dirname(path), {recursive: true})
+            this.fd = openSync(path, 'w')
+            this.ownsFd = true
+        } else {
+            throw new Error(`Invalid events destination: "${spec}". Use fd:N or file:PATH`)
+        }
+    }
+
+    /*
+     Checks if the event feed is enabled
+     @returns True if events are being written
+     */
+    static isEnabled(): boolean {
+        return this.fd !== null
+    }
+
+    /*
+     Emits an event as a single NDJSON line
+     Test events without an explicit test are attributed to the test running in the current async context
+     @param event Event name
+     @param data Event payload
+     @param file Test the event relates to (optional)
+     */
+    static emit(event: string, data: Record<string, unknown> = {}, file?: TestFile): void {
+        if (this.fd === null) {
+            return
+        }
+        const test = file || this.context.getStore()
+        const record = {
+            event,
+            ts: Math.round(performance.now() * 1000) / 1000,
+            ...(test && {path: this.getPath(test.path)}),
+            ...(test?.testCase && {case: test.testCase.name}),
+            ...(test?.matrix && {matrix: test.matrix}),
+            ...data,
+        }
+        try {
+            writeSync(this.fd, JSON.stringify(record) + '\n')
+        } catch (error) {
+            // Consumer went away - stop emitting rather than failing the run
+            console.warn(`⚠️  Event feed disabled: ${error}`)
+            this.fd = null
+        }
+    }
+
+    /*
+     Emits the discovered event listing all tests selected for the run
+     @param tests Discovered test files
+     */
+    static emitDiscovered(tests: TestFile[]): void {
+        const paths = tests.map((test) => Matrix.label(test, TestCases.label(test, this.getPath(test.path))))
+        this.emit('discovered', {count: tests.length, tests: paths})
+    }
+
+    /*
+     Emits the test-end event for a completed test
+     @param result Completed test result
+     */
+    static emitTestEnd(result: TestResult): void {
+        this.emit(
+            'test-end',
+            {
+                status: result.status,
+                duration: Math.round(result.duration),
+                exitCode: result.exitCode ?? null,
+                ...(result.attempts !== undefined && {attempts: result.attempts, flaky: result.flaky === true}),
+                ...(result.sanitizer && {sanitizer: result.sanitizer}),
+                ...(result.error && {error: result.error}),
+            },
+            result.file
+        )
+    }
+
+    /*
+     Runs a function with a test as the current event context
+     Output events emitted while the function runs are attributed to the test
+     @param file Test file
+     @param fn Function to run
+     @returns Result of the function
+     */
+    static runWithTest<T>(file: TestFile, fn: () => Promise<T>): Promise<T> {
+        return this.context.run(file, fn)
+    }
+
+    /*
+     Closes the event feed (file descriptors passed with fd:N are left open)
+     */
+    static close(): void {
+        if (this.fd !== null && this.ownsFd) {
+            try {
+                closeSync(this.fd)
+            } catch {
+                // Ignore close errors
+            }
+        }
+        this.fd = null
+    }
+
+    private static getPath(path: string): string {
+        return relative(this.rootDir, path).replace(/\\/g, '/')
+    }
+}
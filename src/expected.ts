@@ -0,0 +1,305 @@
+import type {GoldenNormalizer, NumericTolerance, TestConfig, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {diffLines, unifiedDiff} from './utils/diff.ts'
+import type {LineMatcher} from './utils/diff.ts'
+import {existsSync, realpathSync} from 'fs'
+import {readFile, writeFile} from 'fs/promises'
+import {basename} from 'path'
+
+/*
+ Wildcard in an expected file line matching any run of characters within the line
+ */
+export const IGNORE_TOKEN = '<<IGNORE>>'
+
+/*
+ Decimal number, with optional sign, fraction and exponent, compared numerically with golden.numericTolerance
+ */
+const NUMBER = /([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)/
+
+/*
+ Most numbers out of tolerance listed in the error of a failed comparison
+ */
+const MAX_NUMERIC_DETAILS = 5
+
+/*
+ Built-in golden.normalize rules, selectable by name. The tmp rule depends on the test and is built per result.
+ */
+const NORMALIZERS: Record<string, {pattern: RegExp; replace: string}> = {
+    timestamps: {
+        pattern: /\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}([.,]\d+)?)?(Z|[+-]\d{2}:?\d{2})?\b/g,
+        replace: '<TIME>',
+    },
+    pids: {pattern: /\b(pid[\s=:#]*)\d+/gi, replace: '$1<PID>'},
+    uuids: {pattern: /\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b/gi, replace: '<UUID>'},
+    addresses: {pattern: /\b0x[0-9a-f]+\b/gi, replace: '<ADDR>'},
+    durations: {pattern: /\b\d+(\.\d+)?\s?(ns|us|µs|ms|s|secs?|seconds?|mins?|minutes?)\b/g, replace: '<DURATION>'},
+}
+
+/*
+ ExpectedOutput - Golden-file comparison of test stdout
+
+ If a file named after the test with an .expected suffix (e.g., foo.tst.sh.expected) sits next
+ to the test, the test's stdout is compared against it. A mismatch fails the test with a unified
+ diff. With --accept (execution.accept), the expected file is rewritten with the current stdout.
+
+ Newline handling is set by execution.expectedNewlines:
+ - normalize (default): CRLF and CR line endings are converted to LF before comparing
+ - exact: stdout must match the expected file byte for byte
+ - trim: normalize, and ignore trailing whitespace on each line and trailing blank lines
+
+ Volatile content such as timestamps and temporary paths is rewritten by the golden.normalize rules, in
+ order, before comparing. A rule is a built-in normalizer name or a {pattern, replace, flags} regular
+ expression (flags default to g). Accepted output is normalized the same way so expected files stay stable.
+
+ For spans that cannot be normalized, an expected line may contain the <<IGNORE>> wildcard, which matches
+ any run of characters, including none, up to the next literal text on the same line. \<<IGNORE>> stands for
+ the literal token and \\<<IGNORE>> for a literal backslash followed by the wildcard. --accept keeps the
+ expected lines that still match, so their wildcards survive.
+
+ With golden.numericTolerance, the numbers in a line are compared by value, within an absolute or relative
+ tolerance, while the text between them must still match exactly. Lines with wildcards are compared by their
+ wildcards only. The error of a failed comparison names the numbers that exceeded the tolerance.
+ */
+export class ExpectedOutput {
+    /*
+     Gets the expected output file path for a test
+     @param testPath Path to the test file
+     @returns Path of the .expected file
+     */
+    static getPath(testPath: string): string {
+        return `${testPath}.expected`
+    }
+
+    /*
+     Compares a test's stdout against its expected output file, if one exists
+     @param result Test result with captured stdout
+     @param config Test configuration
+     @returns The result, failed with a diff on mismatch
+     */
+    static async check(result: TestResult, config: TestConfig): Promise<TestResult> {
+        const path = this.getPath(result.file.path)
+        if (result.stdout === undefined || !existsSync(path)) {
+            return result
+        }
+        if (result.status !== TestStatus.Passed && result.status !== TestStatus.Failed) {
+            return result
+        }
+
+        let stdout: string
+        try {
+            stdout = this.applyRules(result.stdout, config.golden?.normalize || [], config.execution?.tmpDir)
+        } catch (error) {
+            const message = `Invalid golden.normalize rule: ${error instanceof Error ? error.message : error}`
+            return {
+                ...result,
+                status: TestStatus.Error,
+                error: [message, result.error].filter((text) => text).join('\n'),
+            }
+        }
+
+        if (config.execution?.accept) {
+            if (result.status === TestStatus.Passed) {
+                const tolerance = config.golden?.numericTolerance
+                await writeFile(path, this.merge(await readFile(path, 'utf-8'), stdout, tolerance))
+                return {...result, output: `${result.output}\nAccepted output into ${basename(path)}`.trim()}
+            }
+            return result
+        }
+
+        const mode = config.execution?.expectedNewlines || 'normalize'
+        const expected = this.normalize(await readFile(path, 'utf-8'), mode)
+        const actual = this.normalize(stdout, mode)
+        const tolerance = config.golden?.numericTolerance
+        const match = this.getMatcher(tolerance)
+        const diff = unifiedDiff(expected, actual, basename(path), 'stdout', 3, match)
+        if (!diff) {
+            return result
+        }
+        const message = `Output does not match ${basename(path)} (use --accept to update)`
+        const details = tolerance ? this.explainNumbers(expected, actual, match, tolerance) : []
+        return {
+            ...result,
+            status: TestStatus.Failed,
+            output: [result.output, diff].filter((text) => text).join('\n'),
+            error: [message, ...details, result.error].filter((text) => text).join('\n'),
+        }
+    }
+
+    /*
+     Rewrites volatile content with the golden.normalize rules
+     @param text Test stdout
+     @param rules Normalizer names or regular expression rules, applied in order
+     @param tmpDir The test's temporary directory (TESTME_TMP), replaced by the tmp normalizer
+     @returns Normalized text
+     @throws Error if a rule names an unknown normalizer or has an invalid regular expression
+     */
+    static applyRules(text: string, rules: GoldenNormalizer[], tmpDir?: string): string {
+        for (const rule of rules) {
+            if (typeof rule !== 'string') {
+                text = text.replace(new RegExp(rule.pattern, rule.flags ?? 'g'), rule.replace ?? '')
+            } else if (rule === 'tmp') {
+                // Replace the resolved path first, as it may extend the configured one (e.g., /private/var on macOS)
+                const paths = tmpDir && existsSync(tmpDir) ? [realpathSync(tmpDir), tmpDir] : tmpDir ? [tmpDir] : []
+                for (const path of paths) {
+                    text = text.split(path).join('<TMP>')
+                }
+            } else if (NORMALIZERS[rule]) {
+                text = text.replace(NORMALIZERS[rule]!.pattern, NORMALIZERS[rule]!.replace)
+            } else {
+                throw new Error(`unknown normalizer "${rule}"`)
+            }
+        }
+        return text
+    }
+
+    /*
+     Creates a line comparison that honors <<IGNORE>> wildcards in expected lines and the numeric tolerance
+     Patterns are compiled once per distinct expected line.
+     @param tolerance Numeric tolerance, or undefined to compare numbers as text
+     @returns Function testing whether an expected line matches an actual line
+     */
+    static getMatcher(tolerance?: NumericTolerance): LineMatcher {
+        const patterns = new Map<string, RegExp>()
+        return (expected: string, actual: string) => {
+            if (!expected.includes(IGNORE_TOKEN)) {
+                return expected === actual || (!!tolerance && this.compareNumbers(expected, actual, tolerance) === '')
+            }
+            let pattern = patterns.get(expected)
+            if (!pattern) {
+                pattern = this.compileMask(expected)
+                patterns.set(expected, pattern)
+            }
+            return pattern.test(actual)
+        }
+    }
+
+    /*
+     Compares the numbers of two lines within a tolerance, requiring the text around them to match exactly
+     @param expected Expected line
+     @param actual Actual line
+     @param tolerance Numeric tolerance
+     @returns An empty string if the lines match, otherwise the reason they do not
+     */
+    static compareNumbers(expected: string, actual: string, tolerance: NumericTolerance): string {
+        // Splitting on the capturing pattern alternates text (even indexes) and numbers (odd indexes)
+        const want = expected.split(NUMBER)
+        const got = actual.split(NUMBER)
+        if (want.length !== got.length || want.some((part, i) => i % 2 === 0 && part !== got[i])) {
+            return 'text differs'
+        }
+        for (let i = 1; i < want.length; i += 2) {
+            const value = Number(want[i])
+            const difference = Math.abs(Number(got[i]) - value)
+            const allowed = Math.max(tolerance.absolute ?? 0, (tolerance.relative ?? 0) * Math.abs(value))
+            if (!(difference <= allowed)) {
+                const [by, limit] = [difference, allowed].map((n) => Number(n.toPrecision(3)))
+                return `${got[i]} differs from expected ${want[i]} by ${by} (allowed ${limit})`
+            }
+        }
+        return ''
+    }
+
+    /*
+     Describes the numbers out of tolerance in the changed lines of a failed comparison
+     Removed and added lines are paired in order within each change, and pairs whose text differs are skipped.
+     @param expected Expected text
+     @param actual Actual text
+     @param match Line comparison used for the diff
+     @param tolerance Numeric tolerance
+     @returns One description per number out of tolerance, at most MAX_NUMERIC_DETAILS
+     */
+    private static explainNumbers(
+        expected: string,
+        actual: string,
+        match: LineMatcher,
+        tolerance: NumericTolerance
+    ): string[] {
+        const details: string[] = []
+        const edits = diffLines(expected.split('\n'), actual.split('\n'), match)
+        let line = 0
+        for (let i = 0; i < edits.length && details.length < MAX_NUMERIC_DETAILS; ) {
+            if (edits[i]!.op !== '-') {
+                line += edits[i++]!.op === ' ' ? 1 : 0
+                continue
+            }
+            const removed: string[] = []
+            const added: string[] = []
+            while (i < edits.length && edits[i]!.op === '-') {
+                removed.push(edits[i++]!.line)
+            }
+            while (i < edits.length && edits[i]!.op === '+') {
+                added.push(edits[i++]!.line)
+            }
+            for (let k = 0; k < Math.min(removed.length, added.length); k++) {
+                const reason = this.compareNumbers(removed[k]!, added[k]!, tolerance)
+                if (reason && reason !== 'text differs' && details.length < MAX_NUMERIC_DETAILS) {
+                    details.push(`Line ${line + k + 1}: ${reason}`)
+                }
+            }
+            line += removed.length
+        }
+        return details
+    }
+
+    /*
+     Builds the text to write for --accept, keeping expected lines that still match
+     Lines with wildcards or numbers within the tolerance survive, so accepting does not churn the file.
+     @param expected Current expected file content
+     @param actual Normalized test stdout
+     @param tolerance Numeric tolerance, if any
+     @returns New expected file content
+     */
+    private static merge(expected: string, actual: string, tolerance?: NumericTolerance): string {
+        if (!expected.includes(IGNORE_TOKEN) && !tolerance) {
+            return actual
+        }
+        return diffLines(expected.split('\n'), actual.split('\n'), this.getMatcher(tolerance))
+            .filter((edit) => edit.op !== '-')
+            .map((edit) => edit.line)
+            .join('\n')
+    }
+
+    /*
+     Compiles an expected line with wildcards into an anchored regular expression
+     @param line Expected line
+     @returns Pattern matching the actual lines the expected line accepts
+     */
+    private static compileMask(line: string): RegExp {
+        const escape = (text: string) => text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')
+        let source = ''
+        for (const part of line.split(/(\\\\<<IGNORE>>|\\<<IGNORE>>|<<IGNORE>>)/)) {
+            if (part === IGNORE_TOKEN) {
+                source += '.*?'
+            } else if (part === `\\${IGNORE_TOKEN}`) {
+                source += escape(IGNORE_TOKEN)
+            } else if (part === `\\\\${IGNORE_TOKEN}`) {
+                source += escape('\\') + '.*?'
+            } else {
+                source += escape(part)
+            }
+        }
+        return new RegExp(`^${source}$`)
+    }
+
+    /*
+     Applies newline normalization before comparison
+     @param text Text to normalize
+     @param mode Normalization mode
+     @returns Normalized text
+     */
+    private static normalize(text: string, mode: 'normalize' | 'exact' | 'trim'): string {
+        if (mode === 'exact') {
+            return text
+        }
+        const lines = text.replace(/\r\n?/g, '\n')
+        if (mode === 'trim') {
+            const trimmed = lines
+                .split('\n')
+                .map((line) => line.trimEnd())
+                .join('\n')
+                .trimEnd()
+            return trimmed ? trimmed + '\n' : ''
+        }
+        return lines
+    }
+}
@@ -0,0 +1,62 @@
+import type {TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {existsSync} from 'fs'
+import {mkdir, readFile, writeFile} from 'fs/promises'
+import {join, relative, resolve} from 'path'
+
+/*
+ LastFailures - Records failing tests so that --failed can re-run only those
+
+ Failing test paths are stored one per line, relative to the test root, in .testme/last-failures.
+ After each run the file is updated: tests that ran and passed (or were skipped or failed as expected) are
+ removed and tests that failed, errored, timed out or passed unexpectedly are added. Tests that did not run keep their previous entry, so
+ repeated --failed runs converge as failures are fixed.
+ */
+export class LastFailures {
+    /*
+     Gets the failures file path
+     @param rootDir Test root directory
+     @returns Path of the last-failures file
+     */
+    static getPath(rootDir: string): string {
+        return join(rootDir, '.testme', 'last-failures')
+    }
+
+    /*
+     Loads the recorded failures
+     @param rootDir Test root directory
+     @returns Absolute paths of tests that failed, or null if no failures file exists
+     */
+    static async load(rootDir: string): Promise<Set<string> | null> {
+        const path = this.getPath(rootDir)
+        if (!existsSync(path)) {
+            return null
+        }
+        const lines = (await readFile(path, 'utf-8')).split('\n').filter((line) => line.trim())
+        return new Set(lines.map((line) => resolve(rootDir, line.trim())))
+    }
+
+    /*
+     Updates the recorded failures with the results of a run
+     @param rootDir Test root directory
+     @param results Results of the tests that ran
+     */
+    static async save(rootDir: string, results: TestResult[]): Promise<void> {
+        const failures = (await this.load(rootDir)) || new Set<string>()
+        const passed = (result: TestResult) =>
+            result.status === TestStatus.Passed ||
+            result.status === TestStatus.Skipped ||
+            result.status === TestStatus.XFail
+
+        // Cases of a parameterized test share its path, so the path is kept if any case failed
+        for (const result of results.filter(passed)) {
+            failures.delete(result.file.path)
+        }
+        for (const result of results.filter((result) => !passed(result))) {
+            failures.add(result.file.path)
+        }
+        const lines = [...failures].map((path) => relative(rootDir, path).replace(/\\/g, '/')).sort()
+        await mkdir(join(rootDir, '.testme'), {recursive: true})
+        await writeFile(this.getPath(rootDir), lines.map((line) => line + '\n').join(''))
+    }
+}
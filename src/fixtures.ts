@@ -0,0 +1,113 @@
+import type {TestConfig, TestFile} from './types.ts'
+import {ShellDetector} from './platform/shell.ts'
+import {basename, join} from 'path'
+import {existsSync} from 'node:fs'
+
+/*
+ Setup and teardown commands for the tests in one directory. Commands are command lines from the
+ setup and teardown configuration keys, or the paths of setup.tst.* and teardown.tst.* scripts.
+ */
+export type DirectoryFixtures = {
+    directory: string
+    setup: string[]
+    teardown: string[]
+}
+
+/*
+ Tests that run together between the same directory fixtures (none for tests without fixtures)
+ */
+export type FixtureBatch = {
+    fixtures?: DirectoryFixtures
+    tests: TestFile[]
+}
+
+/*
+ File names of fixture scripts, which are never run as tests
+ */
+const FIXTURE_NAME = /^(setup|teardown)\.tst\.[a-z]+$/i
+
+/*
+ Fixtures - Per-directory setup and teardown
+
+ A directory's setup.tst.sh (setup.tst.ps1 or setup.tst.bat on Windows) runs before the tests in
+ that directory and teardown.tst.sh runs after them. The setup and teardown keys of a configuration
+ file other than the root configuration run around all the tests of that configuration group.
+ Setup prints KEY=VALUE lines to export environment variables to the tests and the teardown.
+ */
+export class Fixtures {
+    /*
+     Checks if a file is a fixture script rather than a test
+     @param path File name or path
+     @returns True for setup.tst.* and teardown.tst.* shell scripts
+     */
+    static isFixture(path: string): boolean {
+        const name = basename(path)
+        return FIXTURE_NAME.test(name) && ShellDetector.isShellScript(name)
+    }
+
+    /*
+     Gets the fixtures from the setup and teardown keys of a configuration
+     @param config Group configuration
+     @returns Fixtures, or undefined if neither key is set
+     */
+    static fromConfig(config: TestConfig): DirectoryFixtures | undefined {
+        const setup = this.getCommands(config.setup)
+        const teardown = this.getCommands(config.teardown)
+        if (setup.length === 0 && teardown.length === 0) {
+            return undefined
+        }
+        return {directory: config.configDir || process.cwd(), setup, teardown}
+    }
+
+    /*
+     Finds the fixture scripts in a directory
+     @param directory Test directory
+     @returns Fixtures, or undefined if the directory has no setup or teardown script
+     */
+    static find(directory: string): DirectoryFixtures | undefined {
+        const script = (name: string) =>
+            ShellDetector.getSupportedExtensions()
+                .map((ext) => join(directory, `${name}.tst${ext}`))
+                .find((path) => existsSync(path))
+        const setup = script('setup')
+        const teardown = script('teardown')
+        if (!setup && !teardown) {
+            return undefined
+        }
+        return {directory, setup: setup ? [setup] : [], teardown: teardown ? [teardown] : []}
+    }
+
+    /*
+     Splits tests into batches that share directory fixtures
+     Tests in directories without fixture scripts run together. Batches keep the order in which
+     their first test appears so shuffled and sharded selections stay in order.
+     @param tests Tests of one configuration group
+     @returns Batches in order
+     */
+    static getBatches(tests: TestFile[]): FixtureBatch[] {
+        const batches = new Map<string, FixtureBatch>()
+        const found = new Map<string, DirectoryFixtures | undefined>()
+        for (const test of tests) {
+            if (!found.has(test.directory)) {
+                found.set(test.directory, this.find(test.directory))
+            }
+            const fixtures = found.get(test.directory)
+            const key = fixtures ? test.directory : ''
+            if (!batches.has(key)) {
+                batches.set(key, {fixtures, tests: []})
+            }
+            batches.get(key)!.tests.push(test)
+        }
+        return [...batches.values()]
+    }
+
+    /*
+     Normalizes a setup or teardown value to a list of non-empty commands
+     @param value Command or list of commands
+     @returns Commands in order
+     */
+    static getCommands(value?: string | string[]): string[] {
+        const commands = Array.isArray(value) ? value : value ? [value] : []
+        return commands.filter((command) => command.trim() !== '')
+    }
+}
@@ -4,13 +4,41 @@ import {GlobExpansion} from '../utils/glob-expansion.ts'
 import {ErrorMessages} from '../utils/error-messages.ts'
 import {PlatformDetector} from '../platform/detector.ts'
 import {countAssertions} from '../utils/assertion-counter.ts'
-import {resolve} from 'path'
+import {EventStream} from '../events.ts'
+import {ProcessManager} from '../platform/process.ts'
+import {DryRun} from '../utils/dry-run.ts'
+import {loadDotEnv} from '../utils/dotenv.ts'
+import {Remote} from '../remote.ts'
+import {Docker} from '../docker.ts'
+import {TestPorts} from '../ports.ts'
+import {ConfigManager} from '../config.ts'
+import {getTruncationMarker, parseByteSize, takeOutput} from '../utils/output-limit.ts'
+import {createRedactFilter, getRedactPattern, redact} from '../utils/redact.ts'
+import {describeSignal} from '../utils/crash.ts'
+import type {ContainerSpec} from '../docker.ts'
+import {basename, relative, resolve} from 'path'
+
+/*
+ Exit code and output of a command run by a handler
+ */
+export type CommandResult = {
+    exitCode: number
+    stdout: string
+    stderr: string
+    timedOut?: boolean // Killed by the test timeout
+    idle?: boolean // Killed by the idle timeout after producing no output
+    signal?: NodeJS.Signals // Signal that killed the command (not set when a timeout killed it)
+    truncated?: boolean // Output beyond output.maxBytes was discarded
+}
 
 /*
  Abstract base class for all test handlers
  Provides common functionality for running commands and measuring execution time
  */
 export abstract class BaseTestHandler implements TestHandler {
+    // Raw output and command line of the most recent command (handlers are created fresh for each test)
+    protected lastOutput: (Omit<CommandResult, 'exitCode'> & {command?: string}) | null = null
+
     /*
      Determines if this handler can execute the given test file
      @param file Test file to check
@@ -45,9 +73,13 @@ export abstract class BaseTestHandler implements TestHandler {
 
     /*
      Executes a system command with timeout and environment options
+     Records the raw stdout/stderr so createTestResult can report the streams separately
+     With --dry-run the command is printed and reported as successful without being run
+     With a container (see Docker.getContainer), the command is run by "docker run" with env passed by -e
      @param command Command to execute
      @param args Command arguments
-     @param options Execution options (cwd, timeout, env, config for live streaming, description for error messages)
+     @param options Execution options (cwd, timeout, env, stdin file, config for live streaming, description for
+         errors, container to run in)
      @returns Promise resolving to command execution results
      */
     protected async runCommand(
@@ -57,10 +89,192 @@ export abstract class BaseTestHandler implements TestHandler {
             cwd?: string
             timeout?: number
             env?: Record<string, string>
+            stdin?: string
             config?: TestConfig
             description?: string
+            container?: ContainerSpec
         } = {}
-    ): Promise<{exitCode: number; stdout: string; stderr: string}> {
+    ): Promise<CommandResult> {
+        if (options.container) {
+            const {container, env, ...rest} = options
+            const name = Docker.getName()
+            const dockerArgs = Docker.getRunArgs(container, name, command, args, {
+                cwd: options.cwd,
+                env,
+                stdin: !!options.stdin,
+            })
+            const result = await this.runCommand('docker', dockerArgs, rest)
+            if (result.timedOut) {
+                // Killing the docker client leaves the container running
+                await Bun.spawn(['docker', 'kill', name], {stdout: 'ignore', stderr: 'ignore'}).exited
+            }
+            return result
+        }
+        if (DryRun.isEnabled()) {
+            const {cwd, env, stdin} = options
+            DryRun.print(options.description || command, command, args, {cwd, env, stdin})
+            return {exitCode: 0, stdout: '', stderr: ''}
+        }
+        const result = await this.spawnCommand(command, args, options)
+        const {stdout, stderr, timedOut, idle, signal, truncated} = result
+        const line = DryRun.formatCommand(command, args, {cwd: options.cwd, stdin: options.stdin})
+        this.lastOutput = {stdout, stderr, timedOut, idle, signal, truncated, command: line}
+        return result
+    }
+
+    /*
+     Runs a debugger interactively on the terminal (--debug)
+     The debugger inherits stdin, stdout and stderr and runs in the test's working directory with the environment
+     the test would have run with, so a session reproduces the test run without rebuilding it by hand.
+     @param command Debugger command
+     @param args Debugger arguments
+     @param file Test file being debugged
+     @param config Test configuration
+     @param env Test environment
+     @returns Exit code of the debugger
+     */
+    protected async runDebugger(
+        command: string,
+        args: string[],
+        file: TestFile,
+        config: TestConfig,
+        env: Record<string, string>
+    ): Promise<number> {
+        const proc = Bun.spawn([command, ...args], {
+            cwd: BaseTestHandler.getWorkingDirectory(config, file),
+            env: this.getSpawnEnvironment(env),
+            stdin: 'inherit',
+            stdout: 'inherit',
+            stderr: 'inherit',
+        })
+        return await proc.exited
+    }
+
+    /*
+     Gets the timeout for a test in milliseconds
+     Per-test entries in execution.timeouts (keyed by test file name or a glob relative to the
+     config directory) override execution.timeout. A timeout of 0 disables the timeout.
+     @param config Test configuration
+     @param file Test file being executed
+     @returns Timeout in milliseconds, or undefined for no timeout
+     */
+    static getTimeout(config: TestConfig, file: TestFile): number | undefined {
+        let seconds = config.execution?.timeout ?? 30
+        const timeouts = config.execution?.timeouts
+        if (timeouts) {
+            const relativePath = relative(config.configDir || file.directory, file.path).replace(/\\/g, '/')
+            for (const [pattern, value] of Object.entries(timeouts)) {
+                if (pattern === basename(file.path) || new Bun.Glob(pattern).match(relativePath)) {
+                    seconds = value
+                    break
+                }
+            }
+        }
+        return seconds > 0 ? seconds * 1000 : undefined
+    }
+
+    /*
+     Gets the idle timeout for a test in milliseconds
+     A test that writes nothing to stdout or stderr for this long is killed. Only test commands, which
+     are run with their configuration, are watched, so long silent compilations are not affected.
+     @param config Test configuration with execution.idleTimeout in seconds
+     @returns Idle timeout in milliseconds, or undefined if output inactivity is not watched
+     */
+    static getIdleTimeout(config?: TestConfig): number | undefined {
+        const seconds = config?.execution?.idleTimeout ?? 0
+        return seconds > 0 ? seconds * 1000 : undefined
+    }
+
+    /*
+     Gets the directory a test runs in
+     This is execution.chdir (from the config or a chdir directive) if set, otherwise the test's temporary directory.
+     Without either, such as when a handler is used directly, it is the test's own directory.
+     @param config Test configuration
+     @param file Test file being executed
+     @returns Absolute working directory
+     */
+    static getWorkingDirectory(config: TestConfig, file: TestFile): string {
+        return config.execution?.chdir || config.execution?.tmpDir || file.directory
+    }
+
+    /*
+     Runs a compiled test binary on the remote host (--remote or remote.host)
+     The binary is copied to a staging directory with scp, run over ssh and the directory removed.
+     The remote side enforces the test timeout; the local timeout is a backstop for a hung connection.
+     @param binaryPath Local path of the compiled test
+     @param file Test file
+     @param config Test configuration with remote settings
+     @returns Exit code and output of the test on the remote host
+     */
+    protected async runRemote(
+        binaryPath: string,
+        file: TestFile,
+        config: TestConfig
+    ): Promise<CommandResult> {
+        const host = Remote.getHost(config)!
+        const options = Remote.getOptions(config)
+        const stage = Remote.getStageDir(config, file)
+        const timeout = BaseTestHandler.getTimeout(config, file)
+        let result: CommandResult
+        let testCommand: string | undefined
+
+        result = await this.runCommand('ssh', [...options, host, `mkdir -p ${DryRun.quote(stage)}`], {
+            timeout: 60000,
+            description: `Remote staging for ${file.name}`,
+        })
+        if (result.exitCode !== 0) {
+            return {...result, stderr: `Cannot create ${stage} on ${host}: ${result.stderr.trim()}`}
+        }
+        try {
+            result = await this.runCommand('scp', [...options, '-q', binaryPath, `${host}:${stage}/`], {
+                timeout: 300000,
+                description: `Copy of ${file.name} to ${host}`,
+            })
+            if (result.exitCode !== 0) {
+                result = {...result, stderr: `Cannot copy ${binaryPath} to ${host}:${stage}: ${result.stderr.trim()}`}
+            } else {
+                const args = config.execution?.args || []
+                const command = Remote.getCommand(stage, basename(binaryPath), args, config.remote?.env || {}, timeout)
+                result = await this.runCommand('ssh', [...options, host, command], {
+                    cwd: file.directory,
+                    timeout: timeout && timeout + 30000,
+                    stdin: config.execution?.stdin,
+                    config,
+                    description: `Test ${file.name} on ${host}`,
+                })
+                if (timeout && Remote.isTimeout(result.exitCode)) {
+                    result.timedOut = true
+                }
+                testCommand = this.lastOutput?.command
+            }
+        } finally {
+            await this.runCommand('ssh', [...options, host, `rm -rf ${DryRun.quote(stage)}`], {
+                timeout: 60000,
+                description: `Remote cleanup for ${file.name}`,
+            })
+        }
+        // Report the test's output and command rather than those of the cleanup command
+        const {stdout, stderr, timedOut, idle, signal, truncated} = result
+        this.lastOutput = {stdout, stderr, timedOut, idle, signal, truncated, command: testCommand}
+        return result
+    }
+
+    /*
+     Gets the cap on the output retained for a test command
+     @param config Test configuration with output.maxBytes (a byte count or a size such as "10MB")
+     @returns Cap in bytes, or null if output is not capped
+     */
+    private getMaxOutputBytes(config?: TestConfig): number | null {
+        const value = config?.output?.maxBytes
+        return value === undefined ? null : parseByteSize(value)
+    }
+
+    /*
+     Builds the environment of a spawned command: the TestMe environment with the test's variables merged over it
+     @param env Test environment
+     @returns Environment for Bun.spawn
+     */
+    private getSpawnEnvironment(env: Record<string, string> = {}): Record<string, string> {
         // Build environment - be defensive about PATH handling on Windows
         const spawnEnv: Record<string, string> = {}
 
@@ -71,13 +285,13 @@ export abstract class BaseTestHandler implements TestHandler {
             }
         }
 
-        // Merge options.env
-        for (const [key, value] of Object.entries(options.env || {})) {
+        // Merge the test environment
+        for (const [key, value] of Object.entries(env)) {
             spawnEnv[key] = value
         }
 
         // On Windows, if we have a custom PATH, ensure it completely replaces any variants
-        if (PlatformDetector.isWindows() && options.env?.PATH) {
+        if (PlatformDetector.isWindows() && env.PATH) {
             // Remove any case variants from process.env, keep only our uppercase PATH
             for (const key of Object.keys(spawnEnv)) {
                 if (key !== 'PATH' && key.toUpperCase() === 'PATH') {
@@ -85,31 +299,82 @@ export abstract class BaseTestHandler implements TestHandler {
                 }
             }
         }
+        return spawnEnv
+    }
 
+    /*
+     Spawns a system command and collects its output
+     @param command Command to execute
+     @param args Command arguments
+     @param options Execution options (see runCommand)
+     @returns Promise resolving to command execution results
+     */
+    private async spawnCommand(
+        command: string,
+        args: string[],
+        options: {
+            cwd?: string
+            timeout?: number
+            env?: Record<string, string>
+            stdin?: string
+            config?: TestConfig
+            description?: string
+        }
+    ): Promise<CommandResult> {
+        const spawnEnv = this.getSpawnEnvironment(options.env)
+        // Secrets are replaced as output is read, before it is printed, streamed or retained
+        const secrets = getRedactPattern(options.config?.redact, spawnEnv)
+
+        /*
+            On Unix, run the command in its own process group (detached) so a timeout can kill
+            the whole group, including any subprocesses the test started. On Windows the process
+            is tracked in a job object, which does the same for its process tree.
+         */
+        const detached = !PlatformDetector.isWindows()
+        const pipeStdin = PlatformDetector.isWindows() && !options.stdin
         const proc = Bun.spawn([command, ...args], {
             cwd: options.cwd,
             env: spawnEnv,
             stdout: 'pipe',
             stderr: 'pipe',
-            stdin: PlatformDetector.isWindows() ? 'pipe' : 'ignore',
+            stdin: options.stdin ? Bun.file(options.stdin) : pipeStdin ? 'pipe' : 'ignore',
+            detached,
         })
 
         // On Windows, close stdin pipe immediately to prevent process from waiting for input
-        if (PlatformDetector.isWindows() && proc.stdin) {
+        if (pipeStdin && proc.stdin) {
             proc.stdin.end()
         }
+        ProcessManager.trackProcessGroup(proc.pid)
+        proc.exited.then(() => ProcessManager.untrackProcessGroup(proc.pid))
 
         let timeoutId: Timer | undefined
         let timedOut = false
 
-        // Set up timeout if specified
+        // Set up timeout if specified - kill the process group so orphaned subprocesses don't linger
         if (options.timeout) {
             timeoutId = setTimeout(() => {
                 timedOut = true
-                proc.kill()
+                ProcessManager.killProcessGroup(proc.pid)
             }, options.timeout)
         }
 
+        // The idle timeout restarts whenever the test writes to stdout or stderr
+        const idleTimeout = BaseTestHandler.getIdleTimeout(options.config)
+        let idleId: Timer | undefined
+        let idle = false
+        const resetIdle = () => {
+            if (!idleTimeout || timedOut || idle) {
+                return
+            }
+            clearTimeout(idleId)
+            idleId = setTimeout(() => {
+                idle = true
+                ProcessManager.killProcessGroup(proc.pid)
+            }, idleTimeout)
+        }
+        resetIdle()
+
         try {
             // Check if live streaming is enabled
             // When user explicitly requests monitor mode (-m/--monitor), honor it regardless of TTY status
@@ -118,35 +383,77 @@ export abstract class BaseTestHandler implements TestHandler {
             let stdout = ''
             let stderr = ''
 
-            if (shouldStream) {
+            // Output retained beyond output.maxBytes (shared by stdout and stderr) is discarded
+            const maxBytes = this.getMaxOutputBytes(options.config)
+            let retained = 0
+            let truncated = false
+
+            // Read output incrementally when streaming to the console or to the event feed, or to cap or watch it
+            if (shouldStream || EventStream.isEnabled() || maxBytes !== null || idleTimeout) {
                 // Stream output in real-time while also buffering
                 const stdoutReader = proc.stdout.getReader()
                 const stderrReader = proc.stderr.getReader()
-                const decoder = new TextDecoder()
 
                 const readStream = async (
                     reader: ReadableStreamDefaultReader<Uint8Array>,
                     isStderr: boolean
                 ): Promise<string> => {
+                    const decoder = new TextDecoder()
+                    const filter = secrets ? createRedactFilter(secrets) : null
                     let buffer = ''
+                    let capped = false
+
+                    const emit = (text: string, bytes: Uint8Array) => {
+                        if (maxBytes === null) {
+                            buffer += text
+                        } else if (!capped) {
+                            // Keep reading past the cap so the test is never blocked on a full pipe
+                            const kept = takeOutput(bytes, maxBytes - retained)
+                            retained += kept.length
+                            buffer += kept === bytes ? text : new TextDecoder().decode(kept)
+                            capped = kept !== bytes
+                        }
+
+                        EventStream.emit('test-output', {stream: isStderr ? 'stderr' : 'stdout', data: text})
+
+                        // Stream to console in real-time
+                        if (!shouldStream) {
+                            return
+                        }
+                        if (isStderr) {
+                            process.stderr.write(text)
+                        } else {
+                            process.stdout.write(text)
+                        }
+                    }
+
                     try {
                         while (true) {
                             const {done, value} = await reader.read()
                             if (done) break
+                            resetIdle()
 
                             const text = decoder.decode(value, {stream: true})
-                            buffer += text
-
-                            // Stream to console in real-time
-                            if (isStderr) {
-                                process.stderr.write(text)
-                            } else {
-                                process.stdout.write(text)
+                            if (!filter) {
+                                emit(text, value)
+                                continue
+                            }
+                            const clean = filter(text)
+                            if (clean) {
+                                emit(clean, new TextEncoder().encode(clean))
                             }
                         }
+                        const rest = filter?.()
+                        if (rest) {
+                            emit(rest, new TextEncoder().encode(rest))
+                        }
                     } finally {
                         reader.releaseLock()
                     }
+                    if (capped) {
+                        truncated = true
+                        buffer += getTruncationMarker(maxBytes!)
+                    }
                     return buffer
                 }
 
@@ -163,14 +470,28 @@ export abstract class BaseTestHandler implements TestHandler {
                 if (timeoutId) {
                     clearTimeout(timeoutId)
                 }
+                clearTimeout(idleId)
 
                 if (timedOut) {
-                    const timeoutSeconds = Math.round((options.timeout || 0) / 1000)
+                    const timeoutSeconds = (options.timeout || 0) / 1000
                     const description = options.description || `${command} ${args.join(' ')}`
                     return {
                         exitCode: -1,
                         stdout,
                         stderr: stderr + `\n${description} timed out after ${timeoutSeconds}s`,
+                        timedOut: true,
+                        ...(truncated && {truncated}),
+                    }
+                }
+
+                if (idle) {
+                    const description = options.description || `${command} ${args.join(' ')}`
+                    return {
+                        exitCode: -1,
+                        stdout,
+                        stderr: stderr + `\n${description} produced no output for ${idleTimeout! / 1000}s`,
+                        idle: true,
+                        ...(truncated && {truncated}),
                     }
                 }
 
@@ -178,6 +499,8 @@ export abstract class BaseTestHandler implements TestHandler {
                     exitCode: result,
                     stdout,
                     stderr,
+                    ...(proc.signalCode && {signal: proc.signalCode}),
+                    ...(truncated && {truncated}),
                 }
             } else {
                 // Original buffered mode - read all at once
@@ -187,20 +510,21 @@ export abstract class BaseTestHandler implements TestHandler {
                     new Response(proc.stderr).text(),
                 ])
 
-                stdout = stdoutText
-                stderr = stderrText
+                stdout = redact(stdoutText, secrets)
+                stderr = redact(stderrText, secrets)
 
                 if (timeoutId) {
                     clearTimeout(timeoutId)
                 }
 
                 if (timedOut) {
-                    const timeoutSeconds = Math.round((options.timeout || 0) / 1000)
+                    const timeoutSeconds = (options.timeout || 0) / 1000
                     const description = options.description || `${command} ${args.join(' ')}`
                     return {
                         exitCode: -1,
                         stdout,
                         stderr: stderr + `\n${description} timed out after ${timeoutSeconds}s`,
+                        timedOut: true,
                     }
                 }
 
@@ -208,12 +532,14 @@ export abstract class BaseTestHandler implements TestHandler {
                     exitCode: result,
                     stdout,
                     stderr,
+                    ...(proc.signalCode && {signal: proc.signalCode}),
                 }
             }
         } catch (error) {
             if (timeoutId) {
                 clearTimeout(timeoutId)
             }
+            clearTimeout(idleId)
 
             // Provide helpful error messages for common failures
             let errorMessage = `Failed to execute command: ${error}`
@@ -273,8 +599,8 @@ Original error: ${error}`
     ): Promise<Record<string, string>> {
         const env: Record<string, string> = {}
 
-        // Set TESTME_VERBOSE (always set to 0 or 1)
-        env.TESTME_VERBOSE = config.output?.verbose === true ? '1' : '0'
+        // Set TESTME_VERBOSE (always set to 0 or 1, and 1 for --verbose-on-fail so failures have full detail)
+        env.TESTME_VERBOSE = config.output?.verbose === true || config.output?.verboseOnFail === true ? '1' : '0'
 
         // Set TESTME_QUIET (always set to 0 or 1)
         env.TESTME_QUIET = config.output?.quiet === true ? '1' : '0'
@@ -285,10 +611,8 @@ Original error: ${error}`
         // Set TESTME_STOP (always set to 0 or 1)
         env.TESTME_STOP = config.execution?.stopOnFailure === true ? '1' : '0'
 
-        // Set TESTME_DEPTH if depth is specified
-        if (config.execution?.depth !== undefined) {
-            env.TESTME_DEPTH = config.execution.depth.toString()
-        }
+        // Set TESTME_DEPTH (always set, 0 without --depth and capped by maxDepth)
+        env.TESTME_DEPTH = ConfigManager.getDepth(config).toString()
 
         // Set TESTME_ITERATIONS (default to 1 if not specified)
         env.TESTME_ITERATIONS = (config.execution?.iterations ?? 1).toString()
@@ -303,6 +627,19 @@ Original error: ${error}`
             env.TESTME_CLASS = config.execution.testClass
         }
 
+        // Set TESTME_BENCH in bench mode so tests can run their benchmarks
+        if (config.execution?.bench) {
+            env.TESTME_BENCH = '1'
+        }
+
+        // Set TESTME_TMP to the test's private temporary directory
+        if (config.execution?.tmpDir !== undefined) {
+            env.TESTME_TMP = config.execution.tmpDir
+        }
+
+        // Set TESTME_PORT0, TESTME_PORT1, ... for ports allocated by the ports directive
+        Object.assign(env, TestPorts.getEnvironment(config.execution?.ports || []))
+
         // Create special variables for expansion if we have file context
         let specialVars
         if (file) {
@@ -324,6 +661,9 @@ Original error: ${error}`
             if (specialVars.CONFIGDIR !== undefined) env.TESTME_CONFIGDIR = specialVars.CONFIGDIR
         }
 
+        // Add variables from the .env file, beneath the configured environment
+        Object.assign(env, await loadDotEnv(config))
+
         // Add environment variables from configuration with expansion
         // Support both 'environment' (new) and 'env' (legacy) keys
         const configEnv = config.environment || config.env
@@ -337,8 +677,8 @@ Original error: ${error}`
 
             // First, process base environment variables (exclude platform keys)
             for (const [key, value] of Object.entries(configEnv)) {
-                // Skip platform-specific section keys (legacy format)
-                if (key === 'windows' || key === 'macosx' || key === 'linux' || key === 'default') {
+                // Skip platform-specific section keys (legacy format) and the .env file path
+                if (key === 'windows' || key === 'macosx' || key === 'linux' || key === 'default' || key === 'file') {
                     continue
                 }
 
@@ -435,6 +775,11 @@ Original error: ${error}`
             }
         }
 
+        // Fields of a data-driven test case
+        if (file?.testCase) {
+            Object.assign(env, file.testCase.env)
+        }
+
         return env
     }
 
@@ -500,9 +845,22 @@ Original error: ${error}`
         error?: string,
         exitCode?: number
     ): TestResult {
-        // Count assertions in output (✓ and ✗ symbols from test macros)
+        // Count assertions in output (✓ and ✗ symbols and PASS/FAIL lines from test macros)
         const assertions = countAssertions(output)
 
+        // A test killed by its timeout is reported distinctly from a test that failed
+        if (status === TestStatus.Failed && this.lastOutput?.timedOut) {
+            status = TestStatus.Timeout
+        } else if (status === TestStatus.Failed && this.lastOutput?.idle) {
+            status = TestStatus.IdleTimeout
+        }
+
+        // Name the signal that killed the test ahead of its own error output
+        const signal = status === TestStatus.Failed ? this.lastOutput?.signal : undefined
+        if (signal) {
+            error = [describeSignal(signal), error].filter((text) => text).join('\n')
+        }
+
         return {
             file,
             status,
@@ -511,6 +869,11 @@ Original error: ${error}`
             error,
             exitCode,
             assertions: assertions || undefined,
+            stdout: this.lastOutput?.stdout,
+            stderr: this.lastOutput?.stderr,
+            ...(this.lastOutput?.command && {command: this.lastOutput.command}),
+            ...(signal && {signal}),
+            ...(this.lastOutput?.truncated && {truncated: true}),
         }
     }
 
@@ -587,14 +950,16 @@ Original error: ${error}`
             return
         }
 
+        // Secrets named by the redact config are hidden here as they are in test output
+        const secrets = getRedactPattern(config.redact, {...process.env, ...testEnv})
         console.log(`📄 Config used for ${file.name}:`)
-        console.log(this.formatConfig(config))
+        console.log(redact(this.formatConfig(config), secrets))
 
         // Show environment variables defined by TestMe
         if (Object.keys(testEnv).length > 0) {
             console.log(`\n🌍 TestMe environment variables:`)
             for (const [key, value] of Object.entries(testEnv)) {
-                console.log(`   ${key}=${value}`)
+                console.log(`   ${key}=${redact(value, secrets)}`)
             }
         }
 
@@ -603,7 +968,7 @@ Original error: ${error}`
             console.log(`\n🌍 Full environment (${Object.keys(process.env).length} variables):`)
             const sortedKeys = Object.keys(process.env).sort()
             for (const key of sortedKeys) {
-                console.log(`   ${key}=${process.env[key]}`)
+                console.log(`   ${key}=${redact(process.env[key] || '', secrets)}`)
             }
         }
     }
@@ -624,7 +989,7 @@ Original error: ${error}`
                 workers: config.execution?.workers,
                 keepArtifacts: config.execution?.keepArtifacts,
                 stepMode: config.execution?.stepMode,
-                depth: config.execution?.depth,
+                depth: ConfigManager.getDepth(config),
                 debugMode: config.execution?.debugMode,
                 showCommands: config.execution?.showCommands,
                 iterations: config.execution?.iterations,
@@ -1,4 +1,4 @@
-import type {TestFile, TestResult, TestConfig} from '../types.ts'
+import type {BuildResult, CoverageConfig, CoverageReport, TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
 import {ArtifactManager} from '../artifacts.ts'
@@ -7,8 +7,19 @@ import {CompilerManager, CompilerType} from '../platform/compiler.ts'
 import {PermissionManager} from '../platform/permissions.ts'
 import {PlatformDetector} from '../platform/detector.ts'
 import {ErrorMessages} from '../utils/error-messages.ts'
-import {basename, resolve, isAbsolute, join} from 'path'
-import {stat} from 'fs/promises'
+import {countChecks} from '../utils/assertion-counter.ts'
+import {DryRun} from '../utils/dry-run.ts'
+import {applySanitizerReport, getSanitizerFlags, getSanitizerOptions} from '../utils/sanitizer.ts'
+import {findCoreFile, getBacktrace, isCrashSignal} from '../utils/crash.ts'
+import {getPackageFlags} from '../utils/pkg-config.ts'
+import {checkBuild, hashCommand, saveBuild} from '../utils/build-cache.ts'
+import {CrossTarget} from '../target.ts'
+import {Remote} from '../remote.ts'
+import {Docker} from '../docker.ts'
+import type {PackageFlags} from '../utils/pkg-config.ts'
+import {basename, resolve, isAbsolute, join, relative} from 'path'
+import {readFile, readdir, rm, writeFile} from 'fs/promises'
+import {existsSync, readdirSync} from 'fs'
 import os from 'os'
 
 /*
@@ -16,6 +27,9 @@ import os from 'os'
  Compiles C source to binary in artifact directory, then executes
  */
 export class CTestHandler extends BaseTestHandler {
+    // Artifact directories holding gcov data (.gcno/.gcda) collected during a --coverage run
+    private static coverageDirs: Set<string> = new Set()
+
     private artifactManager: ArtifactManager
 
     /*
@@ -44,6 +58,17 @@ export class CTestHandler extends BaseTestHandler {
         await this.artifactManager.createArtifactDir(file)
     }
 
+    /*
+     Compiles a C test ahead of running it (--build-workers). The test then runs the cached binary.
+     @param file C test file to compile
+     @param config Test configuration with compiler settings
+     @returns Compilation result
+     */
+    async build(file: TestFile, config: TestConfig): Promise<BuildResult> {
+        const {success, duration, output, error} = await this.compile(file, config)
+        return {success, duration, output, error}
+    }
+
     /*
      Compiles and executes C test, returning combined results
      @param file C test file to execute
@@ -68,25 +93,196 @@ export class CTestHandler extends BaseTestHandler {
             return await this.launchDebugger(file, config, compileResult.duration, compileResult.compiler)
         }
 
-        // Normal execution
+        const binaryPath = this.getBinaryPath(file, config)
+        const asan = config.execution?.asan === true
+        const valgrind = config.valgrind?.enable === true
+        if (valgrind && asan) {
+            return this.createTestResult(
+                file,
+                TestStatus.Error,
+                compileResult.duration,
+                '',
+                'Valgrind cannot be used with AddressSanitizer builds'
+            )
+        }
+        // Binaries built for another platform run on the remote host, in the container or through target.runner,
+        // or are only built
+        const remote = Remote.getHost(config)
+        const container = Docker.getContainer(config, file)
+        const triple = CrossTarget.getTriple(config)
+        const cross = triple !== undefined && !CrossTarget.isNative(CrossTarget.parse(triple))
+        const runner = cross && !remote && !container ? CrossTarget.getRunner(config) : []
+        if (cross && !remote && !container && runner.length === 0) {
+            const output = `Built for ${triple}, set target.runner to run it`
+            return {...this.createTestResult(file, TestStatus.Skipped, compileResult.duration, output), target: triple}
+        }
+        if (valgrind && (cross || remote)) {
+            return this.createTestResult(
+                file,
+                TestStatus.Error,
+                compileResult.duration,
+                '',
+                `Valgrind cannot be used ${remote ? 'with --remote' : 'when cross-compiling'}`
+            )
+        }
+        if (valgrind && PlatformDetector.isWindows()) {
+            return this.createTestResult(
+                file,
+                TestStatus.Error,
+                compileResult.duration,
+                '',
+                'Valgrind is not supported on Windows'
+            )
+        }
+
+        if (valgrind) {
+            // Remove any log from a previous run so a failed launch is not mistaken for its report
+            await rm(this.getValgrindLogPath(file), {force: true})
+        }
+
+        // Coverage counters accumulate in .gcda files, so start each run from zero (MSVC is not supported)
+        if (config.coverage?.enable && compileResult.compiler !== 'msvc') {
+            const gcda = (await readdir(file.artifactDir)).filter((name) => name.endsWith('.gcda'))
+            await Promise.all(gcda.map((name) => rm(join(file.artifactDir, name), {force: true})))
+            CTestHandler.coverageDirs.add(file.artifactDir)
+        }
+
+        // Normal execution, optionally wrapped by valgrind or the target runner, or on the remote host
+        const testArgs = config.execution?.args || []
+        const cwd = BaseTestHandler.getWorkingDirectory(config, file)
+        const env = await this.getTestEnvironment(config, file, compileResult.compiler)
+        const started = Date.now()
         const {result, duration} = await this.measureExecution(async () => {
-            const binaryPath = this.getBinaryPath(file)
+            if (remote) {
+                return await this.runRemote(binaryPath, file, config)
+            }
+            const [command, ...args] = valgrind
+                ? ['valgrind', ...this.getValgrindArgs(file, config, binaryPath), ...testArgs]
+                : [...runner, binaryPath, ...testArgs]
+            if (asan) {
+                env.ASAN_OPTIONS = getSanitizerOptions(env.ASAN_OPTIONS ?? process.env.ASAN_OPTIONS)
+            }
 
-            return await this.runCommand(binaryPath, [], {
-                cwd: file.directory, // Always run test with CWD set to test directory
-                timeout: (config.execution?.timeout || 30) * 1000,
-                env: await this.getTestEnvironment(config, file, compileResult.compiler),
+            return await this.runCommand(command!, args, {
+                cwd,
+                timeout: BaseTestHandler.getTimeout(config, file),
+                env,
+                stdin: config.execution?.stdin,
                 config,
                 description: `Test ${file.name}`,
+                container,
             })
         })
 
         const totalDuration = compileResult.duration + duration
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutputs(compileResult.output, result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
+        let status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        let output = this.combineOutputs(compileResult.output, result.stdout, result.stderr)
+        let error = result.exitCode !== 0 ? result.stderr : undefined
+
+        // With --backtrace, a crashed program that ran locally is examined with gdb or lldb
+        let backtrace: string | null = null
+        const direct = !remote && !container && !valgrind && runner.length === 0
+        if (config.execution?.backtrace && direct && result.signal && isCrashSignal(result.signal)) {
+            const core = findCoreFile(cwd, started)
+            const stdin = config.execution?.stdin
+            backtrace = (await getBacktrace(binaryPath, testArgs, {cwd, env, stdin, core})) ?? 'No gdb or lldb found'
+            output = `${output}\n\nBACKTRACE:\n${backtrace}`.trim()
+        }
+
+        // Valgrind errors and leaks fail the test even if the test itself exited cleanly
+        if (valgrind) {
+            const report = await this.readValgrindLog(file)
+            const errors = CTestHandler.countValgrindErrors(report)
+            if (errors > 0) {
+                status = TestStatus.Failed
+                output = `${output}\n\nVALGRIND:\n${report}`.trim()
+                error = [`Valgrind reported ${errors} error(s), see ${this.getValgrindLogPath(file)}`, error]
+                    .filter((text) => text)
+                    .join('\n')
+            }
+        }
+
+        // A failed soft assertion fails the test even if it did not call treport() to exit non-zero
+        const failedChecks = countChecks(output)?.failed || 0
+        if (status === TestStatus.Passed && failedChecks > 0 && (await CTestHandler.includesHeader(file))) {
+            status = TestStatus.Failed
+            error = [error, `${failedChecks} assertion(s) failed`].filter((text) => text).join('\n')
+        }
+
+        const testResult = this.createTestResult(file, status, totalDuration, output, error, result.exitCode)
+        if (triple) {
+            testResult.target = triple
+        }
+        if (backtrace) {
+            testResult.backtrace = backtrace
+            testResult.error = `${testResult.error}\n${backtrace}`
+        }
+        return asan ? applySanitizerReport(testResult) : testResult
+    }
+
+    /*
+     Builds the valgrind command line for a test binary
+     The log is written to valgrind.log in the artifact directory
+     @param file C test file
+     @param config Test configuration with valgrind settings
+     @param binaryPath Path to the compiled test binary
+     @returns Arguments for valgrind
+     */
+    getValgrindArgs(file: TestFile, config: TestConfig, binaryPath: string): string[] {
+        const args = ['--error-exitcode=1', '--leak-check=full', `--log-file=${this.getValgrindLogPath(file)}`]
+        const suppressions = config.valgrind?.suppressions
+        if (suppressions) {
+            const baseDir = config.configDir || file.directory
+            args.push(`--suppressions=${isAbsolute(suppressions) ? suppressions : resolve(baseDir, suppressions)}`)
+        }
+        args.push(...(config.valgrind?.flags || []), binaryPath)
+        return args
+    }
+
+    /*
+     Gets the path of the valgrind log for a test
+     @param file C test file
+     @returns Path to valgrind.log in the artifact directory
+     */
+    private getValgrindLogPath(file: TestFile): string {
+        return this.artifactManager.getArtifactPath(file, 'valgrind.log')
+    }
+
+    /*
+     Reads the valgrind log for a test
+     @param file C test file
+     @returns Log contents, or an empty string if valgrind did not write a log
+     */
+    private async readValgrindLog(file: TestFile): Promise<string> {
+        try {
+            return await readFile(this.getValgrindLogPath(file), 'utf-8')
+        } catch {
+            return ''
+        }
+    }
 
-        return this.createTestResult(file, status, totalDuration, output, error, result.exitCode)
+    /*
+     Counts the errors reported in a valgrind log (with --leak-check=full, leaks count as errors)
+     @param report Valgrind log contents
+     @returns Error count from the last ERROR SUMMARY line, or 0 if there is none
+     */
+    static countValgrindErrors(report: string): number {
+        const summaries = [...report.matchAll(/ERROR SUMMARY: (\d+) errors?/g)]
+        const last = summaries[summaries.length - 1]
+        return last ? parseInt(last[1]!, 10) : 0
+    }
+
+    /*
+     Checks if a C test includes testme.h, whose soft assertions print the PASS and FAIL lines
+     @param file C test file
+     @returns True if the source has an #include of testme.h
+     */
+    static async includesHeader(file: TestFile): Promise<boolean> {
+        try {
+            return /^\s*#\s*include\s*[<"](?:[^<>"]*\/)?testme\.h[>"]/m.test(await readFile(file.path, 'utf-8'))
+        } catch {
+            return false
+        }
     }
 
     /*
@@ -96,13 +292,116 @@ export class CTestHandler extends BaseTestHandler {
      @param file C test file to clean up
      */
     override async cleanup(file: TestFile): Promise<void> {
+        // Keep coverage data until it is merged at the end of the run
+        if (CTestHandler.coverageDirs.has(file.artifactDir)) {
+            return
+        }
         // Clean artifacts (only called for successful tests or when explicitly requested)
         await this.artifactManager.cleanArtifactDir(file)
     }
 
+    /*
+     Merges the C coverage collected during the run into coverage.info in the root directory
+     @param rootDir Test root directory
+     @param config Coverage configuration (for coverage.exclude)
+     @returns Merged C coverage report, or null if no C test collected coverage
+     @throws Error if lcov fails
+     */
+    async collectCoverage(rootDir: string, config: CoverageConfig): Promise<CoverageReport | null> {
+        const path = join(rootDir, 'coverage.info')
+        const percent = await CTestHandler.mergeCoverage(path, rootDir, config.exclude)
+        return percent === null ? null : {language: 'C', unit: 'lines', path, percent}
+    }
+
+    /*
+     Captures the gcov data of all C tests run with --coverage into a single lcov tracefile
+     @param outputPath Path of the lcov .info file to write (e.g., coverage.info)
+     @param rootDir Test root directory, for relative exclude patterns
+     @param exclude Glob patterns of source files to drop from the report (coverage.exclude)
+     @returns Total line coverage percentage, or null if no C coverage data was collected
+     @throws Error if lcov fails
+     */
+    static async mergeCoverage(outputPath: string, rootDir: string, exclude: string[] = []): Promise<number | null> {
+        const dirs = [...CTestHandler.coverageDirs].filter(
+            (dir) => existsSync(dir) && readdirSync(dir).some((name) => name.endsWith('.gcda'))
+        )
+        CTestHandler.coverageDirs.clear()
+        if (dirs.length === 0) {
+            return null
+        }
+        const directories = dirs.flatMap((dir) => ['--directory', dir])
+        const proc = Bun.spawn(['lcov', '--capture', '--quiet', ...directories, '--output-file', outputPath], {
+            stdout: 'pipe',
+            stderr: 'pipe',
+        })
+        const [stderr, exitCode] = await Promise.all([new Response(proc.stderr).text(), proc.exited])
+        if (exitCode !== 0) {
+            throw new Error(`lcov failed: ${stderr.trim()}`)
+        }
+        const info = CTestHandler.excludeCoverage(await readFile(outputPath, 'utf-8'), rootDir, exclude)
+        await writeFile(outputPath, info)
+        return CTestHandler.getCoveragePercent(info)
+    }
+
+    /*
+     Removes source files matching exclude patterns from an lcov tracefile
+     @param info Tracefile contents (records of SF:, DA:, ... lines ending with end_of_record)
+     @param rootDir Test root directory, patterns match either the absolute or root-relative source path
+     @param exclude Glob patterns of source files to remove
+     @returns Tracefile contents without the excluded records
+     */
+    static excludeCoverage(info: string, rootDir: string, exclude: string[]): string {
+        const globs = exclude.map((pattern) => new Bun.Glob(pattern))
+        const kept: string[] = []
+        let record: string[] = []
+        for (const line of info.split('\n')) {
+            if (!line) {
+                continue
+            }
+            record.push(line)
+            if (line === 'end_of_record') {
+                const source = (record.find((text) => text.startsWith('SF:')) || 'SF:').slice(3)
+                const relativePath = relative(rootDir, source).replace(/\\/g, '/')
+                if (!globs.some((glob) => glob.match(source) || glob.match(relativePath))) {
+                    kept.push(...record)
+                }
+                record = []
+            }
+        }
+        return kept.map((line) => line + '\n').join('')
+    }
+
+    /*
+     Computes total line coverage from an lcov tracefile
+     A line reported by more than one record (e.g., a shared source built into several tests) counts once
+     and is covered if any record has a non-zero count.
+     @param info Tracefile contents
+     @returns Percentage of instrumented lines executed, or null if there are none
+     */
+    static getCoveragePercent(info: string): number | null {
+        const lines = new Map<string, boolean>()
+        let source = ''
+        for (const line of info.split('\n')) {
+            if (line.startsWith('SF:')) {
+                source = line.slice(3)
+            } else if (line.startsWith('DA:')) {
+                const [lineNumber, count] = line.slice(3).split(',')
+                const key = `${source}:${lineNumber}`
+                lines.set(key, (lines.get(key) ?? false) || parseInt(count!, 10) > 0)
+            }
+        }
+        if (lines.size === 0) {
+            return null
+        }
+        const covered = [...lines.values()].filter((hit) => hit).length
+        return (covered / lines.size) * 100
+    }
+
     /*
      Compiles C source file to executable binary
-     Skips compilation if binary exists and is newer than source (unless --rebuild is set)
+     Skips compilation if the cached binary was built from the same source, compiler command and included headers
+     (unless --rebuild is set). Whether the binary was built, and why, or reused is in the output shown by
+     --verbose.
      @param file C test file to compile
      @param config Test configuration with compiler settings
      @returns Compilation result with success status, duration, and output
@@ -118,171 +417,38 @@ export class CTestHandler extends BaseTestHandler {
         compiler?: string
         skipped?: boolean
     }> {
-        const binaryPath = this.getBinaryPath(file)
-
-        // Check if we can skip compilation (binary exists and is newer than source)
-        if (!config.execution?.rebuild) {
-            const needsCompile = await this.needsRecompilation(file.path, binaryPath)
-            if (!needsCompile) {
-                // Get compiler info for environment setup (still needed for execution)
-                const compilerConfig = await CompilerManager.getDefaultCompilerConfig(
-                    this.resolveCompilerName(config.compiler?.c?.compiler)
-                )
-                const compilerName =
-                    compilerConfig.type === CompilerType.GCC
-                        ? 'gcc'
-                        : compilerConfig.type === CompilerType.Clang
-                          ? 'clang'
-                          : compilerConfig.type === CompilerType.MSVC
-                            ? 'msvc'
-                            : undefined
-
-                return {
-                    success: true,
-                    duration: 0,
-                    output: 'Using cached binary (source unchanged)',
-                    compiler: compilerName,
-                    skipped: true,
-                }
-            }
-        }
-
-        const {result, duration} = await this.measureExecution(async () => {
-            const baseDir = config.configDir || file.directory
+        const binaryPath = this.getBinaryPath(file, config)
+        const baseDir = config.configDir || file.directory
 
-            // Get compiler configuration (auto-detect if not specified)
-            const compilerConfig = await CompilerManager.getDefaultCompilerConfig(
-                this.resolveCompilerName(config.compiler?.c?.compiler)
-            )
-
-            // Get compiler-specific or default flags and libraries
-            let userFlags: string[] = []
-            let rawLibraries: string[] = []
+        // Get compiler configuration (--cc, compiler.cc, compiler.c.compiler, compiler.toolchain, $CC or auto-detect)
+        const selection = CompilerManager.selectCompiler(config)
+        const compilerConfig = await CompilerManager.getDefaultCompilerConfig(selection.name)
+        const compilerName = this.getCompilerName(compilerConfig.type)
 
-            // Select flags based on detected compiler type
-            const cConfig = config.compiler?.c
-            if (cConfig) {
-                // Determine current platform
-                const platform = PlatformDetector.isWindows()
-                    ? 'windows'
-                    : PlatformDetector.isMacOS()
-                      ? 'macosx'
-                      : 'linux'
-
-                // Start with generic flags/libraries (if present)
-                userFlags = [...(cConfig.flags || [])]
-                rawLibraries = [...(cConfig.libraries || [])]
-
-                // Add compiler-specific config on top
-                if (compilerConfig.type === CompilerType.MSVC && cConfig.msvc) {
-                    if (cConfig.msvc.flags) userFlags.push(...cConfig.msvc.flags)
-                    if (cConfig.msvc.libraries) rawLibraries.push(...cConfig.msvc.libraries)
-                    // Check for platform-specific overrides
-                    const platformSettings = cConfig.msvc[platform]
-                    if (platformSettings) {
-                        if (platformSettings.flags) userFlags.push(...platformSettings.flags)
-                        if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
-                    }
-                } else if (compilerConfig.type === CompilerType.GCC && cConfig.gcc) {
-                    if (cConfig.gcc.flags) userFlags.push(...cConfig.gcc.flags)
-                    if (cConfig.gcc.libraries) rawLibraries.push(...cConfig.gcc.libraries)
-                    // Check for platform-specific overrides
-                    const platformSettings = cConfig.gcc[platform]
-                    if (platformSettings) {
-                        if (platformSettings.flags) userFlags.push(...platformSettings.flags)
-                        if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
-                    }
-                } else if (compilerConfig.type === CompilerType.Clang && cConfig.clang) {
-                    if (cConfig.clang.flags) userFlags.push(...cConfig.clang.flags)
-                    if (cConfig.clang.libraries) rawLibraries.push(...cConfig.clang.libraries)
-                    // Check for platform-specific overrides
-                    const platformSettings = cConfig.clang[platform]
-                    if (platformSettings) {
-                        if (platformSettings.flags) userFlags.push(...platformSettings.flags)
-                        if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
-                    }
-                }
-            }
-
-            // Merge compiler defaults with user flags (defaults first, then user overrides)
-            let flags = [...compilerConfig.flags, ...userFlags]
-
-            // Create special variables for expansion
-            const specialVars = GlobExpansion.createSpecialVariables(
-                file.artifactDir,
-                file.directory,
-                config.configDir,
-                compilerConfig.compiler,
-                config.profile
-            )
-
-            // Expand ${...} references in flags and libraries
-            const expandedFlags = await GlobExpansion.expandArray(flags, baseDir, specialVars)
-            const expandedLibraries = await GlobExpansion.expandArray(rawLibraries, baseDir, specialVars)
-
-            // Normalize rpath values for the current platform
-            const normalizedFlags = CompilerManager.normalizePlatformRpaths(expandedFlags)
-
-            // Convert relative paths to absolute paths since we compile from artifact directory
-            flags = this.resolveRelativePaths(normalizedFlags, baseDir)
-            const libraries = this.resolveRelativePaths(expandedLibraries, baseDir)
-
-            // Process libraries based on compiler type
-            const libraryFlags = CompilerManager.processLibraries(libraries, compilerConfig.type)
-
-            // Build compiler arguments based on compiler type
-            const args: string[] = []
-
-            if (compilerConfig.type === CompilerType.MSVC) {
-                // MSVC syntax: cl.exe [compiler flags] /Fe:output.exe input.c /link [linker flags]
-
-                // Separate compiler flags from linker flags
-                const compilerFlags: string[] = []
-                const linkerFlags: string[] = []
-
-                for (const flag of flags) {
-                    if (flag.startsWith('/LIBPATH:') || flag.endsWith('.lib') || flag.endsWith('.obj')) {
-                        linkerFlags.push(flag)
-                    } else {
-                        compilerFlags.push(flag)
-                    }
-                }
-
-                // Add compiler flags
-                args.push(...compilerFlags)
-                args.push(`/I${file.directory}`) // Include test directory
-                args.push(`/Fe:${binaryPath}`)
-                // Specify unique PDB file in artifact directory to avoid parallel build conflicts
-                const pdbPath = join(file.artifactDir, basename(binaryPath, '.exe') + '.pdb')
-                args.push(`/Fd:${pdbPath}`)
-                // Specify object file output in artifact directory to avoid cluttering test directory
-                const objPath = join(file.artifactDir, basename(file.path, '.c') + '.obj')
-                args.push(`/Fo:${objPath}`)
-                args.push(file.path)
-
-                // Add linker options (everything after /link)
-                const homeDir = os.homedir()
-                args.push('/link')
-                args.push(`/LIBPATH:${homeDir}\\.local\\lib`)
-
-                // Add user's linker flags
-                if (linkerFlags.length > 0) {
-                    args.push(...linkerFlags)
-                }
+        let args: string[]
+        try {
+            args = await this.getCompileArgs(file, config, compilerConfig, binaryPath)
+        } catch (error) {
+            const message = this.enhanceCompilationError((error as Error).message)
+            return {success: false, duration: 0, output: '', error: message, compiler: compilerName}
+        }
 
-                // Add library flags
-                if (libraryFlags.length > 0) {
-                    args.push(...libraryFlags)
-                }
-            } else {
-                // GCC/Clang/MinGW syntax: gcc [flags] -I dir -o output input.c [libraries]
-                args.push(...flags)
-                args.push('-I', file.directory)
-                args.push('-o', binaryPath)
-                args.push(file.path)
-                args.push(...libraryFlags)
+        // Reuse the binary if its source, compiler command and headers are unchanged
+        const command = hashCommand(compilerConfig.compiler, args)
+        const reason = config.execution?.rebuild ? '--rebuild' : await checkBuild(binaryPath, file.path, command)
+        if (reason === null) {
+            return {
+                success: true,
+                duration: 0,
+                output: 'Using cached binary (source, flags and headers unchanged)',
+                compiler: compilerName,
+                skipped: true,
             }
+        }
 
+        // GCC and Clang list the included headers in a dependency file so header changes trigger a rebuild
+        const depPath = compilerConfig.type === CompilerType.MSVC ? undefined : `${binaryPath}.d`
+        const {result, duration} = await this.measureExecution(async () => {
             // Display compile command if showCommands or showWarnings is enabled
             if (config.execution?.showCommands || config.execution?.showWarnings) {
                 // Show full config only for --show (-s), not for --warning (-w)
@@ -292,19 +458,11 @@ export class CTestHandler extends BaseTestHandler {
                     console.log(`📄 Config used for ${file.name}:`)
                     console.log(this.formatConfig(config))
                 }
-                console.log(`🔧 Compiler: ${compilerConfig.compiler} (${compilerConfig.type})`)
+                console.log(`🔧 Compiler: ${compilerConfig.compiler} (${compilerConfig.type}, ${selection.source})`)
                 console.log(`📋 Compile command: ${compilerConfig.compiler} ${args.join(' ')}`)
 
                 // Show environment variables only for --show (-s), not for --warning (-w)
                 if (showFullConfig) {
-                    const compilerName =
-                        compilerConfig.type === CompilerType.GCC
-                            ? 'gcc'
-                            : compilerConfig.type === CompilerType.Clang
-                              ? 'clang'
-                              : compilerConfig.type === CompilerType.MSVC
-                                ? 'msvc'
-                                : undefined
                     const testEnv = await this.getTestEnvironment(config, file, compilerName)
                     if (Object.keys(testEnv).length > 0) {
                         console.log(`\n🌍 TestMe environment variables:`)
@@ -345,18 +503,26 @@ export class CTestHandler extends BaseTestHandler {
                 }
             }
 
-            return await this.runCommand(compilerConfig.compiler, args, {
+            if (DryRun.isEnabled()) {
+                const path = isAbsolute(compilerConfig.compiler)
+                    ? compilerConfig.compiler
+                    : (await PlatformDetector.findInPath(compilerConfig.compiler)) || 'not found'
+                console.log(`🔧 Compiler: ${compilerConfig.type} ${path} (${selection.source})`)
+            }
+            const depFlags = depPath ? ['-MMD', '-MF', depPath] : []
+            return await this.runCommand(compilerConfig.compiler, [...args, ...depFlags], {
                 cwd: baseDir, // Compile from config directory so relative paths in flags work correctly
                 timeout: 60000, // 1 minute for compilation
                 env,
                 description: `Compilation of ${file.name}`,
+                container: Docker.getContainer(config, file, true),
             })
         })
 
         const success = result.exitCode === 0
 
         // Build compilation output
-        let output = result.stdout || 'Compilation completed'
+        let output = result.stdout || `Compiled (${reason})`
 
         // If --warning is enabled, or both --show and --verbose are enabled, include full compilation output
         if ((config.execution?.showWarnings || (config.execution?.showCommands && config.output?.verbose)) && success) {
@@ -380,7 +546,7 @@ export class CTestHandler extends BaseTestHandler {
         }
 
         // Save compilation log to artifacts
-        const logContent = `Compiler: ${config.compiler?.c?.compiler || 'gcc'}
+        const logContent = `Compiler: ${compilerConfig.compiler} (${compilerConfig.type}, ${selection.source})
 Exit Code: ${result.exitCode}
 STDOUT:
 ${result.stdout}
@@ -389,74 +555,224 @@ ${result.stderr}`
 
         try {
             await this.artifactManager.writeArtifact(file, 'compile.log', logContent)
+            if (success && !DryRun.isEnabled()) {
+                await saveBuild(binaryPath, file.path, command, depPath, baseDir)
+            }
         } catch {
-            // Ignore write errors - compilation log is not critical
+            // Ignore write errors - the compilation log and build record are not critical
         }
 
-        // Get compiler name for special variable expansion
-        const compilerConfig = await CompilerManager.getDefaultCompilerConfig(
-            this.resolveCompilerName(config.compiler?.c?.compiler)
-        )
-        const compilerName =
-            compilerConfig.type === CompilerType.GCC
-                ? 'gcc'
-                : compilerConfig.type === CompilerType.Clang
-                  ? 'clang'
-                  : compilerConfig.type === CompilerType.MSVC
-                    ? 'msvc'
-                    : undefined
-
         return {success, duration, output, error, compiler: compilerName}
     }
 
     /*
-     Resolves compiler name from config (handles platform-specific values)
-     @param compiler Compiler config value (string or platform object)
-     @returns Resolved compiler name string
+     Gets the compiler arguments for a C test
+     Combines the compiler defaults, the compiler.c flags and libraries for the compiler and platform, target,
+     sanitizer and coverage flags and pkg-config packages. Relative paths are made absolute.
+     @param file C test file
+     @param config Test configuration with compiler settings
+     @param compilerConfig Selected compiler
+     @param binaryPath Path of the binary to build
+     @returns Compiler arguments
+     @throws Error if pkg-config cannot find a package
      */
-    private resolveCompilerName(
-        compiler?: string | {windows?: string; macosx?: string; linux?: string}
-    ): string | undefined {
-        if (!compiler) {
-            return undefined
+    private async getCompileArgs(
+        file: TestFile,
+        config: TestConfig,
+        compilerConfig: Awaited<ReturnType<typeof CompilerManager.getDefaultCompilerConfig>>,
+        binaryPath: string
+    ): Promise<string[]> {
+        const baseDir = config.configDir || file.directory
+
+        // Get compiler-specific or default flags and libraries
+        let userFlags: string[] = []
+        let rawLibraries: string[] = []
+
+        // Select flags based on detected compiler type
+        const cConfig = config.compiler?.c
+        if (cConfig) {
+            // Determine current platform
+            const platform = PlatformDetector.isWindows()
+                ? 'windows'
+                : PlatformDetector.isMacOS()
+                  ? 'macosx'
+                  : 'linux'
+
+            // Start with generic flags/libraries (if present)
+            userFlags = [...(cConfig.flags || [])]
+            rawLibraries = [...(cConfig.libraries || [])]
+
+            // Add compiler-specific config on top
+            if (compilerConfig.type === CompilerType.MSVC && cConfig.msvc) {
+                if (cConfig.msvc.flags) userFlags.push(...cConfig.msvc.flags)
+                if (cConfig.msvc.libraries) rawLibraries.push(...cConfig.msvc.libraries)
+                // Check for platform-specific overrides
+                const platformSettings = cConfig.msvc[platform]
+                if (platformSettings) {
+                    if (platformSettings.flags) userFlags.push(...platformSettings.flags)
+                    if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
+                }
+            } else if (compilerConfig.type === CompilerType.GCC && cConfig.gcc) {
+                if (cConfig.gcc.flags) userFlags.push(...cConfig.gcc.flags)
+                if (cConfig.gcc.libraries) rawLibraries.push(...cConfig.gcc.libraries)
+                // Check for platform-specific overrides
+                const platformSettings = cConfig.gcc[platform]
+                if (platformSettings) {
+                    if (platformSettings.flags) userFlags.push(...platformSettings.flags)
+                    if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
+                }
+            } else if (compilerConfig.type === CompilerType.Clang && cConfig.clang) {
+                if (cConfig.clang.flags) userFlags.push(...cConfig.clang.flags)
+                if (cConfig.clang.libraries) rawLibraries.push(...cConfig.clang.libraries)
+                // Check for platform-specific overrides
+                const platformSettings = cConfig.clang[platform]
+                if (platformSettings) {
+                    if (platformSettings.flags) userFlags.push(...platformSettings.flags)
+                    if (platformSettings.libraries) rawLibraries.push(...platformSettings.libraries)
+                }
+            }
         }
-        if (typeof compiler === 'string') {
-            return compiler
+
+        // Merge compiler defaults with user flags (defaults first, then user overrides)
+        let flags = [...compilerConfig.flags, ...userFlags]
+
+        // Cross-compile for the target triple (a clang driver is told the target with --target)
+        const triple = CrossTarget.getTriple(config)
+        if (triple) {
+            if (compilerConfig.type === CompilerType.Clang) {
+                flags.push(`--target=${triple}`)
+            }
+            flags.push(...(config.target?.flags || []))
+        }
+        if (config.execution?.asan) {
+            flags.push(...getSanitizerFlags(compilerConfig.type === CompilerType.MSVC))
+        }
+        if (config.coverage?.enable && compilerConfig.type !== CompilerType.MSVC) {
+            flags.push('--coverage')
+        }
+
+        // Create special variables for expansion
+        const specialVars = GlobExpansion.createSpecialVariables(
+            file.artifactDir,
+            file.directory,
+            config.configDir,
+            compilerConfig.compiler,
+            config.profile
+        )
+
+        // Expand ${...} references in flags and libraries
+        const expandedFlags = await GlobExpansion.expandArray(flags, baseDir, specialVars)
+        const expandedLibraries = await GlobExpansion.expandArray(rawLibraries, baseDir, specialVars)
+
+        // Normalize rpath values for the current platform
+        const normalizedFlags = CompilerManager.normalizePlatformRpaths(expandedFlags)
+
+        // Convert relative paths to absolute paths since we compile from artifact directory
+        flags = this.resolveRelativePaths(normalizedFlags, baseDir)
+        const libraries = this.resolveRelativePaths(expandedLibraries, baseDir)
+
+        // Process libraries based on compiler type
+        const libraryFlags = CompilerManager.processLibraries(libraries, compilerConfig.type)
+
+        // Add compile and link flags for pkg-config packages, honoring PKG_CONFIG_PATH from the config or shell
+        let packageFlags: PackageFlags = {cflags: [], libs: []}
+        if (config.compiler?.pkgs?.length) {
+            const env = await this.getTestEnvironment(config, file)
+            const msvc = compilerConfig.type === CompilerType.MSVC
+            packageFlags = await getPackageFlags(config.compiler.pkgs, msvc, env)
+        }
+
+        // Build compiler arguments based on compiler type
+        const args: string[] = []
+
+        if (compilerConfig.type === CompilerType.MSVC) {
+            // MSVC syntax: cl.exe [compiler flags] /Fe:output.exe input.c /link [linker flags]
+
+            // Separate compiler flags from linker flags
+            const compilerFlags: string[] = []
+            const linkerFlags: string[] = []
+
+            for (const flag of flags) {
+                if (flag.startsWith('/LIBPATH:') || flag.endsWith('.lib') || flag.endsWith('.obj')) {
+                    linkerFlags.push(flag)
+                } else {
+                    compilerFlags.push(flag)
+                }
+            }
+
+            // Add compiler flags
+            args.push(...compilerFlags, ...packageFlags.cflags)
+            args.push(`/I${file.directory}`) // Include test directory
+            args.push(`/Fe:${binaryPath}`)
+            // Specify unique PDB file in artifact directory to avoid parallel build conflicts
+            const pdbPath = join(file.artifactDir, basename(binaryPath, '.exe') + '.pdb')
+            args.push(`/Fd:${pdbPath}`)
+            // Specify object file output in artifact directory to avoid cluttering test directory
+            const objPath = join(file.artifactDir, basename(file.path, '.c') + '.obj')
+            args.push(`/Fo:${objPath}`)
+            args.push(file.path)
+
+            // Add linker options (everything after /link)
+            const homeDir = os.homedir()
+            args.push('/link')
+            args.push(`/LIBPATH:${homeDir}\\.local\\lib`)
+
+            // Add user's linker flags
+            if (linkerFlags.length > 0) {
+                args.push(...linkerFlags)
+            }
+
+            // Add library flags
+            if (libraryFlags.length > 0) {
+                args.push(...libraryFlags)
+            }
+            args.push(...packageFlags.libs)
+        } else {
+            // GCC/Clang/MinGW syntax: gcc [flags] -I dir -o output input.c [libraries]
+            args.push(...flags, ...packageFlags.cflags)
+            args.push('-I', file.directory)
+            args.push('-o', binaryPath)
+            args.push(file.path)
+            args.push(...libraryFlags, ...packageFlags.libs)
         }
-        // Resolve platform-specific compiler
-        const platform = PlatformDetector.isWindows() ? 'windows' : PlatformDetector.isMacOS() ? 'macosx' : 'linux'
-        return compiler[platform as 'windows' | 'macosx' | 'linux']
+
+        return args
+    }
+
+    /*
+     Gets the short compiler name used for special variables and the test environment
+     @param type Compiler type
+     @returns gcc, clang, msvc or undefined for other compilers
+     */
+    private getCompilerName(type: CompilerType): string | undefined {
+        return type === CompilerType.GCC
+            ? 'gcc'
+            : type === CompilerType.Clang
+              ? 'clang'
+              : type === CompilerType.MSVC
+                ? 'msvc'
+                : undefined
     }
 
     /*
      Gets the path where the compiled binary should be stored
+     AddressSanitizer, coverage and cross-compiled builds use separate binaries so switching --asan,
+     --coverage or --target on or off never reuses the wrong build
      @param file C test file
+     @param config Test configuration (optional, selects the --asan, --coverage and --target binaries)
      @returns Path to compiled binary in artifact directory (with .exe on Windows)
      */
-    private getBinaryPath(file: TestFile): string {
-        const baseName = basename(file.name, '.tst.c')
+    private getBinaryPath(file: TestFile, config?: TestConfig): string {
+        const triple = config ? CrossTarget.getTriple(config) : undefined
+        const baseName =
+            basename(file.name, '.tst.c') +
+            (config?.execution?.asan ? '-asan' : '') +
+            (config?.coverage?.enable ? '-cov' : '') +
+            (triple ? `-${triple}` : '')
         const binaryName = PermissionManager.addBinaryExtension(baseName)
         return this.artifactManager.getArtifactPath(file, binaryName)
     }
 
-    /*
-     Checks if the C source file needs to be recompiled
-     Compares modification times of source file and compiled binary
-     @param sourceFile Path to the C source file
-     @param binaryPath Path to the compiled binary
-     @returns Promise resolving to true if recompilation is needed
-     */
-    private async needsRecompilation(sourceFile: string, binaryPath: string): Promise<boolean> {
-        try {
-            const [sourceStat, binaryStat] = await Promise.all([stat(sourceFile), stat(binaryPath)])
-            // Rebuild if source is newer than binary
-            return sourceStat.mtimeMs > binaryStat.mtimeMs
-        } catch {
-            // Binary doesn't exist or other error, needs compilation
-            return true
-        }
-    }
-
     /*
      Combines compilation and execution outputs into single formatted string
      @param compileOutput Output from compilation step
@@ -514,13 +830,7 @@ ${result.stderr}`
                     return await this.launchVSCodeDebugger(file, config, compileDuration, compiler)
                 default:
                     // If it's not a known alias, treat it as a path to a debugger executable
-                    return this.createTestResult(
-                        file,
-                        TestStatus.Error,
-                        compileDuration,
-                        '',
-                        `Unknown debugger: ${debuggerName}. Valid options: xcode, lldb, gdb, vs, vscode, or path to debugger executable`
-                    )
+                    return await this.launchCustomDebugger(file, config, compileDuration, debuggerName, compiler)
             }
         } catch (error) {
             return this.createTestResult(
@@ -566,7 +876,7 @@ ${result.stderr}`
             // Get expanded flags and libraries (same as used for compilation)
             const baseDir = config.configDir || file.directory
             const compilerConfig = await CompilerManager.getDefaultCompilerConfig(
-                this.resolveCompilerName(config.compiler?.c?.compiler)
+                CompilerManager.selectCompiler(config).name
             )
 
             // Get compiler-specific or default user flags and libraries (same as compile method)
@@ -720,6 +1030,7 @@ To debug:
         try {
             console.log('🐛 Launching LLDB debugger...')
             console.log(`Binary: ${binaryPath}`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}`)
             console.log('LLDB commands you can use:')
             console.log('  (lldb) run       - Start the program')
             console.log('  (lldb) b main    - Set breakpoint at main')
@@ -729,32 +1040,10 @@ To debug:
             console.log('  (lldb) quit      - Exit debugger')
             console.log('')
 
-            // Launch LLDB in interactive mode with stdin/stdout/stderr inheritance
+            // Launch LLDB in interactive mode with the test's arguments, environment and working directory
             const testEnv = await this.getTestEnvironment(config, file, compiler)
-            const spawnEnv: Record<string, string> = {}
-
-            // Copy all environment variables
-            for (const [key, value] of Object.entries(process.env)) {
-                if (value !== undefined) {
-                    spawnEnv[key] = value
-                }
-            }
-
-            // Merge test environment
-            for (const [key, value] of Object.entries(testEnv)) {
-                spawnEnv[key] = value
-            }
-
-            const proc = Bun.spawn(['lldb', binaryPath], {
-                cwd: file.directory,
-                env: spawnEnv,
-                stdin: 'inherit', // Allow interactive input
-                stdout: 'inherit', // Show output directly
-                stderr: 'inherit', // Show errors directly
-            })
-
-            // Wait for debugger to finish
-            const exitCode = await proc.exited
+            const args = ['--', binaryPath, ...(config.execution?.args || [])]
+            const exitCode = await this.runDebugger('lldb', args, file, config, testEnv)
 
             const output = `LLDB debugging session completed.`
             const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
@@ -783,6 +1072,7 @@ To debug:
         try {
             console.log('🐛 Launching GDB debugger...')
             console.log(`Binary: ${binaryPath}`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}`)
             console.log('GDB commands you can use:')
             console.log('  (gdb) run       - Start the program')
             console.log('  (gdb) break main - Set breakpoint at main')
@@ -792,32 +1082,10 @@ To debug:
             console.log('  (gdb) quit      - Exit debugger')
             console.log('')
 
-            // Launch GDB in interactive mode with stdin/stdout/stderr inheritance
+            // Launch GDB in interactive mode with the test's arguments, environment and working directory
             const testEnv = await this.getTestEnvironment(config, file, compiler)
-            const spawnEnv: Record<string, string> = {}
-
-            // Copy all environment variables
-            for (const [key, value] of Object.entries(process.env)) {
-                if (value !== undefined) {
-                    spawnEnv[key] = value
-                }
-            }
-
-            // Merge test environment
-            for (const [key, value] of Object.entries(testEnv)) {
-                spawnEnv[key] = value
-            }
-
-            const proc = Bun.spawn(['gdb', binaryPath], {
-                cwd: file.directory,
-                env: spawnEnv,
-                stdin: 'inherit', // Allow interactive input
-                stdout: 'inherit', // Show output directly
-                stderr: 'inherit', // Show errors directly
-            })
-
-            // Wait for debugger to finish
-            const exitCode = await proc.exited
+            const args = ['--args', binaryPath, ...(config.execution?.args || [])]
+            const exitCode = await this.runDebugger('gdb', args, file, config, testEnv)
 
             const output = `GDB debugging session completed.`
             const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
@@ -828,6 +1096,32 @@ To debug:
         }
     }
 
+    /*
+     Launches a custom debugger executable with the test binary and its arguments
+     @param file C test file to debug
+     @param config Test execution configuration
+     @param compileDuration Duration of compilation phase
+     @param debuggerPath Debugger command or path
+     @returns Promise resolving to test results
+     */
+    private async launchCustomDebugger(
+        file: TestFile,
+        config: TestConfig,
+        compileDuration: number,
+        debuggerPath: string,
+        compiler?: string
+    ): Promise<TestResult> {
+        const binaryPath = this.getBinaryPath(file)
+        console.log(`🐛 Launching custom debugger: ${debuggerPath}`)
+        console.log(`Binary: ${binaryPath}`)
+        console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}`)
+        const testEnv = await this.getTestEnvironment(config, file, compiler)
+        const args = [binaryPath, ...(config.execution?.args || [])]
+        const exitCode = await this.runDebugger(debuggerPath, args, file, config, testEnv)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, compileDuration, 'Debugging session completed.', undefined, exitCode)
+    }
+
     /*
      Launches Visual Studio debugger for MSVC-compiled tests
      @param file C test file to debug
@@ -850,7 +1144,7 @@ To debug:
 
             // Get compiler config to find devenv path from MSVC installation
             const compilerConfig = await CompilerManager.getDefaultCompilerConfig(
-                this.resolveCompilerName(config.compiler?.c?.compiler)
+                CompilerManager.selectCompiler(config).name
             )
 
             let devenvPath = 'devenv'
@@ -0,0 +1,223 @@
+import type {BuildResult, HandlerConfig, TestFile, TestResult, TestConfig} from '../types.ts'
+import {TestStatus, TestType} from '../types.ts'
+import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
+import {ArtifactManager} from '../artifacts.ts'
+import {Directives} from '../directives.ts'
+import {stat} from 'node:fs/promises'
+
+/**
+ * Handler for tests whose suffix is registered in the `handlers` configuration (e.g., .tst.xyz)
+ * Runs the configured build command, if any, then the run command, with {src}, {out}, {tmp} and {dir} replaced
+ */
+export class CustomTestHandler extends BaseTestHandler {
+    private artifactManager: ArtifactManager
+
+    constructor() {
+        super()
+        this.artifactManager = new ArtifactManager()
+    }
+
+    /**
+     * Checks if this handler can process the given test file
+     *
+     * @param file - Test file to check
+     * @returns true if file has a suffix registered in handlers
+     */
+    canHandle(file: TestFile): boolean {
+        return file.type === TestType.Custom
+    }
+
+    /**
+     * Creates the artifact directory that holds {out}
+     *
+     * @param file - Test file to prepare
+     */
+    override async prepare(file: TestFile): Promise<void> {
+        await this.artifactManager.createArtifactDir(file)
+    }
+
+    /**
+     * Removes the build output and build log
+     *
+     * @param file - Test file to clean up
+     */
+    override async cleanup(file: TestFile): Promise<void> {
+        await this.artifactManager.cleanArtifactDir(file)
+    }
+
+    /**
+     * Builds and runs a test with the commands configured for its suffix
+     *
+     * @param file - Test file to execute
+     * @param config - Test execution configuration
+     * @returns Promise resolving to test results
+     *
+     * @remarks
+     * The run command gets the test's timeout, environment, working directory, stdin and execution.args like
+     * built-in languages, and runs in the container with --docker (or docker.image).
+     * Build failures are reported with error status, distinct from test failures.
+     * Tests should use standard exit codes: 0 for success, non-zero for failure.
+     */
+    async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
+        const handler = config.handlers?.[file.extension]
+        if (!handler) {
+            return this.createTestResult(file, TestStatus.Error, 0, '', `No handler configured for ${file.extension}`)
+        }
+        const built = await this.compile(file, config, handler)
+        if (!built.success) {
+            return this.createTestResult(file, TestStatus.Error, built.duration, built.output, built.error)
+        }
+
+        // Get test environment
+        const testEnv = await this.getTestEnvironment(config, file)
+
+        // Display environment info if showCommands is enabled
+        await this.displayEnvironmentInfo(config, file, testEnv)
+
+        const [command, ...args] = this.expand(handler.run, file, config)
+        if (!command) {
+            return this.createTestResult(file, TestStatus.Error, 0, '', `Empty run command for ${file.extension}`)
+        }
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand(command, [...args, ...(config.execution?.args || [])], {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
+                env: testEnv,
+                stdin: config.execution?.stdin,
+                config,
+                container: Docker.getContainer(config, file),
+                description: `Test ${file.name}`,
+            })
+        })
+
+        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        const output = this.combineOutput(result.stdout, result.stderr)
+        const error = result.exitCode !== 0 ? result.stderr : undefined
+
+        return this.createTestResult(file, status, built.duration + duration, output, error, result.exitCode)
+    }
+
+    /**
+     * Runs the build command ahead of running the test (--build-workers)
+     *
+     * @param file - Test file to build
+     * @param config - Test configuration with the handlers section
+     * @returns Build result, successful at once if the suffix has no build command
+     */
+    async build(file: TestFile, config: TestConfig): Promise<BuildResult> {
+        const handler = config.handlers?.[file.extension]
+        if (!handler) {
+            return {success: false, duration: 0, output: '', error: `No handler configured for ${file.extension}`}
+        }
+        return await this.compile(file, config, handler)
+    }
+
+    /**
+     * Gets the path of the build output in the artifact directory ({out})
+     *
+     * @param file - Test file
+     * @returns Test file name without its suffix, in the artifact directory
+     */
+    getOutputPath(file: TestFile): string {
+        return this.artifactManager.getArtifactPath(file, file.name.slice(0, -file.extension.length))
+    }
+
+    /**
+     * Splits a command template into arguments and replaces the placeholders in each
+     *
+     * @param template - Command template from the handlers configuration
+     * @param file - Test file
+     * @param config - Test configuration giving the temporary directory
+     * @returns Command and arguments
+     *
+     * @remarks
+     * Arguments are split on whitespace, keeping quoted arguments together, before placeholders are replaced so
+     * paths containing spaces stay one argument. {tmp} is the test's private temporary directory (TESTME_TMP), or
+     * the artifact directory when the build runs ahead of the test.
+     */
+    expand(template: string, file: TestFile, config: TestConfig): string[] {
+        const values: Record<string, string> = {
+            src: file.path,
+            out: this.getOutputPath(file),
+            tmp: config.execution?.tmpDir || file.artifactDir,
+            dir: file.directory,
+        }
+        return Directives.splitArgs(template).map((arg) =>
+            arg.replace(/\{(\w+)\}/g, (placeholder, name: string) => values[name] ?? placeholder)
+        )
+    }
+
+    /**
+     * Runs the build command of a test
+     *
+     * @param file - Test file to build
+     * @param config - Test configuration
+     * @param handler - Commands configured for the test's suffix
+     * @returns Build result with success status, duration, and output
+     *
+     * @remarks
+     * A build command that writes {out} is skipped while {out} is newer than the source (unless --rebuild is set).
+     * The command and its output are written to build.log in the artifact directory.
+     */
+    private async compile(file: TestFile, config: TestConfig, handler: HandlerConfig): Promise<BuildResult> {
+        if (!handler.build) {
+            return {success: true, duration: 0, output: ''}
+        }
+        const rebuild = config.execution?.rebuild || !handler.build.includes('{out}')
+        if (!rebuild && !(await this.needsRebuild(file.path, this.getOutputPath(file)))) {
+            return {success: true, duration: 0, output: ''}
+        }
+        await this.artifactManager.createArtifactDir(file)
+        const [command, ...args] = this.expand(handler.build, file, config)
+        if (!command) {
+            return {success: false, duration: 0, output: '', error: `Empty build command for ${file.extension}`}
+        }
+
+        if (config.execution?.showCommands) {
+            console.log(`\n🔧 Build: ${command} ${args.join(' ')}`)
+        }
+
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand(command, args, {
+                cwd: file.directory,
+                timeout: 60000, // 1 minute for the build
+                description: `Build of ${file.name}`,
+                container: Docker.getContainer(config, file, true),
+            })
+        })
+
+        const logContent = `Command: ${command} ${args.join(' ')}
+Exit Code: ${result.exitCode}
+STDOUT:
+${result.stdout}
+STDERR:
+${result.stderr}`
+        try {
+            await this.artifactManager.writeArtifact(file, 'build.log', logContent)
+        } catch {
+            // Ignore write errors - build log is not critical
+        }
+
+        if (result.exitCode !== 0) {
+            return {success: false, duration, output: result.stdout, error: `Build failed:\n${result.stderr}`}
+        }
+        return {success: true, duration, output: ''}
+    }
+
+    /**
+     * Checks if the source is newer than the build output
+     *
+     * @param sourceFile - Path to the test source file
+     * @param outputPath - Path to the build output
+     * @returns Promise resolving to true if the build command must run
+     */
+    private async needsRebuild(sourceFile: string, outputPath: string): Promise<boolean> {
+        try {
+            const [sourceStat, outputStat] = await Promise.all([stat(sourceFile), stat(outputPath)])
+            return sourceStat.mtimeMs > outputStat.mtimeMs
+        } catch {
+            return true
+        }
+    }
+}
@@ -1,11 +1,15 @@
 import type {TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
+import {PlatformDetector} from '../platform/detector.ts'
+import {existsSync} from 'fs'
+import {resolve} from 'path'
 import os from 'os'
 
 /*
  Handler for executing Ejscript tests (.tst.es files)
- Uses ejs command to execute Ejscript test files directly
+ Uses ejs command (or compiler.es.interpreter) to execute Ejscript test files directly
  */
 export class EjscriptTestHandler extends BaseTestHandler {
     /*
@@ -19,11 +23,21 @@ export class EjscriptTestHandler extends BaseTestHandler {
 
     /*
      Executes Ejscript test file using ejs runtime
+     The test is skipped if the interpreter is not installed. It gets the standard TESTME environment, including
+     TESTME_DEPTH, and a non-zero exit fails the test.
      @param file Ejscript test file to execute
      @param config Test execution configuration
      @returns Promise resolving to test results
      */
     async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
+        // The container provides its own interpreter
+        const container = Docker.getContainer(config, file)
+        const ejs = container ? config.compiler?.es?.interpreter || 'ejs' : await this.findInterpreter(config)
+        if (!ejs) {
+            const reason = 'Ejscript not found, install ejs or set compiler.es.interpreter'
+            return this.createTestResult(file, TestStatus.Skipped, 0, reason)
+        }
+
         // Get test environment
         const testEnv = await this.getTestEnvironment(config, file)
 
@@ -32,11 +46,13 @@ export class EjscriptTestHandler extends BaseTestHandler {
 
         const {result, duration} = await this.measureExecution(async () => {
             const args = this.buildEjsArgs(file, config)
-            return await this.runCommand('ejs', args, {
-                cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+            return await this.runCommand(ejs, args, {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container,
                 description: `Test ${file.name}`,
             })
         })
@@ -48,6 +64,19 @@ export class EjscriptTestHandler extends BaseTestHandler {
         return this.createTestResult(file, status, duration, output, error, result.exitCode)
     }
 
+    /*
+     Finds the Ejscript interpreter (compiler.es.interpreter, default ejs)
+     @param config Test configuration
+     @returns Path of the interpreter, or null if it is not installed
+     */
+    private async findInterpreter(config: TestConfig): Promise<string | null> {
+        const ejs = config.compiler?.es?.interpreter || 'ejs'
+        if (/[\\/]/.test(ejs)) {
+            return existsSync(ejs) ? resolve(ejs) : null
+        }
+        return await PlatformDetector.findInPath(ejs)
+    }
+
     /*
      Builds command-line arguments for ejs command
      @param file Ejscript test file to execute
@@ -64,7 +93,7 @@ export class EjscriptTestHandler extends BaseTestHandler {
             args.push('--require', expandedModules)
         }
 
-        args.push(file.path)
+        args.push(file.path, ...(config.execution?.args || []))
         return args
     }
 
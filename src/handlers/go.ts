@@ -1,12 +1,41 @@
-import type {TestFile, TestResult, TestConfig} from '../types.ts'
+import type {BuildResult, CoverageReport, SubResult, TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import type {CommandResult} from './base.ts'
+import {Docker} from '../docker.ts'
+import type {ContainerSpec} from '../docker.ts'
+import {applySanitizerReport, getSanitizerOptions} from '../utils/sanitizer.ts'
+import {CrossTarget} from '../target.ts'
+import {Remote} from '../remote.ts'
+import {existsSync, readdirSync} from 'fs'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {basename, join} from 'path'
+import {devNull, tmpdir} from 'os'
+
+/**
+ * One event of `go test -json` output
+ */
+type GoTestEvent = {
+    Action?: string
+    Test?: string
+    Output?: string
+    Elapsed?: number
+}
+
+// Output lines that go test writes around each test rather than the test itself
+const GO_TEST_FRAME = /^\s*(=== (RUN|PAUSE|CONT|NAME)|--- (PASS|FAIL|SKIP):)/
+
+// Location prefix of a t.Error, t.Fatal or t.Skip message (e.g., "math_test.go:12: ")
+const GO_TEST_LOCATION = /^(\S+\.go):(\d+): /
 
 /**
  * Handler for executing Go tests (.tst.go files)
- * Uses `go run` command to execute Go test files directly
+ * Uses `go run` command to execute Go test files directly, or `go test` for the package of a _test.go file
  */
 export class GoTestHandler extends BaseTestHandler {
+    // Per-test coverage data directories (GOCOVERDIR) collected during a --coverage run
+    private static coverageDirs: string[] = []
+
     /**
      * Checks if this handler can process the given test file
      *
@@ -26,35 +55,431 @@ export class GoTestHandler extends BaseTestHandler {
      *
      * @remarks
      * Uses `go run` to compile and execute Go programs in one step.
+     * With --asan, the program is built with `-asan` and a sanitizer report fails the test.
+     * With --coverage, the program is built with `-cover` and writes coverage data to a temporary
+     * GOCOVERDIR that is merged by mergeCoverage() at the end of the run.
+     * With a target (--target, target.triple, target.goos or target.goarch), GOOS and GOARCH are set. A program
+     * for another platform is run with `go run -exec` and target.runner, or only built if no runner is set.
+     * With --remote (or remote.host), the program is built with `go build` and run on the remote host over ssh.
+     * With --docker (or docker.image), the program is built on the host and run in the container, or run with
+     * `go run` in the container if docker.build is true.
+     * Unless run with `go run` in the container, the program runs in the test's working directory (the temporary
+     * directory or execution.chdir) and is built first with `go build` if that is not the test's directory.
      * Tests should use standard exit codes: 0 for success, non-zero for failure.
      * Go test files must contain a valid main package and main() function.
+     * In package mode (compiler.go.mode), the file is run with `go test` by executePackage() instead.
      */
     async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
         // Handle debug mode
         if (config.execution?.debugMode) {
             return await this.launchDebugger(file, config)
         }
+        if (GoTestHandler.isPackageTest(file, config)) {
+            return await this.executePackage(file, config)
+        }
 
         // Get test environment
+        const asan = config.execution?.asan === true
         const testEnv = await this.getTestEnvironment(config, file)
+        if (asan) {
+            testEnv.ASAN_OPTIONS = getSanitizerOptions(testEnv.ASAN_OPTIONS ?? process.env.ASAN_OPTIONS)
+        }
+
+        const coverDir = config.coverage?.enable ? await mkdtemp(join(tmpdir(), 'testme-gocover-')) : undefined
+        if (coverDir) {
+            GoTestHandler.coverageDirs.push(coverDir)
+            testEnv.GOCOVERDIR = coverDir
+        }
+
+        // Cross-compile by setting GOOS and GOARCH for the target
+        const platform = CrossTarget.getGoPlatform(config)
+        if (platform?.os) {
+            testEnv.GOOS = platform.os
+        }
+        if (platform?.arch) {
+            testEnv.GOARCH = platform.arch
+        }
+        const label = platform && [platform.os, platform.arch].filter(Boolean).join('/')
+        const target = CrossTarget.getTriple(config) || label
+        const remote = Remote.getHost(config)
+        const container = Docker.getContainer(config, file)
+        const cross = platform !== undefined && !CrossTarget.isNative(platform)
+        const runner = cross && !remote && !container ? CrossTarget.getRunner(config) : []
+        const buildOnly = cross && !remote && !container && runner.length === 0
 
         // Display environment info if showCommands is enabled
         await this.displayEnvironmentInfo(config, file, testEnv)
 
+        const buildFlags = [...(asan ? ['-asan'] : []), ...(coverDir ? ['-cover'] : [])]
+        const args = buildOnly
+            ? ['build', '-o', devNull, ...buildFlags, file.path]
+            : [
+                  'run',
+                  ...(runner.length > 0 ? ['-exec', runner.join(' ')] : []),
+                  ...buildFlags,
+                  file.path,
+                  ...(config.execution?.args || []),
+              ]
+        // go run finds the module from its working directory, so a test that runs elsewhere is built first
+        const cwd = BaseTestHandler.getWorkingDirectory(config, file)
+        const elsewhere = !buildOnly && !container && cwd !== file.directory
         const {result, duration} = await this.measureExecution(async () => {
-            return await this.runCommand('go', ['run', file.path], {
+            if (remote || (container && !config.docker?.build) || elsewhere) {
+                return await this.runProgram(file, config, testEnv, buildFlags, container, runner)
+            }
+            return await this.runCommand('go', args, {
                 cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container,
             })
         })
 
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
+        const status =
+            result.exitCode !== 0 ? TestStatus.Failed : buildOnly ? TestStatus.Skipped : TestStatus.Passed
+        const output =
+            status === TestStatus.Skipped
+                ? `Built for ${target}, set target.runner to run it`
+                : this.combineOutput(result.stdout, result.stderr)
         const error = result.exitCode !== 0 ? result.stderr : undefined
 
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const testResult = this.createTestResult(file, status, duration, output, error, result.exitCode)
+        if (target) {
+            testResult.target = target
+        }
+        return asan ? applySanitizerReport(testResult) : testResult
+    }
+
+    /**
+     * Compiles a Go test ahead of running it (--build-workers)
+     *
+     * @param file - Go test file to compile
+     * @param config - Test configuration
+     * @returns Build result, which is always successful
+     *
+     * @remarks
+     * The program is built with `go build` (or `go test -c` in package mode) and discarded, which fills Go's
+     * build cache so the `go run`, `go build` or `go test` of execute() only links. Compile errors are reported
+     * when the test runs, as without a build phase. Nothing is built for tests compiled in the container (docker.build).
+     */
+    async build(file: TestFile, config: TestConfig): Promise<BuildResult> {
+        const pkg = GoTestHandler.isPackageTest(file, config)
+        if (!pkg && Docker.getContainer(config, file) && config.docker?.build) {
+            return {success: true, duration: 0, output: ''}
+        }
+        const env: Record<string, string> = {}
+        const platform = pkg ? undefined : CrossTarget.getGoPlatform(config)
+        if (platform?.os) {
+            env.GOOS = platform.os
+        }
+        if (platform?.arch) {
+            env.GOARCH = platform.arch
+        }
+        const buildFlags = [
+            ...(config.execution?.asan ? ['-asan'] : []),
+            ...(config.coverage?.enable ? ['-cover'] : []),
+        ]
+        // A package test is compiled with its package by `go test -c`
+        const args = pkg
+            ? ['test', '-c', '-o', devNull, ...buildFlags, '.']
+            : ['build', '-o', devNull, ...buildFlags, file.path]
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand('go', args, {
+                cwd: file.directory,
+                timeout: 300000,
+                env,
+                description: `Compilation of ${file.name}`,
+            })
+        })
+        return {success: true, duration, output: this.combineOutput(result.stdout, result.stderr)}
+    }
+
+    /**
+     * Runs the Test functions of a _test.go file with `go test` in its package directory
+     *
+     * @param file - Go test file whose package is tested
+     * @param config - Test configuration
+     * @returns Test result with a sub-result for each Test function
+     *
+     * @remarks
+     * The Test functions declared in the file are selected with `-run`, so each _test.go file of a package is a
+     * separate test. A file forced into package mode that declares none runs every test of its package. The
+     * `-json` events are mapped to a sub-result per top-level Test function, with its failure or skip message,
+     * and the test output is the text the tests printed. The test timeout is passed to `go test -timeout`, and
+     * with --coverage the package is built with `-cover` and writes coverage data for mergeCoverage(). Cross
+     * targets, remote hosts and containers apply to `go run` tests only.
+     */
+    private async executePackage(file: TestFile, config: TestConfig): Promise<TestResult> {
+        const asan = config.execution?.asan === true
+        const testEnv = await this.getTestEnvironment(config, file)
+        if (asan) {
+            testEnv.ASAN_OPTIONS = getSanitizerOptions(testEnv.ASAN_OPTIONS ?? process.env.ASAN_OPTIONS)
+        }
+        const names = GoTestHandler.getTestNames(await readFile(file.path, 'utf-8'))
+        if (names.length === 0 && file.name.endsWith('_test.go')) {
+            return this.createTestResult(file, TestStatus.Skipped, 0, `No Test functions in ${file.name}`)
+        }
+        const coverDir = config.coverage?.enable ? await mkdtemp(join(tmpdir(), 'testme-gocover-')) : undefined
+        if (coverDir) {
+            GoTestHandler.coverageDirs.push(coverDir)
+        }
+        await this.displayEnvironmentInfo(config, file, testEnv)
+
+        // Results are not cached so every run executes the tests (and writes coverage data)
+        const timeout = BaseTestHandler.getTimeout(config, file)
+        const testArgs = [...(coverDir ? [`-test.gocoverdir=${coverDir}`] : []), ...(config.execution?.args || [])]
+        const args = [
+            'test',
+            '-json',
+            '-count=1',
+            ...(names.length > 0 ? ['-run', `^(${names.join('|')})$`] : []),
+            ...(timeout ? [`-timeout=${Math.ceil(timeout / 1000)}s`] : []),
+            ...(asan ? ['-asan'] : []),
+            ...(coverDir ? ['-cover'] : []),
+            '.',
+            ...(testArgs.length > 0 ? ['-args', ...testArgs] : []),
+        ]
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand('go', args, {
+                cwd: file.directory,
+                timeout,
+                env: testEnv,
+                stdin: config.execution?.stdin,
+                config,
+            })
+        })
+
+        const {results, output} = GoTestHandler.parseTestEvents(result.stdout)
+        const failed = results.filter((sub) => sub.status === 'fail').map((sub) => sub.name)
+        const skipped = results.length > 0 && results.every((sub) => sub.status === 'skip')
+        const status =
+            result.exitCode !== 0 ? TestStatus.Failed : skipped ? TestStatus.Skipped : TestStatus.Passed
+        const error =
+            result.exitCode === 0
+                ? undefined
+                : failed.length > 0
+                  ? `${failed.length} of ${results.length} tests failed: ${failed.join(', ')}`
+                  : result.stderr || output
+        const testResult = this.createTestResult(
+            file,
+            status,
+            duration,
+            this.combineOutput(output, result.stderr),
+            error,
+            result.exitCode
+        )
+        testResult.stdout = output
+        if (results.length > 0) {
+            testResult.subResults = results
+            testResult.assertions = {
+                passed: results.filter((sub) => sub.status === 'pass').length,
+                failed: failed.length,
+            }
+        }
+        return asan ? applySanitizerReport(testResult) : testResult
+    }
+
+    /**
+     * Checks if a Go test is run as a package test with `go test`
+     *
+     * @param file - Go test file
+     * @param config - Test configuration with compiler.go.mode
+     * @returns true in 'test' mode, or in 'auto' mode (the default) for a file named *_test.go
+     */
+    static isPackageTest(file: TestFile, config: TestConfig): boolean {
+        const mode = config.compiler?.go?.mode || 'auto'
+        return mode === 'test' || (mode === 'auto' && file.name.endsWith('_test.go'))
+    }
+
+    /**
+     * Lists the Test functions declared in Go source
+     *
+     * @param source - Go source of a _test.go file
+     * @returns Names of the top-level `func TestXxx(t *testing.T)` functions in declaration order
+     */
+    static getTestNames(source: string): string[] {
+        const pattern = /^func\s+(Test(?![a-z])\w*)\s*\(\s*\w+\s+\*testing\.T\s*\)/gm
+        return [...source.matchAll(pattern)].map((match) => match[1]!)
+    }
+
+    /**
+     * Maps `go test -json` output to a sub-result per top-level Test function
+     *
+     * @param text - Standard output of `go test -json`
+     * @returns Sub-results in completion order and the text printed by the tests and go test
+     *
+     * @remarks
+     * Subtest output belongs to its top-level test. The message of a failed or skipped test is its output without
+     * the === RUN and --- FAIL lines, and the file and line come from the first message line. Lines that are not
+     * JSON events (e.g., from an older go) are kept in the output.
+     */
+    static parseTestEvents(text: string): {results: SubResult[]; output: string} {
+        const results: SubResult[] = []
+        const messages = new Map<string, string[]>()
+        let output = ''
+        for (const line of text.split('\n')) {
+            if (!line.trim()) {
+                continue
+            }
+            let event: GoTestEvent
+            try {
+                event = JSON.parse(line)
+            } catch {
+                output += `${line}\n`
+                continue
+            }
+            const name = event.Test?.split('/')[0]
+            if (event.Output !== undefined) {
+                output += event.Output
+                const message = event.Output.trim()
+                if (name && message && !GO_TEST_FRAME.test(message)) {
+                    messages.set(name, [...(messages.get(name) || []), message])
+                }
+            }
+            if (!name || name !== event.Test || !['pass', 'fail', 'skip'].includes(event.Action || '')) {
+                continue
+            }
+            const status = event.Action as SubResult['status']
+            const lines = status === 'pass' ? [] : messages.get(name) || []
+            const location = lines[0]?.match(GO_TEST_LOCATION)
+            results.push({
+                name,
+                status,
+                ...(lines.length > 0 && {message: lines.join('\n')}),
+                ...(location && {file: location[1]!, line: parseInt(location[2]!, 10)}),
+                ...(event.Elapsed !== undefined && {duration: event.Elapsed * 1000}),
+            })
+        }
+        return {results, output}
+    }
+
+    /**
+     * Builds a Go test program on the host and runs it on the remote host, in a container or locally
+     *
+     * @param file - Go test file to build
+     * @param config - Test configuration with remote settings
+     * @param env - Build and test environment (GOOS and GOARCH when cross-compiling)
+     * @param buildFlags - Extra `go build` flags (-asan, -cover)
+     * @param container - Container to run the program in
+     * @param runner - Command that runs a cross-compiled program locally (target.runner)
+     * @returns Build failure, or the exit code and output of the test run
+     */
+    private async runProgram(
+        file: TestFile,
+        config: TestConfig,
+        env: Record<string, string>,
+        buildFlags: string[],
+        container?: ContainerSpec,
+        runner: string[] = []
+    ): Promise<CommandResult> {
+        const dir = await mkdtemp(join(tmpdir(), 'testme-gobuild-'))
+        try {
+            const program = join(dir, basename(file.name, '.tst.go'))
+            const build = await this.runCommand('go', ['build', '-o', program, ...buildFlags, file.path], {
+                cwd: file.directory,
+                timeout: 300000,
+                env,
+                description: `Compilation of ${file.name}`,
+            })
+            if (build.exitCode !== 0) {
+                return build
+            }
+            if (!container && Remote.getHost(config)) {
+                return await this.runRemote(program, file, config)
+            }
+            // The build directory is mounted alongside the test directory
+            const [command, ...args] = [...runner, program, ...(config.execution?.args || [])]
+            return await this.runCommand(command!, args, {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
+                env,
+                stdin: config.execution?.stdin,
+                config,
+                description: `Test ${file.name}`,
+                container: container && {...container, mounts: [...container.mounts, dir]},
+            })
+        } finally {
+            await rm(dir, {recursive: true, force: true})
+        }
+    }
+
+    /**
+     * Merges the Go coverage collected during the run into coverage.out in the root directory
+     *
+     * @param rootDir - Test root directory
+     * @returns Merged Go coverage report, or null if no Go test collected coverage
+     * @throws Error if `go tool covdata` fails
+     */
+    async collectCoverage(rootDir: string): Promise<CoverageReport | null> {
+        const path = join(rootDir, 'coverage.out')
+        const percent = await GoTestHandler.mergeCoverage(path)
+        return percent === null ? null : {language: 'Go', unit: 'statements', path, percent}
+    }
+
+    /**
+     * Merges the coverage data of all Go tests run with --coverage into a single profile
+     *
+     * @param outputPath - Path of the merged text profile (e.g., coverage.out)
+     * @returns Total statement coverage percentage, or null if no Go coverage data was collected
+     * @throws Error if `go tool covdata` fails
+     *
+     * @remarks
+     * The per-test GOCOVERDIR directories are removed after merging.
+     */
+    static async mergeCoverage(outputPath: string): Promise<number | null> {
+        const allDirs = GoTestHandler.coverageDirs
+        GoTestHandler.coverageDirs = []
+        try {
+            // Tests that failed to build leave an empty directory
+            const dirs = allDirs.filter((dir) => existsSync(dir) && readdirSync(dir).length > 0)
+            if (dirs.length === 0) {
+                return null
+            }
+            const proc = Bun.spawn(['go', 'tool', 'covdata', 'textfmt', `-i=${dirs.join(',')}`, `-o=${outputPath}`], {
+                stdout: 'pipe',
+                stderr: 'pipe',
+            })
+            const [stderr, exitCode] = await Promise.all([new Response(proc.stderr).text(), proc.exited])
+            if (exitCode !== 0) {
+                throw new Error(`go tool covdata failed: ${stderr.trim()}`)
+            }
+            return GoTestHandler.getCoveragePercent(await readFile(outputPath, 'utf-8'))
+        } finally {
+            await Promise.all(allDirs.map((dir) => rm(dir, {recursive: true, force: true})))
+        }
+    }
+
+    /**
+     * Computes total statement coverage from a Go text coverage profile
+     *
+     * @param profile - Profile text ("mode: set" followed by "file:start,end statements count" lines)
+     * @returns Percentage of statements executed, or null if the profile has no statements
+     *
+     * @remarks
+     * A block listed more than once (e.g., by several tests) counts as covered if any entry has a non-zero count.
+     */
+    static getCoveragePercent(profile: string): number | null {
+        const blocks = new Map<string, {statements: number; covered: boolean}>()
+        for (const line of profile.split('\n')) {
+            const match = line.trim().match(/^(.+) (\d+) (\d+)$/)
+            if (!match) {
+                continue
+            }
+            const block = blocks.get(match[1]!)
+            blocks.set(match[1]!, {
+                statements: parseInt(match[2]!, 10),
+                covered: (block?.covered ?? false) || parseInt(match[3]!, 10) > 0,
+            })
+        }
+        let total = 0
+        let covered = 0
+        for (const block of blocks.values()) {
+            total += block.statements
+            covered += block.covered ? block.statements : 0
+        }
+        return total > 0 ? (covered / total) * 100 : null
     }
 
     /**
@@ -69,7 +494,7 @@ export class GoTestHandler extends BaseTestHandler {
             const debuggerName = config.debug?.go || this.getDefaultDebugger()
 
             console.log(`\n🐛 Launching ${debuggerName} debugger for: ${file.path}`)
-            console.log(`Working directory: ${file.directory}\n`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}\n`)
 
             switch (debuggerName) {
                 case 'vscode':
@@ -148,17 +573,13 @@ export class GoTestHandler extends BaseTestHandler {
         console.log('  print <var> - print variable')
         console.log('  exit - exit debugger\n')
 
-        const result = await this.runCommand('dlv', ['debug', file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const args = ['debug', file.path, '--', ...(config.execution?.args || [])]
+        const env = await this.getTestEnvironment(config, file)
+        const exitCode = await this.runDebugger('dlv', args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
-
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
     }
 
     /**
@@ -173,16 +594,12 @@ export class GoTestHandler extends BaseTestHandler {
         const startTime = performance.now()
 
         console.log(`Launching custom debugger: ${debuggerPath}`)
-        const result = await this.runCommand(debuggerPath, [file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const args = [file.path, ...(config.execution?.args || [])]
+        const env = await this.getTestEnvironment(config, file)
+        const exitCode = await this.runDebugger(debuggerPath, args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
-
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
     }
 }
@@ -6,6 +6,8 @@ import {TypeScriptTestHandler} from './typescript.ts'
 import {EjscriptTestHandler} from './ejscript.ts'
 import {PythonTestHandler} from './python.ts'
 import {GoTestHandler} from './go.ts'
+import {RustTestHandler} from './rust.ts'
+import {CustomTestHandler} from './custom.ts'
 
 /*
  Creates and returns all available test handlers
@@ -20,6 +22,8 @@ export const createHandlers = (): TestHandler[] => {
         new EjscriptTestHandler(),
         new PythonTestHandler(),
         new GoTestHandler(),
+        new RustTestHandler(),
+        new CustomTestHandler(),
     ]
 }
 
@@ -32,4 +36,6 @@ export {
     EjscriptTestHandler,
     PythonTestHandler,
     GoTestHandler,
+    RustTestHandler,
+    CustomTestHandler,
 }
@@ -1,16 +1,45 @@
 import type {TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
 import {PlatformDetector} from '../platform/detector.ts'
+import {ArtifactManager} from '../artifacts.ts'
+import {pathToFileURL} from 'url'
 import * as path from 'path'
 import * as fs from 'fs'
 import * as os from 'os'
 
+/*
+ Node module hooks that load the test with the module system passed to register() (see getModuleArgs)
+ */
+const NODE_HOOKS = `let test
+
+export function initialize(data) {
+    test = data
+}
+
+export async function load(url, context, nextLoad) {
+    if (url !== test.url) {
+        return nextLoad(url, context)
+    }
+    const {source} = await nextLoad(url, {...context, format: 'module'})
+    return {format: test.format, source, shortCircuit: true}
+}
+`
+
+/*
+ Module system of a JavaScript test run with node
+ */
+type ModuleType = 'esm' | 'cjs'
+
 /*
  Handler for executing JavaScript tests (.tst.js files)
- Uses Bun runtime to execute JavaScript test files directly
+ Uses Bun runtime to execute JavaScript test files directly by default.
+ Set compiler.javascript.mode to 'node' to run them with node.
  */
 export class JavaScriptTestHandler extends BaseTestHandler {
+    private artifactManager = new ArtifactManager()
+
     /*
      Checks if this handler can process the given test file
      @param file Test file to check
@@ -21,7 +50,8 @@ export class JavaScriptTestHandler extends BaseTestHandler {
     }
 
     /*
-     Executes JavaScript test file using Bun runtime
+     Executes JavaScript test file using Bun runtime, or node in node mode
+     In node mode, compiler.javascript.flags are given to node and the test is skipped if node is not installed.
      @param file JavaScript test file to execute
      @param config Test execution configuration
      @returns Promise resolving to test results
@@ -35,6 +65,26 @@ export class JavaScriptTestHandler extends BaseTestHandler {
             return await this.launchDebugger(file, config)
         }
 
+        const mode = config.compiler?.javascript?.mode || 'bun'
+        const container = Docker.getContainer(config, file)
+        let command = 'bun'
+        let args = [file.path]
+
+        if (mode === 'node') {
+            // The container provides its own node
+            const node = container ? config.compiler?.javascript?.node || 'node' : await this.findNode(config)
+            if (!node) {
+                const reason = 'Node.js not found, install node or set compiler.javascript.node'
+                return this.createTestResult(file, TestStatus.Skipped, 0, reason)
+            }
+            command = node
+            const flags = config.compiler?.javascript?.flags || []
+            args = [...flags, ...(await this.getModuleArgs(file, config)), file.path]
+        } else if (mode !== 'bun') {
+            const error = new Error(`Invalid compiler.javascript.mode "${mode}". Use bun or node`)
+            return this.createErrorResult(file, error)
+        }
+
         // Get test environment
         const testEnv = await this.getTestEnvironment(config, file)
 
@@ -42,11 +92,13 @@ export class JavaScriptTestHandler extends BaseTestHandler {
         await this.displayEnvironmentInfo(config, file, testEnv)
 
         const {result, duration} = await this.measureExecution(async () => {
-            return await this.runCommand('bun', [file.path], {
-                cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+            return await this.runCommand(command, [...args, ...(config.execution?.args || [])], {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container,
             })
         })
 
@@ -57,6 +109,97 @@ export class JavaScriptTestHandler extends BaseTestHandler {
         return this.createTestResult(file, status, duration, output, error, result.exitCode)
     }
 
+    /*
+     Removes the module hooks written for a test run with node
+     @param file JavaScript test file to clean up
+     @param config Test configuration
+     */
+    override async cleanup(file: TestFile, config?: TestConfig): Promise<void> {
+        if (config?.compiler?.javascript?.mode === 'node') {
+            await this.artifactManager.cleanArtifactDir(file)
+        }
+    }
+
+    /*
+     Finds the node executable (compiler.javascript.node, default node)
+     @param config Test configuration
+     @returns Path of node, or null if it is not installed
+     */
+    private async findNode(config: TestConfig): Promise<string | null> {
+        const node = config.compiler?.javascript?.node || 'node'
+        if (/[\\/]/.test(node)) {
+            return fs.existsSync(node) ? path.resolve(node) : null
+        }
+        return await PlatformDetector.findInPath(node)
+    }
+
+    /*
+     Gets the node flags that load a test with its module system
+     The module system is compiler.javascript.module, or inferred from the test's syntax in auto mode. If node would
+     load the test with the other system, because of the "type" of the nearest package.json, module hooks registered
+     with --import load it as configured.
+     @param file JavaScript test file
+     @param config Test configuration
+     @returns Node flags, empty if node loads the test as is
+     */
+    private async getModuleArgs(file: TestFile, config: TestConfig): Promise<string[]> {
+        const setting = config.compiler?.javascript?.module || 'auto'
+        const moduleType =
+            setting === 'auto'
+                ? JavaScriptTestHandler.inferModuleType(await fs.promises.readFile(file.path, 'utf-8'))
+                : setting
+        if (!moduleType || moduleType === JavaScriptTestHandler.getPackageType(file.directory)) {
+            return []
+        }
+        const dir = await this.artifactManager.createArtifactDir(file)
+        const hooks = path.join(dir, 'node-hooks.mjs')
+        const register = path.join(dir, 'node-register.mjs')
+        const data = {url: pathToFileURL(file.path).href, format: moduleType === 'esm' ? 'module' : 'commonjs'}
+        await fs.promises.writeFile(hooks, NODE_HOOKS)
+        await fs.promises.writeFile(
+            register,
+            `import {register} from 'node:module'\n\n` +
+                `register('./node-hooks.mjs', import.meta.url, {data: ${JSON.stringify(data)}})\n`
+        )
+        return ['--import', pathToFileURL(register).href]
+    }
+
+    /*
+     Infers the module system of a JavaScript test from its syntax
+     @param source Test source
+     @returns 'esm' for import or export statements, 'cjs' for require() or module.exports, else undefined
+     */
+    static inferModuleType(source: string): ModuleType | undefined {
+        if (/^\s*(import\s*[\w{*'"]|export\s)/m.test(source)) {
+            return 'esm'
+        }
+        if (/\brequire\s*\(|\bmodule\.exports\b|^\s*exports\./m.test(source)) {
+            return 'cjs'
+        }
+        return undefined
+    }
+
+    /*
+     Gets the module system node uses for .js files in a directory
+     @param dir Directory of the test
+     @returns 'esm' if the nearest package.json has "type": "module", else 'cjs'
+     */
+    static getPackageType(dir: string): ModuleType {
+        for (let current = dir; ; current = path.dirname(current)) {
+            const packagePath = path.join(current, 'package.json')
+            if (fs.existsSync(packagePath)) {
+                try {
+                    return JSON.parse(fs.readFileSync(packagePath, 'utf-8')).type === 'module' ? 'esm' : 'cjs'
+                } catch {
+                    return 'cjs'
+                }
+            }
+            if (path.dirname(current) === current) {
+                return 'cjs'
+            }
+        }
+    }
+
     /*
      Ensures testme module is linked by checking for node_modules/testme
      If not found, runs 'bun link testme' in the appropriate directory
@@ -141,7 +284,7 @@ export class JavaScriptTestHandler extends BaseTestHandler {
             const debuggerName = config.debug?.js || this.getDefaultDebugger()
 
             console.log(`\n🐛 Launching ${debuggerName} debugger for: ${file.path}`)
-            console.log(`Working directory: ${file.directory}\n`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}\n`)
 
             switch (debuggerName) {
                 case 'vscode':
@@ -276,16 +419,12 @@ export class JavaScriptTestHandler extends BaseTestHandler {
         const startTime = performance.now()
 
         console.log(`Launching custom debugger: ${debuggerPath}`)
-        const result = await this.runCommand(debuggerPath, [file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const args = [file.path, ...(config.execution?.args || [])]
+        const env = await this.getTestEnvironment(config, file)
+        const exitCode = await this.runDebugger(debuggerPath, args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
-
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
     }
 }
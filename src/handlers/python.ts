@@ -1,10 +1,14 @@
 import type {TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
+import {PlatformDetector} from '../platform/detector.ts'
+import {delimiter, isAbsolute, join, resolve} from 'path'
+import {existsSync} from 'fs'
 
 /**
  * Handler for executing Python tests (.tst.py files)
- * Uses python3/python command to execute Python test files directly
+ * Uses the configured interpreter (default python3/python) to execute Python test files directly
  */
 export class PythonTestHandler extends BaseTestHandler {
     /**
@@ -25,7 +29,9 @@ export class PythonTestHandler extends BaseTestHandler {
      * @returns Promise resolving to test results
      *
      * @remarks
-     * Tries python3 first (preferred), falls back to python if python3 is not available.
+     * Uses `compiler.python.interpreter` if set, otherwise tries python3 first and falls back to python.
+     * If `compiler.python.venv` is set, the virtualenv is activated (PATH and VIRTUAL_ENV) before running.
+     * Extra interpreter flags come from `compiler.python.args`.
      * Tests should use standard exit codes: 0 for success, non-zero for failure.
      */
     async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
@@ -35,20 +41,22 @@ export class PythonTestHandler extends BaseTestHandler {
         }
 
         // Get test environment
-        const testEnv = await this.getTestEnvironment(config, file)
+        const testEnv = await this.getPythonEnvironment(config, file)
 
         // Display environment info if showCommands is enabled
         await this.displayEnvironmentInfo(config, file, testEnv)
 
         const {result, duration} = await this.measureExecution(async () => {
-            // Try python3 first, fall back to python
-            const pythonCommand = await this.getPythonCommand()
+            const pythonCommand = await this.getPythonCommand(config)
+            const args = [...(config.compiler?.python?.args || []), file.path, ...(config.execution?.args || [])]
 
-            return await this.runCommand(pythonCommand, [file.path], {
-                cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+            return await this.runCommand(pythonCommand, args, {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container: Docker.getContainer(config, file),
             })
         })
 
@@ -71,7 +79,7 @@ export class PythonTestHandler extends BaseTestHandler {
             const debuggerName = config.debug?.py || this.getDefaultDebugger()
 
             console.log(`\n🐛 Launching ${debuggerName} debugger for: ${file.path}`)
-            console.log(`Working directory: ${file.directory}\n`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}\n`)
 
             switch (debuggerName) {
                 case 'vscode':
@@ -116,10 +124,10 @@ export class PythonTestHandler extends BaseTestHandler {
         console.log('5. Select "Python File" configuration\n')
         console.log('Alternatively, use pdb debugger: tm --debug with pdb configured\n')
 
-        const pythonCommand = await this.getPythonCommand()
-        const result = await this.runCommand(pythonCommand, [file.path], {
+        const pythonCommand = await this.getPythonCommand(config)
+        const result = await this.runCommand(pythonCommand, [...(config.compiler?.python?.args || []), file.path], {
             cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
+            env: await this.getPythonEnvironment(config, file),
         })
 
         const duration = performance.now() - startTime
@@ -151,18 +159,15 @@ export class PythonTestHandler extends BaseTestHandler {
         console.log('  p <var> - print variable')
         console.log('  q - quit\n')
 
-        const pythonCommand = await this.getPythonCommand()
-        const result = await this.runCommand(pythonCommand, ['-m', 'pdb', file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const pythonCommand = await this.getPythonCommand(config)
+        const pythonArgs = config.compiler?.python?.args || []
+        const args = [...pythonArgs, '-m', 'pdb', file.path, ...(config.execution?.args || [])]
+        const env = await this.getPythonEnvironment(config, file)
+        const exitCode = await this.runDebugger(pythonCommand, args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
-
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
     }
 
     /**
@@ -177,29 +182,90 @@ export class PythonTestHandler extends BaseTestHandler {
         const startTime = performance.now()
 
         console.log(`Launching custom debugger: ${debuggerPath}`)
-        const result = await this.runCommand(debuggerPath, [file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const args = [file.path, ...(config.execution?.args || [])]
+        const env = await this.getPythonEnvironment(config, file)
+        const exitCode = await this.runDebugger(debuggerPath, args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
+    }
 
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+    /**
+     * Gets the test environment with the configured virtualenv activated
+     *
+     * @param config - Test configuration
+     * @param file - Python test file
+     * @returns Promise resolving to environment variables for the test process
+     *
+     * @remarks
+     * Mirrors the venv activate script: sets VIRTUAL_ENV, prepends the venv bin
+     * directory to PATH and removes PYTHONHOME so imports resolve from the venv.
+     */
+    private async getPythonEnvironment(config: TestConfig, file: TestFile): Promise<Record<string, string>> {
+        const env = await this.getTestEnvironment(config, file)
+        const venv = this.getVenvPath(config)
+        if (venv) {
+            const basePath = env.PATH ?? process.env.PATH ?? ''
+            env.VIRTUAL_ENV = venv
+            env.PATH = basePath ? `${this.getVenvBinDir(venv)}${delimiter}${basePath}` : this.getVenvBinDir(venv)
+            env.PYTHONHOME = ''
+        }
+        return env
+    }
+
+    /**
+     * Gets the absolute virtualenv path from compiler.python.venv
+     *
+     * @param config - Test configuration
+     * @returns Absolute venv path, or undefined if no venv is configured
+     */
+    private getVenvPath(config: TestConfig): string | undefined {
+        const venv = config.compiler?.python?.venv
+        if (!venv) {
+            return undefined
+        }
+        return isAbsolute(venv) ? venv : resolve(config.configDir || process.cwd(), venv)
     }
 
     /**
-     * Determines which Python command to use (python3 or python)
+     * Gets the directory containing the virtualenv executables
      *
+     * @param venv - Absolute virtualenv path
+     * @returns Path to the venv bin (or Scripts on Windows) directory
+     */
+    private getVenvBinDir(venv: string): string {
+        return join(venv, PlatformDetector.isWindows() ? 'Scripts' : 'bin')
+    }
+
+    /**
+     * Determines which Python command to use
+     *
+     * @param config - Test configuration
      * @returns Promise resolving to the Python command to use
      *
      * @remarks
-     * Checks for python3 first (modern systems), falls back to python.
-     * Caches the result for performance.
+     * Uses compiler.python.interpreter if set. Bare interpreter names are looked up in the
+     * virtualenv first when compiler.python.venv is set. Without an interpreter, the venv
+     * python is used if present, otherwise python3 is preferred with a fallback to python.
      */
-    private async getPythonCommand(): Promise<string> {
+    private async getPythonCommand(config: TestConfig): Promise<string> {
+        const interpreter = config.compiler?.python?.interpreter
+        const venv = this.getVenvPath(config)
+        if (venv) {
+            const ext = PlatformDetector.isWindows() ? '.exe' : ''
+            const candidates = interpreter ? [interpreter] : ['python3', 'python']
+            for (const name of candidates) {
+                const path = join(this.getVenvBinDir(venv), name + ext)
+                if (!name.includes('/') && !name.includes('\\') && existsSync(path)) {
+                    return path
+                }
+            }
+        }
+        if (interpreter) {
+            return interpreter
+        }
+
         // Try python3 first (preferred on most systems)
         try {
             const proc = Bun.spawn(['which', 'python3'], {
@@ -0,0 +1,203 @@
+import type {BuildResult, TestFile, TestResult, TestConfig} from '../types.ts'
+import {TestStatus, TestType} from '../types.ts'
+import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
+import {ArtifactManager} from '../artifacts.ts'
+import {PermissionManager} from '../platform/permissions.ts'
+import {GlobExpansion} from '../utils/glob-expansion.ts'
+import {Remote} from '../remote.ts'
+import {checkBuild, hashCommand, saveBuild} from '../utils/build-cache.ts'
+import {basename} from 'path'
+
+/**
+ * Handler for executing Rust tests (.tst.rs files)
+ * Compiles each test with rustc into the artifact directory, then runs the binary
+ */
+export class RustTestHandler extends BaseTestHandler {
+    private artifactManager: ArtifactManager
+
+    constructor() {
+        super()
+        this.artifactManager = new ArtifactManager()
+    }
+
+    /**
+     * Checks if this handler can process the given test file
+     *
+     * @param file - Test file to check
+     * @returns true if file is a Rust test
+     */
+    canHandle(file: TestFile): boolean {
+        return file.type === TestType.Rust
+    }
+
+    /**
+     * Creates the artifact directory for the compiled test binary
+     *
+     * @param file - Rust test file to prepare
+     */
+    override async prepare(file: TestFile): Promise<void> {
+        await this.artifactManager.createArtifactDir(file)
+    }
+
+    /**
+     * Removes the compiled test binary and compile log
+     *
+     * @param file - Rust test file to clean up
+     */
+    override async cleanup(file: TestFile): Promise<void> {
+        await this.artifactManager.cleanArtifactDir(file)
+    }
+
+    /**
+     * Compiles and executes a Rust test file
+     *
+     * @param file - Rust test file to execute
+     * @param config - Test execution configuration
+     * @returns Promise resolving to test results
+     *
+     * @remarks
+     * Compiler, flags and libraries come from `compiler.rust` in testme.json5.
+     * Compilation failures are reported with error status, distinct from test failures.
+     * With --remote (or remote.host), the binary is run on the remote host over ssh.
+     * Tests should use standard exit codes: 0 for success, non-zero for failure.
+     */
+    async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
+        const compileResult = await this.compile(file, config)
+        if (!compileResult.success) {
+            return this.createTestResult(
+                file,
+                TestStatus.Error,
+                compileResult.duration,
+                compileResult.output,
+                compileResult.error
+            )
+        }
+
+        // Get test environment
+        const testEnv = await this.getTestEnvironment(config, file)
+
+        // Display environment info if showCommands is enabled
+        await this.displayEnvironmentInfo(config, file, testEnv)
+
+        const {result, duration} = await this.measureExecution(async () => {
+            if (Remote.getHost(config)) {
+                return await this.runRemote(this.getBinaryPath(file), file, config)
+            }
+            return await this.runCommand(this.getBinaryPath(file), config.execution?.args || [], {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
+                env: testEnv,
+                stdin: config.execution?.stdin,
+                config,
+                container: Docker.getContainer(config, file),
+                description: `Test ${file.name}`,
+            })
+        })
+
+        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        const output = this.combineOutput(result.stdout, result.stderr)
+        const error = result.exitCode !== 0 ? result.stderr : undefined
+
+        return this.createTestResult(file, status, compileResult.duration + duration, output, error, result.exitCode)
+    }
+
+    /**
+     * Compiles the Rust test ahead of running it (--build-workers)
+     *
+     * @param file - Rust test file to compile
+     * @param config - Test configuration with compiler.rust settings
+     * @returns Compilation result
+     */
+    async build(file: TestFile, config: TestConfig): Promise<BuildResult> {
+        return await this.compile(file, config)
+    }
+
+    /**
+     * Compiles the Rust test with rustc
+     *
+     * @param file - Rust test file to compile
+     * @param config - Test configuration with compiler.rust settings
+     * @returns Compilation result with success status, duration, and output
+     *
+     * @remarks
+     * Skips compilation if the source, compiler and arguments are unchanged since the binary was built
+     * (unless --rebuild is set), so changing compiler.rust flags or libraries rebuilds the test.
+     * The crate is named after the test, as rustc cannot derive a name from `name.tst.rs`.
+     * Library entries of the form `name=path` are passed as `--extern name=path` crates.
+     * Other entries are passed as native libraries with `-l`.
+     */
+    private async compile(
+        file: TestFile,
+        config: TestConfig
+    ): Promise<{success: boolean; duration: number; output: string; error?: string}> {
+        const binaryPath = this.getBinaryPath(file)
+        const rustConfig = config.compiler?.rust
+        const compiler = rustConfig?.compiler || 'rustc'
+        const baseDir = config.configDir || file.directory
+        const flags = await GlobExpansion.expandArray(rustConfig?.flags || [], baseDir)
+        const libraries: string[] = []
+        for (const library of rustConfig?.libraries || []) {
+            libraries.push(...(library.includes('=') ? ['--extern', library] : ['-l', library]))
+        }
+        // rustc cannot derive a crate name from a file name containing dots (name.tst.rs)
+        const crateName = basename(file.name, '.tst.rs').replace(/\W/g, '_')
+        const args = [...flags, '--crate-name', crateName, '-o', binaryPath, file.path, ...libraries]
+
+        // Reuse the binary if its source and compiler command are unchanged
+        const command = hashCommand(compiler, args)
+        if (!config.execution?.rebuild && (await checkBuild(binaryPath, file.path, command)) === null) {
+            return {success: true, duration: 0, output: ''}
+        }
+
+        if (config.execution?.showCommands) {
+            console.log(`\n🔧 Compile: ${compiler} ${args.join(' ')}`)
+        }
+
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand(compiler, args, {
+                cwd: baseDir, // Compile from config directory so relative paths in flags work correctly
+                timeout: 60000, // 1 minute for compilation
+                description: `Compilation of ${file.name}`,
+                container: Docker.getContainer(config, file, true),
+            })
+        })
+
+        const logContent = `Compiler: ${compiler}
+Command: ${compiler} ${args.join(' ')}
+Exit Code: ${result.exitCode}
+STDOUT:
+${result.stdout}
+STDERR:
+${result.stderr}`
+        try {
+            await this.artifactManager.writeArtifact(file, 'compile.log', logContent)
+            if (result.exitCode === 0) {
+                await saveBuild(binaryPath, file.path, command)
+            }
+        } catch {
+            // Ignore write errors - the compilation log and build record are not critical
+        }
+
+        if (result.exitCode !== 0) {
+            return {
+                success: false,
+                duration,
+                output: result.stdout,
+                error: `Compilation failed:\n${result.stderr}`,
+            }
+        }
+        return {success: true, duration, output: ''}
+    }
+
+    /**
+     * Gets the path of the compiled test binary in the artifact directory
+     *
+     * @param file - Rust test file
+     * @returns Binary path with platform executable extension
+     */
+    private getBinaryPath(file: TestFile): string {
+        const binaryName = PermissionManager.addBinaryExtension(basename(file.name, '.tst.rs'))
+        return this.artifactManager.getArtifactPath(file, binaryName)
+    }
+}
@@ -1,12 +1,14 @@
 import type {TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
 import {PermissionManager} from '../platform/permissions.ts'
 import {ShellDetector} from '../platform/shell.ts'
 
 /*
  Handler for executing shell script tests (.tst.sh files)
- Automatically detects the appropriate shell and makes scripts executable
+ Automatically detects the appropriate shell and makes scripts executable. Unix shell tests run under the interpreter
+ chosen by --shell, the script's shebang or shell.interpreter, with any shell.flags.
  */
 export class ShellTestHandler extends BaseTestHandler {
     /*
@@ -39,31 +41,106 @@ export class ShellTestHandler extends BaseTestHandler {
         @returns Promise resolving to test results
      */
     async execute(file: TestFile, config: TestConfig): Promise<TestResult> {
+        // Handle debug mode
+        if (config.execution?.debugMode) {
+            return await this.launchDebugger(file, config)
+        }
+
+        // Determine shell to use. PowerShell tests are skipped where PowerShell is not installed.
+        const interpreter = await this.getInterpreter(file, config)
+        if (!interpreter) {
+            const reason = 'PowerShell not found, install pwsh or set shell.powershell'
+            return this.createTestResult(file, TestStatus.Skipped, 0, reason)
+        }
+        const [shell, ...flags] = interpreter
+
         // Get test environment
         const testEnv = await this.getTestEnvironment(config, file)
 
         // Display environment info if showCommands is enabled
         await this.displayEnvironmentInfo(config, file, testEnv)
+        const shellType = ShellDetector.getShellTypeFromExtension(file.path)
+        const args = [...flags, ...ShellDetector.getShellArgs(shellType, file.path), ...(config.execution?.args || [])]
 
         const {result, duration} = await this.measureExecution(async () => {
-            // Determine shell to use
-            const shell = await ShellDetector.detectShell(file.path)
-            const shellType = ShellDetector.getShellTypeFromExtension(file.path)
-            const args = ShellDetector.getShellArgs(shellType, file.path)
-
-            return await this.runCommand(shell, args, {
-                cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+            return await this.runCommand(shell!, args, {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container: Docker.getContainer(config, file),
                 description: `Test ${file.name}`,
             })
         })
 
         const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
+        let output = this.combineOutput(result.stdout, result.stderr)
+        if (config.output?.verbose && file.type === TestType.Shell) {
+            output = `Interpreter: ${[shell, ...flags].join(' ')}\n${output}`.trim()
+        }
         const error = result.exitCode !== 0 ? result.stderr : undefined
 
         return this.createTestResult(file, status, duration, output, error, result.exitCode)
     }
+
+    /*
+        Gets the interpreter and its options for a shell test
+        Unix shell tests (.tst.sh) use --shell, the shebang or shell.interpreter with shell.flags. PowerShell tests use
+        shell.powershell or pwsh, and batch tests use cmd.exe.
+        @param file Shell test file
+        @param config Test execution configuration
+        @returns Interpreter followed by its options, or null if PowerShell is not installed
+     */
+    private async getInterpreter(file: TestFile, config: TestConfig): Promise<string[] | null> {
+        if (file.type === TestType.PowerShell) {
+            const powershell = await ShellDetector.selectPowerShell(config.shell?.powershell)
+            return powershell ? [powershell] : null
+        }
+        if (file.type !== TestType.Shell) {
+            return [await ShellDetector.detectShell(file.path)]
+        }
+        const interpreter = config.shell?.interpreter
+        const shell = await ShellDetector.selectInterpreter(file.path, interpreter, config.execution?.shell)
+        return [shell, ...(config.shell?.flags || [])]
+    }
+
+    /*
+        Runs a shell test interactively for debugging (--debug)
+        The default xtrace debugger runs the script with the shell's -x option, tracing each command as it runs.
+        Otherwise debug.sh names a debugger executable, such as bashdb, which is given the script and its arguments.
+        @param file Shell test file to debug
+        @param config Test execution configuration
+        @returns Promise resolving to test results
+     */
+    private async launchDebugger(file: TestFile, config: TestConfig): Promise<TestResult> {
+        const startTime = performance.now()
+        const debuggerName = config.debug?.sh || 'xtrace'
+        const testArgs = config.execution?.args || []
+        let command: string
+        let args: string[]
+        if (debuggerName !== 'xtrace') {
+            command = debuggerName
+            args = [file.path, ...testArgs]
+        } else if (file.type === TestType.Shell) {
+            const [shell, ...flags] = (await this.getInterpreter(file, config))!
+            command = shell!
+            args = [...flags, '-x', file.path, ...testArgs]
+        } else {
+            const error = `The xtrace debugger only supports Unix shell tests, set debug.sh to debug ${file.name}`
+            return this.createTestResult(file, TestStatus.Error, 0, '', error)
+        }
+        try {
+            console.log(`\n🐛 Launching ${debuggerName} debugger for: ${file.path}`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}\n`)
+            const env = await this.getTestEnvironment(config, file)
+            const exitCode = await this.runDebugger(command, args, file, config, env)
+
+            const duration = performance.now() - startTime
+            const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+            return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
+        } catch (error) {
+            return this.createErrorResult(file, error)
+        }
+    }
 }
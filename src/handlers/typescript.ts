@@ -1,16 +1,22 @@
 import type {TestFile, TestResult, TestConfig} from '../types.ts'
 import {TestStatus, TestType} from '../types.ts'
 import {BaseTestHandler} from './base.ts'
+import {Docker} from '../docker.ts'
 import {PlatformDetector} from '../platform/detector.ts'
+import {ArtifactManager} from '../artifacts.ts'
 import * as path from 'path'
 import * as fs from 'fs'
 import * as os from 'os'
 
 /*
  Handler for executing TypeScript tests (.tst.ts files)
- Uses Bun runtime to execute TypeScript test files directly (with transpilation)
+ Uses Bun runtime to execute TypeScript test files directly (with transpilation) by default.
+ Set compiler.typescript.mode to 'tsc' to type-check and transpile with tsc and run with node,
+ or to 'ts-node' to run through ts-node.
  */
 export class TypeScriptTestHandler extends BaseTestHandler {
+    private artifactManager = new ArtifactManager()
+
     /*
      Checks if this handler can process the given test file
      @param file Test file to check
@@ -21,8 +27,9 @@ export class TypeScriptTestHandler extends BaseTestHandler {
     }
 
     /*
-     Executes TypeScript test file using Bun runtime
-     Bun can execute TypeScript files directly with built-in transpilation
+     Executes TypeScript test file using the configured mode
+     Bun (default) executes TypeScript files directly with built-in transpilation.
+     Compile and type errors in tsc and ts-node modes are reported with error status.
      @param file TypeScript test file to execute
      @param config Test execution configuration
      @returns Promise resolving to test results
@@ -36,6 +43,36 @@ export class TypeScriptTestHandler extends BaseTestHandler {
             return await this.launchDebugger(file, config)
         }
 
+        const mode = config.compiler?.typescript?.mode || 'bun'
+        let command = 'bun'
+        let args = [file.path]
+        let compileDuration = 0
+
+        if (mode === 'tsc') {
+            const compileResult = await this.compile(file, config)
+            if (!compileResult.success) {
+                return this.createTestResult(
+                    file,
+                    TestStatus.Error,
+                    compileResult.duration,
+                    compileResult.output,
+                    compileResult.error
+                )
+            }
+            command = 'node'
+            args = [compileResult.outputPath]
+            compileDuration = compileResult.duration
+        } else if (mode === 'ts-node') {
+            const tsconfig = this.getTsconfigPath(config)
+            command = 'ts-node'
+            args = tsconfig ? ['--project', tsconfig, file.path] : [file.path]
+        } else if (mode !== 'bun') {
+            return this.createErrorResult(
+                file,
+                new Error(`Invalid compiler.typescript.mode "${mode}". Use bun, tsc or ts-node`)
+            )
+        }
+
         // Get test environment
         const testEnv = await this.getTestEnvironment(config, file)
 
@@ -43,21 +80,115 @@ export class TypeScriptTestHandler extends BaseTestHandler {
         await this.displayEnvironmentInfo(config, file, testEnv)
 
         const {result, duration} = await this.measureExecution(async () => {
-            // Bun can execute TypeScript files directly
-            return await this.runCommand('bun', [file.path], {
-                cwd: file.directory,
-                timeout: (config.execution?.timeout || 30) * 1000,
+            return await this.runCommand(command, [...args, ...(config.execution?.args || [])], {
+                cwd: BaseTestHandler.getWorkingDirectory(config, file),
+                timeout: BaseTestHandler.getTimeout(config, file),
                 env: testEnv,
+                stdin: config.execution?.stdin,
                 config,
+                container: Docker.getContainer(config, file),
                 description: `Test ${file.name}`,
             })
         })
 
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
         const output = this.combineOutput(result.stdout, result.stderr)
         const error = result.exitCode !== 0 ? result.stderr : undefined
 
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        // ts-node reports type errors as a TSError before the test runs
+        if (mode === 'ts-node' && result.exitCode !== 0 && result.stderr.includes('TSError')) {
+            return this.createTestResult(file, TestStatus.Error, duration, output, `Compilation failed:\n${error}`)
+        }
+        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+
+        return this.createTestResult(file, status, compileDuration + duration, output, error, result.exitCode)
+    }
+
+    /*
+     Removes transpiled output after a successful test (tsc mode only)
+     @param file TypeScript test file to clean up
+     @param config Test configuration
+     */
+    override async cleanup(file: TestFile, config?: TestConfig): Promise<void> {
+        if (config?.compiler?.typescript?.mode === 'tsc') {
+            await this.artifactManager.cleanArtifactDir(file)
+        }
+    }
+
+    /*
+     Type-checks and transpiles the test with tsc into the artifact directory
+     A generated tsconfig.json extends compiler.typescript.tsconfig (if set) and compiles only this test.
+     The output mirrors source paths from the filesystem root so relative imports outside the test
+     directory still compile. A package.json marks the output as CommonJS so node runs it regardless
+     of the project module type.
+     @param file TypeScript test file to compile
+     @param config Test configuration
+     @returns Compilation result with the path of the transpiled test
+     */
+    private async compile(
+        file: TestFile,
+        config: TestConfig
+    ): Promise<{success: boolean; duration: number; outputPath: string; output: string; error?: string}> {
+        const outDir = await this.artifactManager.createArtifactDir(file)
+        const rootDir = path.parse(file.path).root
+        const outputPath = path.join(outDir, path.relative(rootDir, file.path)).replace(/\.ts$/, '.js')
+        const tsconfig = this.getTsconfigPath(config)
+
+        // Reuse the transpiled output if it is newer than the source (e.g., when retrying a test)
+        if (!config.execution?.rebuild && fs.existsSync(outputPath)) {
+            if (fs.statSync(outputPath).mtimeMs >= fs.statSync(file.path).mtimeMs) {
+                return {success: true, duration: 0, outputPath, output: ''}
+            }
+        }
+
+        const project = {
+            ...(tsconfig && {extends: tsconfig}),
+            compilerOptions: {
+                ...(!tsconfig && {target: 'ES2022', esModuleInterop: true, skipLibCheck: true}),
+                module: 'commonjs',
+                moduleResolution: 'node',
+                rootDir,
+                outDir,
+                noEmit: false,
+            },
+            files: [file.path],
+        }
+        const projectPath = path.join(outDir, 'tsconfig.json')
+        fs.writeFileSync(projectPath, JSON.stringify(project, null, 4))
+        fs.writeFileSync(path.join(outDir, 'package.json'), JSON.stringify({type: 'commonjs'}, null, 4))
+
+        if (config.execution?.showCommands) {
+            console.log(`\n🔧 Compile: tsc --project ${projectPath}`)
+        }
+
+        const {result, duration} = await this.measureExecution(async () => {
+            return await this.runCommand('tsc', ['--project', projectPath], {
+                cwd: file.directory,
+                timeout: 60000, // 1 minute for compilation
+                description: `Compilation of ${file.name}`,
+            })
+        })
+
+        if (result.exitCode !== 0) {
+            // tsc writes diagnostics to stdout
+            return {
+                success: false,
+                duration,
+                outputPath,
+                output: result.stdout,
+                error: `Compilation failed:\n${result.stdout || result.stderr}`,
+            }
+        }
+        return {success: true, duration, outputPath, output: ''}
+    }
+
+    /*
+     Gets the absolute path of compiler.typescript.tsconfig
+     @param config Test configuration
+     @returns Absolute tsconfig path, or undefined if not configured
+     */
+    private getTsconfigPath(config: TestConfig): string | undefined {
+        const tsconfig = config.compiler?.typescript?.tsconfig
+        return tsconfig ? path.resolve(config.configDir || process.cwd(), tsconfig) : undefined
     }
 
     /*
@@ -144,7 +275,7 @@ export class TypeScriptTestHandler extends BaseTestHandler {
             const debuggerName = config.debug?.ts || this.getDefaultDebugger()
 
             console.log(`\n🐛 Launching ${debuggerName} debugger for: ${file.path}`)
-            console.log(`Working directory: ${file.directory}\n`)
+            console.log(`Working directory: ${BaseTestHandler.getWorkingDirectory(config, file)}\n`)
 
             switch (debuggerName) {
                 case 'vscode':
@@ -279,16 +410,12 @@ export class TypeScriptTestHandler extends BaseTestHandler {
         const startTime = performance.now()
 
         console.log(`Launching custom debugger: ${debuggerPath}`)
-        const result = await this.runCommand(debuggerPath, [file.path], {
-            cwd: file.directory,
-            env: await this.getTestEnvironment(config, file),
-        })
+        const args = [file.path, ...(config.execution?.args || [])]
+        const env = await this.getTestEnvironment(config, file)
+        const exitCode = await this.runDebugger(debuggerPath, args, file, config, env)
 
         const duration = performance.now() - startTime
-        const status = result.exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
-        const output = this.combineOutput(result.stdout, result.stderr)
-        const error = result.exitCode !== 0 ? result.stderr : undefined
-
-        return this.createTestResult(file, status, duration, output, error, result.exitCode)
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        return this.createTestResult(file, status, duration, 'Debugging session completed.', undefined, exitCode)
     }
 }
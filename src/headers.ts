@@ -0,0 +1,40 @@
+import cHeader from './modules/c/testme.h' with {type: 'text'}
+import goHelper from './modules/go/testme.go' with {type: 'text'}
+import shHelper from './modules/sh/testme.sh' with {type: 'text'}
+
+/*
+ Languages with an assertion header or result helper that --emit-header can print
+ */
+export const HEADER_LANGUAGES = ['c', 'go', 'sh']
+
+/*
+ TestHeaders - Assertion headers and result helpers bundled with testme for --emit-header
+
+ The files are embedded in the tm binary, so a project can vendor the version matching its installed testme
+ without a package install: "tm --emit-header c > testme.h". The C header provides the fatal assertions (ttrue,
+ teqi, ...) and the soft assertions (tcheck, teq_int, teq_str, ...) that print standardized PASS and FAIL lines
+ which testme counts as assertions. The go package and sh script report named sub-results with "TESTME {...}"
+ protocol lines (see HarnessProtocol).
+ */
+export class TestHeaders {
+    /*
+     Gets the assertion header or result helper for a language
+     @param language Language name (c, go or sh)
+     @returns File text
+     @throws Error if the language has no header
+     */
+    static get(language: string): string {
+        switch (language) {
+            case 'c':
+                return cHeader
+            case 'go':
+                return goHelper
+            case 'sh':
+                return shHelper
+            default:
+                throw new Error(
+                    `No header for language: "${language}". Supported languages: ${HEADER_LANGUAGES.join(', ')}`
+                )
+        }
+    }
+}
@@ -0,0 +1,135 @@
+import type {TestResult} from './types.ts'
+import {getCategory} from './utils/categories.ts'
+import {existsSync} from 'fs'
+import {mkdir, readFile, writeFile} from 'fs/promises'
+import {join, relative, resolve} from 'path'
+
+/*
+ Most recent outcomes kept for each test
+ */
+export const HISTORY_SIZE = 20
+
+/*
+ Lowest flakiness score reported by --flaky-report
+ */
+export const FLAKY_THRESHOLD = 0.2
+
+/*
+ A test whose recent outcomes alternate between passing and failing
+ */
+export type FlakyTest = {
+    path: string // Absolute test path
+    history: string // Outcomes, oldest first: P (passed) or F (failed)
+    failures: number // Failed runs in the history
+    score: number // Fraction of consecutive runs whose outcome changed (0 to 1)
+}
+
+/*
+ TestHistory - Records each test's recent pass/fail outcomes so --flaky-report can find unstable tests
+
+ Outcomes are stored in .testme/history.json as a JSON object mapping test paths, relative to the test
+ root, to a string of P (passed) and F (failed) characters, oldest first, capped at the last HISTORY_SIZE
+ runs. A test's cases and matrix cells form one outcome per run, which fails if any of them failed. Skipped
+ tests and tests that did not run are not recorded.
+
+ The flakiness score is the fraction of consecutive runs whose outcome changed, so a test that alternates
+ scores 1 and a test that is always passing or always failing scores 0. A single change is a fix or a
+ regression rather than flakiness, so tests need at least two changes to be reported.
+ */
+export class TestHistory {
+    /*
+     Gets the history file path
+     @param rootDir Test root directory
+     @returns Path of the history file
+     */
+    static getPath(rootDir: string): string {
+        return join(rootDir, '.testme', 'history.json')
+    }
+
+    /*
+     Loads the recorded outcomes
+     @param rootDir Test root directory
+     @returns Outcomes keyed by absolute test path, or null if no history file exists
+     */
+    static async load(rootDir: string): Promise<Map<string, string> | null> {
+        const path = this.getPath(rootDir)
+        if (!existsSync(path)) {
+            return null
+        }
+        try {
+            const data = JSON.parse(await readFile(path, 'utf-8')) as Record<string, unknown>
+            const history = new Map<string, string>()
+            for (const [test, outcomes] of Object.entries(data)) {
+                if (typeof outcomes === 'string' && /^[PF]+$/.test(outcomes)) {
+                    history.set(resolve(rootDir, test), outcomes)
+                }
+            }
+            return history
+        } catch (error) {
+            console.warn(`⚠️  Ignoring invalid history file ${path}: ${error}`)
+            return null
+        }
+    }
+
+    /*
+     Appends the outcomes of a run to the recorded history
+     @param rootDir Test root directory
+     @param results Results of the tests that ran
+     */
+    static async save(rootDir: string, results: TestResult[]): Promise<void> {
+        const history = (await this.load(rootDir)) || new Map<string, string>()
+        const outcomes = new Map<string, string>()
+        for (const result of results) {
+            const category = getCategory(result)
+            if (category !== 'skip') {
+                const failed = outcomes.get(result.file.path) === 'F' || category !== 'pass'
+                outcomes.set(result.file.path, failed ? 'F' : 'P')
+            }
+        }
+        for (const [path, outcome] of outcomes) {
+            history.set(path, ((history.get(path) || '') + outcome).slice(-HISTORY_SIZE))
+        }
+        const data: Record<string, string> = {}
+        for (const [path, outcome] of [...history].sort(([a], [b]) => a.localeCompare(b))) {
+            data[relative(rootDir, path).replace(/\\/g, '/')] = outcome
+        }
+        await mkdir(join(rootDir, '.testme'), {recursive: true})
+        await writeFile(this.getPath(rootDir), JSON.stringify(data, null, 2) + '\n')
+    }
+
+    /*
+     Computes the flakiness score of a test's outcomes
+     @param history Outcomes, oldest first
+     @returns Fraction of consecutive runs whose outcome changed
+     */
+    static getScore(history: string): number {
+        return history.length < 2 ? 0 : this.countChanges(history) / (history.length - 1)
+    }
+
+    /*
+     Finds the tests whose recent outcomes are unstable
+     @param history Recorded outcomes keyed by test path
+     @param threshold Lowest score reported
+     @returns Flaky tests, most unstable first
+     */
+    static getFlaky(history: Map<string, string>, threshold = FLAKY_THRESHOLD): FlakyTest[] {
+        const flaky: FlakyTest[] = []
+        for (const [path, outcomes] of history) {
+            const score = this.getScore(outcomes)
+            if (this.countChanges(outcomes) >= 2 && score >= threshold) {
+                flaky.push({path, history: outcomes, failures: outcomes.split('F').length - 1, score})
+            }
+        }
+        return flaky.sort((a, b) => b.score - a.score || b.failures - a.failures || a.path.localeCompare(b.path))
+    }
+
+    private static countChanges(history: string): number {
+        let changes = 0
+        for (let i = 1; i < history.length; i++) {
+            if (history[i] !== history[i - 1]) {
+                changes++
+            }
+        }
+        return changes
+    }
+}
@@ -2,132 +2,78 @@
 
 import {CliParser} from './cli.ts'
 import {ConfigManager} from './config.ts'
+import {ConfigSchema} from './schema.ts'
 import {TestRunner} from './runner.ts'
 import {ServiceManager} from './services.ts'
 import {TestDiscovery} from './discovery.ts'
 import {VERSION} from './version.ts'
-import type {TestConfig, TestFile} from './types.ts'
-import {TestStatus} from './types.ts'
-import {resolve, relative, join, sep} from 'path'
+import {MultiReporter, createReporter, isStdoutReport, resolveReportSpecs} from './reporters/index.ts'
+import {clearScreen, reserveStdout, useColor} from './utils/tty.ts'
+import {EventStream} from './events.ts'
+import {TestLogs} from './logdir.ts'
+import {RunProgress} from './progress.ts'
+import {ProcessManager} from './platform/process.ts'
+import {FileWatcher} from './watch.ts'
+import {LastFailures} from './failures.ts'
+import {TestTimings} from './timings.ts'
+import {FLAKY_THRESHOLD, HISTORY_SIZE, TestHistory} from './history.ts'
+import {Quarantine} from './quarantine.ts'
+import {Notifier} from './notify.ts'
+import {RunMetrics} from './metrics.ts'
+import {TestShards} from './shards.ts'
+import {ConfigTemplate} from './init.ts'
+import {Doctor} from './doctor.ts'
+import {Completion} from './completion.ts'
+import {TestHeaders} from './headers.ts'
+import {TestCases} from './cases.ts'
+import {TestOrder} from './order.ts'
+import {TestSuites} from './suites.ts'
+import {TestTags} from './tags.ts'
+import {Matrix} from './matrix.ts'
+import {Fixtures} from './fixtures.ts'
+import type {DirectoryFixtures, FixtureBatch} from './fixtures.ts'
+import type {MatrixCell} from './matrix.ts'
+import {dependsOnChanges, getChangedFiles} from './utils/changes.ts'
+import {randomSeed, seededRandom, shuffle} from './utils/shuffle.ts'
+import {DryRun} from './utils/dry-run.ts'
+import {createHandlers} from './handlers/index.ts'
+import type {CliOptions, CoverageReport, TestConfig, TestFile, TestResult, Reporter} from './types.ts'
+import {TestStatus, TestType} from './types.ts'
+import {basename, resolve, relative, join, sep} from 'path'
 import {writeFile} from 'fs/promises'
 import {existsSync} from 'fs'
+import {constants} from 'os'
+
+/*
+ A second interrupt within this many milliseconds of the first forces an immediate exit
+ */
+const FORCE_WINDOW = 3000
+
+/*
+ Milliseconds interrupted tests have to exit before their process groups are killed
+ */
+const KILL_GRACE = 5000
 
 /*
  Handles --init command to create testme.json5 configuration file
- Creates a default configuration with common settings
+ Creates a starter configuration with sections for the test languages found under the current directory
+ @param force Overwrite an existing testme.json5
  */
-async function handleInit(): Promise<void> {
+async function handleInit(force: boolean): Promise<void> {
     const configPath = join(process.cwd(), 'testme.json5')
 
     // Check if file already exists
-    if (existsSync(configPath)) {
-        console.error('❌ Error: testme.json5 already exists in current directory')
+    if (existsSync(configPath) && !force) {
+        console.error('❌ Error: testme.json5 already exists in current directory (use --force to overwrite)')
         process.exit(1)
     }
 
-    const defaultConfig = `{
-    /*
-        TestMe Configuration
-        See https://github.com/embedthis/testme for documentation
-     */
-
-    // Enable or disable tests in this directory
-    // true: run tests normally (default)
-    // false: disable all tests
-    // 'manual': only run when explicitly named by full path or base name
-    enable: true,
-
-    // Minimum depth required to run tests (use tm --depth N)
-    depth: 0,
-
-    // Compiler configuration for different languages
-    compiler: {
-        c: {
-            // Compiler selection has three modes:
-            // 1. Auto-detect: compiler: 'default'
-            // 2. Explicit compiler for all platforms: compiler: 'gcc'
-            // 3. Per-platform compiler map:
-            //    compiler: {
-            //        windows: 'msvc',
-            //        macosx: 'clang',
-            //        linux: 'gcc'
-            //    }
-            compiler: 'default',
-
-            // GCC-specific flags (Unix/Linux/MinGW)
-            gcc: {
-                flags: ['-I..'],
-                libraries: ['m', 'pthread'],
-            },
-
-            // Clang-specific flags (macOS/Unix)
-            clang: {
-                flags: ['-I..'],
-                libraries: ['m', 'pthread'],
-            },
-
-            // MSVC-specific flags (Windows)
-            msvc: {
-                flags: ['/I..'],
-                libraries: [],
-            },
-        },
-        es: {
-            require: 'testme',
-        },
-    },
-
-    // Test execution settings
-    execution: {
-        timeout: 30000,     // Timeout per test in milliseconds
-        parallel: true,     // Run tests in parallel
-        workers: 4,         // Number of parallel workers
-    },
-
-    // Output formatting options
-    output: {
-        verbose: false,     // Show detailed output
-        format: 'simple',   // Output format: simple, detailed, json
-        colors: true,       // Enable colored output
-    },
-
-    // File discovery patterns
-    patterns: {
-        include: [
-            '**/*.tst.sh',
-            '**/*.tst.ps1',
-            '**/*.tst.bat',
-            '**/*.tst.cmd',
-            '**/*.tst.c',
-            '**/*.tst.js',
-            '**/*.tst.ts',
-            '**/*.tst.es',
-        ],
-        exclude: ['**/node_modules/**', '**/.testme/**', '**/.*/**'],
-    },
-
-    // Service management for test setup/teardown
-    services: {
-        skip: '',           // Script to check if tests should be skipped
-        environment: '',    // Script to emit environment variables (key=value lines)
-        globalPrep: '',     // Script to run once before all test groups (global setup)
-        prep: '',           // Script to run once before tests in this group
-        setup: '',          // Background service to start before tests
-        cleanup: '',        // Script to run after tests in this group
-        globalCleanup: '',  // Script to run once after all test groups (global teardown)
-        setupDelay: 1,      // Delay in seconds after setup starts before running tests (default: 1)
-        shutdownTimeout: 5, // Wait time in seconds for graceful shutdown before SIGKILL (default: 5)
-    },
-
-    // Environment variables for test execution
-    environment: {
-        // Example: BIN: '\${../build/\${PLATFORM}-\${PROFILE}/bin}',
-    },
-}
-`
-
-    await writeFile(configPath, defaultConfig, 'utf-8')
+    const types = await ConfigTemplate.detect(process.cwd())
+    await writeFile(configPath, ConfigTemplate.render(types), 'utf-8')
     console.log('✓ Created testme.json5')
+    if (types.size > 0) {
+        console.log(`  Configured for detected test types: ${[...types].sort().join(', ')}`)
+    }
     console.log('\nNext steps:')
     console.log('  1. Edit testme.json5 to configure your test environment')
     console.log('  2. Create test files with .tst.* extension (e.g., math.tst.c)')
@@ -254,37 +200,271 @@ try {
     console.log('  2. Run tests with: tm')
 }
 
+/*
+ Handles --show-config <dir> command to print the effective configuration for a directory
+ Lists the contributing config files in precedence order, then the merged configuration as JSON with any --set
+ values applied
+ */
+async function handleShowConfig(dir: string, settings?: Record<string, unknown>): Promise<void> {
+    if (!existsSync(dir)) {
+        throw new Error(`Directory not found: ${dir}`)
+    }
+    const chain = await ConfigManager.getConfigChain(dir)
+    const config = ConfigManager.applySettings(await ConfigManager.findConfig(dir), settings)
+    console.log(`// Effective configuration for ${dir}`)
+    if (chain.length === 0) {
+        console.log('// No testme.json5 found, using built-in defaults')
+    } else {
+        console.log('// Configuration files (highest precedence first):')
+        for (const {path, inherit} of chain) {
+            const keys = Array.isArray(inherit) ? ` (inherits ${inherit.join(', ')})` : inherit ? ' (inherits all)' : ''
+            console.log(`//   ${path}${keys}`)
+        }
+    }
+    for (const key of Object.keys(settings || {})) {
+        console.log(`// Overridden by --set ${key}`)
+    }
+    // Internal bookkeeping keys start with an underscore
+    console.log(JSON.stringify(config, (key, value) => (key.startsWith('_') ? undefined : value), 4))
+}
+
+/*
+ Handles --flaky-report command to list tests whose recorded outcomes alternate, most unstable first
+ */
+async function handleFlakyReport(rootDir: string): Promise<number> {
+    const history = await TestHistory.load(rootDir)
+    if (!history || history.size === 0) {
+        console.log(`No test history recorded yet (${relative(rootDir, TestHistory.getPath(rootDir))})`)
+        return 0
+    }
+    const flaky = TestHistory.getFlaky(history)
+    if (flaky.length === 0) {
+        console.log(`No flaky tests in the last ${HISTORY_SIZE} runs of ${history.size} test(s)`)
+        return 0
+    }
+    console.log(`Flaky tests (score >= ${FLAKY_THRESHOLD}, last ${HISTORY_SIZE} runs, oldest first):\n`)
+    console.log(`${'SCORE'.padStart(5)}  ${'FAILED'.padStart(6)}  ${'HISTORY'.padEnd(HISTORY_SIZE)}  TEST`)
+    for (const test of flaky) {
+        const failed = `${test.failures}/${test.history.length}`.padStart(6)
+        const name = relative(rootDir, test.path)
+        console.log(`${test.score.toFixed(2).padStart(5)}  ${failed}  ${test.history.padEnd(HISTORY_SIZE)}  ${name}`)
+    }
+    console.log(`\n${flaky.length} of ${history.size} test(s) are flaky. Consider adding them to testme.quarantine.`)
+    return 0
+}
+
+/*
+ Handles --list-suites to print each suite defined by the suite key and directive with its number of tests
+ Test patterns, --filter and --exclude restrict the tests counted
+ */
+async function handleListSuites(rootDir: string, config: TestConfig, options: any): Promise<number> {
+    let tests = await TestDiscovery.discoverTests({
+        rootDir,
+        patterns: config.patterns?.include || [],
+        excludePatterns: config.patterns?.exclude || [],
+        ...TestDiscovery.getArtifactOptions(config),
+        ...TestDiscovery.getWalkOptions(config),
+    })
+    if (options.patterns.length > 0) {
+        tests = TestDiscovery.filterTestsByPatterns(tests, options.patterns, rootDir)
+    }
+    if (options.filter || options.exclude) {
+        tests = TestDiscovery.filterTestsByRegex(tests, rootDir, options.filter, options.exclude)
+    }
+    const suites = await TestSuites.collect(tests)
+    if (suites.size === 0) {
+        console.log('No suites defined (set the suite key in testme.json5 or add a "testme: suite" directive)')
+        return 0
+    }
+    const width = Math.max(...[...suites.keys()].map((name) => name.length))
+    for (const [name, members] of suites) {
+        console.log(`${name.padEnd(width)}  ${members.length} test(s)`)
+    }
+    return 0
+}
+
+/*
+ Handles --check-config command to validate every testme.json5 in the directory tree
+ Returns 1 if any file has an error or (unless --ignore-unknown-keys) an unknown key
+ */
+async function handleCheckConfig(rootDir: string): Promise<number> {
+    const results = await ConfigManager.checkConfigFiles(rootDir)
+    let errors = 0
+    let warnings = 0
+    for (const {path, issues} of results) {
+        const name = relative(rootDir, path)
+        if (issues.length === 0) {
+            console.log(`✓ ${name}`)
+        } else {
+            console.log(`✗ ${name}`)
+            console.log(ConfigSchema.format(name, issues))
+        }
+        errors += issues.filter((issue) => issue.severity === 'error').length
+        warnings += issues.filter((issue) => issue.severity === 'warning').length
+    }
+    console.log(`\n${results.length} config file(s) checked: ${errors} error(s), ${warnings} warning(s)`)
+    return errors + warnings > 0 ? 1 : 0
+}
+
+/*
+ Handles --doctor command to check the toolchain needed by the selected tests
+ Reports each tool with its version and the C include and library directories, and returns 1 if a tool is
+ missing or older than its configured minimum, or a directory does not exist
+ */
+async function handleDoctor(rootDir: string, config: TestConfig, options: any): Promise<number> {
+    let tests = await TestDiscovery.discoverTests({
+        rootDir,
+        patterns: config.patterns?.include || [],
+        excludePatterns: config.patterns?.exclude || [],
+        ...TestDiscovery.getArtifactOptions(config),
+        ...TestDiscovery.getWalkOptions(config),
+    })
+    if (options.patterns.length > 0) {
+        tests = TestDiscovery.filterTestsByPatterns(tests, options.patterns, rootDir)
+    }
+    if (options.filter || options.exclude) {
+        tests = TestDiscovery.filterTestsByRegex(tests, rootDir, options.filter, options.exclude)
+    }
+
+    // Disabled tests and tests above the requested depth need no tools
+    const selected: {file: TestFile; config: TestConfig}[] = []
+    for (const file of tests) {
+        const testConfig = await ConfigManager.findConfig(file.directory)
+        if (testConfig.enable !== false && (testConfig.depth ?? 0) <= (config.execution?.depth ?? 0)) {
+            // Check the tools --cc, --target, --remote and --docker would use
+            const {cc, target, remote, docker} = config.execution || {}
+            const execution = {...testConfig.execution!, cc, target, remote, docker}
+            selected.push({file, config: cc || target || remote || docker ? {...testConfig, execution} : testConfig})
+        }
+    }
+    if (selected.length === 0) {
+        console.log('No tests discovered')
+        return 0
+    }
+
+    let problems = 0
+    console.log(`🩺 Toolchain for ${selected.length} test(s):`)
+    for (const tool of await Doctor.checkTools(selected, config.toolchain)) {
+        const version = tool.version ? ` ${tool.version}` : ''
+        const where = tool.path ? `${version} (${tool.path})` : ' not found'
+        const minimum = tool.minimum ? `, requires ${tool.minimum}` : ''
+        const source = tool.source ? ` (${tool.source})` : ''
+        const uses = `${tool.language}${source}, ${tool.tests} test(s)${minimum}`
+        console.log(
+            `${tool.problem ? '✗' : '✓'} ${tool.name}${where} - ${uses}` +
+                (tool.problem && tool.path ? `: ${tool.problem}` : '')
+        )
+        problems += tool.problem ? 1 : 0
+    }
+
+    const cConfigs = [...new Set(selected.filter(({file}) => file.type === TestType.C).map(({config}) => config))]
+    const paths = Doctor.checkPaths(cConfigs)
+    if (paths.length > 0) {
+        console.log('\nInclude and library directories:')
+        for (const path of paths) {
+            const source = relative(rootDir, path.configDir) || '.'
+            const status = path.problem ? '✗' : '✓'
+            console.log(`${status} ${path.flag} (config in ${source}): ${path.problem || path.path}`)
+            problems += path.problem ? 1 : 0
+        }
+    }
+
+    console.log(problems ? `\n✗ ${problems} problem(s) found` : '\n✓ Toolchain ready')
+    return problems ? 1 : 0
+}
+
 class TestMeApp {
     private runner: TestRunner
     private serviceManagers: Map<string, ServiceManager> = new Map()
     private globalServiceManager: ServiceManager | null = null
     private shouldStop: boolean = false
-    private interruptCount: number = 0
+    private interruptedBy: NodeJS.Signals | null = null
+    private interruptTime: number = 0
+    private watcher: FileWatcher | null = null
+    private lastResults: TestResult[] = []
+    private reporters: Reporter[] = []
 
-    constructor() {
+    /*
+     @param handleSignals Stop the run on SIGINT and SIGTERM. Programs embedding the runner handle their
+        own signals and call stop() instead.
+     */
+    constructor(handleSignals: boolean = true) {
         this.runner = new TestRunner()
-        this.setupSignalHandlers()
+        if (handleSignals) {
+            this.setupSignalHandlers()
+        }
         // Set callback for runner to check if execution should stop
         this.runner.setShouldStopCallback(() => this.shouldStop)
     }
 
     /*
-     Sets up signal handlers for graceful shutdown on Ctrl+C
+     Adds a reporter that receives the run's events alongside those selected by --report and --json
+     @param reporter Reporter
      */
-    private setupSignalHandlers(): void {
-        process.on('SIGINT', () => {
-            this.interruptCount++
+    addReporter(reporter: Reporter): void {
+        this.reporters.push(reporter)
+    }
 
-            if (this.interruptCount === 1) {
-                // First Ctrl+C: stop gracefully
-                console.log('\n\n⚠️  Interrupt received. Stopping tests and cleaning up...')
-                this.shouldStop = true
-            } else {
-                // Second Ctrl+C: force exit immediately
+    /*
+     Gets the results of the last run
+     @returns Test results, empty if no tests ran
+     */
+    getResults(): TestResult[] {
+        return this.lastResults
+    }
+
+    /*
+     Stops the run gracefully
+     No more tests are launched, running tests are sent the signal and the run finishes its teardown,
+     summary and reports. Tests still running after KILL_GRACE are killed.
+     @param signal Signal to send the running tests, also used for the exit status of the run
+     */
+    stop(signal: NodeJS.Signals = 'SIGINT'): void {
+        if (this.interruptedBy) {
+            return
+        }
+        this.interruptedBy = signal
+        this.interruptTime = Date.now()
+        this.shouldStop = true
+        // Running tests are in their own process groups so forward the interrupt
+        ProcessManager.signalProcessGroups(signal)
+        setTimeout(() => ProcessManager.signalProcessGroups('SIGKILL'), KILL_GRACE).unref()
+        // Release watch mode if it is waiting for changes
+        this.watcher?.close()
+    }
+
+    /*
+     Sets up signal handlers for graceful shutdown on Ctrl+C or SIGTERM
+     The first signal stops the run (see stop()). A second signal within FORCE_WINDOW kills the running
+     tests and exits immediately.
+     */
+    private setupSignalHandlers(): void {
+        const interrupt = (signal: NodeJS.Signals) => {
+            const now = Date.now()
+            if (this.interruptedBy && now - this.interruptTime < FORCE_WINDOW) {
                 console.log('\n\n🛑 Force quit. Exiting immediately.')
-                process.exit(130) // 128 + SIGINT(2)
+                ProcessManager.signalProcessGroups('SIGKILL')
+                process.exit(this.getInterruptExitCode(signal))
             }
-        })
+            if (this.interruptedBy) {
+                this.interruptTime = now
+                console.log(`\n⚠️  Still stopping. Press Ctrl+C again within ${FORCE_WINDOW / 1000}s to force quit.`)
+                return
+            }
+            console.log('\n\n⚠️  Interrupt received. Stopping tests and cleaning up...')
+            this.stop(signal)
+        }
+        process.on('SIGINT', interrupt)
+        process.on('SIGTERM', interrupt)
+    }
+
+    /*
+     Gets the exit status of a run stopped by a signal
+     @param signal Signal that stopped the run
+     @returns 128 plus the signal number (130 for SIGINT, 143 for SIGTERM)
+     */
+    private getInterruptExitCode(signal: NodeJS.Signals): number {
+        return 128 + (constants.signals[signal] ?? 2)
     }
 
     private getServiceManager(configDir: string, invocationDir?: string): ServiceManager {
@@ -301,6 +481,82 @@ class TestMeApp {
         return this.globalServiceManager
     }
 
+    /*
+     Runs tests between directory setup and teardown commands
+     Variables exported by setup are added to the test environment. Teardown runs even if tests fail,
+     time out or are interrupted. If setup fails, the tests are not run and are reported as errors.
+     @param tests Tests the fixtures apply to
+     @param config Group configuration
+     @param services Service manager of the group
+     @param fixtures Directory setup and teardown, or undefined to just run the tests
+     @param run Runs the tests with the configuration including the exported variables
+     @returns Test results
+     */
+    private async runWithFixtures(
+        tests: TestFile[],
+        config: TestConfig,
+        services: ServiceManager,
+        fixtures: DirectoryFixtures | undefined,
+        run: (config: TestConfig) => Promise<TestResult[]>
+    ): Promise<TestResult[]> {
+        if (!fixtures) {
+            return await run(config)
+        }
+        let exported: Record<string, string>
+        try {
+            exported = await services.runDirectorySetup(config, fixtures)
+        } catch (error) {
+            const message = error instanceof Error ? error.message : String(error)
+            const results = tests.map((file) => ({
+                file,
+                status: TestStatus.Error,
+                duration: 0,
+                output: '',
+                error: message,
+            }))
+            results.forEach((result) => this.runner.notifyResult(result))
+            await services.runDirectoryTeardown(config, fixtures, {}, false)
+            return results
+        }
+        let results: TestResult[] = []
+        try {
+            results = await run({...config, environment: {...config.environment, ...exported}})
+            return results
+        } finally {
+            await services.runDirectoryTeardown(config, fixtures, exported, this.runner.getExitCode(results) === 0)
+        }
+    }
+
+    /*
+     Runs the tests of a configuration group in batches, each between the fixture scripts of its directory
+     @param batches Tests grouped by directory fixtures
+     @param config Group configuration
+     @param services Service manager of the group
+     @param rootDir Root directory for relative path display
+     @returns Test results
+     */
+    private async runBatches(
+        batches: FixtureBatch[],
+        config: TestConfig,
+        services: ServiceManager,
+        rootDir: string
+    ): Promise<TestResult[]> {
+        const results: TestResult[] = []
+        for (const batch of batches) {
+            if (
+                this.shouldStop ||
+                this.runner.getAbortedBy() ||
+                this.runner.isFailureLimitReached() ||
+                this.runner.isStepQuit()
+            ) {
+                break
+            }
+            const run = (config: TestConfig) => this.runner.executeTestsWithConfig(batch.tests, config, rootDir)
+            results.push(...(await this.runWithFixtures(batch.tests, config, services, batch.fixtures, run)))
+        }
+        return results
+    }
+
     /*
      Checks if a pattern is an explicit test reference (full path or base name)
      Explicit patterns don't contain wildcards or directory separators
@@ -388,6 +644,7 @@ class TestMeApp {
             '.tst.es',
             '.tst.py',
             '.tst.go',
+            '.tst.rs',
         ]
         for (const ext of testExtensions) {
             if (fileName.endsWith(ext)) {
@@ -397,6 +654,195 @@ class TestMeApp {
         return fileName
     }
 
+    /*
+     Runs tests, then re-runs the affected tests whenever files change until interrupted
+     @param rootDir Root directory to watch and start test discovery
+     @param patterns Optional patterns to filter tests
+     @param baseConfig Base configuration to inherit from
+     @param options CLI options
+     @param invocationDir Original directory where tm was invoked (before chdir)
+     @returns Exit code (0 when watching is stopped with Ctrl+C)
+     */
+    private async watchTests(
+        rootDir: string,
+        patterns: string[],
+        baseConfig: TestConfig,
+        options: any,
+        invocationDir: string
+    ): Promise<number> {
+        const watcher = new FileWatcher(rootDir)
+        watcher.start()
+        this.watcher = watcher
+        const tally = {runs: 0, passed: 0, failed: 0}
+        let selected: Set<string> | undefined
+
+        try {
+            while (!this.shouldStop) {
+                // Ignore changes made by the tests themselves while they run
+                watcher.pause()
+                if (tally.runs > 0) {
+                    clearScreen()
+                }
+                this.lastResults = []
+                await this.executeHierarchically(rootDir, patterns, baseConfig, options, invocationDir, selected)
+
+                const passed = this.lastResults.filter((result) => result.status === TestStatus.Passed).length
+                const failed = this.lastResults.filter(
+                    (result) =>
+                        result.status !== TestStatus.Passed &&
+                        result.status !== TestStatus.Skipped &&
+                        result.status !== TestStatus.XFail
+                ).length
+                tally.runs++
+                tally.passed += passed
+                tally.failed += failed
+                if (!this.isQuietMode(baseConfig)) {
+                    console.log(
+                        `\n👀 Run ${tally.runs}: ${passed} passed, ${failed} failed ` +
+                            `(session: ${tally.passed} passed, ${tally.failed} failed)`
+                    )
+                    console.log('   Watching for changes. Press Ctrl+C to exit.')
+                }
+
+                const affected = await this.waitForAffectedTests(watcher, rootDir, patterns, baseConfig, options)
+                if (!affected) {
+                    break
+                }
+                selected = new Set(affected.map((test) => test.path))
+            }
+        } finally {
+            watcher.close()
+            this.watcher = null
+        }
+        return 0
+    }
+
+    /*
+     Waits until a change affects at least one test
+     Changes that touch no test directory or shared setup are ignored and watching continues
+     @param watcher Active file watcher
+     @param rootDir Root directory for test discovery
+     @param patterns CLI patterns to filter tests
+     @param baseConfig Base configuration with discovery patterns
+     @param options CLI options (--filter and --exclude expressions)
+     @returns Affected tests, or null if watching stopped
+     */
+    private async waitForAffectedTests(
+        watcher: FileWatcher,
+        rootDir: string,
+        patterns: string[],
+        baseConfig: TestConfig,
+        options: any
+    ): Promise<TestFile[] | null> {
+        while (true) {
+            const changes = await watcher.next()
+            if (!changes || this.shouldStop) {
+                return null
+            }
+            if (changes.some((path) => basename(path) === 'testme.json5')) {
+                ConfigManager.clearCache()
+            }
+            const allTests = await TestDiscovery.discoverTests({
+                rootDir,
+                patterns: baseConfig.patterns?.include || [],
+                excludePatterns: baseConfig.patterns?.exclude || [],
+                ...TestDiscovery.getArtifactOptions(baseConfig),
+                ...TestDiscovery.getWalkOptions(baseConfig),
+            })
+            let tests =
+                patterns.length > 0 ? TestDiscovery.filterTestsByPatterns(allTests, patterns, rootDir) : allTests
+            tests = TestDiscovery.filterTestsByRegex(tests, rootDir, options.filter, options.exclude)
+
+            // Grouping assigns each test its config directory for shared setup detection
+            const groups = await this.groupTestsByConfig(tests)
+            const affected = FileWatcher.affectedTests([...groups.values()].flat(), changes)
+            if (affected.length > 0) {
+                return affected
+            }
+        }
+    }
+
+    /*
+     Merges the coverage data collected during a run and checks it against the configured threshold
+     Each handler that collected coverage merges its own report into the root directory (e.g., coverage.out for Go).
+     The threshold applies to each language's total.
+     @param coverage Coverage configuration
+     @param rootDir Root directory where the coverage reports are written
+     @param baseConfig Base configuration (for quiet mode)
+     @returns 0 on success, 1 if coverage is below the threshold
+     */
+    private async reportCoverage(
+        coverage: NonNullable<TestConfig['coverage']>,
+        rootDir: string,
+        baseConfig: TestConfig
+    ): Promise<number> {
+        let exitCode = 0
+        for (const handler of createHandlers()) {
+            let report: CoverageReport | null
+            try {
+                report = (await handler.collectCoverage?.(rootDir, coverage)) ?? null
+            } catch (error) {
+                console.warn(`⚠️  Failed to merge coverage: ${error instanceof Error ? error.message : error}`)
+                continue
+            }
+            if (!report) {
+                continue
+            }
+            const percent = report.percent
+            if (!this.isQuietMode(baseConfig)) {
+                console.log(
+                    `\n📊 ${report.language} coverage: ${percent.toFixed(1)}% of ${report.unit} ` +
+                        `(${relative(rootDir, report.path)})`
+                )
+            }
+            if (coverage.threshold !== undefined && percent < coverage.threshold) {
+                console.log(
+                    `✗ ${report.language} coverage ${percent.toFixed(1)}% is below the threshold of ${coverage.threshold}%`
+                )
+                exitCode = 1
+            }
+        }
+        return exitCode
+    }
+
+    /*
+     Selects the tests affected by files changed since a git ref (--since)
+     A test is affected if a file changed in its directory or configuration directory (shared setup scripts),
+     if a testme.json5 changed at or above it, or if a path in its configuration's depends list changed.
+     @param tests Candidate tests
+     @param rootDir Root directory for test discovery
+     @param ref Git ref to compare against
+     @param baseConfig Base configuration (for quiet mode)
+     @returns Affected tests, or all tests if rootDir is not in a git repository
+     */
+    private async selectChangedTests(
+        tests: TestFile[],
+        rootDir: string,
+        ref: string,
+        baseConfig: TestConfig
+    ): Promise<TestFile[]> {
+        const changes = await getChangedFiles(ref, rootDir)
+        if (!changes) {
+            console.log('Not in a git repository, running all tests')
+            return tests
+        }
+
+        // Grouping assigns each test its config directory for shared setup detection
+        const groups = await this.groupTestsByConfig(tests)
+        const affected = new Set(FileWatcher.affectedTests([...groups.values()].flat(), changes))
+        for (const [configDir, groupTests] of groups) {
+            const config = await ConfigManager.findConfig(configDir)
+            if (dependsOnChanges(changes, config.depends, config.configDir || configDir)) {
+                groupTests.forEach((test) => affected.add(test))
+            }
+        }
+        const selected = tests.filter((test) => affected.has(test))
+        if (!this.isQuietMode(baseConfig)) {
+            console.log(`${selected.length} of ${tests.length} test(s) affected by changes since ${ref}`)
+        }
+        return selected
+    }
+
     /*
      Executes tests hierarchically with proper configuration and services handling
      @param rootDir Root directory to start test discovery
@@ -404,6 +850,7 @@ class TestMeApp {
      @param baseConfig Base configuration to inherit from
      @param options CLI options
      @param invocationDir Original directory where tm was invoked (before chdir)
+     @param selected Optional test paths to restrict the run to (used by watch mode)
      @returns Exit code
      */
     private async executeHierarchically(
@@ -411,7 +858,8 @@ class TestMeApp {
         patterns: string[],
         baseConfig: TestConfig,
         options: any,
-        invocationDir: string
+        invocationDir: string,
+        selected?: Set<string>
     ): Promise<number> {
         // Discover all tests in the directory tree using config patterns
         // This ensures we find all potential test files based on their extensions
@@ -419,14 +867,88 @@ class TestMeApp {
             rootDir,
             patterns: baseConfig.patterns?.include || [],
             excludePatterns: baseConfig.patterns?.exclude || [],
+            ...TestDiscovery.getArtifactOptions(baseConfig),
+            ...TestDiscovery.getWalkOptions(baseConfig),
         })
 
         // If CLI patterns are provided, apply them as an additional filter
-        const filteredTests =
+        let filteredTests =
             patterns.length > 0 ? TestDiscovery.filterTestsByPatterns(allTests, patterns, rootDir) : allTests
+        // Apply --filter and --exclude regular expressions. Directories left without tests form no
+        // configuration group below, so their setup and cleanup services do not run.
+        if (options.filter || options.exclude) {
+            filteredTests = TestDiscovery.filterTestsByRegex(filteredTests, rootDir, options.filter, options.exclude)
+            if (!this.isQuietMode(baseConfig)) {
+                console.log(`Matched ${filteredTests.length} of ${allTests.length} discovered test(s)`)
+            }
+        }
+        // Run only the tests of the named suites (--suite). Suites are gathered from every discovered test, so a
+        // suite that has no tests left after the other filters is still known.
+        if (options.suite) {
+            filteredTests = TestSuites.select(filteredTests, options.suite, await TestSuites.collect(allTests))
+        }
+
+        // Record each test's tags for reports, then run only the tests that --tags and --not-tags select
+        await TestTags.mark(filteredTests)
+        if (options.tags || options.notTags) {
+            filteredTests = TestTags.select(filteredTests, options.tags, options.notTags)
+        }
+        if (options.since) {
+            filteredTests = await this.selectChangedTests(filteredTests, rootDir, options.since, baseConfig)
+        }
+        if (selected) {
+            filteredTests = filteredTests.filter((test) => selected.has(test.path))
+        }
+
+        // Restrict to the tests that failed in the previous run (--failed)
+        if (options.failed) {
+            const failures = await LastFailures.load(rootDir)
+            if (!failures) {
+                console.log('No record of previous failures, running all tests')
+            } else if (failures.size === 0) {
+                console.log('No tests failed in the last run')
+                return 0
+            } else {
+                filteredTests = filteredTests.filter((test) => failures.has(test.path))
+            }
+        }
+
+        // Mark known-flaky tests so their failures do not fail the run, unless --no-quarantine
+        if (!options.noQuarantine) {
+            const quarantined = await Quarantine.mark(filteredTests, baseConfig, rootDir)
+            if (quarantined > 0 && !this.isQuietMode(baseConfig)) {
+                console.log(`🚧 ${quarantined} quarantined test(s): failures will not fail the run`)
+            }
+        }
+
+        // Run only this machine's part of the selection (--shard)
+        if (options.shard) {
+            const {index, count} = options.shard
+            const timings = options.balance ? await TestTimings.load(rootDir) : null
+            if (options.balance && !timings) {
+                console.log('No recorded test timings, sharding by path')
+            }
+            const total = filteredTests.length
+            filteredTests = TestShards.select(filteredTests, rootDir, options.shard, timings || undefined)
+            if (!this.isQuietMode(baseConfig)) {
+                console.log(`Shard ${index}/${count}: ${filteredTests.length} of ${total} test(s)`)
+            }
+        }
 
         if (filteredTests.length === 0) {
-            if (patterns.length > 0) {
+            if (options.shard) {
+                console.log(`No tests in shard ${options.shard.index}/${options.shard.count}`)
+            } else if (options.failed) {
+                console.log('No previously failing tests match the current selection')
+            } else if (options.since) {
+                console.log(`No tests affected by changes since ${options.since}`)
+            } else if (options.suite) {
+                console.log(`No tests in suite(s): ${options.suite.join(', ')}`)
+            } else if (options.tags || options.notTags) {
+                console.log('No tests matching --tags/--not-tags')
+            } else if (options.filter || options.exclude) {
+                console.log('No tests matching --filter/--exclude')
+            } else if (patterns.length > 0) {
                 console.log(`No tests matching pattern(s): ${patterns.join(', ')}`)
             } else {
                 console.log('No tests discovered')
@@ -434,6 +956,25 @@ class TestMeApp {
             return 0
         }
 
+        // --debug runs an interactive debugger session, so it needs a single test
+        if (options.debug && filteredTests.length > 1) {
+            console.error(`--debug needs a single test, but ${filteredTests.length} tests match:`)
+            for (const test of filteredTests.slice(0, 10)) {
+                console.error(`  ${relative(rootDir, test.path)}`)
+            }
+            if (filteredTests.length > 10) {
+                console.error('  ...')
+            }
+            console.error('Name one test, e.g., tm --debug unit/math.tst.c')
+            return 1
+        }
+
+        // Parameterized tests run once per case, each as an independent test
+        filteredTests = await TestCases.expand(filteredTests)
+
+        // Prerequisites declared with "testme: after". A missing prerequisite or a cycle is a configuration error.
+        await TestOrder.resolve(filteredTests)
+
         // Get unique test directories for root config discovery
         const testDirectories = [...new Set(filteredTests.map((test) => test.directory))]
 
@@ -442,10 +983,60 @@ class TestMeApp {
         const rootConfig = await ConfigManager.findRootConfig(testDirectories)
 
         // Group tests by their configuration directory
-        const testGroups = await this.groupTestsByConfig(filteredTests)
+        let testGroups = await this.groupTestsByConfig(filteredTests)
 
         console.log(`\nDiscovered ${filteredTests.length} test(s) in ${testGroups.size} configuration group(s)`)
 
+        // Shuffle the group order and the test order within each group. Groups stay together so their
+        // setup, cleanup and serial execution settings still apply to all of their tests.
+        const seed = baseConfig.execution?.seed
+        if (seed !== undefined) {
+            const random = seededRandom(seed)
+            const entries = shuffle([...testGroups.entries()], random)
+            testGroups = new Map(entries.map(([configDir, tests]) => [configDir, shuffle(tests, random)]))
+            console.log(`🔀 Shuffled test order with seed ${seed} (use --seed ${seed} to reproduce)`)
+        }
+        testGroups = TestOrder.sortGroups(testGroups)
+
+        // The whole selection runs once per matrix cell (a single unlabeled cell without a matrix)
+        const cells = Matrix.getCells(baseConfig.matrix, options.matrix)
+        const plannedTests = cells.flatMap((cell) => Matrix.apply(filteredTests, cell))
+        if (cells.length > 1 || cells[0]!.label) {
+            console.log(`🔢 Matrix: ${cells.length} cell(s), ${plannedTests.length} test run(s)`)
+        }
+        this.runner.setOrder(new TestOrder(plannedTests))
+        this.runner.setSettings(options.set)
+
+        // Run the root setup commands once before anything else. A failed setup aborts the run.
+        const globalServices = this.getGlobalServiceManager(rootConfig.configDir || rootDir)
+        if (!options.noServices && (rootConfig.setup || rootConfig.teardown)) {
+            try {
+                await globalServices.runGlobalSetup(this.applyCliOverrides(rootConfig, options))
+            } catch (error) {
+                console.error(`\n❌ ${error instanceof Error ? error.message : String(error)}`)
+                console.error('No tests were run')
+                await globalServices.runGlobalTeardown(false)
+                return 1
+            }
+        }
+
+        EventStream.emitDiscovered(plannedTests)
+        if (options.progress) {
+            RunProgress.begin(plannedTests.length)
+        }
+
+        // Create machine-readable reporters (if requested) and any added by an embedding program. Results
+        // are written as each test completes so partial reports survive an interrupted run.
+        const startTime = Date.now()
+        const reporters: Reporter[] = this.getReportSpecs(baseConfig, options, rootDir)
+            .map((spec) => createReporter(spec, rootDir, baseConfig))
+            .concat(this.reporters)
+        const reporter = reporters.length > 0 ? new MultiReporter(reporters) : null
+        if (reporter) {
+            reporter.runStart(plannedTests)
+            this.runner.setReporter(reporter)
+        }
+
         // Run global prep once before all test groups (if configured in root config)
         if (!options.noServices && rootConfig.services?.globalPrep) {
             // Apply CLI overrides to rootConfig so verbose mode works for global prep
@@ -456,18 +1047,33 @@ class TestMeApp {
         let allResults: any[] = []
         let totalExitCode = 0
 
-        // Execute each configuration group
-        for (const [configDir, tests] of testGroups) {
-            // Check if we should stop (Ctrl+C pressed)
-            if (this.shouldStop) {
+        // Execute each configuration group, once per matrix cell
+        this.runner.resetAbort()
+        const runs = cells.flatMap((cell) =>
+            [...testGroups].map(([configDir, tests]) => ({configDir, tests: Matrix.apply(tests, cell), cell}))
+        )
+        let currentCell: MatrixCell | undefined
+        for (const {configDir, tests, cell} of runs) {
+            // Check if we should stop (Ctrl+C pressed, aborted by --fail-fast, --max-failures reached or step quit)
+            if (
+                this.shouldStop ||
+                this.runner.getAbortedBy() ||
+                this.runner.isFailureLimitReached() ||
+                this.runner.isStepQuit()
+            ) {
                 break
             }
+            RunProgress.beginGroup(tests.length)
+            if (cell.label && cell !== currentCell) {
+                console.log(`\n🔢 Matrix ${cell.label}`)
+            }
+            currentCell = cell
 
             // Get configuration for this group
             const groupConfig = await ConfigManager.findConfig(configDir)
 
-            // Apply CLI overrides to group config
-            let mergedConfig = this.applyCliOverrides(groupConfig, options)
+            // Apply CLI overrides and the matrix cell's variables to group config
+            let mergedConfig = Matrix.applyEnvironment(this.applyCliOverrides(groupConfig, options), cell)
 
             // Check if tests are disabled for this directory
             if (mergedConfig.enable === false) {
@@ -577,13 +1183,14 @@ class TestMeApp {
                         output: skipResult.message || 'Skip script returned non-zero',
                     }))
                     allResults.push(...skippedResults)
+                    skippedResults.forEach((result) => this.runner.notifyResult(result))
                     continue
                 }
             }
 
             // Show parallel execution info if enabled
             const isParallel = mergedConfig.execution?.parallel !== false
-            const workers = mergedConfig.execution?.workers || 4
+            const workers = mergedConfig.execution?.workers || ConfigManager.getDefaultWorkers()
             const actualWorkers = Math.min(workers, filteredTests.length)
             const locationStr = relative(rootDir, configDir) || '.'
 
@@ -619,8 +1226,16 @@ class TestMeApp {
                     await this.getServiceManager(configDir, rootDir).runSetup(mergedConfig)
                 }
 
-                // Execute tests in this group
-                const results = await this.runner.executeTestsWithConfig(filteredTests, mergedConfig, rootDir)
+                // Execute tests in this group between the directory setup and teardown. The setup and teardown
+                // keys of the root configuration are the global hooks, so only other configurations wrap a group.
+                const services = this.getServiceManager(configDir, rootDir)
+                const fixtures =
+                    options.noServices || configDir === (rootConfig.configDir || rootDir)
+                        ? undefined
+                        : Fixtures.fromConfig(mergedConfig)
+                const batches = options.noServices ? [{tests: filteredTests}] : Fixtures.getBatches(filteredTests)
+                const runBatches = (config: TestConfig) => this.runBatches(batches, config, services, rootDir)
+                const results = await this.runWithFixtures(filteredTests, mergedConfig, services, fixtures, runBatches)
 
                 allResults.push(...results)
                 groupExitCode = this.runner.getExitCode(results)
@@ -647,11 +1262,86 @@ class TestMeApp {
             )
         }
 
+        // Run the root teardown commands, including after an interrupt
+        await globalServices.runGlobalTeardown(totalExitCode === 0)
+
+        // Finalize machine-readable reports and the event feed
+        const elapsedTime = Date.now() - startTime
+        if (reporter) {
+            this.runner.setReporter(null)
+            reporter.runEnd(allResults, elapsedTime)
+        }
+        EventStream.emit('run-end', {
+            total: allResults.length,
+            passed: allResults.filter((result) => result.status === TestStatus.Passed).length,
+            failed: allResults.filter((result) => result.status === TestStatus.Failed).length,
+            errors: allResults.filter((result) => result.status === TestStatus.Error).length,
+            timeouts: allResults.filter((result) =>
+                [TestStatus.Timeout, TestStatus.IdleTimeout].includes(result.status)
+            ).length,
+            skipped: allResults.filter((result) => result.status === TestStatus.Skipped).length,
+            xfail: allResults.filter((result) => result.status === TestStatus.XFail).length,
+            xpass: allResults.filter((result) => result.status === TestStatus.XPass).length,
+            interrupted: this.shouldStop,
+            elapsed: elapsedTime,
+        })
+        this.lastResults = allResults
+
+        // A dry run executes nothing, so there are no results to record or report
+        if (options.dryRun) {
+            for (const result of allResults.filter((result) => result.status === TestStatus.Error)) {
+                console.log(`\n❌ ${relative(rootDir, result.file.path)}: ${result.error}`)
+            }
+            const shown = allResults.filter((result) => result.status === TestStatus.Skipped).length
+            console.log(`\n📋 Dry run: commands shown for ${shown} test(s), nothing was executed`)
+            return this.runner.getExitCode(allResults)
+        }
+        await LastFailures.save(rootDir, allResults)
+        await TestTimings.save(rootDir, allResults)
+        await TestHistory.save(rootDir, allResults)
+
         // Report final results
         if (!this.isQuietMode(baseConfig)) {
             this.runner.reportFinalResults(allResults, baseConfig, rootDir)
         }
 
+        // Note an early abort and how many tests never ran
+        const abortedBy = this.runner.getAbortedBy()
+        const notExecuted = [...testGroups.values()].flat().length * cells.length - allResults.length
+        let interruptedBy: NodeJS.Signals | null = null
+        if (abortedBy) {
+            console.log(
+                `\n⛔ Run aborted after ${relative(rootDir, abortedBy.file.path)} failed (--fail-fast): ` +
+                    `${notExecuted} test(s) not executed`
+            )
+            totalExitCode = totalExitCode || 1
+        } else if (this.runner.isFailureLimitReached()) {
+            const limit = this.applyCliOverrides(baseConfig, options).execution?.maxFailures
+            console.log(`\n⛔ Failure limit reached (--max-failures ${limit}): ${notExecuted} test(s) skipped`)
+            totalExitCode = totalExitCode || 1
+        } else if (this.interruptedBy) {
+            console.log(`\n⚠️  Run interrupted by ${this.interruptedBy}: ${notExecuted} test(s) not run`)
+            interruptedBy = this.interruptedBy
+        } else if (this.runner.isStepQuit()) {
+            console.log(`\n🛑 Run quit in step mode: ${notExecuted} test(s) not run`)
+        }
+
+        // Merge coverage data and enforce the coverage threshold
+        const coverage = this.applyCliOverrides(baseConfig, options).coverage
+        if (!interruptedBy && coverage?.enable && (await this.reportCoverage(coverage, rootDir, baseConfig)) !== 0) {
+            totalExitCode = 1
+        }
+
+        // Push run metrics to the Pushgateway and post the run summary to notify.webhook, failures only warn
+        if (!options.watch) {
+            const summary = {results: allResults, rootDir, elapsed: elapsedTime, interrupted: interruptedBy !== null}
+            const metrics = {...baseConfig.metrics, ...(options.metricsPush && {push: options.metricsPush})}
+            await RunMetrics.push(metrics, summary)
+            await Notifier.send(baseConfig.notify, summary)
+        }
+        if (interruptedBy) {
+            return this.getInterruptExitCode(interruptedBy)
+        }
         // If --continue flag is set, always return 0 (success)
         return options.continue ? 0 : totalExitCode
     }
@@ -698,7 +1388,14 @@ class TestMeApp {
      @returns Configuration with CLI overrides applied
      */
     private applyCliOverrides(config: TestConfig, options: any): TestConfig {
-        let mergedConfig = {...config}
+        // --set values apply first, so dedicated options and --env override them
+        let mergedConfig = ConfigManager.applySettings(config, options.set)
+
+        // Color a terminal unless NO_COLOR is set, or as --color or output.color says
+        mergedConfig.output = {
+            ...mergedConfig.output,
+            colors: useColor(options.color ?? mergedConfig.output?.color, mergedConfig.output?.colors !== false),
+        }
 
         if (options.verbose) {
             mergedConfig.output = {
@@ -716,6 +1413,27 @@ class TestMeApp {
             }
         }
 
+        if (options.verboseOnFail) {
+            mergedConfig.output = {
+                ...mergedConfig.output,
+                verboseOnFail: true,
+            }
+        }
+
+        if (options.progress) {
+            mergedConfig.output = {
+                ...mergedConfig.output,
+                progress: true,
+            }
+        }
+
+        if (options.maxOutput !== undefined) {
+            mergedConfig.output = {
+                ...mergedConfig.output,
+                maxBytes: options.maxOutput,
+            }
+        }
+
         if (options.keep) {
             mergedConfig.execution = {
                 ...mergedConfig.execution,
@@ -743,6 +1461,43 @@ class TestMeApp {
             }
         }
 
+        if (options.failFast) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                failFast: true,
+            }
+        }
+
+        if (options.maxFailures !== undefined) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                maxFailures: options.maxFailures,
+            }
+        }
+
+        if (options.strict) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                strict: true,
+            }
+        }
+
+        // Dry run prints commands in order (serial) and shows compile commands even for cached binaries
+        if (options.dryRun) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: false,
+                rebuild: true,
+            }
+        }
+
         if (options.depth !== undefined) {
             mergedConfig.execution = {
                 ...mergedConfig.execution,
@@ -790,6 +1545,15 @@ class TestMeApp {
             }
         }
 
+        if (options.buildWorkers !== undefined) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                buildWorkers: options.buildWorkers,
+            }
+        }
+
         if (options.iterations !== undefined) {
             mergedConfig.execution = {
                 ...mergedConfig.execution,
@@ -825,6 +1589,43 @@ class TestMeApp {
             }
         }
 
+        if (options.idleTimeout !== undefined) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                idleTimeout: options.idleTimeout,
+            }
+        }
+
+        if (options.accept) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                accept: true,
+            }
+        }
+
+        if (options.bench || options.benchUpdate) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                bench: true,
+                benchUpdate: options.benchUpdate,
+            }
+        }
+
+        if (options.retries !== undefined) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                retries: options.retries,
+            }
+        }
+
         if (options.rebuild) {
             mergedConfig.execution = {
                 ...mergedConfig.execution,
@@ -834,6 +1635,125 @@ class TestMeApp {
             }
         }
 
+        // Apply asan flag from CLI - builds C and Go tests with AddressSanitizer
+        if (options.asan) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                asan: true,
+            }
+        }
+
+        if (options.backtrace) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                backtrace: true,
+            }
+        }
+
+        // Apply C compiler from CLI - wins over compiler.cc, compiler.c.compiler and $CC
+        if (options.cc) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                cc: options.cc,
+            }
+        }
+
+        // Apply shell test interpreter from CLI - wins over shebangs and shell.interpreter
+        if (options.shell) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                shell: options.shell,
+            }
+        }
+
+        // Apply cross-compilation target from CLI - wins over target.triple
+        if (options.target) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                target: options.target,
+            }
+        }
+
+        // Apply remote host from CLI - wins over remote.host
+        if (options.remote) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                remote: options.remote,
+            }
+        }
+
+        // Apply container image from CLI - wins over docker.image
+        if (options.docker) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                docker: options.docker,
+            }
+        }
+
+        // Apply temporary directory retention from CLI
+        if (options.keepTmp || options.keepTmpOnFail) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                keepTmp: options.keepTmp,
+                keepTmpOnFail: options.keepTmpOnFail,
+            }
+        }
+
+        // Apply artifact retention from CLI - passing tests are cleaned up, failing tests keep everything
+        if (options.keepOnFail) {
+            mergedConfig.execution = {
+                ...mergedConfig.execution,
+                timeout: mergedConfig.execution?.timeout ?? 30,
+                parallel: mergedConfig.execution?.parallel ?? true,
+                keepOnFail: true,
+            }
+        }
+
+        // Apply valgrind flag from CLI - runs C test binaries under valgrind
+        if (options.valgrind) {
+            mergedConfig.valgrind = {
+                ...mergedConfig.valgrind,
+                enable: true,
+            }
+        }
+
+        // Apply coverage flags from CLI - collects coverage and optionally enforces a minimum
+        if (options.coverage || options.coverageThreshold !== undefined) {
+            mergedConfig.coverage = {
+                ...mergedConfig.coverage,
+                enable: true,
+                ...(options.coverageThreshold !== undefined && {threshold: options.coverageThreshold}),
+            }
+        }
+
+        // Apply --env variables from CLI - these override the configured environment and the .env file
+        if (options.env) {
+            const environment: any = {...(mergedConfig.environment || mergedConfig.env), ...options.env}
+            // The default and platform sections override base values, so the CLI values must win there too
+            for (const section of ['default', 'windows', 'macosx', 'linux']) {
+                if (environment[section] && typeof environment[section] === 'object') {
+                    environment[section] = {...environment[section], ...options.env}
+                }
+            }
+            mergedConfig.environment = environment
+        }
+
         if (options.profile !== undefined) {
             mergedConfig.profile = options.profile
         }
@@ -841,6 +1761,28 @@ class TestMeApp {
         return mergedConfig
     }
 
+    /*
+     Gets the reports to write for a run
+     GitHub Actions annotations are added when running in GitHub Actions unless disabled or quiet.
+     @param config Configuration with output.report (a specification or a list of them) and output.github
+     @param options Command line options (for --json, --no-github and --quiet)
+     @param rootDir Root directory for relative output paths
+     @returns Report specifications (FORMAT:FILE) without duplicates or the console output
+     @throws Error if a specification is invalid or two reports write to the same destination
+     */
+    private getReportSpecs(config: TestConfig, options: CliOptions, rootDir: string): string[] {
+        const report = config.output?.report
+        const specs = Array.isArray(report) ? [...report] : report ? [report] : []
+        if (options.json) {
+            specs.push(`json:${options.json}`)
+        }
+        const github = config.output?.github ?? (process.env.GITHUB_ACTIONS === 'true' && !options.quiet)
+        if (github && !options.noGithub) {
+            specs.push('github')
+        }
+        return resolveReportSpecs(specs, rootDir)
+    }
+
     /*
      Checks if quiet mode is enabled
      @param config Configuration to check
@@ -850,18 +1792,32 @@ class TestMeApp {
         return config.output?.quiet === true
     }
 
+    /*
+     Runs the command line
+     @param args Command line arguments (excluding the program name)
+     @returns Exit status
+     */
     async run(args: string[]): Promise<number> {
-        let isQuiet = false
-        let config: TestConfig | undefined
-        let options: any // Declare at function level so it's accessible in catch block
-        let parsingComplete = false // Track if CLI parsing completed successfully
+        let options: CliOptions
         try {
-            // Parse command line arguments
             options = CliParser.parse(args)
             CliParser.validateOptions(options)
-            parsingComplete = true // Mark parsing as complete
-            isQuiet = options.quiet
+        } catch (error) {
+            this.handleError(error)
+            return 1
+        }
+        return await this.execute(options)
+    }
 
+    /*
+     Runs with parsed command line options
+     @param options Command line options
+     @returns Exit status
+     */
+    async execute(options: CliOptions): Promise<number> {
+        const isQuiet = options.quiet
+        let config: TestConfig | undefined
+        try {
             // Handle help option
             if (options.help) {
                 console.log(CliParser.getUsage())
@@ -874,9 +1830,21 @@ class TestMeApp {
                 return 0
             }
 
+            // Handle completion option - print a shell completion script
+            if (options.completion) {
+                process.stdout.write(Completion.render(options.completion))
+                return 0
+            }
+
+            // Handle emit-header option - print an assertion header
+            if (options.emitHeader) {
+                process.stdout.write(TestHeaders.get(options.emitHeader))
+                return 0
+            }
+
             // Handle init option - create testme.json5
             if (options.init) {
-                await handleInit()
+                await handleInit(!!options.force)
                 return 0
             }
 
@@ -898,6 +1866,25 @@ class TestMeApp {
                 }
             }
 
+            if (options.ignoreUnknownKeys) {
+                ConfigManager.setUnknownKeyWarnings(false)
+            }
+
+            // Handle check-config option - validate all config files and exit
+            if (options.checkConfig) {
+                return await handleCheckConfig(resolve(process.cwd()))
+            }
+
+            if (options.set) {
+                ConfigManager.checkSettings(options.set)
+            }
+
+            // Handle show-config option - print the effective configuration and exit
+            if (options.showConfig !== undefined) {
+                await handleShowConfig(resolve(options.showConfig), options.set)
+                return 0
+            }
+
             // Load configuration
             config = options.config
                 ? await ConfigManager.loadConfigFromFile(options.config)
@@ -985,6 +1972,16 @@ class TestMeApp {
                 }
             }
 
+            // Apply build workers flag from CLI - compiles tests ahead of running them
+            if (options.buildWorkers !== undefined) {
+                config.execution = {
+                    ...config.execution,
+                    timeout: config.execution?.timeout ?? 30,
+                    parallel: config.execution?.parallel ?? true,
+                    buildWorkers: options.buildWorkers,
+                }
+            }
+
             // Apply iterations flag from CLI - sets iteration count
             if (options.iterations !== undefined) {
                 config.execution = {
@@ -1015,16 +2012,49 @@ class TestMeApp {
                 }
             }
 
+            // Apply shuffle and seed flags from CLI - randomizes test order reproducibly
+            if (options.shuffle || options.seed !== undefined) {
+                config.execution = {
+                    ...config.execution,
+                    timeout: config.execution?.timeout ?? 30,
+                    parallel: config.execution?.parallel ?? true,
+                    seed: options.seed ?? randomSeed(),
+                }
+            }
+
             const rootDir = resolve(process.cwd())
 
             // Handle clean option
+            if (options.cleanDryRun) {
+                const paths = await this.runner.cleanArtifacts(rootDir, config, true)
+                for (const path of paths) {
+                    console.log(`Would remove ${path}`)
+                }
+                console.log(`${paths.length} artifact and temporary director${paths.length === 1 ? 'y' : 'ies'} found`)
+                return 0
+            }
             if (options.clean) {
                 console.log('Cleaning test artifacts...')
-                await this.runner.cleanArtifacts(rootDir)
-                console.log('✓ All test artifacts cleaned')
+                const count = (await this.runner.cleanArtifacts(rootDir, config)).length
+                console.log(`✓ All test artifacts cleaned (${count} director${count === 1 ? 'y' : 'ies'})`)
                 return 0
             }
 
+            // Handle doctor option - check the toolchain and exit
+            if (options.doctor) {
+                return await handleDoctor(rootDir, config, options)
+            }
+
+            // Handle flaky report option - list unstable tests from the recorded history and exit
+            if (options.flakyReport) {
+                return await handleFlakyReport(rootDir)
+            }
+
+            // Handle list suites option - print the defined suites and exit
+            if (options.listSuites) {
+                return await handleListSuites(rootDir, config, options)
+            }
+
             // Handle list option
             if (options.list) {
                 // Use config patterns for discovery, then filter by CLI patterns if provided
@@ -1033,17 +2063,61 @@ class TestMeApp {
                         rootDir,
                         patterns: config.patterns?.include || [],
                         excludePatterns: config.patterns?.exclude || [],
+                        ...TestDiscovery.getArtifactOptions(config),
+                        ...TestDiscovery.getWalkOptions(config),
                     },
                     config,
                     invocationDir,
-                    options.patterns
+                    options.patterns,
+                    options
                 )
                 return 0
             }
 
+            // Apply report flags from CLI - write machine-readable reports (overrides config)
+            if (options.report) {
+                config = {
+                    ...config,
+                    output: {
+                        ...config.output,
+                        report: options.report,
+                    },
+                }
+            }
+
+            // Keep stdout clean for report streams such as TAP (human output goes to stderr). Conflicting
+            // reports are rejected here, before anything runs.
+            if (this.getReportSpecs(config, options, rootDir).some(isStdoutReport)) {
+                reserveStdout()
+            }
+
+            // Open the NDJSON event feed (fd:N or file:PATH)
+            if (options.events) {
+                EventStream.open(options.events, rootDir)
+            }
+
+            // Write a log file per test under --logdir
+            if (options.logdir) {
+                TestLogs.open(options.logdir, rootDir)
+            }
+
+            // Print compile, run and service commands instead of running them
+            if (options.dryRun) {
+                DryRun.enable()
+            }
+
             // Execute tests hierarchically with proper configuration and services handling
             console.log(`\n🧪 Test runner starting in: ${rootDir}`)
 
+            // Color a terminal unless NO_COLOR is set, or as --color or output.color says
+            config = {
+                ...config,
+                output: {
+                    ...config.output,
+                    colors: useColor(options.color ?? config.output?.color, config.output?.colors !== false),
+                },
+            }
+
             // Apply quiet mode to base config if needed
             if (options.quiet) {
                 config = {
@@ -1069,10 +2143,59 @@ class TestMeApp {
                 }
             }
 
-            return await this.executeHierarchically(rootDir, options.patterns, config, options, invocationDir)
+            // Print the output of failing tests as they complete and nothing for passing tests
+            if (options.verboseOnFail) {
+                config = {
+                    ...config,
+                    output: {
+                        ...config.output,
+                        verboseOnFail: true,
+                    },
+                }
+            }
+
+            // Repeat failures grouped by directory after a verbose listing
+            if (options.summaryFailures) {
+                config = {
+                    ...config,
+                    output: {
+                        ...config.output,
+                        summaryFailures: true,
+                    },
+                }
+            }
+
+            // List the slowest tests after the run
+            if (options.slowest) {
+                config = {
+                    ...config,
+                    output: {
+                        ...config.output,
+                        slowest: options.slowest,
+                    },
+                }
+            }
+
+            // Cap the output retained for each test
+            if (options.maxOutput !== undefined) {
+                config = {
+                    ...config,
+                    output: {
+                        ...config.output,
+                        maxBytes: options.maxOutput,
+                    },
+                }
+            }
+
+            const exitCode = options.watch
+                ? await this.watchTests(rootDir, options.patterns, config, options, invocationDir)
+                : await this.executeHierarchically(rootDir, options.patterns, config, options, invocationDir)
+            EventStream.close()
+            TestLogs.close()
+            return exitCode
         } catch (error) {
-            // Only run cleanup if parsing completed and services were potentially started
-            if (parsingComplete && options && !options.noServices && this.serviceManagers.size > 0) {
+            // Only run cleanup if services were potentially started
+            if (!options.noServices && this.serviceManagers.size > 0) {
                 try {
                     // If we're in the error handler, tests did not pass
                     // Cleanup all service managers that were created
@@ -1097,7 +2220,7 @@ class TestMeApp {
 
             // Don't output errors in quiet mode
             if (!isQuiet) {
-                this.handleError(error, parsingComplete)
+                this.handleError(error, true)
             }
             return 1
         }
@@ -0,0 +1,219 @@
+import {TestType} from './types.ts'
+import {TestDiscovery} from './discovery.ts'
+
+/*
+ Include patterns written for each test type, in the order they appear in the starter configuration
+ */
+const TYPE_PATTERNS: [TestType, string[]][] = [
+    [TestType.Shell, ['**/*.tst.sh']],
+    [TestType.PowerShell, ['**/*.tst.ps1']],
+    [TestType.Batch, ['**/*.tst.bat', '**/*.tst.cmd']],
+    [TestType.C, ['**/*.tst.c']],
+    [TestType.JavaScript, ['**/*.tst.js']],
+    [TestType.TypeScript, ['**/*.tst.ts']],
+    [TestType.Ejscript, ['**/*.tst.es']],
+    [TestType.Python, ['**/*.tst.py']],
+    [TestType.Go, ['**/*.tst.go']],
+    [TestType.Rust, ['**/*.tst.rs']],
+]
+
+/*
+ Types assumed when no tests exist yet, matching the patterns of earlier starter configurations
+ */
+const DEFAULT_TYPES = [
+    TestType.Shell,
+    TestType.PowerShell,
+    TestType.Batch,
+    TestType.C,
+    TestType.JavaScript,
+    TestType.TypeScript,
+    TestType.Ejscript,
+]
+
+/*
+ ConfigTemplate - Starter testme.json5 for --init
+
+ Scans the directory tree for *.tst.* files and writes a commented configuration with sections for the
+ languages found: compiler settings for C, TypeScript, Ejscript, Python and Rust tests, debugger settings
+ for Go tests, and include patterns for just those test types. Without any tests, a general configuration
+ for C, JavaScript, TypeScript, Ejscript and script tests is written.
+ */
+export class ConfigTemplate {
+    /*
+     Detects the types of test files under a directory
+     @param dir Directory to scan (hidden, node_modules, .testme and build directories are skipped)
+     @returns Test types found
+     */
+    static async detect(dir: string): Promise<Set<TestType>> {
+        const patterns = TYPE_PATTERNS.flatMap(([, patterns]) => patterns)
+        const tests = await TestDiscovery.discoverTests({rootDir: dir, patterns, excludePatterns: []})
+        return new Set(tests.map((test) => test.type))
+    }
+
+    /*
+     Renders the starter configuration
+     @param detected Test types found in the tree (all common types are configured if empty)
+     @returns Commented JSON5 configuration text
+     */
+    static render(detected: Set<TestType>): string {
+        const types = detected.size > 0 ? detected : new Set(DEFAULT_TYPES)
+        const include = TYPE_PATTERNS.filter(([type]) => types.has(type))
+            .flatMap(([, patterns]) => patterns)
+            .map((pattern) => `            '${pattern}',\n`)
+            .join('')
+        const languages = [...types].sort().join(', ')
+        return `{
+    /*
+        TestMe Configuration
+        See https://github.com/embedthis/testme for documentation
+
+        Generated for: ${languages}
+     */
+
+    // Enable or disable tests in this directory
+    // true: run tests normally (default)
+    // false: disable all tests
+    // 'manual': only run when explicitly named by full path or base name
+    enable: true,
+
+    // Minimum depth required to run tests (use tm --depth N)
+    depth: 0,
+${this.renderCompiler(types)}${this.renderDebug(types)}
+    // Test execution settings
+    execution: {
+        timeout: 30,        // Timeout per test in seconds (0 for no timeout)
+        parallel: true,     // Run tests in parallel (false serializes tests in this directory)
+        // workers: 8,      // Number of parallel workers (default: number of CPUs)
+    },
+
+    // Output formatting options
+    output: {
+        verbose: false,     // Show detailed output
+        format: 'simple',   // Output format: simple, detailed, json
+        colors: true,       // Enable colored output
+    },
+
+    // File discovery patterns
+    patterns: {
+        include: [
+${include}        ],
+        exclude: ['**/node_modules/**', '**/.testme/**', '**/.*/**'],
+    },
+
+    // Service management for test setup/teardown
+    services: {
+        skip: '',           // Script to check if tests should be skipped
+        environment: '',    // Script to emit environment variables (key=value lines)
+        globalPrep: '',     // Script to run once before all test groups (global setup)
+        prep: '',           // Script to run once before tests in this group
+        setup: '',          // Background service to start before tests
+        cleanup: '',        // Script to run after tests in this group
+        globalCleanup: '',  // Script to run once after all test groups (global teardown)
+        setupDelay: 1,      // Delay in seconds after setup starts before running tests (default: 1)
+        shutdownTimeout: 5, // Wait time in seconds for graceful shutdown before SIGKILL (default: 5)
+    },
+
+    // Environment variables for test execution
+    environment: {
+        // Example: BIN: '\${../build/\${PLATFORM}-\${PROFILE}/bin}',
+    },
+}
+`
+    }
+
+    /*
+     Renders the compiler section for the detected languages that have compiler settings
+     @param types Test types to configure
+     @returns Section text, or empty if no detected language has compiler settings
+     */
+    private static renderCompiler(types: Set<TestType>): string {
+        const sections: string[] = []
+        if (types.has(TestType.C)) {
+            sections.push(`        c: {
+            // Compiler selection has three modes:
+            // 1. Auto-detect: compiler: 'default'
+            // 2. Explicit compiler for all platforms: compiler: 'gcc'
+            // 3. Per-platform compiler map:
+            //    compiler: {
+            //        windows: 'msvc',
+            //        macosx: 'clang',
+            //        linux: 'gcc'
+            //    }
+            compiler: 'default',
+
+            // GCC-specific flags (Unix/Linux/MinGW)
+            gcc: {
+                flags: ['-I..'],
+                libraries: ['m', 'pthread'],
+            },
+
+            // Clang-specific flags (macOS/Unix)
+            clang: {
+                flags: ['-I..'],
+                libraries: ['m', 'pthread'],
+            },
+
+            // MSVC-specific flags (Windows)
+            msvc: {
+                flags: ['/I..'],
+                libraries: [],
+            },
+        },
+`)
+        }
+        if (types.has(TestType.TypeScript)) {
+            sections.push(`        typescript: {
+            mode: 'bun',        // How .tst.ts files run: bun (default), tsc or ts-node
+            // tsconfig: 'tsconfig.json',
+        },
+`)
+        }
+        if (types.has(TestType.Ejscript)) {
+            sections.push(`        es: {
+            require: 'testme',
+        },
+`)
+        }
+        if (types.has(TestType.Python)) {
+            sections.push(`        python: {
+            // interpreter: 'python3', // Default: python3, falling back to python
+            // venv: '.venv',          // Virtualenv to activate, relative to this file
+            args: [],                  // Extra interpreter flags (e.g., ['-X', 'dev'])
+        },
+`)
+        }
+        if (types.has(TestType.Rust)) {
+            sections.push(`        rust: {
+            compiler: 'rustc',
+            flags: ['--edition', '2021'],
+            libraries: [],      // Crates as 'name=path' or native libraries
+        },
+`)
+        }
+        if (sections.length === 0) {
+            return ''
+        }
+        return `
+    // Compiler configuration for different languages
+    compiler: {
+${sections.join('')}    },
+`
+    }
+
+    /*
+     Renders the debugger section for Go tests, which are run with "go run" and need no compiler settings
+     @param types Test types to configure
+     @returns Section text, or empty if there are no Go tests
+     */
+    private static renderDebug(types: Set<TestType>): string {
+        if (!types.has(TestType.Go)) {
+            return ''
+        }
+        return `
+    // Debugger used by tm --debug. Go tests are run with "go run" and need no compiler settings
+    debug: {
+        go: 'delve',        // delve (default), vscode or a debugger path
+    },
+`
+    }
+}
@@ -0,0 +1,103 @@
+import type {TestFile, TestResult} from './types.ts'
+import {mkdirSync, renameSync, rmSync, writeFileSync} from 'fs'
+import {dirname, join, relative, resolve} from 'path'
+
+/*
+ Characters replaced in case names and matrix labels used as file names
+ */
+const UNSAFE_NAME = /[\\/:*?"<>|]/g
+
+/*
+ TestLogs - Writes a log file per test for archiving and grepping (--logdir)
+
+ Each completed test is written to <logdir>/<test path>.log, where the test path is relative to the test
+ root so the log directory mirrors the test tree. A case of a parameterized test adds its name in brackets
+ (math.tst.c[small].log) and a matrix cell is written under a directory named by its label. A log holds the
+ test's status, exit code, duration and command, followed by its combined stdout and stderr. Each file is
+ written to a temporary name and renamed when its test finishes, so a reader never sees a partial log, even
+ while parallel workers finish tests at the same time. The logs are separate from the reports.
+ */
+export class TestLogs {
+    private static dir: string | null = null
+    private static rootDir: string = process.cwd()
+
+    /*
+     Enables the per-test logs
+     @param dir Log directory, relative to the root directory
+     @param rootDir Test root directory
+     */
+    static open(dir: string, rootDir: string): void {
+        this.dir = resolve(rootDir, dir)
+        this.rootDir = rootDir
+    }
+
+    /*
+     Checks if per-test logs are written
+     @returns True if --logdir was given
+     */
+    static isEnabled(): boolean {
+        return this.dir !== null
+    }
+
+    /*
+     Gets the log file path of a test
+     @param file Test file, with its case and matrix cell if any
+     @returns Path of the test's log file
+     */
+    static getPath(file: TestFile): string {
+        // Tests outside the root are placed in the log directory as if they were under it
+        const path = relative(this.rootDir, file.path).replace(/^(\.\.[\\/])+/, '')
+        const name = file.testCase ? `${path}[${file.testCase.name.replace(UNSAFE_NAME, '_')}]` : path
+        const cell = file.matrix ? file.matrix.replace(UNSAFE_NAME, '_') : ''
+        return join(this.dir || this.rootDir, cell, `${name}.log`)
+    }
+
+    /*
+     Writes the log of a completed test
+     A failure to write is reported as a warning and does not affect the run.
+     @param result Completed test result
+     */
+    static write(result: TestResult): void {
+        if (this.dir === null) {
+            return
+        }
+        const path = this.getPath(result.file)
+        const temp = `${path}.${process.pid}.tmp`
+        try {
+            mkdirSync(dirname(path), {recursive: true})
+            writeFileSync(temp, this.format(result))
+            renameSync(temp, path)
+        } catch (error) {
+            rmSync(temp, {force: true})
+            console.warn(`⚠️  Cannot write test log ${path}: ${error}`)
+        }
+    }
+
+    /*
+     Formats the log of a test
+     @param result Completed test result
+     @returns Log text: a header of test details, a blank line and the test output
+     */
+    static format(result: TestResult): string {
+        const header = [
+            `Test: ${relative(this.rootDir, result.file.path).replace(/\\/g, '/')}`,
+            ...(result.file.testCase ? [`Case: ${result.file.testCase.name}`] : []),
+            ...(result.file.matrix ? [`Matrix: ${result.file.matrix}`] : []),
+            `Status: ${result.status}`,
+            `Exit code: ${result.exitCode ?? 'none'}`,
+            `Duration: ${Math.round(result.duration)}ms`,
+            ...(result.attempts !== undefined ? [`Attempts: ${result.attempts}`] : []),
+            `Command: ${result.command || 'none'}`,
+            ...(result.error ? ['Error:', ...result.error.trimEnd().split('\n').map((line) => `    ${line}`)] : []),
+        ]
+        const output = result.output.trimEnd()
+        return `${header.join('\n')}\n\n${output ? `${output}\n` : ''}`
+    }
+
+    /*
+     Disables the per-test logs
+     */
+    static close(): void {
+        this.dir = null
+    }
+}
@@ -0,0 +1,92 @@
+import type {TestConfig, TestFile} from './types.ts'
+
+/*
+ A combination of matrix variable values
+ */
+export type MatrixCell = {
+    label: string // Variable assignments, e.g. "MODE=fast,OPT=2" (empty when there is no matrix)
+    env: Record<string, string> // Environment variables set for the cell
+}
+
+/*
+ Matrix - Environment matrix expansion
+
+ The "matrix" configuration key maps environment variable names to lists of values. The selected
+ tests run once per combination of values (the cartesian product), in order, with the combination's
+ variables added to the environment of the tests and of their services. Results are labeled with
+ the combination, e.g. "[MODE=fast] math.tst.c", and summarized per combination.
+ --matrix NAME=VALUE pins a variable to one of its values, restricting the run to matching cells.
+ */
+export class Matrix {
+    /*
+     Gets the cells to run, in order
+     @param matrix Matrix configuration (variable names to value lists)
+     @param pins Variable values selected with --matrix
+     @returns Cells to run, or a single unlabeled cell if there is no matrix
+     @throws Error if a pinned variable or value is not in the matrix
+     */
+    static getCells(
+        matrix: Record<string, (string | number | boolean)[]> | undefined,
+        pins: Record<string, string> = {}
+    ): MatrixCell[] {
+        const variables = Object.entries(matrix || {}).map(
+            ([name, values]) => [name, (Array.isArray(values) ? values : [values]).map(String)] as const
+        )
+        for (const [name, value] of Object.entries(pins)) {
+            const values = variables.find(([variable]) => variable === name)?.[1]
+            if (!values) {
+                throw new Error(`--matrix ${name}=${value}: ${name} is not a matrix variable`)
+            }
+            if (!values.includes(value)) {
+                throw new Error(`--matrix ${name}=${value}: ${name} must be one of ${values.join(', ')}`)
+            }
+        }
+
+        let cells: MatrixCell[] = [{label: '', env: {}}]
+        for (const [name, values] of variables) {
+            const selected = pins[name] !== undefined ? [pins[name]!] : values
+            cells = cells.flatMap((cell) =>
+                selected.map((value) => ({
+                    label: cell.label ? `${cell.label},${name}=${value}` : `${name}=${value}`,
+                    env: {...cell.env, [name]: value},
+                }))
+            )
+        }
+        return cells
+    }
+
+    /*
+     Labels tests with a matrix cell
+     @param tests Test files
+     @param cell Matrix cell
+     @returns Copies of the tests carrying the cell label (the tests themselves if the cell is unlabeled)
+     */
+    static apply(tests: TestFile[], cell: MatrixCell): TestFile[] {
+        return cell.label ? tests.map((test) => ({...test, matrix: cell.label})) : tests
+    }
+
+    /*
+     Adds the variables of a matrix cell to a configuration's environment
+     The deprecated env key is merged beneath environment, and the result is set as both so either is complete.
+     @param config Test configuration
+     @param cell Matrix cell
+     @returns Configuration with the cell's variables overriding configured values
+     */
+    static applyEnvironment(config: TestConfig, cell: MatrixCell): TestConfig {
+        if (!cell.label) {
+            return config
+        }
+        const environment = {...config.env, ...config.environment, ...cell.env}
+        return {...config, env: environment, environment}
+    }
+
+    /*
+     Gets the display name of a test, prefixed with its matrix cell
+     @param file Test file
+     @param name Display name of the test
+     @returns Name, preceded by [LABEL] for a test run in a matrix cell
+     */
+    static label(file: TestFile, name: string): string {
+        return file.matrix ? `[${file.matrix}] ${name}` : name
+    }
+}
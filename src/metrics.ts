@@ -0,0 +1,124 @@
+import type {MetricsConfig, RunSummary, TestResult} from './types.ts'
+import {RESULT_CATEGORIES, countCategories, getCategory} from './utils/categories.ts'
+import {hostname} from 'os'
+
+// How long to wait for the Pushgateway to respond
+const TIMEOUT = 10000
+
+/*
+ RunMetrics - Pushes the metrics of a completed run to a Prometheus Pushgateway (--metrics-push, metrics.push)
+
+ The metrics are gauges in the Prometheus text format: run totals (testme_tests_total, testme_tests_passed,
+ testme_tests_failed, testme_tests_skipped), the count per result category, the run duration, success and
+ completion time, and the totals, failures and duration of each test language. Crashes, timeouts and errors
+ count as failures. The metrics are PUT to the job and instance grouping key, replacing those of the previous
+ run, so a test that is no longer run does not leave a stale series behind. Pushing is best effort: problems
+ only print a warning and never change the exit code.
+ */
+export class RunMetrics {
+    /*
+     Pushes the metrics of a completed run if a Pushgateway is configured
+     @param config Metrics configuration
+     @param summary Completed run
+     @returns True if the metrics were pushed
+     */
+    static async push(config: MetricsConfig | undefined, summary: RunSummary): Promise<boolean> {
+        if (!config?.push) {
+            return false
+        }
+        const url = this.getUrl(config.push, config.job || 'testme', config.instance || hostname())
+        try {
+            const response = await fetch(url, {
+                method: 'PUT',
+                headers: {'Content-Type': 'text/plain; version=0.0.4'},
+                body: this.render(summary),
+                signal: AbortSignal.timeout(TIMEOUT),
+            })
+            if (!response.ok) {
+                const text = (await response.text()).trim()
+                throw new Error(`HTTP ${response.status}${text ? `: ${text}` : ''}`)
+            }
+            return true
+        } catch (error) {
+            console.warn(`⚠️  Failed to push metrics to ${config.push}: ${error}`)
+            return false
+        }
+    }
+
+    /*
+     Renders the metrics of a run in the Prometheus text exposition format
+     @param summary Completed run
+     @returns Metrics text
+     */
+    static render(summary: RunSummary): string {
+        const results = summary.results
+        const categories = countCategories(results)
+        const failed = results.length - categories.pass - categories.skip
+        const lines: string[] = []
+        const gauge = (name: string, help: string, samples: [string, number][]) => {
+            lines.push(`# HELP ${name} ${help}`, `# TYPE ${name} gauge`)
+            for (const [labels, value] of samples) {
+                lines.push(`${name}${labels} ${value}`)
+            }
+        }
+        gauge('testme_tests_total', 'Test results in the run, including skipped tests', [['', results.length]])
+        gauge('testme_tests_passed', 'Tests that passed', [['', categories.pass]])
+        gauge('testme_tests_failed', 'Tests that failed, crashed, timed out or could not be built', [['', failed]])
+        gauge('testme_tests_skipped', 'Tests that were skipped', [['', categories.skip]])
+        gauge(
+            'testme_tests_category',
+            'Test results per result category',
+            RESULT_CATEGORIES.map((category) => [this.labels({category}), categories[category]])
+        )
+        gauge('testme_run_duration_seconds', 'Wall-clock duration of the run', [['', summary.elapsed / 1000]])
+        const blocking = results.some(
+            (result) => !result.file.quarantined && !['pass', 'skip'].includes(getCategory(result))
+        )
+        const success = blocking || summary.interrupted ? 0 : 1
+        gauge('testme_run_success', 'Whether the run passed (1) or not (0), as for the exit code', [['', success]])
+        gauge('testme_run_timestamp_seconds', 'Unix time the run completed', [['', Math.floor(Date.now() / 1000)]])
+
+        const languages = new Map<string, TestResult[]>()
+        for (const result of results) {
+            languages.set(result.file.type, [...(languages.get(result.file.type) || []), result])
+        }
+        const byLanguage = (value: (list: TestResult[]) => number): [string, number][] =>
+            [...languages.keys()].sort().map((language) => [this.labels({language}), value(languages.get(language)!)])
+        gauge('testme_language_tests_total', 'Test results per language', byLanguage((list) => list.length))
+        gauge(
+            'testme_language_tests_failed',
+            'Failed tests per language',
+            byLanguage((list) => list.filter((result) => !['pass', 'skip'].includes(getCategory(result))).length)
+        )
+        gauge(
+            'testme_language_duration_seconds',
+            'Summed test duration per language',
+            byLanguage((list) => list.reduce((sum, result) => sum + result.duration, 0) / 1000)
+        )
+        return lines.join('\n') + '\n'
+    }
+
+    /*
+     Builds the Pushgateway URL for a grouping key
+     Label values containing a slash, or empty, use the base64 form of the path segment.
+     @param base Pushgateway URL
+     @param job Job label value
+     @param instance Instance label value
+     @returns URL to push to
+     */
+    static getUrl(base: string, job: string, instance: string): string {
+        const segment = (name: string, value: string) =>
+            value === '' || value.includes('/')
+                ? `${name}@base64/${Buffer.from(value).toString('base64url') || '='}`
+                : `${name}/${encodeURIComponent(value)}`
+        return `${base.replace(/\/+$/, '')}/metrics/${segment('job', job)}/${segment('instance', instance)}`
+    }
+
+    private static labels(labels: Record<string, string>): string {
+        const pairs = Object.entries(labels).map(([name, value]) => {
+            const escaped = value.replace(/\\/g, '\\\\').replace(/"/g, '\\"').replace(/\n/g, '\\n')
+            return `${name}="${escaped}"`
+        })
+        return `{${pairs.join(',')}}`
+    }
+}
@@ -676,6 +676,141 @@ TM_UNUSED static void tReport(int success, const char *loc, const char *expected
                                 tReportPtr(_r, TM_LOC, p, NULL, __VA_ARGS__); \
                             } else
 
+/******************************** Soft Assertion Macros ***********************/
+
+/*
+    Soft assertions print a standardized line for every check and continue after a failure:
+
+        PASS math.tst.c:12 sum == 3
+        FAIL math.tst.c:14 name == "abc": expected abc, received abd
+
+    TestMe counts these lines as assertions and fails a test that includes this header and prints a FAIL
+    line. Call treport() at the end of main() to print the totals and exit with a non-zero status if any
+    soft assertion failed.
+ */
+
+//  Source location as file:line for soft assertion lines
+#define TM_SRC              __FILE__ ":" TM_LINE3
+
+//  Counts of soft assertions that passed and failed
+TM_UNUSED static int tmPassed = 0;
+TM_UNUSED static int tmFailed = 0;
+
+/**
+    Emit a standardized PASS or FAIL line for a soft assertion and record the outcome.
+    @param success The success of the assertion.
+    @param loc The location of the assertion (file:line).
+    @param expr The assertion expression, emitted if there is no message.
+    @param expected Expected value to report on failure, or NULL.
+    @param received Received value to report on failure, or NULL.
+    @param fmt Message to emit
+ */
+TM_UNUSED static void tCheck(int success, const char *loc, const char *expr, const char *expected,
+    const char *received, const char *fmt, ...) {
+    va_list     ap;
+    char        buf[TM_MAX_BUFFER];
+
+    if (fmt && *fmt) {
+        va_start(ap, fmt);
+        vsnprintf(buf, sizeof(buf), fmt, ap);
+        va_end(ap);
+    } else {
+        snprintf(buf, sizeof(buf), "%s", expr);
+    }
+    if (success) {
+        tmPassed++;
+        printf("PASS %s %s\n", loc, buf);
+    } else {
+        tmFailed++;
+        if (expected || received) {
+            printf("FAIL %s %s: expected %s, received %s\n", loc, buf, expected ? expected : "(NULL)",
+                received ? received : "(NULL)");
+        } else {
+            printf("FAIL %s %s\n", loc, buf);
+        }
+    }
+    fflush(stdout);
+}
+
+/**
+    Print the soft assertion totals and exit.
+    Exits with status 1 if any soft assertion failed, otherwise 0. If TESTME_SLEEP is set, pauses on failure
+    for debugging.
+    Example: int main() { teq_int(add(1, 2), 3); treport(); }
+ */
+TM_UNUSED static void treport(void) {
+    printf("%d passed, %d failed\n", tmPassed, tmFailed);
+    fflush(stdout);
+    texit(tmFailed == 0);
+    exit(tmFailed > 0 ? 1 : 0);
+}
+
+/**
+    Soft assertion that an expression is true.
+    @param E The expression to test
+    @param ... Optional printf-style format string and arguments for custom message
+    Example: tcheck(count > 0, "Should have items");
+ */
+#define tcheck(E, ...)      if (1) { \
+                                tCheck((E) != 0, TM_SRC, #E, NULL, NULL, "" __VA_ARGS__); \
+                            } else
+
+/**
+    Soft assertion that two integer values are equal.
+    @param a Received value
+    @param b Expected value
+    @param ... Optional printf-style format string and arguments for custom message
+    Example: teq_int(add(1, 2), 3);
+ */
+#define teq_int(a, b, ...)  if (1) { \
+                                int _a = (int) (a), _b = (int) (b); \
+                                char ebuf[80], rbuf[80]; \
+                                snprintf(ebuf, sizeof(ebuf), "%d", _b); \
+                                snprintf(rbuf, sizeof(rbuf), "%d", _a); \
+                                tCheck(_a == _b, TM_SRC, #a " == " #b, ebuf, rbuf, "" __VA_ARGS__); \
+                            } else
+
+/**
+    Soft assertion that two integer values are not equal.
+    @param a Received value
+    @param b Value it must differ from
+    @param ... Optional printf-style format string and arguments for custom message
+    Example: tneq_int(fd, -1, "Open should succeed");
+ */
+#define tneq_int(a, b, ...) if (1) { \
+                                int _a = (int) (a), _b = (int) (b); \
+                                char ebuf[80], rbuf[80]; \
+                                snprintf(ebuf, sizeof(ebuf), "not %d", _b); \
+                                snprintf(rbuf, sizeof(rbuf), "%d", _a); \
+                                tCheck(_a != _b, TM_SRC, #a " != " #b, ebuf, rbuf, "" __VA_ARGS__); \
+                            } else
+
+/**
+    Soft assertion that two strings are equal. NULL strings are equal only to NULL.
+    @param a Received string
+    @param b Expected string
+    @param ... Optional printf-style format string and arguments for custom message
+    Example: teq_str(getName(), "admin");
+ */
+#define teq_str(a, b, ...)  if (1) { \
+                                const char *_a = (a), *_b = (b); \
+                                int _r = (_a && _b) ? strcmp(_a, _b) == 0 : _a == _b; \
+                                tCheck(_r, TM_SRC, #a " == " #b, _b, _a, "" __VA_ARGS__); \
+                            } else
+
+/**
+    Soft assertion that two strings are not equal. NULL strings are equal only to NULL.
+    @param a Received string
+    @param b String it must differ from
+    @param ... Optional printf-style format string and arguments for custom message
+    Example: tneq_str(token, "", "Token should be set");
+ */
+#define tneq_str(a, b, ...) if (1) { \
+                                const char *_a = (a), *_b = (b); \
+                                int _r = (_a && _b) ? strcmp(_a, _b) != 0 : _a != _b; \
+                                tCheck(_r, TM_SRC, #a " != " #b, NULL, NULL, "" __VA_ARGS__); \
+                            } else
+
 /******************************** Legacy/Deprecated Macros ********************/
 
 /**
@@ -0,0 +1,86 @@
+/*
+   testme.go -- Result helpers for TestMe Go tests
+
+   Reports named sub-results with the TestMe line protocol. Each call prints one line to stdout:
+
+       TESTME {"event":"assert","name":"parse header","status":"fail","message":"got 4, expected 3"}
+
+   Install into a package of your module with "tm --emit-header go > testme/testme.go" and import it from tests.
+   Call Exit at the end of main to exit with status 1 if any result failed.
+
+   Copyright (c) All Rights Reserved. See details at the end of the file.
+*/
+
+package testme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+var failed = 0
+
+// Result reports a named sub-result with status "pass", "fail" or "skip" and an optional message
+func Result(name string, status string, message string) {
+	report(name, status, message)
+}
+
+// report prints the protocol line for a sub-result, located at the caller of the public helper
+func report(name string, status string, message string) {
+	event := map[string]interface{}{"event": "assert", "name": name, "status": status}
+	if message != "" {
+		event["message"] = message
+	}
+	if _, file, line, ok := runtime.Caller(2); ok {
+		event["file"] = file
+		event["line"] = line
+	}
+	if status == "fail" {
+		failed++
+	}
+	data, _ := json.Marshal(event)
+	fmt.Printf("TESTME %s\n", data)
+}
+
+// Pass reports a passing sub-result
+func Pass(name string) {
+	report(name, "pass", "")
+}
+
+// Fail reports a failing sub-result with a printf-style message
+func Fail(name string, format string, args ...interface{}) {
+	report(name, "fail", fmt.Sprintf(format, args...))
+}
+
+// Skip reports a skipped sub-result with a reason
+func Skip(name string, reason string) {
+	report(name, "skip", reason)
+}
+
+// Check reports a sub-result that passes if ok is true
+func Check(name string, ok bool, format string, args ...interface{}) {
+	if ok {
+		report(name, "pass", "")
+	} else {
+		report(name, "fail", fmt.Sprintf(format, args...))
+	}
+}
+
+// Exit exits with status 1 if any sub-result failed, otherwise 0
+func Exit() {
+	if failed > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+/*
+   Copyright (c) Embedthis Software. All Rights Reserved.
+   This software is distributed under commercial and open source licenses.
+   You may use the Embedthis Open Source license or you may acquire a
+   commercial license from Embedthis Software. You agree to be fully bound
+   by the terms of either license. Consult the LICENSE.md distributed with
+   this software for full details and other copyrights.
+*/
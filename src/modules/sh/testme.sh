@@ -0,0 +1,77 @@
+#
+#   testme.sh -- Result helpers for TestMe shell tests
+#
+#   Reports named sub-results with the TestMe line protocol. Each call prints one line to stdout:
+#
+#       TESTME {"event":"assert","name":"config exists","status":"fail","message":"missing app.conf"}
+#
+#   Install with "tm --emit-header sh > testme.sh" and source it from tests: . "$(dirname "$0")/testme.sh"
+#   Call tm_exit at the end of the test to exit with status 1 if any result failed.
+#
+#   Copyright (c) All Rights Reserved. See details at the end of the file.
+#
+
+TM_FAILED=0
+
+#   Quote a string as a JSON string
+tm_json() {
+    printf '"%s"' "$(printf '%s' "$1" | sed -e 's/\\/\\\\/g' -e 's/"/\\"/g' \
+        -e "s/$(printf '\t')/\\\\t/g" -e "s/$(printf '\r')/\\\\r/g" |
+        awk '{ if (NR > 1) printf "\\n"; printf "%s", $0 }')"
+}
+
+#   Report a named sub-result: tm_result NAME pass|fail|skip [MESSAGE]
+tm_result() {
+    if [ "$2" = fail ]; then
+        TM_FAILED=$((TM_FAILED + 1))
+    fi
+    if [ -n "$3" ]; then
+        printf 'TESTME {"event":"assert","name":%s,"status":"%s","message":%s}\n' \
+            "$(tm_json "$1")" "$2" "$(tm_json "$3")"
+    else
+        printf 'TESTME {"event":"assert","name":%s,"status":"%s"}\n' "$(tm_json "$1")" "$2"
+    fi
+}
+
+#   Report a passing sub-result: tm_pass NAME
+tm_pass() {
+    tm_result "$1" pass
+}
+
+#   Report a failing sub-result: tm_fail NAME [MESSAGE]
+tm_fail() {
+    tm_result "$1" fail "$2"
+}
+
+#   Report a skipped sub-result: tm_skip NAME [REASON]
+tm_skip() {
+    tm_result "$1" skip "$2"
+}
+
+#   Run a command and report a sub-result that passes if it exits zero: tm_check NAME COMMAND [ARGS...]
+tm_check() {
+    _tm_name="$1"
+    shift
+    if "$@"; then
+        tm_result "$_tm_name" pass
+    else
+        tm_result "$_tm_name" fail "exit status $?: $*"
+    fi
+}
+
+#   Exit with status 1 if any sub-result failed, otherwise 0
+tm_exit() {
+    if [ "$TM_FAILED" -gt 0 ]; then
+        exit 1
+    fi
+    exit 0
+}
+
+#
+#   Copyright (c) Embedthis Software. All Rights Reserved.
+#   This software is distributed under commercial and open source licenses.
+#   You may use the Embedthis Open Source license or you may acquire a
+#   commercial license from Embedthis Software. You agree to be fully bound
+#   by the terms of either license. Consult the LICENSE.md distributed with
+#   this software for full details and other copyrights.
+#
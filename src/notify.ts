@@ -0,0 +1,149 @@
+import type {NotifyConfig, RunSummary} from './types.ts'
+import {VERSION} from './version.ts'
+import {Matrix} from './matrix.ts'
+import {TestCases} from './cases.ts'
+import {stripAnsi} from './utils/tty.ts'
+import {RESULT_CATEGORIES, countCategories, getCategory} from './utils/categories.ts'
+import type {ResultCategory} from './utils/categories.ts'
+import {hostname} from 'os'
+import {relative} from 'path'
+
+// Most failing tests listed in a notification
+const MAX_FAILURES = 10
+
+// Most characters of a failure's error message included in a notification
+const MAX_MESSAGE = 300
+
+// How long to wait for the webhook to respond
+const TIMEOUT = 10000
+
+/*
+ Notification payload in the 'json' format
+ */
+export type NotifyPayload = {
+    version: string
+    status: 'passed' | 'failed' | 'interrupted'
+    host: string
+    rootDir: string
+    duration: number
+    total: number
+    categories: Record<ResultCategory, number>
+    failures: {test: string; category: ResultCategory; duration: number; error?: string}[]
+}
+
+/*
+ Notifier - Posts a summary of each completed run to a webhook (notify.webhook)
+
+ The payload is JSON with the run status, host, totals per result category, duration and the first
+ MAX_FAILURES failing tests. The 'slack' format wraps the same summary as a Slack incoming-webhook
+ message ({text}) so it can be posted to a channel without a relay. With notify.onlyOnFailure, passing
+ runs send nothing. Delivery problems only print a warning: a notification never changes the exit code.
+ */
+export class Notifier {
+    /*
+     Sends the notification for a completed run if a webhook is configured
+     @param config Notification configuration
+     @param summary Completed run
+     @returns True if a notification was delivered
+     */
+    static async send(config: NotifyConfig | undefined, summary: RunSummary): Promise<boolean> {
+        if (!config?.webhook) {
+            return false
+        }
+        const payload = this.buildPayload(summary)
+        if (config.onlyOnFailure && payload.status === 'passed') {
+            return false
+        }
+        const body = config.format === 'slack' ? this.formatSlack(payload) : payload
+        try {
+            const response = await fetch(config.webhook, {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json', ...config.headers},
+                body: JSON.stringify(body),
+                signal: AbortSignal.timeout(TIMEOUT),
+            })
+            if (!response.ok) {
+                throw new Error(`HTTP ${response.status} ${response.statusText}`.trim())
+            }
+            return true
+        } catch (error) {
+            // Only the origin is shown, webhook paths often embed a secret token
+            console.warn(`⚠️  Failed to send notification to ${this.getOrigin(config.webhook)}: ${error}`)
+            return false
+        }
+    }
+
+    /*
+     Builds the notification payload for a run
+     @param summary Completed run
+     @returns Payload in the 'json' format
+     */
+    static buildPayload(summary: RunSummary): NotifyPayload {
+        const categories = countCategories(summary.results)
+        // Quarantined failures are listed but, as for the exit code, do not make the run fail
+        const failed = summary.results.some(
+            (result) => !result.file.quarantined && !['pass', 'skip'].includes(getCategory(result))
+        )
+        const failures = summary.results
+            .filter((result) => !['pass', 'skip'].includes(getCategory(result)))
+            .slice(0, MAX_FAILURES)
+            .map((result) => {
+                const path = relative(summary.rootDir, result.file.path).replace(/\\/g, '/')
+                const error = result.error ? stripAnsi(result.error).trim().slice(0, MAX_MESSAGE) : undefined
+                return {
+                    test: Matrix.label(result.file, TestCases.label(result.file, path)),
+                    category: getCategory(result),
+                    duration: result.duration,
+                    ...(error ? {error} : {}),
+                }
+            })
+        return {
+            version: VERSION,
+            status: summary.interrupted ? 'interrupted' : failed ? 'failed' : 'passed',
+            host: hostname(),
+            rootDir: summary.rootDir,
+            duration: summary.elapsed,
+            total: summary.results.length,
+            categories,
+            failures,
+        }
+    }
+
+    /*
+     Formats a payload as a Slack incoming-webhook message
+     @param payload Notification payload
+     @returns Slack message
+     */
+    static formatSlack(payload: NotifyPayload): {text: string} {
+        const icon = payload.status === 'passed' ? ':white_check_mark:' : ':x:'
+        const counts = RESULT_CATEGORIES.filter((category) => payload.categories[category])
+            .map((category) => `${payload.categories[category]} ${category}`)
+            .join(', ')
+        const lines = [
+            `${icon} *TestMe run ${payload.status}* on \`${payload.host}\` in ${this.formatDuration(payload.duration)}`,
+            `${payload.total} test(s): ${counts || 'none run'}`,
+        ]
+        for (const failure of payload.failures) {
+            const error = failure.error ? `: ${failure.error.split('\n')[0]}` : ''
+            lines.push(`• \`${failure.test}\` ${failure.category}${error}`)
+        }
+        const listed = payload.failures.length
+        const failing = payload.total - payload.categories.pass - payload.categories.skip
+        if (failing > listed) {
+            lines.push(`_${failing - listed} more failing test(s)_`)
+        }
+        return {text: lines.join('\n')}
+    }
+
+    private static formatDuration(ms: number): string {
+        return ms < 1000 ? `${Math.round(ms)}ms` : `${(ms / 1000).toFixed(1)}s`
+    }
+
+    private static getOrigin(url: string): string {
+        try {
+            return new URL(url).origin
+        } catch {
+            return 'webhook'
+        }
+    }
+}
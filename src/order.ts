@@ -0,0 +1,254 @@
+import type {TestFile, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {Directives} from './directives.ts'
+import {existsSync} from 'fs'
+import {basename, relative, resolve} from 'path'
+
+/*
+ TestOrder - Ordering of tests declared with the "testme: after TEST, ..." directive
+
+ A test runs once every run of its prerequisites (all cases, in the same matrix cell) has completed, and is
+ skipped if any of them did not pass. Configuration groups holding prerequisites run first, and tests are sorted
+ so prerequisites come before the tests that need them. Workers still run independent tests in parallel: each
+ takes the first queued test whose prerequisites are complete and waits only while every queued test is blocked
+ by a running test. Prerequisites not selected for the run are ignored. Cycles are reported before tests run.
+ */
+export class TestOrder {
+    // Runs of each test (by matrix cell and path) that have not completed
+    private remaining = new Map<string, number>()
+    // First run of each test that did not pass
+    private failures = new Map<string, TestResult>()
+    // Tests of the suite being run
+    private suite = new Set<string>()
+    private waiters: Array<() => void> = []
+    private pulling: Promise<void> | null = null
+    private exhausted = false
+
+    /*
+     Creates the ordering of a run
+     @param tests Every test run planned, with prerequisites set by resolve()
+     */
+    constructor(tests: TestFile[] = []) {
+        for (const file of tests) {
+            const key = TestOrder.key(file)
+            this.remaining.set(key, (this.remaining.get(key) ?? 0) + 1)
+        }
+    }
+
+    /*
+     Reads the after directives of tests and sets their prerequisites, relative to each test file
+     @param tests Tests selected for the run
+     @throws Error naming a prerequisite that does not exist, or the tests of a dependency cycle
+     */
+    static async resolve(tests: TestFile[]): Promise<void> {
+        const after = new Map<string, string[]>()
+        for (const file of tests) {
+            if (after.has(file.path)) {
+                continue
+            }
+            const paths = ((await Directives.read(file.path)).after || []).map((name) => resolve(file.directory, name))
+            const missing = paths.find((path) => !existsSync(path))
+            if (missing) {
+                throw new Error(`Prerequisite of ${file.name} not found: ${missing}`)
+            }
+            after.set(file.path, paths)
+        }
+        const cycle = this.findCycle(after)
+        if (cycle) {
+            const names = cycle.map((path) => relative(process.cwd(), path)).join(' → ')
+            throw new Error(`Dependency cycle in "testme: after" directives: ${names}`)
+        }
+        for (const file of tests) {
+            const paths = after.get(file.path)!
+            if (paths.length > 0) {
+                file.after = paths
+            }
+        }
+    }
+
+    /*
+     Sorts tests so each comes after its prerequisites, otherwise keeping their order
+     @param tests Tests with prerequisites set by resolve()
+     @returns Sorted tests
+     */
+    static sortTests(tests: TestFile[]): TestFile[] {
+        const runs = new Map<string, TestFile[]>()
+        for (const file of tests) {
+            const key = this.key(file)
+            runs.set(key, [...(runs.get(key) || []), file])
+        }
+        return this.sort(tests, (file) => (file.after || []).flatMap((path) => runs.get(this.key(file, path)) || []))
+    }
+
+    /*
+     Sorts configuration groups so groups holding prerequisites run before the groups that need them
+     @param groups Tests by configuration directory
+     @returns Groups in run order. Of groups that need each other, tests in the group run first are skipped.
+     */
+    static sortGroups(groups: Map<string, TestFile[]>): Map<string, TestFile[]> {
+        const owners = new Map<string, string>()
+        for (const [dir, tests] of groups) {
+            tests.forEach((file) => owners.set(file.path, dir))
+        }
+        const needs = (dir: string) =>
+            groups
+                .get(dir)!
+                .flatMap((file) => file.after || [])
+                .map((path) => owners.get(path))
+                .filter((owner): owner is string => owner !== undefined && owner !== dir)
+        return new Map(this.sort([...groups.keys()], needs).map((dir) => [dir, groups.get(dir)!]))
+    }
+
+    /*
+     Starts running a suite
+     @param tests Tests of the suite
+     @returns Tests sorted so prerequisites come first
+     */
+    begin(tests: TestFile[]): TestFile[] {
+        this.suite = new Set(tests.map((file) => TestOrder.key(file)))
+        this.pulling = null
+        this.exhausted = false
+        return TestOrder.sortTests(tests)
+    }
+
+    /*
+     Takes the next queued test whose prerequisites are complete, waiting while every queued test is blocked
+     @param queue Tests of the suite waiting to run. The test taken is removed.
+     @param source Gets further tests in turn, e.g., as their builds complete
+     @returns Test to run, or undefined once the queue is empty and the source has no more tests
+     */
+    async next(queue: TestFile[], source?: () => Promise<TestFile | undefined>): Promise<TestFile | undefined> {
+        while (true) {
+            const index = queue.findIndex((file) => this.isReady(file))
+            if (index >= 0) {
+                return queue.splice(index, 1)[0]
+            }
+            if (source && !this.exhausted) {
+                // One worker at a time takes from the source. Others wait for it or for a running test to complete.
+                this.pulling ??= source().then((file) => {
+                    this.pulling = null
+                    if (file) {
+                        queue.push(file)
+                    } else {
+                        this.exhausted = true
+                    }
+                    this.wake()
+                })
+            } else if (queue.length === 0) {
+                return undefined
+            }
+            await new Promise<void>((resolve) => this.waiters.push(resolve))
+        }
+    }
+
+    /*
+     Records a completed test run and wakes workers waiting for it
+     @param result Test result
+     */
+    complete(result: TestResult): void {
+        const key = TestOrder.key(result.file)
+        this.remaining.set(key, Math.max(0, (this.remaining.get(key) ?? 0) - 1))
+        if (result.status !== TestStatus.Passed && !this.failures.has(key)) {
+            this.failures.set(key, result)
+        }
+        this.wake()
+    }
+
+    /*
+     Gets why a test cannot run: a prerequisite run that did not pass or that has not run
+     @param file Test file
+     @returns Reason to skip the test, or null if every prerequisite in the run passed
+     */
+    getSkipReason(file: TestFile): string | null {
+        for (const path of file.after || []) {
+            const key = TestOrder.key(file, path)
+            const failure = this.failures.get(key)
+            if (failure) {
+                return `Prerequisite ${basename(path)} did not pass (${failure.status})`
+            }
+            if (this.remaining.get(key)) {
+                return `Prerequisite ${basename(path)} has not run`
+            }
+        }
+        return null
+    }
+
+    /*
+     Checks if every prerequisite of a test in the current suite has completed
+     A prerequisite in a later suite does not block the test, which is then skipped.
+     */
+    private isReady(file: TestFile): boolean {
+        return (file.after || []).every((path) => {
+            const key = TestOrder.key(file, path)
+            return !this.remaining.get(key) || !this.suite.has(key)
+        })
+    }
+
+    private wake(): void {
+        this.waiters.splice(0).forEach((resolve) => resolve())
+    }
+
+    /*
+     Gets the key of a test's runs in a matrix cell
+     @param file Test file
+     @param path Path of the test, or of a prerequisite in the same matrix cell
+     */
+    private static key(file: TestFile, path = file.path): string {
+        return `${file.matrix ?? ''}\n${path}`
+    }
+
+    /*
+     Finds a cycle of prerequisites
+     @param after Prerequisite paths of each test
+     @returns Paths of the cycle, starting and ending with the same test, or null if there is none
+     */
+    private static findCycle(after: Map<string, string[]>): string[] | null {
+        const done = new Set<string>()
+        const stack: string[] = []
+        const visit = (path: string): string[] | null => {
+            if (stack.includes(path)) {
+                return [...stack.slice(stack.indexOf(path)), path]
+            }
+            if (done.has(path) || !after.has(path)) {
+                return null
+            }
+            stack.push(path)
+            for (const prerequisite of after.get(path)!) {
+                const cycle = visit(prerequisite)
+                if (cycle) {
+                    return cycle
+                }
+            }
+            stack.pop()
+            done.add(path)
+            return null
+        }
+        for (const path of after.keys()) {
+            const cycle = visit(path)
+            if (cycle) {
+                return cycle
+            }
+        }
+        return null
+    }
+
+    /*
+     Sorts items so each comes after its prerequisites, otherwise keeping their order
+     @param items Items in their preferred order
+     @param prerequisites Gets the items an item must follow
+     @returns Sorted items. Items that need each other are sorted from the first reached.
+     */
+    private static sort<T>(items: T[], prerequisites: (item: T) => T[]): T[] {
+        const sorted: T[] = []
+        const seen = new Set<T>()
+        const visit = (item: T) => {
+            if (!seen.has(item)) {
+                seen.add(item)
+                prerequisites(item).forEach(visit)
+                sorted.push(item)
+            }
+        }
+        items.forEach(visit)
+        return sorted
+    }
+}
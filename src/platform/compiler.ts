@@ -1,6 +1,8 @@
 import {PlatformDetector} from './detector.ts'
 import type {CompilerInfo} from './detector.ts'
 import {PermissionManager} from './permissions.ts'
+import type {TestConfig} from '../types.ts'
+import {CrossTarget} from '../target.ts'
 import os from 'os'
 
 export enum CompilerType {
@@ -23,6 +25,22 @@ export interface CompilerConfig {
     }
 }
 
+/*
+ C compiler chosen for a configuration and where the choice came from
+ */
+export interface CompilerSelection {
+    name?: string // Compiler name or path, undefined to auto-detect
+    source:
+        | '--cc'
+        | 'target.cc'
+        | 'target'
+        | 'compiler.cc'
+        | 'compiler.c.compiler'
+        | 'compiler.toolchain'
+        | '$CC'
+        | 'auto-detected'
+}
+
 export interface CompileResult {
     success: boolean
     outputPath: string
@@ -94,6 +112,42 @@ export class CompilerManager {
         return result
     }
 
+    /*
+     Selects the C compiler for a configuration
+     The --cc option wins, then the compiler.cc and compiler.c.compiler keys, then the compiler family named by
+     compiler.toolchain, then $CC. A value of 'default' defers to the next choice, and auto-detection is used if
+     nothing is set. When a target
+     triple is set, the host settings are ignored: --cc wins, then target.cc, then <triple>-gcc.
+     @param config Test configuration
+     @returns Compiler to pass to getDefaultCompilerConfig() and its source
+     */
+    static selectCompiler(config: TestConfig): CompilerSelection {
+        const triple = CrossTarget.getTriple(config)
+        if (triple) {
+            if (config.execution?.cc) {
+                return {name: config.execution.cc, source: '--cc'}
+            }
+            return config.target?.cc
+                ? {name: config.target.cc, source: 'target.cc'}
+                : {name: `${triple}-gcc`, source: 'target'}
+        }
+        const platform = PlatformDetector.isWindows() ? 'windows' : PlatformDetector.isMacOS() ? 'macosx' : 'linux'
+        const choices: [CompilerSelection['source'], string | Record<string, string | undefined> | undefined][] = [
+            ['--cc', config.execution?.cc],
+            ['compiler.cc', config.compiler?.cc],
+            ['compiler.c.compiler', config.compiler?.c?.compiler],
+            ['compiler.toolchain', config.compiler?.toolchain],
+            ['$CC', process.env.CC?.trim()],
+        ]
+        for (const [source, value] of choices) {
+            const name = typeof value === 'object' ? value[platform] : value
+            if (name && name !== 'default') {
+                return {name, source}
+            }
+        }
+        return {source: 'auto-detected'}
+    }
+
     /*
      Gets the default compiler configuration for the platform
      Caches result for each unique compiler name to avoid repeated detection
@@ -127,9 +181,9 @@ export class CompilerManager {
                 type = CompilerType.GCC
             }
         } else if (compiler === 'msvc' || compiler === 'gcc' || compiler === 'clang' || compiler === 'mingw') {
-            // Handle compiler type names - detect and use actual compiler
-            const detected = await this.detectBestCompiler()
-            if (detected && detected.type === compiler) {
+            // Handle compiler type names - use the installed compiler of that type, with its environment for MSVC
+            const detected = (await PlatformDetector.detectCompilers()).find((info) => info.type === compiler)
+            if (detected) {
                 compiler = detected.path
                 type = this.mapCompilerType(detected.type)
                 env = detected.env
@@ -151,6 +205,10 @@ export class CompilerManager {
             }
         } else {
             type = this.detectCompilerType(compiler)
+            if (type === CompilerType.Unknown) {
+                // Generic names such as cc or a $CC wrapper: ask the compiler what it is
+                type = await this.probeCompilerType(compiler)
+            }
         }
 
         const flags = this.getDefaultFlags(type)
@@ -188,6 +246,29 @@ export class CompilerManager {
         return CompilerType.Unknown
     }
 
+    /*
+     Detects the compiler type from the compiler's version output
+     @param compiler Compiler command
+     @returns Compiler type, or Unknown if the compiler cannot be run or is not recognized
+     */
+    private static async probeCompilerType(compiler: string): Promise<CompilerType> {
+        try {
+            const proc = Bun.spawn([compiler, '--version'], {stdout: 'pipe', stderr: 'pipe', stdin: 'ignore'})
+            const output = (await new Response(proc.stdout).text()).toLowerCase()
+            await proc.exited
+            if (output.includes('clang')) {
+                return CompilerType.Clang
+            } else if (output.includes('mingw')) {
+                return CompilerType.MinGW
+            } else if (output.includes('gcc') || output.includes('free software foundation')) {
+                return CompilerType.GCC
+            }
+        } catch {
+            // Compiler not found: leave the type unknown and let compilation report the error
+        }
+        return CompilerType.Unknown
+    }
+
     /*
      Maps detector compiler type to internal compiler type
      @param type Detector compiler type string
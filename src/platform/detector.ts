@@ -423,7 +423,7 @@ export class PlatformDetector {
      @param executable Name of the executable to find
      @returns Promise resolving to full path if found, null otherwise
      */
-    private static async findInPath(executable: string): Promise<string | null> {
+    static async findInPath(executable: string): Promise<string | null> {
         // Check cache first
         if (findInPathCache.has(executable)) {
             return findInPathCache.get(executable)!
@@ -1,10 +1,38 @@
 import {PlatformDetector} from './detector.ts'
+import {dlopen, FFIType} from 'bun:ffi'
+import type {Pointer} from 'bun:ffi'
+
+// OpenProcess access rights needed to add a process to a job object
+const PROCESS_SET_QUOTA = 0x0100
+const PROCESS_TERMINATE = 0x0001
+
+/*
+ Loads the kernel32 job object functions (Windows only)
+ */
+function loadKernel32() {
+    return dlopen('kernel32.dll', {
+        CreateJobObjectW: {args: [FFIType.ptr, FFIType.ptr], returns: FFIType.ptr},
+        OpenProcess: {args: [FFIType.u32, FFIType.i32, FFIType.u32], returns: FFIType.ptr},
+        AssignProcessToJobObject: {args: [FFIType.ptr, FFIType.ptr], returns: FFIType.i32},
+        TerminateJobObject: {args: [FFIType.ptr, FFIType.u32], returns: FFIType.i32},
+        CloseHandle: {args: [FFIType.ptr], returns: FFIType.i32},
+    }).symbols
+}
 
 /*
  Cross-platform process management abstraction
  Provides unified interface for spawning and killing processes across platforms
  */
 export class ProcessManager {
+    // Process groups of running tests (Unix) - killed on interrupt since they don't receive terminal signals
+    private static processGroups: Set<number> = new Set()
+
+    // Job objects holding the process trees of running tests (Windows), keyed by the test's process ID
+    private static jobs: Map<number, Pointer> = new Map()
+
+    // kernel32 functions, loaded on first use (null if they could not be loaded)
+    private static kernel32: ReturnType<typeof loadKernel32> | null | undefined
+
     /*
      Kills a process and its children using platform-appropriate method
      @param pid Process ID to kill
@@ -127,6 +155,114 @@ export class ProcessManager {
         }
     }
 
+    /*
+     Kills a process and all of its descendants immediately
+     On Unix the process must lead its own process group (spawned detached) so the whole group is signalled.
+     On Windows the job object of a tracked process is terminated, which kills descendants even after their
+     parent has exited. Untracked processes are killed with taskkill /T.
+     @param pid Process ID (and process group ID on Unix)
+     @param signal Signal to send on Unix (default: SIGKILL)
+     */
+    static killProcessGroup(pid: number, signal: NodeJS.Signals = 'SIGKILL'): void {
+        if (PlatformDetector.isWindows()) {
+            const job = this.jobs.get(pid)
+            if (job && this.kernel32?.TerminateJobObject(job, 1)) {
+                return
+            }
+            Bun.spawn(['taskkill', '/PID', pid.toString(), '/T', '/F'], {stdout: 'ignore', stderr: 'ignore'})
+            return
+        }
+        try {
+            process.kill(-pid, signal)
+        } catch {
+            // Group already gone - fall back to the process itself
+            try {
+                process.kill(pid, signal)
+            } catch {
+                // Process may already be dead, ignore errors
+            }
+        }
+    }
+
+    /*
+     Registers a running process group so it can be signalled on interrupt
+     On Windows the process is added to a new job object so processes it starts join the job and
+     killProcessGroup can kill them all. Call this straight after spawning, before the test starts others.
+     @param pid Process group ID (the process ID on Windows)
+     */
+    static trackProcessGroup(pid: number): void {
+        this.processGroups.add(pid)
+        if (PlatformDetector.isWindows()) {
+            const job = this.createJob(pid)
+            if (job) {
+                this.jobs.set(pid, job)
+            }
+        }
+    }
+
+    /*
+     Unregisters a process group once its leader has exited
+     Closing the job handle leaves any remaining processes running, as on Unix.
+     @param pid Process group ID
+     */
+    static untrackProcessGroup(pid: number): void {
+        this.processGroups.delete(pid)
+        const job = this.jobs.get(pid)
+        if (job) {
+            this.jobs.delete(pid)
+            this.kernel32?.CloseHandle(job)
+        }
+    }
+
+    /*
+     Signals all running process groups
+     Detached test processes are not in the terminal's foreground group, so Ctrl+C is forwarded explicitly.
+     Windows has no signals to forward (Ctrl+C already reaches tests through the console), so there only
+     SIGKILL acts and it terminates the tests' jobs.
+     @param signal Signal to send (default: SIGINT)
+     */
+    static signalProcessGroups(signal: NodeJS.Signals = 'SIGINT'): void {
+        if (PlatformDetector.isWindows() && signal !== 'SIGKILL') {
+            return
+        }
+        for (const pid of this.processGroups) {
+            this.killProcessGroup(pid, signal)
+        }
+    }
+
+    /*
+     Creates a job object and adds a process to it (Windows)
+     @param pid Process ID
+     @returns Job handle, or null if the job could not be created and taskkill /T must be used instead
+     */
+    private static createJob(pid: number): Pointer | null {
+        if (this.kernel32 === undefined) {
+            try {
+                this.kernel32 = loadKernel32()
+            } catch {
+                this.kernel32 = null
+            }
+        }
+        const kernel32 = this.kernel32
+        if (!kernel32) {
+            return null
+        }
+        const job = kernel32.CreateJobObjectW(null, null)
+        if (!job) {
+            return null
+        }
+        const proc = kernel32.OpenProcess(PROCESS_SET_QUOTA | PROCESS_TERMINATE, 0, pid)
+        const assigned = proc ? kernel32.AssignProcessToJobObject(job, proc) : 0
+        if (proc) {
+            kernel32.CloseHandle(proc)
+        }
+        if (!assigned) {
+            kernel32.CloseHandle(job)
+            return null
+        }
+        return job
+    }
+
     /*
      Checks if a process is running
      Uses process.kill(pid, 0) which is cross-platform and zero-overhead
@@ -1,5 +1,5 @@
 import {PlatformDetector} from './detector.ts'
-import {extname} from 'path'
+import {basename, extname} from 'path'
 
 export enum ShellType {
     Bash = 'bash',
@@ -62,6 +62,42 @@ export class ShellDetector {
         }
     }
 
+    /*
+     Selects the interpreter of a Unix shell test (.tst.sh)
+     The --shell override wins over the script's shebang, which wins over shell.interpreter. Without any of these,
+     scripts run under /bin/sh, or Git Bash on Windows, where bash and sh always mean Git Bash.
+     @param filePath Path to the script
+     @param interpreter Configured interpreter (shell.interpreter)
+     @param override Interpreter chosen with --shell
+     @returns Interpreter name or path
+     */
+    static async selectInterpreter(filePath: string, interpreter?: string, override?: string): Promise<string> {
+        const shell = override || (await this.readShebang(filePath)) || interpreter
+        if (PlatformDetector.isWindows()) {
+            return !shell || ['bash', 'sh'].includes(basename(shell)) ? await this.findGitBash() : shell
+        }
+        return shell || '/bin/sh'
+    }
+
+    /*
+     Reads the interpreter named by a script's shebang line
+     For "#!/usr/bin/env bash" this is the program env runs. Arguments after the interpreter are ignored.
+     @param filePath Path to script file
+     @returns Interpreter name or path, or undefined if the script has no shebang
+     */
+    private static async readShebang(filePath: string): Promise<string | undefined> {
+        try {
+            const firstLine = (await Bun.file(filePath).text()).split('\n')[0]!
+            if (!firstLine.startsWith('#!')) {
+                return undefined
+            }
+            const [program, ...args] = firstLine.slice(2).trim().split(/\s+/)
+            return basename(program || '') === 'env' ? args.find((arg) => !arg.startsWith('-')) : program || undefined
+        } catch {
+            return undefined
+        }
+    }
+
     /*
      Detects shell from shebang line in script
      @param filePath Path to script file
@@ -140,6 +176,19 @@ export class ShellDetector {
         throw new Error('PowerShell not found on this system')
     }
 
+    /*
+     Finds the PowerShell that runs PowerShell tests (.tst.ps1)
+     @param configured PowerShell name or path (shell.powershell), otherwise pwsh, or Windows PowerShell on Windows
+     @returns PowerShell command, or null if it is not installed
+     */
+    static async selectPowerShell(configured?: string): Promise<string | null> {
+        if (!configured) {
+            return await this.findPowerShell().catch(() => null)
+        }
+        const found = /[\\/]/.test(configured) ? await this.fileExists(configured) : await this.findInPath(configured)
+        return found ? configured : null
+    }
+
     /*
      Gets the shell type from a script file extension
      @param filePath Path to script file
@@ -182,8 +231,8 @@ export class ShellDetector {
         switch (shellType) {
             case ShellType.PowerShell:
             case ShellType.PowerShellCore:
-                // PowerShell needs -ExecutionPolicy Bypass and -File
-                return ['-ExecutionPolicy', 'Bypass', '-File', scriptPath]
+                // PowerShell needs -ExecutionPolicy Bypass and -File. An uncaught terminating error exits with 1.
+                return ['-NoProfile', '-NonInteractive', '-ExecutionPolicy', 'Bypass', '-File', scriptPath]
             case ShellType.Cmd:
                 // Use 'call' to execute batch file and return to caller
                 return ['/c', 'call', scriptPath]
@@ -0,0 +1,89 @@
+import {createServer} from 'net'
+
+/*
+ Most ports a test can request with the ports directive
+ */
+export const MAX_PORTS = 32
+
+/*
+ Attempts to find an unreserved free port before giving up
+ */
+const MAX_ATTEMPTS = 100
+
+/*
+ TestPorts - Allocates free localhost TCP ports to tests
+
+ A test requests ports with the "testme: ports N" directive and receives them in TESTME_PORT0 to
+ TESTME_PORT{N-1}. Each port is found by binding port 0 on 127.0.0.1 and closing the socket, so the
+ operating system picks a port that is free at the time. Allocated ports stay reserved until the
+ test completes, so two parallel tests never receive the same port even if the first has not bound
+ its port yet.
+ */
+export class TestPorts {
+    private static reserved = new Set<number>()
+
+    /*
+     Allocates free ports and reserves them until released
+     @param count Number of ports
+     @returns Ports in allocation order
+     @throws Error if a free port cannot be found
+     */
+    static async allocate(count: number): Promise<number[]> {
+        if (count > MAX_PORTS) {
+            throw new Error(`Cannot allocate more than ${MAX_PORTS} ports (testme: ports ${count})`)
+        }
+        const ports: number[] = []
+        try {
+            for (let attempt = 0; ports.length < count; attempt++) {
+                if (attempt >= MAX_ATTEMPTS) {
+                    throw new Error(`Cannot allocate ${count} free port(s)`)
+                }
+                const port = await this.findFreePort()
+                if (!this.reserved.has(port)) {
+                    this.reserved.add(port)
+                    ports.push(port)
+                }
+            }
+        } catch (error) {
+            this.release(ports)
+            throw error
+        }
+        return ports
+    }
+
+    /*
+     Releases ports so they can be allocated again
+     @param ports Ports returned by allocate
+     */
+    static release(ports: number[]): void {
+        for (const port of ports) {
+            this.reserved.delete(port)
+        }
+    }
+
+    /*
+     Gets the environment variables for allocated ports
+     @param ports Allocated ports
+     @returns TESTME_PORT0, TESTME_PORT1, ... in allocation order
+     */
+    static getEnvironment(ports: number[]): Record<string, string> {
+        return Object.fromEntries(ports.map((port, index) => [`TESTME_PORT${index}`, String(port)]))
+    }
+
+    /*
+     Asks the operating system for a free localhost port
+     @returns Port that was free when the probe socket closed
+     */
+    private static findFreePort(): Promise<number> {
+        return new Promise((resolve, reject) => {
+            const server = createServer()
+            server.unref()
+            server.on('error', reject)
+            server.listen(0, '127.0.0.1', () => {
+                const address = server.address()
+                const port = typeof address === 'object' && address ? address.port : 0
+                server.close(() => (port ? resolve(port) : reject(new Error('No port assigned'))))
+            })
+        })
+    }
+}
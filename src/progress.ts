@@ -0,0 +1,129 @@
+import type {TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+
+/*
+ Seconds between plain progress lines when stdout is not a terminal
+ */
+const PLAIN_INTERVAL = 10
+
+/*
+ Statuses counted as failures
+ */
+const FAILING = [TestStatus.Failed, TestStatus.Error, TestStatus.Timeout, TestStatus.IdleTimeout, TestStatus.XPass]
+
+/*
+ Counts shown by the progress line
+ */
+export type ProgressStatus = {
+    completed: number
+    total: number
+    passed: number
+    failed: number
+    elapsed: number // Milliseconds since the run started
+}
+
+/*
+ RunProgress - Live counts for --progress
+
+ Tracks how many of the run's tests have completed, passed and failed across all configuration
+ groups, matrix cells and fixture batches. The total starts as the number of planned tests. It grows
+ when parameterized tests expand into cases and shrinks when a group finishes without running all of
+ its tests (disabled, not selected at this depth, or stopped), so completed reaches total at the end.
+ The reporter renders the counts as an updating line on a terminal, or as a plain line every
+ PLAIN_INTERVAL seconds otherwise.
+ */
+export class RunProgress {
+    private static enabled = false
+    private static started = 0
+    private static total = 0
+    private static completed = 0
+    private static passed = 0
+    private static failed = 0
+    private static groupExpected = 0
+    private static groupCompleted = 0
+    private static printed = 0
+
+    /*
+     Starts tracking a run
+     @param total Number of planned tests
+     */
+    static begin(total: number): void {
+        this.enabled = true
+        this.started = this.printed = Date.now()
+        this.total = total
+        this.completed = this.passed = this.failed = 0
+        this.groupExpected = this.groupCompleted = 0
+    }
+
+    /*
+     Checks if --progress is tracking the run
+     @returns True after begin
+     */
+    static isEnabled(): boolean {
+        return this.enabled
+    }
+
+    /*
+     Starts a configuration group, removing the tests of the previous group that did not run from the total
+     @param count Number of tests in the group
+     */
+    static beginGroup(count: number): void {
+        this.total -= Math.max(0, this.groupExpected - this.groupCompleted)
+        this.groupExpected = count
+        this.groupCompleted = 0
+    }
+
+    /*
+     Adds tests to the current group, such as the extra cases of parameterized tests
+     @param count Number of added tests
+     */
+    static expand(count: number): void {
+        this.total += count
+        this.groupExpected += count
+    }
+
+    /*
+     Counts a completed test
+     @param result Test result
+     */
+    static record(result: TestResult): void {
+        if (!this.enabled) {
+            return
+        }
+        this.completed++
+        this.groupCompleted++
+        if (result.status === TestStatus.Passed) {
+            this.passed++
+        } else if (FAILING.includes(result.status)) {
+            this.failed++
+        }
+    }
+
+    /*
+     Gets the current counts
+     @returns Completed, total, passed and failed counts with the elapsed time
+     */
+    static getStatus(): ProgressStatus {
+        const total = Math.max(this.total, this.completed)
+        return {
+            completed: this.completed,
+            total,
+            passed: this.passed,
+            failed: this.failed,
+            elapsed: Date.now() - this.started,
+        }
+    }
+
+    /*
+     Checks if a plain progress line is due, and if so starts the next interval
+     @returns True every PLAIN_INTERVAL seconds and when the last test completes
+     */
+    static isPlainDue(): boolean {
+        const now = Date.now()
+        if (now - this.printed < PLAIN_INTERVAL * 1000 && this.completed < this.total) {
+            return false
+        }
+        this.printed = now
+        return true
+    }
+}
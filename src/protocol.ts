@@ -0,0 +1,127 @@
+import type {SubResult, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+
+/*
+ Prefix of a protocol line: TESTME {"event":"assert","name":"x","status":"pass"}
+ */
+const PROTOCOL_LINE = /^TESTME (\{.*\})\s*$/
+
+/*
+ Statuses of a sub-result
+ */
+const STATUSES = ['pass', 'fail', 'skip']
+
+/*
+ Most failed sub-results named in the error of a test
+ */
+const MAX_NAMED_FAILURES = 5
+
+/*
+ HarnessProtocol - Named sub-results reported by a test on stdout
+
+ A test reports a sub-result by printing a line starting with "TESTME " followed by a JSON object on one line:
+
+     TESTME {"event":"assert","name":"parse header","status":"fail","message":"expected 3, got 4"}
+
+ The "assert" event requires a name and a status of pass, fail or skip, and may give a message, the source
+ file and line, and a duration in milliseconds. Protocol lines are removed from the test's output and the
+ sub-results are added to those of the result, counted as its assertions and listed by the reporters. A failed
+ sub-result fails a test that exited zero. Lines that are not valid protocol lines, including malformed JSON,
+ pass through untouched, and valid lines with other events are removed and ignored so the protocol can grow.
+ The go and sh helpers printed by --emit-header write these lines.
+ */
+export class HarnessProtocol {
+    /*
+     Parses the protocol lines of test output
+     @param text Test output
+     @returns Sub-results in output order and the output without protocol lines
+     */
+    static parse(text: string): {results: SubResult[]; output: string} {
+        const results: SubResult[] = []
+        const kept: string[] = []
+        for (const line of text.split('\n')) {
+            const event = this.parseLine(line)
+            if (event === undefined) {
+                kept.push(line)
+            } else if (event) {
+                results.push(event)
+            }
+        }
+        return {results, output: kept.join('\n')}
+    }
+
+    /*
+     Attaches the sub-results reported by a test to its result
+     @param result Test result with captured output
+     @returns The result with its sub-results and counts and without protocol lines, failed if a sub-result failed
+     */
+    static apply(result: TestResult): TestResult {
+        const stdout = result.stdout ?? result.output
+        if (!stdout?.includes('TESTME {')) {
+            return result
+        }
+        const {results, output} = this.parse(stdout)
+        if (results.length === 0 && output === stdout) {
+            return result
+        }
+        const applied: TestResult = {
+            ...result,
+            output: this.parse(result.output || '').output,
+            ...(result.stdout !== undefined && {stdout: output}),
+        }
+        if (results.length === 0) {
+            return applied
+        }
+        const failed = results.filter((sub) => sub.status === 'fail')
+        const passed = results.filter((sub) => sub.status === 'pass').length
+        applied.subResults = [...(result.subResults || []), ...results]
+        applied.assertions = {
+            passed: (result.assertions?.passed || 0) + passed,
+            failed: (result.assertions?.failed || 0) + failed.length,
+        }
+        if (failed.length > 0 && result.status === TestStatus.Passed) {
+            const names = failed.slice(0, MAX_NAMED_FAILURES).map((sub) => sub.name)
+            const more = failed.length > names.length ? `, and ${failed.length - names.length} more` : ''
+            const summary = `${failed.length} of ${results.length} results failed: ${names.join(', ')}${more}`
+            applied.status = TestStatus.Failed
+            applied.error = [result.error, summary].filter((text) => text).join('\n')
+        }
+        return applied
+    }
+
+    /*
+     Parses one line of output
+     @param line Output line
+     @returns The sub-result, null for a protocol line with another event, or undefined if it is not a protocol line
+     */
+    private static parseLine(line: string): SubResult | null | undefined {
+        const match = line.replace(/\r$/, '').match(PROTOCOL_LINE)
+        if (!match) {
+            return undefined
+        }
+        let event: Record<string, unknown>
+        try {
+            event = JSON.parse(match[1]!)
+        } catch {
+            return undefined
+        }
+        if (typeof event.event !== 'string') {
+            return undefined
+        }
+        if (event.event !== 'assert') {
+            return null
+        }
+        const {name, status, message, file, line: lineNumber, duration} = event
+        if (typeof name !== 'string' || !name || typeof status !== 'string' || !STATUSES.includes(status)) {
+            return undefined
+        }
+        return {
+            name,
+            status: status as SubResult['status'],
+            ...(typeof message === 'string' && message !== '' && {message}),
+            ...(typeof file === 'string' && file !== '' && {file}),
+            ...(typeof lineNumber === 'number' && {line: lineNumber}),
+            ...(typeof duration === 'number' && {duration}),
+        }
+    }
+}
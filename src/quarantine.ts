@@ -0,0 +1,91 @@
+import type {TestConfig, TestFile, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {existsSync} from 'fs'
+import {readFile} from 'fs/promises'
+import {dirname, resolve, sep} from 'path'
+
+// Quarantine file looked for in the root configuration directory when the quarantine key is not set
+const DEFAULT_FILE = 'testme.quarantine'
+
+/*
+ Quarantine - Known-flaky tests whose failures do not fail the run
+
+ The quarantine file lists test paths, one per line, relative to the file. A directory quarantines every test
+ beneath it, and blank lines and lines starting with '#' are ignored. The file is testme.quarantine next to the
+ root configuration, or the file named by the quarantine key. Quarantined tests still run and report, but
+ they are left out of the exit code, --fail-fast and --max-failures, and their failures are listed separately.
+ Quarantined tests that pass are listed as candidates for removal from the file. --no-quarantine ignores the
+ file so a full audit treats every test the same.
+ */
+export class Quarantine {
+    /*
+     Gets the quarantine file path
+     @param config Root configuration
+     @param rootDir Test root directory, used when the configuration has no directory
+     @returns Path of the quarantine file
+     */
+    static getPath(config: TestConfig, rootDir: string): string {
+        return resolve(config.configDir || rootDir, config.quarantine || DEFAULT_FILE)
+    }
+
+    /*
+     Loads the quarantined paths
+     @param path Quarantine file path
+     @returns Absolute paths of quarantined tests and directories, or null if the file does not exist
+     */
+    static async load(path: string): Promise<string[] | null> {
+        if (!existsSync(path)) {
+            return null
+        }
+        const base = dirname(path)
+        return (await readFile(path, 'utf-8'))
+            .split('\n')
+            .map((line) => line.trim())
+            .filter((line) => line && !line.startsWith('#'))
+            .map((line) => resolve(base, line))
+    }
+
+    /*
+     Marks the tests listed in the quarantine file
+     @param tests Selected tests
+     @param config Root configuration
+     @param rootDir Test root directory
+     @returns Number of quarantined tests
+     */
+    static async mark(tests: TestFile[], config: TestConfig, rootDir: string): Promise<number> {
+        const path = this.getPath(config, rootDir)
+        const entries = await this.load(path)
+        if (!entries) {
+            if (config.quarantine) {
+                console.warn(`⚠️  Quarantine file ${path} not found`)
+            }
+            return 0
+        }
+        let count = 0
+        for (const test of tests) {
+            if (entries.some((entry) => test.path === entry || test.path.startsWith(entry + sep))) {
+                test.quarantined = true
+                count++
+            }
+        }
+        return count
+    }
+
+    /*
+     Gets the quarantined tests that passed every time they ran
+     Cases and matrix cells share the test's path, so a test is only listed if all of its results passed.
+     @param results Test results
+     @returns Sorted paths of the passing quarantined tests
+     */
+    static getPassing(results: TestResult[]): string[] {
+        const passed = new Map<string, boolean>()
+        for (const result of results.filter((result) => result.file.quarantined)) {
+            const path = result.file.path
+            passed.set(path, (passed.get(path) ?? true) && result.status === TestStatus.Passed)
+        }
+        return [...passed.entries()]
+            .filter(([, pass]) => pass)
+            .map(([path]) => path)
+            .sort()
+    }
+}
@@ -0,0 +1,91 @@
+import type {TestConfig, TestFile} from './types.ts'
+import {DryRun} from './utils/dry-run.ts'
+import {basename} from 'path'
+import {randomUUID} from 'crypto'
+
+/*
+ Default remote staging directory (remote.dir)
+ */
+export const REMOTE_DIR = '/tmp/testme'
+
+/*
+ Seconds the remote timeout waits after SIGTERM before sending SIGKILL
+ */
+const KILL_AFTER = 5
+
+/*
+ Remote - Runs compiled tests on another host over ssh
+
+ The host comes from --remote (execution.remote) or remote.host. Each test binary is copied with scp
+ into its own staging directory under remote.dir, run there over ssh with remote.env exported, and
+ the directory is removed afterwards. The test timeout is enforced remotely by timeout(1), which
+ signals the whole process group of the test, so nothing is left running on the host when the local
+ ssh is killed. remote.options are passed to both ssh and scp (e.g., ['-o', 'Port=2222']).
+ */
+export class Remote {
+    /*
+     Gets the remote host for a configuration
+     @param config Test configuration
+     @returns Host (e.g., 'user@board'), or undefined to run tests locally
+     */
+    static getHost(config: TestConfig): string | undefined {
+        return config.execution?.remote || config.remote?.host || undefined
+    }
+
+    /*
+     Gets the options passed to ssh and scp
+     Batch mode stops ssh prompting for a password in the middle of a run
+     @param config Test configuration
+     @returns ssh and scp options
+     */
+    static getOptions(config: TestConfig): string[] {
+        return ['-o', 'BatchMode=yes', ...(config.remote?.options || [])]
+    }
+
+    /*
+     Gets a unique staging directory on the remote host for a test
+     @param config Test configuration with remote.dir
+     @param file Test file
+     @returns Remote directory path
+     */
+    static getStageDir(config: TestConfig, file: TestFile): string {
+        const dir = (config.remote?.dir || REMOTE_DIR).replace(/\/+$/, '') || '/'
+        return `${dir}/${basename(file.name)}-${randomUUID().slice(0, 8)}`
+    }
+
+    /*
+     Builds the remote shell command that runs a staged test
+     @param stage Remote staging directory
+     @param program File name of the test binary in the staging directory
+     @param args Test arguments
+     @param env Variables exported before the test runs (remote.env)
+     @param timeout Timeout in milliseconds, undefined for none
+     @returns Command line for the remote shell
+     */
+    static getCommand(
+        stage: string,
+        program: string,
+        args: string[],
+        env: Record<string, string | number | boolean>,
+        timeout?: number
+    ): string {
+        const parts = [`cd ${DryRun.quote(stage)} &&`]
+        for (const [key, value] of Object.entries(env)) {
+            parts.push(`${key}=${DryRun.quote(String(value))}`)
+        }
+        if (timeout) {
+            parts.push('timeout', '-k', String(KILL_AFTER), String(Math.ceil(timeout / 1000)))
+        }
+        parts.push(`./${DryRun.quote(program)}`, ...args.map((arg) => DryRun.quote(arg)))
+        return parts.join(' ')
+    }
+
+    /*
+     Checks if a remote exit code means the remote timeout killed the test
+     @param exitCode Exit code returned by ssh
+     @returns True for the exit codes of timeout(1) (124, or 137 after SIGKILL)
+     */
+    static isTimeout(exitCode: number): boolean {
+        return exitCode === 124 || exitCode === 137
+    }
+}
@@ -1,13 +1,22 @@
-import type {TestResult, TestFile, TestConfig} from './types.ts'
+import type {SubResult, TestResult, TestFile, TestConfig} from './types.ts'
 import {TestStatus} from './types.ts'
-import {relative} from 'path'
+import {dirname, relative} from 'path'
 import {isInteractiveTTY, writeOverwritable, clearCurrentLine} from './utils/tty.ts'
+import {DryRun} from './utils/dry-run.ts'
+import {getSlowestTests} from './utils/slowest.ts'
+import {isCrash} from './utils/crash.ts'
+import {TestCases} from './cases.ts'
+import {Matrix} from './matrix.ts'
+import {RunProgress} from './progress.ts'
+import {Quarantine} from './quarantine.ts'
+import {Benchmarks} from './bench.ts'
 
 export class TestReporter {
     private config: TestConfig
     private invocationDir: string
     private runningTests: Set<TestFile>
     private hasRunningLine: boolean
+    private phases: {build: number; run: number} | null = null
 
     constructor(config: TestConfig, invocationDir?: string) {
         this.config = config
@@ -16,6 +25,14 @@ export class TestReporter {
         this.hasRunningLine = false
     }
 
+    /*
+     Sets the build and run phase times shown in the summary of a run with --build-workers
+     @param phases Wall clock milliseconds of each phase, or null if tests were not built ahead of running
+     */
+    setPhases(phases: {build: number; run: number} | null): void {
+        this.phases = phases
+    }
+
     reportResults(results: TestResult[], elapsedTime?: number): void {
         if (this.config.output?.format === 'json') {
             this.reportJson(results, elapsedTime)
@@ -32,14 +49,26 @@ export class TestReporter {
             return
         }
 
-        console.log(`\nDiscovered ${results.length} test(s):`)
-
+        // One path per line so the list can be consumed by scripts
         for (const result of results) {
-            const relativePath = this.getRelativePath(result.file.path)
-            console.log(`  ${relativePath}`)
+            console.log(this.getRelativePath(result.file.path))
         }
     }
 
+    /*
+     Reports discovered tests as a JSON array
+     @param results Pending results for the discovered tests
+     @param timeouts Resolved timeout in seconds for each test (0 for no timeout)
+     */
+    reportDiscoveredTestsJson(results: TestResult[], timeouts: Map<TestFile, number>): void {
+        const tests = results.map((result) => ({
+            path: this.getRelativePath(result.file.path),
+            language: result.file.type,
+            timeout: timeouts.get(result.file) ?? 0,
+        }))
+        console.log(JSON.stringify(tests, null, 2))
+    }
+
     reportTestStarting(testFile: TestFile): void {
         // Track this test as running
         this.runningTests.add(testFile)
@@ -47,19 +76,18 @@ export class TestReporter {
         // Only show running status in interactive terminals (not in quiet mode or show mode)
         // Disable TTY cursor control when showCommands is enabled to prevent clearing environment output
         // Disable TTY cursor control when live streaming is enabled to prevent clearing streamed output
+        // Disable TTY cursor control for --dry-run to prevent clearing the printed commands
         const shouldUseTTY =
             !this.config.output?.quiet &&
             !this.config.execution?.showCommands &&
             !this.config.output?.live &&
+            !DryRun.isEnabled() &&
             isInteractiveTTY()
         if (shouldUseTTY) {
             // If we already have a running line displayed, don't show another one
             // (in parallel mode, we only show one "RUN" line at a time)
             if (!this.hasRunningLine) {
-                const relativePath = this.getRelativePath(testFile.path)
-                const runningStatus = this.config.output?.colors ? this.blue('⟳ RUN ') : 'RUNNING'
-
-                writeOverwritable(`${runningStatus} ${relativePath}`)
+                writeOverwritable(this.getRunningLine(testFile))
                 this.hasRunningLine = true
             }
         }
@@ -69,36 +97,98 @@ export class TestReporter {
         // Remove this test from running set
         this.runningTests.delete(result.file)
 
-        const status = this.formatStatus(result.status)
-        const duration = this.formatDuration(result.duration)
-        const relativePath = this.getRelativePath(result.file.path)
+        const status = this.formatResultStatus(result)
+        const quarantined = result.file.quarantined ? ', quarantined' : ''
+        const duration = this.formatDuration(result.duration) + this.formatAttempts(result) + quarantined
+        const relativePath = this.getTestName(result.file)
+        const progress = this.config.output?.progress === true && RunProgress.isEnabled()
+        const failing = this.getFailingTests([result]).length > 0
 
         // If we're in an interactive terminal and not in show mode
         // Disable TTY cursor control when showCommands is enabled to prevent clearing environment output
         // Disable TTY cursor control when live streaming is enabled to prevent clearing streamed output
-        if (isInteractiveTTY() && !this.config.execution?.showCommands && !this.config.output?.live) {
+        const useTTY = !this.config.execution?.showCommands && !this.config.output?.live && !DryRun.isEnabled()
+        if (isInteractiveTTY() && useTTY) {
             // Clear the "running" line if one exists
             if (this.hasRunningLine) {
                 clearCurrentLine()
                 this.hasRunningLine = false
             }
 
-            // Print the completed test result
-            console.log(`${status} ${relativePath} (${duration})`)
+            // Print the completed test result (only failures with --progress)
+            if (!progress || failing) {
+                console.log(`${status} ${this.bold(relativePath)} (${duration})`)
+            }
+            this.reportFailure(result)
 
-            // If there are still tests running, show the next one
+            // If there are still tests running, show the next one (or the progress line)
             if (this.runningTests.size > 0) {
-                const nextRunning = Array.from(this.runningTests)[0]
-                const nextPath = this.getRelativePath(nextRunning.path)
-                const runningStatus = this.config.output?.colors ? this.blue('⟳ RUN ') : 'RUNNING'
-
-                writeOverwritable(`${runningStatus} ${nextPath}`)
+                writeOverwritable(this.getRunningLine(Array.from(this.runningTests)[0]!))
                 this.hasRunningLine = true
             }
         } else {
-            // Non-interactive mode or show mode: no animation
-            console.log(`${status} ${relativePath} (${duration})`)
+            // Non-interactive mode or show mode: no animation, and a plain progress line at intervals
+            if (!progress || failing) {
+                console.log(`${status} ${this.bold(relativePath)} (${duration})`)
+            }
+            this.reportFailure(result)
+            if (progress && RunProgress.isPlainDue()) {
+                console.log(this.formatProgress())
+            }
+        }
+    }
+
+    /*
+     Gets the line shown while tests run: the running test, or the run's counts with --progress
+     @param testFile A running test
+     @returns Line to write in place
+     */
+    private getRunningLine(testFile: TestFile): string {
+        if (this.config.output?.progress && RunProgress.isEnabled()) {
+            return this.formatProgress()
+        }
+        const runningStatus = this.config.output?.colors ? this.blue('⟳ RUN ') : 'RUNNING'
+        return `${runningStatus} ${this.getTestName(testFile)}`
+    }
+
+    /*
+     Formats the run's progress counts
+     @returns Completed and total tests, passed and failed counts and the elapsed time
+     */
+    private formatProgress(): string {
+        const {completed, total, passed, failed, elapsed} = RunProgress.getStatus()
+        const seconds = Math.floor(elapsed / 1000)
+        const minutes = Math.floor(seconds / 60)
+        const time = minutes > 0 ? `${minutes}m ${String(seconds % 60).padStart(2, '0')}s` : `${seconds}s`
+        const failures = failed > 0 ? this.red(`${failed} failed`) : `${failed} failed`
+        return `[${completed}/${total}] ${this.green(`${passed} passed`)}, ${failures} (${time})`
+    }
+
+    /*
+     Prints the complete details of a test that did not pass as soon as it completes (--verbose-on-fail)
+     Tests that pass, are skipped or fail as expected print nothing. The details print as one block under the
+     test's status line, so output from parallel tests never interleaves.
+     @param result Completed test result
+     */
+    private reportFailure(result: TestResult): void {
+        if (this.config.output?.verboseOnFail && this.getFailingTests([result]).length > 0) {
+            this.reportDetailedTest(result)
+            console.log()
+        }
+    }
+
+    /*
+     Prints a test's captured output as one block
+     Used in live mode with parallel workers, where streaming would interleave output from concurrent tests
+     @param result Completed test result
+     */
+    reportOutput(result: TestResult): void {
+        const output = result.output?.trimEnd()
+        if (!output) {
+            return
         }
+        const header = `── ${this.getTestName(result.file)} ──`
+        console.log(`${this.config.output?.colors ? this.blue(header) : header}\n${output}\n`)
     }
 
     reportTestsStarting(): void {
@@ -108,6 +198,9 @@ export class TestReporter {
     reportSummary(results: TestResult[], elapsedTime?: number): void {
         const stats = this.calculateStats(results)
 
+        this.reportSlowest(results)
+        this.reportBenchmarks(results)
+
         console.log('\n' + '='.repeat(60))
         console.log('TEST SUMMARY')
         console.log('='.repeat(60))
@@ -115,29 +208,59 @@ export class TestReporter {
         if (this.config.output?.colors) {
             console.log(`${this.green('✓ Passed:')}  ${stats.passed}`)
             console.log(`${this.red('✗ Failed:')}  ${stats.failed}`)
+            console.log(`${this.red('💥 Crashes:')} ${stats.crashes}`)
+            console.log(`${this.red('⏱ Timeouts:')} ${stats.timeouts}`)
             console.log(`${this.yellow('! Errors:')}  ${stats.errors}`)
             console.log(`${this.blue('- Skipped:')} ${stats.skipped}`)
         } else {
-            console.log(`Passed:  ${stats.passed}`)
-            console.log(`Failed:  ${stats.failed}`)
-            console.log(`Errors:  ${stats.errors}`)
-            console.log(`Skipped: ${stats.skipped}`)
+            console.log(`Passed:   ${stats.passed}`)
+            console.log(`Failed:   ${stats.failed}`)
+            console.log(`Crashes:  ${stats.crashes}`)
+            console.log(`Timeouts: ${stats.timeouts}`)
+            console.log(`Errors:   ${stats.errors}`)
+            console.log(`Skipped:  ${stats.skipped}`)
         }
 
         console.log(`Total:    ${stats.total}`)
+        this.reportMatrix(results)
+
+        // Expected failures do not fail the run, unexpected passes do so the xfail directive gets removed
+        if (stats.xfail > 0) {
+            console.log(`${this.yellow('XFail:')}    ${stats.xfail} (failed as expected)`)
+        }
+        if (stats.xpass > 0) {
+            console.log(`${this.red('XPass:')}    ${stats.xpass} (passed unexpectedly, remove the xfail directive)`)
+            for (const result of results.filter((result) => result.status === TestStatus.XPass)) {
+                console.log(`  ${this.getTestName(result.file)}`)
+            }
+        }
+
+        // Flag tests that only passed on retry so flakiness can be tracked
+        if (stats.flaky > 0) {
+            console.log(`${this.yellow('Flaky:')}    ${stats.flaky} (passed on retry)`)
+            for (const result of results.filter((result) => result.flaky)) {
+                console.log(`  ${this.getTestName(result.file)} (${result.attempts} attempts)`)
+            }
+        }
+        this.reportQuarantine(results)
 
         // Show assertion counts if any tests had assertions
         if (stats.filesWithAssertions > 0) {
             const totalAssertions = stats.assertionsPassed + stats.assertionsFailed
-            console.log(`Assertions: ${stats.assertionsPassed}/${totalAssertions} passed`)
+            const failed = stats.assertionsFailed > 0 ? ` (${this.red(`${stats.assertionsFailed} failed`)})` : ''
+            console.log(`Assertions: ${stats.assertionsPassed}/${totalAssertions} passed${failed}`)
         }
 
         console.log(`Duration: ${this.formatDuration(stats.totalDuration)}`)
         if (elapsedTime !== undefined) {
             console.log(`Elapsed:  ${this.formatDuration(elapsedTime)}`)
         }
+        if (this.phases) {
+            console.log(`Build:    ${this.formatDuration(this.phases.build)}`)
+            console.log(`Run:      ${this.formatDuration(this.phases.run)}`)
+        }
 
-        if (stats.failed > 0 || stats.errors > 0) {
+        if (this.getFailingTests(results).some((result) => !result.file.quarantined)) {
             console.log(`\nResult: ${this.red('FAILED')}`)
         } else {
             console.log(`\nResult: ${this.green('PASSED')}`)
@@ -149,6 +272,70 @@ export class TestReporter {
         }
     }
 
+    /*
+   Reports each benchmark's timing and its change against the baseline (--bench)
+   @param results All test results
+   */
+    private reportBenchmarks(results: TestResult[]): void {
+        const benchmarks = results.flatMap((result) =>
+            (result.benchmarks || []).map((bench) => ({test: this.getTestName(result.file), bench}))
+        )
+        if (benchmarks.length === 0) {
+            return
+        }
+        console.log(`\nBENCHMARKS (${benchmarks.length})`)
+        console.log('='.repeat(60))
+        const width = Math.max(...benchmarks.map(({test, bench}) => `${test} ${bench.name}`.length))
+        for (const {test, bench} of benchmarks) {
+            const line = `${`${test} ${bench.name}`.padEnd(width)}  ${Benchmarks.format(bench)}`
+            console.log(bench.regressed ? `${line} ${this.red('REGRESSED')}` : line)
+        }
+    }
+
+    /*
+   Reports quarantined failures, which do not fail the run, and quarantined tests that now pass
+   @param results All test results
+   */
+    private reportQuarantine(results: TestResult[]): void {
+        const failures = this.getFailingTests(results).filter((result) => result.file.quarantined)
+        if (failures.length > 0) {
+            console.log(`${this.yellow('Quarantined failures:')} ${failures.length} (do not fail the run)`)
+            for (const result of failures) {
+                console.log(`  ${this.getTestName(result.file)} (${result.status})`)
+            }
+        }
+        const passing = Quarantine.getPassing(results)
+        if (passing.length > 0) {
+            const label = this.green('Quarantined passes:')
+            console.log(`${label} ${passing.length} (candidates for removal from the quarantine file)`)
+            for (const path of passing) {
+                console.log(`  ${this.getRelativePath(path)}`)
+            }
+        }
+    }
+
+    /*
+   Reports the slowest tests, longest first, when --slowest is given
+   @param results All test results
+   */
+    private reportSlowest(results: TestResult[]): void {
+        const count = this.config.output?.slowest
+        if (!count) {
+            return
+        }
+        const slowest = getSlowestTests(results, count)
+        if (slowest.length === 0) {
+            return
+        }
+        console.log(`\nSLOWEST TESTS (${slowest.length})`)
+        console.log('='.repeat(60))
+        const durations = slowest.map((result) => this.formatDuration(result.duration))
+        const width = Math.max(...durations.map((duration) => duration.length))
+        slowest.forEach((result, index) => {
+            console.log(`${durations[index]!.padStart(width)}  ${this.getTestName(result.file)}`)
+        })
+    }
+
     private reportJson(results: TestResult[], elapsedTime?: number): void {
         const resultsToShow = this.config.output?.errorsOnly ? this.getFailingTests(results) : results
 
@@ -156,15 +343,28 @@ export class TestReporter {
             summary: {
                 ...this.calculateStats(results),
                 ...(elapsedTime !== undefined && {elapsedTime}),
+                ...(this.phases && {buildTime: this.phases.build, runTime: this.phases.run}),
             },
             tests: resultsToShow.map((result) => ({
                 file: result.file.path,
+                ...(result.file.testCase && {case: result.file.testCase.name}),
+                ...(result.file.matrix && {matrix: result.file.matrix}),
                 type: result.file.type,
                 status: result.status,
                 duration: result.duration,
                 exitCode: result.exitCode,
                 error: result.error,
+                attempts: result.attempts,
+                flaky: result.flaky,
+                assertions: result.assertions,
+                results: result.subResults,
             })),
+            ...(this.config.output?.slowest && {
+                slowest: getSlowestTests(results, this.config.output.slowest).map((result) => ({
+                    file: result.file.path,
+                    duration: result.duration,
+                })),
+            }),
         }
 
         console.log(JSON.stringify(output, null, 2))
@@ -176,33 +376,73 @@ export class TestReporter {
     }
 
     private reportDetailed(results: TestResult[], elapsedTime?: number): void {
-        console.log('\nTEST RESULTS')
-        console.log('='.repeat(60))
-
-        const resultsToShow = this.config.output?.errorsOnly ? this.getFailingTests(results) : results
+        const failures = this.getFailingTests(results).filter((result) => !result.file.quarantined)
+        const quarantined = this.getFailingTests(results).filter((result) => result.file.quarantined)
 
-        if (this.config.output?.errorsOnly && resultsToShow.length === 0) {
-            console.log('\n✓ No failing tests found!')
+        if (this.config.output?.errorsOnly) {
+            this.reportFailures(failures)
+            if (quarantined.length > 0) {
+                this.reportFailures(quarantined, 'QUARANTINED FAILURES')
+            }
         } else {
-            for (const result of resultsToShow) {
+            console.log('\nTEST RESULTS')
+            console.log('='.repeat(60))
+            for (const result of results) {
                 this.reportDetailedTest(result)
             }
+            // Repeat the failures after the full listing so they do not scroll out of sight
+            if (this.config.output?.summaryFailures && failures.length > 0) {
+                this.reportFailures(failures)
+            }
+            if (this.config.output?.summaryFailures && quarantined.length > 0) {
+                this.reportFailures(quarantined, 'QUARANTINED FAILURES')
+            }
         }
 
         this.reportSummary(results, elapsedTime)
     }
 
+    /*
+   Reports failed tests with their output, grouped by directory with a count per directory
+   Directories and the tests within them are sorted, so the section does not depend on the order in which
+   parallel workers finished
+   @param failures Failing test results
+   @param title Section title
+   */
+    private reportFailures(failures: TestResult[], title = 'FAILURES'): void {
+        if (failures.length === 0) {
+            console.log('\n✓ No failing tests found!')
+            return
+        }
+        const groups = new Map<string, TestResult[]>()
+        for (const result of failures) {
+            const dir = dirname(this.getRelativePath(result.file.path))
+            groups.set(dir, [...(groups.get(dir) || []), result])
+        }
+        const where = groups.size === 1 ? '1 directory' : `${groups.size} directories`
+        console.log(`\n${title} (${failures.length} in ${where})`)
+        console.log('='.repeat(60))
+        for (const dir of [...groups.keys()].sort()) {
+            const group = groups.get(dir)!
+            group.sort((a, b) => this.getTestName(a.file).localeCompare(this.getTestName(b.file)))
+            console.log(`\n${this.red(`${dir === '.' ? './' : `${dir}/`} (${group.length} failed)`)}`)
+            for (const result of group) {
+                this.reportDetailedTest(result)
+            }
+        }
+    }
+
     private reportSimple(results: TestResult[], elapsedTime?: number): void {
         // Only show summary - tests are already printed via reportProgress as they run
         this.reportSummary(results, elapsedTime)
     }
 
     private reportDetailedTest(result: TestResult): void {
-        const status = this.formatStatus(result.status)
+        const status = this.formatResultStatus(result)
         const duration = this.formatDuration(result.duration)
-        const relativePath = this.getRelativePath(result.file.path)
+        const relativePath = this.getTestName(result.file)
 
-        console.log(`\n${relativePath}`)
+        console.log(`\n${this.bold(relativePath)}`)
         console.log(`   Path:     ${relativePath}`)
         console.log(`   Status:   ${status}`)
         console.log(`   Duration: ${duration}`)
@@ -211,6 +451,17 @@ export class TestReporter {
             console.log(`   Exit Code: ${result.exitCode}`)
         }
 
+        if (result.attempts !== undefined && result.attempts > 1) {
+            console.log(`   Attempts: ${result.attempts}${result.flaky ? ' (flaky)' : ''}`)
+        }
+
+        if (result.subResults) {
+            console.log('   Results:')
+            for (const sub of result.subResults) {
+                console.log(`     ${this.formatSubResult(sub)}`)
+            }
+        }
+
         if (result.output) {
             console.log('   Output:')
             this.printIndented(result.output, '     ')
@@ -238,6 +489,39 @@ export class TestReporter {
         }
     }
 
+    /*
+   Formats a sub-result reported with the TESTME protocol
+   @param sub Sub-result
+   @returns Status mark, name, message and source location
+   */
+    private formatSubResult(sub: SubResult): string {
+        const mark = sub.status === 'pass' ? this.green('✓') : sub.status === 'fail' ? this.red('✗') : this.blue('-')
+        const message = sub.message ? `: ${sub.message}` : ''
+        const location = sub.file ? ` (${sub.file}${sub.line !== undefined ? `:${sub.line}` : ''})` : ''
+        return `${mark} ${sub.name}${message}${location}`
+    }
+
+    /*
+   Reports pass/fail per matrix cell so the configuration that broke stands out
+   @param results All test results
+   */
+    private reportMatrix(results: TestResult[]): void {
+        const cells = new Map<string, TestResult[]>()
+        for (const result of results.filter((result) => result.file.matrix)) {
+            cells.set(result.file.matrix!, [...(cells.get(result.file.matrix!) || []), result])
+        }
+        if (cells.size === 0) {
+            return
+        }
+        console.log('Matrix:')
+        for (const [label, cellResults] of cells) {
+            const stats = this.calculateStats(cellResults)
+            const failed = stats.failed + stats.errors + stats.timeouts + stats.crashes + stats.xpass
+            const status = failed > 0 ? this.red('✗ FAIL') : this.green('✓ PASS')
+            console.log(`  ${status} ${label}: ${stats.passed} passed, ${failed} failed, ${stats.skipped} skipped`)
+        }
+    }
+
     private printIndented(text: string, indent: string): void {
         const lines = text.split('\n')
         for (const line of lines) {
@@ -257,8 +541,16 @@ export class TestReporter {
                 return this.red('✗ FAIL')
             case TestStatus.Error:
                 return this.red('! ERROR')
+            case TestStatus.Timeout:
+                return this.red('⏱ TIMEOUT')
+            case TestStatus.IdleTimeout:
+                return this.red('⏱ IDLE-TIMEOUT')
             case TestStatus.Skipped:
                 return this.yellow('- SKIP')
+            case TestStatus.XFail:
+                return this.yellow('✗ XFAIL')
+            case TestStatus.XPass:
+                return this.red('✓ XPASS')
             case TestStatus.Running:
                 return this.blue('⟳ RUNNING')
             default:
@@ -266,6 +558,36 @@ export class TestReporter {
         }
     }
 
+    /*
+   Formats the status of a result, distinguishing sanitizer aborts from ordinary failures
+   @param result Test result
+   @returns Formatted status label
+   */
+    private formatResultStatus(result: TestResult): string {
+        if (result.sanitizer) {
+            return this.config.output?.colors ? this.red('✗ ASAN') : 'ASAN'
+        }
+        if (isCrash(result)) {
+            return this.config.output?.colors ? this.red('💥 CRASH') : 'CRASH'
+        }
+        return this.formatStatus(result.status)
+    }
+
+    /*
+   Formats the attempt count for tests that were retried
+   @param result Test result
+   @returns Attempt suffix (e.g., ", flaky: passed on attempt 2") or empty string
+   */
+    private formatAttempts(result: TestResult): string {
+        if (result.attempts === undefined || result.attempts <= 1) {
+            return ''
+        }
+        if (result.flaky) {
+            return `, ${this.yellow('flaky')}: passed on attempt ${result.attempts}`
+        }
+        return `, ${result.attempts} attempts`
+    }
+
     private formatDuration(duration: number): string {
         if (duration < 1000) {
             return `${Math.round(duration)}ms`
@@ -285,14 +607,32 @@ export class TestReporter {
                         stats.passed++
                         break
                     case TestStatus.Failed:
-                        stats.failed++
+                        if (isCrash(result)) {
+                            stats.crashes++
+                        } else {
+                            stats.failed++
+                        }
                         break
                     case TestStatus.Error:
                         stats.errors++
                         break
+                    case TestStatus.Timeout:
+                    case TestStatus.IdleTimeout:
+                        stats.timeouts++
+                        break
                     case TestStatus.Skipped:
                         stats.skipped++
                         break
+                    case TestStatus.XFail:
+                        stats.xfail++
+                        break
+                    case TestStatus.XPass:
+                        stats.xpass++
+                        break
+                }
+
+                if (result.flaky) {
+                    stats.flaky++
                 }
 
                 // Accumulate assertion counts
@@ -309,7 +649,12 @@ export class TestReporter {
                 passed: 0,
                 failed: 0,
                 errors: 0,
+                timeouts: 0,
+                crashes: 0,
                 skipped: 0,
+                flaky: 0,
+                xfail: 0,
+                xpass: 0,
                 totalDuration: 0,
                 assertionsPassed: 0,
                 assertionsFailed: 0,
@@ -335,8 +680,29 @@ export class TestReporter {
         return this.config.output?.colors ? `\x1b[34m${text}\x1b[0m` : text
     }
 
+    private bold(text: string): string {
+        return this.config.output?.colors ? `\x1b[1m${text}\x1b[0m` : text
+    }
+
     private getFailingTests(results: TestResult[]): TestResult[] {
-        return results.filter((result) => result.status === TestStatus.Failed || result.status === TestStatus.Error)
+        return results.filter(
+            (result) =>
+                result.status === TestStatus.Failed ||
+                result.status === TestStatus.Error ||
+                result.status === TestStatus.Timeout ||
+                result.status === TestStatus.IdleTimeout ||
+                result.status === TestStatus.XPass
+        )
+    }
+
+    /*
+   Gets the display name of a test: its relative path, with the case name for a parameterized test and
+   the matrix cell it ran in
+   @param file Test file
+   @returns Display name, e.g. "test/math.tst.c" or "[MODE=fast] test/add.tst.sh[negative]"
+   */
+    private getTestName(file: TestFile): string {
+        return Matrix.label(file, TestCases.label(file, this.getRelativePath(file.path)))
     }
 
     /*
@@ -0,0 +1,185 @@
+import type {TestFile, TestResult, Reporter} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {Matrix} from '../matrix.ts'
+import {TestCases} from '../cases.ts'
+import {stripAnsi} from '../utils/tty.ts'
+import {getCategory} from '../utils/categories.ts'
+import {isAbsolute, relative, resolve} from 'path'
+
+/*
+ Source location of a failure
+ */
+export type SourceLocation = {
+    file: string // Absolute path of the source file
+    line?: number
+    col?: number
+    message?: string // Diagnostic at the location, if it has one of its own
+}
+
+/*
+ Compiler diagnostics: GCC/Clang "file:line:col: error: message", MSVC "file(line,col): error C1234: message"
+ and rustc "error[E0425]: message" followed by " --> file:line:col"
+ */
+const COMPILER_ERRORS = [
+    /^(.+?):(\d+):(?:(\d+):)?\s*(?:fatal )?error:\s*(.+)$/m,
+    /^(.+?)\((\d+)(?:,(\d+))?\)\s*:\s*(?:fatal )?error\s*\w*:\s*(.+)$/m,
+]
+const RUST_ERROR = /^error(?:\[\w+\])?:\s*(.+)\n\s*-->\s*(.+?):(\d+):(\d+)$/m
+
+/*
+ Assertion failure location printed by testme.h ("Test failed at math.tst.c@23" or "FAIL math.tst.c:23 ...")
+ */
+const ASSERTION_FAILURE = /failed at (\S+?)@(\d+)|^FAIL (\S+?):(\d+)\b/m
+
+// Most output lines included in an annotation message
+const MESSAGE_LINES = 20
+
+/*
+ GitHubReporter - Writes GitHub Actions workflow commands so failures appear inline on pull requests
+
+ Each test with output is wrapped in ::group::/::endgroup:: so its output is collapsible in the job log.
+ Failing tests (fail, crash, timeout and error categories) get an ::error annotation on the test file.
+ The line is taken from the first compiler error for build failures (GCC, Clang, MSVC and rustc formats),
+ or from a testme.h assertion failure ("Test failed at file@line" or "FAIL file:line"). Paths are relative
+ to GITHUB_WORKSPACE so annotations attach to the pull request files.
+
+ Commands are written to the console output, interleaved with the human-readable output, rather than to
+ a file, so the format claims no report destination. It is enabled automatically when GITHUB_ACTIONS is
+ "true" (see --no-github and output.github).
+ */
+export class GitHubReporter implements Reporter {
+    private rootDir: string
+    private workspace: string
+
+    /*
+     Creates a GitHub Actions reporter
+     @param rootDir Root directory used to compute test names
+     @param workspace Repository root that annotation paths are relative to (default: GITHUB_WORKSPACE or rootDir)
+     */
+    constructor(rootDir: string, workspace?: string) {
+        this.rootDir = rootDir
+        this.workspace = workspace || process.env.GITHUB_WORKSPACE || rootDir
+    }
+
+    /*
+     Writes the test's output as a collapsible group and annotates the test if it failed
+     @param result Completed test result
+     */
+    testEnd(result: TestResult): void {
+        const text = this.render(result)
+        if (text) {
+            console.log(text)
+        }
+    }
+
+    /*
+     Nothing to finish, every test is written as it completes
+     @param _results All results from the run (unused)
+     */
+    runEnd(_results: TestResult[]): void {}
+
+    /*
+     Renders the workflow commands for a test
+     @param result Completed test result
+     @returns Workflow command lines
+     */
+    render(result: TestResult): string {
+        const name = this.getName(result.file)
+        const lines: string[] = []
+        const output = stripAnsi(result.output || '').trimEnd()
+        if (output) {
+            lines.push(`::group::${this.escapeData(`${name} (${result.status})`)}`, output, '::endgroup::')
+        }
+        const category = getCategory(result)
+        if (category !== 'pass' && category !== 'skip') {
+            lines.push(this.formatError(result, name, category))
+        }
+        return lines.join('\n')
+    }
+
+    /*
+     Formats the ::error command for a failed test
+     @param result Failed test result
+     @param name Test name
+     @param category Result category
+     @returns Workflow command
+     */
+    private formatError(result: TestResult, name: string, category: string): string {
+        const location = this.findLocation(result)
+        const properties = [`file=${this.escapeProperty(this.getPath(location?.file || result.file.path))}`]
+        if (location?.line) {
+            properties.push(`line=${location.line}`)
+        }
+        if (location?.col) {
+            properties.push(`col=${location.col}`)
+        }
+        const title = result.status === TestStatus.Error ? `${name} failed to build` : `${name} ${category}`
+        properties.push(`title=${this.escapeProperty(title)}`)
+        return `::error ${properties.join(',')}::${this.escapeData(location?.message || this.getMessage(result))}`
+    }
+
+    /*
+     Finds the source location of a failure in the test output
+     @param result Failed test result
+     @returns Location, or undefined if the output names none
+     */
+    findLocation(result: TestResult): SourceLocation | undefined {
+        const output = stripAnsi(result.output || '')
+        const dir = result.file.directory
+        if (result.status === TestStatus.Error) {
+            const rust = output.match(RUST_ERROR)
+            if (rust) {
+                return {file: resolve(dir, rust[2]!), line: +rust[3]!, col: +rust[4]!, message: rust[1]}
+            }
+            for (const pattern of COMPILER_ERRORS) {
+                const match = output.match(pattern)
+                if (match) {
+                    const col = match[3] ? +match[3] : undefined
+                    return {file: resolve(dir, match[1]!.trim()), line: +match[2]!, col, message: match[4]}
+                }
+            }
+            return undefined
+        }
+        const assertion = output.match(ASSERTION_FAILURE)
+        if (assertion) {
+            // __FILE__ is the path the compiler was given, so it may be relative to the test directory
+            const path = assertion[1] ?? assertion[3]!
+            const file = isAbsolute(path) ? path : resolve(dir, path)
+            return {file, line: Number(assertion[2] ?? assertion[4])}
+        }
+        return undefined
+    }
+
+    /*
+     Gets the annotation message for a failed test without a located diagnostic
+     @param result Failed test result
+     @returns Error message, or the first lines of the test output
+     */
+    private getMessage(result: TestResult): string {
+        const text = result.error || stripAnsi(result.output || '').trim() || `Test ${result.status}`
+        return text.split('\n').slice(0, MESSAGE_LINES).join('\n')
+    }
+
+    private getName(file: TestFile): string {
+        const path = relative(this.rootDir, file.path).replace(/\\/g, '/')
+        return Matrix.label(file, TestCases.label(file, path))
+    }
+
+    private getPath(path: string): string {
+        return relative(this.workspace, path).replace(/\\/g, '/')
+    }
+
+    /*
+     Escapes workflow command data (the message after ::)
+     */
+    private escapeData(text: string): string {
+        return text.replace(/%/g, '%25').replace(/\r/g, '%0D').replace(/\n/g, '%0A')
+    }
+
+    /*
+     Escapes workflow command property values
+     */
+    private escapeProperty(text: string): string {
+        return this.escapeData(text).replace(/:/g, '%3A').replace(/,/g, '%2C')
+    }
+}
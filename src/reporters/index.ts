@@ -0,0 +1,211 @@
+import type {Reporter, TestConfig, TestFile, TestResult} from '../types.ts'
+import {JUnitReporter} from './junit.ts'
+import {TapReporter} from './tap.ts'
+import {JsonReporter} from './json.ts'
+import {GitHubReporter} from './github.ts'
+import {MarkdownReporter} from './markdown.ts'
+import {resolve} from 'path'
+
+/*
+ Creates a reporter for a report format
+ @param path Output file path, or '-' for stdout (relative paths resolve against rootDir)
+ @param rootDir Root directory for relative output paths and test names
+ @param config Optional configuration for run-wide details (e.g., depth)
+ */
+export type ReporterFactory = (path: string, rootDir: string, config?: TestConfig) => Reporter
+
+/*
+ Registered report formats with their default output files ('-' is stdout). Formats marked console write to the
+ console output alongside the human-readable output, take no file and claim no destination.
+ */
+const formats: Map<string, {file: string; factory: ReporterFactory; console?: boolean}> = new Map([
+    ['junit', {file: 'junit.xml', factory: (path, rootDir) => new JUnitReporter(path, rootDir)}],
+    ['tap', {file: '-', factory: (path, rootDir) => new TapReporter(path, rootDir)}],
+    [
+        'json',
+        {
+            file: 'results.json',
+            factory: (path, rootDir, config) =>
+                new JsonReporter(
+                    path,
+                    rootDir,
+                    config?.execution?.depth ?? 0,
+                    config?.execution?.seed,
+                    config?.output?.slowest
+                ),
+        },
+    ],
+    ['markdown', {file: 'summary.md', factory: (path, rootDir) => new MarkdownReporter(path, rootDir)}],
+    ['github', {file: '', factory: (_path, rootDir) => new GitHubReporter(rootDir), console: true}],
+])
+
+/*
+ Report format naming the human-readable console output, which always goes to stdout
+ */
+export const CONSOLE_FORMAT = 'console'
+
+/*
+ Names of the built-in report formats
+ */
+export const REPORT_FORMATS = [...formats.keys(), CONSOLE_FORMAT]
+
+/*
+ Registers a report format so it can be selected by name with --report or output.report
+ Programs embedding the runner use this to add their own formats. Registering a built-in name replaces it.
+ @param format Format name (e.g., 'html')
+ @param factory Creates the reporter for a run
+ @param file Default output file when the specification has none ('-' for stdout)
+ @throws Error if the name is empty, contains a colon or is 'console'
+ */
+export const registerReporter = (format: string, factory: ReporterFactory, file: string = '-'): void => {
+    const name = format.trim().toLowerCase()
+    if (!name || name.includes(':') || name === CONSOLE_FORMAT) {
+        throw new Error(`Invalid report format name: "${format}"`)
+    }
+    formats.set(name, {file, factory})
+}
+
+/*
+ Parses a report specification of the form FORMAT[:FILE]
+ @param spec Report specification (e.g., 'junit', 'junit:results.xml')
+ @returns Report format and optional output file
+ @throws Error if the format is not supported or the console is given a file
+ */
+export const parseReportSpec = (spec: string): {format: string; file?: string} => {
+    const index = spec.indexOf(':')
+    const format = (index >= 0 ? spec.slice(0, index) : spec).trim().toLowerCase()
+    const file = index >= 0 ? spec.slice(index + 1).trim() || undefined : undefined
+
+    if (format === CONSOLE_FORMAT) {
+        if (file && file !== '-') {
+            throw new Error(`The ${CONSOLE_FORMAT} report is written to stdout and cannot be written to "${file}"`)
+        }
+        return {format}
+    }
+    if (!formats.has(format)) {
+        const names = [...formats.keys(), CONSOLE_FORMAT].join(', ')
+        throw new Error(`Unknown report format: "${format}". Supported formats: ${names}`)
+    }
+    if (file && formats.get(format)!.console) {
+        throw new Error(`The ${format} report is written to the console output and cannot be written to "${file}"`)
+    }
+    return {format, file}
+}
+
+/*
+ Resolves the report specifications of a run to the reporters to create
+ A report repeated with the same format and destination is created once. The console output is written
+ whether or not it is listed; listing it claims stdout, so another report to stdout is then a conflict.
+ @param specs Report specifications (FORMAT[:FILE]) in the order given
+ @param rootDir Root directory for relative output paths
+ @returns Specifications (FORMAT:FILE, or FORMAT for console formats) of the reporters to create, without
+    duplicates or the console
+ @throws Error if a specification is invalid or two reports write to the same destination
+ */
+export const resolveReportSpecs = (specs: string[], rootDir: string): string[] => {
+    const claimed = new Map<string, string>()
+    const resolved: string[] = []
+    for (const spec of specs) {
+        const {format, file} = parseReportSpec(spec)
+        if (formats.get(format)?.console) {
+            if (!resolved.includes(format)) {
+                resolved.push(format)
+            }
+            continue
+        }
+        const path = format === CONSOLE_FORMAT ? '-' : file || formats.get(format)!.file
+        const destination = path === '-' ? path : resolve(rootDir, path)
+        const owner = claimed.get(destination)
+        if (owner === format) {
+            continue
+        }
+        if (owner) {
+            const where = path === '-' ? 'stdout' : `"${path}"`
+            throw new Error(`Conflicting reports: ${owner} and ${format} both write to ${where}`)
+        }
+        claimed.set(destination, format)
+        if (format !== CONSOLE_FORMAT) {
+            resolved.push(`${format}:${path}`)
+        }
+    }
+    return resolved
+}
+
+/*
+ Creates a reporter from a report specification
+ @param spec Report specification (FORMAT[:FILE])
+ @param rootDir Root directory for relative output paths and test classnames
+ @param config Optional configuration for run-wide details (e.g., depth)
+ @returns Reporter instance
+ */
+export const createReporter = (spec: string, rootDir: string, config?: TestConfig): Reporter => {
+    const {format, file} = parseReportSpec(spec)
+    if (format === CONSOLE_FORMAT) {
+        throw new Error(`The ${CONSOLE_FORMAT} output is written by the runner, not by a reporter`)
+    }
+    const {file: defaultFile, factory} = formats.get(format)!
+    return factory(file || defaultFile, rootDir, config)
+}
+
+/*
+ Checks if a report specification writes to stdout
+ @param spec Report specification (FORMAT[:FILE])
+ @returns True if the report is written to stdout
+ */
+export const isStdoutReport = (spec: string): boolean => {
+    const {format, file} = parseReportSpec(spec)
+    if (format === CONSOLE_FORMAT) {
+        return true
+    }
+    const entry = formats.get(format)!
+    return !entry.console && (file || entry.file) === '-'
+}
+
+/*
+ MultiReporter - Sends each lifecycle event to several reporters in turn
+
+ Lets console, JUnit and custom reporters run side by side from one run. A reporter that throws does not
+ stop the others or the run; the error is printed as a warning.
+ */
+export class MultiReporter implements Reporter {
+    private reporters: Reporter[]
+
+    /*
+     @param reporters Reporters to receive the events, in order
+     */
+    constructor(reporters: Reporter[]) {
+        this.reporters = reporters
+    }
+
+    runStart(tests: TestFile[]): void {
+        this.each((reporter) => reporter.runStart?.(tests))
+    }
+
+    testStart(file: TestFile): void {
+        this.each((reporter) => reporter.testStart?.(file))
+    }
+
+    testEnd(result: TestResult): void {
+        this.each((reporter) => reporter.testEnd(result))
+    }
+
+    runEnd(results: TestResult[], elapsedTime?: number): void {
+        this.each((reporter) => reporter.runEnd(results, elapsedTime))
+    }
+
+    /*
+     Calls a reporter method on every reporter
+     @param fn Calls the method on one reporter
+     */
+    private each(fn: (reporter: Reporter) => void): void {
+        for (const reporter of this.reporters) {
+            try {
+                fn(reporter)
+            } catch (error) {
+                console.warn(`⚠️  Reporter failed: ${error instanceof Error ? error.message : error}`)
+            }
+        }
+    }
+}
+
+export {JUnitReporter, TapReporter, JsonReporter, MarkdownReporter, GitHubReporter}
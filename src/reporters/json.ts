@@ -0,0 +1,196 @@
+import type {TestFile, TestResult, Reporter} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {VERSION} from '../version.ts'
+import {getSlowestTests} from '../utils/slowest.ts'
+import {stripAnsi} from '../utils/tty.ts'
+import {countCategories, getCategory} from '../utils/categories.ts'
+import {relative, dirname, resolve} from 'path'
+import {mkdirSync, renameSync, writeFileSync} from 'fs'
+
+/*
+ JsonReporter - Writes a structured JSON results file for dashboards and tooling
+
+ Document layout:
+ {
+     summary: {version, total, passed, failed, skipped, errors, timeouts, categories, flaky, durationMs, seed?,
+               assertions?, complete},
+     tests: [{path, language, status, category, durationMs, exitCode, stdout, stderr, depth, attempts?, flaky?,
+             sanitizer?, target?, truncated?, signal?, backtrace?, quarantined?, tags?, benchmarks?, assertions?,
+             results?: [{name, status, message?, file?, line?, duration?}, ...]}, ...],
+     slowest?: [{path, durationMs}, ...]
+ }
+
+ Status values are normalized to pass, fail, skip, error, timeout and idle-timeout. Categories (pass, fail,
+ crash, timeout, error, skip) group them so crashes and broken builds stand apart from assertion failures.
+ Assertions are {passed, failed} counts from the ✓/✗ and PASS/FAIL lines of a test's output, totalled in the
+ summary when any test reported them. Results are the named sub-results a test reported with "TESTME {...}"
+ lines. The file is rewritten after every result (summary.complete is false until the run finishes) so an
+ interrupted run still leaves the results that completed.
+ */
+export class JsonReporter implements Reporter {
+    private path: string
+    private rootDir: string
+    private depth: number
+    private seed?: number
+    private slowest?: number
+    private results: TestResult[] = []
+    private complete: boolean = false
+    private elapsedTime?: number
+
+    /*
+     Creates a JSON results file writer
+     @param path Output file path (relative paths resolve against rootDir)
+     @param rootDir Root directory used to compute test paths
+     @param depth Test depth for the run (from --depth)
+     @param seed Shuffle seed for the run (from --shuffle or --seed)
+     @param slowest Number of slowest tests to list (from --slowest)
+     */
+    constructor(path: string, rootDir: string, depth: number = 0, seed?: number, slowest?: number) {
+        this.rootDir = rootDir
+        this.path = resolve(rootDir, path)
+        this.depth = depth
+        this.seed = seed
+        this.slowest = slowest
+    }
+
+    /*
+     Writes an empty results file
+     @param _tests Tests that will be run (unused)
+     */
+    runStart(_tests: TestFile[]): void {
+        this.flush()
+    }
+
+    /*
+     Records a completed test and rewrites the results file
+     @param result Completed test result
+     */
+    testEnd(result: TestResult): void {
+        this.results.push(result)
+        this.flush()
+    }
+
+    /*
+     Marks the results as complete and writes the final file
+     @param _results All results from the run (unused, results are tracked incrementally)
+     @param elapsedTime Wall-clock time of the run in milliseconds
+     */
+    runEnd(_results: TestResult[], elapsedTime?: number): void {
+        this.complete = true
+        this.elapsedTime = elapsedTime
+        this.flush()
+    }
+
+    /*
+     Builds the JSON document for the results recorded so far
+     @returns Results document
+     */
+    render() {
+        const count = (status: TestStatus) => this.results.filter((result) => result.status === status).length
+        const path = (result: TestResult) => relative(this.rootDir, result.file.path).replace(/\\/g, '/')
+        return {
+            summary: {
+                version: VERSION,
+                total: this.results.length,
+                passed: count(TestStatus.Passed),
+                failed: count(TestStatus.Failed),
+                skipped: count(TestStatus.Skipped),
+                errors: count(TestStatus.Error),
+                timeouts: count(TestStatus.Timeout) + count(TestStatus.IdleTimeout),
+                categories: countCategories(this.results),
+                xfail: count(TestStatus.XFail),
+                xpass: count(TestStatus.XPass),
+                flaky: this.results.filter((result) => result.flaky).length,
+                durationMs: Math.round(this.results.reduce((sum, result) => sum + result.duration, 0)),
+                ...(this.elapsedTime !== undefined && {elapsedMs: Math.round(this.elapsedTime)}),
+                ...(this.seed !== undefined && {seed: this.seed}),
+                ...this.totalAssertions(),
+                complete: this.complete,
+            },
+            tests: this.results.map((result) => ({
+                path: path(result),
+                ...(result.file.testCase && {case: result.file.testCase.name}),
+                ...(result.file.matrix && {matrix: result.file.matrix}),
+                language: result.file.type,
+                status: this.formatStatus(result.status),
+                category: getCategory(result),
+                durationMs: Math.round(result.duration),
+                exitCode: result.exitCode ?? null,
+                stdout: stripAnsi(result.stdout ?? result.output ?? ''),
+                stderr: stripAnsi(result.stderr ?? result.error ?? ''),
+                depth: this.depth,
+                ...(result.attempts !== undefined && {attempts: result.attempts, flaky: result.flaky === true}),
+                ...(result.sanitizer && {sanitizer: result.sanitizer}),
+                ...(result.target && {target: result.target}),
+                ...(result.truncated && {truncated: true}),
+                ...(result.signal && {signal: result.signal}),
+                ...(result.backtrace && {backtrace: result.backtrace}),
+                ...(result.file.quarantined && {quarantined: true}),
+                ...(result.file.tags && {tags: result.file.tags}),
+                ...(result.benchmarks && {benchmarks: result.benchmarks}),
+                ...(result.assertions && {assertions: result.assertions}),
+                ...(result.subResults && {results: result.subResults}),
+            })),
+            ...(this.slowest && {
+                slowest: getSlowestTests(this.results, this.slowest).map((result) => ({
+                    path: path(result),
+                    durationMs: Math.round(result.duration),
+                })),
+            }),
+        }
+    }
+
+    /*
+     Totals the assertion counts of the results
+     @returns Object with the summary assertions, or empty if no test reported assertions
+     */
+    private totalAssertions(): {assertions?: {passed: number; failed: number}} {
+        const counted = this.results.filter((result) => result.assertions)
+        if (counted.length === 0) {
+            return {}
+        }
+        const passed = counted.reduce((sum, result) => sum + result.assertions!.passed, 0)
+        const failed = counted.reduce((sum, result) => sum + result.assertions!.failed, 0)
+        return {assertions: {passed, failed}}
+    }
+
+    /*
+     Maps a test status to the short status names used in the results file
+     @param status Test status
+     @returns pass, fail, skip, error, timeout, xfail or xpass
+     */
+    private formatStatus(status: TestStatus): string {
+        switch (status) {
+            case TestStatus.Passed:
+                return 'pass'
+            case TestStatus.Failed:
+                return 'fail'
+            case TestStatus.Skipped:
+                return 'skip'
+            case TestStatus.Timeout:
+                return 'timeout'
+            case TestStatus.IdleTimeout:
+                return 'idle-timeout'
+            case TestStatus.XFail:
+                return 'xfail'
+            case TestStatus.XPass:
+                return 'xpass'
+            default:
+                return 'error'
+        }
+    }
+
+    /*
+     Writes the current document to disk via a temporary file and rename
+     */
+    private flush(): void {
+        try {
+            mkdirSync(dirname(this.path), {recursive: true})
+            const tmpPath = `${this.path}.tmp`
+            writeFileSync(tmpPath, JSON.stringify(this.render(), null, 2) + '\n')
+            renameSync(tmpPath, this.path)
+        } catch (error) {
+            console.warn(`⚠️  Failed to write JSON results ${this.path}: ${error}`)
+        }
+    }
+}
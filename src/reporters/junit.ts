@@ -0,0 +1,299 @@
+import type {TestFile, TestResult, Reporter} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {Matrix} from '../matrix.ts'
+import {stripAnsi} from '../utils/tty.ts'
+import {isCrash} from '../utils/crash.ts'
+import {relative, dirname, resolve} from 'path'
+import {mkdirSync, renameSync, writeFileSync} from 'fs'
+
+/*
+ JUnitReporter - Writes JUnit XML reports for CI systems (Jenkins, GitLab, etc.)
+
+ Document layout:
+ - <testsuites> root element with run totals
+ - One <testsuite> per test directory, with a "target" property when its tests were cross-compiled
+ - One <testcase> per .tst.* file with the directory as the classname
+
+ Status mapping:
+ - Failed tests emit <failure> with captured stdout/stderr
+ - Errored tests (including compilation failures) emit <error>
+ - Skipped tests emit <skipped>
+
+ The complete document is rewritten after every result so that a valid, partial
+ report survives an interrupted run.
+ */
+export class JUnitReporter implements Reporter {
+    private path: string
+    private rootDir: string
+    private results: TestResult[] = []
+    private timestamp: string
+
+    /*
+     Creates a JUnit XML report writer
+     @param path Output file path (relative paths resolve against rootDir)
+     @param rootDir Root directory used to compute classnames
+     */
+    constructor(path: string, rootDir: string) {
+        this.rootDir = rootDir
+        this.path = resolve(rootDir, path)
+        this.timestamp = new Date().toISOString().replace(/\.\d+Z$/, '')
+    }
+
+    /*
+     Writes an empty report so consumers find a valid document even if no tests complete
+     @param _tests Tests that will be run (unused)
+     */
+    runStart(_tests: TestFile[]): void {
+        this.flush()
+    }
+
+    /*
+     Records a completed test and flushes the updated report to disk
+     @param result Completed test result
+     */
+    testEnd(result: TestResult): void {
+        this.results.push(result)
+        this.flush()
+    }
+
+    /*
+     Writes the final report
+     @param _results All results from the run (unused, results are tracked incrementally)
+     */
+    runEnd(_results: TestResult[]): void {
+        this.flush()
+    }
+
+    /*
+     Renders the JUnit XML document for the given results
+     @returns XML document string
+     */
+    render(): string {
+        const suites = new Map<string, TestResult[]>()
+        for (const result of this.results) {
+            const classname = this.getClassname(result)
+            if (!suites.has(classname)) {
+                suites.set(classname, [])
+            }
+            suites.get(classname)!.push(result)
+        }
+
+        const totals = this.count(this.results)
+        const lines: string[] = ['<?xml version="1.0" encoding="UTF-8"?>']
+        lines.push(
+            `<testsuites name="testme" tests="${totals.tests}" failures="${totals.failures}" ` +
+                `errors="${totals.errors}" skipped="${totals.skipped}" time="${this.formatTime(totals.time)}">`
+        )
+
+        for (const [classname, results] of suites) {
+            const stats = this.count(results)
+            lines.push(
+                `  <testsuite name="${this.escape(classname)}" tests="${stats.tests}" failures="${stats.failures}" ` +
+                    `errors="${stats.errors}" skipped="${stats.skipped}" time="${this.formatTime(stats.time)}" ` +
+                    `timestamp="${this.timestamp}">`
+            )
+            const target = results.find((result) => result.target)?.target
+            if (target) {
+                lines.push(
+                    '    <properties>',
+                    `      <property name="target" value="${this.escape(target)}"/>`,
+                    '    </properties>'
+                )
+            }
+            for (const result of results) {
+                lines.push(...this.renderTestCase(result, classname))
+            }
+            lines.push('  </testsuite>')
+        }
+        lines.push('</testsuites>')
+        return lines.join('\n') + '\n'
+    }
+
+    /*
+     Renders a single <testcase> element
+     @param result Test result to render
+     @param classname Classname (test directory) for the test case
+     @returns Lines of XML for the test case
+     */
+    private renderTestCase(result: TestResult, classname: string): string[] {
+        const name = Matrix.label(result.file, result.file.name)
+        const open =
+            `    <testcase name="${this.escape(name)}" classname="${this.escape(classname)}" ` +
+            `time="${this.formatTime(result.duration)}"`
+        const output = this.getCapturedOutput(result)
+
+        switch (result.status) {
+            case TestStatus.Passed: {
+                // Flaky tests record how many attempts were needed
+                const properties = result.flaky
+                    ? [
+                          '      <properties>',
+                          `        <property name="attempts" value="${result.attempts}"/>`,
+                          '        <property name="flaky" value="true"/>',
+                          '      </properties>',
+                      ]
+                    : []
+                if (!result.output && properties.length === 0) {
+                    return [`${open}/>`]
+                }
+                return [
+                    `${open}>`,
+                    ...properties,
+                    ...(result.output ? [`      <system-out>${this.escape(result.output)}</system-out>`] : []),
+                    '    </testcase>',
+                ]
+            }
+
+            case TestStatus.Skipped:
+                return [
+                    `${open}>`,
+                    `      <skipped message="${this.escape(this.firstLine(result.output) || 'Skipped')}"/>`,
+                    '    </testcase>',
+                ]
+
+            case TestStatus.XFail:
+                // Expected failures are not failures, report them as skipped with the reason
+                return [
+                    `${open}>`,
+                    `      <skipped message="${this.escape(`Expected failure: ${this.getMessage(result)}`)}"/>`,
+                    '    </testcase>',
+                ]
+
+            case TestStatus.XPass:
+                return [
+                    `${open}>`,
+                    `      <failure message="${this.escape(this.getMessage(result))}" type="xpass">` +
+                        `${this.escape(output)}</failure>`,
+                    '    </testcase>',
+                ]
+
+            case TestStatus.Error:
+                return [
+                    `${open}>`,
+                    `      <error message="${this.escape(this.getMessage(result))}" type="error">` +
+                        `${this.escape(output)}</error>`,
+                    '    </testcase>',
+                ]
+
+            case TestStatus.Timeout:
+            case TestStatus.IdleTimeout:
+                return [
+                    `${open}>`,
+                    `      <failure message="${this.escape(this.getMessage(result))}" type="${result.status}">` +
+                        `${this.escape(output)}</failure>`,
+                    '    </testcase>',
+                ]
+
+            default:
+                return [
+                    `${open}>`,
+                    `      <failure message="${this.escape(this.getMessage(result))}" ` +
+                        `type="${result.sanitizer ? 'sanitizer' : isCrash(result) ? 'crash' : 'failure'}">` +
+                        `${this.escape(output)}</failure>`,
+                    '    </testcase>',
+                ]
+        }
+    }
+
+    /*
+     Combines captured output and error text for failure/error bodies
+     @param result Test result
+     @returns Combined output string
+     */
+    private getCapturedOutput(result: TestResult): string {
+        const parts: string[] = []
+        if (result.output) {
+            parts.push(result.output)
+        }
+        if (result.error && !result.output?.includes(result.error)) {
+            parts.push(result.error)
+        }
+        return parts.join('\n')
+    }
+
+    /*
+     Builds the short message attribute for a failure or error
+     @param result Test result
+     @returns One-line message
+     */
+    private getMessage(result: TestResult): string {
+        const firstError = this.firstLine(result.error || '')
+        if (firstError) {
+            return firstError
+        }
+        if (result.exitCode !== undefined) {
+            return `Exit code ${result.exitCode}`
+        }
+        return result.status === TestStatus.Error ? 'Test error' : 'Test failed'
+    }
+
+    /*
+     Gets the classname for a result: the test directory relative to the root directory
+     @param result Test result
+     @returns Relative directory path, or '.' for the root directory
+     */
+    private getClassname(result: TestResult): string {
+        const dir = relative(this.rootDir, result.file.directory).replace(/\\/g, '/')
+        return dir || '.'
+    }
+
+    private count(results: TestResult[]) {
+        return results.reduce(
+            (stats, result) => {
+                stats.tests++
+                stats.time += result.duration
+                if (result.status === TestStatus.Failed || result.status === TestStatus.Timeout) stats.failures++
+                if (result.status === TestStatus.IdleTimeout) stats.failures++
+                if (result.status === TestStatus.XPass) stats.failures++
+                if (result.status === TestStatus.Error) stats.errors++
+                if (result.status === TestStatus.Skipped || result.status === TestStatus.XFail) stats.skipped++
+                return stats
+            },
+            {tests: 0, failures: 0, errors: 0, skipped: 0, time: 0}
+        )
+    }
+
+    /*
+     Converts milliseconds to seconds with millisecond precision
+     @param duration Duration in milliseconds
+     @returns Seconds formatted for the time attribute
+     */
+    private formatTime(duration: number): string {
+        return (duration / 1000).toFixed(3)
+    }
+
+    private firstLine(text: string): string {
+        return text.trim().split('\n')[0]?.trim() || ''
+    }
+
+    /*
+     Escapes text for XML attributes and content
+     Removes ANSI color sequences and characters that are not valid in XML 1.0
+     @param text Text to escape
+     @returns XML-safe string
+     */
+    private escape(text: string): string {
+        return stripAnsi(text)
+            .replace(/[\x00-\x08\x0B\x0C\x0E-\x1F]/g, '')
+            .replace(/&/g, '&amp;')
+            .replace(/</g, '&lt;')
+            .replace(/>/g, '&gt;')
+            .replace(/"/g, '&quot;')
+            .replace(/'/g, '&apos;')
+    }
+
+    /*
+     Writes the current document to disk
+     Writes to a temporary file and renames it so readers never see a partially written report
+     */
+    private flush(): void {
+        try {
+            mkdirSync(dirname(this.path), {recursive: true})
+            const tmpPath = `${this.path}.tmp`
+            writeFileSync(tmpPath, this.render())
+            renameSync(tmpPath, this.path)
+        } catch (error) {
+            console.warn(`⚠️  Failed to write JUnit report ${this.path}: ${error}`)
+        }
+    }
+}
@@ -0,0 +1,215 @@
+import type {TestFile, TestResult, Reporter} from '../types.ts'
+import {Matrix} from '../matrix.ts'
+import {TestCases} from '../cases.ts'
+import {stripAnsi} from '../utils/tty.ts'
+import {getCategory} from '../utils/categories.ts'
+import {relative, dirname, resolve} from 'path'
+import {mkdirSync, renameSync, writeFileSync} from 'fs'
+
+// Most characters of output kept for each failing test (the end of the output is kept)
+const MAX_OUTPUT = 4000
+
+// Most failing tests given a details block
+const MAX_FAILURES = 50
+
+/*
+ MarkdownReporter - Writes a Markdown results summary for pull request comments
+
+ Document layout:
+ - A status badge and a one-line summary with the run totals and duration
+ - A table with passed, failed and skipped counts and the total duration for each test directory
+ - A collapsible <details> block with the output of each failing test
+
+ Crashes, timeouts and errors count as failures. To keep the comment small, only the last MAX_OUTPUT
+ characters of a failure's output are kept and at most MAX_FAILURES failures are listed, each with a note of
+ what was left out. The file is rewritten after every result so an interrupted run still leaves a summary.
+ */
+export class MarkdownReporter implements Reporter {
+    private path: string
+    private rootDir: string
+    private results: TestResult[] = []
+    private complete: boolean = false
+    private elapsedTime?: number
+
+    /*
+     Creates a Markdown summary writer
+     @param path Output file path (relative paths resolve against rootDir)
+     @param rootDir Root directory used to compute test names and directories
+     */
+    constructor(path: string, rootDir: string) {
+        this.rootDir = rootDir
+        this.path = resolve(rootDir, path)
+    }
+
+    /*
+     Writes an empty summary
+     @param _tests Tests that will be run (unused)
+     */
+    runStart(_tests: TestFile[]): void {
+        this.flush()
+    }
+
+    /*
+     Records a completed test and rewrites the summary
+     @param result Completed test result
+     */
+    testEnd(result: TestResult): void {
+        this.results.push(result)
+        this.flush()
+    }
+
+    /*
+     Marks the summary as complete and writes the final file
+     @param _results All results from the run (unused, results are tracked incrementally)
+     @param elapsedTime Wall-clock time of the run in milliseconds
+     */
+    runEnd(_results: TestResult[], elapsedTime?: number): void {
+        this.complete = true
+        this.elapsedTime = elapsedTime
+        this.flush()
+    }
+
+    /*
+     Renders the Markdown summary for the results recorded so far
+     @returns Markdown document
+     */
+    render(): string {
+        const total = this.count(this.results)
+        const duration = this.elapsedTime ?? this.results.reduce((sum, result) => sum + result.duration, 0)
+        const status = total.failed > 0 ? 'failing' : 'passing'
+        const color = total.failed > 0 ? 'red' : 'brightgreen'
+        const badge = `https://img.shields.io/badge/tests-${status}-${color}`
+        const lines = [
+            `# Test Results${this.complete ? '' : ' (incomplete)'}`,
+            '',
+            `![Tests ${status}](${badge})`,
+            '',
+            `**${total.passed} passed, ${total.failed} failed, ${total.skipped} skipped** of ` +
+                `${this.results.length} test(s) in ${this.formatDuration(duration)}`,
+            '',
+            '| Directory | Passed | Failed | Skipped | Duration |',
+            '| --- | ---: | ---: | ---: | ---: |',
+        ]
+        for (const [dir, results] of this.groupByDirectory()) {
+            const counts = this.count(results)
+            const time = results.reduce((sum, result) => sum + result.duration, 0)
+            lines.push(
+                `| \`${dir.replace(/\|/g, '\\|')}\` | ${counts.passed} | ${counts.failed} | ${counts.skipped} | ` +
+                    `${this.formatDuration(time)} |`
+            )
+        }
+        lines.push(
+            `| **Total** | **${total.passed}** | **${total.failed}** | **${total.skipped}** | ` +
+                `**${this.formatDuration(duration)}** |`
+        )
+
+        const failures = this.results.filter((result) => this.isFailure(result))
+        if (failures.length > 0) {
+            lines.push('', '## Failures', '')
+            for (const result of failures.slice(0, MAX_FAILURES)) {
+                lines.push(...this.renderFailure(result), '')
+            }
+            if (failures.length > MAX_FAILURES) {
+                lines.push(`_${failures.length - MAX_FAILURES} more failing test(s) not shown._`, '')
+            }
+        }
+        return lines.join('\n').trimEnd() + '\n'
+    }
+
+    /*
+     Renders the details block of a failing test
+     @param result Failing test result
+     @returns Markdown lines
+     */
+    private renderFailure(result: TestResult): string[] {
+        const captured = result.stdout !== undefined || result.stderr !== undefined ? this.join(result) : result.output
+        let output = stripAnsi(captured || '').trimEnd() || result.error || 'No output'
+        let note = ''
+        if (output.length > MAX_OUTPUT) {
+            note = `_Output truncated: the first ${output.length - MAX_OUTPUT} characters are omitted._`
+            output = output.slice(-MAX_OUTPUT)
+        }
+        // A fence longer than any run of backticks in the output cannot be closed by it
+        const longest = Math.max(2, ...(output.match(/`+/g) || []).map((run) => run.length))
+        const fence = '`'.repeat(longest + 1)
+        const name = this.getName(result.file).replace(/</g, '&lt;')
+        const summary = `❌ <code>${name}</code> (${result.status}, ${this.formatDuration(result.duration)})`
+        const lines = ['<details>', `<summary>${summary}</summary>`, '']
+        if (note) {
+            lines.push(note, '')
+        }
+        lines.push(`${fence}text`, output, fence, '', '</details>')
+        return lines
+    }
+
+    /*
+     Joins the separately captured stdout and stderr of a test
+     @param result Test result
+     @returns Non-empty streams, stdout first
+     */
+    private join(result: TestResult): string {
+        return [result.stdout, result.stderr].filter((text) => text && text.trim()).join('\n')
+    }
+
+    /*
+     Groups results by test directory, relative to the root and sorted by name
+     @returns Map of directory to results
+     */
+    private groupByDirectory(): Map<string, TestResult[]> {
+        const groups = new Map<string, TestResult[]>()
+        for (const result of this.results) {
+            const dir = relative(this.rootDir, result.file.directory).replace(/\\/g, '/') || '.'
+            if (!groups.has(dir)) {
+                groups.set(dir, [])
+            }
+            groups.get(dir)!.push(result)
+        }
+        return new Map([...groups.entries()].sort(([a], [b]) => a.localeCompare(b)))
+    }
+
+    /*
+     Counts results as passed, failed (including crashes, timeouts and errors) or skipped
+     */
+    private count(results: TestResult[]): {passed: number; failed: number; skipped: number} {
+        const counts = {passed: 0, failed: 0, skipped: 0}
+        for (const result of results) {
+            const category = getCategory(result)
+            if (category === 'pass') {
+                counts.passed++
+            } else if (category === 'skip') {
+                counts.skipped++
+            } else {
+                counts.failed++
+            }
+        }
+        return counts
+    }
+
+    private isFailure(result: TestResult): boolean {
+        const category = getCategory(result)
+        return category !== 'pass' && category !== 'skip'
+    }
+
+    private getName(file: TestFile): string {
+        const path = relative(this.rootDir, file.path).replace(/\\/g, '/')
+        return Matrix.label(file, TestCases.label(file, path))
+    }
+
+    private formatDuration(ms: number): string {
+        return ms < 1000 ? `${Math.round(ms)}ms` : `${(ms / 1000).toFixed(1)}s`
+    }
+
+    /*
+     Writes the current summary to disk via a temporary file and rename
+     */
+    private flush(): void {
+        try {
+            mkdirSync(dirname(this.path), {recursive: true})
+            const tmpPath = `${this.path}.tmp`
+            writeFileSync(tmpPath, this.render())
+            renameSync(tmpPath, this.path)
+        } catch (error) {
+            console.warn(`⚠️  Failed to write Markdown summary ${this.path}: ${error}`)
+        }
+    }
+}
@@ -0,0 +1,187 @@
+import type {TestFile, TestResult, Reporter} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {TestCases} from '../cases.ts'
+import {Matrix} from '../matrix.ts'
+import {stripAnsi} from '../utils/tty.ts'
+import {isCrash} from '../utils/crash.ts'
+import {relative, dirname, resolve} from 'path'
+import {appendFileSync, mkdirSync, writeFileSync} from 'fs'
+
+/*
+ TapReporter - Writes a TAP (Test Anything Protocol) version 13 stream
+
+ Stream layout:
+ - "TAP version 13" header and "1..N" plan once the tests to run are known
+ - "ok N - name" / "not ok N - name" per test in completion order
+ - YAML diagnostic block (indented, between "---" and "...") under failing tests
+ - "# SKIP" directive for skipped tests
+
+ Each test's lines are written with a single write call so interleaved parallel workers
+ cannot corrupt the stream. Tests that never ran (interrupted, disabled or filtered groups)
+ are reported as skipped when the run finishes so the plan always matches.
+ */
+export class TapReporter implements Reporter {
+    private path: string | null
+    private rootDir: string
+    private planned: TestFile[] = []
+    private reported: Set<string> = new Set()
+    private count: number = 0
+
+    /*
+     Creates a TAP stream writer
+     @param path Output file path, or '-' for stdout (relative paths resolve against rootDir)
+     @param rootDir Root directory used to compute test names
+     */
+    constructor(path: string, rootDir: string) {
+        this.rootDir = rootDir
+        this.path = path === '-' ? null : resolve(rootDir, path)
+    }
+
+    /*
+     Writes the TAP header and plan line
+     @param tests Tests that will be run
+     */
+    runStart(tests: TestFile[]): void {
+        this.planned = tests
+        if (this.path) {
+            try {
+                mkdirSync(dirname(this.path), {recursive: true})
+                writeFileSync(this.path, '')
+            } catch (error) {
+                console.warn(`⚠️  Failed to create TAP report ${this.path}: ${error}`)
+            }
+        }
+        this.write(`TAP version 13\n1..${tests.length}\n`)
+    }
+
+    /*
+     Writes the complete TAP block for a completed test
+     @param result Completed test result
+     */
+    testEnd(result: TestResult): void {
+        this.reported.add(this.getName(result.file))
+        this.write(this.render(result, ++this.count))
+    }
+
+    /*
+     Reports any planned tests that never ran as skipped so the plan is satisfied
+     @param _results All results from the run (unused, results are tracked incrementally)
+     */
+    runEnd(_results: TestResult[]): void {
+        for (const test of this.planned) {
+            if (!this.reported.has(this.getName(test))) {
+                this.reported.add(this.getName(test))
+                this.write(`ok ${++this.count} - ${this.getName(test)} # SKIP not run\n`)
+            }
+        }
+    }
+
+    /*
+     Renders the TAP block for a single test
+     @param result Test result
+     @param number Test number in completion order
+     @returns TAP lines including any YAML diagnostic block
+     */
+    render(result: TestResult, number: number): string {
+        const name = this.getName(result.file)
+
+        switch (result.status) {
+            case TestStatus.Passed:
+                return `ok ${number} - ${name}\n`
+
+            case TestStatus.Skipped: {
+                const reason = this.firstLine(result.output)
+                return `ok ${number} - ${name} # SKIP${reason ? ' ' + reason : ''}\n`
+            }
+
+            // A failing TODO test does not fail the TAP run
+            case TestStatus.XFail:
+                return `not ok ${number} - ${name} # TODO expected failure\n`
+
+            default:
+                return `not ok ${number} - ${name}\n` + this.renderDiagnostic(result)
+        }
+    }
+
+    /*
+     Renders the YAML diagnostic block for a failing test
+     @param result Failed or errored test result
+     @returns Indented YAML block
+     */
+    private renderDiagnostic(result: TestResult): string {
+        const lines = ['  ---']
+        const message =
+            this.firstLine(result.error || '') || (result.status === TestStatus.Error ? 'Test error' : 'Test failed')
+        lines.push(`  message: ${JSON.stringify(this.clean(message))}`)
+        const severity =
+            result.status === TestStatus.Error
+                ? 'error'
+                : result.status === TestStatus.Timeout || result.status === TestStatus.IdleTimeout
+                  ? result.status
+                  : result.status === TestStatus.XPass
+                    ? 'xpass'
+                    : result.sanitizer
+                      ? 'sanitizer'
+                      : isCrash(result)
+                        ? 'crash'
+                        : 'fail'
+        lines.push(`  severity: ${severity}`)
+        if (result.exitCode !== undefined) {
+            lines.push(`  exitCode: ${result.exitCode}`)
+        }
+        lines.push(`  duration_ms: ${Math.round(result.duration)}`)
+        if (result.truncated) {
+            lines.push('  truncated: true')
+        }
+
+        const output = this.clean([result.output, result.error].filter((text) => text).join('\n')).trimEnd()
+        if (output) {
+            lines.push('  output: |')
+            for (const line of output.split('\n')) {
+                lines.push(`    ${line}`)
+            }
+        }
+        lines.push('  ...')
+        return lines.join('\n') + '\n'
+    }
+
+    /*
+     Gets the display name for a test: its path relative to the root directory, with its case name and matrix cell
+     @param file Test file
+     @returns Relative path using forward slashes
+     */
+    private getName(file: TestFile): string {
+        // '#' introduces a TAP directive, so escape it in names
+        const path = relative(this.rootDir, file.path).replace(/\\/g, '/')
+        return Matrix.label(file, TestCases.label(file, path)).replace(/#/g, '\\#')
+    }
+
+    private firstLine(text: string): string {
+        return text.trim().split('\n')[0]?.trim() || ''
+    }
+
+    /*
+     Removes ANSI color sequences and carriage returns from captured output
+     @param text Text to clean
+     @returns Cleaned text
+     */
+    private clean(text: string): string {
+        return stripAnsi(text).replace(/\r/g, '')
+    }
+
+    /*
+     Writes a complete block to the output in a single call
+     @param text Text block to write
+     */
+    private write(text: string): void {
+        if (!this.path) {
+            process.stdout.write(text)
+            return
+        }
+        try {
+            appendFileSync(this.path, text)
+        } catch (error) {
+            console.warn(`⚠️  Failed to write TAP report ${this.path}: ${error}`)
+        }
+    }
+}
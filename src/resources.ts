@@ -0,0 +1,56 @@
+/*
+ TestResources - Named locks for tests that share an external resource
+
+ A test declares the resources it uses with the "testme: resource NAME, ..." directive or the
+ execution.resources configuration key. Tests that declare the same resource never run at the same
+ time, while tests with different resources (or none) still run in parallel. Waiting tests are
+ served in the order they asked. A test with several resources takes their locks in sorted name
+ order, so two tests that each need the other's resource cannot deadlock.
+ */
+export class TestResources {
+    // Tail of the queue of holders and waiters for each resource
+    private static locks = new Map<string, Promise<void>>()
+
+    /*
+     Acquires the locks of a test's resources, waiting while other tests hold them
+     @param names Resource names (duplicates are ignored)
+     @returns Function that releases every lock acquired
+     */
+    static async acquire(names: string[]): Promise<() => void> {
+        const releases: Array<() => void> = []
+        for (const name of [...new Set(names)].sort()) {
+            releases.push(await this.lock(name))
+        }
+        return () => releases.reverse().forEach((release) => release())
+    }
+
+    /*
+     Gets the resources of a test
+     @param configured Resources from execution.resources
+     @param declared Resources from the test's resource directive
+     @returns Combined resource names
+     */
+    static getNames(configured?: string[], declared?: string[]): string[] {
+        return [...new Set([...(configured || []), ...(declared || [])])]
+    }
+
+    /*
+     Waits for a resource and takes its lock
+     @param name Resource name
+     @returns Function that releases the lock
+     */
+    private static async lock(name: string): Promise<() => void> {
+        const previous = this.locks.get(name) || Promise.resolve()
+        let unlock: () => void = () => {}
+        const held = new Promise<void>((resolve) => (unlock = resolve))
+        const tail = previous.then(() => held)
+        this.locks.set(name, tail)
+        await previous
+        return () => {
+            unlock()
+            if (this.locks.get(name) === tail) {
+                this.locks.delete(name)
+            }
+        }
+    }
+}
@@ -1,7 +1,17 @@
-import type {TestFile, TestResult, TestConfig, TestHandler, TestSuite, DiscoveryOptions} from './types.ts'
+import type {
+    BuildResult,
+    TestFile,
+    TestResult,
+    TestConfig,
+    TestDirectives,
+    TestHandler,
+    TestSuite,
+    DiscoveryOptions,
+    Reporter,
+} from './types.ts'
 import {TestStatus, TestType} from './types.ts'
 import {TestDiscovery} from './discovery.ts'
-import {ArtifactManager} from './artifacts.ts'
+import {ARTIFACT_DIR, ArtifactManager} from './artifacts.ts'
 import {TestReporter} from './reporter.ts'
 import {
     ShellTestHandler,
@@ -11,8 +21,37 @@ import {
     EjscriptTestHandler,
     PythonTestHandler,
     GoTestHandler,
+    RustTestHandler,
+    CustomTestHandler,
 } from './handlers/index.ts'
 import {ConfigManager} from './config.ts'
+import {EventStream} from './events.ts'
+import {TestLogs} from './logdir.ts'
+import {ExpectedOutput} from './expected.ts'
+import {Benchmarks} from './bench.ts'
+import {HarnessProtocol} from './protocol.ts'
+import {Directives} from './directives.ts'
+import {TestPorts} from './ports.ts'
+import {TestTmp} from './tmp.ts'
+import {TestResources} from './resources.ts'
+import {RunProgress} from './progress.ts'
+import {TestCases} from './cases.ts'
+import {TestBuilds} from './builds.ts'
+import {TestOrder} from './order.ts'
+import {TestSuites} from './suites.ts'
+import {TestTags} from './tags.ts'
+import {ProcessManager} from './platform/process.ts'
+import {BaseTestHandler} from './handlers/base.ts'
+import {DryRun} from './utils/dry-run.ts'
+import {parseAssertions} from './utils/assertion-counter.ts'
+import {existsSync} from 'fs'
+import {rm} from 'fs/promises'
+import {sep} from 'path'
+
+/*
+ Choice made at a step mode prompt
+ */
+type StepAction = 'next' | 'rerun' | 'skip' | 'quit'
 
 /*
  TestRunner - Core test execution orchestrator
@@ -45,6 +84,18 @@ import {ConfigManager} from './config.ts'
 export class TestRunner {
     private artifactManager: ArtifactManager
     private shouldStopCallback: (() => boolean) | null = null
+    private reporter: Reporter | null = null
+    private abortedBy: TestResult | null = null
+    private failureCount: number = 0
+    private failureLimitReached: boolean = false
+    private stepQuit: boolean = false
+    private readInput: (message: string) => string | null = (message) => prompt(message) // Bun's built-in prompt
+    private builds: TestBuilds | null = null
+    private order: TestOrder = new TestOrder()
+    private settings: Record<string, unknown> = {}
+    private phases: {build: number; run: number} | null = null
+    private runStart: number = 0
+    private runEnd: number = 0
 
     /*
    Creates a new TestRunner instance
@@ -62,6 +113,85 @@ export class TestRunner {
         this.shouldStopCallback = callback
     }
 
+    /*
+   Sets the reporter told as each test starts and completes (e.g., for incremental reports)
+   @param reporter Reporter, or null to stop reporting
+   */
+    setReporter(reporter: Reporter | null): void {
+        this.reporter = reporter
+    }
+
+    /*
+   Sets the ordering of the tests of a run declared with "testme: after"
+   @param order Ordering created from every test run planned
+   */
+    setOrder(order: TestOrder): void {
+        this.order = order
+    }
+
+    /*
+   Sets the --set overrides applied over each test's directory configuration
+   @param settings Values keyed by dotted configuration key
+   */
+    setSettings(settings: Record<string, unknown> = {}): void {
+        this.settings = settings
+    }
+
+    /*
+   Sets the function that reads the user's choice in step mode (e.g., to script the answers)
+   @param reader Function that shows a message and returns the line entered, or null at the end of input
+   */
+    setPromptReader(reader: (message: string) => string | null): void {
+        this.readInput = reader
+    }
+
+    /*
+   Gets the failed test that aborted the run in fail-fast mode
+   @returns The failing result, or null if the run was not aborted
+   */
+    getAbortedBy(): TestResult | null {
+        return this.abortedBy
+    }
+
+    /*
+   Checks if the run stopped because it reached the --max-failures limit
+   @returns True if the failure limit was reached
+   */
+    isFailureLimitReached(): boolean {
+        return this.failureLimitReached
+    }
+
+    /*
+   Checks if the user quit the run from a step mode prompt
+   @returns True if the run was quit
+   */
+    isStepQuit(): boolean {
+        return this.stepQuit
+    }
+
+    /*
+   Clears the fail-fast, failure limit and step quit state and the phase timings before a new run
+   */
+    resetAbort(): void {
+        this.abortedBy = null
+        this.failureCount = 0
+        this.failureLimitReached = false
+        this.stepQuit = false
+        this.phases = null
+    }
+
+    /*
+   Publishes a completed test result to the event feed, the test logs and the reporter
+   @param result Completed test result
+   */
+    notifyResult(result: TestResult): void {
+        this.order.complete(result)
+        EventStream.emitTestEnd(result)
+        TestLogs.write(result)
+        RunProgress.record(result)
+        this.reporter?.testEnd(result)
+    }
+
     /*
    Discovers all test files matching the given options
    @param options Discovery options including patterns, root directory, and exclusions
@@ -74,10 +204,18 @@ export class TestRunner {
     /*
    Runs all tests in the test suite
    Handles parallel or sequential execution based on configuration
-   @param testSuite Test suite containing tests and configuration
+   @param suite Test suite containing tests and configuration
    @returns Promise resolving to array of test results
    */
-    async runTests(testSuite: TestSuite): Promise<TestResult[]> {
+    async runTests(suite: TestSuite): Promise<TestResult[]> {
+        // Parameterized tests run once per case, each as an independent test
+        const testSuite = {...suite, tests: await TestCases.expand(suite.tests)}
+        RunProgress.expand(testSuite.tests.length - suite.tests.length)
+
+        // --verbose-on-fail prints a test's output once its status is known, so it is never streamed
+        if (testSuite.config.output?.verboseOnFail) {
+            testSuite.config = {...testSuite.config, output: {...testSuite.config.output, live: false}}
+        }
         const reporter = new TestReporter(testSuite.config)
 
         // Only show "Running tests..." if not in quiet mode and we have tests to run
@@ -85,70 +223,163 @@ export class TestRunner {
             reporter.reportTestsStarting()
         }
 
-        if (testSuite.config.execution?.parallel) {
-            return await this.runTestsParallel(testSuite, reporter)
-        } else {
-            return await this.runTestsSequential(testSuite, reporter)
+        return await this.runSuite(testSuite, reporter)
+    }
+
+    /*
+   Runs a test suite in parallel or sequentially
+   With --build-workers, tests are compiled by their own pool of workers and run as their builds complete
+   Tests are sorted so prerequisites declared with "testme: after" run first
+   @param testSuite Test suite containing tests and configuration
+   @param reporter Reporter for progress updates
+   @returns Promise resolving to array of test results
+   */
+    private async runSuite(suite: TestSuite, reporter: TestReporter): Promise<TestResult[]> {
+        const testSuite = {...suite, tests: this.order.begin(suite.tests)}
+        const buildWorkers = testSuite.config.execution?.buildWorkers
+        if (buildWorkers && !testSuite.config.execution?.debugMode && !DryRun.isEnabled()) {
+            const build = (file: TestFile) => this.buildTest(file, testSuite.config)
+            this.builds = new TestBuilds(testSuite.tests, buildWorkers, build)
+            this.runStart = this.runEnd = 0
+        }
+        try {
+            if (testSuite.config.execution?.parallel) {
+                return await this.runTestsParallel(testSuite, reporter)
+            } else {
+                return await this.runTestsSequential(testSuite, reporter)
+            }
+        } finally {
+            if (this.builds) {
+                // Tests are run once built, so the phases overlap. Each is timed from its first start to its last end.
+                this.builds.stop()
+                this.phases = {
+                    build: (this.phases?.build ?? 0) + this.builds.getDuration(),
+                    run: (this.phases?.run ?? 0) + Math.max(0, this.runEnd - this.runStart),
+                }
+                this.builds = null
+            }
         }
     }
 
-    async cleanArtifacts(rootDir: string): Promise<void> {
-        await this.artifactManager.cleanAllArtifacts(rootDir)
+    /*
+   Gets the wall clock times of the build and run phases of a run with --build-workers
+   @returns Phase times in milliseconds, or null if tests were not built ahead of running
+   */
+    getPhases(): {build: number; run: number} | null {
+        return this.phases
+    }
+
+    /*
+   Removes the artifact directories under the test root and TestMe's leftover temporary directories (--clean)
+   @param rootDir Test root directory
+   @param config Root configuration, whose execution.artifactDir and build.dir are removed as well as .testme
+   @param dryRun List the directories without removing them (--clean-dry-run)
+   @returns Paths of the directories removed, or that would be removed
+   */
+    async cleanArtifacts(rootDir: string, config?: TestConfig, dryRun = false): Promise<string[]> {
+        const names = [...new Set([ARTIFACT_DIR, config?.execution?.artifactDir || ARTIFACT_DIR])]
+        const artifacts = await this.artifactManager.cleanAllArtifacts(rootDir, names, dryRun)
+
+        // The build directory may be inside a removed .testme directory. It is never removed if it holds the
+        // test root or the config directory, so a build.dir of '.' cannot delete sources.
+        const {buildDir, buildRoot} = TestDiscovery.getArtifactOptions(config || {})
+        const within = (dir: string, parent: string) => dir === parent || dir.startsWith(parent + sep)
+        if (
+            buildDir &&
+            existsSync(buildDir) &&
+            !artifacts.some((dir) => within(buildDir, dir)) &&
+            ![rootDir, buildRoot!].some((dir) => within(dir, buildDir))
+        ) {
+            if (!dryRun) {
+                await rm(buildDir, {recursive: true, force: true})
+            }
+            artifacts.push(buildDir)
+        }
+        return [...artifacts, ...(await TestTmp.clean(dryRun))]
     }
 
     private async runTestsSequential(testSuite: TestSuite, reporter: TestReporter): Promise<TestResult[]> {
         const results: TestResult[] = []
 
-        for (let i = 0; i < testSuite.tests.length; i++) {
-            // Check if we should stop (Ctrl+C pressed)
-            if (this.shouldStopCallback && this.shouldStopCallback()) {
+        const tests = testSuite.tests
+        const stepMode = testSuite.config.execution?.stepMode
+
+        for (let i = 0; i < tests.length; i++) {
+            // Check if we should stop (Ctrl+C pressed, aborted by --fail-fast, --max-failures reached or step quit)
+            if (
+                (this.shouldStopCallback && this.shouldStopCallback()) ||
+                this.abortedBy ||
+                this.failureLimitReached ||
+                this.stepQuit
+            ) {
                 break
             }
 
-            const testFile = testSuite.tests[i]
+            const testFile = tests[i]
+            let result = await this.runSequentialTest(testFile, testSuite.config, reporter)
 
-            // Handle step mode prompting
-            if (testSuite.config.execution?.stepMode) {
-                const shouldSkip = await this.promptForNextTest(testFile)
-
-                if (shouldSkip) {
-                    // Create a skipped result
-                    const skippedResult = {
-                        file: testFile,
-                        status: TestStatus.Skipped,
-                        duration: 0,
-                        output: 'Test skipped by user in step mode',
-                    }
-                    results.push(skippedResult)
-
-                    if (!this.isQuietMode(testSuite.config)) {
-                        reporter.reportProgress(skippedResult)
-                    }
-                    continue
-                }
-            }
-
-            // Show test starting (interactive animation)
-            if (!this.isQuietMode(testSuite.config)) {
-                reporter.reportTestStarting(testFile)
+            // In step mode the result is only recorded once the user moves on, so a re-run replaces it
+            let action: StepAction = stepMode ? this.promptForStep(testFile, tests[i + 1], true) : 'next'
+            while (action === 'rerun') {
+                result = await this.runSequentialTest(testFile, testSuite.config, reporter)
+                action = this.promptForStep(testFile, tests[i + 1], true)
             }
-
-            const result = await this.executeTest(testFile, testSuite.config)
             results.push(result)
-
-            if (!this.isQuietMode(testSuite.config)) {
-                reporter.reportProgress(result)
-            }
+            this.notifyResult(result)
 
             // Stop immediately if test failed and stopOnFailure is enabled
-            if (testSuite.config.execution?.stopOnFailure && result.status === TestStatus.Failed) {
+            if (testSuite.config.execution?.stopOnFailure && !result.file.quarantined && this.isFailure(result)) {
+                break
+            }
+            if (this.checkFailFast(result, testSuite.config) || this.checkMaxFailures(result, testSuite.config)) {
                 break
             }
+
+            while (action === 'skip' && i + 1 < tests.length) {
+                const skipped = {
+                    file: tests[++i]!,
+                    status: TestStatus.Skipped,
+                    duration: 0,
+                    output: 'Test skipped by user in step mode',
+                }
+                results.push(skipped)
+                this.notifyResult(skipped)
+                if (!this.isQuietMode(testSuite.config)) {
+                    reporter.reportProgress(skipped)
+                }
+                action = i + 1 < tests.length ? this.promptForStep(testFile, tests[i + 1], false) : 'next'
+            }
+            if (action === 'quit') {
+                this.stepQuit = true
+            }
         }
 
         return results
     }
 
+    /*
+   Runs one test of a sequential run and reports its progress
+   @param testFile Test to run
+   @param config Configuration of the run
+   @param reporter Console reporter
+   @returns Test result
+   */
+    private async runSequentialTest(
+        testFile: TestFile,
+        config: TestConfig,
+        reporter: TestReporter
+    ): Promise<TestResult> {
+        // Show test starting (interactive animation)
+        if (!this.isQuietMode(config)) {
+            reporter.reportTestStarting(testFile)
+        }
+        const result = await this.executeTest(testFile, config)
+        if (!this.isQuietMode(config)) {
+            reporter.reportProgress(result)
+        }
+        return result
+    }
+
     /*
    Runs tests in parallel using a worker pool pattern
 
@@ -166,42 +397,73 @@ export class TestRunner {
    @returns Promise resolving to array of test results
    */
     private async runTestsParallel(testSuite: TestSuite, reporter: TestReporter): Promise<TestResult[]> {
-        const workers = testSuite.config.execution?.workers || 4
+        const workers = testSuite.config.execution?.workers || ConfigManager.getDefaultWorkers()
+        const builds = this.builds
         const results: TestResult[] = []
-        const testsQueue = [...testSuite.tests]
+        const testsQueue = builds ? [] : [...testSuite.tests]
         const activeWorkers: Promise<void>[] = []
         let shouldStop = false // Shared flag to signal workers to stop
 
+        // Live output from concurrent tests would interleave, so buffer it instead and print each
+        // test's output as a single block when the test completes
+        const bufferOutput =
+            testSuite.config.output?.live === true &&
+            !this.isQuietMode(testSuite.config) &&
+            Math.min(workers, testSuite.tests.length) > 1
+        const config = bufferOutput
+            ? {...testSuite.config, output: {...testSuite.config.output!, live: false}}
+            : testSuite.config
+
         // Worker function that processes tests from the queue
         // Each worker runs in a loop, continuously pulling tests until queue is empty
+        // With --build-workers, tests are taken in the order their builds complete. Tests whose prerequisites
+        // have not completed wait in the queue while workers run the tests after them.
         const worker = async () => {
-            while (testsQueue.length > 0 && !shouldStop) {
+            while (!shouldStop && !this.failureLimitReached) {
                 // Check if we should stop (Ctrl+C pressed)
                 if (this.shouldStopCallback && this.shouldStopCallback()) {
                     shouldStop = true
                     testsQueue.length = 0
+                    builds?.stop()
                     break
                 }
 
-                const testFile = testsQueue.shift()
-                if (!testFile) break
+                const testFile = await this.order.next(testsQueue, builds ? () => builds.next() : undefined)
+                if (!testFile || shouldStop || this.failureLimitReached) break
 
                 // Show test starting (interactive animation)
                 if (!this.isQuietMode(testSuite.config)) {
                     reporter.reportTestStarting(testFile)
                 }
 
-                const result = await this.executeTest(testFile, testSuite.config)
+                const result = await this.executeTest(testFile, config)
+
+                // Tests killed because another test aborted the run were not executed to completion. Tests that
+                // completed on their own while the run was being aborted are kept.
+                if (this.abortedBy && this.wasKilled(result)) {
+                    this.order.complete(result)
+                    break
+                }
                 results.push(result)
+                this.notifyResult(result)
 
                 if (!this.isQuietMode(testSuite.config)) {
                     reporter.reportProgress(result)
                 }
+                if (bufferOutput) {
+                    reporter.reportOutput(result)
+                }
 
                 // Stop all workers if test failed and stopOnFailure is enabled
-                if (testSuite.config.execution?.stopOnFailure && result.status === TestStatus.Failed) {
+                if (testSuite.config.execution?.stopOnFailure && !result.file.quarantined && this.isFailure(result)) {
                     shouldStop = true
                     testsQueue.length = 0 // Clear queue to stop other workers
+                    builds?.stop()
+                }
+                if (this.checkFailFast(result, testSuite.config) || this.checkMaxFailures(result, testSuite.config)) {
+                    shouldStop = true
+                    testsQueue.length = 0
+                    builds?.stop()
                 }
             }
         }
@@ -217,7 +479,19 @@ export class TestRunner {
         return results
     }
 
+    /*
+   Executes a single test, attributing any output events to the test
+   @param testFile Test file to execute
+   @param globalConfig Global configuration with CLI overrides applied
+   @returns Promise resolving to the test result
+   */
     private async executeTest(testFile: TestFile, globalConfig: TestConfig): Promise<TestResult> {
+        EventStream.emit('test-start', {type: testFile.type}, testFile)
+        this.reporter?.testStart?.(testFile)
+        return await EventStream.runWithTest(testFile, () => this.runTestWithHandler(testFile, globalConfig))
+    }
+
+    private async runTestWithHandler(testFile: TestFile, globalConfig: TestConfig): Promise<TestResult> {
         const handler = this.createFreshHandler(testFile)
 
         if (!handler) {
@@ -230,25 +504,108 @@ export class TestRunner {
             }
         }
 
+        if (testFile.casesError) {
+            return {file: testFile, status: TestStatus.Error, duration: 0, output: '', error: testFile.casesError}
+        }
+
+        // Skip tests whose "testme: after" prerequisites did not pass
+        const blocked = this.order.getSkipReason(testFile)
+        if (blocked) {
+            return {file: testFile, status: TestStatus.Skipped, duration: 0, output: blocked}
+        }
+
         try {
             // Find the nearest config file to this specific test file
             const testSpecificConfig = await this.findConfigForTest(testFile, globalConfig)
 
+            // Skip tests that do not run on this platform
+            const directives = await Directives.read(testFile.path)
+            const platformReason = Directives.checkPlatform(directives, testSpecificConfig.platform)
+            if (platformReason) {
+                return {file: testFile, status: TestStatus.Skipped, duration: 0, output: platformReason}
+            }
+
+            // Skip (or fail with --strict) tests whose required tools are not installed
+            const missing = await Directives.findMissingTools(directives)
+            if (missing.length > 0) {
+                const message = `Required tool(s) not found on PATH: ${missing.join(', ')}`
+                if (testSpecificConfig.execution?.strict) {
+                    return {file: testFile, status: TestStatus.Failed, duration: 0, output: '', error: message}
+                }
+                return {file: testFile, status: TestStatus.Skipped, duration: 0, output: message}
+            }
+
+            // Arguments and stdin for the test program
+            let testConfig = Directives.applyTestInput(testSpecificConfig, directives, testFile)
+            const stdin = testConfig.execution?.stdin
+            if (stdin && !existsSync(stdin)) {
+                const error = `Stdin file not found: ${stdin}`
+                return {file: testFile, status: TestStatus.Error, duration: 0, output: '', error}
+            }
+            const chdir = testConfig.execution?.chdir
+            if (chdir && !existsSync(chdir)) {
+                const error = `Working directory not found: ${chdir}`
+                return {file: testFile, status: TestStatus.Error, duration: 0, output: '', error}
+            }
+
             // Prepare test (if needed)
             if (handler.prepare) {
                 await handler.prepare(testFile)
             }
 
-            // Execute the test with its specific config
-            const result = await handler.execute(testFile, testSpecificConfig)
+            // A test built ahead of running (--build-workers) reuses its build
+            const build = await this.builds?.wait(testFile)
+            if (build && !build.success) {
+                const {duration, output, error} = build
+                return {file: testFile, status: TestStatus.Error, duration, output, error}
+            }
+            if (build) {
+                testConfig = {...testConfig, execution: {...testConfig.execution!, rebuild: false}}
+            }
+
+            // Wait for tests using the same shared resources, then reserve free localhost ports and a private
+            // temporary directory until the test completes, including retries. The directory is removed after
+            // any timed out process has been killed.
+            const resources = TestResources.getNames(testConfig.execution?.resources, directives.resources)
+            const unlock = await TestResources.acquire(resources)
+            let tmpDir: string | undefined
+            let ports: number[] = []
+            let keep = false
+            let result: TestResult
+            try {
+                tmpDir = await TestTmp.create(testFile)
+                ports = directives.ports ? await TestPorts.allocate(directives.ports) : []
+
+                // Execute the test with its specific config, retrying failures if configured
+                const execution = {...testConfig.execution!, tmpDir, ...(ports.length > 0 && {ports})}
+                this.runStart ||= Date.now()
+                result = await this.executeWithRetries(handler, testFile, {...testConfig, execution}, directives)
+                this.runEnd = Date.now()
+                keep = TestTmp.isKept(testConfig, result)
+            } finally {
+                TestPorts.release(ports)
+                if (tmpDir) {
+                    await TestTmp.release(tmpDir, keep)
+                }
+                unlock()
+            }
+            if (keep) {
+                result = {...result, output: [result.output, `Temporary directory kept: ${tmpDir}`].join('\n').trim()}
+            }
 
             // Cleanup (if needed)
             // Artifacts are kept by default to enable compilation caching for C tests
             // Use --clean to remove all artifacts when desired
-            // Only cleanup if keepArtifacts is explicitly false (not undefined/true)
+            // Only cleanup if keepArtifacts is explicitly false (not undefined/true) or with --keep-on-fail
+            const keepOnFail = testSpecificConfig.execution?.keepOnFail === true
+            if (keepOnFail && !TestTmp.isPassed(result) && existsSync(testFile.artifactDir)) {
+                const kept = `Artifacts kept: ${testFile.artifactDir}`
+                result = {...result, output: [result.output, kept].join('\n').trim()}
+            }
             if (handler.cleanup) {
-                const shouldCleanup =
-                    result.status === TestStatus.Passed && testSpecificConfig.execution?.keepArtifacts === false
+                const shouldCleanup = keepOnFail
+                    ? TestTmp.isPassed(result)
+                    : result.status === TestStatus.Passed && testSpecificConfig.execution?.keepArtifacts === false
                 if (shouldCleanup) {
                     try {
                         await handler.cleanup(testFile, testSpecificConfig)
@@ -275,6 +632,89 @@ export class TestRunner {
         }
     }
 
+    /*
+   Compiles a test ahead of running it, for the build workers of --build-workers
+   Tests that do not run on this platform are not built.
+   @param testFile Test to build
+   @param globalConfig Global configuration with CLI overrides applied
+   @returns Build result, or null if the test's handler has nothing to build
+   */
+    private async buildTest(testFile: TestFile, globalConfig: TestConfig): Promise<BuildResult | null> {
+        const handler = this.createFreshHandler(testFile)
+        if (!handler?.build || testFile.casesError) {
+            return null
+        }
+        const testSpecificConfig = await this.findConfigForTest(testFile, globalConfig)
+        const directives = await Directives.read(testFile.path)
+        if (Directives.checkPlatform(directives, testSpecificConfig.platform)) {
+            return null
+        }
+        if (handler.prepare) {
+            await handler.prepare(testFile)
+        }
+        return await handler.build(testFile, Directives.applyTestInput(testSpecificConfig, directives, testFile))
+    }
+
+    /*
+   Executes a test, re-running it on failure up to execution.retries times
+   Retries reuse the compiled artifact from the first attempt (rebuild is disabled after the first attempt).
+   Errors such as compile failures are not retried.
+   @param handler Handler for the test
+   @param testFile Test file to execute
+   @param config Test-specific configuration
+   @param directives Inline directives of the test
+   @returns Result of the last attempt, with attempts and flaky set when retries are enabled
+   */
+    private async executeWithRetries(
+        handler: TestHandler,
+        testFile: TestFile,
+        config: TestConfig,
+        directives: TestDirectives
+    ): Promise<TestResult> {
+        const retries = config.execution?.retries ?? 0
+        let result = await this.executeAttempt(handler, testFile, config, directives)
+        if (retries <= 0) {
+            return result
+        }
+        const retryConfig = {...config, execution: {...config.execution!, rebuild: false}}
+        let attempts = 1
+        while (this.isFailure(result) && attempts <= retries) {
+            if ((this.shouldStopCallback && this.shouldStopCallback()) || this.abortedBy) {
+                break
+            }
+            attempts++
+            EventStream.emit('test-retry', {attempt: attempts, status: result.status}, testFile)
+            result = await this.executeAttempt(handler, testFile, retryConfig, directives)
+        }
+        return {...result, attempts, flaky: attempts > 1 && result.status === TestStatus.Passed}
+    }
+
+    /*
+   Executes a single attempt of a test, checks its exit code, collects its protocol sub-results, compares its stdout
+   with any .expected file and benchmark baseline, counts its assertion markers (parse.assertions) and applies an
+   xfail directive
+   With --dry-run the handler only prints its commands, so a successful attempt is reported as skipped
+   @param handler Handler for the test
+   @param testFile Test file to execute
+   @param config Test-specific configuration
+   @param directives Inline directives of the test
+   @returns Test result
+   */
+    private async executeAttempt(
+        handler: TestHandler,
+        testFile: TestFile,
+        config: TestConfig,
+        directives: TestDirectives
+    ): Promise<TestResult> {
+        const result = await handler.execute(testFile, config)
+        if (DryRun.isEnabled() && result.status === TestStatus.Passed) {
+            return {...result, status: TestStatus.Skipped, output: 'Dry run: not executed'}
+        }
+        const exited = HarnessProtocol.apply(Directives.applyExpectedExit(result, directives, config))
+        const checked = parseAssertions(Benchmarks.check(await ExpectedOutput.check(exited, config), config), config)
+        return Directives.applyExpectedFailure(checked, directives)
+    }
+
     /*
    Creates a fresh handler instance for each test to avoid shared state conflicts
    @param testFile Test file to create handler for
@@ -298,26 +738,61 @@ export class TestRunner {
                 return new PythonTestHandler()
             case TestType.Go:
                 return new GoTestHandler()
+            case TestType.Rust:
+                return new RustTestHandler()
+            case TestType.Custom:
+                return new CustomTestHandler()
             default:
                 return undefined
         }
     }
 
+    /*
+   Lists the tests that would run without executing them
+   Applies CLI patterns, --filter/--exclude, --suite, --tags, enable and depth settings, then prints one test path per
+   line.
+   With --list-json, prints a JSON array including each test's language and resolved timeout.
+   @param options Discovery options
+   @param config Root configuration with CLI overrides applied
+   @param invocationDir Directory tm was invoked from (paths are printed relative to it)
+   @param cliPatterns Test patterns given on the command line
+   @param listOptions CLI filter, exclude, suites, tags, timeout override and JSON output selection
+   */
     async listTests(
         options: DiscoveryOptions,
         config: TestConfig,
         invocationDir?: string,
-        cliPatterns?: string[]
+        cliPatterns?: string[],
+        listOptions?: {
+            filter?: string
+            exclude?: string
+            suite?: string[]
+            tags?: string[]
+            notTags?: string[]
+            timeout?: number
+            listJson?: boolean
+        }
     ): Promise<void> {
-        let tests = await this.discoverTests(options)
+        const discovered = await this.discoverTests(options)
+        let tests = discovered
 
         // If CLI patterns are provided, apply them as an additional filter
         if (cliPatterns && cliPatterns.length > 0) {
             tests = TestDiscovery.filterTestsByPatterns(tests, cliPatterns, options.rootDir)
         }
+        if (listOptions?.filter || listOptions?.exclude) {
+            tests = TestDiscovery.filterTestsByRegex(tests, options.rootDir, listOptions.filter, listOptions.exclude)
+        }
+        if (listOptions?.suite) {
+            tests = TestSuites.select(tests, listOptions.suite, await TestSuites.collect(discovered))
+        }
+        if (listOptions?.tags || listOptions?.notTags) {
+            await TestTags.mark(tests)
+            tests = TestTags.select(tests, listOptions.tags, listOptions.notTags)
+        }
 
         if (!tests.length) {
-            console.log('No tests discovered')
+            console.log(listOptions?.listJson ? '[]' : 'No tests discovered')
             return
         }
 
@@ -336,9 +811,24 @@ export class TestRunner {
             testGroups.get(configDir)!.push(test)
         }
 
-        // Check each configuration group for enable status
+        // Check each configuration group for enable status and depth requirement
+        const testConfigs = new Map<TestFile, TestConfig>()
         for (const [configDir, groupTests] of testGroups) {
-            const groupConfig = await ConfigManager.findConfig(configDir)
+            const groupConfig = ConfigManager.applySettings(await ConfigManager.findConfig(configDir), this.settings)
+            for (const test of groupTests) {
+                testConfigs.set(test, groupConfig)
+            }
+
+            const requiredDepth = groupConfig.depth ?? 0
+            const currentDepth = config.execution?.depth ?? 0
+            if (currentDepth < requiredDepth) {
+                if (config.output?.verbose) {
+                    const relativeConfigDir =
+                        configDir === options.rootDir ? '.' : configDir.replace(options.rootDir + '/', '')
+                    console.log(`⏭️  Tests in: ${relativeConfigDir} (requires --depth ${requiredDepth})`)
+                }
+                continue
+            }
 
             if (groupConfig.enable === false) {
                 if (config.output?.verbose) {
@@ -383,7 +873,7 @@ export class TestRunner {
 
                         // Check if pattern matches any test in this group (by base name or full path)
                         return groupTests.some((test) => {
-                            const baseName = test.name.replace(/\.tst\.(c|js|ts|sh|ps1|bat|cmd|py|go|es)$/, '')
+                            const baseName = test.name.replace(/\.tst\.(c|js|ts|sh|ps1|bat|cmd|py|go|rs|es)$/, '')
                             if (baseName === p) return true
                             if (test.name === p) return true
 
@@ -418,7 +908,7 @@ export class TestRunner {
         }
 
         if (!enabledTests.length) {
-            console.log('No enabled tests discovered')
+            console.log(listOptions?.listJson ? '[]' : 'No enabled tests discovered')
             return
         }
 
@@ -431,7 +921,20 @@ export class TestRunner {
         }))
 
         const reporter = new TestReporter(config, invocationDir || options.rootDir)
-        reporter.reportDiscoveredTests(mockResults)
+        if (listOptions?.listJson) {
+            // Resolve the timeout each test would run with, including any --timeout override
+            const timeouts = new Map<TestFile, number>()
+            for (const test of enabledTests) {
+                let testConfig = testConfigs.get(test)!
+                if (listOptions.timeout !== undefined) {
+                    testConfig = {...testConfig, execution: {...testConfig.execution!, timeout: listOptions.timeout}}
+                }
+                timeouts.set(test, (BaseTestHandler.getTimeout(testConfig, test) ?? 0) / 1000)
+            }
+            reporter.reportDiscoveredTestsJson(mockResults, timeouts)
+        } else {
+            reporter.reportDiscoveredTests(mockResults)
+        }
     }
 
     async executeTestSuite(rootDir: string, patterns: string[], config: TestConfig): Promise<TestResult[]> {
@@ -440,6 +943,8 @@ export class TestRunner {
             rootDir,
             patterns: patterns.length ? patterns : config.patterns?.include || [],
             excludePatterns: config.patterns?.exclude || [],
+            ...TestDiscovery.getArtifactOptions(config),
+            ...TestDiscovery.getWalkOptions(config),
         })
 
         if (!tests.length) {
@@ -465,11 +970,17 @@ export class TestRunner {
         if (!this.isQuietMode(config)) {
             // Check if there are any failures or errors
             const hasFailures = results.some(
-                (result) => result.status === TestStatus.Failed || result.status === TestStatus.Error
+                (result) =>
+                    result.status === TestStatus.Failed ||
+                    result.status === TestStatus.Error ||
+                    result.status === TestStatus.Timeout ||
+                    result.status === TestStatus.IdleTimeout ||
+                    result.status === TestStatus.XPass
             )
 
             // If there are failures and we're not already in verbose mode, re-report with verbose mode showing only errors
-            if (hasFailures && !config.output?.verbose) {
+            // (--verbose-on-fail has already printed each failure as it completed)
+            if (hasFailures && !config.output?.verbose && !config.output?.verboseOnFail) {
                 const verboseConfig = {
                     ...config,
                     output: {
@@ -481,9 +992,11 @@ export class TestRunner {
                     },
                 }
                 const verboseReporter = new TestReporter(verboseConfig)
+                verboseReporter.setPhases(this.phases)
                 verboseReporter.reportResults(results, elapsedTime)
             } else {
                 const reporter = new TestReporter(config)
+                reporter.setPhases(this.phases)
                 reporter.reportResults(results, elapsedTime)
             }
         }
@@ -492,13 +1005,86 @@ export class TestRunner {
     }
 
     getExitCode(results: TestResult[]): number {
+        // Quarantined tests report their failures but do not fail the run
         const hasFailures = results.some(
-            (result) => result.status === TestStatus.Failed || result.status === TestStatus.Error
+            (result) =>
+                !result.file.quarantined &&
+                (result.status === TestStatus.Failed ||
+                    result.status === TestStatus.Error ||
+                    result.status === TestStatus.Timeout ||
+                    result.status === TestStatus.IdleTimeout ||
+                    result.status === TestStatus.XPass)
         )
 
         return hasFailures ? 1 : 0
     }
 
+    /*
+   Aborts the run if a test failed in fail-fast mode
+   Tests still running in other workers are killed with the same process group kill used for timeouts.
+   @param result Completed test result
+   @param config Configuration of the test's group
+   @returns True if this result aborted the run
+   */
+    private checkFailFast(result: TestResult, config: TestConfig): boolean {
+        if (this.abortedBy || !config.execution?.failFast || !this.isCountedFailure(result)) {
+            return false
+        }
+        this.abortedBy = result
+        ProcessManager.signalProcessGroups('SIGKILL')
+        return true
+    }
+
+    /*
+   Counts failures across the run and stops scheduling tests once --max-failures is reached
+   Unlike fail-fast, tests that are already running are allowed to finish.
+   @param result Completed test result
+   @param config Configuration of the test's group
+   @returns True if the failure limit has been reached
+   */
+    private checkMaxFailures(result: TestResult, config: TestConfig): boolean {
+        const limit = config.execution?.maxFailures
+        if (!limit || !this.isCountedFailure(result)) {
+            return false
+        }
+        this.failureCount++
+        if (this.failureCount >= limit) {
+            this.failureLimitReached = true
+        }
+        return this.failureLimitReached
+    }
+
+    /*
+   Checks if a test was killed rather than running to completion
+   @param result Test result
+   @returns True if the test timed out or was stopped by a signal
+   */
+    private wasKilled(result: TestResult): boolean {
+        return (
+            result.status === TestStatus.Timeout ||
+            result.status === TestStatus.IdleTimeout ||
+            result.signal !== undefined
+        )
+    }
+
+    /*
+   Checks if a result counts as a failure for stopOnFailure (failed or timed out)
+   @param result Test result
+   @returns True if the test failed or timed out
+   */
+    private isFailure(result: TestResult): boolean {
+        return [TestStatus.Failed, TestStatus.Timeout, TestStatus.IdleTimeout].includes(result.status)
+    }
+
+    /*
+   Checks if a result counts toward --fail-fast and --max-failures (a failure or error of a test not quarantined)
+   @param result Test result
+   @returns True if the result counts as a failure
+   */
+    private isCountedFailure(result: TestResult): boolean {
+        return !result.file.quarantined && (this.isFailure(result) || result.status === TestStatus.Error)
+    }
+
     private isQuietMode(config: TestConfig): boolean {
         return config.output?.quiet === true
     }
@@ -507,6 +1093,7 @@ export class TestRunner {
    Finds the most specific config file for a test file
    Walks up from the test file directory looking for testme.json5
    Falls back to global config if no specific config is found
+   --set overrides are applied over the directory config, so dedicated CLI options still win over them
    @param testFile Test file to find config for
    @param globalConfig Fallback global configuration with CLI overrides applied
    @returns Test-specific configuration with CLI overrides preserved
@@ -514,7 +1101,10 @@ export class TestRunner {
     private async findConfigForTest(testFile: TestFile, globalConfig: TestConfig): Promise<TestConfig> {
         try {
             // Look for config starting from the test file's directory
-            const testSpecificConfig = await ConfigManager.findConfig(testFile.directory)
+            const testSpecificConfig = ConfigManager.applySettings(
+                await ConfigManager.findConfig(testFile.directory),
+                this.settings
+            )
 
             // If we found a config and it has a configDir, merge with global CLI overrides
             if (testSpecificConfig.configDir) {
@@ -523,7 +1113,7 @@ export class TestRunner {
                     ...testSpecificConfig,
                     // Preserve execution settings that may have CLI overrides
                     execution: {
-                        timeout: testSpecificConfig.execution?.timeout ?? 30000,
+                        timeout: testSpecificConfig.execution?.timeout ?? 30,
                         parallel: testSpecificConfig.execution?.parallel ?? true,
                         ...testSpecificConfig.execution,
                         // Preserve CLI-specific overrides from global config
@@ -543,6 +1133,9 @@ export class TestRunner {
                         }),
                         ...(globalConfig.execution?.depth !== undefined && {depth: globalConfig.execution.depth}),
                         ...(globalConfig.execution?.workers !== undefined && {workers: globalConfig.execution.workers}),
+                        ...(globalConfig.execution?.buildWorkers !== undefined && {
+                            buildWorkers: globalConfig.execution.buildWorkers,
+                        }),
                         ...(globalConfig.execution?.iterations !== undefined && {
                             iterations: globalConfig.execution.iterations,
                         }),
@@ -550,6 +1143,27 @@ export class TestRunner {
                             duration: globalConfig.execution.duration,
                         }),
                         ...(globalConfig.execution?.rebuild && {rebuild: globalConfig.execution.rebuild}),
+                        ...(globalConfig.execution?.timeout !== undefined && {timeout: globalConfig.execution.timeout}),
+                        ...(globalConfig.execution?.idleTimeout !== undefined && {
+                            idleTimeout: globalConfig.execution.idleTimeout,
+                        }),
+                        ...(globalConfig.execution?.retries !== undefined && {retries: globalConfig.execution.retries}),
+                        ...(globalConfig.execution?.accept && {accept: globalConfig.execution.accept}),
+                        ...(globalConfig.execution?.bench && {bench: true}),
+                        ...(globalConfig.execution?.benchUpdate && {benchUpdate: true}),
+                        ...(globalConfig.execution?.asan && {asan: globalConfig.execution.asan}),
+                        ...(globalConfig.execution?.backtrace && {backtrace: true}),
+                        ...(globalConfig.execution?.cc && {cc: globalConfig.execution.cc}),
+                        ...(globalConfig.execution?.shell && {shell: globalConfig.execution.shell}),
+                        ...(globalConfig.execution?.target && {target: globalConfig.execution.target}),
+                        ...(globalConfig.execution?.remote && {remote: globalConfig.execution.remote}),
+                        ...(globalConfig.execution?.docker && {docker: globalConfig.execution.docker}),
+                        ...(globalConfig.execution?.keepTmp && {keepTmp: globalConfig.execution.keepTmp}),
+                        ...(globalConfig.execution?.keepTmpOnFail && {
+                            keepTmpOnFail: globalConfig.execution.keepTmpOnFail,
+                        }),
+                        ...(globalConfig.execution?.keepOnFail && {keepOnFail: true}),
+                        ...(globalConfig.execution?.strict && {strict: globalConfig.execution.strict}),
                     },
                     // Preserve output settings that may have CLI overrides
                     output: {
@@ -564,6 +1178,19 @@ export class TestRunner {
                             errorsOnly: globalConfig.output.errorsOnly,
                         }),
                         ...(globalConfig.output?.live !== undefined && {live: globalConfig.output.live}),
+                        ...(globalConfig.output?.verboseOnFail && {verboseOnFail: true}),
+                        ...(globalConfig.output?.colors !== undefined && {colors: globalConfig.output.colors}),
+                        ...(globalConfig.output?.maxBytes !== undefined && {maxBytes: globalConfig.output.maxBytes}),
+                    },
+                    // Preserve the --valgrind override while keeping the test's own suppressions and flags
+                    valgrind: {
+                        ...testSpecificConfig.valgrind,
+                        ...(globalConfig.valgrind?.enable && {enable: true}),
+                    },
+                    // Preserve the --coverage override
+                    coverage: {
+                        ...testSpecificConfig.coverage,
+                        ...(globalConfig.coverage?.enable && {enable: true}),
                     },
                     // Preserve environment variables from global config (including those from environment script)
                     environment: {
@@ -582,28 +1209,36 @@ export class TestRunner {
     }
 
     /*
-   Prompts user for input before running the next test in step mode
-   @param testFile The test file about to be executed
-   @returns Promise that resolves to true if test should be skipped, false to continue
+   Prompts the user for the next step after a test has run in step mode
+   At the end of input (e.g., stdin is not a terminal) the run continues.
+   @param last The test that just ran
+   @param next The next test, if any
+   @param rerun Whether the last test can still be re-run
+   @returns The chosen action
    */
-    private async promptForNextTest(testFile: TestFile): Promise<boolean> {
-        console.log(`\n📋 About to run: ${testFile.name}`)
-        console.log(`   Path: ${testFile.path}`)
-        console.log(`   Type: ${testFile.type}`)
-
-        // Use Bun's built-in prompt functionality
-        const input = prompt('Press Enter to continue, "s" to skip, or "q" to quit: ')
-
-        if (input === 'q' || input === 'quit') {
-            console.log('🛑 Test execution stopped by user')
-            process.exit(0)
-        } else if (input === 's' || input === 'skip') {
-            console.log('⏭️  Skipping test')
-            return true // Skip this test
+    private promptForStep(last: TestFile, next: TestFile | undefined, rerun: boolean): StepAction {
+        const choices = [next ? `Enter to run ${next.name}` : 'Enter to finish']
+        if (rerun) {
+            choices.push(`r to re-run ${last.name}`)
+        }
+        if (next) {
+            choices.push(`s to skip ${next.name}`)
+        }
+        choices.push('q to quit')
+
+        for (;;) {
+            const input = this.readInput(`\n⏯️  ${choices.join(', ')}:`)?.trim().toLowerCase()
+            if (!input) {
+                return 'next'
+            } else if ((input === 'r' || input === 'rerun') && rerun) {
+                return 'rerun'
+            } else if ((input === 's' || input === 'skip') && next) {
+                return 'skip'
+            } else if (input === 'q' || input === 'quit') {
+                return 'quit'
+            }
+            console.log(`Unknown choice "${input}"`)
         }
-
-        console.log('▶️  Running test...')
-        return false // Continue with test
     }
 
     /*
@@ -625,11 +1260,7 @@ export class TestRunner {
         const reporter = new TestReporter(config, invocationDir)
 
         // Execute tests
-        if (config.execution?.parallel) {
-            return await this.runTestsParallel(testSuite, reporter)
-        } else {
-            return await this.runTestsSequential(testSuite, reporter)
-        }
+        return await this.runSuite(testSuite, reporter)
     }
 
     /*
@@ -644,11 +1275,17 @@ export class TestRunner {
 
         // Check if there are any failures or errors
         const hasFailures = allResults.some(
-            (result) => result.status === TestStatus.Failed || result.status === TestStatus.Error
+            (result) =>
+                result.status === TestStatus.Failed ||
+                result.status === TestStatus.Error ||
+                result.status === TestStatus.Timeout ||
+                result.status === TestStatus.IdleTimeout ||
+                result.status === TestStatus.XPass
         )
 
         // If there are failures and we're not already in verbose mode, re-report with verbose mode showing only errors
-        if (hasFailures && !config.output?.verbose) {
+        // (--verbose-on-fail has already printed each failure as it completed)
+        if (hasFailures && !config.output?.verbose && !config.output?.verboseOnFail) {
             const verboseConfig = {
                 ...config,
                 output: {
@@ -660,8 +1297,10 @@ export class TestRunner {
                 },
             }
             const verboseReporter = new TestReporter(verboseConfig, invocationDir)
+            verboseReporter.setPhases(this.phases)
             verboseReporter.reportResults(allResults)
         } else {
+            reporter.setPhases(this.phases)
             reporter.reportResults(allResults)
         }
     }
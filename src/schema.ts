@@ -0,0 +1,482 @@
+/*
+ A problem found in a configuration file
+ */
+export type ConfigIssue = {
+    path: string // JSON path of the offending value (e.g., 'execution.timeout')
+    message: string
+    severity: 'error' | 'warning' // Unknown keys are warnings, wrong types and malformed values are errors
+}
+
+/*
+ Description of the values allowed for a configuration key
+ */
+type Schema =
+    | {
+          type: 'string' | 'number' | 'boolean'
+          values?: readonly (string | number | boolean)[]
+          min?: number
+          placeholders?: readonly string[] // {name} placeholders allowed in a string template
+      }
+    | {type: 'array'; items: Schema}
+    | {type: 'object'; keys?: Record<string, Schema>; additional?: Schema; required?: readonly string[]}
+    | {anyOf: Schema[]; expected: string}
+
+const text: Schema = {type: 'string'}
+const bool: Schema = {type: 'boolean'}
+const count: Schema = {type: 'number', min: 0}
+const texts: Schema = {type: 'array', items: text}
+const textOrTexts: Schema = {anyOf: [text, texts], expected: 'a string or array of strings'}
+
+const platformString: Schema = {
+    anyOf: [text, {type: 'object', keys: {windows: text, macosx: text, linux: text}}],
+    expected: 'a string or {windows, macosx, linux} object',
+}
+const platformFlags: Schema = {type: 'object', keys: {flags: texts, libraries: texts}}
+const compilerSettings: Schema = {
+    type: 'object',
+    keys: {flags: texts, libraries: texts, windows: platformFlags, macosx: platformFlags, linux: platformFlags},
+}
+const platformPatterns: Schema = {type: 'object', keys: {include: texts, exclude: texts}}
+const envValue: Schema = {
+    anyOf: [
+        text,
+        {type: 'number'},
+        bool,
+        {type: 'object', keys: {default: text, windows: text, macosx: text, linux: text}},
+    ],
+    expected: 'a text, number, boolean or {default, windows, macosx, linux} object',
+}
+const scalar: Schema = {anyOf: [text, {type: 'number'}, bool], expected: 'a string, number or boolean'}
+const envSection: Schema = {type: 'object', additional: scalar}
+const environment: Schema = {
+    type: 'object',
+    keys: {file: text, default: envSection, windows: envSection, macosx: envSection, linux: envSection},
+    additional: envValue,
+}
+
+const template: Schema = {type: 'string', placeholders: ['src', 'out', 'tmp', 'dir']}
+
+const normalizer: Schema = {
+    anyOf: [
+        {type: 'string', values: ['timestamps', 'tmp', 'pids', 'uuids', 'addresses', 'durations']},
+        {type: 'object', keys: {pattern: text, replace: text, flags: text}},
+    ],
+    expected: 'a built-in normalizer name or {pattern, replace, flags} object',
+}
+
+const CONFIG_SCHEMA: Schema = {
+    type: 'object',
+    keys: {
+        enable: {anyOf: [bool, {type: 'string', values: ['manual']}], expected: "true, false or 'manual'"},
+        depth: count,
+        maxDepth: count,
+        profile: text,
+        inherit: {anyOf: [bool, texts], expected: 'a boolean or array of keys'},
+        depends: texts,
+        platform: textOrTexts,
+        suite: textOrTexts,
+        matrix: {
+            type: 'object',
+            additional: {type: 'array', items: scalar},
+        },
+        setup: textOrTexts,
+        teardown: textOrTexts,
+        quarantine: text,
+        toolchain: {
+            type: 'object',
+            additional: {anyOf: [text, {type: 'number'}], expected: "a version such as '1.21'"},
+        },
+        compiler: {
+            type: 'object',
+            keys: {
+                cc: platformString,
+                pkgs: texts,
+                toolchain: {type: 'string', values: ['gcc', 'clang', 'msvc', 'mingw']},
+                c: {
+                    type: 'object',
+                    keys: {
+                        compiler: platformString,
+                        flags: texts,
+                        libraries: texts,
+                        gcc: compilerSettings,
+                        clang: compilerSettings,
+                        msvc: compilerSettings,
+                    },
+                },
+                es: {type: 'object', keys: {interpreter: text, require: textOrTexts}},
+                python: {type: 'object', keys: {interpreter: text, venv: text, args: texts}},
+                javascript: {
+                    type: 'object',
+                    keys: {
+                        mode: {type: 'string', values: ['bun', 'node']},
+                        node: text,
+                        flags: texts,
+                        module: {type: 'string', values: ['auto', 'esm', 'cjs']},
+                    },
+                },
+                go: {type: 'object', keys: {mode: {type: 'string', values: ['auto', 'run', 'test']}}},
+                typescript: {
+                    type: 'object',
+                    keys: {mode: {type: 'string', values: ['bun', 'tsc', 'ts-node']}, tsconfig: text},
+                },
+                rust: {type: 'object', keys: {compiler: text, flags: texts, libraries: texts}},
+            },
+        },
+        target: {
+            type: 'object',
+            keys: {triple: text, cc: text, flags: texts, goos: text, goarch: text, runner: text},
+        },
+        remote: {type: 'object', keys: {host: text, dir: text, env: envSection, options: texts}},
+        docker: {type: 'object', keys: {image: text, args: texts, build: bool}},
+        debug: {
+            type: 'object',
+            keys: {
+                c: platformString,
+                js: platformString,
+                ts: platformString,
+                py: platformString,
+                go: platformString,
+                es: platformString,
+                sh: platformString,
+            },
+        },
+        valgrind: {type: 'object', keys: {enable: bool, suppressions: text, flags: texts}},
+        coverage: {type: 'object', keys: {enable: bool, threshold: {type: 'number', min: 0}, exclude: texts}},
+        notify: {
+            type: 'object',
+            keys: {
+                webhook: text,
+                onlyOnFailure: bool,
+                format: {type: 'string', values: ['json', 'slack']},
+                headers: {type: 'object', additional: text},
+            },
+        },
+        metrics: {type: 'object', keys: {push: text, job: text, instance: text}},
+        golden: {
+            type: 'object',
+            keys: {
+                normalize: {type: 'array', items: normalizer},
+                numericTolerance: {type: 'object', keys: {absolute: count, relative: count}},
+            },
+        },
+        redact: {type: 'object', keys: {env: texts, patterns: texts}},
+        parse: {type: 'object', keys: {assertions: bool, pass: texts, fail: texts}},
+        build: {type: 'object', keys: {dir: text}},
+        discover: {type: 'object', keys: {followSymlinks: bool, exclude: texts}},
+        shell: {type: 'object', keys: {interpreter: text, flags: texts, powershell: text}},
+        handlers: {
+            type: 'object',
+            additional: {type: 'object', keys: {build: template, run: template}, required: ['run']},
+        },
+        execution: {
+            type: 'object',
+            keys: {
+                timeout: count,
+                timeouts: {type: 'object', additional: count},
+                idleTimeout: count,
+                retries: count,
+                accept: bool,
+                bench: bool,
+                benchUpdate: bool,
+                benchThreshold: count,
+                expectedNewlines: {type: 'string', values: ['normalize', 'exact', 'trim']},
+                asan: bool,
+                backtrace: bool,
+                parallel: bool,
+                workers: {type: 'number', min: 1},
+                buildWorkers: {type: 'number', min: 1},
+                keepArtifacts: bool,
+                keepOnFail: bool,
+                artifactDir: text,
+                rebuild: bool,
+                stepMode: bool,
+                depth: count,
+                debugMode: bool,
+                showCommands: bool,
+                showWarnings: bool,
+                iterations: count,
+                stopOnFailure: bool,
+                failFast: bool,
+                maxFailures: {type: 'number', min: 1},
+                duration: count,
+                testClass: text,
+                seed: count,
+                strict: bool,
+                exitCode: {
+                    anyOf: [{type: 'number'}, {type: 'string', values: ['nonzero']}],
+                    expected: "a number or 'nonzero'",
+                },
+                args: texts,
+                stdin: text,
+                chdir: text,
+                resources: texts,
+            },
+        },
+        output: {
+            type: 'object',
+            keys: {
+                verbose: bool,
+                format: {type: 'string', values: ['simple', 'detailed', 'json']},
+                colors: bool,
+                color: {type: 'string', values: ['auto', 'always', 'never']},
+                maxBytes: {anyOf: [count, text], expected: "a byte count or size such as '10MB'"},
+                quiet: bool,
+                errorsOnly: bool,
+                summaryFailures: bool,
+                slowest: count,
+                live: bool,
+                report: textOrTexts,
+                github: bool,
+            },
+        },
+        patterns: {
+            type: 'object',
+            keys: {
+                include: texts,
+                exclude: texts,
+                windows: platformPatterns,
+                macosx: platformPatterns,
+                linux: platformPatterns,
+            },
+        },
+        services: {
+            type: 'object',
+            keys: {
+                skip: text,
+                environment: text,
+                globalPrep: text,
+                prep: text,
+                setup: text,
+                cleanup: text,
+                globalCleanup: text,
+                skipTimeout: count,
+                environmentTimeout: count,
+                globalPrepTimeout: count,
+                prepTimeout: count,
+                setupTimeout: count,
+                cleanupTimeout: count,
+                globalCleanupTimeout: count,
+                delay: count,
+                setupDelay: count,
+                shutdownTimeout: count,
+                healthCheck: {
+                    type: 'object',
+                    keys: {
+                        type: {type: 'string', values: ['http', 'tcp', 'script', 'file']},
+                        interval: count,
+                        timeout: count,
+                        url: text,
+                        expectedStatus: {type: 'number'},
+                        expectedBody: text,
+                        host: text,
+                        port: {type: 'number', min: 1},
+                        command: text,
+                        expectedExit: {type: 'number'},
+                        path: text,
+                    },
+                },
+            },
+        },
+        environment,
+        env: environment,
+    },
+}
+
+/*
+ ConfigSchema - Validation of testme.json5 files
+
+ Checks a parsed configuration against the known keys and value types of TestConfig and reports
+ each problem with its JSON path. Wrong types and malformed values are errors. Unknown keys are
+ warnings so configs written for newer versions of TestMe still load. Lists may be written as
+ {values: [...], append: boolean} markers for inheritance merging.
+ */
+export class ConfigSchema {
+    /*
+     Validates a parsed configuration
+     @param config Parsed configuration file contents
+     @returns Problems found, in document order (empty if the configuration is valid)
+     */
+    static validate(config: unknown): ConfigIssue[] {
+        const issues: ConfigIssue[] = []
+        this.check(config, CONFIG_SCHEMA, '', issues)
+        return issues
+    }
+
+    /*
+     Formats problems for display
+     @param file Configuration file path
+     @param issues Problems found in the file
+     @returns One line per problem: "file: severity: path: message"
+     */
+    static format(file: string, issues: ConfigIssue[]): string {
+        return issues
+            .map((issue) => `${file}: ${issue.severity}: ${issue.path || '(root)'}: ${issue.message}`)
+            .join('\n')
+    }
+
+    /*
+     Checks a value against a schema, recording problems
+     @param value Value to check
+     @param schema Allowed values
+     @param path JSON path of the value
+     @param issues Problems found so far
+     */
+    private static check(value: unknown, schema: Schema, path: string, issues: ConfigIssue[]): void {
+        if (!this.matches(value, schema)) {
+            const message = `expected ${this.expected(schema)}, got ${this.describe(value)}`
+            issues.push({path, message, severity: 'error'})
+            return
+        }
+        if ('anyOf' in schema) {
+            // Check nested values against the first alternative of the right type
+            this.check(value, schema.anyOf.find((option) => this.matches(value, option))!, path, issues)
+            return
+        }
+        if (schema.type === 'array') {
+            const marker = !Array.isArray(value) ? (value as Record<string, unknown>) : undefined
+            if (marker) {
+                for (const key of Object.keys(marker)) {
+                    if (key === 'append' && typeof marker.append !== 'boolean') {
+                        issues.push({path: `${path}.append`, message: 'expected a boolean', severity: 'error'})
+                    } else if (key !== 'append' && key !== 'values') {
+                        issues.push({path: `${path}.${key}`, message: 'unknown key', severity: 'warning'})
+                    }
+                }
+            }
+            const items = (marker ? marker.values : value) as unknown[]
+            items.forEach((item, index) => this.check(item, schema.items, `${path}[${index}]`, issues))
+        } else if (schema.type === 'object') {
+            for (const key of schema.required || []) {
+                if ((value as Record<string, unknown>)[key] === undefined) {
+                    issues.push({path: path ? `${path}.${key}` : key, message: 'missing', severity: 'error'})
+                }
+            }
+            for (const [key, item] of Object.entries(value as Record<string, unknown>)) {
+                const child = path ? `${path}.${key}` : key
+                const itemSchema = schema.keys?.[key] || schema.additional
+                if (itemSchema) {
+                    this.check(item, itemSchema, child, issues)
+                } else {
+                    const hint = this.suggest(key, Object.keys(schema.keys || {}))
+                    issues.push({path: child, message: `unknown key${hint}`, severity: 'warning'})
+                }
+            }
+        } else if (schema.values && !schema.values.includes(value as string | number | boolean)) {
+            const allowed = schema.values.map((v) => JSON.stringify(v)).join(', ')
+            issues.push({path, message: `must be one of ${allowed}, got ${JSON.stringify(value)}`, severity: 'error'})
+        } else if (schema.type === 'number' && schema.min !== undefined && (value as number) < schema.min) {
+            issues.push({path, message: `must be at least ${schema.min}, got ${value}`, severity: 'error'})
+        } else if (schema.placeholders) {
+            for (const [placeholder, name] of (value as string).matchAll(/\{(\w*)\}/g)) {
+                if (!schema.placeholders.includes(name!)) {
+                    const allowed = schema.placeholders.map((p) => `{${p}}`).join(', ')
+                    const message = `unknown placeholder ${placeholder}, use ${allowed}`
+                    issues.push({path, message, severity: 'error'})
+                }
+            }
+        }
+    }
+
+    /*
+     Tests if a value has the type a schema requires (without checking nested values)
+     @param value Value to test
+     @param schema Schema to test against
+     @returns True if the value has the right type
+     */
+    private static matches(value: unknown, schema: Schema): boolean {
+        if ('anyOf' in schema) {
+            return schema.anyOf.some((option) => this.matches(value, option))
+        }
+        switch (schema.type) {
+            case 'array':
+                return (
+                    Array.isArray(value) ||
+                    (this.isObject(value) && Array.isArray((value as Record<string, unknown>).values))
+                )
+            case 'object':
+                return this.isObject(value)
+            case 'number':
+                return typeof value === 'number' && Number.isFinite(value)
+            default:
+                return typeof value === schema.type
+        }
+    }
+
+    /*
+     Describes the values a schema allows
+     @param schema Schema
+     @returns Description, e.g. "a number"
+     */
+    private static expected(schema: Schema): string {
+        if ('anyOf' in schema) {
+            return schema.expected
+        }
+        switch (schema.type) {
+            case 'array':
+                return 'an array'
+            case 'object':
+                return 'an object'
+            default:
+                return `a ${schema.type}`
+        }
+    }
+
+    /*
+     Describes the type of a value for error messages
+     @param value Value
+     @returns Description, e.g. "string \"10s\""
+     */
+    private static describe(value: unknown): string {
+        if (value === null) {
+            return 'null'
+        } else if (Array.isArray(value)) {
+            return 'an array'
+        } else if (typeof value === 'object') {
+            return 'an object'
+        }
+        return `${typeof value} ${JSON.stringify(value)}`
+    }
+
+    /*
+     Suggests a known key for a misspelled one
+     @param key Unknown key
+     @param known Keys allowed at that level
+     @returns Hint such as ' (did you mean "timeout"?)', or empty if no key is close
+     */
+    private static suggest(key: string, known: string[]): string {
+        const lower = key.toLowerCase()
+        const match = known.find((name) => {
+            const candidate = name.toLowerCase()
+            return candidate === lower || this.distance(candidate, lower) <= Math.max(1, Math.floor(name.length / 4))
+        })
+        return match ? ` (did you mean "${match}"?)` : ''
+    }
+
+    /*
+     Computes the edit distance between two texts
+     @param a First string
+     @param b Second string
+     @returns Number of single character insertions, deletions or substitutions between them
+     */
+    private static distance(a: string, b: string): number {
+        let previous = Array.from({length: b.length + 1}, (_, i) => i)
+        for (let i = 1; i <= a.length; i++) {
+            const current = [i]
+            for (let j = 1; j <= b.length; j++) {
+                const cost = a[i - 1] === b[j - 1] ? 0 : 1
+                current[j] = Math.min(previous[j]! + 1, current[j - 1]! + 1, previous[j - 1]! + cost)
+            }
+            previous = current
+        }
+        return previous[b.length]!
+    }
+
+    /*
+     Tests if a value is a plain object
+     @param value Value
+     @returns True for non-null, non-array objects
+     */
+    private static isObject(value: unknown): boolean {
+        return typeof value === 'object' && value !== null && !Array.isArray(value)
+    }
+}
@@ -5,6 +5,20 @@ import {ProcessManager} from './platform/process.ts'
 import {PlatformDetector} from './platform/detector.ts'
 import {HealthCheckManager} from './services/health-check.ts'
 import {ShellDetector} from './platform/shell.ts'
+import {DryRun} from './utils/dry-run.ts'
+import {loadDotEnv} from './utils/dotenv.ts'
+import {Fixtures} from './fixtures.ts'
+import {ConfigManager} from './config.ts'
+import type {DirectoryFixtures} from './fixtures.ts'
+
+/**
+ * Root teardown commands prepared by global setup, with their parsed arguments and environment
+ */
+type GlobalTeardown = {
+    commands: {text: string; argv: string[]}[]
+    cwd?: string
+    env: Record<string, string>
+}
 
 /**
  * Manages setup and cleanup services for test execution
@@ -22,6 +36,8 @@ import {ShellDetector} from './platform/shell.ts'
  * 5. Tests Execute
  * 6. Cleanup - Runs after tests complete, kills setup if still running
  *
+ * The root setup and teardown commands run once around the whole run (runGlobalSetup, runGlobalTeardown).
+ *
  * Process Management:
  * - Setup processes run in background and are automatically killed on exit
  * - Cleanup handlers registered for SIGINT, SIGTERM, and process exit
@@ -62,6 +78,10 @@ export class ServiceManager {
     private invocationDir: string
     /** @internal */
     private environmentVars: Record<string, string> = {}
+    /** @internal */
+    private teardown: GlobalTeardown | null = null
+    /** @internal */
+    private teardownRegistered = false
 
     /**
      * Creates a new ServiceManager instance
@@ -88,6 +108,10 @@ export class ServiceManager {
         if (!skipCommand) {
             return {shouldSkip: false}
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Skip script', skipCommand, config)
+            return {shouldSkip: false}
+        }
 
         const timeout = (config.services?.skipTimeout || 30) * 1000
 
@@ -173,6 +197,10 @@ export class ServiceManager {
         if (!environmentCommand) {
             return {}
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Environment script', environmentCommand, config)
+            return {}
+        }
 
         const timeout = (config.services?.environmentTimeout || 30) * 1000
 
@@ -224,24 +252,7 @@ export class ServiceManager {
                 throw new Error(`Environment script '${displayPath}' timed out after ${timeout / 1000}s`)
             } else if (result === 0) {
                 // Parse stdout for key=value pairs
-                const envVars: Record<string, string> = {}
-
-                // Parse each line for KEY=VALUE format
-                const lines = stdout.split('\n')
-                for (const line of lines) {
-                    const trimmed = line.trim()
-                    if (!trimmed || trimmed.startsWith('#')) {
-                        // Skip empty lines and comments
-                        continue
-                    }
-
-                    const equalIndex = trimmed.indexOf('=')
-                    if (equalIndex > 0) {
-                        const key = trimmed.substring(0, equalIndex).trim()
-                        const value = trimmed.substring(equalIndex + 1).trim()
-                        envVars[key] = value
-                    }
-                }
+                const envVars = ServiceManager.parseEnvironment(stdout)
 
                 if (config.output?.verbose) {
                     console.log(`✓ Environment script completed - loaded ${Object.keys(envVars).length} variable(s)`)
@@ -279,6 +290,10 @@ export class ServiceManager {
         if (!globalPrepCommand) {
             return
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Global prep', globalPrepCommand, config)
+            return
+        }
 
         const timeout = (config.services?.globalPrepTimeout || 30) * 1000
 
@@ -358,6 +373,10 @@ export class ServiceManager {
         if (!prepCommand) {
             return
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Prep script', prepCommand, config)
+            return
+        }
 
         const timeout = (config.services?.prepTimeout || 30) * 1000
 
@@ -438,6 +457,10 @@ export class ServiceManager {
         if (!setupCommand) {
             return
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Setup service', setupCommand, config, true)
+            return
+        }
 
         const timeout = (config.services?.setupTimeout || 30) * 1000
 
@@ -678,6 +701,10 @@ export class ServiceManager {
         if (!globalCleanupCommand) {
             return
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Global cleanup', globalCleanupCommand, config)
+            return
+        }
 
         const timeout = (config.services?.globalCleanupTimeout || 10) * 1000
 
@@ -746,6 +773,277 @@ export class ServiceManager {
         }
     }
 
+    /**
+     * Runs the root setup commands once before the whole run
+     *
+     * @param config - Root configuration containing the setup and teardown commands
+     * @throws Error with the command's combined output if a setup command fails
+     *
+     * @remarks
+     * Commands run in order in the configuration directory and stop at the first failure.
+     * Output is captured and only shown if a command fails.
+     * The teardown commands are prepared first, so runGlobalTeardown can run them even if setup
+     * fails part way, and they are also run when the process exits early (e.g., a second Ctrl+C).
+     */
+    async runGlobalSetup(config: TestConfig): Promise<void> {
+        const setup = Fixtures.getCommands(config.setup)
+        const teardown = Fixtures.getCommands(config.teardown)
+        if (DryRun.isEnabled()) {
+            for (const command of setup) {
+                await this.printDryRun('Global setup', command, config)
+            }
+            for (const command of teardown) {
+                await this.printDryRun('Global teardown', command, config)
+            }
+            return
+        }
+        const env = await this.getServiceEnvironment(config)
+        if (teardown.length > 0) {
+            const commands = []
+            for (const text of teardown) {
+                commands.push({text, argv: await this.parseCommand(text, config.configDir)})
+            }
+            this.teardown = {commands, cwd: config.configDir, env}
+            if (!this.teardownRegistered) {
+                this.teardownRegistered = true
+                process.once('exit', () => this.runGlobalTeardownSync())
+            }
+        }
+        for (const text of setup) {
+            if (config.output?.verbose) {
+                console.log(`Running global setup: ${text}`)
+            }
+            const argv = await this.parseCommand(text, config.configDir)
+            await this.runFixtureCommand('Global setup', text, argv, config.configDir, env)
+        }
+        if (setup.length > 0) {
+            console.log(`✓ Global setup completed successfully`)
+        }
+    }
+
+    /**
+     * Runs the teardown commands prepared by runGlobalSetup
+     *
+     * @param allTestsPassed - Optional boolean indicating if all tests passed
+     *
+     * @remarks
+     * Every command runs even if an earlier one fails. Failures are logged with the command's
+     * combined output but don't fail the test run. Teardown runs at most once per setup.
+     * Sets TESTME_SUCCESS=1 if allTestsPassed is true, 0 otherwise.
+     */
+    async runGlobalTeardown(allTestsPassed?: boolean): Promise<void> {
+        const teardown = this.teardown
+        if (!teardown) {
+            return
+        }
+        this.teardown = null
+        const env = {...teardown.env, TESTME_SUCCESS: allTestsPassed === true ? '1' : '0'}
+        let failed = false
+        for (const {text, argv} of teardown.commands) {
+            try {
+                await this.runFixtureCommand('Global teardown', text, argv, teardown.cwd, env)
+            } catch (error) {
+                console.warn(`✗ ${error instanceof Error ? error.message : String(error)}`)
+                failed = true
+            }
+        }
+        if (!failed) {
+            console.log(`✓ Global teardown completed successfully`)
+        }
+    }
+
+    /*
+     Runs any pending teardown commands synchronously when the process exits before runGlobalTeardown
+     */
+    private runGlobalTeardownSync(): void {
+        const teardown = this.teardown
+        if (!teardown) {
+            return
+        }
+        this.teardown = null
+        const env = {...teardown.env, TESTME_SUCCESS: '0'}
+        for (const {text, argv} of teardown.commands) {
+            try {
+                const result = Bun.spawnSync(argv, {stdout: 'pipe', stderr: 'pipe', cwd: teardown.cwd, env})
+                if (result.exitCode !== 0) {
+                    const output = this.combineServiceOutput(result.stdout.toString(), result.stderr.toString())
+                    const status = `failed with exit code ${result.exitCode}`
+                    console.warn(`✗ Global teardown '${text}' ${status}${output ? ':\n' + output : ''}`)
+                }
+            } catch (error) {
+                console.warn(`✗ Global teardown '${text}' failed: ${error}`)
+            }
+        }
+    }
+
+    /**
+     * Runs the setup commands of a directory before its tests
+     *
+     * @param config - Group configuration
+     * @param fixtures - Setup and teardown commands or scripts for the directory
+     * @returns Environment variables printed by setup as KEY=VALUE lines, for the tests and teardown
+     * @throws Error with the command's combined output if a setup command fails or times out
+     *
+     * @remarks
+     * Commands run in order in the fixture directory and stop at the first failure.
+     * Output is captured and only shown if a command fails. Each command is limited by
+     * services.prepTimeout (default: 30 seconds) and sees the variables exported by earlier commands.
+     */
+    async runDirectorySetup(config: TestConfig, fixtures: DirectoryFixtures): Promise<Record<string, string>> {
+        const exported: Record<string, string> = {}
+        if (DryRun.isEnabled()) {
+            for (const command of fixtures.setup) {
+                const [program, ...args] = await this.getFixtureCommand(command, fixtures.directory)
+                const env = await this.getServiceEnvironment(config)
+                DryRun.print('Directory setup', program!, args, {cwd: fixtures.directory, env})
+            }
+            return exported
+        }
+        const timeout = (config.services?.prepTimeout || 30) * 1000
+        for (const command of fixtures.setup) {
+            const env = {...(await this.getServiceEnvironment(config)), ...exported}
+            const argv = await this.getFixtureCommand(command, fixtures.directory)
+            const stdout = await this.runFixtureCommand(
+                'Directory setup',
+                this.getDisplayPath(command, config),
+                argv,
+                fixtures.directory,
+                env,
+                timeout
+            )
+            Object.assign(exported, ServiceManager.parseEnvironment(stdout))
+        }
+        return exported
+    }
+
+    /**
+     * Runs the teardown commands of a directory after its tests
+     *
+     * @param config - Group configuration
+     * @param fixtures - Setup and teardown commands or scripts for the directory
+     * @param exported - Environment variables exported by the directory setup
+     * @param allTestsPassed - Optional boolean indicating if all tests passed
+     *
+     * @remarks
+     * Every command runs even if an earlier one fails, limited by services.cleanupTimeout (default: 10 seconds).
+     * Failures are logged with the command's combined output but don't fail the test run.
+     * Sets TESTME_SUCCESS=1 if allTestsPassed is true, 0 otherwise.
+     */
+    async runDirectoryTeardown(
+        config: TestConfig,
+        fixtures: DirectoryFixtures,
+        exported: Record<string, string>,
+        allTestsPassed?: boolean
+    ): Promise<void> {
+        const env = {
+            ...(await this.getServiceEnvironment(config)),
+            ...exported,
+            TESTME_SUCCESS: allTestsPassed === true ? '1' : '0',
+        }
+        const timeout = (config.services?.cleanupTimeout || 10) * 1000
+        for (const command of fixtures.teardown) {
+            const argv = await this.getFixtureCommand(command, fixtures.directory)
+            if (DryRun.isEnabled()) {
+                DryRun.print('Directory teardown', argv[0]!, argv.slice(1), {cwd: fixtures.directory, env})
+                continue
+            }
+            try {
+                const display = this.getDisplayPath(command, config)
+                await this.runFixtureCommand('Directory teardown', display, argv, fixtures.directory, env, timeout)
+            } catch (error) {
+                console.warn(`✗ ${error instanceof Error ? error.message : String(error)}`)
+            }
+        }
+    }
+
+    /**
+     * Parses KEY=VALUE lines printed by an environment or setup script
+     *
+     * @param output - Script standard output
+     * @returns Variables in the order printed. Empty lines, comments and other lines are ignored.
+     */
+    static parseEnvironment(output: string): Record<string, string> {
+        const envVars: Record<string, string> = {}
+        for (const line of output.split('\n')) {
+            const trimmed = line.trim()
+            if (!trimmed || trimmed.startsWith('#')) {
+                // Skip empty lines and comments
+                continue
+            }
+            const equalIndex = trimmed.indexOf('=')
+            if (equalIndex > 0) {
+                const key = trimmed.substring(0, equalIndex).trim()
+                const value = trimmed.substring(equalIndex + 1).trim()
+                envVars[key] = value
+            }
+        }
+        return envVars
+    }
+
+    /*
+     Gets the command line for a fixture: a setup.tst.* or teardown.tst.* script runs with its shell,
+     other commands are parsed like service commands
+     @param command Fixture script path or command line
+     @param directory Fixture directory
+     @returns Command and arguments
+     */
+    private async getFixtureCommand(command: string, directory: string): Promise<string[]> {
+        if (Fixtures.isFixture(command)) {
+            const shell = await ShellDetector.detectShell(command)
+            return [shell, ...ShellDetector.getShellArgs(ShellDetector.getShellTypeFromExtension(command), command)]
+        }
+        return await this.parseCommand(command, directory)
+    }
+
+    /*
+     Runs a setup or teardown command with its output captured
+     @param description Kind of command for messages (e.g., 'Global setup')
+     @param text Command as shown in messages
+     @param argv Command and arguments
+     @param cwd Working directory
+     @param env Command environment
+     @param timeout Timeout in milliseconds, 0 or undefined for none
+     @returns Standard output of the command
+     @throws Error with the combined output if the command cannot run, fails or times out
+     */
+    private async runFixtureCommand(
+        description: string,
+        text: string,
+        argv: string[],
+        cwd: string | undefined,
+        env: Record<string, string>,
+        timeout?: number
+    ): Promise<string> {
+        let result: number
+        let stdout: string
+        let stderr: string
+        let timedOut = false
+        try {
+            const fixtureProcess = Bun.spawn(argv, {stdout: 'pipe', stderr: 'pipe', cwd, env})
+            const timeoutId = timeout
+                ? setTimeout(() => {
+                      timedOut = true
+                      fixtureProcess.kill()
+                  }, timeout)
+                : undefined
+            ;[result, stdout, stderr] = await Promise.all([
+                fixtureProcess.exited,
+                new Response(fixtureProcess.stdout).text(),
+                new Response(fixtureProcess.stderr).text(),
+            ])
+            clearTimeout(timeoutId)
+        } catch (error) {
+            const message = error instanceof Error ? error.message : String(error)
+            throw new Error(`${description} '${text}' failed: ${message}`)
+        }
+        const output = this.combineServiceOutput(stdout, stderr)
+        if (timedOut || result !== 0) {
+            const status = timedOut ? `timed out after ${timeout! / 1000}s` : `failed with exit code ${result}`
+            throw new Error(`${description} '${text}' ${status}${output ? ':\n' + output : ''}`)
+        }
+        return stdout
+    }
+
     /**
      * Runs the cleanup command in the foreground
      *
@@ -763,6 +1061,10 @@ export class ServiceManager {
         if (!cleanupCommand) {
             return
         }
+        if (DryRun.isEnabled()) {
+            await this.printDryRun('Cleanup script', cleanupCommand, config)
+            return
+        }
 
         // Prevent duplicate cleanup execution
         if (this.cleanupHasRun) {
@@ -882,6 +1184,25 @@ export class ServiceManager {
         return this.isSetupRunning && this.setupProcess !== null && !this.setupProcess.killed
     }
 
+    /**
+     * Prints a service command and its environment instead of running it (--dry-run)
+     *
+     * @param description - Service description for display
+     * @param commandString - Full command string from the configuration
+     * @param config - Test configuration containing service settings
+     * @param isBackgroundService - Whether this is the background setup service
+     */
+    private async printDryRun(
+        description: string,
+        commandString: string,
+        config: TestConfig,
+        isBackgroundService: boolean = false
+    ): Promise<void> {
+        const [command, ...args] = await this.parseCommand(commandString, config.configDir, isBackgroundService)
+        const env = await this.getServiceEnvironment(config)
+        DryRun.print(description, command!, args, {cwd: config.configDir, env})
+    }
+
     /*
      Parses a command string into command and arguments, resolving relative paths
      @param commandString Full command string to parse
@@ -1054,7 +1375,7 @@ export class ServiceManager {
               - TESTME_VERBOSE: 1 if verbose mode, 0 otherwise
               - TESTME_QUIET: 1 if quiet mode, 0 otherwise
               - TESTME_KEEP: 1 if keepArtifacts is enabled, 0 otherwise
-              - TESTME_DEPTH: Depth value if --depth specified
+              - TESTME_DEPTH: Depth from --depth (default 0), capped by maxDepth
               - TESTME_ITERATIONS: Iteration count if --iterations specified
               - TESTME_DURATION: Duration in seconds if --duration specified
      */
@@ -1119,10 +1440,8 @@ export class ServiceManager {
         env.TESTME_QUIET = config.output?.quiet === true ? '1' : '0'
         env.TESTME_KEEP = config.execution?.keepArtifacts === true ? '1' : '0'
 
-        // Export depth, iterations, and duration if set
-        if (config.execution?.depth !== undefined) {
-            env.TESTME_DEPTH = String(config.execution.depth)
-        }
+        // Export depth (always, capped by maxDepth), iterations, and duration if set
+        env.TESTME_DEPTH = String(ConfigManager.getDepth(config))
         if (config.execution?.iterations !== undefined) {
             env.TESTME_ITERATIONS = String(config.execution.iterations)
         }
@@ -1133,6 +1452,9 @@ export class ServiceManager {
             env.TESTME_CLASS = config.execution.testClass
         }
 
+        // Add variables from the .env file, beneath the configured environment
+        Object.assign(env, await loadDotEnv(config))
+
         // Add environment variables from configuration with expansion
         // Support both 'environment' (new) and 'env' (legacy) keys
         const configEnv = config.environment || config.env
@@ -1160,6 +1482,7 @@ export class ServiceManager {
                     key === 'macosx' ||
                     key === 'linux' ||
                     key === 'default' ||
+                    key === 'file' ||
                     value === null ||
                     value === undefined
                 ) {
@@ -0,0 +1,130 @@
+import type {TestFile} from './types.ts'
+import {relative} from 'path'
+
+/*
+ One part of a test set partitioned across CI machines
+ */
+export type Shard = {
+    index: number // 1-based shard number
+    count: number // Total number of shards
+}
+
+/*
+ TestShards - Partitioning of the selected tests across parallel CI machines
+
+ --shard i/n runs only the tests in shard i of n. By default a test belongs to the shard given by a hash
+ of its path relative to the test root, so the partition is the same on every run and every machine and
+ a retried job picks up the same tests. Adding or removing a test does not move other tests.
+ With --balance, tests are instead assigned longest first to the shard with the least total recorded
+ duration (greedy longest-processing-time bin packing over .testme/timings.json), so shards take similar
+ wall-clock times rather than running equal numbers of tests. Every shard must then see the same timings
+ file to agree on the split.
+ */
+export class TestShards {
+    /*
+     Parses a shard specification
+     @param spec Shard specification "i/n" (e.g., "2/4")
+     @returns Parsed shard
+     @throws Error if the specification is malformed or i is not between 1 and n
+     */
+    static parse(spec: string): Shard {
+        const match = spec.trim().match(/^(\d+)\/(\d+)$/)
+        if (!match) {
+            throw new Error(`Invalid shard "${spec}", expected i/n (e.g., 1/4)`)
+        }
+        const index = parseInt(match[1]!, 10)
+        const count = parseInt(match[2]!, 10)
+        if (count < 1 || index < 1 || index > count) {
+            throw new Error(`Invalid shard "${spec}", i must be between 1 and n`)
+        }
+        return {index, count}
+    }
+
+    /*
+     Selects the tests that belong to a shard
+     @param tests Selected tests, in any order
+     @param rootDir Test root directory (paths are hashed relative to it)
+     @param shard Shard to select
+     @param timings Recorded durations in milliseconds keyed by test path, to balance shards by duration
+     @returns Tests in the shard, in their original order
+     */
+    static select(tests: TestFile[], rootDir: string, shard: Shard, timings?: Map<string, number>): TestFile[] {
+        const owners = timings ? this.balance(tests, rootDir, shard.count, timings) : undefined
+        return tests.filter((test) => {
+            const owner = owners ? owners.get(test)! : this.hash(this.getKey(test, rootDir)) % shard.count
+            return owner === shard.index - 1
+        })
+    }
+
+    /*
+     Assigns tests to shards longest first, each to the shard with the least total duration
+     Tests without a recorded duration are assumed to take the median recorded duration of the suite.
+     @param tests Tests to assign
+     @param rootDir Test root directory
+     @param count Number of shards
+     @param timings Recorded durations keyed by test path
+     @returns 0-based shard number for each test
+     */
+    private static balance(
+        tests: TestFile[],
+        rootDir: string,
+        count: number,
+        timings: Map<string, number>
+    ): Map<TestFile, number> {
+        const median = this.median([...timings.values()])
+        const weighted = tests.map((test) => ({
+            test,
+            key: this.getKey(test, rootDir),
+            ms: timings.get(test.path) ?? median,
+        }))
+
+        // Sort by duration then path so every machine computes the same assignment
+        weighted.sort((a, b) => b.ms - a.ms || a.key.localeCompare(b.key))
+        const loads = new Array<number>(count).fill(0)
+        const owners = new Map<TestFile, number>()
+        for (const {test, ms} of weighted) {
+            const owner = loads.indexOf(Math.min(...loads))
+            loads[owner] = loads[owner]! + ms
+            owners.set(test, owner)
+        }
+        return owners
+    }
+
+    /*
+     Computes the median of a list of durations
+     @param values Durations
+     @returns Median, or 1 if there are no durations so unknown tests weigh the same
+     */
+    private static median(values: number[]): number {
+        if (values.length === 0) {
+            return 1
+        }
+        const sorted = [...values].sort((a, b) => a - b)
+        const middle = Math.floor(sorted.length / 2)
+        return sorted.length % 2 ? sorted[middle]! : (sorted[middle - 1]! + sorted[middle]!) / 2
+    }
+
+    /*
+     Gets the machine independent key of a test
+     @param test Test file
+     @param rootDir Test root directory
+     @returns Path relative to the test root with forward slashes
+     */
+    private static getKey(test: TestFile, rootDir: string): string {
+        return relative(rootDir, test.path).replace(/\\/g, '/')
+    }
+
+    /*
+     Hashes a string with 32-bit FNV-1a
+     @param text String to hash
+     @returns Unsigned 32-bit hash
+     */
+    private static hash(text: string): number {
+        let hash = 0x811c9dc5
+        for (let i = 0; i < text.length; i++) {
+            hash ^= text.charCodeAt(i)
+            hash = Math.imul(hash, 0x01000193)
+        }
+        return hash >>> 0
+    }
+}
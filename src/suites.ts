@@ -0,0 +1,59 @@
+import type {TestFile} from './types.ts'
+import {ConfigManager} from './config.ts'
+import {Directives} from './directives.ts'
+
+/*
+ TestSuites - Named suites of tests selected with --suite
+
+ A test belongs to the suites named by the suite key of its configuration, which tags every test governed by
+ that testme.json5, and by its own "testme: suite NAME, ..." directive. A nested configuration that inherits
+ from its parent adds its suites to the parent's. Because suites are declared next to the tests rather than by
+ path, they survive moving directories around. A test may belong to several suites, and --suite selects the
+ tests of any suite named.
+ */
+export class TestSuites {
+    /*
+     Gets the suites of a test
+     @param file Test file
+     @returns Suite names from its configuration and its suite directive
+     */
+    static async get(file: TestFile): Promise<string[]> {
+        const config = await ConfigManager.findConfig(file.directory)
+        const configured = typeof config.suite === 'string' ? [config.suite] : config.suite || []
+        const declared = (await Directives.read(file.path)).suites || []
+        return [...new Set([...configured, ...declared])]
+    }
+
+    /*
+     Gathers the tests of every suite
+     @param tests Discovered tests
+     @returns Tests of each suite, by suite name in sorted order
+     */
+    static async collect(tests: TestFile[]): Promise<Map<string, TestFile[]>> {
+        const suites = new Map<string, TestFile[]>()
+        for (const file of tests) {
+            for (const name of await this.get(file)) {
+                suites.set(name, [...(suites.get(name) || []), file])
+            }
+        }
+        return new Map([...suites.entries()].sort(([a], [b]) => a.localeCompare(b)))
+    }
+
+    /*
+     Selects the tests of the named suites
+     @param tests Tests to select from
+     @param names Suite names from --suite
+     @param suites Tests of every suite defined, from collect()
+     @returns Tests that belong to any of the named suites, in their original order
+     @throws Error naming a suite that is not defined
+     */
+    static select(tests: TestFile[], names: string[], suites: Map<string, TestFile[]>): TestFile[] {
+        const unknown = names.filter((name) => !suites.has(name))
+        if (unknown.length > 0) {
+            const defined = suites.size > 0 ? `defined: ${[...suites.keys()].join(', ')}` : 'no suites are defined'
+            throw new Error(`Unknown suite: ${unknown.join(', ')} (${defined})`)
+        }
+        const members = new Set(names.flatMap((name) => suites.get(name)!.map((file) => file.path)))
+        return tests.filter((file) => members.has(file.path))
+    }
+}
@@ -0,0 +1,72 @@
+import type {TestFile} from './types.ts'
+import {Directives} from './directives.ts'
+
+/*
+ TestTags - Labels from the "testme: tags NAME, ..." directive, selected with --tags and --not-tags
+
+ A --tags expression is a comma-separated list of alternatives, each a list of tags joined with "+" that must all
+ be present: "slow+network,db" selects tests tagged both slow and network, or tagged db. Every --tags expression
+ given must match. --not-tags excludes tests carrying any of the tags listed. Tags are recorded on each test so
+ reports can show them.
+ */
+export class TestTags {
+    /*
+     Parses a --tags expression
+     @param expression Alternatives separated by commas, each with tags joined by "+"
+     @returns Alternatives, each the tags it requires
+     @throws Error if an alternative or tag is empty
+     */
+    static parse(expression: string): string[][] {
+        return expression.split(',').map((alternative) => {
+            const tags = alternative.split('+').map((tag) => tag.trim())
+            if (tags.some((tag) => !tag)) {
+                throw new Error(`Invalid tag expression "${expression}" (use a+b for all, a,b for any)`)
+            }
+            return tags
+        })
+    }
+
+    /*
+     Reads the tags directives of tests and records them on each test
+     @param tests Tests to mark
+     */
+    static async mark(tests: TestFile[]): Promise<void> {
+        const tags = new Map<string, string[]>()
+        for (const file of tests) {
+            if (!tags.has(file.path)) {
+                tags.set(file.path, (await Directives.read(file.path)).tags || [])
+            }
+            const names = tags.get(file.path)!
+            if (names.length > 0) {
+                file.tags = names
+            }
+        }
+    }
+
+    /*
+     Checks a test's tags against the selection
+     @param tags Tags of the test
+     @param expressions --tags expressions, all of which must match
+     @param excluded Tags from --not-tags, none of which may be present
+     @returns True if the test is selected
+     */
+    static matches(tags: string[], expressions: string[] = [], excluded: string[] = []): boolean {
+        if (excluded.some((tag) => tags.includes(tag))) {
+            return false
+        }
+        return expressions.every((expression) =>
+            this.parse(expression).some((alternative) => alternative.every((tag) => tags.includes(tag)))
+        )
+    }
+
+    /*
+     Selects tests by their tags
+     @param tests Tests marked by mark()
+     @param expressions --tags expressions
+     @param excluded Tags from --not-tags
+     @returns Tests that match
+     */
+    static select(tests: TestFile[], expressions?: string[], excluded?: string[]): TestFile[] {
+        return tests.filter((file) => this.matches(file.tags || [], expressions, excluded))
+    }
+}
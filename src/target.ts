@@ -0,0 +1,110 @@
+import type {TestConfig} from './types.ts'
+
+/*
+ Target platform in Go naming (GOOS and GOARCH values)
+ */
+export type TargetPlatform = {
+    os?: string // e.g., 'linux', 'darwin', 'windows'
+    arch?: string // e.g., 'amd64', 'arm64', 'arm'
+}
+
+/*
+ Triple architecture prefixes and their Go architecture names
+ */
+const ARCHITECTURES: [RegExp, string][] = [
+    [/^(x86_64|amd64)$/, 'amd64'],
+    [/^(aarch64|arm64)$/, 'arm64'],
+    [/^(arm|armv\d.*|thumb.*)$/, 'arm'],
+    [/^i[3-6]86$/, '386'],
+    [/^riscv64$/, 'riscv64'],
+    [/^(powerpc64le|ppc64le)$/, 'ppc64le'],
+    [/^s390x$/, 's390x'],
+    [/^(wasm32|wasm)$/, 'wasm'],
+]
+
+/*
+ CrossTarget - Cross-compilation target for compiled tests
+
+ Reads the "target" configuration key and the --target option (which overrides target.triple).
+ The triple (e.g., 'aarch64-linux-gnu') selects the C cross compiler and is mapped to GOOS and
+ GOARCH for Go tests. Tests built for a platform other than the host are run through target.runner
+ (e.g., 'qemu-aarch64 -L /usr/aarch64-linux-gnu') or, without a runner, built but not run.
+ */
+export class CrossTarget {
+    /*
+     Gets the target triple for a configuration
+     @param config Test configuration
+     @returns Triple from --target or target.triple, or undefined when building for the host
+     */
+    static getTriple(config: TestConfig): string | undefined {
+        return config.execution?.target || config.target?.triple || undefined
+    }
+
+    /*
+     Parses a target triple into Go platform names
+     @param triple Target triple (e.g., 'arm-linux-gnueabihf', 'x86_64-w64-mingw32')
+     @returns Operating system and architecture, undefined where not recognized
+     */
+    static parse(triple: string): TargetPlatform {
+        const [prefix, ...rest] = triple.toLowerCase().split('-')
+        const arch = ARCHITECTURES.find(([pattern]) => pattern.test(prefix || ''))?.[1]
+        const system = rest.join('-')
+        const os = /linux|android/.test(system)
+            ? 'linux'
+            : /darwin|apple|macos/.test(system)
+              ? 'darwin'
+              : /windows|mingw|w64|msvc/.test(system)
+                ? 'windows'
+                : /freebsd/.test(system)
+                  ? 'freebsd'
+                  : /wasi/.test(system)
+                    ? 'wasip1'
+                    : undefined
+        return {os, arch}
+    }
+
+    /*
+     Gets the Go platform for a configuration
+     target.goos and target.goarch override the values derived from the triple
+     @param config Test configuration
+     @returns GOOS and GOARCH values, or undefined when building for the host
+     */
+    static getGoPlatform(config: TestConfig): TargetPlatform | undefined {
+        const triple = this.getTriple(config)
+        const parsed = triple ? this.parse(triple) : {}
+        const platform = {os: config.target?.goos || parsed.os, arch: config.target?.goarch || parsed.arch}
+        return platform.os || platform.arch ? platform : undefined
+    }
+
+    /*
+     Gets the host platform in Go naming
+     @returns Operating system and architecture of this machine
+     */
+    static getHost(): TargetPlatform {
+        const arches: Record<string, string> = {x64: 'amd64', ia32: '386'}
+        return {
+            os: process.platform === 'win32' ? 'windows' : process.platform,
+            arch: arches[process.arch] || process.arch,
+        }
+    }
+
+    /*
+     Checks if binaries for a platform run directly on this machine
+     Parts that are not known are assumed to match the host
+     @param platform Target platform
+     @returns True if the host can run the binaries
+     */
+    static isNative(platform: TargetPlatform): boolean {
+        const host = this.getHost()
+        return (!platform.os || platform.os === host.os) && (!platform.arch || platform.arch === host.arch)
+    }
+
+    /*
+     Gets the command that runs target binaries
+     @param config Test configuration
+     @returns Runner command and arguments (e.g., ['qemu-arm', '-L', '/usr/arm-linux-gnueabihf']), empty if unset
+     */
+    static getRunner(config: TestConfig): string[] {
+        return (config.target?.runner || '').trim().split(/\s+/).filter(Boolean)
+    }
+}
@@ -0,0 +1,17 @@
+/*
+ Helper sources imported as text so they are embedded in the compiled binary
+ */
+declare module '*.h' {
+    const text: string
+    export default text
+}
+
+declare module '*.go' {
+    const text: string
+    export default text
+}
+
+declare module '*.sh' {
+    const text: string
+    export default text
+}
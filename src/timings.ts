@@ -0,0 +1,79 @@
+import type {TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {existsSync} from 'fs'
+import {mkdir, readFile, writeFile} from 'fs/promises'
+import {join, relative, resolve} from 'path'
+
+/*
+ Weight of the latest run in the moving average of a test's duration
+ */
+const SMOOTHING = 0.3
+
+/*
+ TestTimings - Records how long each test takes so --balance can even out shard run times
+
+ Durations are stored in .testme/timings.json as a JSON object mapping test paths, relative to the
+ test root, to milliseconds. After each run the entries of the tests that ran are updated with an
+ exponential moving average, so a single slow or fast run does not dominate, and other entries are
+ kept. A test's duration is the sum over its cases and matrix cells, as they all run in the shard
+ that owns the test file. Skipped tests are not recorded.
+ */
+export class TestTimings {
+    /*
+     Gets the timings file path
+     @param rootDir Test root directory
+     @returns Path of the timings file
+     */
+    static getPath(rootDir: string): string {
+        return join(rootDir, '.testme', 'timings.json')
+    }
+
+    /*
+     Loads the recorded timings
+     @param rootDir Test root directory
+     @returns Durations in milliseconds keyed by absolute test path, or null if no timings file exists
+     */
+    static async load(rootDir: string): Promise<Map<string, number> | null> {
+        const path = this.getPath(rootDir)
+        if (!existsSync(path)) {
+            return null
+        }
+        try {
+            const data = JSON.parse(await readFile(path, 'utf-8')) as Record<string, unknown>
+            const timings = new Map<string, number>()
+            for (const [test, duration] of Object.entries(data)) {
+                if (typeof duration === 'number' && Number.isFinite(duration) && duration >= 0) {
+                    timings.set(resolve(rootDir, test), duration)
+                }
+            }
+            return timings
+        } catch (error) {
+            console.warn(`⚠️  Ignoring invalid timings file ${path}: ${error}`)
+            return null
+        }
+    }
+
+    /*
+     Updates the recorded timings with the results of a run
+     @param rootDir Test root directory
+     @param results Results of the tests that ran
+     */
+    static async save(rootDir: string, results: TestResult[]): Promise<void> {
+        const timings = (await this.load(rootDir)) || new Map<string, number>()
+        const durations = new Map<string, number>()
+        for (const result of results.filter((result) => result.status !== TestStatus.Skipped)) {
+            durations.set(result.file.path, (durations.get(result.file.path) || 0) + result.duration)
+        }
+        for (const [path, duration] of durations) {
+            const previous = timings.get(path)
+            const average = previous === undefined ? duration : SMOOTHING * duration + (1 - SMOOTHING) * previous
+            timings.set(path, Math.round(average))
+        }
+        const data: Record<string, number> = {}
+        for (const [path, duration] of [...timings].sort(([a], [b]) => a.localeCompare(b))) {
+            data[relative(rootDir, path).replace(/\\/g, '/')] = duration
+        }
+        await mkdir(join(rootDir, '.testme'), {recursive: true})
+        await writeFile(this.getPath(rootDir), JSON.stringify(data, null, 2) + '\n')
+    }
+}
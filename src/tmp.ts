@@ -0,0 +1,113 @@
+import type {TestConfig, TestFile, TestResult} from './types.ts'
+import {TestStatus} from './types.ts'
+import {rmSync} from 'fs'
+import {mkdtemp, readdir, rm} from 'fs/promises'
+import {tmpdir} from 'os'
+import {basename, join} from 'path'
+
+/*
+ Name of a temporary directory created by TestMe: testme-<name>-<mkdtemp suffix>
+ */
+const TMP_NAME = /^testme-.+-[A-Za-z0-9]{6}$/
+
+/*
+ TestTmp - Private temporary directory for each test
+
+ Each test gets a new directory under the system temp directory, exported as TESTME_TMP and used as
+ the test's working directory unless execution.chdir or a chdir directive says otherwise. The
+ directory is created just before the test and removed when it completes, after any timed out
+ process has been killed. --keep-tmp keeps every directory and --keep-tmp-on-fail keeps the
+ directories of tests that did not pass, as does --keep-on-fail, which also removes the build
+ artifacts of passing tests. Directories still in use when tm exits early (a second
+ Ctrl+C) are removed by an exit handler.
+ */
+export class TestTmp {
+    private static active = new Set<string>()
+    private static exitHandler = false
+
+    /*
+     Creates the temporary directory of a test
+     @param file Test file
+     @returns Absolute path of a new, empty directory that no other test receives
+     */
+    static async create(file: TestFile): Promise<string> {
+        const name = basename(file.name).replace(/[^\w.-]/g, '_')
+        const dir = await mkdtemp(join(tmpdir(), `testme-${name}-`))
+        this.active.add(dir)
+        if (!this.exitHandler) {
+            this.exitHandler = true
+            process.once('exit', () => this.removeActive())
+        }
+        return dir
+    }
+
+    /*
+     Checks if a test's temporary directory is kept after the test
+     @param config Test configuration with keepTmp, keepTmpOnFail and keepOnFail
+     @param result Test result
+     @returns True with --keep-tmp, or with --keep-tmp-on-fail or --keep-on-fail if the test did not pass
+     */
+    static isKept(config: TestConfig, result: TestResult): boolean {
+        if (config.execution?.keepTmp) {
+            return true
+        }
+        const onFail = config.execution?.keepTmpOnFail === true || config.execution?.keepOnFail === true
+        return onFail && !this.isPassed(result)
+    }
+
+    /*
+     Checks if a test passed for the purpose of keeping its files
+     @param result Test result
+     @returns True if the test passed, was skipped or failed as expected
+     */
+    static isPassed(result: TestResult): boolean {
+        return [TestStatus.Passed, TestStatus.Skipped, TestStatus.XFail].includes(result.status)
+    }
+
+    /*
+     Removes a test's temporary directory, or keeps it for debugging
+     Removal retries briefly because files may still be open on Windows just after a killed process exits.
+     @param dir Directory returned by create
+     @param keep True to keep the directory
+     */
+    static async release(dir: string, keep: boolean): Promise<void> {
+        this.active.delete(dir)
+        if (!keep) {
+            await rm(dir, {recursive: true, force: true, maxRetries: 5, retryDelay: 100}).catch(() => {})
+        }
+    }
+
+    /*
+     Removes the temporary directories left by --keep-tmp, --keep-on-fail or an interrupted run (--clean)
+     Directories of tests this process is running are never removed.
+     @param dryRun Find the directories without removing them
+     @returns Paths of the directories removed, or that would be removed with dryRun, sorted
+     */
+    static async clean(dryRun = false): Promise<string[]> {
+        const root = tmpdir()
+        const entries = await readdir(root, {withFileTypes: true}).catch(() => [])
+        const dirs = entries
+            .filter((entry) => entry.isDirectory() && TMP_NAME.test(entry.name))
+            .map((entry) => join(root, entry.name))
+            .filter((dir) => !this.active.has(dir))
+            .sort()
+        if (!dryRun) {
+            await Promise.all(dirs.map((dir) => rm(dir, {recursive: true, force: true}).catch(() => {})))
+        }
+        return dirs
+    }
+
+    /*
+     Removes the directories of tests still running when the process exits
+     */
+    private static removeActive(): void {
+        for (const dir of this.active) {
+            try {
+                rmSync(dir, {recursive: true, force: true})
+            } catch {
+                // Ignore - the directory is in the system temp directory
+            }
+        }
+        this.active.clear()
+    }
+}
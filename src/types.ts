@@ -10,6 +10,20 @@ export type TestFile = {
     artifactDir: string
     isManual?: boolean // True if enable='manual' in config
     configDir?: string // Directory containing the config for this test
+    testCase?: TestCase // Data-driven case run by this test (from a .cases.json file)
+    casesError?: string // Error reading the test's .cases.json file
+    matrix?: string // Label of the matrix cell this test runs in (e.g., 'MODE=fast')
+    quarantined?: boolean // Listed in the quarantine file: failures do not fail the run
+    after?: string[] // Absolute paths of the tests this test runs after (from the after directive)
+    tags?: string[] // Labels from the tags directive, shown in JSON reports
+}
+
+/*
+ A data-driven case of a parameterized test
+ */
+export type TestCase = {
+    name: string // Case name, shown as foo.tst.sh[name]
+    env: Record<string, string> // TESTME_CASE and TESTME_CASE_<field> environment variables
 }
 
 /*
@@ -26,6 +40,59 @@ export type TestResult = {
         passed: number
         failed: number
     }
+    stdout?: string // Raw stdout of the last command run for the test
+    stderr?: string // Raw stderr of the last command run for the test
+    command?: string // Command line of the last command run for the test
+    attempts?: number // Number of attempts made when retries are enabled
+    flaky?: boolean // Passed only after one or more retries
+    sanitizer?: string // Sanitizer abort that failed the test (e.g., 'AddressSanitizer: heap-use-after-free')
+    target?: string // Target triple the test was built for when cross-compiling
+    truncated?: boolean // Output exceeded output.maxBytes and the rest was discarded
+    signal?: string // Signal that killed the test (e.g., 'SIGSEGV')
+    backtrace?: string // Debugger backtrace of a crashed test (--backtrace)
+    benchmarks?: BenchResult[] // Benchmark timings reported by the test (--bench)
+    subResults?: SubResult[] // Named results reported with "TESTME {...}" protocol lines
+}
+
+/*
+ A named result reported by a test on a "TESTME {...}" protocol line
+ */
+export type SubResult = {
+    name: string
+    status: 'pass' | 'fail' | 'skip'
+    message?: string // Failure or skip reason
+    file?: string // Source file of the check
+    line?: number // Source line of the check
+    duration?: number // Duration in milliseconds
+}
+
+/*
+ A benchmark timing reported by a test and its change against the baseline
+ */
+export type BenchResult = {
+    name: string // Benchmark name, qualified with the case and matrix cell labels
+    ns: number // Measured time in nanoseconds
+    baseline?: number // Baseline time in nanoseconds, if one is recorded
+    delta?: number // Change against the baseline in percent (positive is slower)
+    regressed?: boolean // Slower than the baseline by more than execution.benchThreshold
+}
+
+/*
+ Inline "testme:" directives read from the top of a test file
+ */
+export type TestDirectives = {
+    xfail?: boolean // Test is expected to fail (testme: xfail)
+    requires?: string[] // Executables that must be on PATH (testme: requires redis-cli, docker)
+    platforms?: string[] // Platforms the test runs on, '!' to exclude (testme: platform linux,darwin)
+    exitCode?: number | 'nonzero' // Expected exit code (testme: exit 2)
+    args?: string[] // Arguments passed to the test program (testme: args --flag value)
+    stdin?: string // File supplied as stdin, relative to the test (testme: stdin input.txt)
+    ports?: number // Free localhost ports to allocate, exported as TESTME_PORT0... (testme: ports 2)
+    chdir?: string // Working directory instead of the temporary directory, relative to the test (testme: chdir .)
+    resources?: string[] // Shared resources the test must not use concurrently with other tests (testme: resource db)
+    after?: string[] // Tests that must complete and pass first, relative to the test (testme: after setup.tst.sh)
+    suites?: string[] // Named suites the test belongs to, selected with --suite (testme: suite integration)
+    tags?: string[] // Labels selected with --tags and --not-tags (testme: tags slow, network)
 }
 
 /*
@@ -34,10 +101,33 @@ export type TestResult = {
 export type TestConfig = {
     enable?: boolean | 'manual' // Enable (true), disable (false), or run only when explicitly named ('manual')
     depth?: number // Minimum depth required to run tests in this directory (default: 0)
+    maxDepth?: number // Highest TESTME_DEPTH given to tests in this directory, capping --depth
     profile?: string // Build profile (dev, prod, debug, release, etc.) - defaults to env.PROFILE or 'dev'
     inherit?: boolean | string[] // Inherit from parent config: true (all), false (none), or array of keys to inherit
+    depends?: string[] // Files or directories (relative to the config) whose changes affect these tests (--since)
+    platform?: string | string[] // Platforms to run these tests on (windows, linux, darwin), '!' to exclude
+    suite?: string | string[] // Named suites these tests belong to, selected with --suite (e.g., 'integration')
+    matrix?: Record<string, (string | number | boolean)[]> // Run all tests once per combination of these variables
+    toolchain?: Record<string, string | number> // Minimum tool versions for --doctor, keyed by tool or language
+    setup?: string | string[] // Commands run before the whole run (root config) or before this group's tests
+    teardown?: string | string[] // Commands run after the whole run (root config) or after this group's tests
+    quarantine?: string // File listing known-flaky tests, relative to the config (default: testme.quarantine)
     compiler?: CompilerConfig
+    target?: TargetConfig
+    remote?: RemoteConfig
+    docker?: DockerConfig
     debug?: DebugConfig
+    valgrind?: ValgrindConfig
+    coverage?: CoverageConfig
+    golden?: GoldenConfig
+    redact?: RedactConfig
+    parse?: ParseConfig
+    build?: BuildConfig
+    discover?: DiscoverConfig
+    handlers?: Record<string, HandlerConfig> // Command templates for tests with other suffixes, keyed by suffix
+    shell?: ShellConfig
+    notify?: NotifyConfig
+    metrics?: MetricsConfig
     execution?: ExecutionConfig
     output?: OutputConfig
     patterns?: PatternConfig
@@ -66,10 +156,50 @@ type CompilerSettings = {
     linux?: PlatformCompilerSettings
 }
 
+/*
+ Cross-compilation target for C and Go tests
+ */
+export type TargetConfig = {
+    triple?: string // Target triple (e.g., 'aarch64-linux-gnu', 'arm-linux-gnueabihf')
+    cc?: string // C cross compiler (default: <triple>-gcc)
+    flags?: string[] // Extra C flags for the target (e.g., ['-march=armv7-a', '--sysroot=/opt/arm'])
+    goos?: string // GOOS for Go tests (default: derived from the triple)
+    goarch?: string // GOARCH for Go tests (default: derived from the triple)
+    runner?: string // Command that runs target binaries (e.g., 'qemu-arm -L /usr/arm-linux-gnueabihf')
+}
+
+/*
+ Remote execution of compiled tests over ssh
+ */
+export type RemoteConfig = {
+    host?: string // Host to run compiled tests on (e.g., 'root@board')
+    dir?: string // Remote staging directory (default: /tmp/testme)
+    env?: Record<string, string | number | boolean> // Variables exported on the remote host before each test
+    options?: string[] // Extra ssh and scp options (e.g., ['-o', 'Port=2222', '-i', 'board.key'])
+}
+
+/*
+ Running tests inside a container image
+ */
+export type DockerConfig = {
+    image?: string // Image to run tests in (e.g., 'gcc:13')
+    args?: string[] // Extra docker run arguments (e.g., ['--network', 'host', '-v', '/data:/data'])
+    build?: boolean // Also build compiled tests inside the container (default: false, build on the host)
+}
+
 /*
  Configuration for language-specific compilers
  */
 export type CompilerConfig = {
+    cc?:
+        | string
+        | {
+              windows?: string
+              macosx?: string
+              linux?: string
+          } // C compiler name or path, taking precedence over c.compiler and $CC
+    pkgs?: string[] // pkg-config packages whose --cflags and --libs are added when compiling C tests
+    toolchain?: 'gcc' | 'clang' | 'msvc' | 'mingw' // C compiler family to detect when no compiler is named
     c?: {
         compiler?:
             | string
@@ -85,8 +215,32 @@ export type CompilerConfig = {
         msvc?: CompilerSettings
     }
     es?: {
+        interpreter?: string // Ejscript interpreter (default: ejs)
         require?: string | string[]
     }
+    python?: {
+        interpreter?: string // Python interpreter (default: python3, falling back to python)
+        venv?: string // Virtualenv directory to activate (relative to the config directory)
+        args?: string[] // Extra interpreter flags (e.g., ['-X', 'dev'])
+    }
+    javascript?: {
+        mode?: 'bun' | 'node' // How .tst.js files are run (default: bun)
+        node?: string // Node executable (default: node)
+        flags?: string[] // Extra node flags (e.g., ['--experimental-vm-modules'])
+        module?: 'auto' | 'esm' | 'cjs' // Module system of tests run with node (default: auto, inferred from syntax)
+    }
+    go?: {
+        mode?: 'auto' | 'run' | 'test' // Run .go files with `go run` or their package with `go test` (default: auto)
+    }
+    typescript?: {
+        mode?: 'bun' | 'tsc' | 'ts-node' // How .tst.ts files are run (default: bun)
+        tsconfig?: string // Existing tsconfig.json to compile with (relative to the config directory)
+    }
+    rust?: {
+        compiler?: string // Rust compiler (default: rustc)
+        flags?: string[] // Compiler flags (e.g., ['--edition', '2021'])
+        libraries?: string[] // Crates as 'name=path' (--extern) or native libraries (-l)
+    }
 }
 
 /*
@@ -110,15 +264,158 @@ export type DebugConfig = {
     py?: PlatformDebugger // Python debugger: vscode, pdb, or path
     go?: PlatformDebugger // Go debugger: vscode, delve, or path
     es?: PlatformDebugger // Ejscript debugger: vscode, or path
+    sh?: PlatformDebugger // Shell debugger: xtrace (sh -x), or path such as bashdb
+}
+
+/*
+ Configuration for code coverage collection (--coverage)
+ */
+export type CoverageConfig = {
+    enable?: boolean // Collect coverage for C and Go tests (also enabled by --coverage)
+    threshold?: number // Fail the run if total coverage is below this percentage
+    exclude?: string[] // Glob patterns of C source files to drop from the coverage report (e.g., third-party code)
+}
+
+/*
+ Merged coverage of the tests a handler ran with --coverage
+ */
+export type CoverageReport = {
+    language: string // Language named in the summary (e.g., 'Go')
+    unit: string // What the percentage counts (e.g., 'statements')
+    path: string // Merged report file
+    percent: number // Total coverage percentage
+}
+
+/*
+ A rule rewriting volatile text in test stdout before golden-file comparison: the name of a built-in
+ normalizer (timestamps, tmp, pids, uuids, addresses, durations) or a regular expression and replacement
+ */
+export type GoldenNormalizer = string | {pattern: string; replace?: string; flags?: string}
+
+/*
+ Configuration for golden-file (.expected) comparison
+ */
+export type GoldenConfig = {
+    normalize?: GoldenNormalizer[] // Rules applied in order to stdout before comparing and when accepting
+    numericTolerance?: NumericTolerance // Compare numbers within these tolerances instead of as text
+}
+
+/*
+ Tolerances for numbers in golden-file comparison. A number matches if it is within either tolerance.
+ */
+export type NumericTolerance = {
+    absolute?: number // Largest allowed difference (default: 0)
+    relative?: number // Largest allowed difference as a fraction of the expected value (default: 0)
+}
+
+/*
+ Secrets replaced with *** in the output captured from tests and build commands
+ */
+export type RedactConfig = {
+    env?: string[] // Environment variables whose values are secrets
+    patterns?: string[] // Regular expressions matching secrets
+}
+
+/*
+ Assertion markers counted in test stdout. Lines starting with a marker, after leading whitespace, are assertions.
+ */
+export type ParseConfig = {
+    assertions?: boolean // Count assertions from the pass and fail markers (default: false)
+    pass?: string[] // Markers of passing assertions (default: ['PASS:', '✓'])
+    fail?: string[] // Markers of failing assertions (default: ['FAIL:', '✗'])
+}
+
+/*
+ Commands that build and run tests with a suffix that has no built-in handler (e.g., '.tst.xyz')
+ Templates are split into arguments like a command line, then {src}, {out}, {tmp} and {dir} are replaced in each.
+ */
+export type HandlerConfig = {
+    build?: string // Command run before the test, skipped while {out} is newer than {src} (e.g., 'xyzc -o {out} {src}')
+    run: string // Command that runs the test, which passes if it exits with 0 (e.g., '{out}' or 'xyz {src}')
+}
+
+/*
+ Interpreter settings for Unix shell tests (.tst.sh)
+ */
+export type ShellConfig = {
+    interpreter?: string // Interpreter for scripts without a shebang (default: /bin/sh)
+    flags?: string[] // Interpreter options placed before the script, e.g. ['-e', '-u']
+    powershell?: string // PowerShell for .tst.ps1 tests (default: pwsh, then Windows PowerShell on Windows)
+}
+
+/*
+ Where compiled tests and their build artifacts are written (root config only)
+ */
+export type BuildConfig = {
+    dir?: string // Directory for all artifact directories, relative to the config, mirroring the source tree
+}
+
+/*
+ How the test tree is walked during discovery (root config only)
+ */
+export type DiscoverConfig = {
+    followSymlinks?: boolean // Descend into symlinked directories (default: false). Symlinked files are always tests.
+    exclude?: string[] // Gitignore-style patterns for paths not to walk, relative to the root config
+}
+
+/*
+ Configuration for the webhook notification sent when a run completes
+ */
+export type NotifyConfig = {
+    webhook?: string // URL to POST the run summary to
+    onlyOnFailure?: boolean // Only notify when a test fails or the run is interrupted (default: false)
+    format?: 'json' | 'slack' // Payload shape: the JSON run summary (default) or a Slack incoming-webhook message
+    headers?: Record<string, string> // Extra request headers (e.g., Authorization)
+}
+
+/*
+ Configuration for pushing run metrics to a Prometheus Pushgateway
+ */
+export type MetricsConfig = {
+    push?: string // Pushgateway URL (also set by --metrics-push)
+    job?: string // Value of the job grouping label (default: 'testme')
+    instance?: string // Value of the instance grouping label (default: the host name)
+}
+
+/*
+ Configuration for running C test binaries under valgrind
+ */
+export type ValgrindConfig = {
+    enable?: boolean // Run C test binaries under valgrind (also enabled by --valgrind)
+    suppressions?: string // Suppression file (relative to the config directory)
+    flags?: string[] // Extra valgrind options
 }
 
 /*
  Configuration for test execution behavior
  */
 export type ExecutionConfig = {
-    timeout: number // Timeout per test in seconds
-    parallel: boolean
-    workers?: number
+    timeout: number // Timeout per test in seconds (0 for no timeout)
+    timeouts?: Record<string, number> // Per-test timeouts in seconds keyed by test name or glob pattern
+    idleTimeout?: number // Kill a test that produces no output for this many seconds (0 or unset to disable)
+    retries?: number // Re-run failing tests up to this many times (default: 0)
+    accept?: boolean // Rewrite .expected files with the current test stdout
+    expectedNewlines?: 'normalize' | 'exact' | 'trim' // Newline handling for .expected comparison
+    bench?: boolean // Compare TESTME-BENCH timings against the .bench baseline files (--bench)
+    benchUpdate?: boolean // Record TESTME-BENCH timings as the new baseline (--bench-update)
+    benchThreshold?: number // Regression in percent of the baseline that fails a benchmark (default: 10)
+    asan?: boolean // Build C and Go tests with AddressSanitizer
+    backtrace?: boolean // Capture a debugger backtrace when a C test crashes (--backtrace)
+    cc?: string // C compiler chosen with --cc, overriding compiler.cc, compiler.c.compiler and $CC
+    shell?: string // Shell test interpreter chosen with --shell, overriding shebangs and shell.interpreter
+    target?: string // Target triple chosen with --target, overriding target.triple
+    remote?: string // Host chosen with --remote, overriding remote.host
+    docker?: string // Image chosen with --docker, overriding docker.image
+    keepTmp?: boolean // Keep each test's temporary directory (--keep-tmp)
+    keepTmpOnFail?: boolean // Keep the temporary directories of tests that did not pass (--keep-tmp-on-fail)
+    keepOnFail?: boolean // Remove the artifacts and temporary directories of passing tests only (--keep-on-fail)
+    artifactDir?: string // Name of the artifact directory in each test directory (root config only, default: .testme)
+    chdir?: string // Working directory for tests instead of their temporary directory, relative to each test file
+    tmpDir?: string // Temporary directory allocated to a test (exported as TESTME_TMP)
+    resources?: string[] // Shared resources used by every test in this directory (e.g., ['db'])
+    parallel: boolean // Run tests in this directory concurrently (false serializes them)
+    workers?: number // Number of parallel workers (default: number of CPUs)
+    buildWorkers?: number // Number of tests compiled concurrently ahead of running them (--build-workers)
     keepArtifacts?: boolean
     rebuild?: boolean // Force recompilation of C tests even if binary is up-to-date
     stepMode?: boolean
@@ -128,10 +425,23 @@ export type ExecutionConfig = {
     showWarnings?: boolean // Show compiler warnings and compile command line
     iterations?: number
     stopOnFailure?: boolean // Stop testing as soon as a test fails
+    failFast?: boolean // Abort the run on the first failure, killing tests still running
+    maxFailures?: number // Stop starting new tests once this many tests have failed
     duration?: number // Duration in seconds (exported as TESTME_DURATION)
     testClass?: string // Test class filter (exported as TESTME_CLASS)
+    seed?: number // Shuffle test order with this seed (set by --shuffle or --seed)
+    strict?: boolean // Fail tests whose required tools are missing instead of skipping them
+    exitCode?: number | 'nonzero' // Expected test exit code (default: 0), 'nonzero' for any non-zero code
+    args?: string[] // Arguments passed to each test program
+    stdin?: string // File supplied as each test's stdin (relative to the config file)
+    ports?: number[] // Free localhost ports allocated to a test by its ports directive (exported as TESTME_PORT0...)
 }
 
+/*
+ When console output is colored: auto colors a terminal unless NO_COLOR is set
+ */
+export type ColorMode = 'auto' | 'always' | 'never'
+
 /*
  Configuration for output formatting and display
  */
@@ -141,7 +451,15 @@ export type OutputConfig = {
     colors: boolean
     quiet?: boolean
     errorsOnly?: boolean
+    summaryFailures?: boolean // Repeat failed tests, grouped by directory, after the full detailed listing
+    slowest?: number // List this many of the slowest tests after the run
     live?: boolean // Stream test output in real-time to console (requires TTY)
+    verboseOnFail?: boolean // Run tests verbosely but print output only for tests that fail (--verbose-on-fail)
+    progress?: boolean // Show a progress line with completed, passed and failed counts instead of passing tests
+    color?: ColorMode // When to color console output (default: auto); colors: false disables auto
+    maxBytes?: number | string // Output retained per test, in bytes or a size such as '10MB' (default: unlimited)
+    report?: string | string[] // Machine-readable reports: FORMAT[:FILE] (e.g., 'junit:results.xml')
+    github?: boolean // Write GitHub Actions annotations (default: when GITHUB_ACTIONS is 'true')
 }
 
 /*
@@ -205,6 +523,7 @@ export type HealthCheckConfig = {
 /*
  Configuration for environment variables to set during test execution
  Supports platform-specific overrides via windows, macosx, linux keys and default fallback values
+ The file key names a .env file of KEY=value lines loaded beneath these variables
  */
 export type EnvironmentConfig = {
     [key: string]:
@@ -224,6 +543,7 @@ export type EnvironmentConfig = {
     linux?: {
         [key: string]: string
     }
+    file?: string // .env file path (relative to the config file, default: .env at the test root)
 }
 
 /*
@@ -233,7 +553,12 @@ export type CliOptions = {
     patterns: string[]
     config?: string
     clean: boolean
+    cleanDryRun?: boolean // List the artifacts and temporary directories --clean would remove
     list: boolean
+    listJson?: boolean // Print the --list output as a JSON array with language and timeout
+    listSuites?: boolean // Print the defined suites with their test counts and exit
+    dryRun?: boolean // Print compile, run and service commands without running them
+    strict?: boolean // Fail tests whose required tools are missing instead of skipping them
     verbose: boolean
     keep: boolean
     rebuild: boolean // Force recompilation of C tests even if binary is up-to-date
@@ -247,17 +572,84 @@ export type CliOptions = {
     show: boolean
     warning: boolean // Show compiler warnings and compile command
     workers?: number
+    buildWorkers?: number // Compile tests ahead of running them with this many build workers
     profile?: string
     init: boolean
+    force?: boolean // Let --init overwrite an existing testme.json5
+    doctor?: boolean // Check the toolchain needed by the selected tests and exit
+    completion?: string // Print the completion script for this shell (bash, zsh or fish) and exit
+    emitHeader?: string // Print the assertion header or result helper for this language (c, go or sh) and exit
     new?: string
     continue: boolean
     noServices: boolean
+    noGithub?: boolean // Do not write GitHub Actions annotations even when GITHUB_ACTIONS is 'true'
+    noQuarantine?: boolean // Ignore the quarantine file, so quarantined failures fail the run
     iterations?: number
     stop: boolean
+    summaryFailures?: boolean // Repeat failed tests grouped by directory after the detailed listing
+    slowest?: number // Number of slowest tests to list after the run
     live: boolean
+    verboseOnFail?: boolean // Print the complete output of failing tests only
+    progress?: boolean // Show live completed/total, pass and fail counts and elapsed time
+    color?: ColorMode // Color console output: auto (TTY without NO_COLOR), always or never
+    maxOutput?: number // Bytes of output retained per test (overrides output.maxBytes)
+    watch: boolean // Re-run affected tests when files change
     duration?: number // Duration in seconds
-    timeout?: number // Timeout in seconds (overrides config)
+    timeout?: number // Timeout in seconds (overrides config, 0 for no timeout)
+    idleTimeout?: number // Idle timeout in seconds (overrides config, 0 for none)
+    retries?: number // Retry count for failing tests (overrides config)
+    accept?: boolean // Rewrite .expected files with the current test stdout
+    bench?: boolean // Compare benchmark timings against their baselines
+    benchUpdate?: boolean // Record benchmark timings as the new baselines (implies bench)
+    valgrind?: boolean // Run C test binaries under valgrind
+    asan?: boolean // Build C and Go tests with AddressSanitizer
+    backtrace?: boolean // Capture a debugger backtrace of crashed C tests
+    cc?: string // C compiler name or path (overrides config and $CC)
+    shell?: string // Interpreter for shell tests (overrides shebangs and shell.interpreter)
+    target?: string // Target triple to cross-compile C and Go tests for (overrides target.triple)
+    remote?: string // Host to run compiled tests on over ssh (overrides remote.host)
+    docker?: string // Image to run tests in with docker run (overrides docker.image)
+    keepTmp?: boolean // Keep each test's temporary directory after the test
+    keepTmpOnFail?: boolean // Keep the temporary directories of tests that did not pass
+    keepOnFail?: boolean // Remove the artifacts and temporary directories of passing tests, keep failing ones
+    coverage?: boolean // Collect coverage and write merged coverage.out (Go) and coverage.info (C) reports
+    coverageThreshold?: number // Fail the run if total coverage is below this percentage (implies coverage)
     testClass?: string // Test class filter (exports TESTME_CLASS)
+    failFast?: boolean // Abort the run on the first failure, killing tests still running
+    maxFailures?: number // Stop starting new tests once this many tests have failed
+    failed?: boolean // Only run tests recorded as failing in the last run
+    flakyReport?: boolean // List tests with unstable outcomes in .testme/history.json and exit
+    shuffle?: boolean // Randomize test order
+    seed?: number // Seed for a reproducible random test order (implies shuffle)
+    since?: string // Only run tests affected by changes since this git ref
+    shard?: {index: number; count: number} // Only run shard index (1-based) of count (--shard i/n)
+    balance?: boolean // Balance shards by recorded test durations instead of path hash
+    checkConfig?: boolean // Validate all testme.json5 files in the tree and exit
+    ignoreUnknownKeys?: boolean // Do not warn about unknown configuration keys
+    showConfig?: string // Print the effective configuration for this directory and exit
+    env?: Record<string, string> // Environment variables from --env (override the config and .env file)
+    set?: Record<string, unknown> // Config values from --set keyed by dotted key (applied over the merged config)
+    matrix?: Record<string, string> // Matrix variables pinned to one value (restricts the matrix cells run)
+    filter?: string // Only run tests whose relative path matches this regular expression
+    suite?: string[] // Only run tests in these named suites (--suite, repeatable)
+    tags?: string[] // Tag expressions that must all match (--tags: a+b requires both, a,b either)
+    notTags?: string[] // Skip tests carrying any of these tags (--not-tags)
+    exclude?: string // Skip tests whose relative path matches this regular expression
+    report?: string[] // Reports: FORMAT[:FILE], one per --report (overrides config)
+    json?: string // Write structured JSON results to this file
+    events?: string // NDJSON event feed destination: fd:N or file:PATH
+    logdir?: string // Directory for a log file per test, mirroring the test tree (--logdir)
+    metricsPush?: string // Prometheus Pushgateway URL to push run metrics to (overrides metrics.push)
+}
+
+/*
+ Summary of a completed run, sent to the notify.webhook and the metrics Pushgateway
+ */
+export type RunSummary = {
+    results: TestResult[]
+    rootDir: string
+    elapsed: number // Wall-clock time of the run in milliseconds
+    interrupted?: boolean // The run was stopped by a signal before all tests ran
 }
 
 /*
@@ -282,6 +674,8 @@ export enum TestType {
     Ejscript = 'ejscript',
     Python = 'python',
     Go = 'go',
+    Rust = 'rust',
+    Custom = 'custom', // Suffix registered in the handlers configuration
 }
 
 /*
@@ -294,6 +688,10 @@ export enum TestStatus {
     Failed = 'failed',
     Skipped = 'skipped',
     Error = 'error',
+    Timeout = 'timeout',
+    IdleTimeout = 'idle-timeout', // Killed after producing no output for execution.idleTimeout
+    XFail = 'xfail', // Failed as expected (testme: xfail)
+    XPass = 'xpass', // Passed but marked as expected to fail
 }
 
 /*
@@ -302,8 +700,31 @@ export enum TestStatus {
 export type TestHandler = {
     canHandle(file: TestFile): boolean
     prepare?(file: TestFile): Promise<void>
+    build?(file: TestFile, config: TestConfig): Promise<BuildResult> // Compile ahead of execute (--build-workers)
     execute(file: TestFile, config: TestConfig): Promise<TestResult>
     cleanup?(file: TestFile, config?: TestConfig): Promise<void>
+    collectCoverage?(rootDir: string, config: CoverageConfig): Promise<CoverageReport | null> // Merge at end of run
+}
+
+/*
+ Outcome of compiling a test ahead of running it
+ */
+export type BuildResult = {
+    success: boolean
+    duration: number // Compile time in milliseconds
+    output: string // Compiler output
+    error?: string
+}
+
+/*
+ Type definition for reporters (JUnit XML, TAP, JSON and custom formats)
+ Reporters receive the run's lifecycle events as they happen so partial reports survive an interrupted run
+ */
+export type Reporter = {
+    runStart?(tests: TestFile[]): void // Before the first test, with every test planned to run
+    testStart?(file: TestFile): void // As a test starts running
+    testEnd(result: TestResult): void // As each test completes or is skipped
+    runEnd(results: TestResult[], elapsedTime?: number): void // After teardown, with all results
 }
 
 /*
@@ -313,6 +734,12 @@ export type DiscoveryOptions = {
     rootDir: string
     patterns: string[]
     excludePatterns: string[]
+    artifactDir?: string // Name of the artifact directory in each test directory (default: .testme)
+    buildDir?: string // Absolute directory holding all artifact directories, mirroring the tree under buildRoot
+    buildRoot?: string // Directory whose tree buildDir mirrors (the root config directory)
+    followSymlinks?: boolean // Descend into symlinked directories, skipping any already walked (cycles)
+    ignore?: string[] // Gitignore-style patterns (discover.exclude) for directories and files not to walk
+    handlers?: string[] // Suffixes of tests run by configured handlers (TestType.Custom)
 }
 
 /*
@@ -321,6 +748,6 @@ export type DiscoveryOptions = {
 export type ArtifactManager = {
     createArtifactDir(testFile: TestFile): Promise<string>
     cleanArtifactDir(testFile: TestFile): Promise<void>
-    cleanAllArtifacts(rootDir: string): Promise<void>
+    cleanAllArtifacts(rootDir: string, names?: string[], dryRun?: boolean): Promise<string[]>
     getArtifactPath(testFile: TestFile, filename: string): string
 }
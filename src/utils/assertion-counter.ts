@@ -3,16 +3,46 @@
 
     Responsibilities:
     - Parse test output for ✓ (pass) and ✗ (fail) symbols
+    - Parse standardized "PASS file:line" and "FAIL file:line" lines from testme.h soft assertions
+    - Parse configurable pass/fail markers from test stdout (parse.assertions)
     - Return assertion counts
 */
 
+import type {TestConfig, TestResult} from '../types.ts'
+
 export type AssertionCounts = {
     passed: number
     failed: number
 }
 
+// Default markers of passing and failing assertion lines for parse.assertions
+export const PASS_MARKERS = ['PASS:', '✓']
+export const FAIL_MARKERS = ['FAIL:', '✗']
+
+// Soft assertion line printed by testme.h: "PASS math.tst.c:12 sum == 3" or "FAIL math.tst.c:14 ..."
+const CHECK_LINE = /^(PASS|FAIL) \S+:\d+\b/gm
+
+/**
+ * Count soft assertions from the standardized PASS and FAIL lines in output
+ *
+ * @param output - Test output string
+ * @returns Object with passed and failed counts, or null if no assertion lines found
+ */
+export function countChecks(output: string): AssertionCounts | null {
+    let passed = 0
+    let failed = 0
+    for (const match of (output || '').replace(/\r/g, '').matchAll(CHECK_LINE)) {
+        if (match[1] === 'PASS') {
+            passed++
+        } else {
+            failed++
+        }
+    }
+    return passed === 0 && failed === 0 ? null : {passed, failed}
+}
+
 /**
- * Count test assertions from output by looking for ✓ and ✗ symbols
+ * Count test assertions from output by looking for ✓ and ✗ symbols and soft assertion lines
  *
  * @param output - Test output string
  * @returns Object with passed and failed counts, or null if no assertions found
@@ -24,11 +54,16 @@ export function countAssertions(output: string): AssertionCounts | null {
 
     // Count ✓ symbols (pass)
     const passedMatches = output.match(/✓/g)
-    const passed = passedMatches ? passedMatches.length : 0
+    let passed = passedMatches ? passedMatches.length : 0
 
     // Count ✗ symbols (fail)
     const failedMatches = output.match(/✗/g)
-    const failed = failedMatches ? failedMatches.length : 0
+    let failed = failedMatches ? failedMatches.length : 0
+
+    // Add PASS and FAIL lines from soft assertions
+    const checks = countChecks(output)
+    passed += checks?.passed || 0
+    failed += checks?.failed || 0
 
     // Only return counts if we found at least one assertion marker
     if (passed === 0 && failed === 0) {
@@ -37,3 +72,50 @@ export function countAssertions(output: string): AssertionCounts | null {
 
     return {passed, failed}
 }
+
+/**
+ * Count assertions from lines that start with a pass or fail marker, after leading whitespace
+ * Soft assertion lines from testme.h are counted as well.
+ *
+ * @param stdout - Test stdout
+ * @param pass - Markers of passing assertions
+ * @param fail - Markers of failing assertions
+ * @returns Object with passed and failed counts, or null if no marked lines found
+ */
+export function countMarkers(
+    stdout: string,
+    pass: string[] = PASS_MARKERS,
+    fail: string[] = FAIL_MARKERS
+): AssertionCounts | null {
+    const counts = {passed: 0, failed: 0}
+    for (const line of (stdout || '').split(/\r?\n/)) {
+        const text = line.trimStart()
+        const check = countChecks(line)
+        if (check) {
+            counts.passed += check.passed
+            counts.failed += check.failed
+        } else if (fail.some((marker) => marker && text.startsWith(marker))) {
+            counts.failed++
+        } else if (pass.some((marker) => marker && text.startsWith(marker))) {
+            counts.passed++
+        }
+    }
+    return counts.passed === 0 && counts.failed === 0 ? null : counts
+}
+
+/**
+ * Replace a test's assertion counts with those parsed from its stdout markers when parse.assertions is enabled
+ * The test status is unchanged: a test can fail on its exit status even if every marked assertion passed.
+ *
+ * @param result - Test result
+ * @param config - Test configuration
+ * @returns The result with its assertion counts
+ */
+export function parseAssertions(result: TestResult, config: TestConfig): TestResult {
+    const parse = config.parse
+    if (!parse?.assertions) {
+        return result
+    }
+    const assertions = countMarkers(result.stdout ?? result.output, parse.pass, parse.fail)
+    return {...result, assertions: assertions || undefined}
+}
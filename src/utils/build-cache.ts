@@ -0,0 +1,151 @@
+/*
+    build-cache.ts - Reuse of compiled C and Rust tests whose inputs have not changed
+
+    Responsibilities:
+    - Hash the source file, the compiler command and the headers the source included
+    - Record the hashes beside each binary after a successful build
+    - Decide whether a cached binary is current, and why not
+    - Parse the make dependency files written by the compiler's -MMD option
+*/
+
+import {createHash} from 'crypto'
+import {existsSync} from 'fs'
+import {readFile, writeFile} from 'fs/promises'
+import {basename, resolve} from 'path'
+
+/**
+ * Hashes of everything a binary was built from, stored in <binary>.build.json
+ */
+export type BuildRecord = {
+    source: string // Hash of the source file
+    command: string // Hash of the compiler and its arguments
+    headers: Record<string, string> // Hash of each included header, keyed by path
+}
+
+/**
+ * Hash a string or file contents
+ *
+ * @param data - Data to hash
+ * @returns Hex SHA-256 digest
+ */
+function hash(data: string | Buffer): string {
+    return createHash('sha256').update(data).digest('hex')
+}
+
+/**
+ * Hash a file, or return an empty string if it cannot be read
+ *
+ * @param path - File to hash
+ * @returns Hex digest, or '' for a missing file
+ */
+async function hashFile(path: string): Promise<string> {
+    try {
+        return hash(await readFile(path))
+    } catch {
+        return ''
+    }
+}
+
+/**
+ * Get the path of the build record for a binary
+ *
+ * @param binaryPath - Compiled test binary
+ * @returns Record path beside the binary
+ */
+export function getRecordPath(binaryPath: string): string {
+    return `${binaryPath}.build.json`
+}
+
+/**
+ * Hash the compiler command
+ * Arguments are joined with NUL so ['-a b'] and ['-a', 'b'] differ.
+ *
+ * @param compiler - Compiler command
+ * @param args - Compiler arguments, without the dependency file options
+ * @returns Hex digest
+ */
+export function hashCommand(compiler: string, args: string[]): string {
+    return hash([compiler, ...args].join('\0'))
+}
+
+/**
+ * Check whether a cached binary can be reused
+ *
+ * @param binaryPath - Compiled test binary
+ * @param sourcePath - Test source file
+ * @param command - Hash of the compiler command from hashCommand
+ * @returns Null if the binary is current, otherwise why it must be rebuilt
+ */
+export async function checkBuild(binaryPath: string, sourcePath: string, command: string): Promise<string | null> {
+    if (!existsSync(binaryPath)) {
+        return 'no cached binary'
+    }
+    let record: BuildRecord
+    try {
+        record = JSON.parse(await readFile(getRecordPath(binaryPath), 'utf-8'))
+    } catch {
+        return 'no build record'
+    }
+    if (record.command !== command) {
+        return 'compiler or flags changed'
+    }
+    if (record.source !== (await hashFile(sourcePath))) {
+        return 'source changed'
+    }
+    for (const [header, digest] of Object.entries(record.headers || {})) {
+        if ((await hashFile(header)) !== digest) {
+            return `${basename(header)} changed`
+        }
+    }
+    return null
+}
+
+/**
+ * Record what a binary was built from
+ * Headers are read from the dependency file written by the compiler. Without one (MSVC) only the source and
+ * command are recorded.
+ *
+ * @param binaryPath - Compiled test binary
+ * @param sourcePath - Test source file
+ * @param command - Hash of the compiler command from hashCommand
+ * @param depPath - Dependency file written by -MMD -MF, if any
+ * @param cwd - Directory the compiler ran in, which relative paths in the dependency file are relative to
+ */
+export async function saveBuild(
+    binaryPath: string,
+    sourcePath: string,
+    command: string,
+    depPath?: string,
+    cwd: string = process.cwd()
+): Promise<void> {
+    const headers: Record<string, string> = {}
+    if (depPath) {
+        try {
+            for (const dependency of parseDependencies(await readFile(depPath, 'utf-8'))) {
+                const path = resolve(cwd, dependency)
+                if (path !== resolve(sourcePath)) {
+                    headers[path] = await hashFile(path)
+                }
+            }
+        } catch {
+            // No dependency file: the source and command still decide
+        }
+    }
+    const record: BuildRecord = {source: await hashFile(sourcePath), command, headers}
+    await writeFile(getRecordPath(binaryPath), JSON.stringify(record, null, 4) + '\n')
+}
+
+/**
+ * Parse a make dependency file
+ * Line continuations are joined and escaped spaces kept in names. The targets before the first ': ' are dropped.
+ *
+ * @param text - Contents of a .d file
+ * @returns Prerequisite paths in order, without duplicates
+ */
+export function parseDependencies(text: string): string[] {
+    const joined = text.replace(/\\\r?\n/g, ' ')
+    const colon = joined.search(/:(\s|$)/)
+    const list = colon >= 0 ? joined.slice(colon + 1) : joined
+    const paths = (list.match(/(?:\\.|[^\s\\])+/g) || []).map((path) => path.replace(/\\([ #])/g, '$1'))
+    return [...new Set(paths)]
+}
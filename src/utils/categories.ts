@@ -0,0 +1,65 @@
+/*
+    categories.ts - Result categories for summaries and reports
+
+    Responsibilities:
+    - Sort results into pass, fail, crash, timeout, error and skip so a logic bug can be told from a crash
+      or a broken environment
+    - Count results per category for the summary and the JSON report
+*/
+
+import type {TestResult} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {isCrash} from './crash.ts'
+
+/*
+ Category of a completed test
+ */
+export type ResultCategory = 'pass' | 'fail' | 'crash' | 'timeout' | 'error' | 'skip'
+
+// Categories in the order they are reported
+export const RESULT_CATEGORIES: readonly ResultCategory[] = ['pass', 'fail', 'crash', 'timeout', 'error', 'skip']
+
+/**
+ * Get the category of a test result
+ * Expected failures count as passes and unexpected passes as failures. Crashes are failed tests killed by a
+ * fault or abort signal or stopped by a sanitizer, timeouts include idle timeouts and errors are tests that
+ * could not be built or launched.
+ *
+ * @param result - Test result
+ * @returns Category
+ */
+export function getCategory(result: TestResult): ResultCategory {
+    switch (result.status) {
+        case TestStatus.Passed:
+        case TestStatus.XFail:
+            return 'pass'
+        case TestStatus.Failed:
+            return isCrash(result) ? 'crash' : 'fail'
+        case TestStatus.XPass:
+            return 'fail'
+        case TestStatus.Timeout:
+        case TestStatus.IdleTimeout:
+            return 'timeout'
+        case TestStatus.Skipped:
+            return 'skip'
+        default:
+            return 'error'
+    }
+}
+
+/**
+ * Count test results per category
+ *
+ * @param results - Test results
+ * @returns Count for every category, including those with no results
+ */
+export function countCategories(results: TestResult[]): Record<ResultCategory, number> {
+    const counts = Object.fromEntries(RESULT_CATEGORIES.map((category) => [category, 0])) as Record<
+        ResultCategory,
+        number
+    >
+    for (const result of results) {
+        counts[getCategory(result)]++
+    }
+    return counts
+}
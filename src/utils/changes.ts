@@ -0,0 +1,73 @@
+/*
+    changes.ts - Changed file detection for --since
+
+    Responsibilities:
+    - List files changed relative to a git ref
+    - Match changed files against a configuration's depends list
+*/
+
+import {resolve, sep} from 'path'
+
+/**
+ * Run a git command and capture its output
+ *
+ * @param args - Git arguments
+ * @param cwd - Working directory
+ * @returns Exit code, stdout and stderr
+ */
+async function git(args: string[], cwd: string): Promise<{code: number; stdout: string; stderr: string}> {
+    const proc = Bun.spawn(['git', ...args], {cwd, stdout: 'pipe', stderr: 'pipe'})
+    const [stdout, stderr, code] = await Promise.all([
+        new Response(proc.stdout).text(),
+        new Response(proc.stderr).text(),
+        proc.exited,
+    ])
+    return {code, stdout, stderr}
+}
+
+/**
+ * Get the files changed since a git ref (git diff --name-only REF)
+ *
+ * @param ref - Git ref to compare the working tree against (branch, tag or commit)
+ * @param cwd - Directory inside the repository
+ * @returns Absolute paths of changed files, or null if cwd is not in a git repository
+ * @throws Error if the ref cannot be resolved
+ */
+export async function getChangedFiles(ref: string, cwd: string): Promise<string[] | null> {
+    let top
+    try {
+        top = await git(['rev-parse', '--show-toplevel'], cwd)
+    } catch {
+        // git is not installed
+        return null
+    }
+    if (top.code !== 0) {
+        return null
+    }
+    const repoDir = top.stdout.trim()
+    const diff = await git(['diff', '--name-only', ref, '--'], cwd)
+    if (diff.code !== 0) {
+        throw new Error(`Cannot get changes since "${ref}": ${diff.stderr.trim()}`)
+    }
+    return diff.stdout
+        .split('\n')
+        .filter((line) => line.trim())
+        .map((line) => resolve(repoDir, line.trim()))
+}
+
+/**
+ * Test whether any changed file is listed in a depends list
+ * A depends entry may name a file or a directory, in which case any file below it matches.
+ *
+ * @param changes - Absolute paths of changed files
+ * @param depends - Dependency paths from the configuration
+ * @param configDir - Directory that relative depends paths are resolved against
+ * @returns True if a dependency changed
+ */
+export function dependsOnChanges(changes: string[], depends: string[] | undefined, configDir: string): boolean {
+    if (!depends || depends.length === 0) {
+        return false
+    }
+    const paths = depends.map((path) => resolve(configDir, path))
+    return changes.some((change) => paths.some((path) => change === path || change.startsWith(path + sep)))
+}
@@ -0,0 +1,127 @@
+/*
+    crash.ts - Crash reporting for tests killed by a signal
+
+    Responsibilities:
+    - Describe the signal that killed a test and tell crashes from other signals
+    - Capture a backtrace of a crashed test with gdb or lldb in batch mode (--backtrace)
+*/
+
+import type {TestResult} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {PlatformDetector} from '../platform/detector.ts'
+import {existsSync, readdirSync, statSync} from 'fs'
+import {join} from 'path'
+
+// Signals raised by the program itself (faults and aborts) rather than sent to it, e.g. by Ctrl+C
+const CRASH_SIGNALS: Record<string, string> = {
+    SIGSEGV: 'segmentation fault',
+    SIGBUS: 'bus error',
+    SIGABRT: 'abort',
+    SIGFPE: 'floating point exception',
+    SIGILL: 'illegal instruction',
+    SIGTRAP: 'trace trap',
+    SIGSYS: 'bad system call',
+}
+
+// Most time a debugger may take to produce a backtrace, in milliseconds
+const DEBUGGER_TIMEOUT = 60000
+
+/**
+ * Check if a signal is a crash
+ *
+ * @param signal - Signal name (e.g., 'SIGSEGV')
+ * @returns True for faults and aborts, false for signals such as SIGINT, SIGTERM and SIGKILL
+ */
+export function isCrashSignal(signal: string): boolean {
+    return signal in CRASH_SIGNALS
+}
+
+/**
+ * Check if a test failed because it crashed rather than on its own
+ *
+ * @param result - Test result
+ * @returns True for failed tests killed by a crash signal or stopped by a sanitizer
+ */
+export function isCrash(result: TestResult): boolean {
+    if (result.status !== TestStatus.Failed) {
+        return false
+    }
+    return result.sanitizer !== undefined || (result.signal !== undefined && isCrashSignal(result.signal))
+}
+
+/**
+ * Describe the signal that killed a test
+ *
+ * @param signal - Signal name
+ * @returns Description, e.g. "Crashed with SIGSEGV (segmentation fault)" or "Killed by SIGTERM"
+ */
+export function describeSignal(signal: string): string {
+    return isCrashSignal(signal) ? `Crashed with ${signal} (${CRASH_SIGNALS[signal]})` : `Killed by ${signal}`
+}
+
+/**
+ * Find the core file left by a crashed test
+ * Only core files written to the test's working directory after it started are found ("core" or "core.PID").
+ *
+ * @param dir - Working directory of the test
+ * @param since - Time the test started (milliseconds since the epoch)
+ * @returns Path of the newest core file, or undefined if there is none
+ */
+export function findCoreFile(dir: string, since: number): string | undefined {
+    if (!existsSync(dir)) {
+        return undefined
+    }
+    return readdirSync(dir)
+        .filter((name) => name === 'core' || /^core\.\d+$/.test(name))
+        .map((name) => ({path: join(dir, name), time: statSync(join(dir, name)).mtimeMs}))
+        .filter((core) => core.time >= since)
+        .sort((a, b) => b.time - a.time)[0]?.path
+}
+
+/**
+ * Capture the backtrace of a crashed test program
+ * The core file is read if there is one, otherwise the program is run again under the debugger with the same
+ * arguments, environment and working directory. lldb is preferred on macOS and gdb elsewhere.
+ *
+ * @param program - Path of the test program
+ * @param args - Test arguments
+ * @param options - Working directory, environment and stdin file of the test, and its core file (if any)
+ * @returns Debugger output with the backtrace, or null if no debugger is available
+ */
+export async function getBacktrace(
+    program: string,
+    args: string[],
+    options: {cwd: string; env: Record<string, string>; stdin?: string; core?: string}
+): Promise<string | null> {
+    const debuggers = PlatformDetector.isMacOS() ? ['lldb', 'gdb'] : ['gdb', 'lldb']
+    for (const name of debuggers) {
+        const path = await PlatformDetector.findInPath(name)
+        if (!path) {
+            continue
+        }
+        const command =
+            name === 'gdb'
+                ? options.core
+                    ? [path, '-batch', '-ex', 'bt', program, options.core]
+                    : [path, '-batch', '-ex', 'run', '-ex', 'bt', '--args', program, ...args]
+                : options.core
+                  ? [path, '--batch', '-c', options.core, '-o', 'bt', program]
+                  : [path, '--batch', '-o', 'run', '-o', 'bt', '--', program, ...args]
+        const proc = Bun.spawn(command, {
+            cwd: options.cwd,
+            env: {...process.env, ...options.env},
+            stdin: options.stdin && !options.core ? Bun.file(options.stdin) : 'ignore',
+            stdout: 'pipe',
+            stderr: 'pipe',
+        })
+        const timer = setTimeout(() => proc.kill('SIGKILL'), DEBUGGER_TIMEOUT)
+        const [stdout, stderr] = await Promise.all([
+            new Response(proc.stdout).text(),
+            new Response(proc.stderr).text(),
+            proc.exited,
+        ])
+        clearTimeout(timer)
+        return `${name}:\n${(stdout + stderr).trim()}`
+    }
+    return null
+}
@@ -0,0 +1,167 @@
+/*
+    diff.ts - Line-based unified diff
+
+    Responsibilities:
+    - Compute the longest common subsequence of two line arrays
+    - Render differences as unified diff hunks with context lines
+    - Compare lines with a custom match function (e.g., expected lines with wildcards)
+*/
+
+// Above this many line comparisons, report the whole text as changed rather than computing an LCS
+const MAX_CELLS = 4_000_000
+
+// A line of an edit script: unchanged (' ', the original line), removed ('-') or added ('+')
+export type Edit = {op: ' ' | '-' | '+'; line: string}
+
+// Tests whether an original line matches a new line
+export type LineMatcher = (expected: string, actual: string) => boolean
+
+const exactMatch: LineMatcher = (expected, actual) => expected === actual
+
+/**
+ * Produce a unified diff between two texts
+ *
+ * @param expected - Original text
+ * @param actual - New text
+ * @param fromName - Label for the original text (--- line)
+ * @param toName - Label for the new text (+++ line)
+ * @param context - Number of unchanged lines to show around each change
+ * @param match - Line comparison, exact by default. Unchanged lines show the original line.
+ * @returns Unified diff, or an empty string if the texts are identical
+ */
+export function unifiedDiff(
+    expected: string,
+    actual: string,
+    fromName: string,
+    toName: string,
+    context: number = 3,
+    match: LineMatcher = exactMatch
+): string {
+    if (expected === actual) {
+        return ''
+    }
+    const edits = diffLines(splitLines(expected), splitLines(actual), match)
+    if (edits.every((edit) => edit.op === ' ')) {
+        return ''
+    }
+    const lines = [`--- ${fromName}`, `+++ ${toName}`]
+
+    // Group edits into hunks separated by more than 2 * context unchanged lines
+    let i = 0
+    while (i < edits.length) {
+        while (i < edits.length && edits[i]!.op === ' ') {
+            i++
+        }
+        if (i >= edits.length) {
+            break
+        }
+        const start = Math.max(0, i - context)
+        let end = i
+        let unchanged = 0
+        while (end < edits.length && unchanged <= context * 2) {
+            unchanged = edits[end]!.op === ' ' ? unchanged + 1 : 0
+            end++
+        }
+        end = Math.min(edits.length, end - Math.max(0, unchanged - context))
+
+        const hunk = edits.slice(start, end)
+        const before = edits.slice(0, start)
+        const oldStart = before.filter((edit) => edit.op !== '+').length + 1
+        const newStart = before.filter((edit) => edit.op !== '-').length + 1
+        const oldCount = hunk.filter((edit) => edit.op !== '+').length
+        const newCount = hunk.filter((edit) => edit.op !== '-').length
+        const oldRange = `${oldCount ? oldStart : oldStart - 1},${oldCount}`
+        const newRange = `${newCount ? newStart : newStart - 1},${newCount}`
+        lines.push(`@@ -${oldRange} +${newRange} @@`)
+        for (const edit of hunk) {
+            lines.push(edit.op + edit.line)
+        }
+        i = end
+    }
+    return lines.join('\n') + '\n'
+}
+
+/**
+ * Split text into lines, marking a missing final newline like diff(1)
+ *
+ * @param text - Text to split
+ * @returns Array of lines
+ */
+function splitLines(text: string): string[] {
+    if (!text) {
+        return []
+    }
+    const lines = text.split('\n')
+    if (lines[lines.length - 1] === '') {
+        lines.pop()
+    } else {
+        lines[lines.length - 1] += '\n\\ No newline at end of file'
+    }
+    return lines
+}
+
+/**
+ * Compute the line edits that turn one array of lines into another
+ *
+ * @param a - Original lines
+ * @param b - New lines
+ * @param match - Line comparison, exact by default
+ * @returns Edit script of unchanged, removed and added lines
+ */
+export function diffLines(a: string[], b: string[], match: LineMatcher = exactMatch): Edit[] {
+    // Trim the common prefix and suffix so the LCS table only covers the changed region
+    let prefix = 0
+    while (prefix < a.length && prefix < b.length && match(a[prefix]!, b[prefix]!)) {
+        prefix++
+    }
+    let suffix = 0
+    while (
+        suffix < a.length - prefix &&
+        suffix < b.length - prefix &&
+        match(a[a.length - 1 - suffix]!, b[b.length - 1 - suffix]!)
+    ) {
+        suffix++
+    }
+    const head: Edit[] = a.slice(0, prefix).map((line) => ({op: ' ', line}))
+    const tail: Edit[] = a.slice(a.length - suffix).map((line) => ({op: ' ', line}))
+    const x = a.slice(prefix, a.length - suffix)
+    const y = b.slice(prefix, b.length - suffix)
+
+    if (x.length * y.length > MAX_CELLS) {
+        return [
+            ...head,
+            ...x.map((line): Edit => ({op: '-', line})),
+            ...y.map((line): Edit => ({op: '+', line})),
+            ...tail,
+        ]
+    }
+
+    // lcs[i][j] is the LCS length of x[i..] and y[j..]
+    const lcs: number[][] = Array.from({length: x.length + 1}, () => new Array(y.length + 1).fill(0))
+    for (let i = x.length - 1; i >= 0; i--) {
+        for (let j = y.length - 1; j >= 0; j--) {
+            lcs[i]![j] = match(x[i]!, y[j]!) ? lcs[i + 1]![j + 1]! + 1 : Math.max(lcs[i + 1]![j]!, lcs[i]![j + 1]!)
+        }
+    }
+
+    const middle: Edit[] = []
+    let i = 0
+    let j = 0
+    while (i < x.length && j < y.length) {
+        if (match(x[i]!, y[j]!)) {
+            middle.push({op: ' ', line: x[i++]!})
+            j++
+        } else if (lcs[i + 1]![j]! >= lcs[i]![j + 1]!) {
+            middle.push({op: '-', line: x[i++]!})
+        } else {
+            middle.push({op: '+', line: y[j++]!})
+        }
+    }
+    while (i < x.length) {
+        middle.push({op: '-', line: x[i++]!})
+    }
+    while (j < y.length) {
+        middle.push({op: '+', line: y[j++]!})
+    }
+    return [...head, ...middle, ...tail]
+}
@@ -0,0 +1,89 @@
+/*
+    dotenv.ts - .env file loading
+
+    Responsibilities:
+    - Parse KEY=value lines with comments and quoted values
+    - Locate the environment file for a configuration (env.file, default .env at the test root)
+*/
+
+import type {TestConfig} from '../types.ts'
+import {existsSync} from 'fs'
+import {readFile} from 'fs/promises'
+import {resolve} from 'path'
+
+/**
+ * Parse the contents of a .env file
+ *
+ * Blank lines and lines starting with # are ignored and an optional "export " prefix is allowed.
+ * Values may be single quoted (taken literally) or double quoted (\n, \t, \" and \\ escapes).
+ * Unquoted values are trimmed and end at a " #" comment.
+ *
+ * @param text - File contents
+ * @returns Variables in file order
+ */
+export function parseDotEnv(text: string): Record<string, string> {
+    const vars: Record<string, string> = {}
+    for (const raw of text.split(/\r?\n/)) {
+        const line = raw.trim()
+        if (!line || line.startsWith('#')) {
+            continue
+        }
+        const match = line.match(/^(?:export\s+)?([A-Za-z_][\w.]*)\s*=\s*(.*)$/)
+        if (!match) {
+            continue
+        }
+        const value = match[2]!
+        const single = value.match(/^'([^']*)'/)
+        const double = value.match(/^"((?:[^"\\]|\\.)*)"/)
+        if (single) {
+            vars[match[1]!] = single[1]!
+        } else if (double) {
+            vars[match[1]!] = double[1]!.replace(/\\([nrt"\\])/g, (_, ch: string) =>
+                ch === 'n' ? '\n' : ch === 'r' ? '\r' : ch === 't' ? '\t' : ch
+            )
+        } else {
+            vars[match[1]!] = value.replace(/\s+#.*$/, '').trim()
+        }
+    }
+    return vars
+}
+
+/**
+ * Get the environment file for a configuration
+ *
+ * A configured env.file is resolved relative to the configuration file that defines it.
+ * Otherwise a .env file at the test root is used if present.
+ *
+ * @param config - Test configuration
+ * @returns Path of the file and whether it was explicitly configured, or undefined if there is none
+ */
+export function getDotEnvPath(config: TestConfig): {path: string; required: boolean} | undefined {
+    const file = (config.environment || config.env)?.file
+    if (typeof file === 'string' && file) {
+        const baseDir = (config as any)._envConfigDir || config.configDir || process.cwd()
+        return {path: resolve(baseDir, file), required: true}
+    }
+    const path = resolve(process.cwd(), '.env')
+    return existsSync(path) ? {path, required: false} : undefined
+}
+
+/**
+ * Load the environment file for a configuration
+ *
+ * @param config - Test configuration
+ * @returns Variables from the file (empty if there is no file)
+ * @throws Error if a configured env.file does not exist
+ */
+export async function loadDotEnv(config: TestConfig): Promise<Record<string, string>> {
+    const location = getDotEnvPath(config)
+    if (!location) {
+        return {}
+    }
+    if (!existsSync(location.path)) {
+        if (location.required) {
+            throw new Error(`Environment file not found: ${location.path}`)
+        }
+        return {}
+    }
+    return parseDotEnv(await readFile(location.path, 'utf-8'))
+}
@@ -0,0 +1,85 @@
+/*
+    dry-run.ts - Command display for --dry-run
+
+    Responsibilities:
+    - Track whether the run is a dry run
+    - Print commands in a form that can be pasted into a shell instead of running them
+*/
+
+export class DryRun {
+    private static enabled = false
+
+    /**
+     * Enable dry-run mode for the rest of the process
+     */
+    static enable(): void {
+        DryRun.enabled = true
+    }
+
+    /**
+     * Check if dry-run mode is enabled
+     *
+     * @returns True if commands should be printed instead of run
+     */
+    static isEnabled(): boolean {
+        return DryRun.enabled
+    }
+
+    /**
+     * Print a command that would be run
+     *
+     * @param description - What the command does (e.g. "Compilation of math.tst.c")
+     * @param command - Command to run
+     * @param args - Command arguments
+     * @param options - Working directory, environment and stdin file the command would run with
+     */
+    static print(
+        description: string,
+        command: string,
+        args: string[],
+        options: {cwd?: string; env?: Record<string, string>; stdin?: string} = {}
+    ): void {
+        console.log(`📋 ${description}:\n   ${DryRun.formatCommand(command, args, options)}`)
+    }
+
+    /**
+     * Format a command as a single shell line
+     * Only environment variables that differ from the current environment are shown.
+     *
+     * @param command - Command to run
+     * @param args - Command arguments
+     * @param options - Working directory, environment and stdin file the command would run with
+     * @returns Shell command line, e.g. "cd /dir && NAME='value' cmd arg < input.txt"
+     */
+    static formatCommand(
+        command: string,
+        args: string[],
+        options: {cwd?: string; env?: Record<string, string>; stdin?: string} = {}
+    ): string {
+        const parts: string[] = []
+        for (const [key, value] of Object.entries(options.env || {})) {
+            if (process.env[key] !== value) {
+                parts.push(`${key}=${DryRun.quote(value)}`)
+            }
+        }
+        parts.push(...[command, ...args].map((arg) => DryRun.quote(arg)))
+        if (options.stdin) {
+            parts.push('<', DryRun.quote(options.stdin))
+        }
+        const line = parts.join(' ')
+        return options.cwd ? `cd ${DryRun.quote(options.cwd)} && ${line}` : line
+    }
+
+    /**
+     * Quote an argument for a POSIX shell if it contains special characters
+     *
+     * @param arg - Argument to quote
+     * @returns Argument, single-quoted if needed
+     */
+    static quote(arg: string): string {
+        if (arg !== '' && /^[\w@%+=:,./-]+$/.test(arg)) {
+            return arg
+        }
+        return `'${arg.replace(/'/g, `'\\''`)}'`
+    }
+}
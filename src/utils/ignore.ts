@@ -0,0 +1,124 @@
+/*
+    ignore.ts - Gitignore-style path patterns for discovery (discover.exclude)
+
+    Responsibilities:
+    - Compile gitignore-style patterns to regular expressions
+    - Decide whether a path relative to the test root is excluded, with the last matching pattern winning
+*/
+
+/**
+ * A compiled pattern
+ */
+export type IgnoreRule = {
+    regex: RegExp // Matches a root-relative path using '/' separators
+    negate: boolean // Pattern started with '!': re-include matching paths
+    directory: boolean // Pattern ended with '/': only matches directories
+}
+
+/**
+ * Compile gitignore-style patterns
+ * `*` and `?` do not match '/', `**` matches any number of directories and `[...]` is a character class. A pattern
+ * with a leading or inner '/' is anchored to the test root, otherwise it matches at any depth. A trailing '/' limits
+ * it to directories and a leading '!' re-includes what an earlier pattern excluded. Empty patterns and '#' comments
+ * are ignored, and `\!` or `\#` start a pattern with a literal character.
+ *
+ * @param patterns - Patterns in order
+ * @returns Compiled rules
+ */
+export function compileIgnore(patterns: string[]): IgnoreRule[] {
+    const rules: IgnoreRule[] = []
+    for (let pattern of patterns) {
+        pattern = pattern.trim()
+        if (!pattern || pattern.startsWith('#')) {
+            continue
+        }
+        const negate = pattern.startsWith('!')
+        if (negate) {
+            pattern = pattern.slice(1)
+        }
+        const directory = pattern.endsWith('/')
+        if (directory) {
+            pattern = pattern.replace(/\/+$/, '')
+        }
+        if (pattern.startsWith('/')) {
+            pattern = pattern.slice(1)
+        } else if (!pattern.includes('/')) {
+            pattern = `**/${pattern}`
+        }
+        if (pattern) {
+            rules.push({regex: globToRegExp(pattern), negate, directory})
+        }
+    }
+    return rules
+}
+
+/**
+ * Check whether a path is excluded
+ * Descent stops at an excluded directory, so as with git a file cannot be re-included if its directory is excluded.
+ *
+ * @param rules - Rules from compileIgnore
+ * @param path - Path relative to the test root using '/' separators
+ * @param directory - Whether the path is a directory
+ * @returns True if the last matching rule excludes the path
+ */
+export function isIgnored(rules: IgnoreRule[], path: string, directory: boolean): boolean {
+    let ignored = false
+    for (const rule of rules) {
+        if ((!rule.directory || directory) && rule.regex.test(path)) {
+            ignored = !rule.negate
+        }
+    }
+    return ignored
+}
+
+/**
+ * Convert one glob to a regular expression matching the whole path
+ *
+ * @param glob - Pattern without a leading or trailing '/'
+ * @returns Anchored regular expression
+ */
+function globToRegExp(glob: string): RegExp {
+    let source = ''
+    for (let i = 0; i < glob.length; i++) {
+        const c = glob[i]
+        if (c === '*' && glob[i + 1] === '*' && (i === 0 || glob[i - 1] === '/')) {
+            if (i + 2 === glob.length) {
+                // Trailing '/**' matches everything inside
+                source += '.*'
+                i++
+                continue
+            }
+            if (glob[i + 2] === '/') {
+                // '**/' matches zero or more directories
+                source += '(?:.*/)?'
+                i += 2
+                continue
+            }
+        }
+        if (c === '*') {
+            source += '[^/]*'
+        } else if (c === '?') {
+            source += '[^/]'
+        } else if (c === '[' && glob.indexOf(']', i + 2) > 0) {
+            const end = glob.indexOf(']', i + 2)
+            const set = glob.slice(i + 1, end)
+            source += `[${set.startsWith('!') ? '^' + set.slice(1) : set}]`
+            i = end
+        } else if (c === '\\' && i + 1 < glob.length) {
+            source += escape(glob[++i])
+        } else {
+            source += escape(c)
+        }
+    }
+    return new RegExp(`^${source}$`)
+}
+
+/**
+ * Escape a character for use in a regular expression
+ *
+ * @param c - Character
+ * @returns Escaped character
+ */
+function escape(c: string): string {
+    return c.replace(/[.+^${}()|[\]\\*?]/g, '\\$&')
+}
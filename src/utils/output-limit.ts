@@ -0,0 +1,51 @@
+/*
+    output-limit.ts - Cap on the test output retained in memory
+
+    Responsibilities:
+    - Parse output.maxBytes and --max-output sizes such as "10MB"
+    - Split output chunks at the cap and mark where output was discarded
+*/
+
+// Size with an optional unit, e.g. "512", "64K", "10MB" or "1.5 GB"
+const SIZE_PATTERN = /^(\d+(?:\.\d+)?)\s*([KMG]?)B?$/i
+
+const UNITS: Record<string, number> = {'': 1, K: 1024, M: 1024 * 1024, G: 1024 * 1024 * 1024}
+
+/**
+ * Parse a byte size
+ *
+ * @param value - Number of bytes, or a size with a K, KB, M, MB, G or GB suffix (powers of 1024)
+ * @returns Size in bytes, or null if the value is not a valid size
+ */
+export function parseByteSize(value: string | number): number | null {
+    if (typeof value === 'number') {
+        return Number.isFinite(value) && value >= 0 ? Math.floor(value) : null
+    }
+    const match = SIZE_PATTERN.exec(value.trim())
+    if (!match) {
+        return null
+    }
+    return Math.floor(parseFloat(match[1]!) * UNITS[match[2]!.toUpperCase()]!)
+}
+
+/**
+ * Take the part of an output chunk that fits within the remaining capacity
+ * The test keeps writing after the cap is reached, the rest of its output is read and discarded.
+ *
+ * @param chunk - Bytes read from the test
+ * @param remaining - Bytes that may still be retained
+ * @returns Bytes to retain, at most remaining bytes of the chunk
+ */
+export function takeOutput(chunk: Uint8Array, remaining: number): Uint8Array {
+    return chunk.length <= remaining ? chunk : chunk.subarray(0, Math.max(remaining, 0))
+}
+
+/**
+ * Get the marker appended to output that exceeded the cap
+ *
+ * @param maxBytes - Cap in bytes
+ * @returns Marker line
+ */
+export function getTruncationMarker(maxBytes: number): string {
+    return `\n[output truncated after ${maxBytes} bytes]\n`
+}
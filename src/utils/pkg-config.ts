@@ -0,0 +1,113 @@
+/*
+    pkg-config.ts - pkg-config support for C tests
+
+    Responsibilities:
+    - Query pkg-config for the compile and link flags of the packages in compiler.pkgs
+    - Report packages that pkg-config cannot find
+    - Cache results so tests sharing packages run pkg-config once
+*/
+
+/**
+ * Compile and link flags for a set of packages
+ */
+export type PackageFlags = {
+    cflags: string[] // Include paths and defines, placed before the source file
+    libs: string[] // Library paths and libraries, placed after the source file
+}
+
+// Results keyed by packages, syntax and PKG_CONFIG_PATH
+const cache = new Map<string, Promise<PackageFlags>>()
+
+/**
+ * Get the compile and link flags for packages from pkg-config
+ * The search path follows PKG_CONFIG_PATH in the given environment, so a value set in testme.json5 or the
+ * shell applies. Flags are split on whitespace.
+ *
+ * @param packages - Package names (e.g. ['openssl', 'zlib'])
+ * @param msvc - True to request MSVC command line syntax (--msvc-syntax)
+ * @param env - Environment variables that override the current environment (e.g. PKG_CONFIG_PATH)
+ * @returns Flags for all packages
+ * @throws Error naming the missing packages, or if pkg-config is not installed
+ */
+export function getPackageFlags(
+    packages: string[],
+    msvc: boolean,
+    env: Record<string, string> = {}
+): Promise<PackageFlags> {
+    const searchPath = env.PKG_CONFIG_PATH ?? process.env.PKG_CONFIG_PATH ?? ''
+    const key = `${packages.join(' ')}|${msvc}|${searchPath}`
+    let flags = cache.get(key)
+    if (!flags) {
+        flags = queryPackages(packages, msvc, {...process.env, ...env} as Record<string, string>)
+        cache.set(key, flags)
+        // Do not keep failures so a later test can retry after the problem is fixed
+        flags.catch(() => cache.delete(key))
+    }
+    return flags
+}
+
+/**
+ * Run pkg-config for each package
+ *
+ * @param packages - Package names
+ * @param msvc - True for MSVC syntax
+ * @param env - Full environment for pkg-config
+ * @returns Flags for all packages
+ */
+async function queryPackages(packages: string[], msvc: boolean, env: Record<string, string>): Promise<PackageFlags> {
+    const syntax = msvc ? ['--msvc-syntax'] : []
+    const result: PackageFlags = {cflags: [], libs: []}
+    const missing: string[] = []
+    let detail = ''
+
+    for (const name of packages) {
+        const cflags = await runPkgConfig([...syntax, '--cflags', name], env)
+        if (cflags.exitCode !== 0) {
+            missing.push(name)
+            detail ||= cflags.stderr.trim().split('\n')[0] || ''
+            continue
+        }
+        const libs = await runPkgConfig([...syntax, '--libs', name], env)
+        result.cflags.push(...split(cflags.stdout))
+        result.libs.push(...split(libs.stdout))
+    }
+    if (missing.length > 0) {
+        const hint = env.PKG_CONFIG_PATH ? `PKG_CONFIG_PATH=${env.PKG_CONFIG_PATH}` : 'PKG_CONFIG_PATH is not set'
+        throw new Error(
+            `pkg-config package(s) not found: ${missing.join(', ')} (${hint})` + (detail ? `\n${detail}` : '')
+        )
+    }
+    return result
+}
+
+/**
+ * Run pkg-config with arguments
+ *
+ * @param args - pkg-config arguments
+ * @param env - Environment for pkg-config
+ * @returns Exit code and output
+ * @throws Error if pkg-config is not installed
+ */
+async function runPkgConfig(
+    args: string[],
+    env: Record<string, string>
+): Promise<{exitCode: number; stdout: string; stderr: string}> {
+    let proc
+    try {
+        proc = Bun.spawn(['pkg-config', ...args], {stdout: 'pipe', stderr: 'pipe', stdin: 'ignore', env})
+    } catch {
+        throw new Error('pkg-config is not installed or not on PATH (needed for compiler.pkgs)')
+    }
+    const [stdout, stderr] = await Promise.all([new Response(proc.stdout).text(), new Response(proc.stderr).text()])
+    return {exitCode: await proc.exited, stdout, stderr}
+}
+
+/**
+ * Split pkg-config output into flags
+ *
+ * @param output - pkg-config output
+ * @returns Flags
+ */
+function split(output: string): string[] {
+    return output.trim().split(/\s+/).filter(Boolean)
+}
@@ -0,0 +1,72 @@
+/*
+    redact.ts - Redaction of secrets in captured test output
+
+    Responsibilities:
+    - Build one pattern from the redact config: values of the named environment variables and regular expressions
+    - Replace secrets with *** in complete output and in output read in chunks
+*/
+
+import type {RedactConfig} from '../types.ts'
+
+// Replacement for each secret
+export const REDACTED = '***'
+
+/**
+ * Build the pattern matching the secrets named by the redact config
+ * Longer values are tried first so a secret containing another is replaced whole. Unset and empty
+ * variables are ignored.
+ *
+ * @param config - Redact configuration
+ * @param env - Environment of the command whose output is redacted
+ * @returns Global pattern, or null if there is nothing to redact
+ * @throws Error if a pattern is not a valid regular expression
+ */
+export function getRedactPattern(
+    config: RedactConfig | undefined,
+    env: Record<string, string | undefined>
+): RegExp | null {
+    const values = (config?.env || [])
+        .map((name) => env[name])
+        .filter((value): value is string => !!value)
+        .sort((a, b) => b.length - a.length)
+        .map((value) => value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&'))
+    const patterns = config?.patterns || []
+    for (const pattern of patterns) {
+        try {
+            new RegExp(pattern)
+        } catch (error) {
+            throw new Error(`Invalid redact pattern "${pattern}": ${(error as Error).message}`)
+        }
+    }
+    const alternatives = [...values, ...patterns.map((pattern) => `(?:${pattern})`)]
+    return alternatives.length > 0 ? new RegExp(alternatives.join('|'), 'g') : null
+}
+
+/**
+ * Replace secrets in text
+ *
+ * @param text - Captured output
+ * @param pattern - Pattern from getRedactPattern, or null
+ * @returns Text with each secret replaced by ***
+ */
+export function redact(text: string, pattern: RegExp | null): string {
+    return pattern ? text.replace(pattern, REDACTED) : text
+}
+
+/**
+ * Create a filter that redacts output read in chunks
+ * A secret may be split across reads, so the text after the last newline is held back until the next
+ * newline or the end of the output.
+ *
+ * @param pattern - Pattern from getRedactPattern
+ * @returns Function taking the next chunk, or undefined at the end, and returning the redacted text to emit
+ */
+export function createRedactFilter(pattern: RegExp): (chunk?: string) => string {
+    let pending = ''
+    return (chunk?: string) => {
+        const text = pending + (chunk ?? '')
+        const end = chunk === undefined ? text.length : text.lastIndexOf('\n') + 1
+        pending = text.slice(end)
+        return redact(text.slice(0, end), pattern)
+    }
+}
@@ -0,0 +1,81 @@
+/*
+    sanitizer.ts - AddressSanitizer support for compiled tests
+
+    Responsibilities:
+    - Provide compiler flags and ASAN_OPTIONS for --asan builds
+    - Detect sanitizer reports in test stderr and apply them to test results
+*/
+
+import type {TestResult} from '../types.ts'
+import {TestStatus} from '../types.ts'
+import {PlatformDetector} from '../platform/detector.ts'
+
+// Start of a sanitizer report, e.g. "==1234==ERROR: AddressSanitizer: heap-use-after-free on address ..."
+const REPORT_PATTERN = /^=+\d+=+ERROR: (\w+Sanitizer): ([^\n]*)/m
+
+/**
+ * Get compiler flags that enable AddressSanitizer
+ *
+ * @param msvc - True for MSVC command line syntax
+ * @returns Flags to append to the compile command
+ */
+export function getSanitizerFlags(msvc: boolean): string[] {
+    return msvc ? ['/fsanitize=address', '/Zi'] : ['-fsanitize=address', '-fno-omit-frame-pointer', '-g']
+}
+
+/**
+ * Get the ASAN_OPTIONS value for sanitized test runs
+ * Options already set by the user are appended so they take precedence.
+ *
+ * @param existing - Current ASAN_OPTIONS value (if any)
+ * @returns ASAN_OPTIONS value
+ */
+export function getSanitizerOptions(existing?: string): string {
+    // Leak detection is only supported by LeakSanitizer on Linux
+    const options = ['halt_on_error=1', 'print_summary=1', ...(PlatformDetector.isLinux() ? ['detect_leaks=1'] : [])]
+    return [...options, ...(existing ? [existing] : [])].join(':')
+}
+
+/**
+ * Find a sanitizer report in test output
+ *
+ * @param stderr - Test stderr
+ * @returns Sanitizer name and error kind (e.g., "AddressSanitizer: heap-use-after-free") with the report text,
+ *          or null if no sanitizer report is present
+ */
+export function findSanitizerReport(stderr: string): {kind: string; report: string} | null {
+    const match = REPORT_PATTERN.exec(stderr)
+    if (!match) {
+        return null
+    }
+    // Keep the error kind and drop addresses, e.g. "heap-use-after-free on address 0x6020... at pc ..."
+    const kind = match[2]!.split(/ on | \(| in thread|:/)[0]!.trim()
+
+    // The report runs from the ERROR line (and the ===== separator line before it) to the end of output
+    let start = match.index
+    const previous = stderr.lastIndexOf('\n', start - 2) + 1
+    if (start > 0 && /^=+$/.test(stderr.slice(previous, start - 1).trim())) {
+        start = previous
+    }
+    return {kind: `${match[1]}: ${kind}`, report: stderr.slice(start).trim()}
+}
+
+/**
+ * Mark a test result as a sanitizer abort if its stderr contains a sanitizer report
+ * The test fails even if it exited with status 0, and the report becomes the error text.
+ *
+ * @param result - Test result from an --asan run
+ * @returns Result with sanitizer set, or the original result if no report was found
+ */
+export function applySanitizerReport(result: TestResult): TestResult {
+    const found = findSanitizerReport(result.stderr || '')
+    if (!found) {
+        return result
+    }
+    return {
+        ...result,
+        status: TestStatus.Failed,
+        sanitizer: found.kind,
+        error: `${found.kind}\n${found.report}`,
+    }
+}
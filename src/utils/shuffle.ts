@@ -0,0 +1,49 @@
+/*
+    shuffle.ts - Reproducible random test ordering
+
+    Responsibilities:
+    - Generate seeds for --shuffle
+    - Provide a seeded pseudo-random generator so a seed always yields the same order
+*/
+
+/**
+ * Generate a random seed
+ *
+ * @returns Unsigned 32-bit seed
+ */
+export function randomSeed(): number {
+    return Math.floor(Math.random() * 0x100000000)
+}
+
+/**
+ * Create a seeded pseudo-random number generator (mulberry32)
+ *
+ * @param seed - Unsigned 32-bit seed
+ * @returns Function returning numbers in [0, 1)
+ */
+export function seededRandom(seed: number): () => number {
+    let state = seed >>> 0
+    return () => {
+        state = (state + 0x6d2b79f5) >>> 0
+        let t = state
+        t = Math.imul(t ^ (t >>> 15), t | 1)
+        t ^= t + Math.imul(t ^ (t >>> 7), t | 61)
+        return ((t ^ (t >>> 14)) >>> 0) / 0x100000000
+    }
+}
+
+/**
+ * Shuffle items with a Fisher-Yates shuffle
+ *
+ * @param items - Items to shuffle (not modified)
+ * @param random - Random number generator from seededRandom()
+ * @returns Shuffled copy of the items
+ */
+export function shuffle<T>(items: T[], random: () => number): T[] {
+    const result = [...items]
+    for (let i = result.length - 1; i > 0; i--) {
+        const j = Math.floor(random() * (i + 1))
+        ;[result[i], result[j]] = [result[j]!, result[i]!]
+    }
+    return result
+}
@@ -0,0 +1,26 @@
+/*
+    slowest.ts - Slowest test selection for --slowest
+
+    Responsibilities:
+    - Pick the tests that took longest so CI time can be optimized
+*/
+
+import type {TestResult} from '../types.ts'
+import {TestStatus} from '../types.ts'
+
+/**
+ * Select the slowest tests of a run
+ *
+ * Durations are each test's own wall-clock time (measured around the test, not summed across
+ * parallel workers), so they reflect how long a test really holds a worker.
+ *
+ * @param results - Results from the run
+ * @param count - Number of tests to select
+ * @returns Up to count results, longest duration first (skipped tests are omitted)
+ */
+export function getSlowestTests(results: TestResult[], count: number): TestResult[] {
+    return results
+        .filter((result) => result.status !== TestStatus.Skipped)
+        .sort((a, b) => b.duration - a.duration)
+        .slice(0, count)
+}
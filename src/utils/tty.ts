@@ -3,11 +3,61 @@
  Provides functions to detect interactive terminals and control cursor/line output
  */
 
+import type {ColorMode} from '../types.ts'
+import {format} from 'util'
+
+/*
+ Set when stdout carries a machine-readable stream (e.g., TAP) and must not receive terminal control codes
+ */
+let stdoutReserved = false
+
+/*
+ Set while console output is redirected to a stream given by a program embedding the runner
+ */
+let outputRedirected = false
+
+/*
+ Reserves stdout for a machine-readable report stream
+ Human-readable console output is redirected to stderr and in-place progress updates are disabled
+ */
+export function reserveStdout(): void {
+    if (stdoutReserved) {
+        return
+    }
+    stdoutReserved = true
+    console.log = console.error
+    console.info = console.error
+}
+
+/*
+ Redirects human-readable console output to a stream
+ Used when the runner is embedded in another program. In-place progress updates are disabled while
+ output is redirected.
+ @param stream Stream to write console output to
+ @returns Function that restores console output
+ */
+export function redirectOutput(stream: NodeJS.WritableStream): () => void {
+    const saved = {log: console.log, info: console.info, warn: console.warn, error: console.error}
+    const write = (...args: unknown[]) => {
+        stream.write(format(...args) + '\n')
+    }
+    console.log = console.info = console.warn = console.error = write
+    outputRedirected = true
+    return () => {
+        Object.assign(console, saved)
+        outputRedirected = false
+    }
+}
+
 /*
  Checks if the output is an interactive terminal (TTY)
  @returns true if stdout is a TTY, false otherwise
  */
 export function isInteractiveTTY(): boolean {
+    if (stdoutReserved || outputRedirected) {
+        return false
+    }
+
     // First check process.stdout.isTTY (works in Node.js)
     if (typeof process !== 'undefined' && process.stdout && process.stdout.isTTY === true) {
         return true
@@ -75,6 +125,9 @@ export const ANSI = {
 
     // Show cursor
     showCursor: '\x1b[?25h',
+
+    // Clear the screen and scrollback, and move cursor to the top left
+    clearScreen: '\x1b[2J\x1b[3J\x1b[H',
 }
 
 /*
@@ -105,6 +158,17 @@ export function clearCurrentLine(): void {
     }
 }
 
+/*
+ Clears the terminal screen (used between watch mode runs)
+ Does nothing if output is not an ANSI-capable terminal
+ */
+export function clearScreen(): void {
+    if (isInteractiveTTY() && supportsANSI()) {
+        // Written synchronously so output from the next run cannot land before the clear
+        process.stdout.write(ANSI.clearScreen)
+    }
+}
+
 /*
  Writes text and moves to a new line (standard output)
  @param text Text to write
@@ -112,3 +176,27 @@ export function clearCurrentLine(): void {
 export function writeLine(text: string): void {
     console.log(text)
 }
+
+/*
+ Decides whether console output is colored
+ With auto, output is colored only on an ANSI-capable terminal and when the NO_COLOR environment
+ variable is not set (https://no-color.org). The colors configuration setting can turn auto off.
+ @param mode --color or output.color setting (default auto)
+ @param enabled output.colors configuration setting
+ @returns True to color console output
+ */
+export function useColor(mode: ColorMode = 'auto', enabled = true): boolean {
+    if (mode !== 'auto') {
+        return mode === 'always'
+    }
+    return enabled && !process.env.NO_COLOR && supportsANSI()
+}
+
+/*
+ Removes ANSI escape sequences, such as colors, from text written to report files
+ @param text Text that may contain escape sequences
+ @returns Plain text
+ */
+export function stripAnsi(text: string): string {
+    return text.replace(/\x1b\[[0-9;?]*[A-Za-z]/g, '')
+}
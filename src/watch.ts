@@ -0,0 +1,145 @@
+import type {TestFile} from './types.ts'
+import {watch, type FSWatcher} from 'fs'
+import {basename, dirname, resolve, sep} from 'path'
+
+/*
+ Directories whose changes never trigger a re-run (build artifacts and tooling)
+ */
+const IGNORED_DIRS = ['.testme', '.git', 'node_modules']
+
+/*
+ FileWatcher - Filesystem change notifications for watch mode
+
+ Watches a directory tree recursively and reports changed paths in batches. Rapid bursts of
+ changes (e.g., an editor saving several files or a build writing outputs) are debounced into
+ a single batch. Changes while paused (a run is in progress) are discarded so that files written
+ by the tests themselves do not trigger another run.
+ */
+export class FileWatcher {
+    private rootDir: string
+    private debounce: number
+    private watcher: FSWatcher | null = null
+    private changes: Set<string> = new Set()
+    private timer: ReturnType<typeof setTimeout> | null = null
+    private waiting: ((changes: string[] | null) => void) | null = null
+    private ready: string[] | null = null
+    private paused: boolean = false
+    private closed: boolean = false
+
+    /*
+     Creates a watcher for a directory tree
+     @param rootDir Directory tree to watch
+     @param debounce Quiet period in milliseconds before a batch of changes is reported
+     */
+    constructor(rootDir: string, debounce: number = 200) {
+        this.rootDir = rootDir
+        this.debounce = debounce
+    }
+
+    /*
+     Starts watching the directory tree
+     @throws Error if recursive watching is not supported on this platform
+     */
+    start(): void {
+        this.watcher = watch(this.rootDir, {recursive: true}, (_event, filename) => {
+            if (filename) {
+                this.record(resolve(this.rootDir, filename.toString()))
+            }
+        })
+        this.watcher.on('error', () => this.close())
+    }
+
+    /*
+     Waits for the next batch of changes
+     @returns Absolute paths of changed files, or null if the watcher was closed
+     */
+    next(): Promise<string[] | null> {
+        this.paused = false
+        if (this.closed) {
+            return Promise.resolve(null)
+        }
+        if (this.ready) {
+            const changes = this.ready
+            this.ready = null
+            return Promise.resolve(changes)
+        }
+        return new Promise((resolve) => {
+            this.waiting = resolve
+        })
+    }
+
+    /*
+     Stops collecting changes until next() is called
+     */
+    pause(): void {
+        this.paused = true
+        this.changes.clear()
+        this.ready = null
+        if (this.timer) {
+            clearTimeout(this.timer)
+            this.timer = null
+        }
+    }
+
+    /*
+     Stops watching and releases any pending next() call
+     */
+    close(): void {
+        if (this.closed) {
+            return
+        }
+        this.closed = true
+        this.pause()
+        this.watcher?.close()
+        this.watcher = null
+        this.waiting?.(null)
+        this.waiting = null
+    }
+
+    private record(path: string): void {
+        if (this.paused || this.closed) {
+            return
+        }
+        if (path.split(sep).some((segment) => IGNORED_DIRS.includes(segment))) {
+            return
+        }
+        this.changes.add(path)
+        if (this.timer) {
+            clearTimeout(this.timer)
+        }
+        this.timer = setTimeout(() => this.flush(), this.debounce)
+    }
+
+    private flush(): void {
+        this.timer = null
+        const changes = [...this.changes]
+        this.changes.clear()
+        if (this.waiting) {
+            this.waiting(changes)
+            this.waiting = null
+        } else {
+            this.ready = [...(this.ready || []), ...changes]
+        }
+    }
+
+    /*
+     Selects the tests affected by a set of changed files
+     A test is affected if a file changed in its own directory, if a file changed in its configuration
+     directory (shared setup scripts and headers), or if a testme.json5 changed at or above its directory.
+     @param tests Candidate tests
+     @param changes Absolute paths of changed files
+     @returns Affected tests
+     */
+    static affectedTests(tests: TestFile[], changes: string[]): TestFile[] {
+        const dirs = new Set(changes.map((path) => dirname(path)))
+        const configDirs = changes
+            .filter((path) => basename(path) === 'testme.json5')
+            .map((path) => dirname(path))
+        return tests.filter(
+            (test) =>
+                dirs.has(test.directory) ||
+                (test.configDir !== undefined && dirs.has(test.configDir)) ||
+                configDirs.some((dir) => test.directory === dir || test.directory.startsWith(dir + sep))
+        )
+    }
+}
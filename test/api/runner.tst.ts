@@ -0,0 +1,80 @@
+/*
+    Library API tests
+    Verifies an embedded run returns per-test results, writes output to the given stream, feeds custom
+    reporters and formats registered by name, and stops gracefully when its abort signal fires
+ */
+
+import {Runner, TestStatus, registerReporter} from '../../src/api.ts'
+import type {Reporter, TestResult} from '../../src/api.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {Writable} from 'stream'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+function collect(): {stream: Writable; text: () => string} {
+    const chunks: string[] = []
+    const stream = new Writable({
+        write(chunk, _encoding, done) {
+            chunks.push(chunk.toString())
+            done()
+        },
+    })
+    return {stream, text: () => chunks.join('')}
+}
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX shell tests - skipping on Windows')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-api-')))
+    const cwd = process.cwd()
+    try {
+        await writeFile(join(rootDir, 'testme.json5'), '{}\n')
+        await writeFile(join(rootDir, 'pass.tst.sh'), 'exit 0\n')
+        await writeFile(join(rootDir, 'fail.tst.sh'), 'exit 1\n')
+
+        const seen: TestResult[] = []
+        const reporter: Reporter = {testEnd: (result) => seen.push(result), runEnd: () => {}}
+        const events: string[] = []
+        registerReporter('events', (path) => ({
+            runStart: (tests) => events.push(`start ${tests.length} ${path}`),
+            testStart: (file) => events.push(`test ${file.name}`),
+            testEnd: (result) => events.push(`end ${result.file.name}`),
+            runEnd: (results) => events.push(`done ${results.length}`),
+        }))
+        const output = collect()
+        const options = {chdir: rootDir, workers: 1, report: ['events:log']}
+        const runner = new Runner({options, output: output.stream})
+        runner.addReporter(reporter)
+        const results = await runner.run()
+        ttrue(results.exitCode === 1 && !results.passed && !results.interrupted, 'Failing run')
+        ttrue(results.tests.length === 2 && results.categories.pass === 1 && results.categories.fail === 1, 'Results')
+        const failed = results.tests.find((result) => result.file.name === 'fail.tst.sh')
+        teq(failed?.status, TestStatus.Failed, 'Per-test outcome')
+        teq(seen.length, 2, 'Custom reporter receives each result')
+        ttrue(events[0] === 'start 2 log' && events[events.length - 1] === 'done 2', 'Registered format by name')
+        ttrue(events.indexOf('test fail.tst.sh') < events.indexOf('end fail.tst.sh'), 'Tests start before they end')
+        ttrue(output.text().includes('TEST SUMMARY'), 'Human output written to the stream')
+        teq(process.cwd(), cwd, 'Working directory restored')
+
+        await writeFile(join(rootDir, 'fail.tst.sh'), 'sleep 60\n')
+        const started = Date.now()
+        const stopped = await new Runner({options: {chdir: rootDir}, output: collect().stream}).run(
+            AbortSignal.timeout(2000)
+        )
+        ttrue(stopped.interrupted && stopped.exitCode === 130, 'Abort stops the run with 128 + SIGINT')
+        ttrue(Date.now() - started < 20000, 'Running test is stopped')
+
+        let error = ''
+        await new Runner({options: {clean: true, list: true}}).run().catch((err) => (error = err.message))
+        ttrue(error !== '', 'Invalid options are rejected')
+    } finally {
+        process.chdir(cwd)
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
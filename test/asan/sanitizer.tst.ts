@@ -0,0 +1,47 @@
+/*
+    AddressSanitizer support unit tests
+    Verifies sanitizer report detection and that a sanitizer abort fails a test distinctly
+ */
+
+import {applySanitizerReport, findSanitizerReport, getSanitizerFlags} from '../../src/utils/sanitizer.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+
+async function test() {
+    const report = [
+        'test output before the abort',
+        '=================================================================',
+        '==4242==ERROR: AddressSanitizer: heap-use-after-free on address 0x602000000010 at pc 0x55d1 bp 0x7ffd',
+        'READ of size 4 at 0x602000000010 thread T0',
+        '    #0 0x55d1 in main leak.tst.c:7',
+        'SUMMARY: AddressSanitizer: heap-use-after-free leak.tst.c:7 in main',
+    ].join('\n')
+
+    const found = findSanitizerReport(report)
+    teq(found?.kind, 'AddressSanitizer: heap-use-after-free', 'Sanitizer error kind parsed')
+    ttrue(found!.report.startsWith('=====') && found!.report.endsWith('in main'), 'Report text extracted')
+    ttrue(!found!.report.includes('before the abort'), 'Test output excluded from report')
+
+    const leak = findSanitizerReport('==77==ERROR: LeakSanitizer: detected memory leaks\n\nDirect leak of 8 byte(s)')
+    teq(leak?.kind, 'LeakSanitizer: detected memory leaks', 'LeakSanitizer report parsed')
+    teq(findSanitizerReport('✗ assertion failed: expected 1 got 2'), null, 'Assertion failure is not a sanitizer abort')
+
+    const file = makeFile('/tmp', 'leak.tst.c')
+    const aborted = applySanitizerReport(makeResult(file, TestStatus.Failed, {exitCode: 1, stderr: report}))
+    ttrue(aborted.status === TestStatus.Failed && aborted.sanitizer !== undefined, 'Sanitizer abort recorded')
+    ttrue(aborted.error!.startsWith('AddressSanitizer: heap-use-after-free\n'), 'Report attached to error')
+
+    const leaked = applySanitizerReport(
+        makeResult(file, TestStatus.Passed, {exitCode: 0, stderr: '==77==ERROR: LeakSanitizer: detected memory leaks'})
+    )
+    teq(leaked.status, TestStatus.Failed, 'Sanitizer report fails a test that exited cleanly')
+
+    const failed = applySanitizerReport(makeResult(file, TestStatus.Failed, {exitCode: 1, stderr: 'assertion failed'}))
+    ttrue(failed.sanitizer === undefined && failed.error === undefined, 'Ordinary failure unchanged')
+
+    ttrue(getSanitizerFlags(false).includes('-fsanitize=address'), 'GCC/Clang flags')
+    ttrue(getSanitizerFlags(true).includes('/fsanitize=address'), 'MSVC flags')
+}
+
+await run(test)
@@ -0,0 +1,54 @@
+/*
+    C build cache unit tests
+    Verifies dependency file parsing and that a cached binary is reused only while its source, compiler command and
+    included headers are unchanged
+ */
+
+import {checkBuild, hashCommand, parseDependencies, saveBuild} from '../../src/utils/build-cache.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const deps = parseDependencies('/t/math: /t/math.tst.c /t/my\\ lib.h \\\n  ../inc/util.h /t/math.tst.c\n')
+    teq(JSON.stringify(deps), '["/t/math.tst.c","/t/my lib.h","../inc/util.h"]', 'Dependency file parsed')
+    teq(parseDependencies('').length, 0, 'Empty dependency file')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-cache-'))
+    try {
+        const source = join(dir, 'math.tst.c')
+        const header = join(dir, 'util.h')
+        const binary = join(dir, 'math')
+        const depPath = join(dir, 'math.d')
+        await writeFile(source, '#include "util.h"\nint main() { return 0; }\n')
+        await writeFile(header, '#define N 1\n')
+        await writeFile(depPath, `${binary}: ${source} util.h\n`)
+        const command = hashCommand('cc', ['-o', binary, source])
+
+        teq(await checkBuild(binary, source, command), 'no cached binary', 'Missing binary rebuilt')
+        await writeFile(binary, 'binary')
+        teq(await checkBuild(binary, source, command), 'no build record', 'Binary without record rebuilt')
+
+        await saveBuild(binary, source, command, depPath, dir)
+        teq(await checkBuild(binary, source, command), null, 'Unchanged build reused')
+        ttrue(hashCommand('cc', ['-a b']) !== hashCommand('cc', ['-a', 'b']), 'Arguments hashed separately')
+        const flags = hashCommand('cc', ['-O2', '-o', binary, source])
+        teq(await checkBuild(binary, source, flags), 'compiler or flags changed', 'Flag change rebuilt')
+
+        await writeFile(header, '#define N 2\n')
+        teq(await checkBuild(binary, source, command), 'util.h changed', 'Header change rebuilt')
+        await saveBuild(binary, source, command, depPath, dir)
+        await writeFile(source, '#include "util.h"\nint main() { return 1; }\n')
+        teq(await checkBuild(binary, source, command), 'source changed', 'Source change rebuilt')
+
+        await saveBuild(binary, source, command, undefined, dir)
+        await rm(header)
+        teq(await checkBuild(binary, source, command), null, 'Headers untracked without a dependency file')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,74 @@
+/*
+    Build phase tests
+    Verifies --build-workers hands tests to the run queue as their builds complete, limits concurrent builds, waits
+    for a single test's build and records a build that throws as failed
+ */
+
+import {TestBuilds} from '../../src/builds.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {BuildResult, TestFile} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+
+function sleep(ms: number): Promise<void> {
+    return new Promise((resolve) => setTimeout(resolve, ms))
+}
+
+async function test() {
+    const [slow, fast, none] = [
+        makeFile('/t', 'slow.tst.c'),
+        makeFile('/t', 'fast.tst.c'),
+        makeFile('/t', 'none.tst.sh'),
+    ]
+    const delays = new Map([
+        [slow, 80],
+        [fast, 10],
+    ])
+    let active = 0
+    let most = 0
+    const build = async (file: TestFile): Promise<BuildResult | null> => {
+        if (!delays.has(file)) {
+            return null
+        }
+        most = Math.max(most, ++active)
+        await sleep(delays.get(file)!)
+        active--
+        return {success: true, duration: delays.get(file)!, output: `Compiled ${file.name}`}
+    }
+
+    let builds = new TestBuilds([slow, fast, none], 2, build)
+    const order: TestFile[] = []
+    let file: TestFile | undefined
+    while ((file = await builds.next())) {
+        order.push(file)
+    }
+    ttrue(order.length === 3 && order[order.length - 1] === slow, 'Tests run in the order builds complete')
+    teq(most, 2, 'Concurrent builds limited to the build workers')
+    ttrue(builds.get(none) === null && builds.get(fast)?.success === true, 'Build results recorded')
+    ttrue(builds.getDuration() >= 70, 'Build phase timed from first start to last end')
+
+    builds = new TestBuilds([slow, fast], 1, build)
+    teq((await builds.wait(fast))?.output, 'Compiled fast.tst.c', 'Waiting starts a queued build at once')
+    teq((await builds.wait(slow))?.success, true, 'Waiting for a build in progress')
+
+    const failing = new TestBuilds([fast], 1, async () => {
+        throw new Error('no compiler')
+    })
+    const failed = await failing.wait(fast)
+    ttrue(failed?.success === false && failed.error!.includes('no compiler'), 'Build that throws recorded as failed')
+
+    builds = new TestBuilds([slow, fast], 1, build)
+    builds.stop()
+    teq(await builds.next(), undefined, 'No tests handed out once stopped')
+
+    teq(CliParser.parse(['--build-workers', '8']).buildWorkers, 8, '--build-workers')
+    let invalid = false
+    try {
+        CliParser.parse(['--build-workers', '0'])
+    } catch {
+        invalid = true
+    }
+    ttrue(invalid, '--build-workers requires a positive number')
+}
+
+await run(test)
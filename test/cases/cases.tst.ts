@@ -0,0 +1,62 @@
+/*
+    Parameterized test unit tests
+    Verifies .cases.json expansion, case environment variables and that each case runs as an independent test
+ */
+
+import {TestCases} from '../../src/cases.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const dir = await mkdtemp(join(tmpdir(), 'testme-cases-'))
+    try {
+        const square = makeFile(dir, 'square.tst.sh')
+        const plain = makeFile(dir, 'plain.tst.sh')
+        const broken = makeFile(dir, 'broken.tst.sh')
+        await writeFile(square.path, '#!/bin/sh\n[ "$((TESTME_CASE_x * TESTME_CASE_x))" = "$TESTME_CASE_expect" ]\n')
+        await writeFile(plain.path, '#!/bin/sh\nexit 0\n')
+        await writeFile(broken.path, '#!/bin/sh\nexit 0\n')
+        await writeFile(
+            TestCases.getPath(square.path),
+            JSON.stringify([{name: 'positive', x: 2, expect: 4}, {name: 'wrong', x: 2, expect: 5}, {x: -3, expect: 9}])
+        )
+        await writeFile(TestCases.getPath(broken.path), '{"name": "not an array"}')
+
+        const tests = await TestCases.expand([square, plain, broken])
+        teq(tests.length, 5, 'Each case becomes a test')
+        teq(tests[0]!.name, 'square.tst.sh[positive]', 'Case named by its name field')
+        teq(tests[2]!.name, 'square.tst.sh[2]', 'Unnamed case named by its index')
+        teq(tests[0]!.testCase?.env.TESTME_CASE_x, '2', 'Case fields exported')
+        teq(tests[0]!.testCase?.env.TESTME_CASE, 'positive', 'Case name exported')
+        ttrue(tests[0]!.artifactDir !== tests[1]!.artifactDir, 'Cases build in separate artifact directories')
+        teq(tests[3], plain, 'Test without cases unchanged')
+        ttrue(!!tests[4]!.casesError?.includes('array'), 'Invalid cases file reported')
+        teq(TestCases.label(tests[1]!, 'test/square.tst.sh'), 'test/square.tst.sh[wrong]', 'Case label')
+
+        if (process.platform === 'win32') {
+            console.log('Shell case execution not supported on Windows - skipping')
+            return
+        }
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: true, workers: 4},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        const runner = new TestRunner()
+        const results = await runner.executeTestsWithConfig(tests, config)
+        const status = (name: string) => results.find((result) => result.file.name === name)?.status
+        teq(status('square.tst.sh[positive]'), TestStatus.Passed, 'Passing case passes')
+        teq(status('square.tst.sh[wrong]'), TestStatus.Failed, 'Failing case fails')
+        teq(status('square.tst.sh[2]'), TestStatus.Passed, 'Other cases still run after a failure')
+        teq(status('broken.tst.sh'), TestStatus.Error, 'Invalid cases file is an error')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,56 @@
+/*
+    Changeset detection unit tests
+    Verifies git changed file listing, the non-repository fallback and depends matching
+ */
+
+import {dependsOnChanges, getChangedFiles} from '../../src/utils/changes.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function git(args: string[], cwd: string): Promise<void> {
+    const proc = Bun.spawn(['git', '-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args], {
+        cwd,
+        stdout: 'ignore',
+        stderr: 'ignore',
+    })
+    await proc.exited
+}
+
+async function test() {
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-changes-')))
+    try {
+        teq(await getChangedFiles('HEAD', rootDir), null, 'Not a git repository returns null')
+
+        await mkdir(join(rootDir, 'unit'), {recursive: true})
+        await writeFile(join(rootDir, 'unit', 'math.tst.sh'), 'exit 0\n')
+        await writeFile(join(rootDir, 'README.md'), 'readme\n')
+        await git(['init', '-q'], rootDir)
+        await git(['add', '-A'], rootDir)
+        await git(['commit', '-q', '-m', 'initial'], rootDir)
+
+        await writeFile(join(rootDir, 'unit', 'math.tst.sh'), 'exit 1\n')
+        const changes = await getChangedFiles('HEAD', join(rootDir, 'unit'))
+        ttrue(changes?.length === 1 && changes[0] === join(rootDir, 'unit', 'math.tst.sh'), 'Changed file listed')
+
+        let error = ''
+        try {
+            await getChangedFiles('no-such-ref', rootDir)
+        } catch (err) {
+            error = (err as Error).message
+        }
+        ttrue(error.includes('no-such-ref'), 'Unknown ref reported')
+
+        const lib = join(rootDir, 'lib', 'api.c')
+        ttrue(dependsOnChanges([lib], ['../lib'], join(rootDir, 'unit')), 'Directory dependency matches')
+        ttrue(dependsOnChanges([lib], ['../lib/api.c'], join(rootDir, 'unit')), 'File dependency matches')
+        ttrue(!dependsOnChanges([lib], ['../library'], join(rootDir, 'unit')), 'Prefix is not a dependency')
+        ttrue(!dependsOnChanges([lib], undefined, rootDir), 'No depends list')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,58 @@
+/*
+    Build directory tests
+    Verifies build.dir places artifact directories in a tree mirroring the sources, that discovery skips it and that
+    --clean removes it but never a build directory holding the sources
+ */
+
+import {TestDiscovery} from '../../src/discovery.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'node:fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const root = await realpath(await mkdtemp(join(tmpdir(), 'testme-build-')))
+    try {
+        await mkdir(join(root, 'net', 'http'), {recursive: true})
+        await mkdir(join(root, 'out', 'net'), {recursive: true})
+        await writeFile(join(root, 'math.tst.sh'), 'exit 0\n')
+        await writeFile(join(root, 'net', 'http', 'get.tst.c'), 'int main() { return 0; }\n')
+        await writeFile(join(root, 'out', 'net', 'copy.tst.sh'), 'exit 0\n')
+
+        const discover = (config: TestConfig) =>
+            TestDiscovery.discoverTests({
+                rootDir: root,
+                patterns: ['**/*.tst.sh', '**/*.tst.c'],
+                excludePatterns: [],
+                ...TestDiscovery.getArtifactOptions(config),
+            })
+        let tests = await discover({configDir: root})
+        const math = () => tests.find((test) => test.name === 'math.tst.sh')!
+        teq(math().artifactDir, join(root, '.testme', 'math.tst'), 'Artifacts beside tests by default')
+
+        const config: TestConfig = {configDir: root, build: {dir: 'out'}}
+        tests = await discover(config)
+        const get = () => tests.find((test) => test.name === 'get.tst.c')!
+        teq(get().artifactDir, join(root, 'out', 'net', 'http', 'get.tst'), 'Artifacts mirror the source tree')
+        teq(math().artifactDir, join(root, 'out', 'math.tst'), 'Root tests at the top of the build directory')
+        teq(tests.length, 2, 'Build directory not searched for tests')
+
+        const elsewhere = join(tmpdir(), 'testme-builds')
+        tests = await discover({configDir: join(root, 'net'), build: {dir: elsewhere}})
+        teq(math().artifactDir, join(root, '.testme', 'math.tst'), 'Tests outside the config directory not moved')
+        teq(get().artifactDir, join(elsewhere, 'http', 'get.tst'), 'Absolute build directory')
+
+        // Dry runs only: TestTmp.clean() would also remove this test's own directory
+        const runner = new TestRunner()
+        ttrue((await runner.cleanArtifacts(root, config, true)).includes(join(root, 'out')), '--clean removes it')
+        const unsafe = await runner.cleanArtifacts(root, {configDir: root, build: {dir: '.'}}, true)
+        ttrue(!unsafe.includes(root), 'Build directory holding the sources never removed')
+    } finally {
+        await rm(root, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,70 @@
+/*
+    Artifact cleaning tests
+    Verifies --clean finds artifact directories under the root, including a configured artifact directory name,
+    leaves sources alone, and that --clean-dry-run removes nothing
+ */
+
+import {ArtifactManager} from '../../src/artifacts.ts'
+import {TestTmp} from '../../src/tmp.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestFile} from '../../src/types.ts'
+import {TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {existsSync} from 'node:fs'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'node:fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const root = await realpath(await mkdtemp(join(tmpdir(), 'testme-clean-')))
+    try {
+        const source = join(root, 'src', 'math.tst.c')
+        await mkdir(join(root, 'src', '.testme', 'math.tst.c'), {recursive: true})
+        await writeFile(source, 'int main() { return 0; }\n')
+        await writeFile(join(root, 'src', '.testme', 'math.tst.c', 'math'), 'binary')
+        await mkdir(join(root, '.testme'))
+        await mkdir(join(root, 'lib', 'out'), {recursive: true})
+        await mkdir(join(root, 'node_modules', 'pkg', '.testme'), {recursive: true})
+
+        const manager = new ArtifactManager()
+        const found = (await manager.cleanAllArtifacts(root, ['.testme', 'out'], true)).sort()
+        const expected = [join(root, '.testme'), join(root, 'lib', 'out'), join(root, 'src', '.testme')]
+        teq(JSON.stringify(found), JSON.stringify(expected), 'Dry run finds artifact directories')
+        ttrue(expected.every((dir) => existsSync(dir)), 'Dry run removes nothing')
+
+        const removed = await manager.cleanAllArtifacts(root)
+        ttrue(removed.length === 2 && !existsSync(join(root, 'src', '.testme')), 'Artifact directories removed')
+        ttrue(existsSync(source) && existsSync(join(root, 'lib', 'out')), 'Sources and other directories kept')
+        ttrue(existsSync(join(root, 'node_modules', 'pkg', '.testme')), 'Skipped directories not searched')
+
+        // Artifacts of a configured directory name land in that directory
+        const file = {name: 'math.tst.c', directory: join(root, 'lib'), artifactDir: join(root, 'lib', 'out', 'math')}
+        await manager.createArtifactDir(file as TestFile)
+        await manager.cleanArtifactDir(file as TestFile)
+        ttrue(!existsSync(join(root, 'lib', 'out')), 'Empty configured artifact directory removed')
+
+        // Kept temporary directories are found, those of running tests are not
+        const tmpFile = {name: 'clean.tst.ts', path: join(root, 'clean.tst.ts'), type: TestType.Shell} as TestFile
+        const [running, kept] = await Promise.all([TestTmp.create(tmpFile), TestTmp.create(tmpFile)])
+        await TestTmp.release(kept!, true)
+        const leftover = await TestTmp.clean(true)
+        ttrue(leftover.includes(kept!) && !leftover.includes(running!), 'Leftover temporary directories found')
+        ttrue(existsSync(kept!), 'Temporary directories kept by dry run')
+        await TestTmp.release(running!, false)
+        await rm(kept!, {recursive: true, force: true})
+
+        teq(CliParser.parse(['--clean-dry-run']).cleanDryRun, true, '--clean-dry-run')
+        let conflict = false
+        try {
+            CliParser.validateOptions(CliParser.parse(['--clean-dry-run', '--list']))
+        } catch {
+            conflict = true
+        }
+        ttrue(conflict, '--clean-dry-run conflicts with --list')
+    } finally {
+        await rm(root, {recursive: true, force: true})
+    }
+}
+
+await run(test)
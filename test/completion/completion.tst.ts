@@ -0,0 +1,70 @@
+/*
+    --completion unit tests
+    Verifies options are read from the help text and each shell script completes flags, report formats and tests
+ */
+
+import {Completion} from '../../src/completion.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {spawn, which} from 'bun'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const options = Completion.getOptions()
+    const option = (long: string) => options.find((option) => option.long === long)
+    ttrue(option('--config')?.short === '-c' && option('--config')?.value === 'FILE', 'Short flag and value parsed')
+    teq(option('--accept')?.value, undefined, 'Switch has no value')
+    ttrue(!!option('--coverage-threshold')?.description, 'Wrapped description read from the next line')
+    ttrue(!!option('--env')?.repeatable && !option('--filter')?.repeatable, 'Repeatable options marked')
+    teq(option('--completion')?.value, 'SHELL', 'Completion option listed')
+
+    const bash = Completion.render('bash')
+    const formats = 'COMPREPLY=($(compgen -W "junit tap json"'
+    ttrue(bash.includes(`--report)\n            ${formats}`), 'Bash completes report formats')
+    ttrue(/--exclude\|--filter\)\n\s+COMPREPLY=\(\$\(compgen -W "\$\(_tm_tests\)"/.test(bash), 'Bash completes tests')
+    ttrue(bash.includes('complete -F _tm tm'), 'Bash registers the completion')
+
+    const zsh = Completion.render('zsh')
+    ttrue(zsh.startsWith('#compdef tm') && zsh.includes("'(-c --config)'{-c,--config}'["), 'Zsh short and long forms')
+    ttrue(zsh.includes(':spec:(junit tap json)') && zsh.includes("'*--env["), 'Zsh values and repeatable options')
+
+    const fish = Completion.render('fish')
+    ttrue(fish.includes("complete -c tm -l report -x -a 'junit tap json'"), 'Fish completes report formats')
+    ttrue(fish.includes('complete -c tm -s c -l config -r -F'), 'Fish completes files')
+
+    let failed = false
+    try {
+        Completion.render('csh')
+    } catch {
+        failed = true
+    }
+    ttrue(failed, 'Unsupported shell rejected')
+
+    // Syntax check the scripts with any shells installed
+    if (process.platform === 'win32') {
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-completion-'))
+    try {
+        for (const [shell, script] of [
+            ['bash', bash],
+            ['zsh', zsh],
+            ['fish', fish],
+        ]) {
+            if (!which(shell!)) {
+                console.log(`${shell} not installed - skipping syntax check`)
+                continue
+            }
+            const path = join(dir, `tm.${shell}`)
+            await writeFile(path, script!)
+            const proc = spawn([shell!, '-n', path], {stdout: 'pipe', stderr: 'pipe'})
+            teq(await proc.exited, 0, `${shell} script is valid`)
+        }
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
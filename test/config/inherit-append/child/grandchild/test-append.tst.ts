@@ -0,0 +1,31 @@
+/*
+    Test list append and replace markers across a chain of inherited configs, and --show-config
+*/
+
+import {ConfigManager} from '../../../../../src/config.ts'
+import {teq} from 'testme'
+import {spawn} from 'bun'
+import {join} from 'path'
+
+const config = await ConfigManager.findConfig(import.meta.dir)
+
+// Flags are appended through the chain (plain list and append: true marker)
+teq(config.compiler?.c?.gcc?.flags?.join(' '), '-DROOT_FLAG -DCHILD_FLAG -DGRANDCHILD_FLAG', 'Flags appended in order')
+
+// Libraries marked append: false replace the inherited list
+teq(config.compiler?.c?.gcc?.libraries?.join(' '), 'z', 'Libraries replaced')
+
+// The chain lists the nearest config first
+const chain = await ConfigManager.getConfigChain(import.meta.dir)
+teq(chain.length, 3, 'Three config files in the chain')
+teq(chain[0]!.path, join(import.meta.dir, 'testme.json5'), 'Nearest config first')
+teq(chain[2]!.inherit, undefined, 'Chain ends at a config without inherit')
+
+// --show-config prints the chain and the merged configuration
+const tmPath = join(import.meta.dir, '..', '..', '..', '..', '..', 'dist', 'tm')
+const proc = spawn([tmPath, '--show-config', import.meta.dir], {stdout: 'pipe', stderr: 'pipe'})
+const output = await new Response(proc.stdout).text()
+teq(await proc.exited, 0, '--show-config exits successfully')
+teq(output.includes('(inherits all)'), true, '--show-config shows inherit settings')
+teq(output.includes('-DGRANDCHILD_FLAG'), true, '--show-config shows the merged configuration')
+teq(output.includes('_envConfigDir'), false, '--show-config omits internal keys')
@@ -17,9 +17,9 @@ import {resolve, sep} from 'path'
 
 // Use platform-specific path separator for splitting
 const pathSeparator = `${sep}test${sep}config${sep}inherit-paths`
-const parts = process.cwd().split(pathSeparator)
+const parts = import.meta.dir.split(pathSeparator)
 if (parts.length === 0 || !parts[0]) {
-    console.error('Failed to determine test root directory from:', process.cwd())
+    console.error('Failed to determine test root directory from:', import.meta.dir)
     process.exit(1)
 }
 const testRootDir = parts[0]
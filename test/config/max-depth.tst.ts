@@ -0,0 +1,23 @@
+/*
+    Test depth unit tests
+    Verifies TESTME_DEPTH defaults to 0, follows --depth and is capped by a directory's maxDepth
+ */
+
+import {ConfigManager} from '../../src/config.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const execution = (depth?: number) => ({timeout: 30, parallel: true, depth})
+    teq(ConfigManager.getDepth({}), 0, 'Depth defaults to 0')
+    teq(ConfigManager.getDepth({execution: execution(3)}), 3, 'Depth from --depth')
+    const capped: TestConfig = {maxDepth: 2, execution: execution(5)}
+    teq(ConfigManager.getDepth(capped), 2, 'maxDepth caps a higher depth')
+    teq(ConfigManager.getDepth({maxDepth: 2, execution: execution(1)}), 1, 'Lower depth is unchanged')
+    teq(ConfigManager.getDepth({maxDepth: 0, execution: execution(4)}), 0, 'maxDepth 0 keeps tests quick')
+    teq(ConfigManager.validateConfig({maxDepth: 2}).length, 0, 'maxDepth is a valid key')
+    teq(ConfigManager.validateConfig({maxDepth: -1}).length, 1, 'maxDepth must be a count')
+}
+
+await run(test)
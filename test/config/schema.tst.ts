@@ -0,0 +1,64 @@
+/*
+    Configuration schema unit tests
+    Verifies type, value and unknown key checks with JSON paths, and the --check-config command
+ */
+
+import {ConfigSchema} from '../../src/schema.ts'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const valid = {
+        enable: 'manual',
+        compiler: {c: {compiler: {linux: 'gcc'}, gcc: {flags: {values: ['-O0'], append: false}}}},
+        execution: {timeout: 30, parallel: true, exitCode: 'nonzero'},
+        environment: {BIN: '${../bin}', LEVEL: 2, LIB: {default: 'a', linux: 'b'}, linux: {X: '1'}, file: '.env'},
+        services: {healthCheck: {type: 'tcp', host: 'localhost', port: 8080}},
+    }
+    teq(ConfigSchema.validate(valid).length, 0, 'Valid configuration accepted')
+
+    const issues = ConfigSchema.validate({
+        execution: {timeout: '10s', timout: 5, workers: 0},
+        output: {format: 'fancy'},
+        compiler: {c: {flags: ['-g', 3]}},
+        services: {healthCheck: {port: 'http'}},
+    })
+    const find = (path: string) => issues.find((issue) => issue.path === path)
+    teq(find('execution.timeout')?.message, 'expected a number, got string "10s"', 'Wrong type reported')
+    teq(find('execution.timout')?.severity, 'warning', 'Unknown key is a warning')
+    ttrue(!!find('execution.timout')?.message.includes('did you mean "timeout"'), 'Unknown key suggests known key')
+    teq(find('execution.workers')?.severity, 'error', 'Out of range value reported')
+    ttrue(!!find('output.format')?.message.includes('"simple"'), 'Invalid enumerated value lists allowed values')
+    teq(find('compiler.c.flags[1]')?.severity, 'error', 'Array item path reported')
+    ttrue(!!find('services.healthCheck.port'), 'Nested path reported')
+    const line = ConfigSchema.format('t.json5', [find('output.format')!])
+    ttrue(line.startsWith('t.json5: error: output.format:'), 'Issue formatted with file, severity and path')
+
+    if (process.platform === 'win32') {
+        console.log('--check-config run test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-schema-')))
+    try {
+        await mkdir(join(rootDir, 'sub'))
+        await writeFile(join(rootDir, 'testme.json5'), '{execution: {timeout: 10}}\n')
+        await writeFile(join(rootDir, 'sub', 'testme.json5'), '{futureKey: true}\n')
+
+        const warned = await runTm(['--check-config'], rootDir)
+        const warning = 'sub/testme.json5: warning: futureKey: unknown key'
+        ttrue(warned.exitCode === 1 && warned.stdout.includes(warning), 'Unknown key fails the check')
+        const ignored = await runTm(['--check-config', '--ignore-unknown-keys'], rootDir)
+        ttrue(ignored.exitCode === 0 && ignored.stdout.includes('0 error(s), 0 warning(s)'), 'Unknown keys ignored')
+
+        await writeFile(join(rootDir, 'sub', 'testme.json5'), "{output: {colors: 'yes'}}\n")
+        const invalid = await runTm(['--check-config', '--ignore-unknown-keys'], rootDir)
+        ttrue(invalid.exitCode === 1 && invalid.stdout.includes('output.colors'), 'Invalid value fails')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,88 @@
+/*
+    Config override unit tests
+    Verifies --set values are parsed as JSON or text, validated, applied over the merged configuration, shown by
+    --show-config and applied over the configuration of each test's directory when tests run
+ */
+
+import {CliParser} from '../../src/cli.ts'
+import {ConfigManager} from '../../src/config.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run, runTm, throws, tmPath} from '../helpers.ts'
+import {spawn} from 'bun'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const options = CliParser.parse([
+        '--set',
+        'execution.timeout=60',
+        '--set',
+        'compiler.c.flags=["-O2"]',
+        '--set',
+        'compiler.c.compiler=gcc-13',
+        '--set',
+        'output.verbose=true',
+    ])
+    teq(options.set!['execution.timeout'], 60, 'Numbers parsed as JSON')
+    teq(JSON.stringify(options.set!['compiler.c.flags']), '["-O2"]', 'Arrays parsed as JSON')
+    teq(options.set!['compiler.c.compiler'], 'gcc-13', 'Other values kept as text')
+    teq(options.set!['output.verbose'], true, 'Booleans parsed as JSON')
+    ttrue(throws(() => CliParser.parse(['--set', 'timeout'])), 'Missing value rejected')
+    ttrue(throws(() => CliParser.parse(['--set', 'a..b=1'])), 'Empty key rejected')
+    ttrue(throws(() => CliParser.parse(['--set', '__proto__.x=1'])), 'Reserved key rejected')
+
+    const config: TestConfig = {
+        execution: {timeout: 30, parallel: true, workers: 4},
+        compiler: {c: {flags: ['-g']}},
+    }
+    const updated = ConfigManager.applySettings(config, options.set)
+    ttrue(updated.execution!.timeout === 60 && updated.execution!.workers === 4, 'Sibling keys kept')
+    teq(JSON.stringify(updated.compiler!.c!.flags), '["-O2"]', 'Lists replaced, not appended')
+    teq(updated.output!.verbose, true, 'Missing sections created')
+    ttrue(config.execution!.timeout === 30 && config.output === undefined, 'Original configuration unchanged')
+
+    ConfigManager.checkSettings({'execution.timeout': 60})
+    ttrue(throws(() => ConfigManager.checkSettings({'execution.timeout': 'soon'})), 'Wrong type rejected')
+
+    if (process.platform === 'win32') {
+        console.log('--show-config run test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-set-')))
+    try {
+        await writeFile(join(rootDir, 'testme.json5'), '{execution: {timeout: 10}}\n')
+        const proc = spawn([tmPath, '--show-config', '.', '--set', 'execution.timeout=99'], {
+            cwd: rootDir,
+            stdout: 'pipe',
+            stderr: 'pipe',
+        })
+        const stdout = await new Response(proc.stdout).text()
+        teq(await proc.exited, 0, '--show-config with --set succeeds')
+        ttrue(stdout.includes('// Overridden by --set execution.timeout'), 'Overrides listed')
+        const json = JSON.parse(stdout.replace(/^\/\/.*$/gm, ''))
+        teq(json.execution.timeout, 99, 'Override visible in the effective configuration')
+
+        if (!(await PlatformDetector.findInPath('cc'))) {
+            console.log('--set run test needs a C compiler - skipping')
+            return
+        }
+        // The test's own directory config sets VALUE=1 and the test passes only if --set replaces it
+        const unit = join(rootDir, 'unit')
+        await mkdir(unit)
+        await writeFile(join(unit, 'testme.json5'), "{compiler: {c: {flags: ['-DVALUE=1']}}}\n")
+        await writeFile(
+            join(unit, 'value.tst.c'),
+            '#include <stdio.h>\nint main() { printf("value=%d\\n", VALUE); return VALUE == 2 ? 0 : 1; }\n'
+        )
+        teq((await runTm(['unit/value.tst.c'], rootDir)).exitCode, 1, 'Directory config used without --set')
+        const result = await runTm(['--set', 'compiler.c.flags=["-DVALUE=2"]', 'unit/value.tst.c'], rootDir)
+        teq(result.exitCode, 0, '--set compiler.c.flags overrides the directory config when the test runs')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
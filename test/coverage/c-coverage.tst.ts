@@ -0,0 +1,43 @@
+/*
+    C coverage unit tests
+    Verifies lcov tracefile exclusion and total line coverage
+ */
+
+import {CTestHandler} from '../../src/handlers/c.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const info = [
+        'TN:',
+        'SF:/work/project/src/math.c',
+        'DA:1,1',
+        'DA:2,0',
+        'LF:2',
+        'LH:1',
+        'end_of_record',
+        'TN:',
+        'SF:/work/project/third_party/zlib/inflate.c',
+        'DA:10,0',
+        'DA:11,0',
+        'end_of_record',
+        'TN:',
+        'SF:/work/project/src/math.c',
+        'DA:2,3',
+        'end_of_record',
+    ].join('\n')
+
+    teq(CTestHandler.getCoveragePercent(info), 50, 'Lines covered by any record count once')
+
+    const kept = CTestHandler.excludeCoverage(info, '/work/project', ['third_party/**'])
+    ttrue(!kept.includes('inflate.c') && kept.includes('math.c'), 'Relative exclude pattern drops third-party file')
+    teq(CTestHandler.getCoveragePercent(kept), 100, 'Coverage computed without excluded files')
+
+    const absolute = CTestHandler.excludeCoverage(info, '/work/project', ['/work/project/src/**'])
+    ttrue(!absolute.includes('math.c') && absolute.includes('inflate.c'), 'Absolute exclude pattern')
+
+    teq(CTestHandler.excludeCoverage(info, '/work/project', []).split('end_of_record').length, 4, 'No patterns')
+    teq(CTestHandler.getCoveragePercent(''), null, 'Empty tracefile has no coverage')
+}
+
+await run(test)
@@ -0,0 +1,58 @@
+/*
+    Go coverage unit tests
+    Verifies total statement coverage computed from merged Go coverage profiles and that the Go handler merges the
+    coverage of the tests it ran into coverage.out through its collectCoverage() hook
+ */
+
+import {GoTestHandler, createHandlers} from '../../src/handlers/index.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import {TestStatus} from '../../src/types.ts'
+import {existsSync} from 'fs'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+
+async function test() {
+    const profile = [
+        'mode: set',
+        'command-line-arguments/math.tst.go:8.13,10.2 2 1',
+        'command-line-arguments/math.tst.go:12.16,14.3 1 0',
+        'command-line-arguments/math.tst.go:15.2,15.10 1 0',
+    ].join('\n')
+    teq(GoTestHandler.getCoveragePercent(profile), 50, 'Covered statements over total statements')
+
+    // The same block from two tests counts once and is covered if either test ran it
+    const merged = profile + '\ncommand-line-arguments/math.tst.go:12.16,14.3 1 1\n'
+    teq(GoTestHandler.getCoveragePercent(merged), 75, 'Duplicate blocks merged')
+
+    teq(GoTestHandler.getCoveragePercent('mode: set\n'), null, 'Empty profile has no coverage')
+    teq(await GoTestHandler.mergeCoverage('/tmp/unused-coverage.out'), null, 'No Go tests ran')
+    const hooks = createHandlers().filter((handler) => handler.collectCoverage)
+    teq(hooks.map((handler) => handler.constructor.name).join(','), 'CTestHandler,GoTestHandler', 'Coverage hooks')
+
+    if (!(await PlatformDetector.findInPath('go'))) {
+        console.log('Go not installed - skipping Go coverage run')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-gocover-test-'))
+    try {
+        const file = makeFile(dir, 'math.tst.go')
+        const source = 'package main\n\nfunc half(n int) int {\n\tif n < 0 {\n\t\treturn 0\n\t}\n\treturn n / 2\n}\n\n'
+        await writeFile(file.path, source + 'func main() {\n\thalf(4)\n}\n')
+        const handler = new GoTestHandler()
+        const result = await handler.execute(file, {coverage: {enable: true}, execution: {timeout: 120}})
+        teq(result.status, TestStatus.Passed, 'Test built with -cover passes')
+
+        const report = await handler.collectCoverage(dir)
+        teq(report?.language, 'Go', 'Report names the language')
+        teq(report?.path, join(dir, 'coverage.out'), 'Merged into coverage.out in the root directory')
+        ttrue(existsSync(join(dir, 'coverage.out')) && report!.percent > 0 && report!.percent < 100, 'Partial')
+        teq(await handler.collectCoverage(dir), null, 'Coverage data is merged once')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,53 @@
+/*
+    Crash reporting unit tests
+    Verifies crash signals are told from other signals, a test killed by a signal reports it and core files are found
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {describeSignal, findCoreFile, isCrash, isCrashSignal} from '../../src/utils/crash.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    ttrue(isCrashSignal('SIGSEGV') && isCrashSignal('SIGABRT'), 'Faults and aborts are crashes')
+    ttrue(!isCrashSignal('SIGINT') && !isCrashSignal('SIGKILL'), 'Signals sent to a test are not crashes')
+    teq(describeSignal('SIGSEGV'), 'Crashed with SIGSEGV (segmentation fault)', 'Crash description')
+    teq(describeSignal('SIGTERM'), 'Killed by SIGTERM', 'Other signal description')
+
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX signals - skipping the rest on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-crash-'))
+    try {
+        const started = Date.now() - 1000
+        teq(findCoreFile(dir, started), undefined, 'No core file')
+        await writeFile(join(dir, 'core.1234'), '')
+        await writeFile(join(dir, 'core.txt'), '')
+        teq(findCoreFile(dir, started), join(dir, 'core.1234'), 'Core file found')
+        teq(findCoreFile(dir, Date.now() + 60000), undefined, 'Older core files are ignored')
+
+        const file = makeFile(dir, 'abort.tst.sh')
+        // The shell replaces itself with a process that kills itself, as a crashing program would
+        await writeFile(file.path, '#!/bin/sh\necho before\nexec sh -c \'kill -SEGV $$\'\n')
+        await chmod(file.path, 0o755)
+        const config: TestConfig = {configDir: dir, execution: {timeout: 30, parallel: false}}
+        const result = await new ShellTestHandler().execute(file, config)
+        ttrue(result.status === TestStatus.Failed && result.signal === 'SIGSEGV', 'Signal recorded')
+        ttrue(isCrash(result), 'Result is a crash')
+        ttrue((result.error || '').startsWith('Crashed with SIGSEGV'), 'Error names the signal')
+
+        await writeFile(file.path, '#!/bin/sh\nexit 3\n')
+        const failed = await new ShellTestHandler().execute(file, config)
+        ttrue(failed.signal === undefined && !isCrash(failed), 'Ordinary failures are not crashes')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,69 @@
+/*
+    Custom handler tests
+    Verifies handlers templates are validated when configuration loads, that their suffixes are discovered, that
+    placeholders are replaced and that tests are built once and run with the test's arguments and timeout
+ */
+
+import {ConfigManager} from '../../src/config.ts'
+import {ConfigSchema} from '../../src/schema.ts'
+import {TestDiscovery} from '../../src/discovery.ts'
+import {CustomTestHandler} from '../../src/handlers/custom.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus, TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdtemp, readFile, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const issues = ConfigSchema.validate({handlers: {'.tst.xyz': {build: 'xyzc -o {bin} {src}'}, '.tst.q': {run: 'q'}}})
+    const find = (path: string) => issues.find((issue) => issue.path === path)
+    ttrue(!!find('handlers..tst.xyz.build')?.message.includes('unknown placeholder {bin}'), 'Unknown placeholder')
+    teq(find('handlers..tst.xyz.run')?.message, 'missing', 'Missing run command')
+    teq(issues.length, 2, 'Valid handler accepted')
+
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-custom-')))
+    try {
+        const handlers = {'.tst.xyz': {build: "cp {src} '{out}'", run: 'sh {out}'}}
+        await writeFile(join(rootDir, 'testme.json5'), JSON.stringify({handlers}))
+        await writeFile(join(rootDir, 'a b.tst.xyz'), 'echo "args: $*"\nexit 0\n')
+        await writeFile(join(rootDir, 'hang.tst.xyz'), 'sleep 30\n')
+        await writeFile(join(rootDir, 'other.xyz'), 'exit 1\n')
+
+        const config = await ConfigManager.findConfig(rootDir)
+        ttrue(!!config.patterns?.include.includes('**/*.tst.xyz'), 'Handler suffix added to include patterns')
+        const tests = await TestDiscovery.discoverTests({
+            rootDir,
+            patterns: config.patterns!.include,
+            excludePatterns: [],
+            ...TestDiscovery.getWalkOptions(config),
+        })
+        const [spaced, hang] = tests.sort((a, b) => a.name.localeCompare(b.name))
+        ttrue(tests.length === 2 && spaced!.type === TestType.Custom, 'Handler suffix discovered as a custom test')
+        teq(spaced!.extension, '.tst.xyz', 'Test extension is the handler suffix')
+
+        const handler = new CustomTestHandler()
+        const out = handler.getOutputPath(spaced!)
+        teq(out, join(spaced!.artifactDir, 'a b'), 'Output path in the artifact directory')
+        const execution = {timeout: 0, tmpDir: '/t', parallel: false}
+        const args = handler.expand("cc -o {out} '{tmp}/x' {dir}", spaced!, {execution})
+        teq(args.join('|'), `cc|-o|${out}|/t/x|${rootDir}`, 'Placeholders replaced after splitting')
+
+        if (process.platform === 'win32') {
+            console.log('Templates use sh - skipping the runs on Windows')
+            return
+        }
+        const options = {...config, execution: {...config.execution!, args: ['one', 'two'], timeout: 1}} as TestConfig
+        const passed = await handler.execute(spaced!, options)
+        ttrue(passed.status === TestStatus.Passed && passed.output.includes('args: one two'), 'Built and run')
+        ttrue((await readFile(join(spaced!.artifactDir, 'build.log'), 'utf-8')).includes('Exit Code: 0'), 'Build log')
+        teq((await handler.build(spaced!, options)).duration, 0, 'Unchanged test not rebuilt')
+        const timedOut = await handler.execute(hang!, options)
+        teq(timedOut.status, TestStatus.Timeout, 'Test timeout honored')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
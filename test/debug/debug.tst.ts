@@ -0,0 +1,57 @@
+/*
+    Debug mode unit tests
+    Verifies a configured debugger is given the test and its arguments, and runs in the test's working directory
+    with its environment
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdir, mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    teq(CliParser.parse(['--debug', 'math']).debug, true, '--debug')
+
+    if (process.platform === 'win32') {
+        console.log('Uses a shell script debugger - skipping the rest on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-debug-'))
+    try {
+        const file = makeFile(dir, 'setup.tst.sh')
+        await writeFile(file.path, '#!/bin/sh\nexit 0\n')
+        const work = join(dir, 'work')
+        await mkdir(work)
+
+        // The debugger records what it was given instead of debugging
+        const log = join(dir, 'debugger.log')
+        const debuggerPath = join(dir, 'debugger.sh')
+        await writeFile(debuggerPath, `#!/bin/sh\necho "$@" > ${log}\npwd >> ${log}\necho "$MARK" >> ${log}\nexit 2\n`)
+        await chmod(debuggerPath, 0o755)
+
+        const config: TestConfig = {
+            configDir: dir,
+            debug: {sh: debuggerPath},
+            environment: {MARK: 'marked'},
+            execution: {timeout: 30, parallel: false, debugMode: true, chdir: work, args: ['--fast']},
+        }
+        const result = await new ShellTestHandler().execute(file, config)
+        const [args, cwd, mark] = (await readFile(log, 'utf-8')).trim().split('\n')
+        teq(args, `${file.path} --fast`, 'Debugger given the test and its arguments')
+        teq(cwd, work, 'Debugger runs in the working directory')
+        teq(mark, 'marked', 'Debugger has the test environment')
+        ttrue(result.status === TestStatus.Failed && result.exitCode === 2, 'Result follows the debugger exit code')
+
+        const traced = await new ShellTestHandler().execute(file, {...config, debug: {}})
+        teq(traced.status, TestStatus.Passed, 'Shell tests traced with sh -x by default')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
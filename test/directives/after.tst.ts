@@ -0,0 +1,87 @@
+/*
+    Test ordering directive tests
+    Verifies the after directive, that prerequisites run first while independent tests keep running in parallel, that
+    a test is skipped when its prerequisite fails and that missing prerequisites and cycles are configuration errors
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestOrder} from '../../src/order.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function expectError(tests: TestFile[], text: string): Promise<boolean> {
+    try {
+        await TestOrder.resolve(tests)
+        return false
+    } catch (error) {
+        return (error as Error).message.includes(text)
+    }
+}
+
+async function test() {
+    const parsed = Directives.parse('# testme: after setup.tst.sh, db/seed.tst.sh\n# testme: after a.tst.sh\n')
+    teq(parsed.after?.join(), 'setup.tst.sh,db/seed.tst.sh,a.tst.sh', 'Prerequisites from all after directives')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-after-'))
+    try {
+        const a = makeFile(dir, 'a.tst.sh')
+        const b = makeFile(dir, 'b.tst.sh')
+        const lone = makeFile(dir, 'lone.tst.sh')
+        await writeFile(a.path, '# testme: after b.tst.sh\n')
+        await writeFile(b.path, '# testme: after a.tst.sh\n')
+        await writeFile(lone.path, '# testme: after missing.tst.sh\n')
+        ttrue(await expectError([a, b], 'Dependency cycle'), 'Cycle reported as a configuration error')
+        ttrue(await expectError([lone], 'missing.tst.sh'), 'Missing prerequisite reported')
+
+        const sorted = TestOrder.sortTests([{...a, after: [b.path]}, b, lone])
+        teq(sorted.map((file) => file.name).join(), 'b.tst.sh,a.tst.sh,lone.tst.sh', 'Prerequisites sorted first')
+        const groups = new Map([
+            ['x', [{...a, after: [b.path]}]],
+            ['y', [b]],
+        ])
+        teq([...TestOrder.sortGroups(groups).keys()].join(), 'y,x', 'Prerequisite groups run first')
+
+        if (process.platform === 'win32') {
+            console.log('Shell test ordering not supported on Windows - skipping')
+            return
+        }
+        const ready = join(dir, 'ready')
+        const setup = makeFile(dir, 'setup.tst.sh')
+        const use = makeFile(dir, 'use.tst.sh')
+        const fail = makeFile(dir, 'fail.tst.sh')
+        const dependent = makeFile(dir, 'dependent.tst.sh')
+        const free = makeFile(dir, 'free.tst.sh')
+        await writeFile(setup.path, `sleep 1\ntouch ${ready}\n`)
+        await writeFile(use.path, `# testme: after setup.tst.sh\ntest -f ${ready}\n`)
+        await writeFile(fail.path, 'exit 1\n')
+        await writeFile(dependent.path, '# testme: after ./fail.tst.sh\nexit 0\n')
+        await writeFile(free.path, 'exit 0\n')
+
+        const tests = [use, dependent, setup, fail, free]
+        await TestOrder.resolve(tests)
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: true, workers: 4},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        const runner = new TestRunner()
+        runner.setOrder(new TestOrder(tests))
+        const results = await runner.executeTestsWithConfig(tests, config)
+        const order = results.map((result) => result.file.name)
+        const status = (name: string) => results.find((result) => result.file.name === name)?.status
+        teq(status('use.tst.sh'), TestStatus.Passed, 'Test ran after its prerequisite')
+        ttrue(order.indexOf('free.tst.sh') < order.indexOf('setup.tst.sh'), 'Independent tests run in parallel')
+        const skipped = results.find((result) => result.file.name === 'dependent.tst.sh')!
+        teq(skipped.status, TestStatus.Skipped, 'Test skipped when its prerequisite failed')
+        ttrue(skipped.output.includes('fail.tst.sh'), 'Skip reason names the prerequisite')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,41 @@
+/*
+    Expected exit code unit tests
+    Verifies the exit directive, the execution.exitCode default and the mismatch message
+ */
+
+import {Directives} from '../../src/directives.ts'
+import type {TestConfig, TestDirectives} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+
+async function test() {
+    teq(Directives.parse('# testme: exit 2\n').exitCode, 2, 'Numeric exit directive')
+    teq(Directives.parse('// testme: exit nonzero\n').exitCode, 'nonzero', 'Nonzero exit directive')
+    teq(Directives.parse('// testme: exit often\n').exitCode, undefined, 'Invalid exit code ignored')
+
+    const config: TestConfig = {}
+    const file = makeFile('/tmp', 'usage.tst.sh')
+    const apply = (exitCode: number, directives: TestDirectives, testConfig = config) => {
+        const status = exitCode === 0 ? TestStatus.Passed : TestStatus.Failed
+        const error = exitCode === 0 ? undefined : 'usage: tool [options]'
+        return Directives.applyExpectedExit(makeResult(file, status, {error, exitCode}), directives, testConfig)
+    }
+
+    const matched = apply(2, {exitCode: 2})
+    ttrue(matched.status === TestStatus.Passed && !matched.error, 'Expected exit code passes')
+    const wrong = apply(1, {exitCode: 2})
+    ttrue(wrong.status === TestStatus.Failed && !!wrong.error?.startsWith('Exit code 1, expected 2'), 'Mismatch')
+    teq(apply(0, {exitCode: 2}).status, TestStatus.Failed, 'Exit 0 fails when another code is expected')
+
+    teq(apply(3, {exitCode: 'nonzero'}).status, TestStatus.Passed, 'Any non-zero code passes')
+    const zero = apply(0, {exitCode: 'nonzero'})
+    ttrue(zero.status === TestStatus.Failed && zero.error === 'Exit code 0, expected non-zero', 'Exit 0 fails')
+
+    const configured: TestConfig = {execution: {timeout: 30, parallel: true, exitCode: 'nonzero'}}
+    teq(apply(1, {}, configured).status, TestStatus.Passed, 'Configured default applies')
+    teq(apply(1, {exitCode: 0}, configured).status, TestStatus.Failed, 'Directive overrides configuration')
+    teq(apply(1, {}).status, TestStatus.Failed, 'Default expects exit 0')
+}
+
+await run(test)
@@ -0,0 +1,68 @@
+/*
+    Test input unit tests
+    Verifies the args and stdin directives, their configuration defaults and how they reach the test program
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestRunner} from '../../src/runner.ts'
+import {DryRun} from '../../src/utils/dry-run.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const parsed = Directives.parse(`// testme: args --name "two words" 'x y' -v\n// testme: stdin data/input.txt\n`)
+    teq(parsed.args?.join('|'), '--name|two words|x y|-v', 'Quoted arguments kept together')
+    teq(parsed.stdin, 'data/input.txt', 'Stdin directive parsed')
+
+    const file = makeFile('/work/cli', 'echo.tst.sh')
+    const config: TestConfig = {
+        configDir: '/work',
+        execution: {timeout: 30, parallel: true, args: ['-q'], stdin: 'in.txt'},
+    }
+    const defaults = Directives.applyTestInput(config, {}, file)
+    teq(defaults.execution?.stdin, '/work/in.txt', 'Configured stdin is relative to the config file')
+    teq(defaults.execution?.args?.join(), '-q', 'Configured arguments used')
+    const overridden = Directives.applyTestInput(config, parsed, file)
+    teq(overridden.execution?.stdin, '/work/cli/data/input.txt', 'Directive stdin is relative to the test')
+    teq(overridden.execution?.args?.length, 4, 'Directive arguments override configuration')
+    teq(Directives.applyTestInput({}, {}, file).execution, undefined, 'No input leaves configuration unchanged')
+
+    const line = DryRun.formatCommand('sh', ['echo.tst.sh', '--name', 'two words'], {stdin: '/work/in.txt'})
+    teq(line, "sh echo.tst.sh --name 'two words' < /work/in.txt", 'Dry run shows arguments and stdin')
+
+    if (process.platform === 'win32') {
+        console.log('Shell input test not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-input-'))
+    try {
+        const path = join(dir, 'input.tst.sh')
+        await writeFile(join(dir, 'input.txt'), 'hello\n')
+        await writeFile(
+            path,
+            '#!/bin/sh\n# testme: args "two words" second\n# testme: stdin input.txt\n' +
+                'read line\n[ "$line" = hello ] && [ "$1" = "two words" ] && [ "$2" = second ]\n'
+        )
+        const test = makeFile(dir, 'input.tst.sh')
+        const runConfig: TestConfig = {
+            execution: {timeout: 10, parallel: false},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        const runner = new TestRunner()
+        const [result] = await runner.executeTestsWithConfig([test], runConfig)
+        teq(result!.status, TestStatus.Passed, 'Test receives its arguments and stdin')
+
+        await writeFile(path, '#!/bin/sh\n# testme: stdin missing.txt\nexit 0\n')
+        const [missing] = await runner.executeTestsWithConfig([test], runConfig)
+        ttrue(missing!.status === TestStatus.Error && !!missing!.error?.includes('missing.txt'), 'Missing stdin file')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,30 @@
+/*
+    Platform directive unit tests
+    Verifies platform lists from directives and configuration, including negation and skip reasons
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const parsed = Directives.parse('// testme: platform linux,darwin\n')
+    teq(parsed.platforms?.join(), 'linux,darwin', 'Platform directive parsed')
+
+    ttrue(Directives.matchesPlatform(['linux', 'darwin'], 'linux'), 'Listed platform allowed')
+    ttrue(!Directives.matchesPlatform(['linux', 'darwin'], 'windows'), 'Unlisted platform not allowed')
+    ttrue(!Directives.matchesPlatform(['!windows'], 'windows'), 'Negated platform excluded')
+    ttrue(Directives.matchesPlatform(['!windows'], 'darwin'), 'Negation alone allows other platforms')
+    ttrue(Directives.matchesPlatform(['macosx'], 'darwin'), 'macosx is an alias for darwin')
+
+    teq(Directives.checkPlatform(parsed, undefined, 'linux'), null, 'Runs on a listed platform')
+    const reason = Directives.checkPlatform(parsed, undefined, 'win32')
+    teq(reason, 'Not supported on windows (testme: platform linux, darwin)', 'Directive skip reason')
+
+    const configReason = Directives.checkPlatform({}, 'linux', 'darwin')
+    ttrue(!!configReason?.includes('testme.json5'), 'Configuration platform key applies')
+    teq(Directives.checkPlatform({}, ['!windows'], 'linux'), null, 'Configuration negation')
+    teq(Directives.checkPlatform({}, undefined, 'win32'), null, 'No restriction')
+}
+
+await run(test)
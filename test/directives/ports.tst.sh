@@ -0,0 +1,13 @@
+#!/bin/sh
+# testme: ports 2
+# Verifies the ports directive exports two different ports to the test
+
+if [ -z "$TESTME_PORT0" ] || [ -z "$TESTME_PORT1" ]; then
+    echo "✗ TESTME_PORT0 and TESTME_PORT1 are not set"
+    exit 1
+fi
+if [ "$TESTME_PORT0" = "$TESTME_PORT1" ]; then
+    echo "✗ Ports are not unique: $TESTME_PORT0"
+    exit 1
+fi
+echo "✓ Ports $TESTME_PORT0 and $TESTME_PORT1"
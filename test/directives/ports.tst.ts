@@ -0,0 +1,45 @@
+/*
+    Port allocation directive tests
+    Verifies the ports directive, unique reservations for parallel tests and the exported variables
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestPorts, MAX_PORTS} from '../../src/ports.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {createServer} from 'net'
+
+function bind(port: number): Promise<boolean> {
+    return new Promise((resolve) => {
+        const server = createServer()
+        server.on('error', () => resolve(false))
+        server.listen(port, '127.0.0.1', () => server.close(() => resolve(true)))
+    })
+}
+
+async function test() {
+    teq(Directives.parse('// testme: ports 3\n').ports, 3, 'Ports directive parsed')
+    teq(Directives.parse('# testme: ports\n').ports, 1, 'One port by default')
+    teq(Directives.parse('// testme: ports many\n').ports, undefined, 'Invalid count ignored')
+
+    // Allocations made at the same time never share a port
+    const batches = await Promise.all([1, 2, 3, 4].map(() => TestPorts.allocate(4)))
+    const ports = batches.flat()
+    teq(new Set(ports).size, ports.length, `${ports.length} concurrent ports are unique`)
+    ttrue(await bind(ports[0]!), 'Allocated port can be bound')
+
+    const env = TestPorts.getEnvironment(batches[0]!)
+    ttrue(env.TESTME_PORT0 === String(batches[0]![0]) && env.TESTME_PORT3 === String(batches[0]![3]), 'Variables')
+    teq(Object.keys(env).length, 4, 'One variable per port')
+    batches.forEach((batch) => TestPorts.release(batch))
+
+    let error = ''
+    try {
+        await TestPorts.allocate(MAX_PORTS + 1)
+    } catch (err) {
+        error = (err as Error).message
+    }
+    ttrue(error.startsWith('Cannot allocate more than'), 'Too many ports rejected')
+}
+
+await run(test)
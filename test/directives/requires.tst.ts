@@ -0,0 +1,51 @@
+/*
+    Required tools directive unit tests
+    Verifies tests with missing tools are skipped with a reason, or failed with --strict
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const parsed = Directives.parse('#!/bin/sh\n# testme: requires redis-cli, docker\n/* testme: requires make */\n')
+    teq(parsed.requires?.join(), 'redis-cli,docker,make', 'Tools from all requires directives')
+
+    const missingTool = 'testme-no-such-tool'
+    const missing = await Directives.findMissingTools({requires: ['git', missingTool]})
+    ttrue(missing.length === 1 && missing[0] === missingTool, 'Only missing tools reported')
+
+    if (process.platform === 'win32') {
+        console.log('Shell requires test not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-requires-'))
+    try {
+        const path = join(dir, 'needs.tst.sh')
+        await writeFile(path, `#!/bin/sh\n# testme: requires ${missingTool}\nexit 0\n`)
+        const test = makeFile(dir, 'needs.tst.sh')
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: false},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+
+        const runner = new TestRunner()
+        const [skipped] = await runner.executeTestsWithConfig([test], config)
+        teq(skipped!.status, TestStatus.Skipped, 'Test with a missing tool is skipped')
+        ttrue(skipped!.output.includes(missingTool), 'Skip reason names the missing tool')
+
+        const strictConfig = {...config, execution: {...config.execution!, strict: true}}
+        const [failed] = await runner.executeTestsWithConfig([test], strictConfig)
+        ttrue(failed!.status === TestStatus.Failed && !!failed!.error?.includes(missingTool), 'Strict mode fails')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
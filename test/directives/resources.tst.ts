@@ -0,0 +1,66 @@
+/*
+    Shared resource directive tests
+    Verifies the resource directive, mutual exclusion per resource and deadlock-free locking of several resources
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestResources} from '../../src/resources.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+function sleep(ms: number): Promise<void> {
+    return new Promise((resolve) => setTimeout(resolve, ms))
+}
+
+/*
+    Simulates a test holding its resources, recording the most tests that ran at the same time
+ */
+async function occupy(names: string[], running: Map<string, number>, peak: Map<string, number>, order?: string[]) {
+    const unlock = await TestResources.acquire(names)
+    try {
+        for (const name of names) {
+            running.set(name, (running.get(name) || 0) + 1)
+            peak.set(name, Math.max(peak.get(name) || 0, running.get(name)!))
+        }
+        order?.push(names.join())
+        await sleep(10)
+    } finally {
+        names.forEach((name) => running.set(name, running.get(name)! - 1))
+        unlock()
+    }
+}
+
+async function test() {
+    const directives = Directives.parse('// testme: resource db, cache\n// testme: resource device\n')
+    teq(directives.resources?.join(), 'db,cache,device', 'Resource directives parsed')
+    teq(TestResources.getNames(['db'], ['db', 'cache']).join(), 'db,cache', 'Config and directive combined')
+
+    const running = new Map<string, number>()
+    const peak = new Map<string, number>()
+    const order: string[] = []
+    await Promise.all([
+        occupy(['db'], running, peak, order),
+        occupy(['db'], running, peak, order),
+        occupy(['queue'], running, peak),
+        occupy(['db'], running, peak, order),
+    ])
+    teq(peak.get('db'), 1, 'Tests sharing a resource never overlap')
+    teq(order.length, 3, 'Every waiting test runs')
+
+    // Different resources run together
+    const started = Date.now()
+    await Promise.all([occupy(['a'], running, peak), occupy(['b'], running, peak), occupy(['c'], running, peak)])
+    ttrue(Date.now() - started < 200, 'Tests with different resources run in parallel')
+
+    // Opposite declaration orders would deadlock without a consistent locking order
+    const crossed = Promise.all([
+        occupy(['x', 'y'], running, peak),
+        occupy(['y', 'x'], running, peak),
+        occupy(['y', 'x', 'x'], running, peak),
+    ])
+    const timeout = sleep(2000).then(() => 'deadlock')
+    teq(await Promise.race([crossed.then(() => 'done'), timeout]), 'done', 'Several resources do not deadlock')
+    ttrue(peak.get('x') === 1 && peak.get('y') === 1, 'Each of several resources is exclusive')
+}
+
+await run(test)
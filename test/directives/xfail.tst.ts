@@ -0,0 +1,35 @@
+/*
+    Expected failure directive unit tests
+    Verifies testme: directive parsing and the xfail/xpass status mapping
+ */
+
+import {Directives} from '../../src/directives.ts'
+import {TestRunner} from '../../src/runner.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+
+async function test() {
+    teq(Directives.parse('// testme: xfail\nint main() {}\n').xfail, true, 'C comment directive')
+    teq(Directives.parse('#!/bin/bash\n# testme: xfail\n').xfail, true, 'Shell comment directive')
+    teq(Directives.parse('/* testme: xfail */\n').xfail, true, 'Block comment directive')
+    teq(Directives.parse('@echo off\nREM testme: xfail\n').xfail, true, 'Batch comment directive')
+    ttrue(!Directives.parse('console.log("testme: xfail")\n').xfail, 'Directive must be in a comment')
+    ttrue(!Directives.parse('\n'.repeat(30) + '// testme: xfail\n').xfail, 'Only the top of the file is searched')
+    ttrue(!Directives.parse('// testme: unknown\n').xfail, 'Unknown directive ignored')
+
+    const file = makeFile('/tmp', 'bug.tst.c')
+    const apply = (status: TestStatus, directives = {xfail: true}) =>
+        Directives.applyExpectedFailure(makeResult(file, status), directives).status
+    teq(apply(TestStatus.Failed), TestStatus.XFail, 'Failure is an expected failure')
+    teq(apply(TestStatus.Timeout), TestStatus.XFail, 'Timeout is an expected failure')
+    teq(apply(TestStatus.Passed), TestStatus.XPass, 'Pass is an unexpected pass')
+    teq(apply(TestStatus.Error), TestStatus.Error, 'Error is kept')
+    teq(apply(TestStatus.Failed, {xfail: false}), TestStatus.Failed, 'No directive')
+
+    const runner = new TestRunner()
+    teq(runner.getExitCode([makeResult(file, TestStatus.XFail)]), 0, 'Expected failure does not fail the run')
+    teq(runner.getExitCode([makeResult(file, TestStatus.XPass)]), 1, 'Unexpected pass fails the run')
+}
+
+await run(test)
@@ -0,0 +1,72 @@
+/*
+    Discovery exclude tests
+    Verifies discover.exclude gitignore-style patterns (**, leading and trailing '/', negation) and that discovery does
+    not enter excluded directories
+ */
+
+import {TestDiscovery} from '../../src/discovery.ts'
+import {compileIgnore, isIgnored} from '../../src/utils/ignore.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join, relative} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const ignored = (patterns: string[], path: string, directory = false) =>
+        isIgnored(compileIgnore(patterns), path, directory)
+
+    ttrue(ignored(['vendor/'], 'lib/vendor', true), 'Unanchored pattern matches at any depth')
+    ttrue(!ignored(['vendor/'], 'vendor'), 'Trailing slash only matches directories')
+    ttrue(ignored(['/out'], 'out', true) && !ignored(['/out'], 'src/out', true), 'Leading slash anchors to the root')
+    ttrue(ignored(['doc/*.sh'], 'doc/a.sh') && !ignored(['doc/*.sh'], 'x/doc/a.sh'), 'Inner slash anchors to the root')
+    ttrue(!ignored(['doc/*.sh'], 'doc/x/a.sh'), 'Star does not match a slash')
+    ttrue(ignored(['a/**/gen'], 'a/gen', true) && ignored(['a/**/gen'], 'a/b/c/gen', true), 'Double star directories')
+    ttrue(ignored(['third_party/**'], 'third_party/x/y.tst.c'), 'Trailing double star matches everything inside')
+    ttrue(!ignored(['*.tst.c', '!keep.tst.c'], 'src/keep.tst.c'), 'Negation re-includes')
+    ttrue(ignored(['!keep.tst.c', '*.tst.c'], 'src/keep.tst.c'), 'Last matching pattern wins')
+    ttrue(ignored(['t[0-9]?'], 't1x') && !ignored(['t[!0-9]'], 't1'), 'Character classes')
+    teq(compileIgnore(['', '# comment']).length, 0, 'Empty patterns and comments ignored')
+
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-exclude-')))
+    try {
+        for (const dir of ['unit', 'vendor/lib', 'out/deep', 'gen']) {
+            await mkdir(join(rootDir, dir), {recursive: true})
+        }
+        for (const file of ['unit/a.tst.sh', 'unit/skip.tst.sh', 'vendor/lib/v.tst.sh', 'out/deep/o.tst.sh']) {
+            await writeFile(join(rootDir, file), 'exit 0\n')
+        }
+        await writeFile(join(rootDir, 'gen', 'keep.tst.sh'), 'exit 0\n')
+        await writeFile(join(rootDir, 'gen', 'g.tst.sh'), 'exit 0\n')
+
+        // Record the directories the walk enters
+        const walked: string[] = []
+        const discovery = TestDiscovery as any
+        const search = discovery.searchDirectory
+        discovery.searchDirectory = function (dir: string, ...args: unknown[]) {
+            walked.push(relative(rootDir, dir))
+            return search.call(this, dir, ...args)
+        }
+        try {
+            const tests = await TestDiscovery.discoverTests({
+                rootDir,
+                patterns: ['**/*.tst.sh'],
+                excludePatterns: [],
+                ignore: ['vendor/', '/out', 'skip.tst.sh', 'gen/*', '!gen/keep.tst.sh'],
+            })
+            const found = tests.map((test) => relative(rootDir, test.path)).sort()
+            teq(found.join(), 'gen/keep.tst.sh,unit/a.tst.sh', 'Excluded paths not discovered')
+            const entered = walked.filter((dir) => dir.startsWith('vendor') || dir.startsWith('out'))
+            teq(entered.length, 0, 'Excluded directories not entered')
+        } finally {
+            discovery.searchDirectory = search
+        }
+
+        const config = {discover: {exclude: ['unit/']}}
+        teq(TestDiscovery.getWalkOptions(config).ignore?.join(), 'unit/', 'discover.exclude passed to discovery')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
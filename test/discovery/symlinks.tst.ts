@@ -0,0 +1,70 @@
+/*
+    Symlink discovery tests
+    Verifies symlinked directories are skipped by default, that discover.followSymlinks walks them without looping on a
+    link cycle, that a test reached by two paths is discovered once and that symlinked test files are found
+ */
+
+import {TestDiscovery} from '../../src/discovery.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, symlink, writeFile} from 'fs/promises'
+import {join, relative} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Creating symlinks needs extra privileges on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-symlinks-')))
+    try {
+        /*
+            unit/math.tst.sh
+            unit/loop -> ..                 (cycle)
+            shared -> outside               (directory outside the tree)
+            alias -> unit                   (second path to unit/math.tst.sh)
+            copy.tst.sh -> unit/math.tst.sh (symlinked file, same test again)
+            broken.tst.sh -> missing
+         */
+        const outside = await realpath(await mkdtemp(join(tmpdir(), 'testme-shared-')))
+        await writeFile(join(outside, 'fixture.tst.sh'), 'exit 0\n')
+        await mkdir(join(rootDir, 'unit'))
+        await writeFile(join(rootDir, 'unit', 'math.tst.sh'), 'exit 0\n')
+        await symlink('..', join(rootDir, 'unit', 'loop'))
+        await symlink(outside, join(rootDir, 'shared'))
+        await symlink('unit', join(rootDir, 'alias'))
+        await symlink(join('unit', 'math.tst.sh'), join(rootDir, 'copy.tst.sh'))
+        await symlink('missing', join(rootDir, 'broken.tst.sh'))
+
+        const discover = async (followSymlinks?: boolean) => {
+            const options = {rootDir, patterns: ['**/*.tst.sh'], excludePatterns: [], followSymlinks}
+            const tests = await TestDiscovery.discoverTests(options)
+            return tests.map((test) => relative(rootDir, test.path)).sort()
+        }
+
+        try {
+            const skipped = await discover()
+            teq(skipped.join(), 'unit/math.tst.sh', 'Symlinked directories not followed by default')
+            ttrue(!skipped.includes('broken.tst.sh'), 'Broken link ignored')
+
+            const followed = await discover(true)
+            ttrue(followed.includes('shared/fixture.tst.sh'), 'Symlinked directory followed when enabled')
+            ttrue(!followed.some((path) => path.includes('loop')), 'Symlink cycle not walked')
+            teq(followed.filter((path) => path.endsWith('math.tst.sh')).length, 1, 'Test discovered once')
+            ttrue(followed.includes('unit/math.tst.sh'), 'Path without symlinks preferred')
+
+            await rm(join(rootDir, 'unit', 'math.tst.sh'))
+            await writeFile(join(rootDir, 'only.tst.sh'), 'exit 0\n')
+            await symlink(join(rootDir, 'only.tst.sh'), join(rootDir, 'unit', 'linked.tst.sh'))
+            await rm(join(rootDir, 'copy.tst.sh'))
+            await symlink(join(outside, 'fixture.tst.sh'), join(rootDir, 'copy.tst.sh'))
+            teq((await discover()).join(), 'copy.tst.sh,only.tst.sh', 'Symlinked test file discovered')
+        } finally {
+            await rm(outside, {recursive: true, force: true})
+        }
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,45 @@
+/*
+    Container execution unit tests
+    Verifies image selection, build placement, directory mounts and the docker run arguments
+ */
+
+import {Docker} from '../../src/docker.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Host paths are mounted at the same path - skipping on Windows')
+        return
+    }
+    const file = {name: 'math.tst.c', path: '/src/tests/math.tst.c', directory: '/src/tests', type: TestType.C}
+    teq(Docker.getContainer({}, file as TestFile), undefined, 'Tests run on the host by default')
+
+    const config: TestConfig = {configDir: '/src', docker: {image: 'gcc:13', args: ['--network', 'host']}}
+    const container = Docker.getContainer(config, file as TestFile)!
+    teq(container.image, 'gcc:13', 'docker.image used')
+    teq(container.mounts.join(' '), '/src', 'Test directory inside the config directory is not mounted twice')
+    teq(Docker.getContainer(config, file as TestFile, true), undefined, 'Builds run on the host by default')
+    const build = Docker.getContainer({...config, docker: {...config.docker, build: true}}, file as TestFile, true)
+    teq(build?.image, 'gcc:13', 'docker.build runs builds in the container')
+
+    const cli: TestConfig = {...config, execution: {timeout: 30, parallel: true, docker: 'alpine'}}
+    teq(Docker.getImage(cli), 'alpine', '--docker overrides docker.image')
+
+    const args = Docker.getRunArgs(container, 'testme-1', './math', ['-v'], {
+        cwd: '/src/tests',
+        env: {TESTME_VERBOSE: '1', PATH: '/host/bin'},
+        stdin: true,
+    })
+    teq(
+        args.join(' '),
+        'run --rm --name testme-1 -i -v /src:/src -w /src/tests -e TESTME_VERBOSE=1 ' +
+            '--network host gcc:13 ./math -v',
+        'docker run arguments'
+    )
+    ttrue(Docker.getName() !== Docker.getName(), 'Container names are unique')
+}
+
+await run(test)
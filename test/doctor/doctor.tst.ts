@@ -0,0 +1,58 @@
+/*
+    --doctor unit tests
+    Verifies version parsing and comparison, tool lookup with minimum versions and compiler directory checks
+ */
+
+import {Doctor} from '../../src/doctor.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    teq(Doctor.parseVersion('go version go1.22.1 linux/amd64'), '1.22.1', 'Go version parsed')
+    teq(Doctor.parseVersion('gcc (GCC) 13.2.0\nCopyright'), '13.2.0', 'GCC version parsed')
+    teq(Doctor.parseVersion('no version here'), undefined, 'Missing version')
+    ttrue(Doctor.compareVersions('1.22.1', '1.21') > 0, 'Newer version compares greater')
+    ttrue(Doctor.compareVersions('3.8.10', '3.9') < 0, 'Older version compares less')
+    teq(Doctor.compareVersions('11', '11.0.0'), 0, 'Missing components are zero')
+
+    if (process.platform === 'win32') {
+        console.log('Toolchain lookup test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-doctor-')))
+    try {
+        await mkdir(join(rootDir, 'include'))
+        await writeFile(join(rootDir, 'a.tst.sh'), '#!/bin/sh\nexit 0\n')
+        const shell = makeFile(rootDir, 'a.tst.sh')
+        const rust = makeFile(rootDir, 'b.tst.rs')
+        const config: TestConfig = {configDir: rootDir, compiler: {rust: {compiler: join(rootDir, 'missing-rustc')}}}
+
+        const tools = await Doctor.checkTools([
+            {file: shell, config},
+            {file: {...shell, path: join(rootDir, 'c.tst.sh')}, config},
+            {file: rust, config},
+        ])
+        const sh = tools.find((tool) => tool.language === 'Shell')!
+        ttrue(!!sh.path && !sh.problem && sh.tests === 2, 'Shell found and counted once per test')
+        const rustc = tools.find((tool) => tool.language === 'Rust')!
+        ttrue(rustc.path === null && rustc.problem === 'not found', 'Missing tool reported')
+
+        const strict = await Doctor.checkTools([{file: shell, config}], {[sh.name]: '99999'})
+        ttrue(!!strict[0]!.problem && strict[0]!.minimum === '99999', 'Minimum version enforced')
+
+        const paths = Doctor.checkPaths([
+            {configDir: rootDir, compiler: {c: {flags: ['-I./include', '-L../nolib', '-I${TOP}/inc', '-DX']}}},
+        ])
+        teq(paths.length, 2, 'Directory flags checked, others skipped')
+        ttrue(!paths[0]!.problem && paths[0]!.path === join(rootDir, 'include'), 'Existing directory passes')
+        teq(paths[1]!.problem, 'not found', 'Missing directory reported')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,58 @@
+/*
+    Dry-run unit tests
+    Verifies shell quoting of printed commands and that tm --dry-run prints service and test commands in order
+ */
+
+import {DryRun} from '../../src/utils/dry-run.ts'
+import {teq, ttrue} from 'testme'
+import {run, tmPath} from '../helpers.ts'
+import {spawn} from 'bun'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {existsSync} from 'fs'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    teq(DryRun.quote('-I/usr/include'), '-I/usr/include', 'Plain argument not quoted')
+    teq(DryRun.quote('a b'), "'a b'", 'Argument with a space quoted')
+    teq(DryRun.quote("it's"), `'it'\\''s'`, 'Embedded single quote escaped')
+    teq(DryRun.quote(''), "''", 'Empty argument quoted')
+
+    const line = DryRun.formatCommand('gcc', ['-o', 'out', 'math.tst.c'], {
+        cwd: '/work/my tests',
+        env: {TESTME_DRY_RUN_CHECK: '1', PATH: process.env.PATH!},
+    })
+    teq(line, "cd '/work/my tests' && TESTME_DRY_RUN_CHECK=1 gcc -o out math.tst.c", 'Command line format')
+
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-dry-run-')))
+    try {
+        await writeFile(
+            join(rootDir, 'testme.json5'),
+            "{services: {prep: './prep.sh', cleanup: './cleanup.sh'}, environment: {GREETING: 'hello'}}\n"
+        )
+        await writeFile(join(rootDir, 'prep.sh'), 'touch prep-ran\n')
+        await writeFile(join(rootDir, 'cleanup.sh'), 'touch cleanup-ran\n')
+        await writeFile(join(rootDir, 'hello.tst.sh'), 'touch test-ran\nexit 1\n')
+
+        const proc = spawn([tmPath, '--dry-run'], {cwd: rootDir, stdout: 'pipe', stderr: 'pipe'})
+        const stdout = await new Response(proc.stdout).text()
+        await proc.exited
+
+        teq(proc.exitCode, 0, 'Dry run exits successfully without running the failing test')
+        const prep = stdout.indexOf('Prep script:')
+        const body = stdout.indexOf('Test hello.tst.sh:')
+        const cleanup = stdout.indexOf('Cleanup script:')
+        ttrue(prep >= 0 && prep < body && body < cleanup, 'Prep, test and cleanup commands printed in order')
+        ttrue(stdout.includes('GREETING=hello'), 'Configured environment shown')
+        ttrue(
+            !existsSync(join(rootDir, 'prep-ran')) &&
+                !existsSync(join(rootDir, 'test-ran')) &&
+                !existsSync(join(rootDir, 'cleanup-ran')),
+            'Nothing executed'
+        )
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
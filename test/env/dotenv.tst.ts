@@ -0,0 +1,67 @@
+/*
+    .env file unit tests
+    Verifies .env parsing, env.file resolution and the precedence of --env, configuration, .env and process variables
+ */
+
+import {getDotEnvPath, loadDotEnv, parseDotEnv} from '../../src/utils/dotenv.ts'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const vars = parseDotEnv(
+        [
+            '# comment',
+            '',
+            'PLAIN=value # trailing comment',
+            'export EXPORTED=yes',
+            "SINGLE='literal # \\n'",
+            'DOUBLE="line\\none \\"quoted\\""',
+            'EMPTY=',
+            'not a variable',
+        ].join('\r\n')
+    )
+    teq(vars.PLAIN, 'value', 'Unquoted value ends at a comment')
+    teq(vars.EXPORTED, 'yes', 'Export prefix allowed')
+    teq(vars.SINGLE, 'literal # \\n', 'Single quoted value taken literally')
+    teq(vars.DOUBLE, 'line\none "quoted"', 'Double quoted value escapes expanded')
+    teq(vars.EMPTY, '', 'Empty value')
+    teq(Object.keys(vars).length, 5, 'Comments and invalid lines ignored')
+
+    const configured = getDotEnvPath({configDir: '/work', environment: {file: 'env/test.env'}})
+    ttrue(configured?.path === join('/work', 'env', 'test.env') && configured.required, 'env.file relative to config')
+    let missing = false
+    try {
+        await loadDotEnv({configDir: '/nonexistent', environment: {file: 'missing.env'}})
+    } catch {
+        missing = true
+    }
+    ttrue(missing, 'Missing configured file is an error')
+
+    if (process.platform === 'win32') {
+        console.log('.env run test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-dotenv-')))
+    try {
+        await writeFile(join(rootDir, '.env'), 'FROM_PROCESS=file\nFROM_FILE=file\nFROM_CONFIG=file\nFROM_CLI=file\n')
+        await writeFile(join(rootDir, 'testme.json5'), "{environment: {FROM_CONFIG: 'config', FROM_CLI: 'config'}}\n")
+        await writeFile(
+            join(rootDir, 'env.tst.sh'),
+            '#!/bin/sh\n[ "$FROM_PROCESS" = file ] && [ "$FROM_FILE" = file ] && ' +
+                '[ "$FROM_CONFIG" = config ] && [ "$FROM_CLI" = cli ]\n'
+        )
+        const env = {FROM_PROCESS: 'process'}
+        const result = await runTm(['--env', 'FROM_CLI=cli'], rootDir, env)
+        teq(result.exitCode, 0, '--env overrides configuration, which overrides .env, which overrides process')
+
+        const without = await runTm([], rootDir, env)
+        teq(without.exitCode, 1, 'Configured value used without --env')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,71 @@
+/*
+    Benchmark baseline unit tests
+    Verifies TESTME-BENCH lines are parsed, regressions beyond the threshold fail the test and --bench-update
+    records qualified baselines
+ */
+
+import {Benchmarks} from '../../src/bench.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+import {existsSync} from 'fs'
+import {mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+// Passing result of parse.tst.c that printed stdout
+function captured(dir: string, stdout: string, matrix?: string): TestResult {
+    return makeResult(makeFile(dir, 'parse.tst.c', {matrix}), TestStatus.Passed, {output: stdout, stdout, exitCode: 0})
+}
+
+function makeConfig(execution: Partial<NonNullable<TestConfig['execution']>> = {}): TestConfig {
+    return {execution: {timeout: 30, parallel: false, bench: true, ...execution}}
+}
+
+async function test() {
+    const lines = 'noise\nTESTME-BENCH name=a ns=100\r\nTESTME-BENCH ns=5\nTESTME-BENCH name=b ns=x\n'
+    const timings = Benchmarks.parse(lines)
+    ttrue(timings.size === 1 && timings.get('a') === 100, 'Parses benchmark lines and ignores invalid ones')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-bench-'))
+    try {
+        const path = Benchmarks.getPath(join(dir, 'parse.tst.c'))
+        const stdout = 'TESTME-BENCH name=small ns=1200\nTESTME-BENCH name=large ns=5000\n'
+
+        let result = Benchmarks.check(captured(dir, stdout), {execution: {timeout: 30, parallel: false}})
+        teq(result.benchmarks, undefined, 'Ignored without --bench')
+
+        result = Benchmarks.check(captured(dir, stdout), makeConfig())
+        ttrue(result.status === TestStatus.Passed && result.benchmarks!.length === 2, 'No baseline never fails')
+        teq(Benchmarks.format(result.benchmarks![0]!), '1200 ns (no baseline)', 'Format without baseline')
+
+        result = Benchmarks.check(captured(dir, stdout, 'MODE=fast'), makeConfig({benchUpdate: true}))
+        ttrue(result.output.includes('Recorded 2 benchmark(s) in parse.tst.c.bench'), 'Update noted in output')
+        const saved = JSON.parse(await readFile(path, 'utf-8'))
+        ttrue(saved['[MODE=fast] small'] === 1200 && saved['[MODE=fast] large'] === 5000, 'Names qualified by cell')
+
+        await writeFile(path, JSON.stringify({small: 1000, large: 5000}))
+        result = Benchmarks.check(captured(dir, stdout), makeConfig())
+        teq(result.status, TestStatus.Failed, 'Regression beyond 10% fails')
+        ttrue(result.error!.startsWith('Benchmark small regressed 20.0% (1200 ns vs 1000 ns'), 'Regression error')
+        teq(result.benchmarks![1]!.regressed, false, 'Unchanged benchmark not regressed')
+        teq(Benchmarks.format(result.benchmarks![0]!), '1200 ns (baseline 1000 ns, +20.0%)', 'Format delta')
+
+        result = Benchmarks.check(captured(dir, stdout), makeConfig({benchThreshold: 25}))
+        teq(result.status, TestStatus.Passed, 'Threshold is configurable')
+
+        const failed = {...captured(dir, stdout), status: TestStatus.Failed}
+        await rm(path)
+        Benchmarks.check(failed, makeConfig({benchUpdate: true}))
+        ttrue(!existsSync(path), 'Failing tests do not update the baseline')
+
+        const options = CliParser.parse(['--bench', '--bench-update'])
+        ttrue(options.bench === true && options.benchUpdate === true, '--bench and --bench-update')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,136 @@
+/*
+    Expected output (golden file) unit tests
+    Verifies stdout comparison, unified diffs, newline normalization, golden.normalize rules, <<IGNORE>>
+    wildcards, numeric tolerance and --accept
+ */
+
+import {ExpectedOutput} from '../../src/expected.ts'
+import {unifiedDiff} from '../../src/utils/diff.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+// Passing result of cli.tst.sh that printed stdout
+function captured(dir: string, stdout: string): TestResult {
+    return makeResult(join(dir, 'cli.tst.sh'), TestStatus.Passed, {output: stdout, stdout, exitCode: 0})
+}
+
+function makeConfig(execution: Partial<NonNullable<TestConfig['execution']>> = {}): TestConfig {
+    return {execution: {timeout: 30, parallel: false, ...execution}}
+}
+
+async function test() {
+    const diff = unifiedDiff('a\nb\nc\n', 'a\nB\nc\n', 'expected', 'stdout')
+    ttrue(diff.includes('@@ -1,3 +1,3 @@') && diff.includes('-b\n+B'), 'Unified diff hunk')
+    teq(unifiedDiff('same\n', 'same\n', 'a', 'b'), '', 'Identical text has no diff')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-expected-'))
+    try {
+        const expectedPath = join(dir, 'cli.tst.sh.expected')
+
+        // No expected file - result is unchanged
+        let result = await ExpectedOutput.check(captured(dir, 'anything\n'), makeConfig())
+        teq(result.status, TestStatus.Passed, 'No expected file skips comparison')
+
+        await writeFile(expectedPath, 'line one\nline two\n')
+        result = await ExpectedOutput.check(captured(dir, 'line one\nline two\n'), makeConfig())
+        teq(result.status, TestStatus.Passed, 'Matching output passes')
+
+        result = await ExpectedOutput.check(captured(dir, 'line one\nline 2\n'), makeConfig())
+        teq(result.status, TestStatus.Failed, 'Mismatched output fails')
+        ttrue(result.output.includes('-line two\n+line 2'), 'Mismatch includes unified diff')
+
+        // Newline normalization
+        const crlf = captured(dir, 'line one\r\nline two\r\n')
+        teq((await ExpectedOutput.check(crlf, makeConfig())).status, TestStatus.Passed, 'CRLF normalized')
+        const exact = makeConfig({expectedNewlines: 'exact'})
+        teq((await ExpectedOutput.check(crlf, exact)).status, TestStatus.Failed, 'Exact mode keeps CRLF')
+        const trailing = captured(dir, 'line one  \nline two\n\n')
+        const trim = makeConfig({expectedNewlines: 'trim'})
+        teq((await ExpectedOutput.check(trailing, trim)).status, TestStatus.Passed, 'Trim ignores trailing space')
+
+        // Volatile content normalization
+        const volatile = 'at 2026-10-15T09:30:12.345Z pid=4123 id 0b6e1c4a-1f2e-4c3d-9a8b-7c6d5e4f3a2b took 12ms\n'
+        const rules = ['timestamps', 'pids', 'uuids', 'durations']
+        const normalized = 'at <TIME> pid=<PID> id <UUID> took <DURATION>\n'
+        teq(ExpectedOutput.applyRules(volatile, rules), normalized, 'Built-in normalizers')
+        const tmpDir = join(dir, 'tmp')
+        teq(ExpectedOutput.applyRules(`${tmpDir}/out.log\n`, ['tmp'], tmpDir), '<TMP>/out.log\n', 'Temp paths')
+        const custom = [{pattern: 'port \\d+', replace: 'port <PORT>'}]
+        teq(ExpectedOutput.applyRules('port 8080, port 9090', custom), 'port <PORT>, port <PORT>', 'Custom rules')
+
+        await writeFile(expectedPath, 'started at <TIME>\n')
+        const golden = {normalize: ['timestamps']}
+        const stamped = captured(dir, 'started at 2026-10-15 09:30:12\n')
+        result = await ExpectedOutput.check(stamped, {...makeConfig(), golden})
+        teq(result.status, TestStatus.Passed, 'Output normalized before comparing')
+        result = await ExpectedOutput.check(stamped, {...makeConfig(), golden: {normalize: ['nonsense']}})
+        ttrue(result.status === TestStatus.Error && result.error!.includes('nonsense'), 'Unknown normalizer')
+        result = await ExpectedOutput.check(stamped, {...makeConfig({accept: true}), golden})
+        teq(await readFile(expectedPath, 'utf-8'), 'started at <TIME>\n', 'Accepted output normalized')
+
+        // Masked regions
+        const match = ExpectedOutput.getMatcher()
+        ttrue(match('id: <<IGNORE>> created', 'id: 4f2a created'), 'Wildcard matches a span')
+        ttrue(match('id: <<IGNORE>> created', 'id:  created'), 'Wildcard matches an empty span')
+        ttrue(!match('id: <<IGNORE>> created', 'id: 4f2a deleted'), 'Literal text after a wildcard must match')
+        ttrue(match('a.b<<IGNORE>>', 'a.b(x)') && !match('a.b<<IGNORE>>', 'axb'), 'Literal text is not a pattern')
+        ttrue(match('\\<<IGNORE>>', '<<IGNORE>>') && !match('\\<<IGNORE>>', 'x'), 'Escaped token is literal')
+        ttrue(match('\\\\<<IGNORE>>!', '\\any!'), 'Escaped backslash before a wildcard')
+
+        await writeFile(expectedPath, 'begin\nsession <<IGNORE>> open\nend\n')
+        result = await ExpectedOutput.check(captured(dir, 'begin\nsession 81 open\nend\n'), makeConfig())
+        teq(result.status, TestStatus.Passed, 'Masked expected file matches')
+        result = await ExpectedOutput.check(captured(dir, 'begin\nsession 81 shut\nend\n'), makeConfig())
+        ttrue(result.output.includes('-session <<IGNORE>> open\n+session 81 shut'), 'Masked mismatch diff')
+        const accept = makeConfig({accept: true})
+        await ExpectedOutput.check(captured(dir, 'begin\nsession 93 open\nnew\nend\n'), accept)
+        const kept = 'begin\nsession <<IGNORE>> open\nnew\nend\n'
+        teq(await readFile(expectedPath, 'utf-8'), kept, 'Accept keeps matching wildcard lines')
+
+        // Numeric tolerance
+        const tolerance = {absolute: 0.01, relative: 0.05}
+        teq(ExpectedOutput.compareNumbers('pi 3.14159', 'pi 3.1466', tolerance), '', 'Absolute tolerance')
+        teq(ExpectedOutput.compareNumbers('n=1.0e6', 'n=1040000', tolerance), '', 'Relative tolerance')
+        teq(ExpectedOutput.compareNumbers('x 1 y', 'z 1 y', tolerance), 'text differs', 'Text must match')
+        teq(ExpectedOutput.compareNumbers('x 1', 'x 1 2', tolerance), 'text differs', 'Number count must match')
+        const reason = ExpectedOutput.compareNumbers('mean 10', 'mean 11', tolerance)
+        teq(reason, '11 differs from expected 10 by 1 (allowed 0.5)', 'Reason names the number')
+
+        await writeFile(expectedPath, 'mean 2.500
+max 10
+')
+        const numeric = {...makeConfig(), golden: {numericTolerance: tolerance}}
+        result = await ExpectedOutput.check(captured(dir, 'mean 2.5049
+max 10.2
+'), numeric)
+        teq(result.status, TestStatus.Passed, 'Numbers within tolerance pass')
+        result = await ExpectedOutput.check(captured(dir, 'mean 2.5049
+max 12
+'), numeric)
+        teq(result.status, TestStatus.Failed, 'Number out of tolerance fails')
+        ttrue(result.error!.includes('Line 2: 12 differs from expected 10'), 'Error names the line and number')
+        result = await ExpectedOutput.check(captured(dir, 'mean 2.5049
+max 10.2
+'), makeConfig())
+        teq(result.status, TestStatus.Failed, 'Numbers compared as text without a tolerance')
+        await ExpectedOutput.check(captured(dir, 'mean 2.51
+max 10
+min 1
+'), {...numeric, ...makeConfig({accept: true})})
+        teq(await readFile(expectedPath, 'utf-8'), 'mean 2.500 max 10 min 1 ', 'Accept keeps lines in tolerance')
+
+        // Accept rewrites the expected file
+        result = await ExpectedOutput.check(captured(dir, 'new output\n'), makeConfig({accept: true}))
+        teq(result.status, TestStatus.Passed, 'Accept passes')
+        teq(await readFile(expectedPath, 'utf-8'), 'new output\n', 'Accept rewrites expected file')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
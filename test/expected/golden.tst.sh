@@ -0,0 +1,7 @@
+#!/bin/sh
+# Golden file test - stdout is compared with golden.tst.sh.expected
+# test/expected/golden.tst.sh
+
+echo "TestMe golden output"
+printf 'alpha\nbeta\ngamma\n'
+exit 0
@@ -0,0 +1,8 @@
+#!/bin/sh
+# Golden file test with masked regions - the session id differs on every run
+# test/expected/masked.tst.sh
+
+echo "TestMe masked output"
+echo "session id: $$ created"
+echo "done at $(date +%s) seconds"
+exit 0
@@ -0,0 +1,41 @@
+/*
+    Fail-fast tests
+    Verifies --fail-fast kills a long-running test in another worker, skips the rest and reports how many did not run
+ */
+
+import {existsSync} from 'fs'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Shell fail-fast test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-fail-fast-')))
+    try {
+        await writeFile(join(rootDir, 'a-fail.tst.sh'), 'sleep 1\nexit 1\n')
+        await writeFile(join(rootDir, 'b-slow.tst.sh'), `sleep 60\ntouch ${join(rootDir, 'finished')}\n`)
+        await writeFile(join(rootDir, 'c-later.tst.sh'), `touch ${join(rootDir, 'started')}\n`)
+
+        const started = Date.now()
+        const {exitCode, stdout} = await runTm(['--fail-fast', '--workers', '2'], rootDir)
+
+        ttrue(exitCode !== 0, 'Aborted run fails')
+        ttrue(Date.now() - started < 20000, 'Long-running sibling is killed')
+        ttrue(!existsSync(join(rootDir, 'finished')), 'Killed test did not run to completion')
+        ttrue(!existsSync(join(rootDir, 'started')), 'No new tests are started')
+        teq(
+            stdout.match(/⛔ Run aborted after .*/)?.[0],
+            '⛔ Run aborted after a-fail.tst.sh failed (--fail-fast): 2 test(s) not executed',
+            'Abort summary names the failing test and counts the killed and skipped tests'
+        )
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,42 @@
+/*
+    Last failures record unit tests
+    Verifies that failing tests are recorded and that re-runs converge as failures are fixed
+ */
+
+import {LastFailures} from '../../src/failures.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-failures-'))
+    try {
+        teq(await LastFailures.load(rootDir), null, 'Missing record returns null')
+
+        await LastFailures.save(rootDir, [
+            makeResult(join(rootDir, 'unit', 'a.tst.sh'), TestStatus.Passed),
+            makeResult(join(rootDir, 'unit', 'b.tst.sh'), TestStatus.Failed),
+            makeResult(join(rootDir, 'unit', 'c.tst.sh'), TestStatus.Timeout),
+        ])
+        const content = await readFile(LastFailures.getPath(rootDir), 'utf-8')
+        teq(content, 'unit/b.tst.sh\nunit/c.tst.sh\n', 'Failures stored relative to the root')
+
+        const failures = await LastFailures.load(rootDir)
+        ttrue(failures!.has(join(rootDir, 'unit', 'b.tst.sh')) && failures!.size === 2, 'Failures loaded')
+
+        // Re-run only b: it now passes, c did not run and keeps its entry
+        await LastFailures.save(rootDir, [makeResult(join(rootDir, 'unit', 'b.tst.sh'), TestStatus.Passed)])
+        const remaining = await LastFailures.load(rootDir)
+        ttrue(remaining!.size === 1 && remaining!.has(join(rootDir, 'unit', 'c.tst.sh')), 'Fixed test removed')
+
+        await LastFailures.save(rootDir, [makeResult(join(rootDir, 'unit', 'c.tst.sh'), TestStatus.Passed)])
+        teq((await LastFailures.load(rootDir))!.size, 0, 'Record empty when all failures fixed')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,59 @@
+/*
+    Test history unit tests
+    Verifies outcomes are appended and capped per test, and that flaky tests are scored and sorted by instability
+ */
+
+import {HISTORY_SIZE, TestHistory} from '../../src/history.ts'
+import {CliParser} from '../../src/cli.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-history-'))
+    try {
+        teq(await TestHistory.load(rootDir), null, 'Missing history returns null')
+
+        await TestHistory.save(rootDir, [
+            makeResult(join(rootDir, 'unit', 'math.tst.sh'), TestStatus.Passed),
+            makeResult(makeFile(join(rootDir, 'unit'), 'net.tst.sh', {matrix: 'MODE=fast'}), TestStatus.Passed),
+            makeResult(makeFile(join(rootDir, 'unit'), 'net.tst.sh', {matrix: 'MODE=safe'}), TestStatus.Timeout),
+            makeResult(join(rootDir, 'unit', 'skip.tst.sh'), TestStatus.Skipped),
+        ])
+        const data = JSON.parse(await readFile(TestHistory.getPath(rootDir), 'utf-8'))
+        ttrue(data['unit/math.tst.sh'] === 'P' && data['unit/net.tst.sh'] === 'F', 'One outcome per test and run')
+        ttrue(!('unit/skip.tst.sh' in data), 'Skipped tests not recorded')
+
+        for (let round = 0; round < HISTORY_SIZE + 5; round++) {
+            await TestHistory.save(rootDir, [
+                makeResult(join(rootDir, 'unit', 'math.tst.sh'), round % 2 ? TestStatus.Failed : TestStatus.Passed),
+            ])
+        }
+        const history = (await TestHistory.load(rootDir))!
+        const math = history.get(join(rootDir, 'unit', 'math.tst.sh'))!
+        ttrue(math.length === HISTORY_SIZE && math.endsWith('PFP'), 'History capped to the most recent runs')
+
+        ttrue(TestHistory.getScore('PPPP') === 0 && TestHistory.getScore('PFPF') === 1, 'Scores')
+        teq(TestHistory.getScore('F'), 0, 'A single run is not flaky')
+        const flaky = TestHistory.getFlaky(
+            new Map([
+                ['/t/stable.tst.sh', 'PPPPPPPP'],
+                ['/t/broken.tst.sh', 'PPPPFFFF'],
+                ['/t/fixed.tst.sh', 'FP'],
+                ['/t/rare.tst.sh', 'PPPPPPPPPPFPPPPPPPPF'],
+                ['/t/some.tst.sh', 'PPFPPFPP'],
+                ['/t/wild.tst.sh', 'PFPFPFPF'],
+            ])
+        )
+        teq(flaky.map((test) => test.path).join(','), '/t/wild.tst.sh,/t/some.tst.sh', 'Sorted by instability')
+        ttrue(flaky[1]!.failures === 2 && flaky[1]!.score.toFixed(2) === '0.57', 'Failures and score')
+        teq(CliParser.parse(['--flaky-report']).flakyReport, true, '--flaky-report')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
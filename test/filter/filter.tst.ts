@@ -0,0 +1,41 @@
+/*
+    Regular expression test filtering unit tests
+    Verifies --filter/--exclude selection against root-relative paths and option validation
+ */
+
+import {TestDiscovery} from '../../src/discovery.ts'
+import {CliParser} from '../../src/cli.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {basename, dirname, join} from 'path'
+
+async function test() {
+    const rootDir = '/work/project'
+    const tests = ['unit/math.tst.sh', 'unit/slow-io.tst.sh', 'integration/api.tst.sh'].map((path) =>
+        makeFile(join(rootDir, dirname(path)), basename(path))
+    )
+    const select = (filter?: string, exclude?: string) =>
+        TestDiscovery.filterTestsByRegex(tests, rootDir, filter, exclude)
+            .map((test) => test.name)
+            .join(',')
+
+    teq(select('^unit/'), 'math.tst.sh,slow-io.tst.sh', 'Filter matches relative path')
+    teq(select(undefined, 'slow'), 'math.tst.sh,api.tst.sh', 'Exclude drops matching tests')
+    teq(select('^unit/', 'slow'), 'math.tst.sh', 'Filter and exclude combine')
+    teq(select('^project/'), '', 'Root directory is not part of the matched path')
+    teq(select(), 'math.tst.sh,slow-io.tst.sh,api.tst.sh', 'No expressions keeps all tests')
+
+    const options = CliParser.parse(['--filter', 'api', '--exclude', 'slow', 'math'])
+    ttrue(options.filter === 'api' && options.exclude === 'slow', 'Options parsed')
+    teq(options.patterns.join(), 'math', 'Patterns still collected')
+
+    let error = ''
+    try {
+        CliParser.validateOptions(CliParser.parse(['--filter', 'unit/(']))
+    } catch (err) {
+        error = (err as Error).message
+    }
+    ttrue(error.startsWith('Invalid --filter regular expression'), 'Invalid expression rejected')
+}
+
+await run(test)
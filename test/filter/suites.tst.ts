@@ -0,0 +1,64 @@
+/*
+    Named suite unit tests
+    Verifies suites from the suite key, nested inheriting configurations and the suite directive, selection with
+    --suite, unknown suite errors and option parsing
+ */
+
+import {TestSuites} from '../../src/suites.ts'
+import {Directives} from '../../src/directives.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestFile} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdir, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const options = CliParser.parse(['--suite', 'unit,db', '--suite', 'integration', '--list-suites'])
+    teq(options.suite?.join(), 'unit,db,integration', '--suite is repeatable and comma-separated')
+    teq(options.listSuites, true, '--list-suites')
+    teq(Directives.parse('// testme: suite slow, db\n').suites?.join(), 'slow,db', 'Suite directive')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-suites-'))
+    try {
+        await mkdir(join(dir, 'unit'))
+        await mkdir(join(dir, 'integration', 'db'), {recursive: true})
+        await writeFile(join(dir, 'unit', 'testme.json5'), "{suite: 'unit'}\n")
+        await writeFile(join(dir, 'integration', 'testme.json5'), "{suite: ['integration', 'slow']}\n")
+        await writeFile(join(dir, 'integration', 'db', 'testme.json5'), "{inherit: ['suite'], suite: 'db'}\n")
+        const math = makeFile(join(dir, 'unit'), 'math.tst.sh')
+        const io = makeFile(join(dir, 'unit'), 'io.tst.sh')
+        const api = makeFile(join(dir, 'integration'), 'api.tst.sh')
+        const store = makeFile(join(dir, 'integration', 'db'), 'store.tst.sh')
+        await writeFile(math.path, 'exit 0\n')
+        await writeFile(io.path, '# testme: suite slow\nexit 0\n')
+        await writeFile(api.path, 'exit 0\n')
+        await writeFile(store.path, 'exit 0\n')
+
+        teq((await TestSuites.get(io)).join(), 'unit,slow', 'Directive adds to the configured suite')
+        teq((await TestSuites.get(store)).join(), 'integration,slow,db', 'Nested config adds to inherited suites')
+
+        const tests = [math, io, api, store]
+        const suites = await TestSuites.collect(tests)
+        const counts = [...suites].map(([name, members]) => `${name}=${members.length}`).join()
+        teq(counts, 'db=1,integration=2,slow=3,unit=2', 'Suites gathered in name order with counts')
+
+        const names = (selected: TestFile[]) => selected.map((file) => file.name).join()
+        teq(names(TestSuites.select(tests, ['integration'], suites)), 'api.tst.sh,store.tst.sh', 'Select a suite')
+        const either = TestSuites.select(tests, ['db', 'unit'], suites)
+        teq(names(either), 'math.tst.sh,io.tst.sh,store.tst.sh', 'Tests of any named suite, in order')
+
+        let error = ''
+        try {
+            TestSuites.select(tests, ['nightly'], suites)
+        } catch (err) {
+            error = (err as Error).message
+        }
+        ttrue(error.includes('Unknown suite: nightly') && error.includes('unit'), 'Unknown suite names those defined')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
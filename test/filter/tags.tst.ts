@@ -0,0 +1,68 @@
+/*
+    Tag selection unit tests
+    Verifies the tags directive, --tags AND/OR expressions, --not-tags exclusion, option parsing and that tags are
+    written to the JSON report
+ */
+
+import {TestTags} from '../../src/tags.ts'
+import {Directives} from '../../src/directives.ts'
+import {CliParser} from '../../src/cli.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const parsed = Directives.parse('# testme: tags slow,network\n# testme: tags db slow\n')
+    teq(parsed.tags?.join(), 'slow,network,db', 'Tags from all tags directives')
+    teq(JSON.stringify(TestTags.parse('slow+network,db')), '[["slow","network"],["db"]]', 'Expression parsed')
+
+    const options = CliParser.parse(['--tags', 'slow+network', '--tags', 'db', '--not-tags', 'flaky,wip'])
+    teq(options.tags?.join(' '), 'slow+network db', '--tags is repeatable')
+    teq(options.notTags?.join(), 'flaky,wip', '--not-tags takes a comma-separated list')
+    for (const expression of ['slow,', 'a++b']) {
+        let invalid = false
+        try {
+            CliParser.parse(['--tags', expression])
+        } catch {
+            invalid = true
+        }
+        ttrue(invalid, `Empty tag in "${expression}" rejected`)
+    }
+
+    ttrue(TestTags.matches(['slow', 'network'], ['slow+network']), 'AND matches when all tags are present')
+    ttrue(!TestTags.matches(['slow'], ['slow+network']), 'AND fails when a tag is missing')
+    ttrue(TestTags.matches(['db'], ['slow+network,db']), 'OR matches any alternative')
+    ttrue(!TestTags.matches(['slow', 'db'], ['slow', 'network']), 'Every --tags expression must match')
+    ttrue(!TestTags.matches(['slow', 'flaky'], ['slow'], ['flaky']), '--not-tags excludes')
+    ttrue(TestTags.matches([], [], ['flaky']), 'Untagged test kept by --not-tags alone')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-tags-'))
+    try {
+        const slow = makeFile(dir, 'slow.tst.sh')
+        const plain = makeFile(dir, 'plain.tst.sh')
+        await writeFile(slow.path, '# testme: tags slow, network\nexit 0\n')
+        await writeFile(plain.path, 'exit 0\n')
+        const tests = [slow, plain]
+        await TestTags.mark(tests)
+        ttrue(slow.tags?.join() === 'slow,network' && plain.tags === undefined, 'Tags recorded on tests')
+        const selected = TestTags.select(tests, undefined, ['network'])
+        teq(selected.map((file) => file.name).join(), 'plain.tst.sh', 'Select by --not-tags')
+
+        const report = join(dir, 'results.json')
+        const reporter = new JsonReporter(report, dir)
+        reporter.testEnd({file: slow, status: TestStatus.Passed, duration: 1, output: ''})
+        reporter.testEnd({file: plain, status: TestStatus.Passed, duration: 1, output: ''})
+        reporter.runEnd([], 2)
+        const json = JSON.parse(await readFile(report, 'utf-8'))
+        teq(json.tests[0].tags.join(), 'slow,network', 'Tags in the JSON report')
+        ttrue(!('tags' in json.tests[1]), 'Untagged tests have no tags in the JSON report')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
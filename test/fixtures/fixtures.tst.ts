@@ -0,0 +1,67 @@
+/*
+    Directory setup and teardown unit tests
+    Verifies fixture scripts are found and excluded from tests, batching by directory and exported setup variables
+ */
+
+import {Fixtures} from '../../src/fixtures.ts'
+import {ServiceManager} from '../../src/services.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {existsSync} from 'node:fs'
+import {mkdir, mkdtemp, readFile, rm, writeFile} from 'node:fs/promises'
+import {tmpdir} from 'node:os'
+import {join} from 'path'
+
+function testFile(directory: string, name: string): TestFile {
+    return {name, path: join(directory, name), extension: '.sh', directory, type: TestType.Shell} as TestFile
+}
+
+async function test() {
+    ttrue(Fixtures.isFixture('/src/db/setup.tst.sh') && Fixtures.isFixture('teardown.tst.ps1'), 'Fixture scripts')
+    ttrue(!Fixtures.isFixture('setup.tst.c') && !Fixtures.isFixture('db-setup.tst.sh'), 'Other files are tests')
+    teq(Fixtures.fromConfig({}), undefined, 'No fixtures without setup or teardown keys')
+    const keys = Fixtures.fromConfig({configDir: '/src/db', setup: 'make db', teardown: ['', 'make clean']})
+    ttrue(keys?.setup.join() === 'make db' && keys?.teardown.join() === 'make clean', 'Keys become fixture commands')
+
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX fixture scripts - skipping the rest on Windows')
+        return
+    }
+    const root = await mkdtemp(join(tmpdir(), 'testme-fixtures-'))
+    try {
+        const db = join(root, 'db')
+        await mkdir(db)
+        await writeFile(join(db, 'setup.tst.sh'), 'echo "starting"\necho "DB_PORT=5433"\n')
+        await writeFile(join(db, 'teardown.tst.sh'), 'echo "$DB_PORT $TESTME_SUCCESS" > stopped\n')
+
+        teq(Fixtures.find(root), undefined, 'Directory without scripts has no fixtures')
+        const tests = [testFile(root, 'a.tst.sh'), testFile(db, 'b.tst.sh'), testFile(root, 'c.tst.sh')]
+        const batches = Fixtures.getBatches(tests)
+        teq(batches.length, 2, 'One batch per fixture directory plus the other tests')
+        ttrue(batches[0]!.fixtures === undefined && batches[0]!.tests.length === 2, 'Tests without fixtures together')
+        teq(batches[1]!.fixtures?.setup[0], join(db, 'setup.tst.sh'), 'Setup script found')
+
+        const services = new ServiceManager(root)
+        const config: TestConfig = {configDir: root}
+        const exported = await services.runDirectorySetup(config, batches[1]!.fixtures!)
+        ttrue(exported.DB_PORT === '5433' && Object.keys(exported).length === 1, 'Setup exports KEY=VALUE lines')
+        await services.runDirectoryTeardown(config, batches[1]!.fixtures!, exported, true)
+        const stopped = join(db, 'stopped')
+        ttrue(existsSync(stopped) && (await readFile(stopped, 'utf8')).trim() === '5433 1', 'Teardown sees exports')
+
+        await writeFile(join(db, 'setup.tst.sh'), 'echo "no database"\nexit 3\n')
+        let error = ''
+        try {
+            await services.runDirectorySetup(config, batches[1]!.fixtures!)
+        } catch (err) {
+            error = (err as Error).message
+        }
+        ttrue(error.includes('failed with exit code 3') && error.includes('no database'), 'Failed setup output')
+    } finally {
+        await rm(root, {recursive: true, force: true})
+    }
+}
+
+await run(test)
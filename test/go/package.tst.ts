@@ -0,0 +1,105 @@
+/*
+    Go package mode tests
+    Verifies that a _test.go file is run with `go test`, that its Test functions are mapped from -json output to
+    sub-results with their messages and locations, and that compiler.go.mode selects the mode
+ */
+
+import {GoTestHandler} from '../../src/handlers/go.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+const MATH_TEST = `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+\tif Add(1, 2) != 3 {
+\t\tt.Fatal("bad sum")
+\t}
+}
+
+func TestSub(t *testing.T) {
+\tt.Run("negative", func(t *testing.T) {
+\t\tif Sub(1, 2) != -2 {
+\t\t\tt.Errorf("got %d, expected -2", Sub(1, 2))
+\t\t}
+\t})
+}
+
+func TestNetwork(t *testing.T) {
+\tt.Skip("offline")
+}
+
+func helper(t *testing.T) {}
+
+func TestMain(m *testing.M) { m.Run() }
+`
+
+async function test() {
+    const names = GoTestHandler.getTestNames(MATH_TEST)
+    teq(names.join(), 'TestAdd,TestSub,TestNetwork', 'Test functions found, helpers and TestMain ignored')
+    teq(GoTestHandler.getTestNames('func Testify(t *testing.T) {}').length, 0, 'Testify is not a test')
+
+    const events = [
+        '{"Action":"run","Test":"TestSub"}',
+        '{"Action":"output","Test":"TestSub","Output":"=== RUN   TestSub\\n"}',
+        '{"Action":"output","Test":"TestSub/negative","Output":"    math_test.go:14: got -1, expected -2\\n"}',
+        '{"Action":"output","Test":"TestSub/negative","Output":"--- FAIL: TestSub/negative (0.00s)\\n"}',
+        '{"Action":"fail","Test":"TestSub/negative","Elapsed":0}',
+        '{"Action":"fail","Test":"TestSub","Elapsed":0.25}',
+        '{"Action":"pass","Test":"TestAdd","Elapsed":0}',
+        'not an event',
+        '{"Action":"output","Output":"FAIL\\n"}',
+        '{"Action":"fail","Elapsed":0.3}',
+    ].join('\n')
+    const {results, output} = GoTestHandler.parseTestEvents(events)
+    teq(results.map((sub) => `${sub.name}=${sub.status}`).join(), 'TestSub=fail,TestAdd=pass', 'Top-level tests')
+    const sub = results[0]
+    teq(sub?.message, 'math_test.go:14: got -1, expected -2', 'Subtest message belongs to its test')
+    ttrue(sub?.file === 'math_test.go' && sub.line === 14 && sub.duration === 250, 'Location and duration')
+    teq(results[1]?.message, undefined, 'Passed test has no message')
+    ttrue(output.includes('--- FAIL: TestSub/negative') && output.includes('not an event\n'), 'Output text')
+
+    const config: TestConfig = {execution: {timeout: 60}}
+    const dir = await mkdtemp(join(tmpdir(), 'testme-gopkg-'))
+    try {
+        const file = makeFile(dir, 'math_test.go')
+        ttrue(GoTestHandler.isPackageTest(file, config), 'A _test.go file runs in package mode')
+        ttrue(!GoTestHandler.isPackageTest(makeFile(dir, 'math.tst.go'), config), 'A .tst.go file runs with go run')
+        const forced: TestConfig = {compiler: {go: {mode: 'run'}}}
+        ttrue(!GoTestHandler.isPackageTest(file, forced), 'compiler.go.mode run overrides detection')
+
+        await writeFile(join(dir, 'go.mod'), 'module example.com/calc\n\ngo 1.21\n')
+        await writeFile(
+            join(dir, 'calc.go'),
+            'package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Sub(a, b int) int { return b - a }\n'
+        )
+        await writeFile(join(dir, 'math_test.go'), MATH_TEST)
+        const other = 'package calc\n\nimport "testing"\n\nfunc TestOther(t *testing.T) {}\n'
+        await writeFile(join(dir, 'other_test.go'), other)
+
+        const result = await new GoTestHandler().execute(file, config)
+        const statuses = (result.subResults || []).map((sub) => `${sub.name}=${sub.status}`).join()
+        teq(result.status, TestStatus.Failed, 'Failed Test function fails the file')
+        teq(statuses, 'TestAdd=pass,TestSub=fail,TestNetwork=skip', 'Only the Test functions of the file run')
+        teq(result.error, '1 of 3 tests failed: TestSub', 'Error names the failed tests')
+        ttrue(result.assertions?.passed === 1 && result.assertions.failed === 1, 'Tests counted as assertions')
+        ttrue(result.output.includes('got 1, expected -2') && !result.output.includes('"Action"'), 'Readable output')
+
+        const passed = await new GoTestHandler().execute(makeFile(dir, 'other_test.go'), config)
+        ttrue(passed.status === TestStatus.Passed && passed.subResults?.length === 1, 'Other file passes separately')
+
+        await writeFile(join(dir, 'empty_test.go'), 'package calc\n')
+        const empty = await new GoTestHandler().execute(makeFile(dir, 'empty_test.go'), config)
+        teq(empty.status, TestStatus.Skipped, 'File without Test functions is skipped')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,52 @@
+/*
+    Ejscript handler tests
+    Verifies that .tst.es tests run with compiler.es.interpreter, get TESTME_DEPTH and the timeout, fail on a non-zero
+    exit and are skipped when the interpreter is not installed. A stand-in interpreter script replaces ejs.
+ */
+
+import {EjscriptTestHandler} from '../../src/handlers/ejscript.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const dir = await mkdtemp(join(tmpdir(), 'testme-ejs-'))
+    try {
+        const handler = new EjscriptTestHandler()
+        const missing: TestConfig = {compiler: {es: {interpreter: join(dir, 'no-such-ejs')}}}
+        const skipped = await handler.execute(makeFile(dir, 'missing.tst.es'), missing)
+        teq(skipped.status, TestStatus.Skipped, 'Test skipped when the interpreter is not installed')
+        ttrue(skipped.output.includes('compiler.es.interpreter'), 'Skip reason names the setting')
+
+        if (process.platform === 'win32') {
+            console.log('Skipping interpreter checks on Windows')
+            return
+        }
+        // The stand-in runs the test file as a shell script
+        const ejs = join(dir, 'ejs')
+        await writeFile(ejs, '#!/bin/sh\nexec /bin/sh "$@"\n')
+        await chmod(ejs, 0o755)
+        await writeFile(join(dir, 'depth.tst.es'), 'echo "depth=$TESTME_DEPTH"\n')
+        await writeFile(join(dir, 'fail.tst.es'), 'exit 2\n')
+        await writeFile(join(dir, 'slow.tst.es'), 'sleep 10\n')
+
+        const config: TestConfig = {compiler: {es: {interpreter: ejs}}, execution: {timeout: 30, depth: 3}}
+        const passed = await handler.execute(makeFile(dir, 'depth.tst.es'), config)
+        teq(passed.status, TestStatus.Passed, 'Zero exit passes')
+        ttrue(passed.output.includes('depth=3'), 'TESTME_DEPTH exported')
+
+        const failed = await handler.execute(makeFile(dir, 'fail.tst.es'), config)
+        ttrue(failed.status === TestStatus.Failed && failed.exitCode === 2, 'Non-zero exit fails')
+
+        const slow = await handler.execute(makeFile(dir, 'slow.tst.es'), {...config, execution: {timeout: 1}})
+        teq(slow.status, TestStatus.Timeout, 'Timeout applies')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
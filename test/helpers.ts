@@ -0,0 +1,120 @@
+/*
+    Shared test helpers
+    Factories for the TestFile and TestResult records given to the units under test, a runner for the built tm
+    binary, console capture and the wrapper that runs the body of an async test
+ */
+
+import type {TestFile, TestResult, TestStatus} from '../src/types.ts'
+import {TestType} from '../src/types.ts'
+import {spawn} from 'bun'
+import {basename, dirname, extname, join} from 'path'
+
+// Path of the tm binary built by "make build"
+export const tmPath = join(import.meta.dir, '..', 'dist', 'tm')
+
+// Test types of the test file extensions
+const TYPES: Record<string, TestType> = {
+    '.tst.sh': TestType.Shell,
+    '.tst.ps1': TestType.PowerShell,
+    '.tst.bat': TestType.Batch,
+    '.tst.cmd': TestType.Batch,
+    '.tst.c': TestType.C,
+    '.tst.js': TestType.JavaScript,
+    '.tst.ts': TestType.TypeScript,
+    '.tst.es': TestType.Ejscript,
+    '.tst.py': TestType.Python,
+    '.tst.go': TestType.Go,
+    '.go': TestType.Go,
+    '.tst.rs': TestType.Rust,
+}
+
+/*
+    Creates the record of a test file, typed from its extension
+    @param dir Directory of the test
+    @param name File name of the test, e.g. math.tst.c
+    @param extra Fields to override, e.g. {configDir} or {type}
+    @returns Test file with its artifact directory at dir/.testme/name
+ */
+export function makeFile(dir: string, name: string, extra: Partial<TestFile> = {}): TestFile {
+    const extension = name.includes('.tst.') ? name.slice(name.indexOf('.tst.')) : extname(name)
+    return {
+        path: join(dir, name),
+        name,
+        extension,
+        type: TYPES[extension] ?? TestType.Custom,
+        directory: dir,
+        artifactDir: join(dir, '.testme', name),
+        ...extra,
+    }
+}
+
+/*
+    Creates the result of a test
+    @param file Test file, or the path of one
+    @param status Status of the result
+    @param extra Fields to override, e.g. {duration: 1500} or {stdout}
+    @returns Test result that took 1ms with no output unless overridden
+ */
+export function makeResult(file: TestFile | string, status: TestStatus, extra: Partial<TestResult> = {}): TestResult {
+    const testFile = typeof file === 'string' ? makeFile(dirname(file), basename(file)) : file
+    return {file: testFile, status, duration: 1, output: '', ...extra}
+}
+
+/*
+    Runs the built tm binary
+    @param args Command line arguments
+    @param cwd Directory to run in
+    @param env Variables to add to the environment
+    @returns Exit code and the captured stdout and stderr
+ */
+export async function runTm(
+    args: string[],
+    cwd: string,
+    env: Record<string, string> = {}
+): Promise<{exitCode: number; stdout: string; stderr: string}> {
+    const proc = spawn([tmPath, ...args], {cwd, env: {...process.env, ...env}, stdout: 'pipe', stderr: 'pipe'})
+    const [stdout, stderr] = await Promise.all([new Response(proc.stdout).text(), new Response(proc.stderr).text()])
+    return {exitCode: await proc.exited, stdout, stderr}
+}
+
+/*
+    Captures what a function prints with console.log
+    @param fn Function to run
+    @returns The printed lines joined with newlines
+ */
+export function capture(fn: () => void): string {
+    const log = console.log
+    const lines: string[] = []
+    console.log = (...args: unknown[]) => lines.push(args.join(' '))
+    try {
+        fn()
+    } finally {
+        console.log = log
+    }
+    return lines.join('\n')
+}
+
+/*
+    Tests if a function throws
+    @param fn Function to run
+    @returns True if the function threw
+ */
+export function throws(fn: () => unknown): boolean {
+    try {
+        fn()
+        return false
+    } catch {
+        return true
+    }
+}
+
+/*
+    Runs the body of a test and exits
+    A failed ttrue() or teq() exits with status 1 and an error thrown by the body fails the test. The explicit exit
+    stops timers or servers left by the code under test from keeping the test running.
+    @param test Body of the test
+ */
+export async function run(test: () => Promise<void>): Promise<void> {
+    await test()
+    process.exit(0)
+}
@@ -0,0 +1,54 @@
+/*
+    --init unit tests
+    Verifies language detection, the generated sections and that existing configs are only replaced with --force
+ */
+
+import {ConfigTemplate} from '../../src/init.ts'
+import {ConfigSchema} from '../../src/schema.ts'
+import {TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+import JSON5 from 'json5'
+import {mkdir, mkdtemp, readFile, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-init-')))
+    try {
+        await mkdir(join(rootDir, 'src', 'node_modules'), {recursive: true})
+        await writeFile(join(rootDir, 'src', 'math.tst.c'), 'int main() { return 0; }\n')
+        await writeFile(join(rootDir, 'api.tst.go'), 'package main\n')
+        await writeFile(join(rootDir, 'src', 'node_modules', 'dep.tst.py'), '')
+
+        const types = await ConfigTemplate.detect(rootDir)
+        ttrue(types.has(TestType.C) && types.has(TestType.Go), 'Test languages detected')
+        ttrue(!types.has(TestType.Python), 'node_modules not scanned')
+
+        const config = JSON5.parse(ConfigTemplate.render(types))
+        teq(ConfigSchema.validate(config).length, 0, 'Generated configuration is valid')
+        ttrue(!!config.compiler.c.gcc && config.debug.go === 'delve', 'C and Go sections included')
+        ttrue(!config.compiler.python && !config.compiler.es, 'Undetected languages omitted')
+        teq(config.patterns.include.join(), '**/*.tst.c,**/*.tst.go', 'Include patterns for detected types')
+
+        const empty = JSON5.parse(ConfigTemplate.render(new Set()))
+        teq(ConfigSchema.validate(empty).length, 0, 'Default configuration is valid')
+        ttrue(empty.patterns.include.includes('**/*.tst.sh') && !!empty.compiler.c, 'Default configuration is general')
+
+        if (process.platform === 'win32') {
+            console.log('--init run test not supported on Windows - skipping')
+            return
+        }
+        const configPath = join(rootDir, 'testme.json5')
+        teq((await runTm(['--init'], rootDir)).exitCode, 0, '--init creates testme.json5')
+        await writeFile(configPath, '{}\n')
+        teq((await runTm(['--init'], rootDir)).exitCode, 1, '--init refuses to overwrite')
+        teq(await readFile(configPath, 'utf-8'), '{}\n', 'Existing config kept')
+        teq((await runTm(['--init', '--force'], rootDir)).exitCode, 0, '--init --force succeeds')
+        ttrue((await readFile(configPath, 'utf-8')).includes('delve'), '--force overwrites the config')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,45 @@
+/*
+    Test listing without running
+    Verifies --list prints one path per line after filters and depth, and --list-json adds language and timeout
+ */
+
+import {mkdir, mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+
+async function test() {
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-list-')))
+    try {
+        await writeFile(join(rootDir, 'testme.json5'), "{execution: {timeout: 45, timeouts: {'slow.tst.sh': 120}}}\n")
+        await writeFile(join(rootDir, 'fast.tst.sh'), 'exit 1\n')
+        await writeFile(join(rootDir, 'slow.tst.sh'), 'exit 1\n')
+        await mkdir(join(rootDir, 'deep'))
+        await writeFile(join(rootDir, 'deep', 'testme.json5'), '{depth: 5}\n')
+        await writeFile(join(rootDir, 'deep', 'stress.tst.sh'), 'exit 1\n')
+
+        const list = await runTm(['--list'], rootDir)
+        const lines = list.stdout.trim().split('\n')
+        teq(list.exitCode, 0, 'List exits without running the failing tests')
+        ttrue(lines.length === 2 && lines.includes('fast.tst.sh') && lines.includes('slow.tst.sh'), 'One path per line')
+        ttrue(!list.stdout.includes('stress'), 'Tests below the required depth are not listed')
+
+        const deep = await runTm(['--list', '--depth', '5', '--filter', 'stress'], rootDir)
+        teq(deep.stdout.trim(), join('deep', 'stress.tst.sh'), 'Depth and filter applied')
+
+        const json = JSON.parse((await runTm(['--list-json', '--exclude', 'fast'], rootDir)).stdout)
+        ttrue(json.length === 1 && json[0].path === 'slow.tst.sh', 'JSON lists the same set')
+        ttrue(json[0].language === 'shell' && json[0].timeout === 120, 'JSON includes language and per-test timeout')
+
+        const override = JSON.parse((await runTm(['--list-json', '--timeout', '10', 'fast'], rootDir)).stdout)
+        teq(override[0].timeout, 10, 'JSON timeout includes the --timeout override')
+
+        const none = await runTm(['--list-json', '--filter', 'nothing'], rootDir)
+        teq(none.stdout.trim(), '[]', 'Empty JSON array when nothing matches')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
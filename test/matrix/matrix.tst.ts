@@ -0,0 +1,58 @@
+/*
+    Environment matrix unit tests
+    Verifies cell expansion, --matrix pins and that tm runs the test set once per cell
+ */
+
+import {Matrix} from '../../src/matrix.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run, runTm, throws} from '../helpers.ts'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const matrix = {MODE: ['fast', 'safe'], LEVEL: [1, 2]}
+    const cells = Matrix.getCells(matrix)
+    teq(cells.length, 4, 'Cartesian product of values')
+    teq(cells[1]!.label, 'MODE=fast,LEVEL=2', 'Cell label lists variables in order')
+    teq(cells[1]!.env.LEVEL, '2', 'Values exported as strings')
+    teq(Matrix.getCells(matrix, {MODE: 'safe'}).length, 2, 'Pin restricts cells')
+    teq(Matrix.getCells(matrix, {MODE: 'safe', LEVEL: '1'})[0]!.label, 'MODE=safe,LEVEL=1', 'Single cell')
+    ttrue(throws(() => Matrix.getCells(matrix, {OTHER: 'x'})), 'Unknown variable rejected')
+    ttrue(throws(() => Matrix.getCells(matrix, {MODE: 'slow'})), 'Unknown value rejected')
+
+    const none = Matrix.getCells(undefined)
+    ttrue(none.length === 1 && none[0]!.label === '', 'No matrix is a single unlabeled cell')
+    const test = makeFile('/work', 'math.tst.c')
+    teq(Matrix.apply([test], none[0]!)[0], test, 'Unlabeled cell leaves tests unchanged')
+    const labeled = Matrix.apply([test], cells[0]!)[0]!
+    teq(Matrix.label(labeled, 'math.tst.c'), '[MODE=fast,LEVEL=1] math.tst.c', 'Matrix label prefix')
+    const env = Matrix.applyEnvironment({environment: {MODE: 'default', HOME_DIR: '/h'}}, cells[0]!).environment
+    ttrue(env?.MODE === 'fast' && env?.HOME_DIR === '/h', 'Cell variables override configured environment')
+    const both = {env: {OLD: '1', MODE: 'env'}, environment: {MODE: 'default'}}
+    const merged = Matrix.applyEnvironment(both, cells[0]!)
+    ttrue(merged.environment?.OLD === '1' && merged.environment.MODE === 'fast', 'Deprecated env merged beneath')
+    ttrue(merged.env?.OLD === '1' && merged.env.MODE === 'fast', 'Merged variables also set as env')
+
+    if (process.platform === 'win32') {
+        console.log('Matrix run test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-matrix-')))
+    try {
+        await writeFile(join(rootDir, 'testme.json5'), "{matrix: {MODE: ['fast', 'safe']}}\n")
+        await writeFile(join(rootDir, 'mode.tst.sh'), '#!/bin/sh\n[ "$MODE" = fast ]\n')
+
+        const all = await runTm([], rootDir)
+        teq(all.exitCode, 1, 'Failing cell fails the run')
+        ttrue(all.stdout.includes('[MODE=safe] mode.tst.sh'), 'Results prefixed with the cell')
+        ttrue(/PASS.*MODE=fast/.test(all.stdout) && /FAIL.*MODE=safe/.test(all.stdout), 'Summary per cell')
+
+        const pinned = await runTm(['--matrix', 'MODE=fast'], rootDir)
+        ttrue(pinned.exitCode === 0 && !pinned.stdout.includes('MODE=safe'), '--matrix runs only the pinned cell')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,36 @@
+/*
+    Failure limit tests
+    Verifies --max-failures stops starting tests once the limit is reached and reports how many were skipped
+ */
+
+import {mkdtemp, readFile, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Shell failure limit test not supported on Windows - skipping')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-max-failures-')))
+    try {
+        const ran = join(rootDir, 'ran')
+        for (const name of ['a', 'b', 'c', 'd', 'e']) {
+            await writeFile(join(rootDir, `${name}.tst.sh`), `echo ${name} >> ${ran}\nexit 1\n`)
+        }
+        const {exitCode, stdout} = await runTm(['--max-failures', '2', '--workers', '1'], rootDir)
+
+        ttrue(exitCode !== 0, 'Run fails')
+        teq((await readFile(ran, 'utf-8')).split('\n').filter((line) => line).length, 2, 'Run stops at the limit')
+        ttrue(
+            stdout.includes('⛔ Failure limit reached (--max-failures 2): 3 test(s) skipped'),
+            'Summary reports the tests not run'
+        )
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,59 @@
+/*
+    Node mode tests
+    Verifies that compiler.javascript.mode 'node' runs .tst.js files with node and its flags, passes the TESTME
+    environment, loads CommonJS and ES module tests whatever the package type and skips when node is missing
+ */
+
+import {JavaScriptTestHandler} from '../../src/handlers/javascript.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    teq(JavaScriptTestHandler.inferModuleType("import fs from 'fs'\n"), 'esm', 'Import statement is ESM')
+    teq(JavaScriptTestHandler.inferModuleType('export const x = 1\n'), 'esm', 'Export statement is ESM')
+    teq(JavaScriptTestHandler.inferModuleType("const fs = require('fs')\n"), 'cjs', 'require() is CommonJS')
+    teq(JavaScriptTestHandler.inferModuleType("await import('fs')\n"), undefined, 'Dynamic import is neither')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-node-'))
+    try {
+        const config: TestConfig = {
+            compiler: {javascript: {mode: 'node', flags: ['--no-warnings']}},
+            execution: {timeout: 30},
+        }
+        await writeFile(join(dir, 'helper.cjs'), "module.exports = 'helper'\n")
+        const cjs = "const helper = require('./helper.cjs')\nconsole.log(`cjs ${helper}`)\n"
+        await writeFile(join(dir, 'cjs.tst.js'), cjs)
+        await writeFile(join(dir, 'esm.tst.js'), "import {sep} from 'path'\nconsole.log(`esm ${typeof sep}`)\n")
+        await writeFile(join(dir, 'env.tst.js'), "console.log(`verbose=${process.env.TESTME_VERBOSE}`)\n")
+        await writeFile(join(dir, 'fail.tst.js'), 'process.exit(3)\n')
+
+        const handler = new JavaScriptTestHandler()
+        teq(JavaScriptTestHandler.getPackageType(dir), 'cjs', 'No package.json type is CommonJS')
+        let result = await handler.execute(makeFile(dir, 'esm.tst.js'), config)
+        ttrue(result.status === TestStatus.Passed && result.output.includes('esm string'), 'ESM test in CJS package')
+
+        await writeFile(join(dir, 'package.json'), '{"type": "module"}\n')
+        teq(JavaScriptTestHandler.getPackageType(dir), 'esm', 'Package type module is ESM')
+        result = await handler.execute(makeFile(dir, 'cjs.tst.js'), config)
+        ttrue(result.status === TestStatus.Passed && result.output.includes('cjs helper'), 'CJS test in ESM package')
+
+        result = await handler.execute(makeFile(dir, 'env.tst.js'), {...config, output: {verbose: true}})
+        ttrue(result.output.includes('verbose=1'), 'TESTME environment passed to node')
+
+        result = await handler.execute(makeFile(dir, 'fail.tst.js'), config)
+        ttrue(result.status === TestStatus.Failed && result.exitCode === 3, 'Non-zero exit fails the test')
+
+        const missing: TestConfig = {compiler: {javascript: {mode: 'node', node: join(dir, 'no-such-node')}}}
+        result = await handler.execute(makeFile(dir, 'esm.tst.js'), missing)
+        teq(result.status, TestStatus.Skipped, 'Test skipped when node is not installed')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,14 @@
+#!/usr/bin/env bun
+
+// Skip Node tests if Node.js is not installed
+try {
+    const proc = Bun.spawnSync(['node', '--version'])
+    if (proc.exitCode !== 0) {
+        console.log('Node.js not installed - skipping Node tests')
+        process.exit(1)
+    }
+} catch (e) {
+    console.log('Node.js not installed - skipping Node tests')
+    process.exit(1)
+}
+process.exit(0)
@@ -0,0 +1,73 @@
+/*
+    Prometheus metrics unit tests
+    Verifies the text-format metrics, the Pushgateway grouping key URL, --metrics-push and that push failures only warn
+ */
+
+import {RunMetrics} from '../../src/metrics.ts'
+import {CliParser} from '../../src/cli.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+
+async function test() {
+    const summary = {
+        results: [
+            makeResult('/work/test/math.tst.c', TestStatus.Passed, {duration: 1500}),
+            makeResult('/work/test/str.tst.c', TestStatus.Failed, {duration: 500}),
+            makeResult('/work/test/cli.tst.sh', TestStatus.Timeout, {duration: 3000}),
+            makeResult('/work/test/web.tst.sh', TestStatus.Skipped, {duration: 0}),
+        ],
+        rootDir: '/work/test',
+        elapsed: 4250,
+    }
+    const lines = RunMetrics.render(summary).split('\n')
+    ttrue(lines.includes('# TYPE testme_tests_total gauge') && lines.includes('testme_tests_total 4'), 'Total')
+    ttrue(lines.includes('testme_tests_passed 1') && lines.includes('testme_tests_failed 2'), 'Passed and failed')
+    ttrue(lines.includes('testme_tests_skipped 1'), 'Skipped')
+    ttrue(lines.includes('testme_tests_category{category="timeout"} 1'), 'Result categories')
+    ttrue(lines.includes('testme_tests_category{category="crash"} 0'), 'Empty categories reported')
+    ttrue(lines.includes('testme_run_duration_seconds 4.25') && lines.includes('testme_run_success 0'), 'Run')
+    ttrue(lines.includes('testme_language_tests_total{language="c"} 2'), 'Per-language totals')
+    ttrue(lines.includes('testme_language_tests_failed{language="shell"} 1'), 'Per-language failures')
+    ttrue(lines.includes('testme_language_duration_seconds{language="c"} 2'), 'Per-language duration')
+    const passing = RunMetrics.render({...summary, results: summary.results.slice(0, 1)})
+    ttrue(passing.includes('testme_run_success 1\n'), 'Successful run')
+    ttrue(RunMetrics.render({...summary, results: [], interrupted: true}).includes('testme_run_success 0\n'), 'Stopped')
+
+    teq(
+        RunMetrics.getUrl('http://gw:9091/', 'ci', 'host 1'),
+        'http://gw:9091/metrics/job/ci/instance/host%201',
+        'Grouping key URL'
+    )
+    ttrue(RunMetrics.getUrl('http://gw', 'a/b', '').endsWith('/job@base64/YS9i/instance@base64/='), 'Base64 labels')
+    teq(CliParser.parse(['--metrics-push', 'http://gw:9091']).metricsPush, 'http://gw:9091', '--metrics-push')
+
+    const pushes: {method: string; path: string; body: string}[] = []
+    const server = Bun.serve({
+        port: 0,
+        async fetch(request) {
+            const path = new URL(request.url).pathname
+            pushes.push({method: request.method, path, body: await request.text()})
+            const rejected = path.includes('/job/bad/')
+            return new Response(rejected ? 'invalid metric' : '', {status: rejected ? 400 : 200})
+        },
+    })
+    const warnings: string[] = []
+    const warn = console.warn
+    console.warn = (message: string) => warnings.push(message)
+    try {
+        const url = `http://localhost:${server.port}`
+        ttrue(!(await RunMetrics.push({}, summary)), 'Nothing pushed without a URL')
+        ttrue(await RunMetrics.push({push: url, job: 'nightly', instance: 'ci-1'}, summary), 'Pushed')
+        ttrue(pushes[0]!.method === 'PUT' && pushes[0]!.path === '/metrics/job/nightly/instance/ci-1', 'Grouping key')
+        ttrue(pushes[0]!.body.includes('testme_tests_failed 2'), 'Metrics body')
+        ttrue(!(await RunMetrics.push({push: url, job: 'bad'}, summary)), 'Rejected push')
+        ttrue(!(await RunMetrics.push({push: 'http://127.0.0.1:1'}, summary)), 'Unreachable Pushgateway')
+    } finally {
+        console.warn = warn
+        server.stop(true)
+    }
+    ttrue(warnings.length === 2 && warnings[0]!.includes('HTTP 400: invalid metric'), 'Push failures warn')
+}
+
+await run(test)
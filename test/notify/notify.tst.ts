@@ -0,0 +1,74 @@
+/*
+    Run notification unit tests
+    Verifies the webhook payload, the Slack format, onlyOnFailure, and that delivery failures only warn
+ */
+
+import {Notifier} from '../../src/notify.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {hostname} from 'os'
+
+async function test() {
+    const received: {body: any; auth: string | null}[] = []
+    const server = Bun.serve({
+        port: 0,
+        async fetch(request) {
+            received.push({body: await request.json(), auth: request.headers.get('authorization')})
+            return new Response('', {status: new URL(request.url).pathname === '/broken' ? 500 : 200})
+        },
+    })
+    const url = `http://localhost:${server.port}`
+    try {
+        const ok = makeResult('/work/test/ok.tst.c', TestStatus.Passed)
+        const passing = {results: [ok], rootDir: '/work/test', elapsed: 1500}
+        const failing = {
+            results: [
+                ok,
+                makeResult('/work/test/math.tst.c', TestStatus.Failed, {error: 'Exit code 1\nmore'}),
+                makeResult('/work/test/slow.tst.c', TestStatus.Timeout),
+                makeResult('/work/test/skip.tst.c', TestStatus.Skipped),
+            ],
+            rootDir: '/work/test',
+            elapsed: 2500,
+        }
+
+        ttrue(!(await Notifier.send(undefined, passing)), 'Nothing sent without a webhook')
+        ttrue(!(await Notifier.send({webhook: url, onlyOnFailure: true}, passing)), 'onlyOnFailure skips a pass')
+        teq(received.length, 0, 'No requests made')
+
+        const headers = {Authorization: 'Bearer secret'}
+        ttrue(await Notifier.send({webhook: url, onlyOnFailure: true, headers}, failing), 'Failure delivered')
+        const payload = received[0]!.body
+        ttrue(payload.status === 'failed' && payload.host === hostname() && payload.duration === 2500, 'Run status')
+        ttrue(payload.total === 4 && payload.categories.fail === 1 && payload.categories.timeout === 1, 'Totals')
+        ttrue(payload.failures.length === 2 && payload.failures[0].test === 'math.tst.c', 'Top failures')
+        ttrue(payload.failures[0].error === 'Exit code 1\nmore' && !('error' in payload.failures[1]), 'Errors')
+        teq(received[0]!.auth, 'Bearer secret', 'Extra headers')
+
+        const interrupted = {...passing, interrupted: true}
+        await Notifier.send({webhook: url, onlyOnFailure: true, format: 'slack'}, interrupted)
+        teq(received.length, 2, 'An interrupted run is not a pass')
+        const lines = received[1]!.body.text.split('\n')
+        teq(lines[0], `:x: *TestMe run interrupted* on \`${hostname()}\` in 1.5s`, 'Slack heading')
+        teq(lines[1], '1 test(s): 1 pass', 'Slack totals')
+        await Notifier.send({webhook: url, format: 'slack'}, failing)
+        ttrue(received[2]!.body.text.includes('• `math.tst.c` fail: Exit code 1'), 'Slack failure list')
+
+        const warnings: string[] = []
+        const warn = console.warn
+        console.warn = (message: string) => warnings.push(message)
+        try {
+            ttrue(!(await Notifier.send({webhook: `${url}/broken`}, failing)), 'HTTP error not delivered')
+            ttrue(!(await Notifier.send({webhook: 'http://127.0.0.1:1/hook/token'}, failing)), 'Unreachable')
+        } finally {
+            console.warn = warn
+        }
+        ttrue(warnings.length === 2 && warnings[0]!.includes('HTTP 500'), 'Delivery failures warn')
+        ttrue(warnings[1]!.includes('http://127.0.0.1:1:') && !warnings[1]!.includes('token'), 'Webhook path hidden')
+    } finally {
+        server.stop(true)
+    }
+}
+
+await run(test)
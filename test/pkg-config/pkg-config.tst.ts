@@ -0,0 +1,44 @@
+/*
+    compiler.pkgs unit tests
+    Verifies pkg-config flags are read using PKG_CONFIG_PATH and that missing packages are reported
+ */
+
+import {getPackageFlags} from '../../src/utils/pkg-config.ts'
+import {ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {which} from 'bun'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    if (process.platform === 'win32' || !which('pkg-config')) {
+        console.log('pkg-config not available - skipping')
+        return
+    }
+    const dir = await realpath(await mkdtemp(join(tmpdir(), 'testme-pkgs-')))
+    try {
+        await writeFile(
+            join(dir, 'testme-demo.pc'),
+            `prefix=${dir}\nName: testme-demo\nDescription: Demo\nVersion: 1.0\n` +
+                'Cflags: -I${prefix}/include -DDEMO=1\nLibs: -L${prefix}/lib -ldemo\n'
+        )
+        const env = {PKG_CONFIG_PATH: dir}
+        const flags = await getPackageFlags(['testme-demo'], false, env)
+        ttrue(flags.cflags.includes(`-I${dir}/include`) && flags.cflags.includes('-DDEMO=1'), 'Compile flags read')
+        ttrue(flags.libs.includes(`-L${dir}/lib`) && flags.libs.includes('-ldemo'), 'Link flags read')
+
+        let message = ''
+        try {
+            await getPackageFlags(['testme-demo', 'testme-missing'], false, env)
+        } catch (err) {
+            message = (err as Error).message
+        }
+        ttrue(message.includes('not found: testme-missing'), 'Missing package named')
+        ttrue(message.includes(`PKG_CONFIG_PATH=${dir}`), 'Search path shown in the error')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
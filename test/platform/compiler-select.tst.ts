@@ -0,0 +1,53 @@
+/*
+    C compiler selection unit tests
+    Verifies --cc, compiler.cc, compiler.c.compiler, compiler.toolchain and $CC precedence and compiler type probing
+ */
+
+import {CompilerManager, CompilerType} from '../../src/platform/compiler.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const savedCC = process.env.CC
+    try {
+        delete process.env.CC
+        teq(CompilerManager.selectCompiler({}).source, 'auto-detected', 'Auto-detect without any setting')
+        const deferred: TestConfig = {compiler: {c: {compiler: 'default'}}}
+        teq(CompilerManager.selectCompiler(deferred).name, undefined, "'default' means auto-detect")
+
+        process.env.CC = 'cc-from-env'
+        const fromEnv = CompilerManager.selectCompiler(deferred)
+        ttrue(fromEnv.name === 'cc-from-env' && fromEnv.source === '$CC', '$CC used when config defers')
+
+        const config: TestConfig = {compiler: {cc: 'clang', c: {compiler: 'gcc'}}}
+        teq(CompilerManager.selectCompiler(config).source, 'compiler.cc', 'compiler.cc wins over c.compiler')
+        teq(CompilerManager.selectCompiler({compiler: {c: {compiler: 'gcc'}}}).name, 'gcc', 'c.compiler over $CC')
+        const toolchain = CompilerManager.selectCompiler({compiler: {toolchain: 'msvc'}})
+        ttrue(toolchain.name === 'msvc' && toolchain.source === 'compiler.toolchain', 'compiler.toolchain over $CC')
+        const named = CompilerManager.selectCompiler({compiler: {toolchain: 'msvc', c: {compiler: 'gcc'}}})
+        teq(named.name, 'gcc', 'c.compiler over compiler.toolchain')
+
+        const platform = PlatformDetector.isWindows() ? 'windows' : PlatformDetector.isMacOS() ? 'macosx' : 'linux'
+        const mapped = CompilerManager.selectCompiler({compiler: {cc: {[platform]: 'mapped-cc'}}})
+        teq(mapped.name, 'mapped-cc', 'Platform map resolved')
+
+        const cli = CompilerManager.selectCompiler({...config, execution: {timeout: 30, parallel: true, cc: 'tcc'}})
+        ttrue(cli.name === 'tcc' && cli.source === '--cc', '--cc wins over config and $CC')
+    } finally {
+        if (savedCC === undefined) {
+            delete process.env.CC
+        } else {
+            process.env.CC = savedCC
+        }
+    }
+
+    // A generic compiler name is identified from its --version output
+    if (!PlatformDetector.isWindows() && (await PlatformDetector.findInPath('cc'))) {
+        const type = (await CompilerManager.getDefaultCompilerConfig('cc')).type
+        ttrue(type === CompilerType.GCC || type === CompilerType.Clang, `cc probed as ${type}`)
+    }
+}
+
+await run(test)
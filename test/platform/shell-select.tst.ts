@@ -0,0 +1,65 @@
+/*
+    Shell interpreter selection tests
+    Verifies shell tests run under --shell, then their shebang, then shell.interpreter (default /bin/sh), that
+    shell.flags are passed to the interpreter, that verbose output names the interpreter and that PowerShell tests are
+    skipped when PowerShell is not installed
+ */
+
+import {ShellDetector} from '../../src/platform/shell.ts'
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    teq(CliParser.parse(['--shell', 'bash']).shell, 'bash', '--shell')
+
+    const ps1 = join(tmpdir(), 'power.tst.ps1')
+    const psArgs = ShellDetector.getShellArgs(ShellDetector.getShellTypeFromExtension(ps1), ps1)
+    ttrue(psArgs.includes('-NonInteractive') && psArgs.slice(-2).join(' ') === `-File ${ps1}`, 'PowerShell arguments')
+    const missing = join(tmpdir(), 'no-such-dir', 'pwsh')
+    teq(await ShellDetector.selectPowerShell(missing), null, 'Missing PowerShell not selected')
+    const power = makeFile(tmpdir(), 'power.tst.ps1')
+    const skipped = await new ShellTestHandler().execute(power, {shell: {powershell: missing}})
+    ttrue(skipped.status === TestStatus.Skipped && skipped.output.includes('PowerShell not found'), 'PowerShell skip')
+
+    if (process.platform === 'win32') {
+        console.log('Shell tests run under Git Bash on Windows - skipping the rest')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-shell-'))
+    try {
+        const plain = join(dir, 'plain.tst.sh')
+        const env = join(dir, 'env.tst.sh')
+        const path = join(dir, 'path.tst.sh')
+        await writeFile(plain, 'echo "$UNSET_TESTME_VAR"\n')
+        await writeFile(env, '#!/usr/bin/env -S bash -e\nexit 0\n')
+        await writeFile(path, '#!/bin/dash\nexit 0\n')
+
+        teq(await ShellDetector.selectInterpreter(plain), '/bin/sh', 'Default interpreter is /bin/sh')
+        teq(await ShellDetector.selectInterpreter(plain, 'bash'), 'bash', 'Configured interpreter')
+        teq(await ShellDetector.selectInterpreter(env, 'zsh'), 'bash', 'Shebang run by env wins over config')
+        teq(await ShellDetector.selectInterpreter(path), '/bin/dash', 'Shebang interpreter path')
+        teq(await ShellDetector.selectInterpreter(path, 'zsh', 'sh'), 'sh', '--shell wins over the shebang')
+
+        const file = makeFile(dir, 'plain.tst.sh')
+        const execution = {timeout: 30, parallel: false}
+        const handler = new ShellTestHandler()
+        const loose = await handler.execute(file, {execution, output: {verbose: true}} as TestConfig)
+        teq(loose.status, TestStatus.Passed, 'Unset variable allowed without flags')
+        ttrue(loose.output.startsWith('Interpreter: /bin/sh'), 'Verbose output names the interpreter')
+
+        const strict = await handler.execute(file, {execution, shell: {flags: ['-u']}})
+        teq(strict.status, TestStatus.Failed, 'shell.flags passed to the interpreter')
+        ttrue(!strict.output.includes('Interpreter:'), 'Interpreter only reported in verbose mode')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -33,10 +33,12 @@ if (process.env.TESTME_VERBOSE !== undefined) {
     console.log(`✓ TESTME_VERBOSE = ${process.env.TESTME_VERBOSE}`)
 }
 
-// Test TESTME_DEPTH (may or may not be set depending on --depth flag)
-if (process.env.TESTME_DEPTH !== undefined) {
-    console.log(`✓ TESTME_DEPTH = ${process.env.TESTME_DEPTH}`)
+// Test TESTME_DEPTH (always set, 0 without --depth)
+if (!/^\d+$/.test(process.env.TESTME_DEPTH || '')) {
+    console.error(`✗ TESTME_DEPTH should be a number, got: ${process.env.TESTME_DEPTH}`)
+    process.exit(1)
 }
+console.log(`✓ TESTME_DEPTH = ${process.env.TESTME_DEPTH}`)
 
 // Validate some expected patterns
 if (process.env.TESTME_PLATFORM && !process.env.TESTME_PLATFORM.includes('-')) {
@@ -0,0 +1,72 @@
+/*
+    Python handler tests
+    Verifies that compiler.python.venv activates the virtualenv and selects its interpreter, that
+    compiler.python.interpreter is used as given, that compiler.python.args come before the test file and test
+    arguments after it, and that a non-zero exit fails the test. Stand-in interpreter scripts show the command line.
+ */
+
+import {PythonTestHandler} from '../../src/handlers/python.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdir, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+// Stand-in interpreter that prints its name, the activated environment and its arguments
+async function standIn(path: string, name: string): Promise<void> {
+    const script = `#!/bin/sh\necho "${name} venv=$VIRTUAL_ENV path=\${PATH%%:*} home=[$PYTHONHOME] args=$*"\n`
+    await writeFile(path, script)
+    await chmod(path, 0o755)
+}
+
+async function test() {
+    const dir = await mkdtemp(join(tmpdir(), 'testme-python-'))
+    try {
+        const handler = new PythonTestHandler()
+        const file = makeFile(dir, 'check.tst.py')
+        await writeFile(file.path, 'import sys\nprint(f"dev={sys.flags.dev_mode}")\nsys.exit(int(sys.argv[1]))\n')
+
+        let result = await handler.execute(file, {compiler: {python: {args: ['-X', 'dev']}}, execution: {args: ['0']}})
+        teq(result.status, TestStatus.Passed, 'Zero exit passes')
+        ttrue(result.output.includes('dev=True'), 'compiler.python.args passed to the interpreter')
+        result = await handler.execute(file, {execution: {args: ['3']}})
+        ttrue(result.status === TestStatus.Failed && result.exitCode === 3, 'Non-zero exit fails the test')
+        ttrue(result.output.includes('dev=False'), 'No interpreter flags by default')
+
+        if (process.platform === 'win32') {
+            console.log('Stand-in interpreters are shell scripts - skipping on Windows')
+            return
+        }
+        const venv = join(dir, 'venv')
+        await mkdir(join(venv, 'bin'), {recursive: true})
+        await standIn(join(venv, 'bin', 'python3'), 'venv-python3')
+        await standIn(join(dir, 'custom-python'), 'custom')
+
+        const config: TestConfig = {
+            configDir: dir,
+            compiler: {python: {venv: 'venv', args: ['-X', 'dev']}},
+            execution: {args: ['--fast']},
+        }
+        result = await handler.execute(file, config)
+        const activated = `venv=${venv} path=${join(venv, 'bin')} home=[]`
+        ttrue(result.output.includes(`venv-python3 ${activated}`), 'Venv activated and its python3 selected')
+        ttrue(result.output.includes(`args=-X dev ${file.path} --fast`), 'Interpreter flags, test file, test arguments')
+
+        config.compiler!.python!.interpreter = 'python3'
+        result = await handler.execute(file, config)
+        ttrue(result.output.includes('venv-python3'), 'Bare interpreter name resolved from the venv first')
+
+        config.compiler!.python!.interpreter = join(dir, 'custom-python')
+        result = await handler.execute(file, config)
+        ttrue(result.output.includes(`custom ${activated}`), 'Interpreter path used as given with the venv active')
+
+        result = await handler.execute(file, {compiler: {python: {interpreter: join(dir, 'custom-python')}}})
+        ttrue(result.output.includes('custom') && !result.output.includes(venv), 'No virtualenv unless configured')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,76 @@
+/*
+    Quarantine unit tests
+    Verifies the quarantine file is read, quarantined failures do not fail the run and are reported separately,
+    and passing quarantined tests are listed as removal candidates
+ */
+
+import {Quarantine} from '../../src/quarantine.ts'
+import {TestRunner} from '../../src/runner.ts'
+import {TestReporter} from '../../src/reporter.ts'
+import {CliParser} from '../../src/cli.ts'
+import type {TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-quarantine-'))
+    try {
+        const unit = join(rootDir, 'unit')
+        const slow = join(rootDir, 'slow')
+        const tests = [
+            makeFile(unit, 'math.tst.sh'),
+            makeFile(unit, 'net.tst.sh'),
+            makeFile(unit, 'fixed.tst.sh'),
+            makeFile(slow, 'load.tst.sh'),
+            makeFile(join(rootDir, 'slowest'), 'big.tst.sh'),
+        ]
+        teq(await Quarantine.mark(tests, {}, rootDir), 0, 'No quarantine file')
+
+        const list = '# Flaky on CI\nunit/net.tst.sh\n\n  unit/fixed.tst.sh  \nslow\n'
+        await writeFile(join(rootDir, 'testme.quarantine'), list)
+        teq(await Quarantine.mark(tests, {configDir: rootDir}, rootDir), 3, 'Listed tests quarantined')
+        ttrue(!tests[0]!.quarantined && tests[1]!.quarantined === true, 'Test paths')
+        ttrue(tests[3]!.quarantined === true && !tests[4]!.quarantined, 'Directories, not name prefixes')
+
+        const results: TestResult[] = [
+            {file: tests[0]!, status: TestStatus.Passed, duration: 1, output: ''},
+            {file: tests[1]!, status: TestStatus.Failed, duration: 1, output: '', error: 'Exit code 1'},
+            {file: tests[2]!, status: TestStatus.Passed, duration: 1, output: ''},
+            {file: {...tests[3]!, matrix: 'MODE=fast'}, status: TestStatus.Passed, duration: 1, output: ''},
+            {file: {...tests[3]!, matrix: 'MODE=safe'}, status: TestStatus.Timeout, duration: 1, output: ''},
+        ]
+        const runner = new TestRunner()
+        teq(runner.getExitCode(results), 0, 'Quarantined failures do not fail the run')
+        const failing = {file: tests[0]!, status: TestStatus.Failed, duration: 1, output: ''}
+        teq(runner.getExitCode([...results, failing]), 1, 'Other failures still fail the run')
+        const passing = Quarantine.getPassing(results)
+        ttrue(passing.length === 1 && passing[0] === tests[2]!.path, 'Only tests passing in every cell are candidates')
+
+        const lines: string[] = []
+        const log = console.log
+        console.log = (...args: unknown[]) => lines.push(args.join(' '))
+        try {
+            new TestReporter({output: {colors: false, quiet: true}}, rootDir).reportSummary(results)
+        } finally {
+            console.log = log
+        }
+        const summary = lines.join('\n')
+        ttrue(summary.includes('Quarantined failures: 2 (do not fail the run)'), 'Quarantined failures listed')
+        ttrue(summary.includes('  unit/net.tst.sh (failed)') && summary.includes('(timeout)'), 'Failing tests named')
+        ttrue(summary.includes('Quarantined passes: 1') && summary.includes('  unit/fixed.tst.sh'), 'Candidates')
+        ttrue(summary.includes('Result: PASSED'), 'Result follows the exit code')
+
+        await writeFile(join(rootDir, 'flaky.txt'), 'unit/math.tst.sh\n')
+        const others = [makeFile(unit, 'math.tst.sh')]
+        teq(await Quarantine.mark(others, {configDir: rootDir, quarantine: 'flaky.txt'}, rootDir), 1, 'Key')
+        teq(CliParser.parse(['--no-quarantine']).noQuarantine, true, '--no-quarantine')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,36 @@
+/*
+    Remote execution unit tests
+    Verifies host selection, staging directories and the remote command line with environment and timeout
+ */
+
+import {Remote, REMOTE_DIR} from '../../src/remote.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    teq(Remote.getHost({}), undefined, 'Tests run locally by default')
+    const config: TestConfig = {remote: {host: 'root@board', dir: '/data/testme/', options: ['-o', 'Port=2222']}}
+    teq(Remote.getHost(config), 'root@board', 'remote.host used')
+    const cli: TestConfig = {...config, execution: {timeout: 30, parallel: true, remote: 'pi@other'}}
+    teq(Remote.getHost(cli), 'pi@other', '--remote overrides remote.host')
+    teq(Remote.getOptions(config).join(' '), '-o BatchMode=yes -o Port=2222', 'Batch mode and extra options')
+
+    const file = {name: 'math.tst.c', path: '/src/math.tst.c', type: TestType.C} as TestFile
+    const stage = Remote.getStageDir(config, file)
+    ttrue(/^\/data\/testme\/math\.tst\.c-[0-9a-f]{8}$/.test(stage), `Staging directory under remote.dir: ${stage}`)
+    ttrue(stage !== Remote.getStageDir(config, file), 'Staging directories are unique')
+    ttrue(Remote.getStageDir({}, file).startsWith(`${REMOTE_DIR}/`), 'Default staging directory')
+
+    const command = Remote.getCommand('/tmp/t 1', 'math', ['--name', "it's"], {MODE: 'fast', N: 2}, 30000)
+    teq(
+        command,
+        `cd '/tmp/t 1' && MODE=fast N=2 timeout -k 5 30 ./math --name 'it'\\''s'`,
+        'Command quotes arguments, exports env and applies the timeout'
+    )
+    teq(Remote.getCommand('/tmp/t', 'math', [], {}), 'cd /tmp/t && ./math', 'No timeout wrapper without timeout')
+    ttrue(Remote.isTimeout(124) && !Remote.isTimeout(1), 'timeout(1) exit status recognized')
+}
+
+await run(test)
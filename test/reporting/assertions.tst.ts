@@ -0,0 +1,104 @@
+/*
+    Soft assertion tests
+    Verifies that PASS and FAIL lines from testme.h soft assertions are counted, that a FAIL line fails a C test
+    that includes testme.h and exits zero but not other tests, that --emit-header prints the bundled C header, and
+    that parse.assertions counts configured markers and totals them in the JSON report
+ */
+
+import {countAssertions, countChecks, countMarkers, parseAssertions} from '../../src/utils/assertion-counter.ts'
+import {HEADER_LANGUAGES, TestHeaders} from '../../src/headers.ts'
+import {CliParser} from '../../src/cli.ts'
+import {CTestHandler} from '../../src/handlers/c.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const output = 'PASS math.tst.c:4 1 + 1 == 2\r\nFAIL math.tst.c:5 sum == 3: expected 3, received 4\nPASS: done\n'
+    teq(JSON.stringify(countChecks(output)), '{"passed":1,"failed":1}', 'PASS and FAIL lines counted')
+    teq(countChecks('PASS\nok\n'), null, 'Lines without a location are not assertions')
+    const mixed = countAssertions(`✓ ready\n${output}`)
+    ttrue(mixed?.passed === 2 && mixed.failed === 1, 'Soft assertions added to ✓ and ✗ counts')
+
+    const go = 'PASS: add\n  FAIL: sub got 1\nPASS: mul\nPASSED later\nsummary ✓\nPASS io.tst.c:3 open\n'
+    teq(JSON.stringify(countMarkers(go)), '{"passed":3,"failed":1}', 'Default markers at line start')
+    const custom = countMarkers('ok 1\nnot ok 2\nok 3\n', ['ok '], ['not ok '])
+    ttrue(custom?.passed === 2 && custom.failed === 1, 'Configured markers')
+    teq(countMarkers('nothing here\n'), null, 'No markers')
+
+    const markerFile = makeFile(tmpdir(), 'markers.tst.sh')
+    const failed = {file: markerFile, status: TestStatus.Failed, duration: 1, output: go, stdout: go, exitCode: 1}
+    teq(parseAssertions(failed, {}), failed, 'Marker parsing is opt-in')
+    const parsed = parseAssertions(failed, {parse: {assertions: true}})
+    ttrue(parsed.assertions?.passed === 3 && parsed.status === TestStatus.Failed, 'Counted without changing status')
+
+    const header = TestHeaders.get('c')
+    const source = await readFile(join(import.meta.dir, '../../src/modules/c/testme.h'), 'utf-8')
+    teq(header, source, 'Bundled header matches src/modules/c/testme.h')
+    ttrue(['tcheck', 'teq_int', 'teq_str', 'treport'].every((name) => header.includes(`${name}(`)), 'Soft macros')
+    const legacy = header.slice(header.indexOf('#define tassert(')).split('} else')[0]!
+    ttrue(legacy.includes('tReportString(') && !legacy.includes('tCheck('), 'tassert remains a fatal assertion')
+    teq(CliParser.parse(['--emit-header', 'c']).emitHeader, 'c', '--emit-header parsed')
+    let unsupported = ''
+    try {
+        TestHeaders.get('cobol')
+    } catch (err) {
+        unsupported = (err as Error).message
+    }
+    ttrue(unsupported.includes(HEADER_LANGUAGES.join(', ')), 'Unsupported language lists those available')
+
+    if (process.platform === 'win32') {
+        console.log('Shell soft assertions not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-assert-'))
+    try {
+        const good = makeFile(dir, 'good.tst.sh')
+        const bad = makeFile(dir, 'bad.tst.sh')
+        await writeFile(good.path, 'echo "PASS good.tst.sh:1 one"\necho "PASS good.tst.sh:2 two"\n')
+        await writeFile(bad.path, 'echo "PASS bad.tst.sh:1 one"\necho "FAIL bad.tst.sh:2 two"\nexit 0\n')
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: false},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        const results = await new TestRunner().executeTestsWithConfig([good, bad], config)
+        const result = (name: string) => results.find((item) => item.file.name === name)!
+        teq(result('good.tst.sh').status, TestStatus.Passed, 'Test with only PASS lines passes')
+        teq(result('good.tst.sh').assertions?.passed, 2, 'Assertions counted on the result')
+        teq(result('bad.tst.sh').status, TestStatus.Passed, 'FAIL line does not fail a shell test')
+        teq(result('bad.tst.sh').assertions?.failed, 1, 'FAIL line of a shell test still counted')
+
+        const reporter = new JsonReporter(join(dir, 'results.json'), dir)
+        reporter.testEnd(result('good.tst.sh'))
+        reporter.testEnd(result('bad.tst.sh'))
+        const json = reporter.render()
+        teq(JSON.stringify(json.summary.assertions), '{"passed":3,"failed":1}', 'Run totals in the JSON summary')
+        teq(json.tests[0]?.assertions?.passed, 2, 'Per-test counts in the JSON report')
+
+        const soft = makeFile(dir, 'soft.tst.c')
+        const plain = makeFile(dir, 'plain.tst.c')
+        await writeFile(join(dir, 'testme.h'), header)
+        await writeFile(soft.path, '#include "testme.h"\nint main() { tcheck(1 == 1); tcheck(1 == 2); return 0; }\n')
+        await writeFile(plain.path, '#include <stdio.h>\nint main() { printf("FAIL plain.tst.c:2 x\\n"); return 0; }\n')
+        ttrue(await CTestHandler.includesHeader(soft), 'Header include detected')
+        ttrue(!(await CTestHandler.includesHeader(plain)), 'Test without the header')
+        if (await PlatformDetector.findInPath('cc')) {
+            const cResults = await new TestRunner().executeTestsWithConfig([soft, plain], config)
+            const cResult = (name: string) => cResults.find((item) => item.file.name === name)!
+            teq(cResult('soft.tst.c').status, TestStatus.Failed, 'Failed tcheck fails a C test that exits zero')
+            teq(cResult('soft.tst.c').error?.includes('1 assertion(s) failed'), true, 'Failure names the count')
+            teq(cResult('plain.tst.c').status, TestStatus.Passed, 'FAIL line without testme.h does not fail')
+        }
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,65 @@
+/*
+    Buffered parallel output tests
+    Verifies that live output from tests running in parallel is printed as one block per test and not interleaved
+ */
+
+import {TestReporter} from '../../src/reporter.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {capture, makeFile, makeResult, run} from '../helpers.ts'
+
+async function test() {
+    const reporter = new TestReporter({output: {verbose: false, format: 'simple', colors: false}}, '/work')
+    const buffered = makeResult('/work/x.tst.sh', TestStatus.Passed, {output: 'x1\nx2\n'})
+    teq(capture(() => reporter.reportOutput(buffered)), '── x.tst.sh ──\nx1\nx2\n', 'Printed under a header')
+    const silent = makeResult('/work/y.tst.sh', TestStatus.Passed)
+    teq(capture(() => reporter.reportOutput(silent)), '', 'No block for a test without output')
+
+    if (process.platform === 'win32') {
+        console.log('Shell output test not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-buffered-'))
+    try {
+        // The two tests print alternately while both are running
+        await writeFile(join(dir, 'a.tst.sh'), 'for i in 1 2 3; do echo a$i; sleep 0.2; done\n')
+        await writeFile(join(dir, 'b.tst.sh'), 'sleep 0.1\nfor i in 1 2 3; do echo b$i; sleep 0.2; done\n')
+        const tests = [makeFile(dir, 'a.tst.sh'), makeFile(dir, 'b.tst.sh')]
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: true, workers: 2},
+            output: {verbose: false, format: 'simple', colors: false, live: true},
+        }
+
+        // Capture both console.log and streamed writes
+        const printed: string[] = []
+        const log = console.log
+        const write = process.stdout.write
+        console.log = (...args: unknown[]) => printed.push(args.join(' ') + '\n')
+        process.stdout.write = ((chunk: string | Uint8Array) => {
+            printed.push(typeof chunk === 'string' ? chunk : new TextDecoder().decode(chunk))
+            return true
+        }) as typeof process.stdout.write
+        let results
+        try {
+            results = await new TestRunner().executeTestsWithConfig(tests, config, dir)
+        } finally {
+            console.log = log
+            process.stdout.write = write
+        }
+        const output = printed.join('')
+
+        ttrue(results.every((result) => result.status === TestStatus.Passed), 'Both tests pass')
+        ttrue(output.includes('a1\na2\na3') && output.includes('b1\nb2\nb3'), 'Output of each test is not interleaved')
+        teq(output.match(/── .*\.tst\.sh ──/g)?.length, 2, 'One block per test')
+        ttrue(/── a\.tst\.sh ──\n[^─]*a1\na2\na3/.test(output), 'Block is headed by the test name')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,55 @@
+/*
+    Result category unit tests
+    Verifies results are sorted into pass, fail, crash, timeout, error and skip and counted in the JSON report
+ */
+
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {countCategories, getCategory} from '../../src/utils/categories.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const results = [
+        makeResult('/tmp/pass.tst.c', TestStatus.Passed),
+        makeResult('/tmp/xfail.tst.c', TestStatus.XFail),
+        makeResult('/tmp/assert.tst.c', TestStatus.Failed, {exitCode: 1}),
+        makeResult('/tmp/xpass.tst.c', TestStatus.XPass),
+        makeResult('/tmp/segv.tst.c', TestStatus.Failed, {signal: 'SIGSEGV'}),
+        makeResult('/tmp/asan.tst.c', TestStatus.Failed, {exitCode: 1, sanitizer: 'address'}),
+        makeResult('/tmp/killed.tst.c', TestStatus.Failed, {signal: 'SIGTERM'}),
+        makeResult('/tmp/slow.tst.c', TestStatus.Timeout),
+        makeResult('/tmp/silent.tst.c', TestStatus.IdleTimeout),
+        makeResult('/tmp/build.tst.c', TestStatus.Error),
+        makeResult('/tmp/skip.tst.c', TestStatus.Skipped),
+    ]
+    const [pass, xfail, assert, xpass, segv, asan, killed, slow, silent, build, skip] = results.map(getCategory)
+    ttrue(pass === 'pass' && xfail === 'pass', 'Passes and expected failures are passes')
+    ttrue(assert === 'fail' && xpass === 'fail', 'Non-zero exits and unexpected passes are failures')
+    ttrue(segv === 'crash' && asan === 'crash', 'Crash signals and sanitizer reports are crashes')
+    teq(killed, 'fail', 'Tests killed by other signals are failures')
+    ttrue(slow === 'timeout' && silent === 'timeout', 'Timeouts include idle timeouts')
+    ttrue(build === 'error' && skip === 'skip', 'Errors and skips')
+
+    const counts = countCategories(results)
+    ttrue(counts.pass === 2 && counts.fail === 3 && counts.crash === 2, 'Pass, fail and crash counts')
+    ttrue(counts.timeout === 2 && counts.error === 1 && counts.skip === 1, 'Timeout, error and skip counts')
+    ttrue(Object.values(countCategories([])).every((count) => count === 0), 'Empty categories are counted as zero')
+
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-categories-'))
+    try {
+        const reporter = new JsonReporter('results.json', rootDir)
+        results.forEach((result) => reporter.testEnd(result))
+        reporter.runEnd(results)
+        const doc = JSON.parse(await readFile(join(rootDir, 'results.json'), 'utf-8'))
+        ttrue(doc.summary.categories.crash === 2 && doc.summary.categories.fail === 3, 'Categories in JSON summary')
+        ttrue(doc.tests[4].category === 'crash' && doc.tests[4].status === 'fail', 'Category recorded per test')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
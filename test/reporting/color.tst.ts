@@ -0,0 +1,51 @@
+/*
+    Color control unit tests
+    Verifies --color auto, always and never, the NO_COLOR convention and plain text in report files
+ */
+
+import {stripAnsi, useColor} from '../../src/utils/tty.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestReporter} from '../../src/reporter.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {capture, makeFile, makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'node:fs/promises'
+import {tmpdir} from 'node:os'
+import {join} from 'path'
+
+async function test() {
+    ttrue(useColor('always', false) && !useColor('never'), 'always and never force colors')
+    const saved = process.env.NO_COLOR
+    process.env.NO_COLOR = '1'
+    ttrue(!useColor('auto') && useColor('always'), 'NO_COLOR disables auto only')
+    if (saved === undefined) {
+        delete process.env.NO_COLOR
+    } else {
+        process.env.NO_COLOR = saved
+    }
+    ttrue(!useColor('auto', false), 'colors: false disables auto')
+    teq(stripAnsi('\x1b[1m\x1b[31mred\x1b[0m text'), 'red text', 'Escape sequences removed')
+
+    const root = await mkdtemp(join(tmpdir(), 'testme-color-'))
+    try {
+        const file = makeFile(root, 'a.tst.sh')
+        const result = makeResult(file, TestStatus.Failed, {output: '\x1b[31mboom\x1b[0m'})
+
+        const colored = new TestReporter({output: {verbose: false, format: 'simple', colors: true}}, root)
+        const line = capture(() => colored.reportProgress(result))
+        ttrue(line.includes('\x1b[31m✗ FAIL') && line.includes('\x1b[1ma.tst.sh'), 'FAIL is red and names bold')
+        const plain = new TestReporter({output: {verbose: false, format: 'simple', colors: false}}, root)
+        ttrue(!capture(() => plain.reportProgress(result)).includes('\x1b['), 'No colors when disabled')
+
+        const path = join(root, 'results.json')
+        const writer = new JsonReporter(path, root)
+        writer.testEnd(result)
+        writer.runEnd([result], 1)
+        const json = await readFile(path, 'utf8')
+        ttrue(json.includes('"stdout": "boom"') && !json.includes('\\u001b'), 'JSON report has no color codes')
+    } finally {
+        await rm(root, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,73 @@
+/*
+    NDJSON event feed unit tests
+    Verifies event records, monotonic timestamps and per-test output attribution
+ */
+
+import {EventStream} from '../../src/events.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-events-'))
+    try {
+        const a = makeFile(rootDir, 'a.tst.sh')
+        const b = makeFile(join(rootDir, 'sub'), 'b.tst.sh')
+
+        EventStream.open('file:events.ndjson', rootDir)
+        ttrue(EventStream.isEnabled(), 'Event feed enabled')
+        EventStream.emitDiscovered([a, b])
+
+        // Interleave two "workers" - output must be attributed to the right test
+        await Promise.all(
+            [a, b].map((file) =>
+                EventStream.runWithTest(file, async () => {
+                    EventStream.emit('test-start', {}, file)
+                    await Bun.sleep(5)
+                    EventStream.emit('test-output', {stream: 'stdout', data: `hello from ${file.name}`})
+                })
+            )
+        )
+        EventStream.emitTestEnd({file: a, status: TestStatus.Passed, duration: 5, output: '', exitCode: 0})
+        EventStream.emit('run-end', {total: 1})
+        EventStream.close()
+        ttrue(!EventStream.isEnabled(), 'Event feed closed')
+
+        const lines = (await readFile(join(rootDir, 'events.ndjson'), 'utf-8')).trim().split('\n')
+        const events = lines.map((line) => JSON.parse(line))
+        ttrue(events[0].event === 'discovered' && events[0].count === 2, 'Discovered event')
+        teq(events[0].tests[1], 'sub/b.tst.sh', 'Relative test paths')
+
+        const outputs = events.filter((event) => event.event === 'test-output')
+        teq(outputs.length, 2, 'Output events emitted')
+        ttrue(
+            outputs.every((event) => event.data === `hello from ${event.path.split('/').pop()}`),
+            'Output attributed to its test'
+        )
+
+        let monotonic = true
+        for (let i = 1; i < events.length; i++) {
+            monotonic = monotonic && events[i].ts >= events[i - 1].ts
+        }
+        ttrue(monotonic, 'Timestamps are monotonic')
+
+        const end = events.find((event) => event.event === 'test-end')
+        ttrue(end.path === 'a.tst.sh' && end.status === 'passed' && end.exitCode === 0, 'Test end event')
+        teq(events[events.length - 1].event, 'run-end', 'Run end event last')
+
+        let threw = false
+        try {
+            EventStream.open('pipe:3', rootDir)
+        } catch {
+            threw = true
+        }
+        ttrue(threw, 'Invalid destination rejected')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
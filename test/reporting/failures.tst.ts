@@ -0,0 +1,53 @@
+/*
+    Failure summary unit tests
+    Verifies failed tests are repeated grouped by directory, with counts, independent of completion order
+ */
+
+import {TestReporter} from '../../src/reporter.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {ttrue} from 'testme'
+import {capture, makeResult, run} from '../helpers.ts'
+import {join} from 'path'
+
+const rootDir = '/work'
+
+// Result of a shell test that printed its name
+function shellResult(dir: string, name: string, status: TestStatus): TestResult {
+    const exitCode = status === TestStatus.Passed ? 0 : 1
+    return makeResult(join(rootDir, dir, name), status, {duration: 5, output: `output of ${name}`, exitCode})
+}
+
+function makeConfig(output: Partial<NonNullable<TestConfig['output']>>): TestConfig {
+    return {output: {verbose: true, format: 'detailed', colors: false, ...output}}
+}
+
+async function test() {
+    // Results in the order parallel workers might complete them
+    const results = [
+        shellResult('net', 'socket.tst.sh', TestStatus.Failed),
+        shellResult('util', 'strings.tst.sh', TestStatus.Timeout),
+        shellResult('net', 'http.tst.sh', TestStatus.Error),
+        shellResult('util', 'math.tst.sh', TestStatus.Passed),
+    ]
+
+    const failures = capture(() => new TestReporter(makeConfig({errorsOnly: true}), rootDir).reportResults(results))
+    ttrue(failures.includes('FAILURES (3 in 2 directories)'), 'Failure count and directory count')
+    ttrue(failures.includes('net/ (2 failed)') && failures.includes('util/ (1 failed)'), 'Count per directory')
+    const order = ['net/ (2', 'net/http.tst.sh', 'net/socket.tst.sh', 'util/ (1', 'util/strings.tst.sh']
+    const positions = order.map((text) => failures.indexOf(text))
+    ttrue(positions.every((pos, i) => pos >= 0 && (i === 0 || pos > positions[i - 1]!)), 'Groups and tests sorted')
+    ttrue(failures.includes('output of socket.tst.sh'), 'Captured output repeated')
+    ttrue(!failures.includes('math.tst.sh'), 'Passing tests omitted')
+    ttrue(failures.indexOf('FAILURES') < failures.indexOf('TEST SUMMARY'), 'Failures precede the summary')
+
+    const verbose = capture(() => new TestReporter(makeConfig({}), rootDir).reportResults(results))
+    ttrue(verbose.includes('math.tst.sh') && !verbose.includes('FAILURES'), 'Verbose listing without summary')
+
+    const summarized = capture(() =>
+        new TestReporter(makeConfig({summaryFailures: true}), rootDir).reportResults(results)
+    )
+    ttrue(summarized.indexOf('FAILURES') > summarized.indexOf('math.tst.sh'), '--summary-failures repeats failures')
+}
+
+await run(test)
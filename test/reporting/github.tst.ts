@@ -0,0 +1,64 @@
+/*
+    GitHub Actions reporter unit tests
+    Verifies output groups, ::error annotations with locations from compiler and assertion output, and escaping
+ */
+
+import {GitHubReporter, isStdoutReport, resolveReportSpecs} from '../../src/reporters/index.ts'
+import type {TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+
+// Result of a test in /work/repo/test that printed output
+function withOutput(name: string, status: TestStatus, output: string, extra: Partial<TestResult> = {}): TestResult {
+    return makeResult(`/work/repo/test/${name}`, status, {output, ...extra})
+}
+
+async function test() {
+    const reporter = new GitHubReporter('/work/repo/test', '/work/repo')
+
+    const passed = reporter.render(withOutput('ok.tst.c', TestStatus.Passed, 'hello\n'))
+    teq(passed, '::group::ok.tst.c (passed)\nhello\n::endgroup::', 'Output is grouped')
+    teq(reporter.render(withOutput('quiet.tst.c', TestStatus.Passed, '')), '', 'Nothing for a silent pass')
+
+    const assertion = withOutput('math.tst.c', TestStatus.Failed, '✗ Test failed at math.tst.c@23: 1 + 1\n', {
+        error: 'Exit code 1',
+    })
+    const failed = reporter.render(assertion).split('\n').pop()!
+    ttrue(failed.startsWith('::error file=test/math.tst.c,line=23,title=math.tst.c fail::'), 'Assertion location')
+    ttrue(failed.endsWith('::Exit code 1'), 'Error message')
+    const soft = withOutput('io.tst.c', TestStatus.Failed, 'PASS io.tst.c:8 open\nFAIL io.tst.c:12 size == 4\n')
+    teq(reporter.findLocation(soft)?.line, 12, 'Soft assertion location')
+
+    const gcc = withOutput('build.tst.c', TestStatus.Error, "build.tst.c:7:5: error: unknown type name 'bool'\n")
+    ttrue(
+        reporter.render(gcc).endsWith(
+            "::error file=test/build.tst.c,line=7,col=5,title=build.tst.c failed to build::unknown type name 'bool'"
+        ),
+        'GCC and Clang compile errors'
+    )
+    const msvc = withOutput('win.tst.c', TestStatus.Error, 'C:/src/win.tst.c(12): error C2065: x: undeclared\n')
+    teq(reporter.findLocation(msvc)?.line, 12, 'MSVC compile errors')
+    const rustOutput = 'error[E0425]: cannot find value `y`\n --> lib.tst.rs:3:13\n'
+    const rust = withOutput('lib.tst.rs', TestStatus.Error, rustOutput)
+    const location = reporter.findLocation(rust)
+    ttrue(location?.line === 3 && location.col === 13 && location.message === 'cannot find value `y`', 'rustc errors')
+
+    const timeout = withOutput('slow.tst.c', TestStatus.Timeout, '', {error: 'Timed out: 50%\nafter 5s'})
+    const annotation = '::error file=test/slow.tst.c,title=slow.tst.c timeout::Timed out: 50%25%0Aafter 5s'
+    teq(reporter.render(timeout), annotation, 'Unlocated failures annotate the test file and escape the message')
+    const skipped = withOutput('skip.tst.c', TestStatus.Skipped, '')
+    teq(reporter.render(skipped), '', 'No annotation for skipped tests')
+
+    ttrue(!isStdoutReport('github'), 'Annotations do not claim stdout')
+    teq(resolveReportSpecs(['console', 'github', 'github'], '/tmp').join(','), 'github', 'Written once')
+    let error = ''
+    try {
+        resolveReportSpecs(['github:out.txt'], '/tmp')
+    } catch (err) {
+        error = (err as Error).message
+    }
+    ttrue(error.includes('written to the console output'), 'Annotations take no file')
+}
+
+await run(test)
@@ -0,0 +1,60 @@
+/*
+    JSON results file unit tests
+    Verifies the results document and that partial results are written before the run finishes
+ */
+
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestStatus} from '../../src/types.ts'
+import {VERSION} from '../../src/version.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-json-'))
+    try {
+        const path = join(rootDir, 'results.json')
+        const reporter = new JsonReporter('results.json', rootDir, 3)
+        reporter.runStart([])
+
+        reporter.testEnd({
+            file: makeFile(rootDir, 'math.tst.c'),
+            status: TestStatus.Failed,
+            duration: 42.4,
+            output: 'STDOUT:\nhello\nSTDERR:\nboom',
+            stdout: 'hello\n',
+            stderr: 'boom\n',
+            exitCode: 1,
+        })
+
+        // Partial results are on disk before the run finishes (e.g., after Ctrl-C)
+        let doc = JSON.parse(await readFile(path, 'utf-8'))
+        teq(doc.summary.complete, false, 'Partial results marked incomplete')
+        teq(doc.tests.length, 1, 'Partial results written incrementally')
+
+        reporter.testEnd({
+            file: makeFile(join(rootDir, 'sub'), 'api.tst.js'),
+            status: TestStatus.Skipped,
+            duration: 0,
+            output: 'Skipped',
+        })
+        reporter.runEnd([], 100)
+
+        doc = JSON.parse(await readFile(path, 'utf-8'))
+        const [math, api] = doc.tests
+        teq(doc.summary.version, VERSION, 'Summary includes version')
+        ttrue(doc.summary.total === 2 && doc.summary.failed === 1 && doc.summary.skipped === 1, 'Summary totals')
+        teq(doc.summary.complete, true, 'Final results marked complete')
+        ttrue(math.path === 'math.tst.c' && math.language === 'c', 'Path and language')
+        ttrue(math.status === 'fail' && math.exitCode === 1 && math.durationMs === 42, 'Status, exit code, duration')
+        ttrue(math.stdout === 'hello\n' && math.stderr === 'boom\n', 'Separate stdout and stderr')
+        teq(math.depth, 3, 'Depth recorded')
+        ttrue(api.path === 'sub/api.tst.js' && api.status === 'skip' && api.exitCode === null, 'Skipped test')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
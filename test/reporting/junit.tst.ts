@@ -0,0 +1,76 @@
+/*
+    JUnit reporter unit tests
+    Verifies the JUnit XML document structure and incremental flushing
+ */
+
+import {JUnitReporter} from '../../src/reporters/junit.ts'
+import {parseReportSpec} from '../../src/reporters/index.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-junit-'))
+    try {
+        const reportPath = join(rootDir, 'out', 'results.xml')
+        const timed = {duration: 1500}
+        const reporter = new JUnitReporter('out/results.xml', rootDir)
+
+        reporter.runStart([])
+        let xml = await readFile(reportPath, 'utf-8')
+        ttrue(xml.includes('<testsuites name="testme" tests="0"'), 'Empty report written on begin')
+
+        reporter.testEnd(makeResult(join(rootDir, 'unit', 'math.tst.c'), TestStatus.Passed, timed))
+        xml = await readFile(reportPath, 'utf-8')
+        ttrue(xml.includes('tests="1"'), 'Report flushed after first result')
+
+        reporter.testEnd(
+            makeResult(join(rootDir, 'unit', 'fail.tst.c'), TestStatus.Failed, {
+                ...timed,
+                output: 'expected <1> & got "2"',
+                error: 'assertion failed',
+                exitCode: 1,
+            })
+        )
+        reporter.testEnd(
+            makeResult(join(rootDir, 'broken.tst.c'), TestStatus.Error, {
+                ...timed,
+                error: 'Compilation failed: syntax error',
+            })
+        )
+        reporter.testEnd(
+            makeResult(join(rootDir, 'later.tst.c'), TestStatus.Skipped, {...timed, output: 'Requires network'})
+        )
+        reporter.runEnd([])
+
+        xml = await readFile(reportPath, 'utf-8')
+        ttrue(xml.startsWith('<?xml version="1.0" encoding="UTF-8"?>'), 'XML declaration present')
+        ttrue(xml.includes('tests="4" failures="1" errors="1" skipped="1" time="6.000"'), 'Totals and time in seconds')
+        ttrue(xml.includes('<testsuite name="unit"'), 'Suite per directory')
+        ttrue(xml.includes('name="math.tst.c" classname="unit" time="1.500"/>'), 'Passing testcase')
+        ttrue(
+            xml.includes('<failure message="assertion failed" type="failure">expected &lt;1&gt; &amp; got &quot;2&quot;'),
+            'Failure with escaped output'
+        )
+        ttrue(xml.includes('<error message="Compilation failed: syntax error"'), 'Compilation failure as error')
+        ttrue(xml.includes('classname="."'), 'Root directory classname')
+        ttrue(xml.includes('<skipped message="Requires network"/>'), 'Skipped testcase')
+
+        teq(parseReportSpec('junit:results.xml').file, 'results.xml', 'Parse report file')
+        teq(parseReportSpec('JUnit').format, 'junit', 'Parse report format only')
+        let threw = false
+        try {
+            parseReportSpec('bogus')
+        } catch {
+            threw = true
+        }
+        ttrue(threw, 'Unknown format rejected')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
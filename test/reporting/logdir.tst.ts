@@ -0,0 +1,78 @@
+/*
+    Per-test log file tests (--logdir)
+    Verifies the log paths mirroring the test tree, the log header and output, and that a run writes one complete
+    log per test without leaving temporary files
+ */
+
+import {TestLogs} from '../../src/logdir.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {existsSync, readdirSync} from 'fs'
+import {mkdir, mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const root = await mkdtemp(join(tmpdir(), 'testme-logdir-'))
+    const logs = join(root, 'logs')
+    try {
+        TestLogs.open('logs', root)
+        const dir = join(root, 'unit')
+        const file = makeFile(dir, 'math.tst.sh')
+        teq(TestLogs.getPath(file), join(logs, 'unit', 'math.tst.sh.log'), 'Log path mirrors the test tree')
+        const testCase = {...file, testCase: {name: 'a/b', env: {}}}
+        teq(TestLogs.getPath(testCase), join(logs, 'unit', 'math.tst.sh[a_b].log'), 'Case name in the path')
+        const cell = {...file, matrix: 'cc=gcc'}
+        teq(TestLogs.getPath(cell), join(logs, 'cc=gcc', 'unit', 'math.tst.sh.log'), 'Matrix cell directory')
+
+        const result: TestResult = {
+            file,
+            status: TestStatus.Failed,
+            duration: 12.4,
+            output: 'out line\nerr line\n',
+            error: 'err line',
+            exitCode: 2,
+            command: 'cd /tmp && /bin/sh math.tst.sh',
+        }
+        const text = TestLogs.format(result)
+        ttrue(text.startsWith('Test: unit/math.tst.sh\nStatus: failed\nExit code: 2\nDuration: 12ms\n'), 'Log header')
+        ttrue(text.includes('Command: cd /tmp && /bin/sh math.tst.sh\nError:\n    err line\n'), 'Command and error')
+        ttrue(text.endsWith('\n\nout line\nerr line\n'), 'Combined output after the header')
+
+        if (process.platform === 'win32') {
+            console.log('Shell tests not supported on Windows - skipping')
+            return
+        }
+        await mkdir(dir, {recursive: true})
+        const passing = makeFile(dir, 'pass.tst.sh')
+        const failing = makeFile(dir, 'fail.tst.sh')
+        await writeFile(passing.path, 'echo "to stdout"\necho "to stderr" >&2\nexit 0\n')
+        await writeFile(failing.path, 'echo "failing"\nexit 3\n')
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: true, workers: 2},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        await new TestRunner().executeTestsWithConfig([passing, failing], config)
+
+        const passLog = await readFile(join(logs, 'unit', 'pass.tst.sh.log'), 'utf-8')
+        ttrue(passLog.includes('Status: passed') && passLog.includes('Exit code: 0'), 'Passing test logged')
+        ttrue(passLog.includes('to stdout') && passLog.includes('to stderr'), 'Stdout and stderr logged')
+        ttrue(/Command: .*pass\.tst\.sh/.test(passLog), 'Command logged')
+        const failLog = await readFile(join(logs, 'unit', 'fail.tst.sh.log'), 'utf-8')
+        ttrue(failLog.includes('Status: failed') && failLog.includes('Exit code: 3'), 'Failing test logged')
+        ttrue(readdirSync(join(logs, 'unit')).every((name) => name.endsWith('.log')), 'No temporary files left')
+
+        TestLogs.close()
+        await rm(logs, {recursive: true, force: true})
+        await new TestRunner().executeTestsWithConfig([passing], config)
+        ttrue(!existsSync(logs), 'No logs without --logdir')
+    } finally {
+        TestLogs.close()
+        await rm(root, {recursive: true, force: true})
+    }
+}
+
+await run(test)
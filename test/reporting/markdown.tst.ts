@@ -0,0 +1,55 @@
+/*
+    Markdown summary unit tests
+    Verifies the status badge, per-directory table, failure details and truncation of large failure output
+ */
+
+import {MarkdownReporter} from '../../src/reporters/index.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-markdown-'))
+    try {
+        const unit = join(rootDir, 'unit')
+        const reporter = new MarkdownReporter('out/summary.md', rootDir)
+        reporter.runStart([])
+        let doc = await readFile(join(rootDir, 'out', 'summary.md'), 'utf-8')
+        ttrue(doc.startsWith('# Test Results (incomplete)') && doc.includes('tests-passing'), 'Empty summary written')
+
+        const results = [
+            makeResult(join(unit, 'math.tst.sh'), TestStatus.Passed),
+            makeResult(join(unit, 'str.tst.sh'), TestStatus.Failed, {
+                duration: 500,
+                stdout: 'expected ```x```\n',
+                stderr: 'boom\n',
+            }),
+            makeResult(join(rootDir, 'big.tst.sh'), TestStatus.Timeout, {output: 'x'.repeat(10000)}),
+            makeResult(join(rootDir, 'later.tst.sh'), TestStatus.Skipped),
+        ]
+        results.forEach((result) => reporter.testEnd(result))
+        reporter.runEnd(results, 2500)
+
+        doc = await readFile(join(rootDir, 'out', 'summary.md'), 'utf-8')
+        const lines = doc.split('\n')
+        teq(lines[0], '# Test Results', 'Complete summary')
+        teq(lines[2], '![Tests failing](https://img.shields.io/badge/tests-failing-red)', 'Status badge')
+        teq(lines[4], '**1 passed, 2 failed, 1 skipped** of 4 test(s) in 2.5s', 'Totals and duration')
+        ttrue(lines.includes('| `.` | 0 | 1 | 1 | 1.0s |'), 'Root directory row')
+        ttrue(lines.includes('| `unit` | 1 | 1 | 0 | 1.0s |'), 'Directory row')
+        ttrue(lines.includes('| **Total** | **1** | **2** | **1** | **2.5s** |'), 'Total row')
+        ttrue(doc.indexOf('`.`') < doc.indexOf('`unit`'), 'Directories sorted')
+        ttrue(doc.includes('<summary>❌ <code>unit/str.tst.sh</code> (failed, 500ms)</summary>'), 'Failure details')
+        ttrue(doc.includes('````text\nexpected ```x```\nboom\n````'), 'Fence longer than backticks in the output')
+        ttrue(doc.includes('_Output truncated: the first 6000 characters are omitted._'), 'Truncation note')
+        ttrue(!doc.includes('x'.repeat(4001)) && doc.includes('x'.repeat(4000)), 'Large output truncated')
+        ttrue(!doc.includes('math.tst.sh') && !doc.includes('later.tst.sh'), 'Only failures have details')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
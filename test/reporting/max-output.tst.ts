@@ -0,0 +1,55 @@
+/*
+    Output cap tests
+    Verifies size parsing, that output beyond output.maxBytes is discarded with a marker and the test still completes
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {getTruncationMarker, parseByteSize, takeOutput} from '../../src/utils/output-limit.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdtemp, readFile, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    ttrue(parseByteSize('512') === 512 && parseByteSize(2048) === 2048, 'Plain byte counts')
+    ttrue(parseByteSize('10MB') === 10 * 1024 * 1024 && parseByteSize('64k') === 65536, 'Size suffixes')
+    teq(parseByteSize('1.5 GB'), 1.5 * 1024 * 1024 * 1024, 'Fractional sizes')
+    ttrue(parseByteSize('ten') === null && parseByteSize('5TB') === null && parseByteSize(-1) === null, 'Bad sizes')
+    const chunk = new Uint8Array([1, 2, 3, 4])
+    ttrue(takeOutput(chunk, 10) === chunk && takeOutput(chunk, 3).length === 3, 'Chunks are cut at the cap')
+    teq(takeOutput(chunk, -2).length, 0, 'Nothing is kept past the cap')
+
+    if (process.platform === 'win32') {
+        console.log('Uses a POSIX shell test - skipping the rest on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-max-output-'))
+    try {
+        const file = makeFile(dir, 'noisy.tst.sh')
+        // Writes about 1MB, far more than a pipe buffer, then proves it ran to the end
+        const script = `yes 0123456789abcdef | head -c 1000000\necho error >&2\ntouch ${join(dir, 'done')}\n`
+        await writeFile(file.path, `#!/bin/sh\n${script}`)
+        await chmod(file.path, 0o755)
+
+        const handler = new ShellTestHandler()
+        const config: TestConfig = {configDir: dir, execution: {timeout: 30, parallel: false}}
+        const output = {verbose: false, format: 'simple' as const, colors: false, maxBytes: '1K'}
+        const result = await handler.execute(file, {...config, output})
+        teq(result.status, TestStatus.Passed, 'Capped test runs to completion')
+        teq(await readFile(join(dir, 'done'), 'utf8'), '', 'Test wrote past the cap')
+        const marker = getTruncationMarker(1024)
+        ttrue(result.truncated === true && result.stdout!.endsWith(marker), 'Truncation is marked')
+        teq(result.stdout!.length, 1024 + marker.length, 'Output kept up to the cap')
+        ttrue(!result.stderr!.includes('error'), 'Streams share the cap')
+
+        const full = await handler.execute(file, config)
+        ttrue(!full.truncated && full.stdout!.length === 1000000, 'Output is not capped by default')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
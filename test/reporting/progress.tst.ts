@@ -0,0 +1,57 @@
+/*
+    Progress line unit tests
+    Verifies run counts across groups and cases, and the plain progress lines printed when stdout is not a terminal
+ */
+
+import {RunProgress} from '../../src/progress.ts'
+import {TestReporter} from '../../src/reporter.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {capture, makeResult, run} from '../helpers.ts'
+import {join} from 'path'
+
+const rootDir = '/work'
+
+// Result of a shell test that exits non-zero unless it passed
+function shellResult(name: string, status: TestStatus): TestResult {
+    return makeResult(join(rootDir, name), status, {duration: 5, exitCode: status === TestStatus.Passed ? 0 : 1})
+}
+
+async function test() {
+    RunProgress.begin(6)
+    RunProgress.beginGroup(3)
+    RunProgress.expand(1)
+    RunProgress.record(shellResult('a.tst.sh', TestStatus.Passed))
+    RunProgress.record(shellResult('b.tst.sh', TestStatus.Failed))
+    RunProgress.record(shellResult('c.tst.sh', TestStatus.Timeout))
+    RunProgress.record(shellResult('d.tst.sh', TestStatus.Skipped))
+    let status = RunProgress.getStatus()
+    ttrue(status.completed === 4 && status.total === 7, 'Cases of parameterized tests add to the total')
+    ttrue(status.passed === 1 && status.failed === 2, 'Pass and fail counts')
+
+    // A disabled group runs nothing and a stopped group runs part of its tests
+    RunProgress.beginGroup(2)
+    RunProgress.beginGroup(1)
+    status = RunProgress.getStatus()
+    teq(status.total, 5, 'Tests of a group that did not run leave the total')
+
+    const config: TestConfig = {output: {verbose: false, format: 'simple', colors: false, progress: true}}
+    const reporter = new TestReporter(config, rootDir)
+    const passed = capture(() => {
+        RunProgress.record(shellResult('e.tst.sh', TestStatus.Passed))
+        reporter.reportProgress(shellResult('e.tst.sh', TestStatus.Passed))
+    })
+    ttrue(!passed.includes('e.tst.sh'), 'Passing tests print no status line')
+    ttrue(passed.includes('[5/5] 2 passed, 2 failed (0s)'), 'Plain progress line after the last test')
+
+    RunProgress.begin(3)
+    RunProgress.beginGroup(3)
+    const failed = capture(() => {
+        RunProgress.record(shellResult('f.tst.sh', TestStatus.Failed))
+        reporter.reportProgress(shellResult('f.tst.sh', TestStatus.Failed))
+    })
+    ttrue(failed.includes('f.tst.sh') && !failed.includes('[1/3]'), 'Failures print status and plain lines wait')
+}
+
+await run(test)
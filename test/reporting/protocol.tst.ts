@@ -0,0 +1,87 @@
+/*
+    Harness protocol tests
+    Verifies that "TESTME {...}" lines become named sub-results, that other output passes through untouched, that a
+    failed sub-result fails the test, the JSON report results and the shell helper printed by --emit-header
+ */
+
+import {HarnessProtocol} from '../../src/protocol.ts'
+import {TestHeaders} from '../../src/headers.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+import {mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const stdout = [
+        'starting',
+        'TESTME {"event":"assert","name":"add","status":"pass","duration":2}',
+        'TESTME {"event":"assert","name":"sub","status":"fail","message":"got 1","file":"math.go","line":9}',
+        'TESTME {"event":"progress","percent":50}',
+        'TESTME {"event":"assert","name":"net","status":"skip"}\r',
+        'TESTME {not json}',
+        'TESTME {"event":"assert","status":"pass"}',
+        'done',
+    ].join('\n')
+    const {results, output} = HarnessProtocol.parse(stdout)
+    teq(results.map((sub) => `${sub.name}=${sub.status}`).join(), 'add=pass,sub=fail,net=skip', 'Sub-results')
+    const sub = results[1]
+    ttrue(sub?.message === 'got 1' && sub.file === 'math.go' && sub.line === 9, 'Message and location')
+    teq(results[0]?.duration, 2, 'Duration')
+    const kept = 'starting\nTESTME {not json}\nTESTME {"event":"assert","status":"pass"}\ndone'
+    teq(output, kept, 'Other output and invalid protocol lines pass through untouched')
+
+    const dir = await mkdtemp(join(tmpdir(), 'testme-protocol-'))
+    try {
+        const file = makeFile(dir, 'math.tst.sh')
+        const passed = makeResult(file, TestStatus.Passed, {output: stdout, stdout, exitCode: 0})
+        const applied = HarnessProtocol.apply(passed)
+        teq(applied.status, TestStatus.Failed, 'Failed sub-result fails a test that exited zero')
+        teq(applied.error, '1 of 3 results failed: sub', 'Error names the failed results')
+        ttrue(applied.assertions?.passed === 1 && applied.assertions.failed === 1, 'Sub-results counted')
+        ttrue(applied.output === kept && applied.stdout === kept, 'Protocol lines removed from the output')
+        const plain = makeResult(file, TestStatus.Passed, {output: 'ok\n'})
+        teq(HarnessProtocol.apply(plain), plain, 'Output without protocol lines is unchanged')
+
+        const reporter = new JsonReporter(join(dir, 'results.json'), dir)
+        reporter.testEnd(applied)
+        const json = reporter.render()
+        teq(json.tests[0]?.results?.[1]?.message, 'got 1', 'Sub-results in the JSON report')
+
+        const helper = TestHeaders.get('go')
+        ttrue(helper.includes('package testme') && helper.includes('TESTME %s'), 'Go helper')
+        if (process.platform === 'win32') {
+            console.log('Shell helper not supported on Windows - skipping')
+            return
+        }
+        await writeFile(join(dir, 'testme.sh'), TestHeaders.get('sh'))
+        const shell = makeFile(dir, 'helper.tst.sh')
+        const script = [
+            '. "$(dirname "$0")/testme.sh"',
+            'echo "plain output"',
+            'tm_pass \'quoted "name"\'',
+            'tm_check exists test -f /nonexistent/file',
+            'tm_skip net "no \\\\ network"',
+            'exit 0',
+        ]
+        await writeFile(shell.path, script.join('\n') + '\n')
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: false},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+        const result = (await new TestRunner().executeTestsWithConfig([shell], config))[0]!
+        const names = (result.subResults || []).map((sub) => `${sub.name}=${sub.status}`).join()
+        teq(names, 'quoted "name"=pass,exists=fail,net=skip', 'Shell helper escapes names')
+        teq(result.subResults?.[2]?.message, 'no \\ network', 'Shell helper escapes messages')
+        teq(result.status, TestStatus.Failed, 'Shell test with a failed result fails')
+        ttrue(result.output.includes('plain output') && !result.output.includes('TESTME'), 'Shell output kept')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
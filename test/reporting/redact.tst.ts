@@ -0,0 +1,71 @@
+/*
+    Secret redaction tests
+    Verifies redact.env values and redact.patterns matches are replaced in captured output, including output read
+    in chunks with a secret split across reads
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {createRedactFilter, getRedactPattern, redact} from '../../src/utils/redact.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const env = {TOKEN: 's3cr3t', LONG: 's3cr3t-plus', EMPTY: '', DOT: 'a.b'}
+    const pattern = getRedactPattern({env: ['TOKEN', 'LONG', 'EMPTY', 'MISSING', 'DOT'], patterns: ['ghp_\\w+']}, env)!
+    teq(redact('key=s3cr3t', pattern), 'key=***', 'Environment value replaced')
+    teq(redact('key=s3cr3t-plus', pattern), 'key=***', 'Longer values replaced whole')
+    teq(redact('axb a.b', pattern), 'axb ***', 'Values are matched literally')
+    teq(redact('auth ghp_abc123 ok', pattern), 'auth *** ok', 'Pattern matches replaced')
+    ttrue(getRedactPattern({env: ['EMPTY']}, env) === null && getRedactPattern(undefined, env) === null, 'Nothing')
+    let invalid = false
+    try {
+        getRedactPattern({patterns: ['(']}, env)
+    } catch (error) {
+        invalid = (error as Error).message.startsWith('Invalid redact pattern "("')
+    }
+    ttrue(invalid, 'Invalid pattern reported')
+
+    const filter = createRedactFilter(pattern)
+    const chunks = [filter('one s3c'), filter('r3t two\nthree s3'), filter('cr3t'), filter()]
+    teq(chunks.join(''), 'one *** two\nthree ***', 'Secrets split across reads replaced')
+    ttrue(chunks[0] === '' && chunks[1] === 'one *** two\n', 'Partial lines held back')
+
+    if (process.platform === 'win32') {
+        console.log('Uses a POSIX shell test - skipping the rest on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-redact-'))
+    try {
+        const file = makeFile(dir, 'leak.tst.sh')
+        await writeFile(file.path, '#!/bin/sh\necho "token=$API_TOKEN"\necho "id ghp_abc" >&2\n')
+        await chmod(file.path, 0o755)
+
+        const config: TestConfig = {
+            configDir: dir,
+            environment: {API_TOKEN: 'hunter2'},
+            redact: {env: ['API_TOKEN'], patterns: ['ghp_\\w+']},
+            execution: {timeout: 30, parallel: false},
+        }
+        const handler = new ShellTestHandler()
+        let result = await handler.execute(file, config)
+        teq(result.status, TestStatus.Passed, 'Redacted test passes')
+        ttrue(result.stdout!.includes('token=***') && !result.output.includes('hunter2'), 'Buffered stdout redacted')
+        ttrue(result.stderr!.includes('id ***'), 'Buffered stderr redacted')
+
+        const output = {verbose: false, format: 'simple' as const, colors: false, maxBytes: '1M'}
+        result = await handler.execute(file, {...config, output})
+        ttrue(result.stdout!.includes('token=***') && !result.output.includes('hunter2'), 'Streamed output redacted')
+
+        result = await handler.execute(file, {...config, redact: undefined})
+        ttrue(result.stdout!.includes('token=hunter2'), 'Output unchanged without redact')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
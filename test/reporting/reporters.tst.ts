@@ -0,0 +1,110 @@
+/*
+    Reporter registry unit tests
+    Verifies custom formats are selected by name, repeated reports are resolved without duplicates or conflicting
+    destinations, and a multi reporter sends every event to each reporter in order
+ */
+
+import {CliParser} from '../../src/cli.ts'
+import {
+    MultiReporter,
+    REPORT_FORMATS,
+    createReporter,
+    isStdoutReport,
+    parseReportSpec,
+    registerReporter,
+    resolveReportSpecs,
+} from '../../src/reporters/index.ts'
+import type {Reporter} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, makeResult, run} from '../helpers.ts'
+
+function recorder(name: string, events: string[]): Reporter {
+    return {
+        runStart: (tests) => events.push(`${name} start ${tests.length}`),
+        testStart: (file) => events.push(`${name} test ${file.name}`),
+        testEnd: (result) => events.push(`${name} end ${result.file.name}`),
+        runEnd: (results) => events.push(`${name} done ${results.length}`),
+    }
+}
+
+async function test() {
+    teq(REPORT_FORMATS.join(','), 'junit,tap,json,markdown,github,console', 'Built-in formats')
+
+    let error = ''
+    try {
+        parseReportSpec('html:report.html')
+    } catch (err) {
+        error = (err as Error).message
+    }
+    ttrue(error.includes('Unknown report format: "html"'), 'Unregistered format rejected')
+
+    const events: string[] = []
+    let created = ''
+    registerReporter(
+        'HTML',
+        (path, rootDir) => {
+            created = `${rootDir}/${path}`
+            return recorder('html', events)
+        },
+        'report.html'
+    )
+    teq(parseReportSpec('html:out.html').format, 'html', 'Registered format selected by name')
+    createReporter('html', '/tmp')
+    teq(created, '/tmp/report.html', 'Default file of a registered format')
+    ttrue(!isStdoutReport('html') && isStdoutReport('tap'), 'Stdout detection')
+
+    error = ''
+    try {
+        registerReporter('bad:name', () => recorder('bad', events))
+    } catch (err) {
+        error = (err as Error).message
+    }
+    ttrue(error.includes('Invalid report format name'), 'Format names cannot contain a colon')
+
+    const options = CliParser.parse(['--report', 'console', '--report', 'junit:out.xml', '--report', 'json'])
+    teq(options.report!.join(','), 'console,junit:out.xml,json', '--report is repeatable')
+    const specs = resolveReportSpecs([...options.report!, 'JSON:results.json', 'junit:./out.xml'], '/tmp')
+    teq(specs.join(','), 'junit:out.xml,json:results.json', 'Console and duplicate reports are not created')
+    const conflict = (list: string[]) => {
+        try {
+            resolveReportSpecs(list, '/tmp')
+            return ''
+        } catch (err) {
+            return (err as Error).message
+        }
+    }
+    teq(conflict(['console', 'tap']), 'Conflicting reports: console and tap both write to stdout', 'Two on stdout')
+    ttrue(conflict(['tap', 'json:-']).includes('both write to stdout'), 'Explicit stdout conflicts')
+    ttrue(conflict(['junit:out.xml', 'json:out.xml']).includes('both write to "out.xml"'), 'Same file conflicts')
+    ttrue(conflict(['tap', 'junit']) === '' && resolveReportSpecs(['tap'], '/tmp')[0] === 'tap:-', 'No conflict')
+    ttrue(conflict(['console:log.txt']).includes('cannot be written to "log.txt"'), 'Console has no file')
+
+    const file = makeFile('/tmp', 'math.tst.c')
+    const result = makeResult(file, TestStatus.Passed)
+    events.length = 0
+    const failing: Reporter = {
+        testEnd: () => {
+            throw new Error('disk full')
+        },
+        runEnd: () => {},
+    }
+    const multi = new MultiReporter([recorder('a', events), failing, recorder('b', events)])
+    multi.runStart([file])
+    multi.testStart(file)
+    multi.testEnd(result)
+    multi.runEnd([result])
+    const expected = [
+        'a start 1',
+        'b start 1',
+        'a test math.tst.c',
+        'b test math.tst.c',
+        'a end math.tst.c',
+        'b end math.tst.c',
+        'a done 1',
+        'b done 1',
+    ]
+    teq(events.join('|'), expected.join('|'), 'Every reporter receives every event in order')
+}
+
+await run(test)
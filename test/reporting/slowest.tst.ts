@@ -0,0 +1,55 @@
+/*
+    Slowest tests unit tests
+    Verifies --slowest selection, the console listing and the slowest array in JSON results
+ */
+
+import {getSlowestTests} from '../../src/utils/slowest.ts'
+import {TestReporter} from '../../src/reporter.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeResult, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-slowest-'))
+    try {
+        const results = [
+            makeResult(join(rootDir, 'fast.tst.sh'), TestStatus.Passed, {duration: 20}),
+            makeResult(join(rootDir, 'skipped.tst.sh'), TestStatus.Skipped, {duration: 5000}),
+            makeResult(join(rootDir, 'slow.tst.sh'), TestStatus.Failed, {duration: 3000}),
+            makeResult(join(rootDir, 'medium.tst.sh'), TestStatus.Passed, {duration: 400}),
+        ]
+        const slowest = getSlowestTests(results, 2)
+        teq(slowest.map((r) => r.file.name).join(' '), 'slow.tst.sh medium.tst.sh', 'Longest first, skips omitted')
+        teq(results[0]!.file.name, 'fast.tst.sh', 'Results not reordered')
+        teq(getSlowestTests(results, 10).length, 3, 'Fewer tests than requested')
+
+        const log = console.log
+        const lines: string[] = []
+        console.log = (...args: unknown[]) => lines.push(args.join(' '))
+        try {
+            const config = {output: {verbose: false, format: 'simple' as const, colors: false, slowest: 2}}
+            new TestReporter(config, rootDir).reportSummary(results)
+        } finally {
+            console.log = log
+        }
+        const output = lines.join('\n')
+        ttrue(output.includes('SLOWEST TESTS (2)'), 'Slowest section printed')
+        ttrue(output.indexOf('slow.tst.sh') < output.indexOf('medium.tst.sh'), 'Listing sorted descending')
+        ttrue(output.indexOf('SLOWEST') < output.indexOf('TEST SUMMARY'), 'Listing precedes the summary')
+
+        const reporter = new JsonReporter('results.json', rootDir, 0, undefined, 1)
+        results.forEach((result) => reporter.testEnd(result))
+        reporter.runEnd(results)
+        const doc = JSON.parse(await readFile(join(rootDir, 'results.json'), 'utf-8'))
+        ttrue(doc.slowest.length === 1 && doc.slowest[0].path === 'slow.tst.sh', 'JSON results list slowest tests')
+        teq(doc.slowest[0].durationMs, 3000, 'JSON results include durations')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,52 @@
+/*
+    TAP reporter unit tests
+    Verifies plan line, ok/not ok lines, YAML diagnostics and SKIP directives
+ */
+
+import {TapReporter} from '../../src/reporters/tap.ts'
+import type {TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-tap-'))
+    try {
+        const pass = makeFile(rootDir, 'pass.tst.sh')
+        const fail = makeFile(join(rootDir, 'sub'), 'fail.tst.sh')
+        const skip = makeFile(rootDir, 'skip.tst.sh')
+        const never = makeFile(rootDir, 'never.tst.sh')
+
+        const reporter = new TapReporter('tap.out', rootDir)
+        reporter.runStart([pass, fail, skip, never])
+
+        const results: TestResult[] = [
+            {file: fail, status: TestStatus.Failed, duration: 12, output: 'line one\nline two', exitCode: 2},
+            {file: pass, status: TestStatus.Passed, duration: 5, output: ''},
+            {file: skip, status: TestStatus.Skipped, duration: 0, output: 'Requires docker'},
+        ]
+        for (const result of results) {
+            reporter.testEnd(result)
+        }
+        reporter.runEnd(results)
+
+        const tap = await readFile(join(rootDir, 'tap.out'), 'utf-8')
+        const lines = tap.split('\n')
+        teq(lines[0], 'TAP version 13', 'Version header')
+        teq(lines[1], '1..4', 'Plan line')
+        teq(lines[2], 'not ok 1 - sub/fail.tst.sh', 'Failures numbered in completion order')
+        teq(lines[3], '  ---', 'YAML block opens under failing test')
+        ttrue(tap.includes('  exitCode: 2\n'), 'Exit code diagnostic')
+        ttrue(tap.includes('  output: |\n    line one\n    line two\n  ...\n'), 'Indented output block')
+        ttrue(tap.includes('ok 2 - pass.tst.sh\n'), 'Passing test line')
+        ttrue(tap.includes('ok 3 - skip.tst.sh # SKIP Requires docker\n'), 'SKIP directive')
+        ttrue(tap.includes('ok 4 - never.tst.sh # SKIP not run\n'), 'Unrun tests satisfy the plan')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
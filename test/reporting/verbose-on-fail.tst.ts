@@ -0,0 +1,43 @@
+/*
+    Verbose on failure unit tests
+    Verifies --verbose-on-fail prints the details of failing tests under their status line and nothing for passes
+ */
+
+import {TestReporter} from '../../src/reporter.ts'
+import type {TestConfig, TestResult} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {ttrue} from 'testme'
+import {capture, makeResult, run} from '../helpers.ts'
+import {join} from 'path'
+
+const rootDir = '/work'
+
+// Result of a shell test that printed its name and failed
+function shellResult(name: string, status: TestStatus): TestResult {
+    const extra = {duration: 5, output: `output of ${name}`, error: `error of ${name}`, exitCode: 1}
+    return makeResult(join(rootDir, name), status, extra)
+}
+
+async function test() {
+    const config: TestConfig = {output: {verbose: false, format: 'simple', colors: false, verboseOnFail: true}}
+    const reporter = new TestReporter(config, rootDir)
+
+    const passed = capture(() => reporter.reportProgress(shellResult('pass.tst.sh', TestStatus.Passed)))
+    ttrue(passed.includes('pass.tst.sh') && !passed.includes('output of'), 'Passing test prints its status only')
+    const xfail = capture(() => reporter.reportProgress(shellResult('known.tst.sh', TestStatus.XFail)))
+    ttrue(!xfail.includes('output of'), 'Expected failure prints its status only')
+
+    const failed = capture(() => reporter.reportProgress(shellResult('fail.tst.sh', TestStatus.Failed)))
+    const lines = failed.split('\n')
+    ttrue(lines[0]!.includes('fail.tst.sh'), 'Status line comes first')
+    ttrue(failed.includes('output of fail.tst.sh') && failed.includes('error of fail.tst.sh'), 'Output and error')
+    ttrue(failed.includes('Exit Code: 1'), 'Exit code printed')
+    const timeout = capture(() => reporter.reportProgress(shellResult('slow.tst.sh', TestStatus.Timeout)))
+    ttrue(timeout.includes('output of slow.tst.sh'), 'Timed out test prints its output')
+
+    const quiet = new TestReporter({output: {verbose: false, format: 'simple', colors: false}}, rootDir)
+    const normal = capture(() => quiet.reportProgress(shellResult('fail.tst.sh', TestStatus.Failed)))
+    ttrue(!normal.includes('output of'), 'Without the option failures wait for the final report')
+}
+
+await run(test)
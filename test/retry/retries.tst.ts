@@ -0,0 +1,61 @@
+/*
+    Retry unit tests
+    Verifies failing tests are re-run and tests that pass on retry are flagged as flaky
+ */
+
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestStatus, TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {chmod, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function makeTest(dir: string, name: string, script: string): Promise<TestFile> {
+    const path = join(dir, name)
+    await writeFile(path, script)
+    await chmod(path, 0o755)
+    return {
+        path,
+        name,
+        extension: '.tst.sh',
+        type: TestType.Shell,
+        directory: dir,
+        artifactDir: join(dir, '.testme', name),
+    }
+}
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Shell retry test not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-retry-'))
+    try {
+        // Fails on the first attempt, passes on the second
+        const flaky = await makeTest(
+            dir,
+            'flaky.tst.sh',
+            `#!/bin/sh\nif [ -f ${dir}/ran ]; then exit 0; fi\ntouch ${dir}/ran\nexit 1\n`
+        )
+        const broken = await makeTest(dir, 'broken.tst.sh', '#!/bin/sh\nexit 1\n')
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: false, retries: 2},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+
+        const runner = new TestRunner()
+        const [flakyResult, brokenResult] = await runner.executeTestsWithConfig([flaky, broken], config)
+
+        teq(flakyResult!.status, TestStatus.Passed, 'Test passing on retry counts as passed')
+        ttrue(flakyResult!.attempts === 2 && flakyResult!.flaky === true, 'Flaky test flagged with attempts')
+        teq(brokenResult!.status, TestStatus.Failed, 'Consistently failing test still fails')
+        ttrue(brokenResult!.attempts === 3 && !brokenResult!.flaky, 'Failing test retried N times')
+        teq(runner.getExitCode([flakyResult!]), 0, 'Flaky pass does not fail the run')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
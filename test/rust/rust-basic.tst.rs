@@ -0,0 +1,34 @@
+use std::env;
+use std::process::exit;
+
+fn test_arithmetic() -> bool {
+    let tests = [("Addition", 2 + 2, 4), ("Subtraction", 10 - 5, 5), ("Multiplication", 3 * 4, 12)];
+    for (name, got, expected) in tests.iter() {
+        if got != expected {
+            println!("FAIL: {} test failed: got {}, expected {}", name, got, expected);
+            return false;
+        }
+        println!("PASS: {} test passed", name);
+    }
+    true
+}
+
+fn test_environment() -> bool {
+    match env::var("TESTME_VERBOSE") {
+        Ok(_) => {
+            println!("PASS: TESTME_VERBOSE is set");
+            true
+        }
+        Err(_) => {
+            println!("FAIL: TESTME_VERBOSE is not set");
+            false
+        }
+    }
+}
+
+fn main() {
+    if !test_arithmetic() || !test_environment() {
+        exit(1);
+    }
+    println!("All Rust tests passed");
+}
@@ -0,0 +1,74 @@
+/*
+    Rust configuration tests
+    Verifies that compiler.rust.compiler, flags and libraries reach rustc (libraries as --extern crates or -l native
+    libraries) and that a cached test binary is rebuilt when the flags or libraries change
+ */
+
+import {spawnSync} from 'bun'
+import {chmod, mkdtemp, readFile, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {run, runTm} from '../helpers.ts'
+
+const SOURCE = `#[cfg(not(testme_flag))]
+compile_error!("compiler.rust.flags not passed");
+
+fn main() {
+    // TryFrom is only in the prelude from edition 2021
+    let value = u8::try_from(helper::value()).unwrap();
+    std::process::exit(if value == 2 { 0 } else { 1 });
+}
+`
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Stand-in compiler is a shell script - skipping on Windows')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-rust-')))
+    try {
+        // A crate for --extern and a stand-in compiler that records each command before running rustc
+        await writeFile(join(rootDir, 'helper.rs'), 'pub fn value() -> i32 {\n    2\n}\n')
+        const crate = join(rootDir, 'libhelper.rlib')
+        const built = spawnSync(['rustc', '--crate-type', 'rlib', '--crate-name', 'helper', '-o', crate, 'helper.rs'], {
+            cwd: rootDir,
+        })
+        teq(built.exitCode, 0, 'Helper crate built')
+        const compiler = join(rootDir, 'compile-rust')
+        const log = join(rootDir, 'compiles')
+        await writeFile(compiler, `#!/bin/sh\necho "$*" >> "${log}"\nexec rustc "$@"\n`)
+        await chmod(compiler, 0o755)
+        await writeFile(join(rootDir, 'value.tst.rs'), SOURCE)
+
+        const configure = (flags: string[], libraries: string[]) =>
+            writeFile(join(rootDir, 'testme.json5'), JSON.stringify({compiler: {rust: {compiler, flags, libraries}}}))
+        const compiles = async () => (await readFile(log, 'utf-8')).trim().split('\n')
+        const flags = ['--edition', '2021', '--cfg', 'testme_flag']
+
+        await configure(flags, [`helper=${crate}`, 'm'])
+        teq((await runTm(['value.tst.rs'], rootDir)).exitCode, 0, 'Test built with the configured compiler passes')
+        const command = (await compiles())[0]!
+        ttrue(command.startsWith('--edition 2021 --cfg testme_flag --crate-name value -o '), 'Flags passed first')
+        ttrue(command.endsWith(`value.tst.rs --extern helper=${crate} -l m`), 'name=path is a crate, others native')
+
+        teq((await runTm(['value.tst.rs'], rootDir)).exitCode, 0, 'Cached binary passes')
+        teq((await compiles()).length, 1, 'Unchanged test is not recompiled')
+
+        await configure([...flags, '-O'], [`helper=${crate}`, 'm'])
+        teq((await runTm(['value.tst.rs'], rootDir)).exitCode, 0, 'Test passes with changed flags')
+        teq((await compiles()).length, 2, 'Changed flags rebuild the test')
+
+        await configure([...flags, '-O'], [`helper=${crate}`])
+        teq((await runTm(['value.tst.rs'], rootDir)).exitCode, 0, 'Test passes with changed libraries')
+        teq((await compiles()).length, 3, 'Changed libraries rebuild the test')
+
+        await configure(['--edition', '2021'], [`helper=${crate}`])
+        const failed = await runTm(['value.tst.rs'], rootDir)
+        ttrue(failed.exitCode !== 0, 'Compile error without the configured flags')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,14 @@
+#!/usr/bin/env bun
+
+// Skip Rust tests if rustc is not installed
+try {
+    const proc = Bun.spawnSync(['rustc', '--version'])
+    if (proc.exitCode !== 0) {
+        console.log('Rust not installed - skipping Rust tests')
+        process.exit(1)
+    }
+} catch (e) {
+    console.log('Rust not installed - skipping Rust tests')
+    process.exit(1)
+}
+process.exit(0)
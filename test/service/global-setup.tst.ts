@@ -0,0 +1,62 @@
+/*
+    Global setup and teardown tests
+    Verifies the root setup commands run in order, a failed setup reports its output and teardown always runs
+ */
+
+import {ServiceManager} from '../../src/services.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {existsSync} from 'node:fs'
+import {mkdtemp, rm} from 'node:fs/promises'
+import {tmpdir} from 'node:os'
+import {join} from 'path'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX commands - skipping on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-global-'))
+    try {
+        const services = new ServiceManager(dir)
+        const config: TestConfig = {
+            configDir: dir,
+            setup: ['touch first', 'cp first second'],
+            teardown: 'touch stopped',
+        }
+        await services.runGlobalSetup(config)
+        ttrue(existsSync(join(dir, 'second')), 'Setup commands run in order in the config directory')
+        ttrue(!existsSync(join(dir, 'stopped')), 'Teardown waits for the end of the run')
+        await services.runGlobalTeardown(true)
+        ttrue(existsSync(join(dir, 'stopped')), 'Teardown runs after the run')
+        await rm(join(dir, 'stopped'))
+        await services.runGlobalTeardown(true)
+        ttrue(!existsSync(join(dir, 'stopped')), 'Teardown runs once per setup')
+
+        const failing: TestConfig = {
+            configDir: dir,
+            setup: ['ls missing-file', 'touch never'],
+            teardown: 'touch stopped',
+        }
+        let error = ''
+        try {
+            await services.runGlobalSetup(failing)
+        } catch (err) {
+            error = (err as Error).message
+        }
+        ttrue(/^Global setup 'ls missing-file' failed with exit code \d+/.test(error), 'Failed setup is reported')
+        ttrue(error.includes('No such file'), 'Setup output shown on failure')
+        ttrue(!existsSync(join(dir, 'never')), 'Setup stops at the first failure')
+        await services.runGlobalTeardown(false)
+        ttrue(existsSync(join(dir, 'stopped')), 'Teardown runs after a failed setup')
+
+        await services.runGlobalSetup({configDir: dir, teardown: ['false', 'touch after']})
+        await services.runGlobalTeardown(false)
+        ttrue(existsSync(join(dir, 'after')), 'Every teardown command runs after a failure')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,67 @@
+/*
+    Test sharding unit tests
+    Verifies that shards partition the tests stably and that recorded timings balance them
+ */
+
+import {TestShards} from '../../src/shards.ts'
+import {TestTimings} from '../../src/timings.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, rm} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    ttrue(TestShards.parse('2/4').index === 2 && TestShards.parse('2/4').count === 4, 'Shard parsed')
+    for (const spec of ['0/4', '5/4', '2', 'a/b']) {
+        let failed = false
+        try {
+            TestShards.parse(spec)
+        } catch {
+            failed = true
+        }
+        ttrue(failed, `Invalid shard ${spec} rejected`)
+    }
+
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-shards-'))
+    try {
+        const tests = Array.from({length: 40}, (_, i) => makeFile(join(rootDir, `dir${i % 3}`), `t${i}.tst.sh`))
+        const shards = [1, 2, 3].map((index) => TestShards.select(tests, rootDir, {index, count: 3}))
+        const all = shards.flat()
+        ttrue(all.length === tests.length && new Set(all).size === tests.length, 'Every test in exactly one shard')
+        ttrue(shards.every((shard) => shard.length > 0), 'Tests spread across shards')
+
+        // The same tests land in the same shard regardless of order or other tests
+        const again = TestShards.select([...tests].reverse().slice(5), rootDir, {index: 1, count: 3})
+        ttrue(again.every((test) => shards[0]!.includes(test)), 'Partition is stable')
+
+        // Record durations: one long test and many short ones
+        const results = tests.map((file, i) => ({
+            file,
+            status: i === 7 ? TestStatus.Skipped : TestStatus.Passed,
+            duration: i === 0 ? 10000 : 100,
+            output: '',
+        }))
+        await TestTimings.save(rootDir, results)
+        const timings = (await TestTimings.load(rootDir))!
+        ttrue(timings.get(tests[0]!.path) === 10000 && !timings.has(tests[7]!.path), 'Timings recorded')
+
+        // Later runs are blended into the history rather than replacing it
+        await TestTimings.save(rootDir, [{...results[1]!, duration: 1100}])
+        const blended = (await TestTimings.load(rootDir))!.get(tests[1]!.path)!
+        ttrue(blended > 100 && blended < 1100, 'Timings kept as a moving average')
+        teq((await TestTimings.load(rootDir))!.get(tests[2]!.path), 100, 'Tests that did not run keep history')
+
+        const balanced = [1, 2, 3].map((index) => TestShards.select(tests, rootDir, {index, count: 3}, timings))
+        const owner = balanced.find((shard) => shard.includes(tests[0]!))!
+        teq(owner.length, 1, 'Long test gets a shard of its own')
+        const others = balanced.filter((shard) => shard !== owner).map((shard) => shard.length)
+        ttrue(Math.abs(others[0]! - others[1]!) <= 2, 'Remaining tests split evenly')
+        teq(balanced.flat().length, tests.length, 'Balanced shards cover every test')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
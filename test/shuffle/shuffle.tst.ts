@@ -0,0 +1,29 @@
+/*
+    Shuffled test order unit tests
+    Verifies that a seed reproduces the same order and that the JSON report records it
+ */
+
+import {randomSeed, seededRandom, shuffle} from '../../src/utils/shuffle.ts'
+import {JsonReporter} from '../../src/reporters/json.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const items = Array.from({length: 20}, (_, i) => i)
+
+    const first = shuffle(items, seededRandom(1234))
+    const second = shuffle(items, seededRandom(1234))
+    teq(first.join(), second.join(), 'Same seed gives the same order')
+    ttrue(first.join() !== items.join(), 'Order is shuffled')
+    ttrue(shuffle(items, seededRandom(99)).join() !== first.join(), 'Different seed gives a different order')
+    teq([...first].sort((a, b) => a - b).join(), items.join(), 'All items kept')
+    ttrue(items[0] === 0 && items[19] === 19, 'Input not modified')
+
+    const seed = randomSeed()
+    ttrue(Number.isInteger(seed) && seed >= 0 && seed <= 0xffffffff, 'Random seed is an unsigned 32-bit integer')
+
+    teq(new JsonReporter('results.json', '/tmp', 0, 42).render().summary.seed, 42, 'Seed in JSON summary')
+    ttrue(!('seed' in new JsonReporter('results.json', '/tmp').render().summary), 'No seed when not shuffled')
+}
+
+await run(test)
@@ -0,0 +1,47 @@
+/*
+    Graceful interrupt tests
+    Verifies SIGTERM stops launching tests, kills running tests, runs teardown, reports a partial summary and exits 143
+ */
+
+import {spawn} from 'bun'
+import {existsSync} from 'fs'
+import {mkdtemp, readFile, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {run, tmPath} from '../helpers.ts'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX signals - skipping on Windows')
+        return
+    }
+    const rootDir = await realpath(await mkdtemp(join(tmpdir(), 'testme-interrupt-')))
+    try {
+        await writeFile(join(rootDir, 'testme.json5'), `{teardown: 'touch ${join(rootDir, 'stopped')}'}\n`)
+        await writeFile(join(rootDir, 'a-fast.tst.sh'), 'exit 0\n')
+        await writeFile(join(rootDir, 'b-slow.tst.sh'), 'sleep 60\n')
+        await writeFile(join(rootDir, 'c-never.tst.sh'), `touch ${join(rootDir, 'started')}\n`)
+
+        const started = Date.now()
+        const args = ['--workers', '1', '--json', 'results.json']
+        const proc = spawn([tmPath, ...args], {cwd: rootDir, stdout: 'pipe', stderr: 'pipe'})
+        const stdout = new Response(proc.stdout).text()
+        await Bun.sleep(2000)
+        proc.kill('SIGTERM')
+        await proc.exited
+        const output = await stdout
+
+        teq(proc.exitCode, 143, 'Exits with 128 + SIGTERM')
+        ttrue(Date.now() - started < 20000, 'Running test is stopped')
+        ttrue(!existsSync(join(rootDir, 'started')), 'No new tests are started')
+        ttrue(existsSync(join(rootDir, 'stopped')), 'Teardown runs after an interrupt')
+        ttrue(/interrupted by SIGTERM: 1 test\(s\) not run/.test(output), 'Partial summary counts tests not run')
+        const results = JSON.parse(await readFile(join(rootDir, 'results.json'), 'utf8'))
+        ttrue(results.tests.some((t: {path: string}) => t.path.endsWith('a-fast.tst.sh')), 'Report is flushed')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
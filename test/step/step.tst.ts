@@ -0,0 +1,77 @@
+/*
+    Step mode tests
+    Verifies the re-run, skip, quit and continue choices with scripted answers, that only the last run of a re-run test
+    is recorded and that quitting reports the tests not run
+ */
+
+import {TestRunner} from '../../src/runner.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {spawn} from 'bun'
+import {existsSync} from 'fs'
+import {mkdtemp, realpath, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {makeFile, run, tmPath} from '../helpers.ts'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Shell step mode test not supported on Windows - skipping')
+        return
+    }
+    const dir = await realpath(await mkdtemp(join(tmpdir(), 'testme-step-')))
+    try {
+        // Fails on the first run and passes when re-run
+        await writeFile(join(dir, 'a.tst.sh'), `if [ -f ${dir}/ran ]; then exit 0; fi\ntouch ${dir}/ran\nexit 1\n`)
+        await writeFile(join(dir, 'b.tst.sh'), 'exit 0\n')
+        await writeFile(join(dir, 'c.tst.sh'), `touch ${dir}/c-ran\n`)
+        await writeFile(join(dir, 'd.tst.sh'), `touch ${dir}/d-ran\n`)
+        const tests = ['a.tst.sh', 'b.tst.sh', 'c.tst.sh', 'd.tst.sh'].map((name) => makeFile(dir, name))
+        const config: TestConfig = {
+            execution: {timeout: 10, parallel: false, stepMode: true},
+            output: {verbose: false, format: 'simple', colors: false, quiet: true},
+        }
+
+        // Re-run a, continue to b, skip c, then quit before d
+        const answers = ['r', '', 's', 'q']
+        const prompts: string[] = []
+        const runner = new TestRunner()
+        runner.setPromptReader((message) => {
+            prompts.push(message)
+            return answers.shift() ?? null
+        })
+        const results = await runner.executeTestsWithConfig(tests, config)
+
+        teq(prompts.length, 4, 'One prompt per choice')
+        ttrue(prompts[0]!.includes('r to re-run a.tst.sh') && prompts[0]!.includes('s to skip b.tst.sh'), 'Choices')
+        ttrue(!prompts[3]!.includes('re-run'), 'A skipped test cannot be re-run')
+        teq(results.map((result) => result.file.name).join(), 'a.tst.sh,b.tst.sh,c.tst.sh', 'One result per test')
+        teq(results[0]!.status, TestStatus.Passed, 'Only the last run of a re-run test is recorded')
+        teq(results[2]!.status, TestStatus.Skipped, 'Skipped test recorded as skipped')
+        ttrue(!existsSync(join(dir, 'c-ran')) && !existsSync(join(dir, 'd-ran')), 'Skipped and quit tests not run')
+        ttrue(runner.isStepQuit(), 'Quit recorded')
+        teq(tests.length - results.length, 1, 'Quit leaves the rest of the tests not run')
+
+        // At the end of input the run continues
+        const finished = new TestRunner()
+        finished.setPromptReader(() => null)
+        const all = await finished.executeTestsWithConfig(tests, config)
+        ttrue(all.length === 4 && !finished.isStepQuit(), 'Every test runs when there is no input')
+
+        // Quitting from the command line reports how many tests were not run
+        const proc = spawn([tmPath, '--step', 'a.tst.sh', 'b.tst.sh', 'c.tst.sh'], {
+            cwd: dir,
+            stdin: new TextEncoder().encode('q\n'),
+            stdout: 'pipe',
+            stderr: 'pipe',
+        })
+        const stdout = await new Response(proc.stdout).text()
+        await proc.exited
+        ttrue(stdout.includes('🛑 Run quit in step mode: 2 test(s) not run'), 'Quit summary counts the tests not run')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
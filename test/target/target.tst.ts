@@ -0,0 +1,48 @@
+/*
+    Cross-compilation target unit tests
+    Verifies triples map to Go platforms, host detection, runner parsing and cross compiler selection
+ */
+
+import {CrossTarget} from '../../src/target.ts'
+import {CompilerManager} from '../../src/platform/compiler.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+
+async function test() {
+    const arm = CrossTarget.parse('arm-linux-gnueabihf')
+    ttrue(arm.os === 'linux' && arm.arch === 'arm', 'arm-linux-gnueabihf is linux/arm')
+    const arm64 = CrossTarget.parse('aarch64-apple-darwin')
+    ttrue(arm64.os === 'darwin' && arm64.arch === 'arm64', 'aarch64-apple-darwin is darwin/arm64')
+    const mingw = CrossTarget.parse('x86_64-w64-mingw32')
+    ttrue(mingw.os === 'windows' && mingw.arch === 'amd64', 'x86_64-w64-mingw32 is windows/amd64')
+    teq(CrossTarget.parse('i686-linux-gnu').arch, '386', 'i686 is 386')
+
+    const host = CrossTarget.getHost()
+    ttrue(CrossTarget.isNative(host), 'Host platform is native')
+    const other = host.arch === 'arm' ? 'riscv64' : 'arm'
+    ttrue(!CrossTarget.isNative({os: host.os, arch: other}), 'Other architecture is not native')
+
+    teq(CrossTarget.getTriple({}), undefined, 'No target by default')
+    const config: TestConfig = {
+        target: {triple: 'arm-linux-gnueabihf', goarch: 'arm64', runner: ' qemu-arm  -L /usr/arm-linux-gnueabihf '},
+    }
+    const go = CrossTarget.getGoPlatform(config)
+    ttrue(go?.os === 'linux' && go?.arch === 'arm64', 'target.goarch overrides the triple')
+    teq(CrossTarget.getRunner(config).join('|'), 'qemu-arm|-L|/usr/arm-linux-gnueabihf', 'Runner split')
+    teq(CrossTarget.getGoPlatform({target: {goos: 'freebsd'}})?.os, 'freebsd', 'GOOS without a triple')
+
+    const cli: TestConfig = {...config, execution: {timeout: 30, parallel: true, target: 'aarch64-linux-gnu'}}
+    teq(CrossTarget.getTriple(cli), 'aarch64-linux-gnu', '--target overrides target.triple')
+
+    // Cross compiler selection ignores host settings
+    const withHost: TestConfig = {...cli, compiler: {cc: 'clang'}}
+    const selected = CompilerManager.selectCompiler(withHost)
+    ttrue(selected.name === 'aarch64-linux-gnu-gcc' && selected.source === 'target', '<triple>-gcc by default')
+    const clang = CompilerManager.selectCompiler({...withHost, target: {cc: 'clang'}})
+    ttrue(clang.name === 'clang' && clang.source === 'target.cc', 'target.cc chooses the cross compiler')
+    const forced = CompilerManager.selectCompiler({...withHost, execution: {...cli.execution!, cc: 'tcc'}})
+    ttrue(forced.name === 'tcc' && forced.source === '--cc', '--cc still wins when cross-compiling')
+}
+
+await run(test)
@@ -0,0 +1,49 @@
+/*
+    Idle timeout unit tests
+    Verifies a silent test is killed with idle-timeout status and a test that keeps writing output is not
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Uses POSIX shell tests - skipping on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-idle-'))
+    try {
+        const handler = new ShellTestHandler()
+        const config: TestConfig = {configDir: dir, execution: {timeout: 30, idleTimeout: 1, parallel: false}}
+
+        const silent = makeFile(dir, 'silent.tst.sh')
+        await writeFile(silent.path, '#!/bin/sh\necho starting\nsleep 60\n')
+        await chmod(silent.path, 0o755)
+        const started = Date.now()
+        const result = await handler.execute(silent, config)
+        teq(result.status, TestStatus.IdleTimeout, 'Silent test has idle-timeout status')
+        ttrue((result.error || '').includes('produced no output for 1s'), 'Idle timeout message')
+        ttrue(Date.now() - started < 10000, 'Killed long before its overall timeout')
+
+        const chatty = makeFile(dir, 'chatty.tst.sh')
+        await writeFile(chatty.path, '#!/bin/sh\nfor i in 1 2 3 4; do echo $i; sleep 0.5; done\n')
+        await chmod(chatty.path, 0o755)
+        teq((await handler.execute(chatty, config)).status, TestStatus.Passed, 'Output restarts the idle timer')
+
+        const disabled = {...config, execution: {...config.execution!, idleTimeout: 0}}
+        const slow = makeFile(dir, 'slow.tst.sh')
+        await writeFile(slow.path, '#!/bin/sh\nsleep 2\n')
+        await chmod(slow.path, 0o755)
+        teq((await handler.execute(slow, disabled)).status, TestStatus.Passed, 'Zero disables the idle timeout')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,69 @@
+/*
+    Per-test timeout unit tests
+    Verifies timeout status, per-test overrides, zero timeouts and that the process-group kill takes the test's
+    children and grandchildren with it
+ */
+
+import {ShellTestHandler} from '../../src/handlers/shell.ts'
+import {ProcessManager} from '../../src/platform/process.ts'
+import type {TestConfig, TestFile} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdtemp, readFile, rm, writeFile, chmod} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+class TimeoutHandler extends ShellTestHandler {
+    timeoutFor(config: TestConfig, file: TestFile): number | undefined {
+        return this.getTimeout(config, file)
+    }
+}
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Process group test not supported on Windows - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-timeout-'))
+    try {
+        const handler = new TimeoutHandler()
+        const hang = makeFile(dir, 'hang.tst.sh')
+        const quick = makeFile(dir, 'quick.tst.sh')
+        const config: TestConfig = {configDir: dir, execution: {timeout: 1, parallel: false}}
+
+        // Resolve the effective timeout for each test
+        teq(handler.timeoutFor(config, quick), 1000, 'Configured timeout in milliseconds')
+        teq(handler.timeoutFor({execution: {timeout: 0, parallel: false}}, quick), undefined, 'Zero means none')
+        const overrides = {...config, execution: {timeout: 1, parallel: false, timeouts: {'hang.tst.sh': 5}}}
+        teq(handler.timeoutFor(overrides, hang), 5000, 'Per-test override by name')
+        const globs = {...config, execution: {timeout: 1, parallel: false, timeouts: {'h*.tst.sh': 0}}}
+        teq(handler.timeoutFor(globs, hang), undefined, 'Per-test override by glob')
+
+        // The test starts a background child which starts a grandchild, both would outlive a simple kill of the shell
+        const pidFile = join(dir, 'child.pid')
+        const grandFile = join(dir, 'grandchild.pid')
+        const spawner = `sh -c 'sleep 60 & echo $! > ${grandFile}; wait' &`
+        await writeFile(hang.path, `#!/bin/sh\n${spawner}\necho $! > ${pidFile}\nsleep 60\n`)
+        await chmod(hang.path, 0o755)
+
+        const result = await handler.execute(hang, config)
+        teq(result.status, TestStatus.Timeout, 'Hung test has timeout status')
+        ttrue((result.error || '').includes('timed out after 1s'), 'Timeout message')
+
+        const childPid = parseInt(await readFile(pidFile, 'utf-8'), 10)
+        const grandPid = parseInt(await readFile(grandFile, 'utf-8'), 10)
+        await Bun.sleep(100)
+        ttrue(!(await ProcessManager.isProcessRunning(childPid)), 'Background subprocess killed with the group')
+        ttrue(!(await ProcessManager.isProcessRunning(grandPid)), 'Grandchild killed with the group')
+
+        await writeFile(quick.path, '#!/bin/sh\nexit 1\n')
+        await chmod(quick.path, 0o755)
+        const failed = await handler.execute(quick, config)
+        teq(failed.status, TestStatus.Failed, 'Failing test keeps failed status')
+    } finally {
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,78 @@
+/*
+    Per-test temporary directory tests
+    Verifies unique directories, removal and keeping (including --keep-on-fail), the working directory and the chdir
+    directive
+ */
+
+import {TestTmp} from '../../src/tmp.ts'
+import {Directives} from '../../src/directives.ts'
+import {BaseTestHandler} from '../../src/handlers/base.ts'
+import {CliParser} from '../../src/cli.ts'
+import {DryRun} from '../../src/utils/dry-run.ts'
+import type {TestConfig, TestFile, TestResult} from '../../src/types.ts'
+import {TestStatus, TestType} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {run} from '../helpers.ts'
+import {existsSync} from 'node:fs'
+import {rm, writeFile} from 'node:fs/promises'
+import {join} from 'path'
+
+function result(status: TestStatus): TestResult {
+    return {status} as TestResult
+}
+
+async function test() {
+    const file = {
+        name: 'math test.tst.c',
+        path: join(import.meta.dir, 'math test.tst.c'),
+        extension: '.c',
+        directory: import.meta.dir,
+        type: TestType.C,
+    } as TestFile
+
+    // Tests running at the same time never share a directory
+    const dirs = await Promise.all([1, 2, 3, 4].map(() => TestTmp.create(file)))
+    ttrue(new Set(dirs).size === dirs.length && dirs.every((dir) => existsSync(dir)), 'Unique directories created')
+    ttrue(dirs[0]!.includes('testme-math_test.tst.c-'), 'Directory named after the test')
+    await writeFile(join(dirs[0]!, 'scratch'), 'data')
+    await TestTmp.release(dirs[0]!, false)
+    ttrue(!existsSync(dirs[0]!), 'Directory and its files removed')
+    await TestTmp.release(dirs[1]!, true)
+    ttrue(existsSync(dirs[1]!), 'Kept directory remains')
+    await Promise.all(dirs.slice(2).map((dir) => TestTmp.release(dir, false)))
+    await rm(dirs[1]!, {recursive: true, force: true})
+
+    const keepAll: TestConfig = {execution: {timeout: 30, parallel: true, keepTmp: true}}
+    const keepFailed: TestConfig = {execution: {timeout: 30, parallel: true, keepTmpOnFail: true}}
+    ttrue(TestTmp.isKept(keepAll, result(TestStatus.Passed)), '--keep-tmp keeps passing tests')
+    ttrue(!TestTmp.isKept(keepFailed, result(TestStatus.Passed)), '--keep-tmp-on-fail removes passing tests')
+    ttrue(TestTmp.isKept(keepFailed, result(TestStatus.Timeout)), '--keep-tmp-on-fail keeps timed out tests')
+    ttrue(!TestTmp.isKept({}, result(TestStatus.Failed)), 'Removed by default')
+    const keepOnFail: TestConfig = {execution: {timeout: 30, parallel: true, keepOnFail: true}}
+    ttrue(TestTmp.isKept(keepOnFail, result(TestStatus.Error)), '--keep-on-fail keeps failing tests')
+    ttrue(!TestTmp.isKept(keepOnFail, result(TestStatus.XFail)), '--keep-on-fail removes expected failures')
+    ttrue(TestTmp.isPassed(result(TestStatus.Skipped)) && !TestTmp.isPassed(result(TestStatus.XPass)), 'Passing')
+    teq(CliParser.parse(['--keep-on-fail']).keepOnFail, true, '--keep-on-fail')
+    let conflict = false
+    try {
+        CliParser.validateOptions(CliParser.parse(['--keep-on-fail', '--keep']))
+    } catch {
+        conflict = true
+    }
+    ttrue(conflict, '--keep-on-fail conflicts with --keep')
+
+    // The temporary directory is the working directory unless chdir says otherwise
+    const execution = {timeout: 30, parallel: true, tmpDir: '/tmp/testme-x'}
+    teq(BaseTestHandler.getWorkingDirectory({execution}, file), '/tmp/testme-x', 'Runs in TESTME_TMP')
+    teq(BaseTestHandler.getWorkingDirectory({}, file), import.meta.dir, 'Test directory without TESTME_TMP')
+    teq(Directives.parse('// testme: chdir data\n').chdir, 'data', 'Chdir directive parsed')
+    teq(Directives.parse('# testme: chdir\n').chdir, '.', 'Chdir alone is the test directory')
+    const config = Directives.applyTestInput({execution}, Directives.parse('// testme: chdir data\n'), file)
+    teq(config.execution?.chdir, join(import.meta.dir, 'data'), 'Chdir relative to the test file')
+    teq(BaseTestHandler.getWorkingDirectory(config, file), join(import.meta.dir, 'data'), 'Chdir overrides')
+    const parent = Directives.applyTestInput({execution: {...execution, chdir: '../fixtures'}}, {}, file)
+    teq(parent.execution?.chdir, join(import.meta.dir, '..', 'fixtures'), 'Configured chdir from the test file')
+    teq(DryRun.formatCommand('sh', ['t.sh'], {cwd: '/w d'}), "cd '/w d' && sh t.sh", 'Dry run shows chdir')
+}
+
+await run(test)
@@ -0,0 +1,88 @@
+/*
+    TypeScript mode tests
+    Verifies that compiler.typescript.mode selects tsc or ts-node, that an unknown mode is an error, and that tsc
+    diagnostics and ts-node TSError output give an Error status while a failing test is Failed. A stand-in script
+    replaces ts-node and the tsc checks are skipped when tsc or node is not installed.
+ */
+
+import {TypeScriptTestHandler} from '../../src/handlers/typescript.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {chmod, mkdir, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    if (process.platform === 'win32') {
+        console.log('Stand-in ts-node is a shell script - skipping on Windows')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-typescript-'))
+    const path = process.env.PATH
+    try {
+        const handler = new TypeScriptTestHandler()
+        await writeFile(join(dir, 'pass.tst.ts'), "const count: number = 1\nconsole.log(`count ${count}`)\n")
+        await writeFile(join(dir, 'typed.tst.ts'), "const count: number = 'one'\nconsole.log(count)\n")
+        await writeFile(join(dir, 'fail.tst.ts'), "throw new Error('boom')\n")
+
+        const deno = {compiler: {typescript: {mode: 'deno'}}} as any as TestConfig
+        const invalid = await handler.execute(makeFile(dir, 'pass.tst.ts'), deno)
+        teq(invalid.status, TestStatus.Error, 'Unknown mode is an error')
+        ttrue(!!invalid.error?.includes('Invalid compiler.typescript.mode "deno"'), 'Error names the mode')
+
+        // The stand-in ts-node echoes its arguments and reports a TSError for the badly typed test
+        const bin = join(dir, 'bin')
+        await mkdir(bin)
+        const script = [
+            '#!/bin/sh',
+            'echo "ts-node $*"',
+            'case "$*" in *typed.tst.ts) echo "TSError: Unable to compile TypeScript" >&2; exit 1;; esac',
+            'case "$*" in *fail.tst.ts) echo "Error: boom" >&2; exit 1;; esac',
+        ]
+        await writeFile(join(bin, 'ts-node'), script.join('\n') + '\n')
+        await chmod(join(bin, 'ts-node'), 0o755)
+        process.env.PATH = `${bin}:${path}`
+
+        const tsNode: TestConfig = {
+            configDir: dir,
+            compiler: {typescript: {mode: 'ts-node', tsconfig: 'tsconfig.json'}},
+            execution: {timeout: 30, args: ['--fast']},
+        }
+        let result = await handler.execute(makeFile(dir, 'pass.tst.ts'), tsNode)
+        teq(result.status, TestStatus.Passed, 'ts-node mode runs the test with ts-node')
+        const expected = `ts-node --project ${join(dir, 'tsconfig.json')} ${join(dir, 'pass.tst.ts')} --fast`
+        ttrue(result.output.includes(expected), 'tsconfig passed as --project and test arguments follow the file')
+
+        result = await handler.execute(makeFile(dir, 'typed.tst.ts'), tsNode)
+        teq(result.status, TestStatus.Error, 'TSError is a compile error')
+        ttrue(!!result.error?.startsWith('Compilation failed:\n') && result.error.includes('TSError'), 'TSError shown')
+        result = await handler.execute(makeFile(dir, 'fail.tst.ts'), tsNode)
+        teq(result.status, TestStatus.Failed, 'Runtime error without TSError fails the test')
+        process.env.PATH = path
+
+        if (!(await PlatformDetector.findInPath('tsc')) || !(await PlatformDetector.findInPath('node'))) {
+            console.log('tsc mode needs tsc and node - skipping')
+            return
+        }
+        const tsc: TestConfig = {compiler: {typescript: {mode: 'tsc'}}, execution: {timeout: 60}}
+        const pass = makeFile(dir, 'pass.tst.ts')
+        result = await handler.execute(pass, tsc)
+        teq(result.status, TestStatus.Passed, 'tsc mode compiles and runs the test with node')
+        ttrue(result.output.includes('count 1'), 'Transpiled test output')
+        await handler.cleanup(pass, tsc)
+
+        result = await handler.execute(makeFile(dir, 'typed.tst.ts'), tsc)
+        teq(result.status, TestStatus.Error, 'tsc type error is a compile error')
+        ttrue(!!result.error?.startsWith('Compilation failed:\n'), 'tsc diagnostics shown')
+        result = await handler.execute(makeFile(dir, 'fail.tst.ts'), tsc)
+        teq(result.status, TestStatus.Failed, 'Test that throws under node fails')
+    } finally {
+        process.env.PATH = path
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
@@ -0,0 +1,85 @@
+/*
+    Valgrind unit tests
+    Verifies the valgrind command line, suppression file resolution, ERROR SUMMARY counting and that valgrind errors
+    fail a test that exited cleanly
+ */
+
+import {CTestHandler} from '../../src/handlers/c.ts'
+import {PlatformDetector} from '../../src/platform/detector.ts'
+import type {TestConfig} from '../../src/types.ts'
+import {TestStatus} from '../../src/types.ts'
+import {chmod, mkdir, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+
+async function test() {
+    teq(CTestHandler.countValgrindErrors(''), 0, 'No log is no errors')
+    teq(CTestHandler.countValgrindErrors('==7== ERROR SUMMARY: 0 errors from 0 contexts'), 0, 'Clean run')
+    teq(CTestHandler.countValgrindErrors('==7== ERROR SUMMARY: 1 error from 1 contexts'), 1, 'Singular error')
+    const log = '==7== ERROR SUMMARY: 1 errors from 1 contexts\n==7== ERROR SUMMARY: 3 errors from 2 contexts\n'
+    teq(CTestHandler.countValgrindErrors(log), 3, 'Last ERROR SUMMARY wins')
+
+    const handler = new CTestHandler()
+    const file = makeFile('/work/unit', 'math.tst.c')
+    const binary = '/work/unit/.testme/math.tst.c/math'
+    const logFile = `--log-file=${join(file.artifactDir, 'valgrind.log')}`
+    teq(
+        handler.getValgrindArgs(file, {valgrind: {enable: true}}, binary).join(' '),
+        `--error-exitcode=1 --leak-check=full ${logFile} ${binary}`,
+        'Default arguments'
+    )
+    const config: TestConfig = {
+        configDir: '/work',
+        valgrind: {enable: true, suppressions: 'valgrind.supp', flags: ['--track-origins=yes', '--leak-check=no']},
+    }
+    teq(
+        handler.getValgrindArgs(file, config, binary).slice(3).join(' '),
+        `--suppressions=/work/valgrind.supp --track-origins=yes --leak-check=no ${binary}`,
+        'Suppressions relative to the config file, then user flags so they override the defaults'
+    )
+    const absolute = {valgrind: {enable: true, suppressions: '/etc/valgrind.supp'}}
+    ttrue(handler.getValgrindArgs(file, absolute, binary).includes('--suppressions=/etc/valgrind.supp'), 'Absolute')
+    const local = {valgrind: {enable: true, suppressions: 'local.supp'}}
+    ttrue(handler.getValgrindArgs(file, local, binary).includes('--suppressions=/work/unit/local.supp'), 'Test dir')
+
+    if (PlatformDetector.isWindows() || !(await PlatformDetector.findInPath('cc'))) {
+        console.log('Valgrind run test needs a C compiler - skipping')
+        return
+    }
+    const dir = await mkdtemp(join(tmpdir(), 'testme-valgrind-'))
+    const path = process.env.PATH
+    try {
+        // A stand-in valgrind that reports errors in its log and runs the test
+        const bin = join(dir, 'bin')
+        await mkdir(bin)
+        const script = [
+            '#!/bin/sh',
+            'for arg; do case "$arg" in --log-file=*) log="${arg#--log-file=}";; --*) ;; *) break;; esac; shift; done',
+            'echo "==7== ERROR SUMMARY: $ERRORS errors from 1 contexts" > "$log"',
+            'exec "$@"',
+        ]
+        await writeFile(join(bin, 'valgrind'), script.join('\n') + '\n')
+        await chmod(join(bin, 'valgrind'), 0o755)
+        process.env.PATH = `${bin}:${path}`
+
+        const leak = makeFile(dir, 'leak.tst.c')
+        await writeFile(leak.path, 'int main() { return 0; }\n')
+        const runWith = (errors: number) =>
+            handler.execute(leak, {
+                execution: {timeout: 30, parallel: false},
+                valgrind: {enable: true},
+                environment: {ERRORS: String(errors)},
+            })
+        teq((await runWith(0)).status, TestStatus.Passed, 'No valgrind errors passes')
+        const failed = await runWith(2)
+        teq(failed.status, TestStatus.Failed, 'ERROR SUMMARY errors fail a test that exited zero')
+        ttrue(!!failed.error?.startsWith('Valgrind reported 2 error(s)'), 'Error names the count')
+    } finally {
+        process.env.PATH = path
+        await rm(dir, {recursive: true, force: true})
+    }
+}
+
+await run(test)
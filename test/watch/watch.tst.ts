@@ -0,0 +1,69 @@
+/*
+    Watch mode unit tests
+    Verifies change debouncing, ignored artifact directories and affected test selection
+ */
+
+import {FileWatcher} from '../../src/watch.ts'
+import type {TestFile} from '../../src/types.ts'
+import {teq, ttrue} from 'testme'
+import {makeFile, run} from '../helpers.ts'
+import {mkdir, mkdtemp, rm, writeFile} from 'fs/promises'
+import {join} from 'path'
+import {tmpdir} from 'os'
+
+async function test() {
+    const rootDir = await mkdtemp(join(tmpdir(), 'testme-watch-'))
+    try {
+        // Affected test selection
+        const unit = join(rootDir, 'unit')
+        const deep = join(unit, 'deep')
+        const other = join(rootDir, 'other')
+        const a = makeFile(unit, 'a.tst.sh', {configDir: unit})
+        const b = makeFile(deep, 'b.tst.sh', {configDir: unit})
+        const c = makeFile(other, 'c.tst.sh', {configDir: other})
+        const tests = [a, b, c]
+        const names = (files: TestFile[]) => files.map((file) => file.name).join(',')
+
+        teq(names(FileWatcher.affectedTests(tests, [join(deep, 'b.tst.sh')])), 'b.tst.sh', 'Edited test re-runs')
+        teq(names(FileWatcher.affectedTests(tests, [join(other, 'helper.sh')])), 'c.tst.sh', 'Same directory')
+        teq(
+            names(FileWatcher.affectedTests(tests, [join(unit, 'setup.sh')])),
+            'a.tst.sh,b.tst.sh',
+            'Shared setup in config directory'
+        )
+        teq(
+            names(FileWatcher.affectedTests(tests, [join(rootDir, 'testme.json5')])),
+            'a.tst.sh,b.tst.sh,c.tst.sh',
+            'Parent config change affects all tests below it'
+        )
+        teq(FileWatcher.affectedTests(tests, [join(rootDir, 'README.md')]).length, 0, 'Unrelated change')
+
+        // Debouncing and ignored directories
+        await mkdir(join(rootDir, '.testme'), {recursive: true})
+        const watcher = new FileWatcher(rootDir, 100)
+        watcher.start()
+        const pending = watcher.next()
+        await writeFile(join(rootDir, '.testme', 'artifact'), 'x')
+        await writeFile(join(rootDir, 'one.txt'), '1')
+        await Bun.sleep(20)
+        await writeFile(join(rootDir, 'two.txt'), '2')
+        const changes = (await pending) || []
+        ttrue(changes.some((path) => path.endsWith('one.txt')), 'First change reported')
+        ttrue(changes.some((path) => path.endsWith('two.txt')), 'Rapid changes batched together')
+        ttrue(!changes.some((path) => path.includes('.testme')), 'Artifact directory ignored')
+
+        // Changes while paused are discarded and close releases a waiting caller
+        watcher.pause()
+        await writeFile(join(rootDir, 'three.txt'), '3')
+        await Bun.sleep(200)
+        const waiting = watcher.next()
+        const early = await Promise.race([waiting, Bun.sleep(300).then(() => 'idle')])
+        teq(early, 'idle', 'Changes while paused discarded')
+        watcher.close()
+        teq(await waiting, null, 'Close releases waiting caller')
+    } finally {
+        await rm(rootDir, {recursive: true, force: true})
+    }
+}
+
+await run(test)